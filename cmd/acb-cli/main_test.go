@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/acb/internal/encryption"
+)
+
+func TestRun_Usage(t *testing.T) {
+	for _, args := range [][]string{nil, {"keys"}, {"keys", "shred"}, {"contexts", "rotate"}, {"keys", "generate", "-bogus"}, {"contexts", "reindex", "-batch", "0"},
+		{"tenants", "export"}, {"tenants", "export", "-tenant", "acme", "-format", "zip"},
+		{"tenants", "import", "-tenant", "acme"}, {"tenants", "import", "-file", "a.ndjson", "-on-conflict", "merge"}} {
+		if err := run(context.Background(), args, &bytes.Buffer{}); !errors.Is(err, errUsage) {
+			t.Fatalf("args %v: expected usage error, got %v", args, err)
+		}
+	}
+}
+
+func TestRun_KeysGenerate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"keys", "generate", "-file", path, "-id", "k1"}, &out); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "k1") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	provider, err := encryption.NewFileKeyProvider(path)
+	if err != nil {
+		t.Fatalf("failed to load generated key file: %v", err)
+	}
+	if provider.CurrentKeyID() != "k1" {
+		t.Fatalf("expected current key k1, got %s", provider.CurrentKeyID())
+	}
+}
@@ -6,14 +6,21 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/acb/internal/auth"
+	"github.com/acb/internal/constants"
 	contextmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/encryption"
 	"github.com/acb/internal/registry"
+	"github.com/acb/internal/schema"
 	"github.com/acb/internal/server"
 	"github.com/acb/internal/storage"
+	"github.com/acb/internal/transfer"
+	"github.com/acb/internal/vector"
 )
 
 func main() {
@@ -23,6 +30,36 @@ func main() {
 	redisPassword := getEnv("REDIS_PASSWORD", "acb_redis_password")
 	httpPort := getEnv("HTTP_PORT", "8080")
 	jwtSecret := getEnv("JWT_SECRET", "development-secret-key-change-in-production")
+	masterKeyFile := getEnv("MASTER_KEY_FILE", "")
+	encryptedMetadata := getEnvList("CONTEXT_ENCRYPTED_METADATA")
+
+	// Deleted contexts and agents can be restored for this long, then purged
+	restoreWindow := getEnvDuration("RESTORE_WINDOW", time.Duration(constants.DefaultRestoreWindow)*time.Second)
+
+	reaperConfig := registry.DefaultReaperConfig()
+	reaperConfig.Interval = getEnvDuration("AGENT_SWEEP_INTERVAL", reaperConfig.Interval)
+	reaperConfig.OfflineAfter = getEnvDuration("AGENT_OFFLINE_AFTER", reaperConfig.OfflineAfter)
+	reaperConfig.DeleteAfter = getEnvDuration("AGENT_DELETE_AFTER", reaperConfig.DeleteAfter)
+	reaperConfig.PurgeAfter = restoreWindow
+
+	janitorConfig := contextmgr.DefaultJanitorConfig()
+	janitorConfig.Interval = getEnvDuration("CONTEXT_SWEEP_INTERVAL", janitorConfig.Interval)
+	janitorConfig.BatchSize = getEnvInt("CONTEXT_SWEEP_BATCH_SIZE", janitorConfig.BatchSize)
+	janitorConfig.MaxBatches = getEnvInt("CONTEXT_SWEEP_MAX_BATCHES", janitorConfig.MaxBatches)
+	janitorConfig.EventRetention = getEnvDuration("CONTEXT_EVENT_RETENTION", janitorConfig.EventRetention)
+	janitorConfig.PurgeAfter = restoreWindow
+	touchInterval := getEnvDuration("CONTEXT_TOUCH_INTERVAL", time.Duration(constants.ContextTouchInterval)*time.Second)
+
+	payloadCodec := storage.DefaultPayloadCodec()
+	payloadCodec.Threshold = getEnvInt("CONTEXT_COMPRESSION_THRESHOLD", payloadCodec.Threshold)
+	payloadCodec.Level = getEnvInt("CONTEXT_COMPRESSION_LEVEL", payloadCodec.Level)
+	if err := payloadCodec.Validate(); err != nil {
+		log.Fatalf("Invalid payload compression settings: %v", err)
+	}
+
+	agentCacheTTL := getEnvDuration("CACHE_AGENT_TTL", time.Duration(constants.AgentCacheTTL)*time.Second)
+	contextCacheTTL := getEnvDuration("CACHE_CONTEXT_TTL", time.Duration(constants.ContextCacheTTL)*time.Second)
+	cacheNegativeTTL := getEnvDuration("CACHE_NEGATIVE_TTL", time.Duration(constants.CacheNegativeTTL)*time.Second)
 
 	log.Println("Starting ACB Server...")
 	log.Printf("HTTP Port: %s", httpPort)
@@ -43,20 +80,82 @@ func main() {
 		defer redisStore.Close()
 	}
 
-	// Initialize stores
-	agentStore := storage.NewPostgresAgentStore(postgresPool.Pool())
-	contextStore := storage.NewPostgresContextStore(postgresPool.Pool())
+	// Initialize stores, behind read-through caches when Redis is available
+	var agentStore storage.AgentStore = storage.NewPostgresAgentStore(postgresPool.Pool())
+	contextOpts := []storage.PostgresContextOption{storage.WithPayloadCodec(payloadCodec)}
+	var contextCacheOpts []storage.RedisContextCacheOption
+	if masterKeyFile != "" {
+		provider, err := encryption.NewFileKeyProvider(masterKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load master keys: %v", err)
+		}
+		keyring := encryption.NewKeyring(storage.NewPostgresDataKeyStore(postgresPool.Pool()), provider)
+		contextOpts = append(contextOpts, storage.WithEncryption(keyring, encryptedMetadata...))
+		contextCacheOpts = append(contextCacheOpts, storage.WithCacheEncryption(keyring))
+		log.Printf("Encrypting context payloads at rest with master key %s", provider.CurrentKeyID())
+	}
+	postgresContexts := storage.NewPostgresContextStore(postgresPool.Pool(), contextOpts...)
+	var contextStore storage.ContextStore = postgresContexts
+	var importStore storage.ImportStore = storage.NewPostgresImportStore(postgresPool.Pool(), postgresContexts)
+	schemaStore := storage.NewPostgresSchemaStore(postgresPool.Pool())
+	embeddingStore := storage.NewPostgresEmbeddingStore(postgresPool.Pool())
+	expirationStore := storage.NewPostgresExpirationStore(postgresPool.Pool())
+	policyStore := storage.NewPostgresLifecyclePolicyStore(postgresPool.Pool())
+	leaseStore := storage.NewPostgresLeaseStore(postgresPool.Pool())
+	var serverOpts []server.Option
+	if redisStore != nil {
+		cachedAgents := storage.NewCachedAgentStore(agentStore, storage.NewRedisAgentCache(redisStore), agentCacheTTL, cacheNegativeTTL)
+		cachedContexts := storage.NewCachedContextStore(contextStore, storage.NewRedisContextCache(redisStore, contextCacheOpts...), contextCacheTTL, cacheNegativeTTL)
+		agentStore, contextStore = cachedAgents, cachedContexts
+		importStore = storage.NewCachedImportStore(importStore, cachedAgents, cachedContexts)
+		serverOpts = append(serverOpts,
+			server.WithCacheStats("agents", cachedAgents.Stats),
+			server.WithCacheStats("contexts", cachedContexts.Stats),
+		)
+	}
 
 	// Initialize services
-	registrySvc := registry.NewService(agentStore)
-	contextMgr := contextmgr.NewManager(contextStore)
+	registrySvc := registry.NewService(agentStore).WithRestoreWindow(restoreWindow)
+	schemaRegistry := schema.NewRegistry(schemaStore)
+	transferSvc := transfer.NewService(agentStore, contextStore, importStore)
+	embeddingIndex := contextmgr.NewEmbeddingIndex(contextStore, embeddingStore, vector.DefaultHNSWConfig())
+	toucher, err := contextmgr.NewToucher(contextStore, touchInterval)
+	if err != nil {
+		log.Fatalf("Failed to initialize context toucher: %v", err)
+	}
+	eventHub := contextmgr.NewEventHub()
+	contextMgr := contextmgr.NewManager(contextStore,
+		contextmgr.WithPayloadValidator(schemaRegistry),
+		contextmgr.WithEmbeddings(embeddingStore, embeddingIndex),
+		contextmgr.WithExpiration(expirationStore, toucher),
+		contextmgr.WithLifecyclePolicies(policyStore),
+		contextmgr.WithLeases(leaseStore),
+		contextmgr.WithEventHub(eventHub),
+		contextmgr.WithRestoreWindow(restoreWindow),
+	)
+
+	// Initialize background workers
+	locker := storage.NewPostgresLocker(postgresPool.Pool())
+	reaper, err := registry.NewReaper(agentStore, locker, reaperConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize agent reaper: %v", err)
+	}
+	janitor, err := contextmgr.NewJanitor(contextStore, locker, janitorConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize context janitor: %v", err)
+	}
 
 	// Initialize auth
 	jwtManager := auth.NewJWTManager(jwtSecret)
 	rbac := auth.NewRBAC()
 
 	// Initialize HTTP server
-	httpSrv := server.NewHTTPServer(httpPort, registrySvc, contextMgr, jwtManager, rbac)
+	serverOpts = append(serverOpts,
+		server.WithSchemaRegistry(schemaRegistry),
+		server.WithEventHub(eventHub),
+		server.WithTransfer(transferSvc),
+	)
+	httpSrv := server.NewHTTPServer(httpPort, registrySvc, contextMgr, jwtManager, rbac, serverOpts...)
 
 	// Graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -66,6 +165,18 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Start agent liveness reaper, expired context janitor, the toucher that
+	// writes batched expiry extensions, and the listener that wakes event
+	// streams and waiting reads when any replica logs a context event
+	go reaper.Run(ctx)
+	go janitor.Run(ctx)
+	touchDone := make(chan struct{})
+	go func() {
+		defer close(touchDone)
+		toucher.Run(ctx)
+	}()
+	go eventHub.Run(ctx, storage.NewPostgresEventNotifier(postgresPool.Pool()))
+
 	// Start HTTP server in goroutine
 	errChan := make(chan error, 1)
 	go func() {
@@ -89,8 +200,13 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
-	// Shutdown servers gracefully
-	_ = shutdownCtx
+	// Shutdown servers gracefully, then wait for the toucher to write the
+	// expiry extensions still queued
+	cancel()
+	select {
+	case <-touchDone:
+	case <-shutdownCtx.Done():
+	}
 
 	log.Println("Server stopped")
 }
@@ -101,3 +217,40 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList splits a comma-separated variable, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid integer %q for %s, using %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid duration %q for %s, using %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return d
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestGetEnv(t *testing.T) {
@@ -17,3 +18,50 @@ func TestGetEnv(t *testing.T) {
 		t.Fatalf("expected value, got %s", v)
 	}
 }
+
+func TestGetEnvList(t *testing.T) {
+	const key = "TEST_GET_ENV_LIST_KEY"
+	os.Unsetenv(key)
+	if v := getEnvList(key); len(v) != 0 {
+		t.Fatalf("expected no values, got %v", v)
+	}
+	os.Setenv(key, " email, ,ssn,")
+	defer os.Unsetenv(key)
+	if v := getEnvList(key); len(v) != 2 || v[0] != "email" || v[1] != "ssn" {
+		t.Fatalf("expected [email ssn], got %v", v)
+	}
+}
+
+func TestGetEnvInt(t *testing.T) {
+	const key = "TEST_GET_ENV_INT_KEY"
+	os.Unsetenv(key)
+	if v := getEnvInt(key, 500); v != 500 {
+		t.Fatalf("expected default, got %d", v)
+	}
+	os.Setenv(key, "25")
+	defer os.Unsetenv(key)
+	if v := getEnvInt(key, 500); v != 25 {
+		t.Fatalf("expected 25, got %d", v)
+	}
+	os.Setenv(key, "many")
+	if v := getEnvInt(key, 500); v != 500 {
+		t.Fatalf("expected default for invalid value, got %d", v)
+	}
+}
+
+func TestGetEnvDuration(t *testing.T) {
+	const key = "TEST_GET_ENV_DURATION_KEY"
+	os.Unsetenv(key)
+	if v := getEnvDuration(key, time.Minute); v != time.Minute {
+		t.Fatalf("expected default, got %s", v)
+	}
+	os.Setenv(key, "45s")
+	defer os.Unsetenv(key)
+	if v := getEnvDuration(key, time.Minute); v != 45*time.Second {
+		t.Fatalf("expected 45s, got %s", v)
+	}
+	os.Setenv(key, "not-a-duration")
+	if v := getEnvDuration(key, time.Minute); v != time.Minute {
+		t.Fatalf("expected default for invalid value, got %s", v)
+	}
+}
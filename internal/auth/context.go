@@ -0,0 +1,27 @@
+package auth
+
+import "context"
+
+// claimsKey is the context key for authenticated claims
+type claimsKey struct{}
+
+// ContextWithClaims returns a copy of ctx carrying the authenticated caller's claims
+func ContextWithClaims(ctx context.Context, claims *JWTClaims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the authenticated caller's claims carried by ctx
+func ClaimsFromContext(ctx context.Context) (*JWTClaims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*JWTClaims)
+	return claims, ok && claims != nil
+}
+
+// HasRole checks if the claims include the given role
+func (c *JWTClaims) HasRole(role Role) bool {
+	for _, r := range c.Roles {
+		if Role(r) == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClaimsContext(t *testing.T) {
+	if _, ok := ClaimsFromContext(context.Background()); ok {
+		t.Fatal("expected no claims in empty context")
+	}
+
+	claims := &JWTClaims{AgentID: "a", TenantID: "t", Roles: []string{"observer"}}
+	ctx := ContextWithClaims(context.Background(), claims)
+	got, ok := ClaimsFromContext(ctx)
+	if !ok || got != claims {
+		t.Fatal("expected claims round-trip")
+	}
+
+	if _, ok := ClaimsFromContext(ContextWithClaims(context.Background(), nil)); ok {
+		t.Fatal("expected nil claims to be ignored")
+	}
+}
+
+func TestJWTClaims_HasRole(t *testing.T) {
+	claims := &JWTClaims{Roles: []string{"observer", "admin"}}
+	if !claims.HasRole(RoleAdmin) {
+		t.Fatal("expected admin role")
+	}
+	if claims.HasRole(RoleAgentFull) {
+		t.Fatal("unexpected agent-full role")
+	}
+}
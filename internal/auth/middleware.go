@@ -43,6 +43,7 @@ func AuthMiddleware(jwtManager *JWTManager) gin.HandlerFunc {
 		c.Set("tenant_id", claims.TenantID)
 		c.Set("roles", claims.Roles)
 		c.Set("claims", claims)
+		c.Request = c.Request.WithContext(ContextWithClaims(c.Request.Context(), claims))
 
 		c.Next()
 	}
@@ -126,3 +126,30 @@ func TestRBACMiddleware_Allowed(t *testing.T) {
 		t.Fatalf("expected 200, got %d", w.Code)
 	}
 }
+
+func TestAuthMiddleware_ClaimsInRequestContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwt := NewJWTManager("s")
+	token, err := jwt.GenerateAccessToken("a", "tenant-1", []string{"admin"})
+	if err != nil {
+		t.Fatalf("token error: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/p", AuthMiddleware(jwt), func(c *gin.Context) {
+		claims, ok := ClaimsFromContext(c.Request.Context())
+		if !ok || claims.AgentID != "a" || claims.TenantID != "tenant-1" || !claims.HasRole(RoleAdmin) {
+			c.String(500, "claims missing")
+			return
+		}
+		c.String(200, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/p", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
@@ -22,9 +22,63 @@ const (
 	// Agent heartbeat
 	DefaultHeartbeatInterval = 30 // seconds
 	AgentTimeoutThreshold    = 90 // seconds (mark offline after 90s without heartbeat)
+	AgentSweepInterval       = 30 // seconds between liveness sweeps
 
 	// Storage
-	DefaultContextTTL = 86400 // 24 hours in seconds
+	DefaultContextTTL           = 86400 // 24 hours in seconds
+	PayloadCompressionThreshold = 1024  // payloads from this many bytes are stored compressed
+
+	// Context expiry
+	ContextSweepInterval   = 60  // seconds between expiry sweeps
+	ContextSweepBatchSize  = 500 // contexts deleted per transaction
+	ContextSweepMaxBatches = 20  // batches per sweep; the rest waits for the next sweep
+
+	// Sliding expiration
+	ContextTouchInterval   = 10   // seconds between batched writes of extended expiries
+	ContextTouchBatchSize  = 1000 // expiries written per statement
+	ContextTouchResolution = 10   // reads extend a sliding expiry in steps of at least TTL divided by this
+
+	// Context leases
+	DefaultLeaseTTL = 30   // seconds a lease lasts when no TTL is given
+	MaxLeaseTTL     = 3600 // seconds a lease may last before it must be renewed
+
+	// Soft delete
+	DefaultRestoreWindow = 604800 // 7 days in seconds a deleted context or agent can be restored before it is purged
+
+	// Context change streams
+	ContextEventRetention     = 86400 // seconds events stay in the log for consumers to resume from
+	ContextStreamPollInterval = 5     // seconds between log polls when no notification arrives
+	ContextStreamHeartbeat    = 15    // seconds between keep-alive comments on idle streams
+
+	// Long-polling reads
+	DefaultContextWaitTimeout = 30 // seconds a read waiting for a new revision is held open by default
+	MaxContextWaitTimeout     = 60 // seconds such a read may be held open at most
+
+	// Read-through cache
+	AgentCacheTTL    = 30  // seconds an agent stays cached; bounds staleness after reaper sweeps
+	ContextCacheTTL  = 300 // seconds a context stays cached, capped at its expiry
+	CacheNegativeTTL = 5   // seconds a lookup of an unknown ID stays cached
+
+	// Full-text search
+	MaxSearchTextSize    = 256 * 1024 // bytes of payload text indexed per context
+	MaxSearchQueryLength = 256        // characters in a search query
+
+	// Similarity search
+	DefaultSimilarityTopK = 10  // results returned when top_k is not given
+	MaxSimilarityTopK     = 100 // results returned at most
+	SimilarityOverfetch   = 4   // index candidates fetched per result, to survive filtering
+
+	// Lineage walks
+	DefaultLineageDepth = 3   // levels walked when depth is not given
+	MaxLineageDepth     = 10  // levels walked at most
+	MaxLineageNodes     = 500 // contexts returned by one walk at most
+
+	// Batch requests
+	MaxBatchItems        = 100              // items in one batch request
+	MaxBatchPayloadBytes = 10 * 1024 * 1024 // payload bytes summed over a batch create
+
+	// Tenant transfer
+	MaxImportArchiveBytes = 512 * 1024 * 1024 // archive bytes accepted by the import endpoint
 
 	// Idempotency
 	IdempotencyKeyTTL = 86400 // 24 hours in seconds
@@ -15,6 +15,24 @@ func TestConstantsHaveExpectedRanges(t *testing.T) {
 	if DefaultContextTTL <= 0 || IdempotencyKeyTTL <= 0 {
 		t.Fatal("invalid storage TTLs")
 	}
+	if MaxBatchItems <= 0 || MaxBatchPayloadBytes < MaxDirectContextSize {
+		t.Fatal("invalid batch limits")
+	}
+	if MaxImportArchiveBytes < MaxBatchPayloadBytes {
+		t.Fatal("invalid import limit")
+	}
+	if ContextTouchInterval <= 0 || ContextTouchBatchSize <= 0 || ContextTouchResolution <= 0 {
+		t.Fatal("invalid touch settings")
+	}
+	if DefaultLeaseTTL <= 0 || MaxLeaseTTL < DefaultLeaseTTL {
+		t.Fatal("invalid lease TTLs")
+	}
+	if DefaultRestoreWindow <= 0 {
+		t.Fatal("invalid restore window")
+	}
+	if DefaultContextWaitTimeout <= 0 || MaxContextWaitTimeout < DefaultContextWaitTimeout {
+		t.Fatal("invalid context wait timeouts")
+	}
 	if DefaultPageLimit <= 0 || MaxPageLimit < DefaultPageLimit {
 		t.Fatal("invalid pagination limits")
 	}
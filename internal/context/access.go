@@ -0,0 +1,97 @@
+package context
+
+import (
+	"context"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+)
+
+// Access control semantics, evaluated against the caller's JWT claims:
+//
+//   - Contexts are never visible across tenants; they are reported as not found.
+//   - Admins may read, write and delete every context in their tenant.
+//   - The creating agent may always read, write and delete its own context.
+//   - public:  every agent in the tenant may read; only the owner may write.
+//   - private: only the owner may read or write.
+//   - group, shared: agents listed in AllowedIDs may read and write.
+//   - Only the owner may change access control or delete the context, unless
+//     the lifecycle policy of its type lets agents that may write it delete it.
+//   - While a context is leased, only the lease holder may write or delete it.
+//
+// A caller that may not read a context gets NOT_FOUND so that existence is not
+// leaked; a caller that may read but not modify it gets FORBIDDEN.
+
+// callerFromContext returns the authenticated caller's claims
+func callerFromContext(ctx context.Context) (*auth.JWTClaims, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, acberrors.Unauthorized("caller identity required")
+	}
+	return claims, nil
+}
+
+// isOwnerOrAdmin reports whether the caller owns c or administers its tenant
+func isOwnerOrAdmin(claims *auth.JWTClaims, c *models.Context) bool {
+	return claims.TenantID == c.TenantID && (claims.AgentID == c.AgentID || claims.HasRole(auth.RoleAdmin))
+}
+
+// isAllowed reports whether the caller is listed in a group/shared context
+func isAllowed(claims *auth.JWTClaims, c *models.Context) bool {
+	if c.AccessControl.Scope != models.ScopeGroup && c.AccessControl.Scope != models.ScopeShared {
+		return false
+	}
+	for _, id := range c.AccessControl.AllowedIDs {
+		if id == claims.AgentID {
+			return true
+		}
+	}
+	return false
+}
+
+// canRead reports whether the caller may read c
+func canRead(claims *auth.JWTClaims, c *models.Context) bool {
+	if claims.TenantID != c.TenantID {
+		return false
+	}
+	if isOwnerOrAdmin(claims, c) {
+		return true
+	}
+	return c.AccessControl.Scope == models.ScopePublic || isAllowed(claims, c)
+}
+
+// canWrite reports whether the caller may modify the payload and metadata of c
+func canWrite(claims *auth.JWTClaims, c *models.Context) bool {
+	return isOwnerOrAdmin(claims, c) || (claims.TenantID == c.TenantID && isAllowed(claims, c))
+}
+
+// authorizeRead returns the error to report when the caller may not read c
+func authorizeRead(claims *auth.JWTClaims, c *models.Context) error {
+	if !canRead(claims, c) {
+		return acberrors.NotFound("context not found")
+	}
+	return nil
+}
+
+// authorizeWrite returns the error to report when the caller may not modify c
+func authorizeWrite(claims *auth.JWTClaims, c *models.Context) error {
+	if err := authorizeRead(claims, c); err != nil {
+		return err
+	}
+	if !canWrite(claims, c) {
+		return acberrors.Forbidden("not allowed to modify this context")
+	}
+	return nil
+}
+
+// authorizeOwner returns the error to report when the caller is neither the owner nor an admin
+func authorizeOwner(claims *auth.JWTClaims, c *models.Context) error {
+	if err := authorizeRead(claims, c); err != nil {
+		return err
+	}
+	if !isOwnerOrAdmin(claims, c) {
+		return acberrors.Forbidden("only the owner may perform this operation")
+	}
+	return nil
+}
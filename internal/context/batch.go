@@ -0,0 +1,250 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/acb/internal/constants"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+)
+
+// BatchResult is the outcome of one item of a batch request: the context, or
+// the error that item failed with. Deleted items carry neither.
+type BatchResult struct {
+	Context *models.Context
+	Err     error
+}
+
+// BatchCreate creates contexts in one transaction, validating each as Create
+// does. The payloads may total at most MaxBatchPayloadBytes. In atomic mode
+// the first failing item fails the batch with a *storage.BatchError and
+// nothing is created; in partial mode each item succeeds or fails alone.
+// Parents must exist before the batch; an item cannot derive from another.
+func (m *Manager) BatchCreate(ctx context.Context, mode models.BatchMode, reqs []*CreateRequest) ([]*BatchResult, error) {
+	if err := validateBatch(mode, len(reqs)); err != nil {
+		return nil, err
+	}
+	size := 0
+	for _, req := range reqs {
+		size += len(req.Payload)
+	}
+	if size > constants.MaxBatchPayloadBytes {
+		return nil, acberrors.ValidationError(fmt.Sprintf("batch payloads total %d bytes, exceeding %d", size, constants.MaxBatchPayloadBytes))
+	}
+
+	results := make([]*BatchResult, len(reqs))
+	var pending []*models.Context
+	var indexes []int
+	seen := make(map[string]bool, len(reqs))
+	configs := make(map[string]*typeConfig)
+	for i, req := range reqs {
+		cfg, ok := configs[req.Type]
+		if !ok {
+			var err error
+			if cfg, err = m.loadTypeConfig(ctx, req.TenantID, req.Type); err != nil {
+				return nil, err
+			}
+			configs[req.Type] = cfg
+		}
+		c := newContext(req, cfg)
+		err := m.validateNew(ctx, c, cfg.policy)
+		if err == nil && seen[c.ID] {
+			err = duplicateItemError(c.ID)
+		}
+		seen[c.ID] = true
+		if err != nil {
+			if mode == models.BatchAtomic {
+				return nil, &storage.BatchError{Index: i, Err: err}
+			}
+			results[i] = &BatchResult{Err: err}
+			continue
+		}
+		results[i] = &BatchResult{Context: c}
+		pending = append(pending, c)
+		indexes = append(indexes, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	errs, err := m.store.CreateBatch(ctx, pending, mode == models.BatchAtomic)
+	var batchErr *storage.BatchError
+	if errors.As(err, &batchErr) {
+		return nil, &storage.BatchError{Index: indexes[batchErr.Index], Err: createError(pending[batchErr.Index].ID, batchErr.Err)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contexts: %w", err)
+	}
+	for j, itemErr := range errs {
+		if itemErr != nil {
+			results[indexes[j]] = &BatchResult{Err: createError(pending[j].ID, itemErr)}
+			continue
+		}
+		m.notify(pending[j].TenantID, pending[j].ID)
+	}
+	return results, nil
+}
+
+// BatchGet retrieves contexts by ID. Each context that does not exist, has
+// expired or that the caller may not read is reported as not found.
+func (m *Manager) BatchGet(ctx context.Context, contextIDs []string) ([]*BatchResult, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBatchSize(len(contextIDs)); err != nil {
+		return nil, err
+	}
+
+	found, err := m.lookup(ctx, claims.TenantID, contextIDs)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*BatchResult, len(contextIDs))
+	var read []*models.Context
+	for i, id := range contextIDs {
+		c := found[id]
+		if c == nil {
+			results[i] = &BatchResult{Err: acberrors.NotFound("context not found")}
+			continue
+		}
+		if err := authorizeRead(claims, c); err != nil {
+			results[i] = &BatchResult{Err: err}
+			continue
+		}
+		results[i] = &BatchResult{Context: c}
+		read = append(read, c)
+	}
+	m.touchRead(ctx, read...)
+	return results, nil
+}
+
+// BatchDelete removes contexts the caller may delete, as Delete decides, in
+// one transaction. Contexts leased to other agents fail with a conflict.
+// Failures are reported as by BatchCreate.
+func (m *Manager) BatchDelete(ctx context.Context, mode models.BatchMode, contextIDs []string) ([]*BatchResult, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBatch(mode, len(contextIDs)); err != nil {
+		return nil, err
+	}
+
+	found, err := m.lookup(ctx, claims.TenantID, contextIDs)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*BatchResult, len(contextIDs))
+	var pending []string
+	var indexes []int
+	seen := make(map[string]bool, len(contextIDs))
+	policies := make(map[string]*models.LifecyclePolicy)
+	for i, id := range contextIDs {
+		var err error
+		switch c := found[id]; {
+		case c == nil:
+			err = acberrors.NotFound("context not found")
+		case seen[id]:
+			err = duplicateItemError(id)
+		default:
+			policy, ok := policies[c.Type]
+			if !ok {
+				if policy, err = m.lifecyclePolicy(ctx, c.TenantID, c.Type); err != nil {
+					return nil, err
+				}
+				policies[c.Type] = policy
+			}
+			if err = authorizeDelete(claims, c, policy); err == nil {
+				err = m.checkLease(ctx, claims, c)
+			}
+		}
+		seen[id] = true
+		if err != nil {
+			if mode == models.BatchAtomic {
+				return nil, &storage.BatchError{Index: i, Err: err}
+			}
+			results[i] = &BatchResult{Err: err}
+			continue
+		}
+		results[i] = &BatchResult{}
+		pending = append(pending, id)
+		indexes = append(indexes, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	// Leases that changed since they were checked fail their items
+	itemError := func(id string, err error) error {
+		if storage.IsLeased(err) {
+			return m.leaseWriteError(ctx, claims, found[id])
+		}
+		return deleteError(err)
+	}
+	errs, err := m.store.DeleteBatch(m.guardWrite(ctx, claims), pending, mode == models.BatchAtomic)
+	var batchErr *storage.BatchError
+	if errors.As(err, &batchErr) {
+		return nil, &storage.BatchError{Index: indexes[batchErr.Index], Err: itemError(pending[batchErr.Index], batchErr.Err)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete contexts: %w", err)
+	}
+	for j, itemErr := range errs {
+		if itemErr != nil {
+			results[indexes[j]] = &BatchResult{Err: itemError(pending[j], itemErr)}
+			continue
+		}
+		m.notify(found[pending[j]].TenantID, pending[j])
+	}
+	return results, nil
+}
+
+// lookup fetches the unexpired contexts of a tenant with the given IDs, keyed
+// by ID
+func (m *Manager) lookup(ctx context.Context, tenantID string, contextIDs []string) (map[string]*models.Context, error) {
+	contexts, err := m.store.List(ctx, &storage.ContextFilters{TenantID: tenantID, IDs: contextIDs, Limit: len(contextIDs)})
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]*models.Context, len(contexts))
+	for _, c := range contexts {
+		if !m.withPending(c).IsExpired() {
+			found[c.ID] = c
+		}
+	}
+	return found, nil
+}
+
+// validateBatch checks the mode and size of a batch write
+func validateBatch(mode models.BatchMode, n int) error {
+	if err := mode.Validate(); err != nil {
+		return acberrors.ValidationError(err.Error())
+	}
+	return validateBatchSize(n)
+}
+
+// validateBatchSize checks that a batch has between 1 and MaxBatchItems items
+func validateBatchSize(n int) error {
+	if n == 0 || n > constants.MaxBatchItems {
+		return acberrors.ValidationError(fmt.Sprintf("batch must have between 1 and %d items", constants.MaxBatchItems))
+	}
+	return nil
+}
+
+// duplicateItemError reports an ID given twice in one batch
+func duplicateItemError(contextID string) error {
+	return acberrors.ValidationError(fmt.Sprintf("context %s appears more than once in the batch", contextID))
+}
+
+// deleteError translates a failed store delete of a context removed
+// concurrently
+func deleteError(err error) error {
+	if storage.IsNotFound(err) {
+		return acberrors.NotFound("context not found").WithError(err)
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/acb/internal/constants"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noteRequest returns a create request for a public note owned by agent-1
+func noteRequest(id string) *CreateRequest {
+	return &CreateRequest{
+		ID:            id,
+		Type:          "note",
+		AgentID:       "agent-1",
+		TenantID:      "default",
+		Payload:       []byte(id),
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+	}
+}
+
+func TestManager_BatchCreate(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store)
+	ctx := context.Background()
+
+	results, err := mgr.BatchCreate(ctx, models.BatchAtomic, []*CreateRequest{noteRequest("a"), noteRequest("b")})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "b", results[1].Context.ID)
+	assert.Len(t, store.contexts, 2)
+
+	// Validation and write failures fail an atomic batch at their index
+	invalid := noteRequest("c")
+	invalid.Type = ""
+	for name, tc := range map[string]struct {
+		reqs  []*CreateRequest
+		index int
+		code  acberrors.ErrorCode
+	}{
+		"existing ID":  {[]*CreateRequest{noteRequest("d"), noteRequest("a")}, 1, acberrors.ErrorCodeConflict},
+		"repeated ID":  {[]*CreateRequest{noteRequest("d"), noteRequest("d")}, 1, acberrors.ErrorCodeValidationError},
+		"invalid item": {[]*CreateRequest{invalid, noteRequest("d")}, 0, ""},
+	} {
+		_, err := mgr.BatchCreate(ctx, models.BatchAtomic, tc.reqs)
+		var batchErr *storage.BatchError
+		require.True(t, errors.As(err, &batchErr), name)
+		assert.Equal(t, tc.index, batchErr.Index, name)
+		if tc.code != "" {
+			assert.True(t, acberrors.Is(err, tc.code), name)
+		}
+		assert.Len(t, store.contexts, 2, name)
+	}
+
+	// Partial batches create the items that succeed
+	results, err = mgr.BatchCreate(ctx, models.BatchPartial, []*CreateRequest{noteRequest("a"), invalid, noteRequest("d")})
+	require.NoError(t, err)
+	assert.True(t, acberrors.Is(results[0].Err, acberrors.ErrorCodeConflict))
+	assert.Error(t, results[1].Err)
+	assert.Equal(t, "d", results[2].Context.ID)
+	assert.Contains(t, store.contexts, "d")
+
+	// Batch size and total payload are capped
+	_, err = mgr.BatchCreate(ctx, models.BatchAtomic, nil)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	big := noteRequest("big")
+	big.Payload = make([]byte, constants.MaxBatchPayloadBytes/2+1)
+	_, err = mgr.BatchCreate(ctx, models.BatchPartial, []*CreateRequest{big, big})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	_, err = mgr.BatchCreate(ctx, "eventual", []*CreateRequest{noteRequest("e")})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+}
+
+func TestManager_BatchGetAndDelete(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store)
+	createDerived(t, mgr, "shared", "agent-1", models.ScopePublic)
+	createDerived(t, mgr, "secret", "agent-1", models.ScopePrivate)
+	createDerived(t, mgr, "other", "agent-2", models.ScopePublic)
+
+	// Unreadable and missing contexts are reported as not found
+	results, err := mgr.BatchGet(callerCtx("agent-2"), []string{"shared", "secret", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, "shared", results[0].Context.ID)
+	assert.True(t, acberrors.Is(results[1].Err, acberrors.ErrorCodeNotFound))
+	assert.True(t, acberrors.Is(results[2].Err, acberrors.ErrorCodeNotFound))
+
+	// An atomic delete stops at the first context the caller does not own
+	_, err = mgr.BatchDelete(callerCtx("agent-1"), models.BatchAtomic, []string{"shared", "other"})
+	var batchErr *storage.BatchError
+	require.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, 1, batchErr.Index)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+	assert.Len(t, store.contexts, 3)
+
+	results, err = mgr.BatchDelete(callerCtx("agent-1"), models.BatchPartial, []string{"shared", "other", "secret", "shared"})
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.True(t, acberrors.Is(results[1].Err, acberrors.ErrorCodeForbidden))
+	assert.NoError(t, results[2].Err)
+	assert.True(t, acberrors.Is(results[3].Err, acberrors.ErrorCodeValidationError))
+	assert.Equal(t, []string{"other"}, keys(store.contexts))
+	assert.Equal(t, models.ContextEventDeleted, store.events[len(store.events)-1].Type)
+
+	// Admins may delete any context in their tenant
+	_, err = mgr.BatchDelete(callerCtx("admin", "admin"), models.BatchAtomic, []string{"other"})
+	require.NoError(t, err)
+	assert.Empty(t, store.contexts)
+}
+
+func keys(m map[string]*models.Context) []string {
+	var ids []string
+	for id := range m {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,95 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+)
+
+// WithRestoreWindow sets how long deleted contexts can be restored. It should
+// match the delay after which the janitor purges them.
+func WithRestoreWindow(window time.Duration) Option {
+	return func(m *Manager) {
+		m.restoreWindow = window
+	}
+}
+
+// RestoreWindow returns how long deleted contexts can be restored
+func (m *Manager) RestoreWindow() time.Duration {
+	return m.restoreWindow
+}
+
+// RestoreDeleted brings back a deleted context within the restore window.
+// Whoever may delete the context may restore it. A context that expired while
+// it was deleted stays deleted until it is purged.
+func (m *Manager) RestoreDeleted(ctx context.Context, contextID string) (*models.Context, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := m.store.GetDeleted(ctx, contextID)
+	if storage.IsNotFound(err) {
+		return nil, acberrors.NotFound("deleted context not found").WithError(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy, err := m.lifecyclePolicy(ctx, c.TenantID, c.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeDelete(claims, c, policy); err != nil {
+		return nil, err
+	}
+	if !m.restorable(c.DeletedAt) {
+		return nil, acberrors.NotFound(fmt.Sprintf("context %s was deleted more than %s ago and can no longer be restored", contextID, m.restoreWindow))
+	}
+	if c.IsExpired() {
+		return nil, acberrors.Conflict(fmt.Sprintf("context %s expired while it was deleted", contextID))
+	}
+
+	if err := m.store.Restore(ctx, contextID); storage.IsNotFound(err) {
+		return nil, acberrors.NotFound("deleted context not found").WithError(err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to restore context: %w", err)
+	}
+	m.notify(c.TenantID, contextID)
+	return m.load(ctx, contextID)
+}
+
+// ListDeleted returns up to limit contexts of the caller's tenant that were
+// deleted within the restore window, most recently deleted first. Only
+// admins may list them.
+func (m *Manager) ListDeleted(ctx context.Context, limit int) ([]*models.Context, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.HasRole(auth.RoleAdmin) {
+		return nil, acberrors.Forbidden("only admins may list deleted contexts")
+	}
+
+	deleted, err := m.store.ListDeleted(ctx, claims.TenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted contexts: %w", err)
+	}
+	restorable := make([]*models.Context, 0, len(deleted))
+	for _, c := range deleted {
+		if m.restorable(c.DeletedAt) {
+			restorable = append(restorable, c)
+		}
+	}
+	return restorable, nil
+}
+
+// restorable reports whether something deleted at deletedAt is still within
+// the restore window
+func (m *Manager) restorable(deletedAt *time.Time) bool {
+	return deletedAt != nil && time.Since(*deletedAt) < m.restoreWindow
+}
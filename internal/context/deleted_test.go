@@ -0,0 +1,95 @@
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_RestoreDeleted(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store)
+	ctx := callerCtx("agent-1")
+	c := createSession(t, mgr, &CreateRequest{})
+	_, err := mgr.Update(ctx, c.ID, &UpdateRequest{Payload: []byte("v2")})
+	require.NoError(t, err)
+	require.NoError(t, mgr.Delete(ctx, c.ID))
+
+	_, err = mgr.Get(ctx, c.ID)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	// Deleted contexts stay hidden from callers that could not read them
+	_, err = mgr.RestoreDeleted(callerCtx("agent-2"), c.ID)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+
+	restored, err := mgr.RestoreDeleted(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, restored.Revision)
+	assert.Equal(t, []byte("v2"), restored.Payload)
+	assert.Nil(t, restored.DeletedAt)
+	assert.Equal(t, models.ContextEventRestored, store.events[len(store.events)-1].Type)
+
+	_, err = mgr.RestoreDeleted(ctx, c.ID)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+}
+
+func TestManager_RestoreDeletedRequiresDeleteAccess(t *testing.T) {
+	mgr := NewManager(NewMockContextStore())
+	c := createSession(t, mgr, &CreateRequest{})
+	_, err := mgr.Update(callerCtx("agent-1"), c.ID, &UpdateRequest{AccessControl: models.AccessControl{Scope: models.ScopePublic}})
+	require.NoError(t, err)
+	require.NoError(t, mgr.Delete(callerCtx("agent-1"), c.ID))
+
+	_, err = mgr.RestoreDeleted(callerCtx("agent-2"), c.ID)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+	_, err = mgr.RestoreDeleted(callerCtx("admin", string(auth.RoleAdmin)), c.ID)
+	assert.NoError(t, err)
+}
+
+func TestManager_RestoreDeletedWindow(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store, WithRestoreWindow(time.Hour))
+	ctx := callerCtx("agent-1")
+	assert.Equal(t, time.Hour, mgr.RestoreWindow())
+
+	late := createSession(t, mgr, &CreateRequest{})
+	require.NoError(t, mgr.Delete(ctx, late.ID))
+	*store.deleted[late.ID].DeletedAt = time.Now().Add(-2 * time.Hour)
+	_, err := mgr.RestoreDeleted(ctx, late.ID)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+
+	expired := createSession(t, mgr, &CreateRequest{})
+	require.NoError(t, mgr.Delete(ctx, expired.ID))
+	store.deleted[expired.ID].ExpiresAt = time.Now().Add(-time.Minute)
+	_, err = mgr.RestoreDeleted(ctx, expired.ID)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeConflict))
+}
+
+func TestManager_ListDeleted(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store, WithRestoreWindow(time.Hour))
+	ctx := callerCtx("agent-1")
+	admin := callerCtx("admin", string(auth.RoleAdmin))
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		c := createSession(t, mgr, &CreateRequest{})
+		require.NoError(t, mgr.Delete(ctx, c.ID))
+		ids = append(ids, c.ID)
+		*store.deleted[c.ID].DeletedAt = time.Now().Add(-time.Duration(i) * 45 * time.Minute)
+	}
+
+	_, err := mgr.ListDeleted(ctx, 10)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+
+	// Contexts past the restore window wait for the purge unlisted
+	deleted, err := mgr.ListDeleted(admin, 10)
+	require.NoError(t, err)
+	require.Len(t, deleted, 2)
+	assert.Equal(t, ids[0], deleted[0].ID)
+	assert.Equal(t, ids[1], deleted[1].ID)
+}
@@ -0,0 +1,174 @@
+package context
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/acb/internal/models"
+)
+
+// ChangeOp describes how a value differs between two revisions
+type ChangeOp string
+
+const (
+	ChangeAdd     ChangeOp = "add"
+	ChangeRemove  ChangeOp = "remove"
+	ChangeReplace ChangeOp = "replace"
+)
+
+// PayloadFormat reports how payloads were compared
+type PayloadFormat string
+
+const (
+	PayloadFormatJSON   PayloadFormat = "json"   // Both payloads are JSON; changes point into the document
+	PayloadFormatBinary PayloadFormat = "binary" // Payloads are compared as opaque bytes
+)
+
+// Change is a single difference, addressed by a JSON Pointer (RFC 6901)
+// rooted at the context: /payload/..., /metadata/<key>, /version or
+// /access_control/...
+type Change struct {
+	Op   ChangeOp    `json:"op"`
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// Diff is the structured difference between two revisions of a context
+type Diff struct {
+	ContextID     string        `json:"context_id"`
+	FromRevision  int           `json:"from_revision"`
+	ToRevision    int           `json:"to_revision"`
+	PayloadFormat PayloadFormat `json:"payload_format"`
+	Changes       []Change      `json:"changes"`
+}
+
+// diffRevisions compares a and b field by field. Payloads that are both valid
+// JSON are compared structurally; anything else is a single replacement.
+func diffRevisions(a, b *models.ContextRevision) *Diff {
+	d := &Diff{
+		ContextID:     b.ContextID,
+		FromRevision:  a.Revision,
+		ToRevision:    b.Revision,
+		PayloadFormat: PayloadFormatBinary,
+		Changes:       []Change{},
+	}
+
+	fromDoc, fromOK := decodeJSON(a.Payload)
+	toDoc, toOK := decodeJSON(b.Payload)
+	if fromOK && toOK {
+		d.PayloadFormat = PayloadFormatJSON
+		diffValues("/payload", fromDoc, toDoc, &d.Changes)
+	} else if !bytes.Equal(a.Payload, b.Payload) {
+		d.Changes = append(d.Changes, Change{Op: ChangeReplace, Path: "/payload", From: a.Payload, To: b.Payload})
+	}
+
+	diffValues("/metadata", stringMap(a.Metadata), stringMap(b.Metadata), &d.Changes)
+	diffValues("/version", a.Version, b.Version, &d.Changes)
+	diffValues("/access_control", accessControlDoc(a.AccessControl), accessControlDoc(b.AccessControl), &d.Changes)
+	return d
+}
+
+// diffValues appends the changes that turn a into b, recursing into objects
+// and arrays so that only the innermost differing values are reported
+func diffValues(path string, a, b interface{}, changes *[]Change) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, seen := av[k]; !seen {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "/" + escapePointer(k)
+			from, inA := av[k]
+			to, inB := bv[k]
+			switch {
+			case !inA:
+				*changes = append(*changes, Change{Op: ChangeAdd, Path: child, To: to})
+			case !inB:
+				*changes = append(*changes, Change{Op: ChangeRemove, Path: child, From: from})
+			default:
+				diffValues(child, from, to, changes)
+			}
+		}
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		common := len(av)
+		if len(bv) < common {
+			common = len(bv)
+		}
+		for i := 0; i < common; i++ {
+			diffValues(path+"/"+strconv.Itoa(i), av[i], bv[i], changes)
+		}
+		for i := common; i < len(bv); i++ {
+			*changes = append(*changes, Change{Op: ChangeAdd, Path: path + "/" + strconv.Itoa(i), To: bv[i]})
+		}
+		// Remove from the end so indices stay valid when applied in order
+		for i := len(av) - 1; i >= common; i-- {
+			*changes = append(*changes, Change{Op: ChangeRemove, Path: path + "/" + strconv.Itoa(i), From: av[i]})
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Op: ChangeReplace, Path: path, From: a, To: b})
+	}
+}
+
+// decodeJSON decodes a JSON payload, preserving number formatting
+func decodeJSON(payload []byte) (interface{}, bool) {
+	if len(payload) == 0 || !json.Valid(payload) {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// stringMap converts metadata into the generic form compared by diffValues
+func stringMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// accessControlDoc converts access control into the generic form compared by diffValues
+func accessControlDoc(ac models.AccessControl) map[string]interface{} {
+	ids := make([]interface{}, len(ac.AllowedIDs))
+	for i, id := range ac.AllowedIDs {
+		ids[i] = id
+	}
+	return map[string]interface{}{
+		"scope":       string(ac.Scope),
+		"allowed_ids": ids,
+	}
+}
+
+// escapePointer escapes a key for use as a JSON Pointer reference token
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,75 @@
+package context
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/acb/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func revisionWith(n int, payload string) *models.ContextRevision {
+	return &models.ContextRevision{
+		ContextID:     "ctx-1",
+		Revision:      n,
+		Payload:       []byte(payload),
+		Metadata:      map[string]string{"env": "prod"},
+		Version:       "1",
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+	}
+}
+
+func TestDiffRevisions_JSONPayload(t *testing.T) {
+	a := revisionWith(1, `{"user":{"name":"ada","tags":["x","y","z"]},"a/b":1,"gone":true}`)
+	b := revisionWith(2, `{"user":{"name":"grace","tags":["x"]},"a/b":2,"new":null}`)
+
+	d := diffRevisions(a, b)
+	assert.Equal(t, PayloadFormatJSON, d.PayloadFormat)
+	assert.Equal(t, 1, d.FromRevision)
+	assert.Equal(t, 2, d.ToRevision)
+	assert.Equal(t, []Change{
+		{Op: ChangeReplace, Path: "/payload/a~1b", From: json.Number("1"), To: json.Number("2")},
+		{Op: ChangeRemove, Path: "/payload/gone", From: true},
+		{Op: ChangeAdd, Path: "/payload/new"},
+		{Op: ChangeReplace, Path: "/payload/user/name", From: "ada", To: "grace"},
+		{Op: ChangeRemove, Path: "/payload/user/tags/2", From: "z"},
+		{Op: ChangeRemove, Path: "/payload/user/tags/1", From: "y"},
+	}, d.Changes)
+}
+
+func TestDiffRevisions_BinaryPayload(t *testing.T) {
+	a := revisionWith(1, "hello")
+	b := revisionWith(2, `{"k":1}`)
+
+	d := diffRevisions(a, b)
+	assert.Equal(t, PayloadFormatBinary, d.PayloadFormat)
+	assert.Equal(t, []Change{
+		{Op: ChangeReplace, Path: "/payload", From: []byte("hello"), To: []byte(`{"k":1}`)},
+	}, d.Changes)
+
+	assert.Empty(t, diffRevisions(a, revisionWith(2, "hello")).Changes)
+}
+
+func TestDiffRevisions_MetadataAndAccessControl(t *testing.T) {
+	a := revisionWith(1, "p")
+	b := revisionWith(2, "p")
+	b.Metadata = map[string]string{"env": "dev", "team": "core"}
+	b.Version = "2"
+	b.AccessControl = models.AccessControl{Scope: models.ScopeGroup, AllowedIDs: []string{"agent-2"}}
+
+	d := diffRevisions(a, b)
+	assert.Equal(t, []Change{
+		{Op: ChangeReplace, Path: "/metadata/env", From: "prod", To: "dev"},
+		{Op: ChangeAdd, Path: "/metadata/team", To: "core"},
+		{Op: ChangeReplace, Path: "/version", From: "1", To: "2"},
+		{Op: ChangeAdd, Path: "/access_control/allowed_ids/0", To: "agent-2"},
+		{Op: ChangeReplace, Path: "/access_control/scope", From: "public", To: "group"},
+	}, d.Changes)
+}
+
+func TestDiffRevisions_TypeChange(t *testing.T) {
+	d := diffRevisions(revisionWith(1, `{"k":[1]}`), revisionWith(2, `{"k":{"v":1}}`))
+	assert.Equal(t, []Change{
+		{Op: ChangeReplace, Path: "/payload/k", From: []interface{}{json.Number("1")}, To: map[string]interface{}{"v": json.Number("1")}},
+	}, d.Changes)
+}
@@ -0,0 +1,404 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/acb/internal/auth"
+	"github.com/acb/internal/constants"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/selector"
+	"github.com/acb/internal/storage"
+	"github.com/acb/internal/vector"
+)
+
+// eventCatchUpBatch is how many log events an index applies per query when
+// catching up
+const eventCatchUpBatch = 1000
+
+// SimilarityRequest is a nearest neighbour query over the embeddings of one
+// context type
+type SimilarityRequest struct {
+	Type     string                  // Context type to search
+	Vector   []float32               // Query embedding, of the type's dimension
+	TopK     int                     // Results to return; DefaultSimilarityTopK when zero
+	Metric   models.SimilarityMetric // Defaults to the metric the type is indexed for
+	Exact    bool                    // Score every candidate instead of using the index
+	AgentID  string                  // Only contexts created by this agent
+	Selector selector.Selector       // Only contexts whose metadata matches
+}
+
+// WithEmbeddings enables embeddings on contexts, validated against the
+// dimension declared for their type in store. Similarity searches use index
+// when it is non-nil and score every candidate otherwise.
+func WithEmbeddings(store storage.EmbeddingStore, index *EmbeddingIndex) Option {
+	return func(m *Manager) {
+		m.embeddings = store
+		m.index = index
+	}
+}
+
+// EmbeddingConfig returns the embedding configuration of a context type in
+// the caller's tenant
+func (m *Manager) EmbeddingConfig(ctx context.Context, contextType string) (*models.EmbeddingConfig, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.embeddings == nil {
+		return nil, acberrors.NotFound("embeddings are not enabled")
+	}
+	cfg, err := m.embeddings.GetConfig(ctx, claims.TenantID, contextType)
+	if storage.IsNotFound(err) {
+		return nil, acberrors.NotFound(fmt.Sprintf("context type %s declares no embeddings", contextType)).WithError(err)
+	}
+	return cfg, err
+}
+
+// SetEmbeddingConfig declares the embedding dimension and metric of a
+// context type in the caller's tenant. Only tenant admins may change it, and
+// the dimension cannot change while contexts of the type carry embeddings.
+func (m *Manager) SetEmbeddingConfig(ctx context.Context, cfg *models.EmbeddingConfig) error {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !claims.HasRole(auth.RoleAdmin) {
+		return acberrors.Forbidden("only admins may configure embeddings")
+	}
+	if m.embeddings == nil {
+		return acberrors.ServiceUnavailable("embeddings are not enabled")
+	}
+	if cfg.Metric == "" {
+		cfg.Metric = models.MetricCosine
+	}
+	if err := cfg.Validate(); err != nil {
+		return acberrors.ValidationError(err.Error()).WithError(err)
+	}
+
+	cfg.TenantID = claims.TenantID
+	err = m.embeddings.SetConfig(ctx, cfg)
+	if storage.IsConflict(err) {
+		return acberrors.Conflict("contexts of this type have embeddings of another dimension").WithError(err)
+	}
+	return err
+}
+
+// SearchSimilar returns the contexts of a type whose embeddings are most
+// similar to req.Vector, most similar first, among those the caller may read.
+// Filters are applied to the index candidates; when they leave fewer than
+// TopK results, or the metric differs from the one the type is indexed for,
+// every matching context is scored instead.
+func (m *Manager) SearchSimilar(ctx context.Context, req *SimilarityRequest) ([]*models.ContextMatch, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.embeddings == nil {
+		return nil, acberrors.ServiceUnavailable("embeddings are not enabled")
+	}
+	if req.Type == "" {
+		return nil, acberrors.ValidationError("type is required")
+	}
+	if err := models.ValidateEmbedding(req.Vector); err != nil {
+		return nil, acberrors.ValidationError(err.Error()).WithError(err)
+	}
+
+	cfg, err := m.embeddings.GetConfig(ctx, claims.TenantID, req.Type)
+	if storage.IsNotFound(err) {
+		return nil, acberrors.ValidationError(fmt.Sprintf("context type %s declares no embeddings", req.Type)).WithError(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Vector) != cfg.Dimension {
+		return nil, acberrors.ValidationError(fmt.Sprintf("vector has dimension %d, type %s expects %d", len(req.Vector), req.Type, cfg.Dimension))
+	}
+
+	metric := req.Metric
+	if metric == "" {
+		metric = cfg.Metric
+	}
+	if err := metric.Validate(); err != nil {
+		return nil, acberrors.ValidationError(err.Error()).WithError(err)
+	}
+	k := req.TopK
+	if k == 0 {
+		k = constants.DefaultSimilarityTopK
+	}
+	if k < 0 || k > constants.MaxSimilarityTopK {
+		return nil, acberrors.ValidationError(fmt.Sprintf("top_k must be between 1 and %d", constants.MaxSimilarityTopK))
+	}
+
+	filters := storage.ContextFilters{
+		TenantID: claims.TenantID,
+		Type:     req.Type,
+		AgentID:  req.AgentID,
+		Selector: req.Selector,
+	}
+	if !claims.HasRole(auth.RoleAdmin) {
+		filters.VisibleTo = claims.AgentID
+	}
+
+	if !req.Exact && m.index != nil && metric == cfg.Metric {
+		want := k * constants.SimilarityOverfetch
+		hits, err := m.index.Search(ctx, cfg, req.Vector, want)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := m.resolveMatches(ctx, filters, hits, k)
+		if err != nil || len(matches) == k || len(hits) < want {
+			return matches, err
+		}
+		// Filters rejected too many candidates; score every match instead
+	}
+
+	embeddings, err := m.embeddings.List(ctx, &filters)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]vector.Item, 0, len(embeddings))
+	for _, e := range embeddings {
+		if len(e.Vector) == cfg.Dimension {
+			items = append(items, vector.Item{ID: e.ContextID, Vector: e.Vector})
+		}
+	}
+	return m.resolveMatches(ctx, filters, vector.Exact(req.Vector, items, k, similarity(metric)), k)
+}
+
+// resolveMatches loads the contexts of hits that pass filters, keeping the
+// first k in hit order
+func (m *Manager) resolveMatches(ctx context.Context, filters storage.ContextFilters, hits []vector.Hit, k int) ([]*models.ContextMatch, error) {
+	matches := []*models.ContextMatch{}
+	if len(hits) == 0 {
+		return matches, nil
+	}
+	filters.IDs = make([]string, len(hits))
+	for i, hit := range hits {
+		filters.IDs[i] = hit.ID
+	}
+	filters.Limit = len(hits)
+
+	contexts, err := m.store.List(ctx, &filters)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Context, len(contexts))
+	for _, c := range contexts {
+		byID[c.ID] = c
+	}
+	for _, hit := range hits {
+		if c, ok := byID[hit.ID]; ok {
+			matches = append(matches, &models.ContextMatch{Context: c, Score: float64(hit.Score)})
+			if len(matches) == k {
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+// validateEmbedding checks c.Embedding against the dimension declared for
+// its type
+func (m *Manager) validateEmbedding(ctx context.Context, c *models.Context) error {
+	if c.Embedding == nil {
+		return nil
+	}
+	if m.embeddings == nil {
+		return acberrors.ValidationError("embeddings are not enabled")
+	}
+	cfg, err := m.embeddings.GetConfig(ctx, c.TenantID, c.Type)
+	if storage.IsNotFound(err) {
+		return acberrors.ValidationError(fmt.Sprintf("context type %s declares no embeddings", c.Type)).WithError(err)
+	}
+	if err != nil {
+		return err
+	}
+	if len(c.Embedding) != cfg.Dimension {
+		return acberrors.ValidationError(fmt.Sprintf("embedding has dimension %d, type %s expects %d", len(c.Embedding), c.Type, cfg.Dimension))
+	}
+	return nil
+}
+
+// similarity returns the scoring function of a metric
+func similarity(metric models.SimilarityMetric) vector.Similarity {
+	if metric == models.MetricDot {
+		return vector.Dot
+	}
+	return vector.Cosine
+}
+
+// EmbeddingIndex keeps an HNSW graph of the embeddings of each context type
+// in memory. A graph is built on the first search of its type and caught up
+// with the context event log before every search, so contexts written
+// through other servers are found without a shared index. Graphs may hold
+// contexts the caller cannot read or that have just been deleted; callers
+// check candidates against the store.
+type EmbeddingIndex struct {
+	store      storage.ContextStore
+	embeddings storage.EmbeddingStore
+	cfg        vector.HNSWConfig
+
+	mu     sync.Mutex
+	graphs map[graphKey]*typeGraph
+}
+
+type graphKey struct {
+	tenantID    string
+	contextType string
+}
+
+// typeGraph is the graph of one context type
+type typeGraph struct {
+	mu        sync.Mutex // Serialises builds and catch-ups
+	graph     *vector.HNSW
+	dimension int
+	metric    models.SimilarityMetric
+	lastEvent int64 // Newest log event applied to the graph
+}
+
+// NewEmbeddingIndex creates an empty index that reads embeddings from
+// embeddings and follows the event log of store
+func NewEmbeddingIndex(store storage.ContextStore, embeddings storage.EmbeddingStore, cfg vector.HNSWConfig) *EmbeddingIndex {
+	return &EmbeddingIndex{
+		store:      store,
+		embeddings: embeddings,
+		cfg:        cfg,
+		graphs:     make(map[graphKey]*typeGraph),
+	}
+}
+
+// Search returns up to k contexts of cfg's type most similar to query under
+// cfg.Metric, from an up-to-date graph
+func (x *EmbeddingIndex) Search(ctx context.Context, cfg *models.EmbeddingConfig, query []float32, k int) ([]vector.Hit, error) {
+	graph, err := x.graph(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return graph.Search(indexable(query, cfg.Metric), k, k), nil
+}
+
+// graph returns the graph of cfg's type, building it or applying the log
+// events recorded since it was last used
+func (x *EmbeddingIndex) graph(ctx context.Context, cfg *models.EmbeddingConfig) (*vector.HNSW, error) {
+	key := graphKey{tenantID: cfg.TenantID, contextType: cfg.ContextType}
+	x.mu.Lock()
+	g, ok := x.graphs[key]
+	if !ok {
+		g = &typeGraph{}
+		x.graphs[key] = g
+	}
+	x.mu.Unlock()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	first, last, err := x.store.EventBounds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log bounds: %w", err)
+	}
+	// Rebuild when the type was reconfigured or events the graph has not
+	// seen were pruned
+	if g.graph == nil || g.dimension != cfg.Dimension || g.metric != cfg.Metric || (last > g.lastEvent && first > g.lastEvent+1) {
+		if err := x.build(ctx, key, g, cfg, last); err != nil {
+			return nil, err
+		}
+		return g.graph, nil
+	}
+	if last > g.lastEvent {
+		if err := x.catchUp(ctx, key, g, last); err != nil {
+			return nil, err
+		}
+	}
+	return g.graph, nil
+}
+
+// build loads every embedding of the type into a new graph. Events up to
+// last are reflected in the loaded embeddings; applying them again later is
+// harmless.
+func (x *EmbeddingIndex) build(ctx context.Context, key graphKey, g *typeGraph, cfg *models.EmbeddingConfig, last int64) error {
+	embeddings, err := x.embeddings.List(ctx, &storage.ContextFilters{TenantID: key.tenantID, Type: key.contextType})
+	if err != nil {
+		return fmt.Errorf("failed to load embeddings: %w", err)
+	}
+	graph := vector.NewHNSW(vector.Dot, x.cfg)
+	for _, e := range embeddings {
+		if len(e.Vector) == cfg.Dimension {
+			graph.Add(e.ContextID, indexable(e.Vector, cfg.Metric))
+		}
+	}
+	g.graph, g.dimension, g.metric, g.lastEvent = graph, cfg.Dimension, cfg.Metric, last
+	return nil
+}
+
+// catchUp applies the log events of the type recorded after g.lastEvent, up
+// to last
+func (x *EmbeddingIndex) catchUp(ctx context.Context, key graphKey, g *typeGraph, last int64) error {
+	changed := make(map[string]bool) // Context ID to whether it may still exist
+	after := g.lastEvent
+	for {
+		events, err := x.store.ListEvents(ctx, &storage.EventFilters{
+			TenantID:    key.tenantID,
+			ContextType: key.contextType,
+			AfterID:     after,
+			Limit:       eventCatchUpBatch,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read event log: %w", err)
+		}
+		for _, e := range events {
+			switch e.Type {
+			// Stale contexts stay live and readable, so they stay indexed
+			case models.ContextEventCreated, models.ContextEventUpdated, models.ContextEventRestored, models.ContextEventStale:
+				changed[e.ContextID] = true
+			default:
+				changed[e.ContextID] = false
+			}
+			after = max(after, e.ID)
+		}
+		if len(events) < eventCatchUpBatch {
+			break
+		}
+	}
+
+	var reload []string
+	for id, exists := range changed {
+		if exists {
+			reload = append(reload, id)
+		} else {
+			g.graph.Remove(id)
+		}
+	}
+	if len(reload) > 0 {
+		embeddings, err := x.embeddings.List(ctx, &storage.ContextFilters{TenantID: key.tenantID, Type: key.contextType, IDs: reload})
+		if err != nil {
+			return fmt.Errorf("failed to load embeddings: %w", err)
+		}
+		found := make(map[string]bool, len(embeddings))
+		for _, e := range embeddings {
+			if len(e.Vector) == g.dimension {
+				g.graph.Add(e.ContextID, indexable(e.Vector, g.metric))
+				found[e.ContextID] = true
+			}
+		}
+		// Contexts whose embedding was cleared, or that were deleted since
+		for _, id := range reload {
+			if !found[id] {
+				g.graph.Remove(id)
+			}
+		}
+	}
+
+	g.lastEvent = max(after, last)
+	return nil
+}
+
+// indexable returns v as stored in a graph scored by dot product: normalized
+// for cosine similarity
+func indexable(v []float32, metric models.SimilarityMetric) []float32 {
+	if metric == models.MetricCosine {
+		return vector.Normalize(v)
+	}
+	return v
+}
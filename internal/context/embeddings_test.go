@@ -0,0 +1,231 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/acb/internal/vector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockEmbeddingStore serves the embeddings of the contexts in a
+// MockContextStore
+type mockEmbeddingStore struct {
+	contexts *MockContextStore
+	configs  map[string]*models.EmbeddingConfig
+	lists    int
+}
+
+func newMockEmbeddingStore(contexts *MockContextStore) *mockEmbeddingStore {
+	return &mockEmbeddingStore{contexts: contexts, configs: make(map[string]*models.EmbeddingConfig)}
+}
+
+func (s *mockEmbeddingStore) GetConfig(ctx context.Context, tenantID, contextType string) (*models.EmbeddingConfig, error) {
+	cfg, ok := s.configs[tenantID+"/"+contextType]
+	if !ok {
+		return nil, fmt.Errorf("no embedding configuration: %w", storage.ErrNotFound)
+	}
+	return cfg, nil
+}
+
+func (s *mockEmbeddingStore) SetConfig(ctx context.Context, cfg *models.EmbeddingConfig) error {
+	s.configs[cfg.TenantID+"/"+cfg.ContextType] = cfg
+	return nil
+}
+
+func (s *mockEmbeddingStore) List(ctx context.Context, filters *storage.ContextFilters) ([]*models.Embedding, error) {
+	s.lists++
+	contexts, _ := s.contexts.List(ctx, filters)
+	var embeddings []*models.Embedding
+	for _, c := range contexts {
+		if c.Embedding != nil {
+			embeddings = append(embeddings, &models.Embedding{ContextID: c.ID, Vector: c.Embedding})
+		}
+	}
+	return embeddings, nil
+}
+
+func newEmbeddingManager(t *testing.T) (*Manager, *mockEmbeddingStore) {
+	store := NewMockContextStore()
+	embeddings := newMockEmbeddingStore(store)
+	mgr := NewManager(store, WithEmbeddings(embeddings, NewEmbeddingIndex(store, embeddings, vector.DefaultHNSWConfig())))
+	admin := callerCtx("admin", string(auth.RoleAdmin))
+	require.NoError(t, mgr.SetEmbeddingConfig(admin, &models.EmbeddingConfig{ContextType: "memory", Dimension: 3}))
+	return mgr, embeddings
+}
+
+func createEmbedded(t *testing.T, mgr *Manager, id, agentID string, scope models.ContextScope, embedding []float32) {
+	_, err := mgr.Create(context.Background(), &CreateRequest{
+		ID:            id,
+		Type:          "memory",
+		AgentID:       agentID,
+		TenantID:      "default",
+		Payload:       []byte(id),
+		AccessControl: models.AccessControl{Scope: scope},
+		Embedding:     embedding,
+	})
+	require.NoError(t, err)
+}
+
+func matchIDs(matches []*models.ContextMatch) []string {
+	ids := make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func TestManager_EmbeddingConfig(t *testing.T) {
+	mgr, _ := newEmbeddingManager(t)
+
+	cfg, err := mgr.EmbeddingConfig(callerCtx("agent-1"), "memory")
+	require.NoError(t, err)
+	assert.Equal(t, 3, cfg.Dimension)
+	assert.Equal(t, models.MetricCosine, cfg.Metric)
+	assert.Equal(t, "default", cfg.TenantID)
+
+	_, err = mgr.EmbeddingConfig(callerCtx("agent-1"), "notes")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+
+	err = mgr.SetEmbeddingConfig(callerCtx("agent-1"), &models.EmbeddingConfig{ContextType: "notes", Dimension: 3})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+	err = mgr.SetEmbeddingConfig(callerCtx("admin", string(auth.RoleAdmin)), &models.EmbeddingConfig{ContextType: "notes", Dimension: 3, Metric: "l2"})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+}
+
+func TestManager_EmbeddingValidation(t *testing.T) {
+	mgr, _ := newEmbeddingManager(t)
+	ctx := callerCtx("agent-1")
+
+	create := func(contextType string, embedding []float32) error {
+		_, err := mgr.Create(ctx, &CreateRequest{
+			Type:          contextType,
+			AgentID:       "agent-1",
+			TenantID:      "default",
+			AccessControl: models.AccessControl{Scope: models.ScopePublic},
+			Embedding:     embedding,
+		})
+		return err
+	}
+	assert.True(t, acberrors.Is(create("notes", []float32{1, 0, 0}), acberrors.ErrorCodeValidationError), "type without embeddings")
+	assert.True(t, acberrors.Is(create("memory", []float32{1, 0}), acberrors.ErrorCodeValidationError), "wrong dimension")
+	require.NoError(t, create("notes", nil))
+
+	createEmbedded(t, mgr, "m1", "agent-1", models.ScopePublic, []float32{1, 0, 0})
+	_, err := mgr.Update(ctx, "m1", &UpdateRequest{Embedding: []float32{1, 0}})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+
+	// Updates without an embedding keep the current one
+	updated, err := mgr.Update(ctx, "m1", &UpdateRequest{Payload: []byte("new")})
+	require.NoError(t, err)
+	assert.Equal(t, []float32{1, 0, 0}, updated.Embedding)
+
+	// Without an embedding store embeddings are rejected
+	plain := NewManager(NewMockContextStore())
+	_, err = plain.Create(ctx, &CreateRequest{Type: "memory", AgentID: "agent-1", TenantID: "default",
+		AccessControl: models.AccessControl{Scope: models.ScopePublic}, Embedding: []float32{1, 0, 0}})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+}
+
+func TestManager_SearchSimilar(t *testing.T) {
+	mgr, embeddings := newEmbeddingManager(t)
+	createEmbedded(t, mgr, "east", "agent-1", models.ScopePublic, []float32{1, 0, 0})
+	createEmbedded(t, mgr, "north-east", "agent-1", models.ScopePublic, []float32{1, 1, 0})
+	createEmbedded(t, mgr, "north", "agent-1", models.ScopePublic, []float32{0, 1, 0})
+	createEmbedded(t, mgr, "secret", "agent-2", models.ScopePrivate, []float32{1, 0.1, 0})
+	createEmbedded(t, mgr, "up", "agent-1", models.ScopePublic, []float32{0, 0, 1})
+
+	ctx := callerCtx("agent-1")
+	matches, err := mgr.SearchSimilar(ctx, &SimilarityRequest{Type: "memory", Vector: []float32{1, 0.2, 0}, TopK: 2})
+	require.NoError(t, err)
+	// The private context of another agent is never returned
+	assert.Equal(t, []string{"east", "north-east"}, matchIDs(matches))
+	assert.InDelta(t, 0.98, matches[0].Score, 0.01)
+
+	matches, err = mgr.SearchSimilar(callerCtx("agent-2"), &SimilarityRequest{Type: "memory", Vector: []float32{1, 0.2, 0}, TopK: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"secret"}, matchIDs(matches))
+
+	// The index follows writes through the event log
+	require.NoError(t, mgr.Delete(ctx, "east"))
+	_, err = mgr.Update(ctx, "up", &UpdateRequest{Embedding: []float32{1, 0.2, 0}})
+	require.NoError(t, err)
+	matches, err = mgr.SearchSimilar(ctx, &SimilarityRequest{Type: "memory", Vector: []float32{1, 0.2, 0}, TopK: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up", "north-east"}, matchIDs(matches))
+	assert.Equal(t, 2, embeddings.lists, "expected one build and one reload")
+
+	// Exact search and a metric the type is not indexed for score every candidate
+	exact, err := mgr.SearchSimilar(ctx, &SimilarityRequest{Type: "memory", Vector: []float32{1, 0.2, 0}, TopK: 2, Exact: true})
+	require.NoError(t, err)
+	assert.Equal(t, matchIDs(matches), matchIDs(exact))
+	// Cosine prefers the direction of up, the dot product the length of north-east
+	dot, err := mgr.SearchSimilar(ctx, &SimilarityRequest{Type: "memory", Vector: []float32{1, 0.3, 0}, TopK: 1, Metric: models.MetricDot})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"north-east"}, matchIDs(dot))
+	assert.InDelta(t, 1.3, dot[0].Score, 1e-6)
+
+	// Filters leaving fewer than TopK candidates fall back to exact search
+	matches, err = mgr.SearchSimilar(ctx, &SimilarityRequest{Type: "memory", Vector: []float32{0, 1, 0}, TopK: 10, AgentID: "agent-1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"north", "north-east", "up"}, matchIDs(matches))
+}
+
+func TestManager_SearchSimilarKeepsStaleContexts(t *testing.T) {
+	mgr, _ := newEmbeddingManager(t)
+	ctx := callerCtx("agent-1")
+	createEmbedded(t, mgr, "source", "agent-1", models.ScopePublic, []float32{0, 0, 1})
+	_, err := mgr.Create(ctx, &CreateRequest{
+		ID:            "summary",
+		Type:          "memory",
+		AgentID:       "agent-1",
+		TenantID:      "default",
+		Payload:       []byte("summary"),
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+		Embedding:     []float32{1, 0, 0},
+		DerivedFrom:   []models.ContextLink{{ParentID: "source"}},
+	})
+	require.NoError(t, err)
+
+	query := &SimilarityRequest{Type: "memory", Vector: []float32{1, 0, 0}, TopK: 1}
+	matches, err := mgr.SearchSimilar(ctx, query)
+	require.NoError(t, err)
+	require.Equal(t, []string{"summary"}, matchIDs(matches))
+
+	// Catching up on the stale event keeps the descendant in the graph
+	_, err = mgr.Update(ctx, "source", &UpdateRequest{Payload: []byte("v2"), MarkDescendantsStale: true})
+	require.NoError(t, err)
+	stale, err := mgr.Get(ctx, "summary")
+	require.NoError(t, err)
+	require.True(t, stale.Stale)
+	matches, err = mgr.SearchSimilar(ctx, query)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"summary"}, matchIDs(matches))
+}
+
+func TestManager_SearchSimilarValidation(t *testing.T) {
+	mgr, _ := newEmbeddingManager(t)
+	ctx := callerCtx("agent-1")
+
+	for name, req := range map[string]*SimilarityRequest{
+		"missing type":    {Vector: []float32{1, 0, 0}},
+		"undeclared type": {Type: "notes", Vector: []float32{1, 0, 0}},
+		"missing vector":  {Type: "memory"},
+		"wrong dimension": {Type: "memory", Vector: []float32{1, 0}},
+		"top_k too large": {Type: "memory", Vector: []float32{1, 0, 0}, TopK: 1000},
+		"unknown metric":  {Type: "memory", Vector: []float32{1, 0, 0}, Metric: "l2"},
+		"negative top_k":  {Type: "memory", Vector: []float32{1, 0, 0}, TopK: -1},
+	} {
+		_, err := mgr.SearchSimilar(ctx, req)
+		assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError), name)
+	}
+
+	_, err := mgr.SearchSimilar(context.Background(), &SimilarityRequest{Type: "memory", Vector: []float32{1, 0, 0}})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeUnauthorized))
+}
@@ -0,0 +1,67 @@
+package context
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+)
+
+// ConflictError reports that a conditional write lost a race: the context is
+// no longer at the revision the caller expected. It is returned wrapped in a
+// PRECONDITION_FAILED ACBError; use errors.As to inspect it.
+type ConflictError struct {
+	ContextID        string
+	ExpectedRevision int
+	CurrentRevision  int // Zero if the current revision could not be determined
+}
+
+func (e *ConflictError) Error() string {
+	if e.CurrentRevision == 0 {
+		return fmt.Sprintf("context %s is no longer at revision %d", e.ContextID, e.ExpectedRevision)
+	}
+	return fmt.Sprintf("context %s is at revision %d, expected %d", e.ContextID, e.CurrentRevision, e.ExpectedRevision)
+}
+
+// conflictError wraps a ConflictError in the API error reported to callers
+func conflictError(contextID string, expected, current int) error {
+	conflict := &ConflictError{ContextID: contextID, ExpectedRevision: expected, CurrentRevision: current}
+	return acberrors.PreconditionFailed(conflict.Error()).WithError(conflict)
+}
+
+// PolicyError reports that a context breaks the lifecycle policy of its type.
+// It is returned wrapped in a VALIDATION_ERROR ACBError; use errors.As to
+// inspect it.
+type PolicyError struct {
+	ContextType string
+	Violations  []string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("context violates the lifecycle policy of type %s: %s", e.ContextType, strings.Join(e.Violations, "; "))
+}
+
+// policyError wraps a PolicyError in the API error reported to callers
+func policyError(contextType string, violations []string) error {
+	policy := &PolicyError{ContextType: contextType, Violations: violations}
+	return acberrors.ValidationError(policy.Error()).WithError(policy)
+}
+
+// LeaseError reports that a context is leased to another agent, which alone
+// may write it until the lease expires or is released. It is returned wrapped
+// in a CONFLICT ACBError; use errors.As to inspect it.
+type LeaseError struct {
+	Lease *models.ContextLease
+}
+
+func (e *LeaseError) Error() string {
+	return fmt.Sprintf("context %s is leased to %s until %s", e.Lease.ContextID, e.Lease.HolderID, e.Lease.ExpiresAt.Format(time.RFC3339))
+}
+
+// leaseError wraps a LeaseError in the API error reported to callers
+func leaseError(lease *models.ContextLease) error {
+	leased := &LeaseError{Lease: lease}
+	return acberrors.Conflict(leased.Error()).WithError(leased)
+}
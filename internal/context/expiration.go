@@ -0,0 +1,228 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/acb/internal/auth"
+	"github.com/acb/internal/constants"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/acb/internal/worker"
+)
+
+// Toucher batches the expiry extensions of sliding contexts, so that reads
+// do not each cost an UPDATE. Pending extensions are written on every
+// interval, as few statements as possible.
+type Toucher struct {
+	store    storage.ContextStore
+	interval time.Duration
+	mu       sync.Mutex
+	pending  map[string]time.Time
+}
+
+// NewToucher creates a toucher that writes pending extensions to store
+// every interval
+func NewToucher(store storage.ContextStore, interval time.Duration) (*Toucher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("touch interval must be positive")
+	}
+	return &Toucher{store: store, interval: interval, pending: make(map[string]time.Time)}, nil
+}
+
+// Touch queues moving the expiry of a context to expiresAt. Of several
+// touches of a context before a flush only the latest expiry is written.
+func (t *Toucher) Touch(contextID string, expiresAt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if expiresAt.After(t.pending[contextID]) {
+		t.pending[contextID] = expiresAt
+	}
+}
+
+// Pending returns the expiry queued for a context, if any
+func (t *Toucher) Pending(contextID string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	expiresAt, ok := t.pending[contextID]
+	return expiresAt, ok
+}
+
+// Flush writes every pending extension in batches of ContextTouchBatchSize
+// and returns how many contexts were extended. Extensions that fail to be
+// written are queued again for the next flush.
+func (t *Toucher) Flush(ctx context.Context) (int, error) {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[string]time.Time)
+	t.mu.Unlock()
+
+	touched := 0
+	batch := make(map[string]time.Time, min(len(pending), constants.ContextTouchBatchSize))
+	write := func() error {
+		n, err := t.store.Touch(ctx, batch)
+		if err != nil {
+			for id, expiresAt := range batch {
+				t.Touch(id, expiresAt)
+			}
+			return err
+		}
+		touched += n
+		clear(batch)
+		return nil
+	}
+	for id, expiresAt := range pending {
+		delete(pending, id)
+		batch[id] = expiresAt
+		if len(batch) < constants.ContextTouchBatchSize {
+			continue
+		}
+		if err := write(); err != nil {
+			t.requeue(pending)
+			return touched, fmt.Errorf("failed to extend context expiries: %w", err)
+		}
+	}
+	if len(batch) > 0 {
+		if err := write(); err != nil {
+			return touched, fmt.Errorf("failed to extend context expiries: %w", err)
+		}
+	}
+	return touched, nil
+}
+
+// requeue queues extensions a failed flush did not reach
+func (t *Toucher) requeue(pending map[string]time.Time) {
+	for id, expiresAt := range pending {
+		t.Touch(id, expiresAt)
+	}
+}
+
+// Run flushes on every interval until ctx is cancelled, then flushes once
+// more so that extensions queued before shutdown are not lost
+func (t *Toucher) Run(ctx context.Context) {
+	flush := func(ctx context.Context) error {
+		_, err := t.Flush(ctx)
+		return err
+	}
+	worker.Run(ctx, "context toucher", t.interval, flush)
+
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), t.interval)
+	defer cancel()
+	worker.Once(flushCtx, "context toucher", flush)
+}
+
+// WithExpiration applies the expiration defaults configured per context type
+// in store to contexts created without their own. Reads of sliding contexts
+// queue their extension on toucher; when toucher is nil each extension is
+// written immediately.
+func WithExpiration(store storage.ExpirationStore, toucher *Toucher) Option {
+	return func(m *Manager) {
+		m.expirations = store
+		m.toucher = toucher
+	}
+}
+
+// ExpirationConfig returns the expiration defaults of a context type in the
+// caller's tenant
+func (m *Manager) ExpirationConfig(ctx context.Context, contextType string) (*models.ExpirationConfig, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.expirations == nil {
+		return nil, acberrors.NotFound("expiration defaults are not enabled")
+	}
+	cfg, err := m.expirations.GetConfig(ctx, claims.TenantID, contextType)
+	if storage.IsNotFound(err) {
+		return nil, acberrors.NotFound(fmt.Sprintf("context type %s has no expiration defaults", contextType)).WithError(err)
+	}
+	return cfg, err
+}
+
+// SetExpirationConfig sets the expiration defaults of a context type in the
+// caller's tenant. Only tenant admins may change them, and they apply to
+// contexts created afterwards.
+func (m *Manager) SetExpirationConfig(ctx context.Context, cfg *models.ExpirationConfig) error {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !claims.HasRole(auth.RoleAdmin) {
+		return acberrors.Forbidden("only admins may configure expiration defaults")
+	}
+	if m.expirations == nil {
+		return acberrors.ServiceUnavailable("expiration defaults are not enabled")
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = models.ExpirationFixed
+	}
+	if err := cfg.Validate(); err != nil {
+		return acberrors.ValidationError(err.Error()).WithError(err)
+	}
+
+	cfg.TenantID = claims.TenantID
+	return m.expirations.SetConfig(ctx, cfg)
+}
+
+// expirationDefaults returns the expiration defaults of a context type, or
+// nil when it has none
+func (m *Manager) expirationDefaults(ctx context.Context, tenantID, contextType string) (*models.ExpirationConfig, error) {
+	if m.expirations == nil {
+		return nil, nil
+	}
+	cfg, err := m.expirations.GetConfig(ctx, tenantID, contextType)
+	if storage.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expiration defaults: %w", err)
+	}
+	return cfg, nil
+}
+
+// withPending returns c with the expiry queued for it by an earlier read, so
+// that it is not reported expired before the extension is written
+func (m *Manager) withPending(c *models.Context) *models.Context {
+	if m.toucher == nil {
+		return c
+	}
+	if expiresAt, ok := m.toucher.Pending(c.ID); ok && expiresAt.After(c.ExpiresAt) {
+		c.ExpiresAt = expiresAt
+	}
+	return c
+}
+
+// touchRead extends the expiry of the sliding_on_read contexts among those
+// just read. An expiry only moves once it would by ContextTouchResolution of
+// its TTL, which bounds the writes of a context read in a tight loop. An
+// extension is queued on the toucher unless the stored expiry could pass
+// before the next flush.
+func (m *Manager) touchRead(ctx context.Context, contexts ...*models.Context) {
+	now := time.Now()
+	immediate := make(map[string]time.Time)
+	for _, c := range contexts {
+		if c.Expiration != models.ExpirationSlidingOnRead {
+			continue
+		}
+		expiresAt := c.ExpiryFrom(now)
+		if expiresAt.Sub(c.ExpiresAt) < c.TTL/constants.ContextTouchResolution {
+			continue
+		}
+		if m.toucher != nil && c.ExpiresAt.Sub(now) > 2*m.toucher.interval {
+			m.toucher.Touch(c.ID, expiresAt)
+		} else {
+			immediate[c.ID] = expiresAt
+		}
+		c.ExpiresAt = expiresAt
+	}
+	if len(immediate) == 0 {
+		return
+	}
+	if _, err := m.store.Touch(ctx, immediate); err != nil {
+		log.Printf("context manager: failed to extend context expiries: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockExpirationStore keeps expiration defaults in memory
+type mockExpirationStore struct {
+	configs map[string]*models.ExpirationConfig
+}
+
+func (s *mockExpirationStore) GetConfig(ctx context.Context, tenantID, contextType string) (*models.ExpirationConfig, error) {
+	cfg, ok := s.configs[tenantID+"/"+contextType]
+	if !ok {
+		return nil, fmt.Errorf("no expiration configuration: %w", storage.ErrNotFound)
+	}
+	return cfg, nil
+}
+
+func (s *mockExpirationStore) SetConfig(ctx context.Context, cfg *models.ExpirationConfig) error {
+	s.configs[cfg.TenantID+"/"+cfg.ContextType] = cfg
+	return nil
+}
+
+func newExpirationManager(t *testing.T) (*Manager, *MockContextStore, *Toucher) {
+	store := NewMockContextStore()
+	toucher, err := NewToucher(store, time.Second)
+	require.NoError(t, err)
+	return NewManager(store, WithExpiration(&mockExpirationStore{configs: map[string]*models.ExpirationConfig{}}, toucher)), store, toucher
+}
+
+func createSession(t *testing.T, mgr *Manager, req *CreateRequest) *models.Context {
+	req.Type = "session"
+	req.AgentID = "agent-1"
+	req.TenantID = "default"
+	req.Payload = []byte("turns")
+	req.AccessControl = models.AccessControl{Scope: models.ScopePrivate}
+	c, err := mgr.Create(context.Background(), req)
+	require.NoError(t, err)
+	return c
+}
+
+func TestManager_SlidingOnRead(t *testing.T) {
+	mgr, store, toucher := newExpirationManager(t)
+	ctx := callerCtx("agent-1")
+	c := createSession(t, mgr, &CreateRequest{Expiration: models.ExpirationSlidingOnRead, TTL: time.Hour})
+
+	// A read shortly after a write does not move the expiry enough to touch
+	_, err := mgr.Get(ctx, c.ID)
+	require.NoError(t, err)
+	_, pending := toucher.Pending(c.ID)
+	assert.False(t, pending)
+
+	// Halfway to expiry, reads queue an extension instead of writing it
+	store.contexts[c.ID].ExpiresAt = time.Now().Add(30 * time.Minute)
+	got, err := mgr.Get(ctx, c.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), got.ExpiresAt, time.Minute)
+	queued, pending := toucher.Pending(c.ID)
+	require.True(t, pending)
+	assert.Equal(t, 0, store.touches)
+
+	// Until the flush, reads see the queued expiry rather than the stored one
+	store.contexts[c.ID].ExpiresAt = time.Now().Add(-time.Second)
+	_, err = mgr.Get(ctx, c.ID)
+	require.NoError(t, err)
+	store.contexts[c.ID].ExpiresAt = time.Now().Add(30 * time.Minute)
+
+	touched, err := toucher.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, touched)
+	assert.Equal(t, 1, store.touches)
+	assert.False(t, store.contexts[c.ID].ExpiresAt.Before(queued))
+	_, pending = toucher.Pending(c.ID)
+	assert.False(t, pending)
+}
+
+func TestManager_SlidingOnRead_NearExpiryWritesImmediately(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store)
+	c := createSession(t, mgr, &CreateRequest{Expiration: models.ExpirationSlidingOnRead, TTL: time.Hour})
+
+	store.contexts[c.ID].ExpiresAt = time.Now().Add(time.Minute)
+	_, err := mgr.Get(callerCtx("agent-1"), c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.touches)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), store.contexts[c.ID].ExpiresAt, time.Minute)
+}
+
+func TestManager_SlidingExpiryIsCappedByMaxLifetime(t *testing.T) {
+	mgr, store, _ := newExpirationManager(t)
+	ctx := callerCtx("agent-1")
+	c := createSession(t, mgr, &CreateRequest{Expiration: models.ExpirationSlidingOnWrite, TTL: time.Hour, MaxLifetime: 2 * time.Hour})
+
+	// Writes slide the expiry, but never past the deadline
+	store.contexts[c.ID].CreatedAt = time.Now().Add(-90 * time.Minute)
+	updated, err := mgr.Update(ctx, c.ID, &UpdateRequest{Payload: []byte("more turns")})
+	require.NoError(t, err)
+	assert.True(t, updated.ExpiresAt.Equal(updated.Deadline()))
+
+	// Reads do not move the expiry of sliding_on_write contexts
+	store.contexts[c.ID].ExpiresAt = time.Now().Add(time.Minute)
+	_, err = mgr.Get(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, store.touches)
+
+	// Fixed contexts only move on an explicit TTL
+	fixed := createSession(t, mgr, &CreateRequest{TTL: time.Hour})
+	assert.Equal(t, models.ExpirationFixed, fixed.Expiration)
+	updated, err = mgr.Update(ctx, fixed.ID, &UpdateRequest{Payload: []byte("edit")})
+	require.NoError(t, err)
+	assert.True(t, updated.ExpiresAt.Equal(fixed.ExpiresAt))
+}
+
+func TestManager_ExpirationDefaults(t *testing.T) {
+	mgr, _, _ := newExpirationManager(t)
+	admin := callerCtx("admin", string(auth.RoleAdmin))
+
+	_, err := mgr.ExpirationConfig(admin, "session")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	err = mgr.SetExpirationConfig(callerCtx("agent-1"), &models.ExpirationConfig{ContextType: "session"})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+	err = mgr.SetExpirationConfig(admin, &models.ExpirationConfig{ContextType: "session", TTL: 2 * time.Hour, MaxLifetime: time.Hour})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+
+	require.NoError(t, mgr.SetExpirationConfig(admin, &models.ExpirationConfig{
+		ContextType: "session",
+		Policy:      models.ExpirationSlidingOnRead,
+		TTL:         30 * time.Minute,
+		MaxLifetime: 8 * time.Hour,
+	}))
+	cfg, err := mgr.ExpirationConfig(admin, "session")
+	require.NoError(t, err)
+	assert.Equal(t, "default", cfg.TenantID)
+
+	// Contexts of the type inherit what they do not set themselves
+	c := createSession(t, mgr, &CreateRequest{})
+	assert.Equal(t, models.ExpirationSlidingOnRead, c.Expiration)
+	assert.Equal(t, 30*time.Minute, c.TTL)
+	assert.Equal(t, 8*time.Hour, c.MaxLifetime)
+	c = createSession(t, mgr, &CreateRequest{Expiration: models.ExpirationFixed, TTL: time.Hour})
+	assert.Equal(t, models.ExpirationFixed, c.Expiration)
+	assert.Equal(t, time.Hour, c.TTL)
+
+	results, err := mgr.BatchCreate(context.Background(), models.BatchAtomic, []*CreateRequest{{
+		Type: "session", AgentID: "agent-1", TenantID: "default", Payload: []byte("p"),
+		AccessControl: models.AccessControl{Scope: models.ScopePrivate},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, models.ExpirationSlidingOnRead, results[0].Context.Expiration)
+}
+
+func TestNewToucher_RejectsInvalidInterval(t *testing.T) {
+	_, err := NewToucher(NewMockContextStore(), 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package context
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/acb/internal/storage"
+)
+
+const (
+	hubMinBackoff = time.Second
+	hubMaxBackoff = 30 * time.Second
+)
+
+// EventHub wakes the context change streams and waiting reads served by this
+// replica when new events may have been logged. Wakeups carry no data:
+// subscribers always read the store, so a lost or repeated wakeup only delays
+// or repeats a read. Subscribers are keyed by tenant and context, so a signal
+// only wakes the streams of its tenant and the reads waiting on its context.
+type EventHub struct {
+	mu          sync.Mutex
+	subscribers map[hubKey]map[chan struct{}]struct{}
+}
+
+// hubKey identifies what a subscriber waits on; an empty contextID stands for
+// every context of the tenant
+type hubKey struct {
+	tenantID  string
+	contextID string
+}
+
+// NewEventHub creates a new event hub
+func NewEventHub() *EventHub {
+	return &EventHub{subscribers: make(map[hubKey]map[chan struct{}]struct{})}
+}
+
+// Subscribe returns a channel that receives after each Notify concerning
+// contextID, or any context of tenantID when contextID is empty, and a
+// cancel function that must be called once the subscriber is done
+func (h *EventHub) Subscribe(tenantID, contextID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	key := hubKey{tenantID: tenantID, contextID: contextID}
+
+	h.mu.Lock()
+	subs, ok := h.subscribers[key]
+	if !ok {
+		subs = make(map[chan struct{}]struct{})
+		h.subscribers[key] = subs
+	}
+	subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		delete(subs, ch)
+		if len(subs) == 0 {
+			delete(h.subscribers, key)
+		}
+		h.mu.Unlock()
+	}
+	return ch, cancel
+}
+
+// Notify wakes the subscribers signal concerns. It never blocks: a subscriber
+// that has not consumed its previous wakeup still has one pending.
+func (h *EventHub) Notify(signal storage.EventSignal) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if signal.TenantID == "" || signal.ContextID == "" {
+		// Rare signals covering whole tenants walk every key
+		for key, subs := range h.subscribers {
+			if signal.TenantID == "" || key.tenantID == signal.TenantID {
+				wake(subs)
+			}
+		}
+		return
+	}
+	wake(h.subscribers[hubKey{tenantID: signal.TenantID}])
+	wake(h.subscribers[hubKey{tenantID: signal.TenantID, contextID: signal.ContextID}])
+}
+
+// wake signals each channel in subs that has no wakeup pending
+func wake(subs map[chan struct{}]struct{}) {
+	for ch := range subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run relays signals from notifier to subscribers until ctx is cancelled,
+// reconnecting with backoff when the notifier fails
+func (h *EventHub) Run(ctx context.Context, notifier storage.EventNotifier) {
+	backoff := hubMinBackoff
+
+	for {
+		started := time.Now()
+		err := notifier.Listen(ctx, h.Notify)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > hubMaxBackoff {
+			backoff = hubMinBackoff
+		}
+		log.Printf("context event hub: %v; reconnecting in %s", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > hubMaxBackoff {
+			backoff = hubMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyNotifier fails its first Listen and signals once on the next
+type flakyNotifier struct {
+	calls atomic.Int32
+}
+
+func (n *flakyNotifier) Listen(ctx context.Context, notify func(storage.EventSignal)) error {
+	if n.calls.Add(1) == 1 {
+		return errors.New("connection reset")
+	}
+	notify(storage.EventSignal{})
+	<-ctx.Done()
+	return nil
+}
+
+func TestEventHub_NotifyCoalescesWakeups(t *testing.T) {
+	hub := NewEventHub()
+	wake, cancel := hub.Subscribe("default", "ctx-1")
+	signal := storage.EventSignal{TenantID: "default", ContextID: "ctx-1"}
+
+	// A slow subscriber never blocks Notify and keeps one pending wakeup
+	hub.Notify(signal)
+	hub.Notify(signal)
+	<-wake
+	select {
+	case <-wake:
+		t.Fatal("wakeups were not coalesced")
+	default:
+	}
+
+	cancel()
+	hub.Notify(signal)
+	select {
+	case <-wake:
+		t.Fatal("cancelled subscriber was woken")
+	default:
+	}
+}
+
+func TestEventHub_NotifyWakesMatchingSubscribers(t *testing.T) {
+	hub := NewEventHub()
+	tenant, cancelTenant := hub.Subscribe("default", "")
+	defer cancelTenant()
+	waiter, cancelWaiter := hub.Subscribe("default", "ctx-1")
+	defer cancelWaiter()
+	other, cancelOther := hub.Subscribe("other", "")
+	defer cancelOther()
+
+	woken := func() []bool {
+		var got []bool
+		for _, ch := range []<-chan struct{}{tenant, waiter, other} {
+			select {
+			case <-ch:
+				got = append(got, true)
+			default:
+				got = append(got, false)
+			}
+		}
+		return got
+	}
+
+	hub.Notify(storage.EventSignal{TenantID: "default", ContextID: "ctx-2"})
+	assert.Equal(t, []bool{true, false, false}, woken())
+	hub.Notify(storage.EventSignal{TenantID: "default", ContextID: "ctx-1"})
+	assert.Equal(t, []bool{true, true, false}, woken())
+	hub.Notify(storage.EventSignal{TenantID: "default"})
+	assert.Equal(t, []bool{true, true, false}, woken())
+	hub.Notify(storage.EventSignal{})
+	assert.Equal(t, []bool{true, true, true}, woken())
+}
+
+func TestEventHub_RunReconnects(t *testing.T) {
+	hub := NewEventHub()
+	wake, cancel := hub.Subscribe("default", "ctx-1")
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	notifier := &flakyNotifier{}
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx, notifier)
+		close(done)
+	}()
+
+	select {
+	case <-wake:
+	case <-time.After(3 * time.Second):
+		t.Fatal("hub did not relay after reconnecting")
+	}
+	assert.Equal(t, int32(2), notifier.calls.Load())
+
+	stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "hub did not stop after cancel")
+	}
+}
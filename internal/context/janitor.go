@@ -0,0 +1,163 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/acb/internal/constants"
+	"github.com/acb/internal/storage"
+	"github.com/acb/internal/worker"
+)
+
+// janitorLockName is the cluster-wide lock held while a replica sweeps
+const janitorLockName = "acb:context:janitor"
+
+// JanitorConfig configures the expired context janitor
+type JanitorConfig struct {
+	Interval       time.Duration // Time between sweeps
+	BatchSize      int           // Contexts deleted per transaction
+	MaxBatches     int           // Batches per sweep, bounding how long a sweep runs
+	EventRetention time.Duration // How long context events stay in the log
+	PurgeAfter     time.Duration // Permanently remove deleted contexts this long after deletion (0 disables)
+}
+
+// DefaultJanitorConfig returns the janitor configuration derived from constants
+func DefaultJanitorConfig() JanitorConfig {
+	return JanitorConfig{
+		Interval:       time.Duration(constants.ContextSweepInterval) * time.Second,
+		BatchSize:      constants.ContextSweepBatchSize,
+		MaxBatches:     constants.ContextSweepMaxBatches,
+		EventRetention: time.Duration(constants.ContextEventRetention) * time.Second,
+		PurgeAfter:     time.Duration(constants.DefaultRestoreWindow) * time.Second,
+	}
+}
+
+// Validate validates a JanitorConfig
+func (c JanitorConfig) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("sweep interval must be positive")
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	if c.MaxBatches <= 0 {
+		return fmt.Errorf("max batches must be positive")
+	}
+	if c.EventRetention <= 0 {
+		return fmt.Errorf("event retention must be positive")
+	}
+	if c.PurgeAfter < 0 {
+		return fmt.Errorf("purge delay cannot be negative")
+	}
+	return nil
+}
+
+// JanitorResult reports what a single sweep did
+type JanitorResult struct {
+	Deleted      int
+	Batches      int
+	More         bool // The batch limit was reached with expired contexts left
+	EventsPruned int  // Events older than the retention removed from the log
+	Purged       int  // Deleted contexts permanently removed
+	Skipped      bool // Another replica held the lock
+}
+
+// Janitor periodically deletes expired contexts in bounded batches. Each
+// deletion is logged as a context.expired event in the same transaction.
+// It also prunes the event log to the configured retention and purges
+// contexts deleted longer ago than PurgeAfter.
+type Janitor struct {
+	store  storage.ContextStore
+	locker storage.Locker
+	config JanitorConfig
+	now    func() time.Time
+}
+
+// NewJanitor creates a new expired context janitor. A nil locker means every
+// replica sweeps independently.
+func NewJanitor(store storage.ContextStore, locker storage.Locker, config JanitorConfig) (*Janitor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid janitor config: %w", err)
+	}
+	return &Janitor{
+		store:  store,
+		locker: locker,
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+// WithClock overrides the time source used to decide what has expired
+func (j *Janitor) WithClock(now func() time.Time) *Janitor {
+	j.now = now
+	return j
+}
+
+// Sweep runs a single expiry pass of at most MaxBatches batches
+func (j *Janitor) Sweep(ctx context.Context) (*JanitorResult, error) {
+	unlock, acquired, err := worker.TryLock(ctx, j.locker, janitorLockName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire janitor lock: %w", err)
+	}
+	if !acquired {
+		return &JanitorResult{Skipped: true}, nil
+	}
+	defer unlock()
+
+	now := j.now()
+	result := &JanitorResult{}
+
+	for !result.More {
+		events, err := j.store.DeleteExpired(ctx, now, j.config.BatchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to delete expired contexts: %w", err)
+		}
+		result.Batches++
+		result.Deleted += len(events)
+		if len(events) < j.config.BatchSize {
+			break
+		}
+		result.More = result.Batches == j.config.MaxBatches
+	}
+
+	pruned, err := j.store.PruneEvents(ctx, now.Add(-j.config.EventRetention), j.config.BatchSize*j.config.MaxBatches)
+	if err != nil {
+		return result, fmt.Errorf("failed to prune context events: %w", err)
+	}
+	result.EventsPruned = pruned
+
+	if j.config.PurgeAfter > 0 {
+		purged, err := j.store.PurgeDeleted(ctx, now.Add(-j.config.PurgeAfter), j.config.BatchSize*j.config.MaxBatches)
+		if err != nil {
+			return result, fmt.Errorf("failed to purge deleted contexts: %w", err)
+		}
+		result.Purged = purged
+	}
+	return result, nil
+}
+
+// Run sweeps on every interval until ctx is cancelled. Errors and panics
+// in a sweep are logged and the next sweep still runs.
+func (j *Janitor) Run(ctx context.Context) {
+	worker.Run(ctx, "context janitor", j.config.Interval, func(ctx context.Context) error {
+		result, err := j.Sweep(ctx)
+		if err != nil {
+			return err
+		}
+		if result.Deleted > 0 {
+			log.Printf("context janitor: deleted %d expired contexts in %d batches", result.Deleted, result.Batches)
+		}
+		if result.More {
+			log.Printf("context janitor: batch limit reached, remaining expired contexts wait for the next sweep")
+		}
+		if result.EventsPruned > 0 {
+			log.Printf("context janitor: pruned %d context events past retention", result.EventsPruned)
+		}
+		if result.Purged > 0 {
+			log.Printf("context janitor: purged %d deleted contexts past the restore window", result.Purged)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,213 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockLocker is an in-process Locker shared by simulated replicas
+type mockLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func (l *mockLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[name] {
+		return nil, false, nil
+	}
+	if l.held == nil {
+		l.held = make(map[string]bool)
+	}
+	l.held[name] = true
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, name)
+	}, true, nil
+}
+
+func seedContext(store *MockContextStore, id string, expiresAt time.Time) {
+	store.contexts[id] = &models.Context{
+		ID:            id,
+		Type:          "task",
+		AgentID:       "agent-1",
+		TenantID:      "default",
+		Revision:      1,
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+		ExpiresAt:     expiresAt,
+	}
+}
+
+func TestJanitorConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  JanitorConfig
+		wantErr bool
+	}{
+		{"defaults", DefaultJanitorConfig(), false},
+		{"zero interval", JanitorConfig{BatchSize: 1, MaxBatches: 1, EventRetention: time.Hour}, true},
+		{"zero batch size", JanitorConfig{Interval: time.Second, MaxBatches: 1, EventRetention: time.Hour}, true},
+		{"zero max batches", JanitorConfig{Interval: time.Second, BatchSize: 1, EventRetention: time.Hour}, true},
+		{"zero event retention", JanitorConfig{Interval: time.Second, BatchSize: 1, MaxBatches: 1}, true},
+		{"negative purge delay", JanitorConfig{Interval: time.Second, BatchSize: 1, MaxBatches: 1, EventRetention: time.Hour, PurgeAfter: -time.Hour}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestJanitor_Sweep_DeletesInBatches(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMockContextStore()
+	for i := 0; i < 5; i++ {
+		seedContext(store, fmt.Sprintf("expired-%d", i), now.Add(-time.Duration(i+1)*time.Minute))
+	}
+	seedContext(store, "live", now.Add(time.Minute))
+	seedContext(store, "forever", time.Time{})
+
+	janitor, err := NewJanitor(store, nil, JanitorConfig{Interval: time.Second, BatchSize: 2, MaxBatches: 2, EventRetention: time.Hour})
+	require.NoError(t, err)
+	janitor.WithClock(func() time.Time { return now })
+
+	// The batch limit bounds a sweep; the rest waits for the next one
+	result, err := janitor.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &JanitorResult{Deleted: 4, Batches: 2, More: true}, result)
+
+	result, err = janitor.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &JanitorResult{Deleted: 1, Batches: 1}, result)
+
+	assert.Len(t, store.contexts, 2)
+	assert.Contains(t, store.contexts, "live")
+	assert.Contains(t, store.contexts, "forever")
+
+	// Every expiry is logged, oldest expiry first
+	require.Len(t, store.events, 5)
+	assert.Equal(t, "expired-4", store.events[0].ContextID)
+	for _, e := range store.events {
+		assert.Equal(t, models.ContextEventExpired, e.Type)
+		assert.Equal(t, "task", e.ContextType)
+	}
+}
+
+func TestJanitor_Sweep_PrunesEvents(t *testing.T) {
+	now := time.Now()
+	store := NewMockContextStore()
+	for i := 0; i < 3; i++ {
+		seedContext(store, fmt.Sprintf("ctx-%d", i), time.Time{})
+		store.recordEvent(models.ContextEventCreated, store.contexts[fmt.Sprintf("ctx-%d", i)])
+	}
+	store.events[0].OccurredAt = now.Add(-2 * time.Hour)
+	store.events[1].OccurredAt = now.Add(-90 * time.Minute)
+
+	janitor, err := NewJanitor(store, nil, JanitorConfig{Interval: time.Second, BatchSize: 10, MaxBatches: 1, EventRetention: time.Hour})
+	require.NoError(t, err)
+	janitor.WithClock(func() time.Time { return now })
+
+	result, err := janitor.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.EventsPruned)
+	require.Len(t, store.events, 1)
+
+	// Pruned IDs are never reused, so consumers can tell they missed events
+	first, last, err := store.EventBounds(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), first)
+	assert.Equal(t, int64(3), last)
+}
+
+func TestJanitor_Sweep_PurgesDeletedContexts(t *testing.T) {
+	now := time.Now()
+	store := NewMockContextStore()
+	for _, id := range []string{"old", "recent"} {
+		seedContext(store, id, time.Time{})
+		require.NoError(t, store.Delete(context.Background(), id))
+	}
+	*store.deleted["old"].DeletedAt = now.Add(-2 * time.Hour)
+
+	janitor, err := NewJanitor(store, nil, JanitorConfig{Interval: time.Second, BatchSize: 10, MaxBatches: 1, EventRetention: time.Hour, PurgeAfter: time.Hour})
+	require.NoError(t, err)
+	janitor.WithClock(func() time.Time { return now })
+
+	result, err := janitor.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Purged)
+	assert.NotContains(t, store.deleted, "old")
+	assert.Contains(t, store.deleted, "recent")
+
+	// Without a purge delay deleted contexts are kept
+	janitor, err = NewJanitor(store, nil, JanitorConfig{Interval: time.Second, BatchSize: 10, MaxBatches: 1, EventRetention: time.Hour})
+	require.NoError(t, err)
+	janitor.WithClock(func() time.Time { return now.Add(24 * time.Hour) })
+	result, err = janitor.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, result.Purged)
+	assert.Contains(t, store.deleted, "recent")
+}
+
+func TestJanitor_Sweep_SkipsWhenLockHeld(t *testing.T) {
+	store := NewMockContextStore()
+	seedContext(store, "expired", time.Now().Add(-time.Hour))
+
+	locker := &mockLocker{}
+	unlock, acquired, err := locker.TryLock(context.Background(), janitorLockName)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	janitor, err := NewJanitor(store, locker, DefaultJanitorConfig())
+	require.NoError(t, err)
+
+	result, err := janitor.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.True(t, result.Skipped)
+	assert.Contains(t, store.contexts, "expired")
+
+	unlock()
+	result, err = janitor.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Deleted)
+	assert.Empty(t, locker.held)
+}
+
+func TestJanitor_Run_StopsOnCancel(t *testing.T) {
+	janitor, err := NewJanitor(NewMockContextStore(), &mockLocker{}, JanitorConfig{Interval: time.Millisecond, BatchSize: 1, MaxBatches: 1, EventRetention: time.Hour})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		janitor.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("janitor did not stop after cancel")
+	}
+}
+
+func TestNewJanitor_InvalidConfig(t *testing.T) {
+	_, err := NewJanitor(NewMockContextStore(), nil, JanitorConfig{})
+	assert.Error(t, err)
+}
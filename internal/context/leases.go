@@ -0,0 +1,263 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/acb/internal/auth"
+	"github.com/acb/internal/constants"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+)
+
+// WithLeases lets agents lease contexts for exclusive work. While a lease is
+// held only its holder may write or delete the context.
+func WithLeases(store storage.LeaseStore) Option {
+	return func(m *Manager) {
+		m.leases = store
+	}
+}
+
+// fencingTokenKey is the context key for the fencing token of a write
+type fencingTokenKey struct{}
+
+// WithFencingToken returns a copy of ctx whose writes are made under the lease
+// with the given fencing token. Such writes are rejected unless that lease is
+// still held, even by the same agent.
+func WithFencingToken(ctx context.Context, token int64) context.Context {
+	return context.WithValue(ctx, fencingTokenKey{}, token)
+}
+
+// FencingTokenFromContext returns the fencing token carried by ctx
+func FencingTokenFromContext(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(fencingTokenKey{}).(int64)
+	return token, ok
+}
+
+// AcquireLease leases a context to holderID for ttl. The holder defaults to
+// the caller; only admins may lease a context on behalf of another agent, and
+// the holder must be allowed to write the context. A zero ttl means
+// DefaultLeaseTTL. Acquiring a context leased to anyone, the holder included,
+// fails with a *LeaseError until the lease expires or is released.
+func (m *Manager) AcquireLease(ctx context.Context, contextID, holderID string, ttl time.Duration) (*models.ContextLease, error) {
+	claims, err := m.leaseCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := m.load(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeWrite(claims, c); err != nil {
+		return nil, err
+	}
+
+	if holderID == "" {
+		holderID = claims.AgentID
+	}
+	if holderID != claims.AgentID {
+		if !claims.HasRole(auth.RoleAdmin) {
+			return nil, acberrors.Forbidden("only admins may lease a context to another agent")
+		}
+		if !canWrite(&auth.JWTClaims{AgentID: holderID, TenantID: c.TenantID}, c) {
+			return nil, acberrors.ValidationError(fmt.Sprintf("agent %s may not write context %s", holderID, contextID))
+		}
+	}
+	if ttl, err = leaseTTL(ttl); err != nil {
+		return nil, err
+	}
+
+	lease := &models.ContextLease{ContextID: contextID, TenantID: c.TenantID, HolderID: holderID, TTL: ttl}
+	err = m.leases.Acquire(ctx, lease)
+	if storage.IsConflict(err) {
+		held, getErr := m.leases.Get(ctx, contextID)
+		if getErr != nil {
+			// The lease ended in between; the caller may simply retry
+			return nil, acberrors.Conflict(fmt.Sprintf("context %s is already leased", contextID)).WithError(err)
+		}
+		return nil, leaseError(held)
+	}
+	if storage.IsNotFound(err) {
+		return nil, acberrors.NotFound("context not found").WithError(err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return lease, nil
+}
+
+// Lease returns the lease held on a context the caller may read
+func (m *Manager) Lease(ctx context.Context, contextID string) (*models.ContextLease, error) {
+	claims, err := m.leaseCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := m.load(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeRead(claims, c); err != nil {
+		return nil, err
+	}
+
+	lease, err := m.leases.Get(ctx, contextID)
+	if storage.IsNotFound(err) {
+		return nil, acberrors.NotFound(fmt.Sprintf("context %s is not leased", contextID)).WithError(err)
+	}
+	return lease, err
+}
+
+// RenewLease extends the caller's lease with the given fencing token to ttl
+// from now. A lease that has expired, even if no one took it over since,
+// cannot be renewed and must be acquired again.
+func (m *Manager) RenewLease(ctx context.Context, contextID string, token int64, ttl time.Duration) (*models.ContextLease, error) {
+	claims, err := m.leaseCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ttl, err = leaseTTL(ttl); err != nil {
+		return nil, err
+	}
+	if _, err := m.heldLease(ctx, claims, contextID, token); err != nil {
+		return nil, err
+	}
+
+	lease, err := m.leases.Renew(ctx, contextID, token, ttl)
+	if storage.IsNotFound(err) {
+		return nil, leaseLostError(contextID, token)
+	}
+	return lease, err
+}
+
+// ReleaseLease ends the caller's lease with the given fencing token. Admins
+// may release any lease on a context of their tenant; a zero token then
+// releases the current one.
+func (m *Manager) ReleaseLease(ctx context.Context, contextID string, token int64) error {
+	claims, err := m.leaseCaller(ctx)
+	if err != nil {
+		return err
+	}
+	if claims.HasRole(auth.RoleAdmin) {
+		if token == 0 {
+			lease, err := m.Lease(ctx, contextID)
+			if err != nil {
+				return err
+			}
+			token = lease.FencingToken
+		}
+	} else if _, err := m.heldLease(ctx, claims, contextID, token); err != nil {
+		return err
+	}
+
+	if err := m.leases.Release(ctx, contextID, token); storage.IsNotFound(err) {
+		return leaseLostError(contextID, token)
+	} else if err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
+
+// leaseCaller returns the caller's claims when leases are enabled
+func (m *Manager) leaseCaller(ctx context.Context) (*auth.JWTClaims, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.leases == nil {
+		return nil, acberrors.ServiceUnavailable("context leases are not enabled")
+	}
+	return claims, nil
+}
+
+// heldLease returns the lease on a context the caller may read, checking that
+// the caller holds it under the given fencing token
+func (m *Manager) heldLease(ctx context.Context, claims *auth.JWTClaims, contextID string, token int64) (*models.ContextLease, error) {
+	c, err := m.load(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeRead(claims, c); err != nil {
+		return nil, err
+	}
+
+	lease, err := m.leases.Get(ctx, contextID)
+	if storage.IsNotFound(err) {
+		return nil, leaseLostError(contextID, token)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lease: %w", err)
+	}
+	if lease.HolderID != claims.AgentID {
+		return nil, leaseError(lease)
+	}
+	if lease.FencingToken != token {
+		return nil, leaseLostError(contextID, token)
+	}
+	return lease, nil
+}
+
+// checkLease returns the error to report when a lease on c stops the caller
+// from writing it: the lease is held by another agent, or the write carries
+// the fencing token of a lease that is no longer held
+func (m *Manager) checkLease(ctx context.Context, claims *auth.JWTClaims, c *models.Context) error {
+	if m.leases == nil {
+		return nil
+	}
+	token, fenced := FencingTokenFromContext(ctx)
+	lease, err := m.leases.Get(ctx, c.ID)
+	if storage.IsNotFound(err) {
+		if fenced {
+			return leaseLostError(c.ID, token)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load lease: %w", err)
+	}
+	if lease.HolderID != claims.AgentID {
+		return leaseError(lease)
+	}
+	if fenced && lease.FencingToken != token {
+		return leaseLostError(c.ID, token)
+	}
+	return nil
+}
+
+// guardWrite returns a copy of ctx whose store writes are checked against the
+// lease held on the context in the same transaction, so a lease that expires
+// or changes hands after checkLease still stops the write
+func (m *Manager) guardWrite(ctx context.Context, claims *auth.JWTClaims) context.Context {
+	if m.leases == nil {
+		return ctx
+	}
+	token, _ := FencingTokenFromContext(ctx)
+	return storage.WithLeaseGuard(ctx, storage.LeaseGuard{HolderID: claims.AgentID, FencingToken: token})
+}
+
+// leaseWriteError returns the error to report when the store rejected a write
+// to c because the lease on it changed after checkLease
+func (m *Manager) leaseWriteError(ctx context.Context, claims *auth.JWTClaims, c *models.Context) error {
+	if err := m.checkLease(ctx, claims, c); err != nil {
+		return err
+	}
+	return acberrors.Conflict(fmt.Sprintf("the lease on context %s changed during the write", c.ID))
+}
+
+// leaseTTL applies the default lease TTL and checks its bounds
+func leaseTTL(ttl time.Duration) (time.Duration, error) {
+	if ttl == 0 {
+		return constants.DefaultLeaseTTL * time.Second, nil
+	}
+	if ttl < time.Second || ttl > constants.MaxLeaseTTL*time.Second {
+		return 0, acberrors.ValidationError(fmt.Sprintf("lease ttl must be between 1 and %d seconds", constants.MaxLeaseTTL))
+	}
+	return ttl, nil
+}
+
+// leaseLostError reports a fencing token whose lease has expired, been
+// released or been superseded
+func leaseLostError(contextID string, token int64) error {
+	return acberrors.PreconditionFailed(fmt.Sprintf("lease %d on context %s is no longer held", token, contextID))
+}
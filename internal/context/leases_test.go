@@ -0,0 +1,236 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockLeaseStore keeps leases in memory, drawing tokens from a counter
+type mockLeaseStore struct {
+	leases    map[string]*models.ContextLease
+	lastToken int64
+}
+
+func (s *mockLeaseStore) Acquire(ctx context.Context, l *models.ContextLease) error {
+	if _, err := s.Get(ctx, l.ContextID); err == nil {
+		return fmt.Errorf("already leased: %w", storage.ErrConflict)
+	}
+	s.lastToken++
+	l.FencingToken = s.lastToken
+	l.AcquiredAt = time.Now()
+	l.ExpiresAt = l.AcquiredAt.Add(l.TTL)
+	cp := *l
+	s.leases[l.ContextID] = &cp
+	return nil
+}
+
+func (s *mockLeaseStore) Get(ctx context.Context, contextID string) (*models.ContextLease, error) {
+	l, ok := s.leases[contextID]
+	if !ok || !l.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("not leased: %w", storage.ErrNotFound)
+	}
+	cp := *l
+	return &cp, nil
+}
+
+func (s *mockLeaseStore) Renew(ctx context.Context, contextID string, token int64, ttl time.Duration) (*models.ContextLease, error) {
+	l, err := s.Get(ctx, contextID)
+	if err != nil || l.FencingToken != token {
+		return nil, fmt.Errorf("no longer held: %w", storage.ErrNotFound)
+	}
+	l.TTL = ttl
+	l.ExpiresAt = time.Now().Add(ttl)
+	s.leases[contextID] = l
+	return l, nil
+}
+
+func (s *mockLeaseStore) Release(ctx context.Context, contextID string, token int64) error {
+	l, err := s.Get(ctx, contextID)
+	if err != nil || l.FencingToken != token {
+		return fmt.Errorf("no longer held: %w", storage.ErrNotFound)
+	}
+	delete(s.leases, contextID)
+	return nil
+}
+
+func newLeaseManager(t *testing.T) (*Manager, *mockLeaseStore, *models.Context) {
+	leases := &mockLeaseStore{leases: map[string]*models.ContextLease{}}
+	mgr := NewManager(NewMockContextStore(), WithLeases(leases))
+	c, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "task", AgentID: "agent-1", TenantID: "default", Payload: []byte("todo"),
+		AccessControl: models.AccessControl{Scope: models.ScopeGroup, AllowedIDs: []string{"agent-2", "agent-3"}},
+	})
+	require.NoError(t, err)
+	return mgr, leases, c
+}
+
+func TestManager_LeaseExcludesOtherWriters(t *testing.T) {
+	mgr, _, c := newLeaseManager(t)
+	worker := callerCtx("agent-2")
+
+	lease, err := mgr.AcquireLease(worker, c.ID, "", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "agent-2", lease.HolderID)
+	assert.Equal(t, time.Minute, lease.TTL)
+
+	// Neither other agents nor the owner may take the lease or write
+	_, err = mgr.AcquireLease(callerCtx("agent-3"), c.ID, "", 0)
+	var leased *LeaseError
+	require.True(t, errors.As(err, &leased))
+	assert.Equal(t, "agent-2", leased.Lease.HolderID)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeConflict))
+	_, err = mgr.Update(callerCtx("agent-1"), c.ID, &UpdateRequest{Payload: []byte("done")})
+	assert.True(t, errors.As(err, &leased))
+	err = mgr.Delete(callerCtx("agent-1"), c.ID)
+	assert.True(t, errors.As(err, &leased))
+	results, err := mgr.BatchDelete(callerCtx("agent-1"), models.BatchPartial, []string{c.ID})
+	require.NoError(t, err)
+	assert.True(t, errors.As(results[0].Err, &leased))
+
+	// The holder writes, with or without its fencing token
+	_, err = mgr.Update(worker, c.ID, &UpdateRequest{Payload: []byte("doing")})
+	require.NoError(t, err)
+	_, err = mgr.Update(WithFencingToken(worker, lease.FencingToken), c.ID, &UpdateRequest{Payload: []byte("done")})
+	require.NoError(t, err)
+
+	// Once released anyone may write again, but not under the old token
+	require.NoError(t, mgr.ReleaseLease(worker, c.ID, lease.FencingToken))
+	_, err = mgr.Update(WithFencingToken(worker, lease.FencingToken), c.ID, &UpdateRequest{Payload: []byte("late")})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodePreconditionFailed), "%v", err)
+	_, err = mgr.Update(callerCtx("agent-1"), c.ID, &UpdateRequest{Payload: []byte("reviewed")})
+	assert.NoError(t, err)
+}
+
+func TestManager_LeaseFencingTokensIncrease(t *testing.T) {
+	mgr, leases, c := newLeaseManager(t)
+	worker := callerCtx("agent-2")
+
+	first, err := mgr.AcquireLease(worker, c.ID, "", time.Minute)
+	require.NoError(t, err)
+	renewed, err := mgr.RenewLease(worker, c.ID, first.FencingToken, 2*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, first.FencingToken, renewed.FencingToken)
+	_, err = mgr.RenewLease(callerCtx("agent-3"), c.ID, first.FencingToken, time.Minute)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeConflict))
+
+	// An expired lease cannot be renewed, and its token is superseded by the
+	// next holder's
+	leases.leases[c.ID].ExpiresAt = time.Now().Add(-time.Second)
+	_, err = mgr.RenewLease(worker, c.ID, first.FencingToken, time.Minute)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodePreconditionFailed), "%v", err)
+	second, err := mgr.AcquireLease(callerCtx("agent-3"), c.ID, "", 0)
+	require.NoError(t, err)
+	assert.Greater(t, second.FencingToken, first.FencingToken)
+	_, err = mgr.Update(WithFencingToken(worker, first.FencingToken), c.ID, &UpdateRequest{Payload: []byte("stale")})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeConflict))
+
+	// Admins may break a lease
+	require.NoError(t, mgr.ReleaseLease(callerCtx("admin", string(auth.RoleAdmin)), c.ID, 0))
+	_, err = mgr.Lease(worker, c.ID)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+}
+
+func TestManager_AcquireLeaseValidation(t *testing.T) {
+	mgr, _, c := newLeaseManager(t)
+
+	_, err := mgr.AcquireLease(callerCtx("agent-2"), c.ID, "agent-3", 0)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+	_, err = mgr.AcquireLease(callerCtx("admin", string(auth.RoleAdmin)), c.ID, "agent-9", 0)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	_, err = mgr.AcquireLease(callerCtx("agent-2"), c.ID, "", 2*time.Hour)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	_, err = mgr.AcquireLease(callerCtx("agent-9"), c.ID, "", 0)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+
+	lease, err := mgr.AcquireLease(callerCtx("admin", string(auth.RoleAdmin)), c.ID, "agent-3", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "agent-3", lease.HolderID)
+	assert.Equal(t, 30*time.Second, lease.TTL)
+
+	_, err = NewManager(NewMockContextStore()).AcquireLease(callerCtx("agent-1"), c.ID, "", 0)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeServiceUnavailable))
+}
+
+// guardedContextStore checks lease guards against leases as the Postgres
+// store does, after running beforeWrite to change the lease between the
+// manager's check and the update
+type guardedContextStore struct {
+	storage.ContextStore
+	leases      *mockLeaseStore
+	beforeWrite func()
+}
+
+func (s *guardedContextStore) checkGuard(ctx context.Context, contextID string) error {
+	if s.beforeWrite != nil {
+		s.beforeWrite()
+	}
+	guard, ok := storage.LeaseGuardFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	l, err := s.leases.Get(ctx, contextID)
+	if err != nil {
+		if guard.FencingToken != 0 {
+			return fmt.Errorf("lease no longer held: %w", storage.ErrLeased)
+		}
+		return nil
+	}
+	if l.HolderID != guard.HolderID || (guard.FencingToken != 0 && l.FencingToken != guard.FencingToken) {
+		return fmt.Errorf("leased to %s: %w", l.HolderID, storage.ErrLeased)
+	}
+	return nil
+}
+
+func (s *guardedContextStore) Update(ctx context.Context, c *models.Context) error {
+	if err := s.checkGuard(ctx, c.ID); err != nil {
+		return err
+	}
+	return s.ContextStore.Update(ctx, c)
+}
+
+func TestManager_LeaseExpiresBeforeWrite(t *testing.T) {
+	leases := &mockLeaseStore{leases: map[string]*models.ContextLease{}}
+	store := &guardedContextStore{ContextStore: NewMockContextStore(), leases: leases}
+	mgr := NewManager(store, WithLeases(leases))
+	c, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "task", AgentID: "agent-1", TenantID: "default", Payload: []byte("todo"),
+		AccessControl: models.AccessControl{Scope: models.ScopeGroup, AllowedIDs: []string{"agent-2", "agent-3"}},
+	})
+	require.NoError(t, err)
+	worker := callerCtx("agent-2")
+	lease, err := mgr.AcquireLease(worker, c.ID, "", time.Minute)
+	require.NoError(t, err)
+
+	// The lease expires after the manager checked it
+	store.beforeWrite = func() {
+		store.beforeWrite = nil
+		leases.leases[c.ID].ExpiresAt = time.Now().Add(-time.Second)
+	}
+	_, err = mgr.Update(WithFencingToken(worker, lease.FencingToken), c.ID, &UpdateRequest{Payload: []byte("stale")})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodePreconditionFailed), "%v", err)
+
+	// Another agent takes the lease after an unfenced write was checked
+	store.beforeWrite = func() {
+		store.beforeWrite = nil
+		_, err := mgr.AcquireLease(callerCtx("agent-3"), c.ID, "", time.Minute)
+		require.NoError(t, err)
+	}
+	_, err = mgr.Update(worker, c.ID, &UpdateRequest{Payload: []byte("stale")})
+	var leased *LeaseError
+	require.True(t, errors.As(err, &leased), "%v", err)
+	assert.Equal(t, "agent-3", leased.Lease.HolderID)
+
+	got, err := mgr.Get(callerCtx("agent-1"), c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("todo"), got.Payload)
+}
@@ -0,0 +1,181 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/acb/internal/auth"
+	"github.com/acb/internal/constants"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+)
+
+// Lineage walks the derivation edges of a context towards its ancestors or
+// descendants, up to depth levels (DefaultLineageDepth when zero). The walk
+// only passes through contexts the caller may read, so a context reachable
+// solely through one it may not read is left out.
+func (m *Manager) Lineage(ctx context.Context, contextID string, direction models.LineageDirection, depth int) (*models.Lineage, error) {
+	root, err := m.Get(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+	claims, _ := callerFromContext(ctx)
+	if err := direction.Validate(); err != nil {
+		return nil, acberrors.ValidationError(err.Error()).WithError(err)
+	}
+	if depth == 0 {
+		depth = constants.DefaultLineageDepth
+	}
+	if depth < 1 || depth > constants.MaxLineageDepth {
+		return nil, acberrors.ValidationError(fmt.Sprintf("depth must be between 1 and %d", constants.MaxLineageDepth))
+	}
+
+	lineage := &models.Lineage{
+		ContextID: root.ID,
+		Direction: direction,
+		Depth:     depth,
+		Nodes:     []*models.LineageNode{},
+		Edges:     []*models.LineageEdge{},
+	}
+	visited := map[string]bool{root.ID: true}
+	frontier := []string{root.ID}
+	for level := 1; level <= depth && len(frontier) > 0 && !lineage.Truncated; level++ {
+		edges, err := m.store.ListLinks(ctx, frontier, direction)
+		if err != nil {
+			return nil, err
+		}
+
+		// Contexts seen before are not expanded again, which also stops the
+		// walk going round a cycle
+		var next []string
+		for _, e := range edges {
+			if id := linkTarget(e, direction); !visited[id] && !slices.Contains(next, id) {
+				next = append(next, id)
+			}
+		}
+		readable, err := m.readable(ctx, claims, next)
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = nil
+		for _, id := range next {
+			c, ok := readable[id]
+			if !ok {
+				continue
+			}
+			if len(lineage.Nodes) == constants.MaxLineageNodes {
+				lineage.Truncated = true
+				break
+			}
+			lineage.Nodes = append(lineage.Nodes, &models.LineageNode{Context: c, Depth: level})
+			visited[id] = true
+			frontier = append(frontier, id)
+		}
+		for _, e := range edges {
+			if visited[linkTarget(e, direction)] {
+				lineage.Edges = append(lineage.Edges, e)
+			}
+		}
+	}
+	return lineage, nil
+}
+
+// linkTarget returns the end of e a walk in direction moves to
+func linkTarget(e *models.LineageEdge, direction models.LineageDirection) string {
+	if direction == models.LineageDescendants {
+		return e.ChildID
+	}
+	return e.ParentID
+}
+
+// readable loads the contexts among ids that the caller may read
+func (m *Manager) readable(ctx context.Context, claims *auth.JWTClaims, ids []string) (map[string]*models.Context, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	filters := &storage.ContextFilters{TenantID: claims.TenantID, IDs: ids, Limit: len(ids)}
+	if !claims.HasRole(auth.RoleAdmin) {
+		filters.VisibleTo = claims.AgentID
+	}
+	contexts, err := m.store.List(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Context, len(contexts))
+	for _, c := range contexts {
+		if !c.IsExpired() {
+			byID[c.ID] = c
+		}
+	}
+	return byID, nil
+}
+
+// normalizeLinks returns links with empty relations set to DefaultRelation
+func normalizeLinks(links []models.ContextLink) []models.ContextLink {
+	if links == nil {
+		return nil
+	}
+	normalized := make([]models.ContextLink, len(links))
+	for i, l := range links {
+		if l.Relation == "" {
+			l.Relation = models.DefaultRelation
+		}
+		normalized[i] = l
+	}
+	return normalized
+}
+
+// validateParents checks that every parent c derives from exists in its
+// tenant and can be read by the caller
+func (m *Manager) validateParents(ctx context.Context, claims *auth.JWTClaims, c *models.Context) error {
+	if len(c.DerivedFrom) == 0 {
+		return nil
+	}
+	var ids []string
+	for _, l := range c.DerivedFrom {
+		if !slices.Contains(ids, l.ParentID) {
+			ids = append(ids, l.ParentID)
+		}
+	}
+	parents, err := m.readable(ctx, claims, ids)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, id := range ids {
+		if _, ok := parents[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return acberrors.ValidationError(fmt.Sprintf("unknown parent contexts: %s", strings.Join(missing, ", ")))
+	}
+	return nil
+}
+
+// markStale flags the descendants of a context that was just written as
+// stale, when the writer asked for it. The write has committed by then, so a
+// failure is logged rather than reported as a failed write.
+func (m *Manager) markStale(ctx context.Context, c *models.Context, requested bool, err error) (*models.Context, error) {
+	if err != nil || !requested {
+		return c, err
+	}
+	if _, err := m.store.MarkStale(ctx, c.ID); err != nil {
+		log.Printf("context manager: context %s updated but marking its descendants stale failed: %v", c.ID, err)
+	}
+	return c, nil
+}
+
+// cycleError translates a lineage cycle reported by the store
+func cycleError(err error) error {
+	if errors.Is(err, storage.ErrCycle) {
+		return acberrors.Conflict("derived_from would make the context derive from itself").WithError(err)
+	}
+	return err
+}
@@ -0,0 +1,214 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/acb/internal/constants"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createDerived creates a context owned by agentID derived from parents
+func createDerived(t *testing.T, mgr *Manager, id, agentID string, scope models.ContextScope, parents ...string) {
+	t.Helper()
+	var links []models.ContextLink
+	for _, p := range parents {
+		links = append(links, models.ContextLink{ParentID: p})
+	}
+	_, err := mgr.Create(context.Background(), &CreateRequest{
+		ID:            id,
+		Type:          "note",
+		AgentID:       agentID,
+		TenantID:      "default",
+		Payload:       []byte(id),
+		AccessControl: models.AccessControl{Scope: scope},
+		DerivedFrom:   links,
+	})
+	require.NoError(t, err)
+}
+
+func nodeIDs(lineage *models.Lineage) []string {
+	ids := make([]string, len(lineage.Nodes))
+	for i, n := range lineage.Nodes {
+		ids[i] = n.ID
+	}
+	return ids
+}
+
+func TestManager_CreateDerived(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store)
+	createDerived(t, mgr, "transcript", "agent-1", models.ScopePublic)
+	createDerived(t, mgr, "private", "agent-2", models.ScopePrivate)
+
+	_, err := mgr.Create(context.Background(), &CreateRequest{
+		ID: "summary", Type: "note", AgentID: "agent-1", TenantID: "default",
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+		DerivedFrom:   []models.ContextLink{{ParentID: "transcript", Relation: "summarizes"}, {ParentID: "transcript"}},
+	})
+	require.NoError(t, err)
+	summary, err := mgr.Get(callerCtx("agent-1"), "summary")
+	require.NoError(t, err)
+	assert.Equal(t, []models.ContextLink{{ParentID: "transcript", Relation: "summarizes"}, {ParentID: "transcript", Relation: models.DefaultRelation}}, summary.DerivedFrom)
+
+	// Parents must exist and be readable by the creator
+	for _, parent := range []string{"missing", "private"} {
+		_, err = mgr.Create(context.Background(), &CreateRequest{
+			Type: "note", AgentID: "agent-1", TenantID: "default",
+			AccessControl: models.AccessControl{Scope: models.ScopePublic},
+			DerivedFrom:   []models.ContextLink{{ParentID: parent}},
+		})
+		assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError), parent)
+	}
+}
+
+func TestManager_Lineage(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store)
+	// transcript <- summary <- plan <- report, profile <- plan, and a private
+	// draft derived from plan that only agent-2 can read
+	createDerived(t, mgr, "transcript", "agent-1", models.ScopePublic)
+	createDerived(t, mgr, "profile", "agent-1", models.ScopePublic)
+	createDerived(t, mgr, "summary", "agent-1", models.ScopePublic, "transcript")
+	createDerived(t, mgr, "plan", "agent-1", models.ScopePublic, "summary", "profile")
+	createDerived(t, mgr, "report", "agent-1", models.ScopePublic, "plan")
+	createDerived(t, mgr, "draft", "agent-2", models.ScopePrivate, "plan")
+	ctx := callerCtx("agent-1")
+
+	lineage, err := mgr.Lineage(ctx, "plan", models.LineageAncestors, 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"summary", "profile", "transcript"}, nodeIDs(lineage))
+	assert.Len(t, lineage.Edges, 3)
+	assert.Equal(t, constants.DefaultLineageDepth, lineage.Depth)
+	for _, n := range lineage.Nodes {
+		want := 1
+		if n.ID == "transcript" {
+			want = 2
+		}
+		assert.Equal(t, want, n.Depth, n.ID)
+	}
+
+	lineage, err = mgr.Lineage(ctx, "plan", models.LineageAncestors, 1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"summary", "profile"}, nodeIDs(lineage))
+
+	// Contexts the caller may not read are left out of the walk
+	lineage, err = mgr.Lineage(ctx, "transcript", models.LineageDescendants, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"summary", "plan", "report"}, nodeIDs(lineage))
+	assert.Len(t, lineage.Edges, 3)
+	lineage, err = mgr.Lineage(callerCtx("agent-2"), "transcript", models.LineageDescendants, 5)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"summary", "plan", "report", "draft"}, nodeIDs(lineage))
+
+	for name, tc := range map[string]struct {
+		direction models.LineageDirection
+		depth     int
+		code      acberrors.ErrorCode
+	}{
+		"bad direction": {"sideways", 1, acberrors.ErrorCodeValidationError},
+		"too deep":      {models.LineageAncestors, 11, acberrors.ErrorCodeValidationError},
+		"negative":      {models.LineageAncestors, -1, acberrors.ErrorCodeValidationError},
+	} {
+		_, err := mgr.Lineage(ctx, "plan", tc.direction, tc.depth)
+		assert.True(t, acberrors.Is(err, tc.code), name)
+	}
+	_, err = mgr.Lineage(ctx, "draft", models.LineageAncestors, 1)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+}
+
+func TestManager_LineageCycles(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store)
+	createDerived(t, mgr, "a", "agent-1", models.ScopePublic)
+	createDerived(t, mgr, "b", "agent-1", models.ScopePublic, "a")
+	createDerived(t, mgr, "c", "agent-1", models.ScopePublic, "b")
+	ctx := callerCtx("agent-1")
+
+	// Making a derive from one of its descendants is rejected
+	_, err := mgr.Update(ctx, "a", &UpdateRequest{DerivedFrom: []models.ContextLink{{ParentID: "c"}}})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeConflict))
+	_, err = mgr.Update(ctx, "a", &UpdateRequest{DerivedFrom: []models.ContextLink{{ParentID: "a"}}})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+
+	// A cycle already in the store does not trap the walk
+	store.contexts["a"].DerivedFrom = []models.ContextLink{{ParentID: "c", Relation: models.DefaultRelation}}
+	lineage, err := mgr.Lineage(ctx, "a", models.LineageAncestors, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b"}, nodeIDs(lineage))
+	assert.Len(t, lineage.Edges, 3)
+
+	// Parents can be replaced and removed
+	updated, err := mgr.Update(ctx, "c", &UpdateRequest{DerivedFrom: []models.ContextLink{}})
+	require.NoError(t, err)
+	assert.Empty(t, updated.DerivedFrom)
+
+	// A context recreated under a deleted ID inherits the edges of its
+	// children, so it cannot derive from them
+	require.NoError(t, mgr.Delete(ctx, "a"))
+	_, err = mgr.Create(ctx, &CreateRequest{
+		ID: "a", Type: "note", AgentID: "agent-1", TenantID: "default",
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+		DerivedFrom:   []models.ContextLink{{ParentID: "b"}},
+	})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeConflict))
+}
+
+func TestManager_MarkDescendantsStale(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store)
+	createDerived(t, mgr, "transcript", "agent-1", models.ScopePublic)
+	createDerived(t, mgr, "summary", "agent-2", models.ScopePublic, "transcript")
+	createDerived(t, mgr, "plan", "agent-2", models.ScopePublic, "summary")
+	ctx := callerCtx("agent-1")
+
+	// Only updates that ask for it mark descendants stale
+	_, err := mgr.Update(ctx, "transcript", &UpdateRequest{Payload: []byte("v2")})
+	require.NoError(t, err)
+	assert.False(t, store.contexts["summary"].Stale)
+
+	_, err = mgr.Update(ctx, "transcript", &UpdateRequest{Payload: []byte("v3"), MarkDescendantsStale: true})
+	require.NoError(t, err)
+	for _, id := range []string{"summary", "plan"} {
+		c, err := mgr.Get(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, c.Stale, id)
+	}
+	last := store.events[len(store.events)-1]
+	assert.Equal(t, models.ContextEventStale, last.Type)
+
+	// Patches can mark descendants too, and rewriting a context clears its flag
+	updated, err := mgr.Update(callerCtx("agent-2"), "summary", &UpdateRequest{Payload: []byte(`{"text":"new"}`)})
+	require.NoError(t, err)
+	assert.False(t, updated.Stale)
+	_, err = mgr.Patch(callerCtx("agent-2"), "summary", &PatchRequest{Type: PatchTypeMerge, Patch: []byte(`{"text":"newer"}`), MarkDescendantsStale: true})
+	require.NoError(t, err)
+	assert.True(t, store.contexts["plan"].Stale)
+	assert.False(t, store.contexts["summary"].Stale)
+}
+
+// staleFailingStore fails to mark descendants stale
+type staleFailingStore struct {
+	*MockContextStore
+}
+
+func (s *staleFailingStore) MarkStale(ctx context.Context, contextID string) ([]string, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestManager_MarkDescendantsStaleFailureKeepsWrite(t *testing.T) {
+	store := &staleFailingStore{MockContextStore: NewMockContextStore()}
+	mgr := NewManager(store)
+	createDerived(t, mgr, "transcript", "agent-1", models.ScopePublic)
+	createDerived(t, mgr, "summary", "agent-2", models.ScopePublic, "transcript")
+
+	// The update has committed, so it is reported as such
+	updated, err := mgr.Update(callerCtx("agent-1"), "transcript", &UpdateRequest{Payload: []byte("v2"), MarkDescendantsStale: true})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v2"), updated.Payload)
+	assert.False(t, store.contexts["summary"].Stale)
+}
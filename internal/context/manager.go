@@ -1,33 +1,94 @@
 package context
 
 import (
+	"cmp"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/acb/internal/auth"
 	"github.com/acb/internal/constants"
+	acberrors "github.com/acb/internal/errors"
 	"github.com/acb/internal/models"
 	"github.com/acb/internal/storage"
 	"github.com/google/uuid"
 )
 
+// maxWriteAttempts bounds how often an unconditional write is retried after
+// losing a compare-and-swap race with a concurrent writer
+const maxWriteAttempts = 3
+
+// PayloadValidator checks a context payload against the schema it references
+type PayloadValidator interface {
+	ValidatePayload(ctx context.Context, c *models.Context) error
+}
+
 // Manager provides context management operations
 type Manager struct {
-	store storage.ContextStore
-	// cache storage.ContextCache // Will be implemented later
+	store       storage.ContextStore
+	validator   PayloadValidator
+	embeddings  storage.EmbeddingStore
+	index       *EmbeddingIndex
+	expirations storage.ExpirationStore
+	toucher     *Toucher
+	policies    storage.LifecyclePolicyStore
+	leases      storage.LeaseStore
+	hub         *EventHub
+
+	restoreWindow time.Duration // How long deleted contexts can be restored
+}
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// WithPayloadValidator validates the payload of every created or updated
+// context, typically against the schema registry
+func WithPayloadValidator(v PayloadValidator) Option {
+	return func(m *Manager) {
+		m.validator = v
+	}
 }
 
 // NewManager creates a new context manager
-func NewManager(store storage.ContextStore) *Manager {
-	return &Manager{
-		store: store,
+func NewManager(store storage.ContextStore, opts ...Option) *Manager {
+	m := &Manager{
+		store:         store,
+		restoreWindow: constants.DefaultRestoreWindow * time.Second,
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
 // Create creates a new context
 func (m *Manager) Create(ctx context.Context, req *CreateRequest) (*models.Context, error) {
+	cfg, err := m.loadTypeConfig(ctx, req.TenantID, req.Type)
+	if err != nil {
+		return nil, err
+	}
+	c := newContext(req, cfg)
+	if err := m.validateNew(ctx, c, cfg.policy); err != nil {
+		return nil, err
+	}
+
+	if err := m.store.Create(ctx, c); err != nil {
+		return nil, createError(c.ID, err)
+	}
+	m.notify(c.TenantID, c.ID)
+
+	return c, nil
+}
+
+// newContext builds the first revision of a context from a create request.
+// Expiration settings the request leaves unset come from the expiration
+// defaults of the context type, then from its lifecycle policy. A default TTL
+// is capped at the policy's maximum; an explicit one is checked against it.
+func newContext(req *CreateRequest, cfg *typeConfig) *models.Context {
 	// Generate context ID if not provided
 	contextID := req.ID
 	if contextID == "" {
@@ -38,11 +99,15 @@ func (m *Manager) Create(ctx context.Context, req *CreateRequest) (*models.Conte
 	checksum := calculateChecksum(req.Payload)
 
 	// Calculate expiration
-	ttl := req.TTL
-	if ttl == 0 {
-		ttl = time.Duration(constants.DefaultContextTTL) * time.Second
+	defaults := cmp.Or(cfg.expiration, &models.ExpirationConfig{})
+	lifecycle := cmp.Or(cfg.policy, &models.LifecyclePolicy{})
+	policy := cmp.Or(req.Expiration, defaults.Policy, models.ExpirationFixed)
+	ttl := cmp.Or(req.TTL, defaults.TTL, lifecycle.DefaultTTL, time.Duration(constants.DefaultContextTTL)*time.Second)
+	if req.TTL == 0 && lifecycle.MaxTTL > 0 {
+		ttl = min(ttl, lifecycle.MaxTTL)
 	}
-	expiresAt := time.Now().Add(ttl)
+	maxLifetime := cmp.Or(req.MaxLifetime, defaults.MaxLifetime)
+	now := time.Now()
 
 	c := &models.Context{
 		ID:            contextID,
@@ -52,79 +117,369 @@ func (m *Manager) Create(ctx context.Context, req *CreateRequest) (*models.Conte
 		Payload:       req.Payload,
 		Metadata:      req.Metadata,
 		Version:       req.Version,
+		SchemaID:      req.SchemaID,
 		AccessControl: req.AccessControl,
 		TTL:           ttl,
-		ExpiresAt:     expiresAt,
 		Checksum:      checksum,
-		CreatedAt:     time.Now(),
+		CreatedAt:     now,
+		Revision:      1,
+		UpdatedBy:     req.AgentID,
+		Embedding:     req.Embedding,
+		DerivedFrom:   normalizeLinks(req.DerivedFrom),
+		Expiration:    policy,
+		MaxLifetime:   maxLifetime,
 	}
+	c.ExpiresAt = c.ExpiryFrom(now)
+	return c
+}
 
+// validateNew checks a context about to be created against the model rules
+// and the lifecycle policy of its type
+func (m *Manager) validateNew(ctx context.Context, c *models.Context, policy *models.LifecyclePolicy) error {
 	if err := c.Validate(); err != nil {
-		return nil, fmt.Errorf("validation failed: %w", err)
+		return fmt.Errorf("validation failed: %w", err)
 	}
-
-	if err := m.store.Create(ctx, c); err != nil {
-		return nil, fmt.Errorf("failed to create context: %w", err)
+	if err := checkPolicy(policy, c); err != nil {
+		return err
+	}
+	if err := m.validatePayload(ctx, c); err != nil {
+		return err
 	}
+	if err := m.validateEmbedding(ctx, c); err != nil {
+		return err
+	}
+	// The creating agent must be able to read every parent
+	return m.validateParents(ctx, &auth.JWTClaims{AgentID: c.AgentID, TenantID: c.TenantID}, c)
+}
 
-	return c, nil
+// createError translates a failed store create, reporting an ID that is
+// already taken as a conflict
+func createError(contextID string, err error) error {
+	if storage.IsConflict(err) {
+		return acberrors.Conflict(fmt.Sprintf("context with ID %s already exists", contextID)).WithError(err)
+	}
+	return cycleError(fmt.Errorf("failed to create context: %w", err))
 }
 
-// Get retrieves a context by ID
+// Get retrieves a context by ID if the caller may read it
 func (m *Manager) Get(ctx context.Context, contextID string) (*models.Context, error) {
-	return m.store.Get(ctx, contextID)
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := m.load(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeRead(claims, c); err != nil {
+		return nil, err
+	}
+	m.touchRead(ctx, c)
+	return c, nil
 }
 
-// Update updates an existing context
+// Update updates an existing context if the caller may modify it.
+// When req.ExpectedRevision is set the update only applies if the context is
+// still at that revision; otherwise a *ConflictError is returned.
 func (m *Manager) Update(ctx context.Context, contextID string, req *UpdateRequest) (*models.Context, error) {
-	c, err := m.store.Get(ctx, contextID)
+	c, err := m.mutate(ctx, contextID, req.ExpectedRevision, func(claims *auth.JWTClaims, c *models.Context) error {
+		if req.AccessControl.Scope != "" {
+			if err := authorizeOwner(claims, c); err != nil {
+				return err
+			}
+		}
+
+		// Update fields
+		if len(req.Payload) > 0 {
+			c.Payload = req.Payload
+			c.Checksum = calculateChecksum(req.Payload)
+		}
+		if req.Metadata != nil {
+			c.Metadata = req.Metadata
+		}
+		if req.Version != "" {
+			c.Version = req.Version
+		}
+		if req.SchemaID != "" {
+			c.SchemaID = req.SchemaID
+		}
+		if req.AccessControl.Scope != "" {
+			c.AccessControl = req.AccessControl
+		}
+		if req.Expiration != "" {
+			c.Expiration = req.Expiration
+		}
+		if req.TTL > 0 {
+			c.TTL = req.TTL
+			c.ExpiresAt = c.ExpiryFrom(time.Now())
+		}
+		if req.DerivedFrom != nil {
+			c.DerivedFrom = normalizeLinks(req.DerivedFrom)
+			if err := models.ValidateLinks(c.ID, c.DerivedFrom); err != nil {
+				return acberrors.ValidationError(err.Error()).WithError(err)
+			}
+			if err := m.validateParents(ctx, claims, c); err != nil {
+				return err
+			}
+		}
+		if req.Embedding != nil {
+			c.Embedding = req.Embedding
+			return m.validateEmbedding(ctx, c)
+		}
+		return nil
+	})
+	return m.markStale(ctx, c, req.MarkDescendantsStale, err)
+}
+
+// mutate loads a context, checks that the caller may modify it and that it is
+// at the expected revision, applies fn and stores the result as a new
+// revision with compare-and-swap. Unconditional writes (expected == 0) that
+// race with another writer are retried against the fresh revision. Writing a
+// context clears its stale flag and extends the expiry of sliding contexts.
+// The result must comply with the lifecycle policy of its type, whose
+// revision limit is applied once the write succeeds. While the context is
+// leased only the lease holder may write it.
+func (m *Manager) mutate(ctx context.Context, contextID string, expected int, fn func(claims *auth.JWTClaims, c *models.Context) error) (*models.Context, error) {
+	claims, err := callerFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update fields
-	if len(req.Payload) > 0 {
-		c.Payload = req.Payload
-		c.Checksum = calculateChecksum(req.Payload)
+	for attempt := 1; ; attempt++ {
+		c, err := m.load(ctx, contextID)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeWrite(claims, c); err != nil {
+			return nil, err
+		}
+		if err := m.checkLease(ctx, claims, c); err != nil {
+			return nil, err
+		}
+		if expected != 0 && c.Revision != expected {
+			return nil, conflictError(contextID, expected, c.Revision)
+		}
+		if err := fn(claims, c); err != nil {
+			return nil, err
+		}
+
+		c.UpdatedBy = claims.AgentID
+		c.Stale = false
+		if c.Sliding() {
+			c.ExpiresAt = c.ExpiryFrom(time.Now())
+		}
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("validation failed: %w", err)
+		}
+		policy, err := m.lifecyclePolicy(ctx, c.TenantID, c.Type)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPolicy(policy, c); err != nil {
+			return nil, err
+		}
+		if err := m.validatePayload(ctx, c); err != nil {
+			return nil, err
+		}
+
+		loaded := c.Revision
+		err = m.store.Update(m.guardWrite(ctx, claims), c)
+		if err == nil {
+			m.pruneRevisions(ctx, policy, c)
+			m.notify(c.TenantID, c.ID)
+			return c, nil
+		}
+		if storage.IsLeased(err) {
+			return nil, m.leaseWriteError(ctx, claims, c)
+		}
+		if !storage.IsConflict(err) {
+			return nil, cycleError(fmt.Errorf("failed to update context: %w", err))
+		}
+		if expected != 0 || attempt == maxWriteAttempts {
+			return nil, m.conflict(ctx, contextID, loaded)
+		}
+	}
+}
+
+// Delete removes a context if the caller owns it, or may write it when the
+// lifecycle policy of its type allows non-owners to delete
+func (m *Manager) Delete(ctx context.Context, contextID string) error {
+	return m.DeleteAtRevision(ctx, contextID, 0)
+}
+
+// DeleteAtRevision removes a context if the caller may delete it, holds any
+// lease on it and, when expected is non-zero, it is still at that revision
+func (m *Manager) DeleteAtRevision(ctx context.Context, contextID string, expected int) error {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	c, err := m.load(ctx, contextID)
+	if err != nil {
+		return err
 	}
-	if req.Metadata != nil {
-		c.Metadata = req.Metadata
+	policy, err := m.lifecyclePolicy(ctx, c.TenantID, c.Type)
+	if err != nil {
+		return err
 	}
-	if req.Version != "" {
-		c.Version = req.Version
+	if err := authorizeDelete(claims, c, policy); err != nil {
+		return err
 	}
-	if req.AccessControl.Scope != "" {
-		c.AccessControl = req.AccessControl
+	if err := m.checkLease(ctx, claims, c); err != nil {
+		return err
 	}
-	if req.TTL > 0 {
-		c.TTL = req.TTL
-		c.ExpiresAt = time.Now().Add(req.TTL)
+	if expected != 0 && c.Revision != expected {
+		return conflictError(contextID, expected, c.Revision)
 	}
 
-	if err := c.Validate(); err != nil {
-		return nil, fmt.Errorf("validation failed: %w", err)
+	err = m.store.DeleteAtRevision(m.guardWrite(ctx, claims), contextID, expected)
+	if storage.IsLeased(err) {
+		return m.leaseWriteError(ctx, claims, c)
+	}
+	if storage.IsConflict(err) {
+		return m.conflict(ctx, contextID, expected)
+	}
+	if storage.IsNotFound(err) {
+		return acberrors.NotFound("context not found").WithError(err)
+	}
+	if err == nil {
+		m.notify(c.TenantID, c.ID)
 	}
+	return err
+}
 
-	if err := m.store.Update(ctx, c); err != nil {
-		return nil, fmt.Errorf("failed to update context: %w", err)
+// conflict builds the error for a lost compare-and-swap, looking up the
+// revision the context has moved on to
+func (m *Manager) conflict(ctx context.Context, contextID string, expected int) error {
+	current := 0
+	if c, err := m.store.Get(ctx, contextID); err == nil {
+		current = c.Revision
 	}
+	return conflictError(contextID, expected, current)
+}
 
-	return c, nil
+// List retrieves the contexts matching filters that the caller may read.
+// Tenant and visibility restrictions are applied by the store query.
+func (m *Manager) List(ctx context.Context, filters *storage.ContextFilters) ([]*models.Context, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := storage.ContextFilters{Limit: constants.DefaultPageLimit}
+	if filters != nil {
+		scoped = *filters
+	}
+	scoped.TenantID = claims.TenantID
+	scoped.VisibleTo = ""
+	if !claims.HasRole(auth.RoleAdmin) {
+		scoped.VisibleTo = claims.AgentID
+	}
+	return m.store.List(ctx, &scoped)
 }
 
-// Delete removes a context
-func (m *Manager) Delete(ctx context.Context, contextID string) error {
-	return m.store.Delete(ctx, contextID)
+// Count returns the number of contexts matching filters that the caller may
+// read, scoped like List
+func (m *Manager) Count(ctx context.Context, filters *storage.ContextFilters) (int, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var scoped storage.ContextFilters
+	if filters != nil {
+		scoped = *filters
+	}
+	scoped.TenantID = claims.TenantID
+	scoped.VisibleTo = ""
+	if !claims.HasRole(auth.RoleAdmin) {
+		scoped.VisibleTo = claims.AgentID
+	}
+	return m.store.Count(ctx, &scoped)
 }
 
-// List retrieves contexts with filters
-func (m *Manager) List(ctx context.Context, filters *storage.ContextFilters) ([]*models.Context, error) {
-	return m.store.List(ctx, filters)
+// Search runs a full-text query over the contexts matching filters that the
+// caller may read, most relevant first, scoped like List
+func (m *Manager) Search(ctx context.Context, query string, filters *storage.ContextFilters) ([]*models.ContextSearchResult, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, acberrors.ValidationError("search query is required")
+	}
+	if utf8.RuneCountInString(query) > constants.MaxSearchQueryLength {
+		return nil, acberrors.ValidationError(fmt.Sprintf("search query exceeds %d characters", constants.MaxSearchQueryLength))
+	}
+
+	scoped := storage.ContextFilters{Limit: constants.DefaultPageLimit}
+	if filters != nil {
+		scoped = *filters
+	}
+	scoped.TenantID = claims.TenantID
+	scoped.VisibleTo = ""
+	if !claims.HasRole(auth.RoleAdmin) {
+		scoped.VisibleTo = claims.AgentID
+	}
+	return m.store.Search(ctx, query, &scoped)
+}
+
+// ListEvents retrieves logged context events in the caller's tenant for
+// contexts the caller could read when the event was recorded
+func (m *Manager) ListEvents(ctx context.Context, filters *storage.EventFilters) ([]*models.ContextEvent, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := storage.EventFilters{Limit: constants.DefaultPageLimit}
+	if filters != nil {
+		scoped = *filters
+	}
+	scoped.TenantID = claims.TenantID
+	scoped.VisibleTo = ""
+	if !claims.HasRole(auth.RoleAdmin) {
+		scoped.VisibleTo = claims.AgentID
+	}
+	return m.store.ListEvents(ctx, &scoped)
 }
 
-// DeleteExpired removes expired contexts
-func (m *Manager) DeleteExpired(ctx context.Context) (int, error) {
-	return m.store.DeleteExpired(ctx)
+// EventBounds returns the oldest retained event ID and the newest event ID
+// that can be listed. Change streams use them to start at the head of the log
+// and to detect consumers resuming from pruned events.
+func (m *Manager) EventBounds(ctx context.Context) (first, last int64, err error) {
+	if _, err := callerFromContext(ctx); err != nil {
+		return 0, 0, err
+	}
+	return m.store.EventBounds(ctx)
+}
+
+// load fetches a context, translating storage misses into NOT_FOUND.
+// Expired contexts are reported as not found before the janitor removes them.
+func (m *Manager) load(ctx context.Context, contextID string) (*models.Context, error) {
+	c, err := m.store.Get(ctx, contextID)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return nil, acberrors.NotFound("context not found").WithError(err)
+		}
+		return nil, err
+	}
+	if m.withPending(c).IsExpired() {
+		return nil, acberrors.NotFound("context not found")
+	}
+	return c, nil
+}
+
+// validatePayload checks c against its schema when a validator is configured
+func (m *Manager) validatePayload(ctx context.Context, c *models.Context) error {
+	if m.validator == nil {
+		return nil
+	}
+	return m.validator.ValidatePayload(ctx, c)
 }
 
 // calculateChecksum calculates SHA-256 checksum
@@ -142,15 +497,26 @@ type CreateRequest struct {
 	Payload       []byte
 	Metadata      map[string]string
 	Version       string
+	SchemaID      string // Schema the payload must satisfy, if any
 	AccessControl models.AccessControl
 	TTL           time.Duration
+	Expiration    models.ExpirationPolicy // Defaults to the type's policy, then ExpirationFixed
+	MaxLifetime   time.Duration           // Caps how far sliding can move the expiry; defaults to the type's
+	Embedding     []float32               // Optional; the type must declare its embedding dimension
+	DerivedFrom   []models.ContextLink
 }
 
 // UpdateRequest contains context update data
 type UpdateRequest struct {
-	Payload       []byte
-	Metadata      map[string]string
-	Version       string
-	AccessControl models.AccessControl
-	TTL           time.Duration
+	Payload              []byte
+	Metadata             map[string]string
+	Version              string
+	SchemaID             string // Moves the context to another schema, typically a newer version
+	AccessControl        models.AccessControl
+	TTL                  time.Duration
+	Expiration           models.ExpirationPolicy // Replaces the expiration policy when set
+	Embedding            []float32               // Replaces the embedding when set
+	DerivedFrom          []models.ContextLink    // Replaces the parents when non-nil; empty removes them
+	MarkDescendantsStale bool                    // Flag every context derived from this one as stale
+	ExpectedRevision     int                     // When non-zero, fail with a conflict unless the context is at this revision
 }
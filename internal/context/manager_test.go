@@ -2,59 +2,243 @@ package context
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
 	"github.com/acb/internal/models"
 	"github.com/acb/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// callerCtx returns a context carrying claims for an agent in the default tenant
+func callerCtx(agentID string, roles ...string) context.Context {
+	return auth.ContextWithClaims(context.Background(), &auth.JWTClaims{
+		AgentID:  agentID,
+		TenantID: "default",
+		Roles:    roles,
+	})
+}
+
 // MockContextStore is a mock implementation for testing
 type MockContextStore struct {
-	contexts map[string]*models.Context
+	contexts   map[string]*models.Context
+	deleted    map[string]*models.Context
+	revisions  map[string][]*models.ContextRevision
+	events     []*models.ContextEvent
+	eventSeq   int64
+	lastEvents *storage.EventFilters
+	lastSearch *storage.ContextFilters
+	touches    int // Calls to Touch
 }
 
 func NewMockContextStore() *MockContextStore {
 	return &MockContextStore{
-		contexts: make(map[string]*models.Context),
+		contexts:  make(map[string]*models.Context),
+		deleted:   make(map[string]*models.Context),
+		revisions: make(map[string][]*models.ContextRevision),
+	}
+}
+
+// recordRevision appends a snapshot of c as its next revision
+func (m *MockContextStore) recordRevision(c *models.Context) {
+	c.Revision = len(m.revisions[c.ID]) + 1
+	m.revisions[c.ID] = append(m.revisions[c.ID], &models.ContextRevision{
+		ContextID:     c.ID,
+		Revision:      c.Revision,
+		Payload:       c.Payload,
+		Metadata:      c.Metadata,
+		Version:       c.Version,
+		AccessControl: c.AccessControl,
+		Checksum:      c.Checksum,
+		AuthorID:      c.UpdatedBy,
+		CreatedAt:     time.Now(),
+	})
+}
+
+// recordEvent appends an event for c to the event log
+func (m *MockContextStore) recordEvent(eventType models.ContextEventType, c *models.Context) *models.ContextEvent {
+	m.eventSeq++
+	e := &models.ContextEvent{
+		ID:            m.eventSeq,
+		Type:          eventType,
+		TenantID:      c.TenantID,
+		ContextID:     c.ID,
+		ContextType:   c.Type,
+		AgentID:       c.AgentID,
+		Revision:      c.Revision,
+		Metadata:      c.Metadata,
+		AccessControl: c.AccessControl,
+		OccurredAt:    time.Now(),
 	}
+	m.events = append(m.events, e)
+	return e
 }
 
 func (m *MockContextStore) Create(ctx context.Context, c *models.Context) error {
 	if _, exists := m.contexts[c.ID]; exists {
 		return fmt.Errorf("context already exists")
 	}
-	m.contexts[c.ID] = c
+	if _, deleted := m.deleted[c.ID]; deleted {
+		delete(m.deleted, c.ID)
+		delete(m.revisions, c.ID)
+	}
+	descendants := m.descendants(c.ID)
+	for _, l := range c.DerivedFrom {
+		if descendants[l.ParentID] {
+			return fmt.Errorf("context %s derives from %s: %w", l.ParentID, c.ID, storage.ErrCycle)
+		}
+	}
+	m.recordRevision(c)
+	stored := *c
+	m.contexts[c.ID] = &stored
+	m.recordEvent(models.ContextEventCreated, c)
 	return nil
 }
 
+// CreateBatch checks every context before writing so that an atomic batch
+// writes nothing when one fails
+func (m *MockContextStore) CreateBatch(ctx context.Context, contexts []*models.Context, atomic bool) ([]error, error) {
+	errs := make([]error, len(contexts))
+	for i, c := range contexts {
+		if _, exists := m.contexts[c.ID]; exists {
+			errs[i] = fmt.Errorf("context with ID %s already exists: %w", c.ID, storage.ErrConflict)
+			if atomic {
+				return nil, &storage.BatchError{Index: i, Err: errs[i]}
+			}
+		}
+	}
+	for i, c := range contexts {
+		if errs[i] == nil {
+			errs[i] = m.Create(ctx, c)
+		}
+	}
+	return errs, nil
+}
+
 func (m *MockContextStore) Get(ctx context.Context, contextID string) (*models.Context, error) {
 	c, exists := m.contexts[contextID]
 	if !exists {
-		return nil, fmt.Errorf("context not found")
+		return nil, fmt.Errorf("context not found: %w", storage.ErrNotFound)
 	}
-	return c, nil
+	cp := *c
+	return &cp, nil
 }
 
 func (m *MockContextStore) Update(ctx context.Context, c *models.Context) error {
-	if _, exists := m.contexts[c.ID]; !exists {
-		return fmt.Errorf("context not found")
+	stored, exists := m.contexts[c.ID]
+	if !exists {
+		return fmt.Errorf("context not found: %w", storage.ErrNotFound)
+	}
+	if c.Revision != 0 && c.Revision != stored.Revision {
+		return fmt.Errorf("context is at revision %d: %w", stored.Revision, storage.ErrConflict)
 	}
-	m.contexts[c.ID] = c
+	descendants := m.descendants(c.ID)
+	for _, l := range c.DerivedFrom {
+		if descendants[l.ParentID] {
+			return fmt.Errorf("context %s derives from %s: %w", l.ParentID, c.ID, storage.ErrCycle)
+		}
+	}
+	m.recordRevision(c)
+	cp := *c
+	m.contexts[c.ID] = &cp
+	m.recordEvent(models.ContextEventUpdated, c)
 	return nil
 }
 
 func (m *MockContextStore) Delete(ctx context.Context, contextID string) error {
-	if _, exists := m.contexts[contextID]; !exists {
-		return fmt.Errorf("context not found")
+	return m.DeleteAtRevision(ctx, contextID, 0)
+}
+
+func (m *MockContextStore) DeleteAtRevision(ctx context.Context, contextID string, revision int) error {
+	stored, exists := m.contexts[contextID]
+	if !exists {
+		return fmt.Errorf("context not found: %w", storage.ErrNotFound)
+	}
+	if revision != 0 && revision != stored.Revision {
+		return fmt.Errorf("context is at revision %d: %w", stored.Revision, storage.ErrConflict)
 	}
 	delete(m.contexts, contextID)
+	deletedAt := time.Now()
+	stored.DeletedAt = &deletedAt
+	m.deleted[contextID] = stored
+	m.recordEvent(models.ContextEventDeleted, stored)
+	return nil
+}
+
+func (m *MockContextStore) DeleteBatch(ctx context.Context, contextIDs []string, atomic bool) ([]error, error) {
+	errs := make([]error, len(contextIDs))
+	for i, id := range contextIDs {
+		if _, exists := m.contexts[id]; !exists {
+			errs[i] = fmt.Errorf("context not found: %w", storage.ErrNotFound)
+			if atomic {
+				return nil, &storage.BatchError{Index: i, Err: errs[i]}
+			}
+		}
+	}
+	for i, id := range contextIDs {
+		if errs[i] == nil {
+			errs[i] = m.DeleteAtRevision(ctx, id, 0)
+		}
+	}
+	return errs, nil
+}
+
+func (m *MockContextStore) GetDeleted(ctx context.Context, contextID string) (*models.Context, error) {
+	c, exists := m.deleted[contextID]
+	if !exists {
+		return nil, fmt.Errorf("deleted context not found: %w", storage.ErrNotFound)
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (m *MockContextStore) ListDeleted(ctx context.Context, tenantID string, limit int) ([]*models.Context, error) {
+	var contexts []*models.Context
+	for _, c := range m.deleted {
+		if c.TenantID == tenantID {
+			contexts = append(contexts, c)
+		}
+	}
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].DeletedAt.After(*contexts[j].DeletedAt) })
+	if len(contexts) > limit {
+		contexts = contexts[:limit]
+	}
+	return contexts, nil
+}
+
+func (m *MockContextStore) Restore(ctx context.Context, contextID string) error {
+	c, exists := m.deleted[contextID]
+	if !exists {
+		return fmt.Errorf("deleted context not found: %w", storage.ErrNotFound)
+	}
+	delete(m.deleted, contextID)
+	c.DeletedAt = nil
+	m.contexts[contextID] = c
+	m.recordEvent(models.ContextEventRestored, c)
 	return nil
 }
 
+func (m *MockContextStore) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int, error) {
+	purged := 0
+	for id, c := range m.deleted {
+		if purged < limit && c.DeletedAt.Before(before) {
+			delete(m.deleted, id)
+			delete(m.revisions, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
 func (m *MockContextStore) List(ctx context.Context, filters *storage.ContextFilters) ([]*models.Context, error) {
 	var contexts []*models.Context
 	for _, c := range m.contexts {
@@ -67,20 +251,172 @@ func (m *MockContextStore) List(ctx context.Context, filters *storage.ContextFil
 		if filters != nil && filters.AgentID != "" && c.AgentID != filters.AgentID {
 			continue
 		}
+		if filters != nil && filters.VisibleTo != "" && !canRead(&auth.JWTClaims{AgentID: filters.VisibleTo, TenantID: c.TenantID}, c) {
+			continue
+		}
+		if filters != nil && filters.IDs != nil && !slices.Contains(filters.IDs, c.ID) {
+			continue
+		}
 		contexts = append(contexts, c)
 	}
 	return contexts, nil
 }
 
-func (m *MockContextStore) DeleteExpired(ctx context.Context) (int, error) {
-	count := 0
-	for id, c := range m.contexts {
-		if c.IsExpired() {
-			delete(m.contexts, id)
-			count++
+func (m *MockContextStore) Count(ctx context.Context, filters *storage.ContextFilters) (int, error) {
+	contexts, err := m.List(ctx, filters)
+	return len(contexts), err
+}
+
+func (m *MockContextStore) Search(ctx context.Context, query string, filters *storage.ContextFilters) ([]*models.ContextSearchResult, error) {
+	m.lastSearch = filters
+	contexts, _ := m.List(ctx, filters)
+	var results []*models.ContextSearchResult
+	for _, c := range contexts {
+		if strings.Contains(strings.ToLower(string(c.Payload)), strings.ToLower(query)) {
+			results = append(results, &models.ContextSearchResult{Context: c, Rank: 1})
+		}
+	}
+	return results, nil
+}
+
+func (m *MockContextStore) DeleteExpired(ctx context.Context, before time.Time, limit int) ([]*models.ContextEvent, error) {
+	var expired []*models.Context
+	for _, c := range m.contexts {
+		if !c.ExpiresAt.IsZero() && c.ExpiresAt.Before(before) {
+			expired = append(expired, c)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ExpiresAt.Before(expired[j].ExpiresAt) })
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	var events []*models.ContextEvent
+	for _, c := range expired {
+		delete(m.contexts, c.ID)
+		events = append(events, m.recordEvent(models.ContextEventExpired, c))
+	}
+	return events, nil
+}
+
+func (m *MockContextStore) ListEvents(ctx context.Context, filters *storage.EventFilters) ([]*models.ContextEvent, error) {
+	m.lastEvents = filters
+	var events []*models.ContextEvent
+	for _, e := range m.events {
+		if e.TenantID == filters.TenantID && e.ID > filters.AfterID {
+			events = append(events, e)
 		}
 	}
-	return count, nil
+	return events, nil
+}
+
+func (m *MockContextStore) PruneEvents(ctx context.Context, before time.Time, limit int) (int, error) {
+	pruned := 0
+	for pruned < len(m.events) && pruned < limit && m.events[pruned].OccurredAt.Before(before) {
+		pruned++
+	}
+	m.events = m.events[pruned:]
+	return pruned, nil
+}
+
+func (m *MockContextStore) EventBounds(ctx context.Context) (int64, int64, error) {
+	if len(m.events) == 0 {
+		return 0, 0, nil
+	}
+	return m.events[0].ID, m.events[len(m.events)-1].ID, nil
+}
+
+func (m *MockContextStore) ListRevisions(ctx context.Context, contextID string) ([]*models.ContextRevision, error) {
+	revisions := m.revisions[contextID]
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("context not found: %w", storage.ErrNotFound)
+	}
+	out := make([]*models.ContextRevision, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0 && revisions[i] != nil; i-- {
+		out = append(out, revisions[i])
+	}
+	return out, nil
+}
+
+func (m *MockContextStore) GetRevision(ctx context.Context, contextID string, revision int) (*models.ContextRevision, error) {
+	revisions := m.revisions[contextID]
+	if revision < 1 || revision > len(revisions) || revisions[revision-1] == nil {
+		return nil, fmt.Errorf("revision not found: %w", storage.ErrNotFound)
+	}
+	return revisions[revision-1], nil
+}
+
+// PruneRevisions clears pruned revisions in place so that revision numbers
+// keep indexing the history
+func (m *MockContextStore) PruneRevisions(ctx context.Context, contextID string, keep int) (int, error) {
+	revisions := m.revisions[contextID]
+	pruned := 0
+	for i := 0; i < len(revisions)-keep; i++ {
+		if revisions[i] != nil {
+			revisions[i] = nil
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (m *MockContextStore) ListLinks(ctx context.Context, contextIDs []string, direction models.LineageDirection) ([]*models.LineageEdge, error) {
+	var edges []*models.LineageEdge
+	for _, c := range m.contexts {
+		for _, l := range c.DerivedFrom {
+			from := c.ID
+			if direction == models.LineageDescendants {
+				from = l.ParentID
+			}
+			if slices.Contains(contextIDs, from) {
+				edges = append(edges, &models.LineageEdge{ChildID: c.ID, ParentID: l.ParentID, Relation: l.Relation})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].ChildID+edges[i].ParentID < edges[j].ChildID+edges[j].ParentID })
+	return edges, nil
+}
+
+func (m *MockContextStore) MarkStale(ctx context.Context, contextID string) ([]string, error) {
+	var ids []string
+	for id := range m.descendants(contextID) {
+		if c := m.contexts[id]; id != contextID && !c.Stale {
+			c.Stale = true
+			m.recordEvent(models.ContextEventStale, c)
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (m *MockContextStore) Touch(ctx context.Context, expiries map[string]time.Time) (int, error) {
+	m.touches++
+	n := 0
+	for id, expiresAt := range expiries {
+		if c, ok := m.contexts[id]; ok && !c.IsExpired() && c.ExpiresAt.Before(expiresAt) {
+			c.ExpiresAt = expiresAt
+			n++
+		}
+	}
+	return n, nil
+}
+
+// descendants returns the IDs of the contexts derived from contextID,
+// directly or transitively
+func (m *MockContextStore) descendants(contextID string) map[string]bool {
+	found := map[string]bool{}
+	for frontier := []string{contextID}; len(frontier) > 0; {
+		edges, _ := m.ListLinks(context.Background(), frontier, models.LineageDescendants)
+		frontier = nil
+		for _, e := range edges {
+			if !found[e.ChildID] {
+				found[e.ChildID] = true
+				frontier = append(frontier, e.ChildID)
+			}
+		}
+	}
+	return found
 }
 
 func TestManager_Create(t *testing.T) {
@@ -122,7 +458,7 @@ func TestManager_Get(t *testing.T) {
 	created, err := mgr.Create(context.Background(), req)
 	require.NoError(t, err)
 
-	got, err := mgr.Get(context.Background(), created.ID)
+	got, err := mgr.Get(callerCtx("agent-1"), created.ID)
 	require.NoError(t, err)
 	assert.Equal(t, created.ID, got.ID)
 }
@@ -149,7 +485,7 @@ func TestManager_Update(t *testing.T) {
 		Version: "2.0",
 	}
 
-	updated, err := mgr.Update(context.Background(), created.ID, updateReq)
+	updated, err := mgr.Update(callerCtx("agent-1"), created.ID, updateReq)
 	require.NoError(t, err)
 	assert.Equal(t, "updated data", string(updated.Payload))
 	assert.Equal(t, "2.0", updated.Version)
@@ -172,10 +508,10 @@ func TestManager_Delete(t *testing.T) {
 	created, err := mgr.Create(context.Background(), req)
 	require.NoError(t, err)
 
-	err = mgr.Delete(context.Background(), created.ID)
+	err = mgr.Delete(callerCtx("agent-1"), created.ID)
 	require.NoError(t, err)
 
-	_, err = mgr.Get(context.Background(), created.ID)
+	_, err = mgr.Get(callerCtx("agent-1"), created.ID)
 	assert.Error(t, err)
 }
 
@@ -201,37 +537,89 @@ func TestManager_List(t *testing.T) {
 	filters := &storage.ContextFilters{
 		TenantID: "default",
 	}
-	contexts, err := mgr.List(context.Background(), filters)
+	contexts, err := mgr.List(callerCtx("agent-1"), filters)
 	require.NoError(t, err)
 	assert.GreaterOrEqual(t, len(contexts), 3)
 }
 
-func TestManager_DeleteExpired(t *testing.T) {
+func TestManager_Search(t *testing.T) {
 	mockStore := NewMockContextStore()
 	mgr := NewManager(mockStore)
 
-	// Create expired context
-	req := &CreateRequest{
-		Type:     "user-profile",
-		AgentID:  "agent-1",
-		TenantID: "default",
-		Payload:  []byte("test data"),
-		AccessControl: models.AccessControl{
-			Scope: models.ScopePublic,
-		},
-		TTL: -1 * time.Hour, // Expired
+	for _, payload := range []string{"quarterly invoice", "shipping label"} {
+		_, err := mgr.Create(context.Background(), &CreateRequest{
+			Type:          "note",
+			AgentID:       "agent-1",
+			TenantID:      "default",
+			Payload:       []byte(payload),
+			AccessControl: models.AccessControl{Scope: models.ScopePrivate},
+		})
+		require.NoError(t, err)
 	}
 
-	created, err := mgr.Create(context.Background(), req)
+	results, err := mgr.Search(callerCtx("agent-1"), "  invoice ", &storage.ContextFilters{TenantID: "other", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []byte("quarterly invoice"), results[0].Payload)
+	assert.Equal(t, &storage.ContextFilters{TenantID: "default", VisibleTo: "agent-1", Limit: 10}, mockStore.lastSearch)
+
+	// Private contexts of other agents are not searched
+	results, err = mgr.Search(callerCtx("agent-2"), "invoice", nil)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	_, err = mgr.Search(callerCtx("admin", string(auth.RoleAdmin)), "invoice", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", mockStore.lastSearch.VisibleTo)
+
+	_, err = mgr.Search(callerCtx("agent-1"), "   ", nil)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	_, err = mgr.Search(callerCtx("agent-1"), strings.Repeat("x", 257), nil)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+
+	_, err = mgr.Search(context.Background(), "invoice", nil)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeUnauthorized))
+}
+
+func TestManager_ExpiredIsNotFound(t *testing.T) {
+	mockStore := NewMockContextStore()
+	mgr := NewManager(mockStore)
+	ctx := callerCtx("agent-1")
+
+	created, err := mgr.Create(ctx, &CreateRequest{
+		Type:          "user-profile",
+		AgentID:       "agent-1",
+		TenantID:      "default",
+		Payload:       []byte("test data"),
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+		TTL:           time.Hour,
+	})
 	require.NoError(t, err)
 
-	// Manually set expired time
-	created.ExpiresAt = time.Now().Add(-1 * time.Hour)
-	mockStore.contexts[created.ID] = created
+	// Expired but not yet swept by the janitor
+	mockStore.contexts[created.ID].ExpiresAt = time.Now().Add(-time.Minute)
+
+	_, err = mgr.Get(ctx, created.ID)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	_, err = mgr.Update(ctx, created.ID, &UpdateRequest{Payload: []byte("revived")})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	assert.True(t, acberrors.Is(mgr.Delete(ctx, created.ID), acberrors.ErrorCodeNotFound))
+}
+
+func TestManager_ListEvents(t *testing.T) {
+	mockStore := NewMockContextStore()
+	mgr := NewManager(mockStore)
+
+	_, err := mgr.ListEvents(callerCtx("agent-2"), &storage.EventFilters{TenantID: "other", AfterID: 7, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, &storage.EventFilters{TenantID: "default", AfterID: 7, VisibleTo: "agent-2", Limit: 10}, mockStore.lastEvents)
 
-	count, err := mgr.DeleteExpired(context.Background())
+	_, err = mgr.ListEvents(callerCtx("admin", string(auth.RoleAdmin)), nil)
 	require.NoError(t, err)
-	assert.GreaterOrEqual(t, count, 1)
+	assert.Equal(t, "", mockStore.lastEvents.VisibleTo)
+
+	_, err = mgr.ListEvents(context.Background(), nil)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeUnauthorized))
 }
 
 func TestCalculateChecksum(t *testing.T) {
@@ -265,7 +653,7 @@ func TestManager_Update_TTLAndAccessControl(t *testing.T) {
 
 	// Now update without payload but with TTL and AccessControl change
 	before := created.ExpiresAt
-	updated, err := mgr.Update(context.Background(), created.ID, &UpdateRequest{
+	updated, err := mgr.Update(callerCtx("agent-1"), created.ID, &UpdateRequest{
 		AccessControl: models.AccessControl{Scope: models.ScopePublic},
 		TTL:           2 * time.Minute,
 	})
@@ -273,3 +661,428 @@ func TestManager_Update_TTLAndAccessControl(t *testing.T) {
 	assert.Equal(t, models.ScopePublic, updated.AccessControl.Scope)
 	assert.True(t, updated.ExpiresAt.After(before))
 }
+
+func TestManager_AccessControlMatrix(t *testing.T) {
+	const (
+		ok        = acberrors.ErrorCode("")
+		notFound  = acberrors.ErrorCodeNotFound
+		forbidden = acberrors.ErrorCodeForbidden
+	)
+
+	callers := map[string]*auth.JWTClaims{
+		"owner":        {AgentID: "owner", TenantID: "default"},
+		"member":       {AgentID: "member", TenantID: "default"},
+		"stranger":     {AgentID: "stranger", TenantID: "default"},
+		"other-tenant": {AgentID: "member", TenantID: "other"},
+		"admin":        {AgentID: "root", TenantID: "default", Roles: []string{string(auth.RoleAdmin)}},
+		"other-admin":  {AgentID: "root", TenantID: "other", Roles: []string{string(auth.RoleAdmin)}},
+	}
+
+	type expectation struct {
+		read, write, acl, del acberrors.ErrorCode
+		listed                bool
+	}
+	full := expectation{ok, ok, ok, ok, true}
+	hidden := expectation{notFound, notFound, notFound, notFound, false}
+	readOnly := expectation{ok, forbidden, forbidden, forbidden, true}
+	collaborator := expectation{ok, ok, forbidden, forbidden, true}
+
+	matrix := map[models.ContextScope]map[string]expectation{
+		models.ScopePublic: {
+			"owner": full, "member": readOnly, "stranger": readOnly,
+			"other-tenant": hidden, "admin": full, "other-admin": hidden,
+		},
+		models.ScopePrivate: {
+			"owner": full, "member": hidden, "stranger": hidden,
+			"other-tenant": hidden, "admin": full, "other-admin": hidden,
+		},
+		models.ScopeGroup: {
+			"owner": full, "member": collaborator, "stranger": hidden,
+			"other-tenant": hidden, "admin": full, "other-admin": hidden,
+		},
+		models.ScopeShared: {
+			"owner": full, "member": collaborator, "stranger": hidden,
+			"other-tenant": hidden, "admin": full, "other-admin": hidden,
+		},
+	}
+
+	assertCode := func(t *testing.T, op string, want acberrors.ErrorCode, err error) {
+		t.Helper()
+		if want == ok {
+			assert.NoError(t, err, op)
+			return
+		}
+		assert.True(t, acberrors.Is(err, want), "%s: expected %s, got %v", op, want, err)
+	}
+
+	for scope, row := range matrix {
+		for name, want := range row {
+			t.Run(string(scope)+"/"+name, func(t *testing.T) {
+				store := NewMockContextStore()
+				mgr := NewManager(store)
+				created, err := mgr.Create(context.Background(), &CreateRequest{
+					Type:          "doc",
+					AgentID:       "owner",
+					TenantID:      "default",
+					Payload:       []byte("p"),
+					AccessControl: models.AccessControl{Scope: scope, AllowedIDs: []string{"member"}},
+					TTL:           time.Minute,
+				})
+				require.NoError(t, err)
+				ctx := auth.ContextWithClaims(context.Background(), callers[name])
+
+				_, err = mgr.Get(ctx, created.ID)
+				assertCode(t, "read", want.read, err)
+
+				_, err = mgr.Update(ctx, created.ID, &UpdateRequest{Payload: []byte("q")})
+				assertCode(t, "write", want.write, err)
+
+				_, err = mgr.Update(ctx, created.ID, &UpdateRequest{
+					AccessControl: models.AccessControl{Scope: scope, AllowedIDs: []string{"member", "stranger"}},
+				})
+				assertCode(t, "acl", want.acl, err)
+				if want.acl == ok {
+					store.contexts[created.ID].AccessControl.AllowedIDs = []string{"member"}
+				}
+
+				listed, err := mgr.List(ctx, nil)
+				require.NoError(t, err)
+				assert.Equal(t, want.listed, len(listed) == 1, "list")
+
+				err = mgr.Delete(ctx, created.ID)
+				assertCode(t, "delete", want.del, err)
+			})
+		}
+	}
+}
+
+func TestManager_RequiresCaller(t *testing.T) {
+	mgr := NewManager(NewMockContextStore())
+
+	_, err := mgr.Get(context.Background(), "ctx-1")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeUnauthorized))
+
+	_, err = mgr.List(context.Background(), nil)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeUnauthorized))
+}
+
+func TestManager_Get_MissingIsNotFound(t *testing.T) {
+	mgr := NewManager(NewMockContextStore())
+
+	_, err := mgr.Get(callerCtx("agent-1"), "missing")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+}
+
+func TestManager_Revisions(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store)
+
+	created, err := mgr.Create(context.Background(), &CreateRequest{
+		Type:          "doc",
+		AgentID:       "agent-1",
+		TenantID:      "default",
+		Payload:       []byte(`{"step":1}`),
+		AccessControl: models.AccessControl{Scope: models.ScopeGroup, AllowedIDs: []string{"agent-2"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created.Revision)
+
+	_, err = mgr.Update(callerCtx("agent-2"), created.ID, &UpdateRequest{Payload: []byte(`{"step":2}`)})
+	require.NoError(t, err)
+	updated, err := mgr.Update(callerCtx("agent-1"), created.ID, &UpdateRequest{Payload: []byte(`{"step":3}`)})
+	require.NoError(t, err)
+	assert.Equal(t, 3, updated.Revision)
+
+	revisions, err := mgr.ListRevisions(callerCtx("agent-2"), created.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	assert.Equal(t, 3, revisions[0].Revision)
+	assert.Equal(t, "agent-2", revisions[1].AuthorID)
+
+	old, err := mgr.GetRevision(callerCtx("agent-1"), created.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, old.Revision)
+	assert.Equal(t, []byte(`{"step":1}`), old.Payload)
+	assert.Equal(t, []byte(`{"step":3}`), store.contexts[created.ID].Payload)
+
+	_, err = mgr.GetRevision(callerCtx("agent-1"), created.ID, 9)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	_, err = mgr.GetRevision(callerCtx("stranger"), created.ID, 1)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+
+	diff, err := mgr.Diff(callerCtx("agent-1"), created.ID, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, diff.FromRevision)
+	assert.Equal(t, 3, diff.ToRevision)
+	assert.Equal(t, []Change{{Op: ChangeReplace, Path: "/payload/step", From: json.Number("2"), To: json.Number("3")}}, diff.Changes)
+
+	// Restoring appends a new revision rather than rewriting history
+	restored, err := mgr.Restore(callerCtx("agent-2"), created.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, restored.Revision)
+	assert.Equal(t, []byte(`{"step":1}`), restored.Payload)
+	assert.Equal(t, calculateChecksum([]byte(`{"step":1}`)), restored.Checksum)
+	assert.Equal(t, "agent-2", restored.UpdatedBy)
+	assert.Len(t, store.revisions[created.ID], 4)
+}
+
+func TestManager_GetRevision_UsesRevisionAccessControl(t *testing.T) {
+	mgr := NewManager(NewMockContextStore())
+	owner := callerCtx("agent-1")
+
+	created, err := mgr.Create(context.Background(), &CreateRequest{
+		Type:          "doc",
+		AgentID:       "agent-1",
+		TenantID:      "default",
+		Payload:       []byte("shared draft"),
+		AccessControl: models.AccessControl{Scope: models.ScopeGroup, AllowedIDs: []string{"agent-2"}},
+	})
+	require.NoError(t, err)
+	_, err = mgr.Update(owner, created.ID, &UpdateRequest{Payload: []byte("private notes"), AccessControl: models.AccessControl{Scope: models.ScopePrivate}})
+	require.NoError(t, err)
+	_, err = mgr.Update(owner, created.ID, &UpdateRequest{Payload: []byte("published"), AccessControl: models.AccessControl{Scope: models.ScopePublic}})
+	require.NoError(t, err)
+
+	// Each revision is readable by whoever could read it when it was written
+	old, err := mgr.GetRevision(callerCtx("agent-2"), created.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("shared draft"), old.Payload)
+	_, err = mgr.GetRevision(callerCtx("agent-2"), created.ID, 2)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	_, err = mgr.GetRevision(callerCtx("stranger"), created.ID, 1)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	_, err = mgr.GetRevision(callerCtx("stranger"), created.ID, 3)
+	assert.NoError(t, err)
+
+	// Listings leave out revisions the caller could not read
+	revisions, err := mgr.ListRevisions(callerCtx("agent-2"), created.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 3, revisions[0].Revision)
+	assert.Equal(t, 1, revisions[1].Revision)
+	revisions, err = mgr.ListRevisions(callerCtx("stranger"), created.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, 3, revisions[0].Revision)
+	revisions, err = mgr.ListRevisions(owner, created.ID)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 3)
+
+	// Diffs need access to both revisions
+	_, err = mgr.Diff(callerCtx("stranger"), created.ID, 2, 3)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	_, err = mgr.Diff(owner, created.ID, 2, 3)
+	assert.NoError(t, err)
+}
+
+func TestManager_Restore_AccessControlRequiresOwner(t *testing.T) {
+	mgr := NewManager(NewMockContextStore())
+
+	created, err := mgr.Create(context.Background(), &CreateRequest{
+		Type:          "doc",
+		AgentID:       "agent-1",
+		TenantID:      "default",
+		Payload:       []byte("p"),
+		AccessControl: models.AccessControl{Scope: models.ScopeShared, AllowedIDs: []string{"agent-2"}},
+	})
+	require.NoError(t, err)
+	_, err = mgr.Update(callerCtx("agent-1"), created.ID, &UpdateRequest{
+		AccessControl: models.AccessControl{Scope: models.ScopeShared, AllowedIDs: []string{"agent-2", "agent-3"}},
+	})
+	require.NoError(t, err)
+
+	_, err = mgr.Restore(callerCtx("agent-2"), created.ID, 1)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+
+	_, err = mgr.Restore(callerCtx("agent-1"), created.ID, 1)
+	assert.NoError(t, err)
+}
+
+// racingStore simulates another writer committing just before each of the
+// next races updates
+type racingStore struct {
+	*MockContextStore
+	races int
+}
+
+func (r *racingStore) Update(ctx context.Context, c *models.Context) error {
+	if r.races > 0 {
+		r.races--
+		other := *r.contexts[c.ID]
+		other.Revision = 0
+		other.UpdatedBy = "someone-else"
+		_ = r.MockContextStore.Update(ctx, &other)
+	}
+	return r.MockContextStore.Update(ctx, c)
+}
+
+func TestManager_Update_ExpectedRevision(t *testing.T) {
+	mgr := NewManager(NewMockContextStore())
+	created, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "doc", AgentID: "agent-1", TenantID: "default", Payload: []byte("p"),
+		AccessControl: models.AccessControl{Scope: models.ScopePrivate},
+	})
+	require.NoError(t, err)
+
+	updated, err := mgr.Update(callerCtx("agent-1"), created.ID, &UpdateRequest{Payload: []byte("q"), ExpectedRevision: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Revision)
+
+	// Stale revision
+	_, err = mgr.Update(callerCtx("agent-1"), created.ID, &UpdateRequest{Payload: []byte("r"), ExpectedRevision: 1})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodePreconditionFailed))
+	var conflict *ConflictError
+	require.True(t, errors.As(err, &conflict))
+	assert.Equal(t, 1, conflict.ExpectedRevision)
+	assert.Equal(t, 2, conflict.CurrentRevision)
+
+	// Delete honours the precondition too
+	err = mgr.DeleteAtRevision(callerCtx("agent-1"), created.ID, 1)
+	assert.True(t, errors.As(err, &conflict))
+	assert.NoError(t, mgr.DeleteAtRevision(callerCtx("agent-1"), created.ID, 2))
+}
+
+func TestManager_Update_LostRace(t *testing.T) {
+	store := &racingStore{MockContextStore: NewMockContextStore()}
+	mgr := NewManager(store)
+	created, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "doc", AgentID: "agent-1", TenantID: "default", Payload: []byte("p"),
+		AccessControl: models.AccessControl{Scope: models.ScopePrivate},
+	})
+	require.NoError(t, err)
+
+	// A conditional write never overwrites a concurrent change
+	store.races = 1
+	_, err = mgr.Update(callerCtx("agent-1"), created.ID, &UpdateRequest{Payload: []byte("mine"), ExpectedRevision: 1})
+	var conflict *ConflictError
+	require.True(t, errors.As(err, &conflict))
+	assert.Equal(t, 2, conflict.CurrentRevision)
+	assert.Equal(t, "someone-else", store.contexts[created.ID].UpdatedBy)
+
+	// An unconditional write is retried on top of the concurrent change
+	store.races = maxWriteAttempts - 1
+	updated, err := mgr.Update(callerCtx("agent-1"), created.ID, &UpdateRequest{Payload: []byte("mine")})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("mine"), store.contexts[created.ID].Payload)
+	assert.Equal(t, store.contexts[created.ID].Revision, updated.Revision)
+
+	// ... but gives up after maxWriteAttempts
+	store.races = maxWriteAttempts
+	_, err = mgr.Update(callerCtx("agent-1"), created.ID, &UpdateRequest{Payload: []byte("again")})
+	assert.True(t, errors.As(err, &conflict))
+}
+
+func TestManager_Patch(t *testing.T) {
+	store := NewMockContextStore()
+	mgr := NewManager(store)
+	created, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "doc", AgentID: "agent-1", TenantID: "default",
+		Payload:       []byte(`{"plan":{"step":1,"owner":"a"},"log":["start"]}`),
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+	})
+	require.NoError(t, err)
+
+	patched, err := mgr.Patch(callerCtx("agent-1"), created.ID, &PatchRequest{
+		Type:  PatchTypeMerge,
+		Patch: []byte(`{"plan":{"step":2,"owner":null}}`),
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"plan":{"step":2},"log":["start"]}`, string(patched.Payload))
+	assert.Equal(t, calculateChecksum(patched.Payload), patched.Checksum)
+	assert.Equal(t, 2, patched.Revision)
+
+	patched, err = mgr.Patch(callerCtx("agent-1"), created.ID, &PatchRequest{
+		Type:             PatchTypeJSON,
+		Patch:            []byte(`[{"op":"test","path":"/plan/step","value":2},{"op":"add","path":"/log/-","value":"step 2"}]`),
+		ExpectedRevision: 2,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"plan":{"step":2},"log":["start","step 2"]}`, string(store.contexts[created.ID].Payload))
+	assert.Equal(t, 3, patched.Revision)
+
+	// Failed test operations leave the payload untouched
+	_, err = mgr.Patch(callerCtx("agent-1"), created.ID, &PatchRequest{
+		Type:  PatchTypeJSON,
+		Patch: []byte(`[{"op":"remove","path":"/log"},{"op":"test","path":"/plan/step","value":1}]`),
+	})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeConflict))
+	assert.Equal(t, 3, store.contexts[created.ID].Revision)
+
+	_, err = mgr.Patch(callerCtx("agent-1"), created.ID, &PatchRequest{Type: PatchTypeJSON, Patch: []byte(`{}`)})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+
+	_, err = mgr.Patch(callerCtx("agent-1"), created.ID, &PatchRequest{Type: "xml", Patch: []byte(`{}`)})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+
+	// Public contexts are read-only for other agents
+	_, err = mgr.Patch(callerCtx("agent-2"), created.ID, &PatchRequest{Type: PatchTypeMerge, Patch: []byte(`{"x":1}`)})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+}
+
+func TestManager_Patch_PayloadConstraints(t *testing.T) {
+	mgr := NewManager(NewMockContextStore())
+
+	binary, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "blob", AgentID: "agent-1", TenantID: "default", Payload: []byte{0xff, 0x00},
+		AccessControl: models.AccessControl{Scope: models.ScopePrivate},
+	})
+	require.NoError(t, err)
+	_, err = mgr.Patch(callerCtx("agent-1"), binary.ID, &PatchRequest{Type: PatchTypeMerge, Patch: []byte(`{"a":1}`)})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeConflict))
+
+	large := []byte(`{"s":"` + strings.Repeat("x", models.MaxDirectContextSize-16) + `"}`)
+	big, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "doc", AgentID: "agent-1", TenantID: "default", Payload: large,
+		AccessControl: models.AccessControl{Scope: models.ScopePrivate},
+	})
+	require.NoError(t, err)
+	_, err = mgr.Patch(callerCtx("agent-1"), big.ID, &PatchRequest{Type: PatchTypeMerge, Patch: []byte(`{"t":"0123456789abcdef"}`)})
+	assert.True(t, errors.Is(err, models.ErrPayloadTooLarge))
+}
+
+// stubValidator rejects payloads containing "invalid" for contexts with a schema
+type stubValidator struct {
+	calls []string // SchemaID of every validated context
+}
+
+func (v *stubValidator) ValidatePayload(ctx context.Context, c *models.Context) error {
+	v.calls = append(v.calls, c.SchemaID)
+	if c.SchemaID != "" && strings.Contains(string(c.Payload), "invalid") {
+		return acberrors.ValidationError("payload does not match schema")
+	}
+	return nil
+}
+
+func TestManager_PayloadValidation(t *testing.T) {
+	store := NewMockContextStore()
+	validator := &stubValidator{}
+	mgr := NewManager(store, WithPayloadValidator(validator))
+
+	_, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "task", AgentID: "agent-1", TenantID: "default", SchemaID: "s1",
+		Payload:       []byte(`{"state":"invalid"}`),
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+	})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	assert.Empty(t, store.contexts)
+
+	created, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "task", AgentID: "agent-1", TenantID: "default", SchemaID: "s1",
+		Payload:       []byte(`{"state":"ok"}`),
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "s1", created.SchemaID)
+
+	// Updates and patches are validated against the resulting payload
+	_, err = mgr.Update(callerCtx("agent-1"), created.ID, &UpdateRequest{Payload: []byte(`{"state":"invalid"}`)})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	_, err = mgr.Patch(callerCtx("agent-1"), created.ID, &PatchRequest{Type: PatchTypeMerge, Patch: []byte(`{"state":"invalid"}`)})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	assert.Equal(t, 1, store.contexts[created.ID].Revision)
+
+	updated, err := mgr.Update(callerCtx("agent-1"), created.ID, &UpdateRequest{SchemaID: "s2"})
+	require.NoError(t, err)
+	assert.Equal(t, "s2", updated.SchemaID)
+	assert.Equal(t, []string{"s1", "s1", "s1", "s1", "s2"}, validator.calls)
+}
@@ -0,0 +1,61 @@
+package context
+
+import (
+	"context"
+	"errors"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/patch"
+)
+
+// PatchType identifies the format of a payload patch
+type PatchType string
+
+const (
+	PatchTypeMerge PatchType = "merge" // JSON Merge Patch (RFC 7386)
+	PatchTypeJSON  PatchType = "json"  // JSON Patch (RFC 6902)
+)
+
+// PatchRequest contains a partial payload update
+type PatchRequest struct {
+	Type                 PatchType
+	Patch                []byte
+	MarkDescendantsStale bool // Flag every context derived from this one as stale
+	ExpectedRevision     int  // When non-zero, fail with a conflict unless the context is at this revision
+}
+
+// Patch applies a patch to the JSON payload of a context. The patch is applied
+// to the current payload server-side and stored with compare-and-swap, so
+// concurrent patches to different fields are never lost.
+func (m *Manager) Patch(ctx context.Context, contextID string, req *PatchRequest) (*models.Context, error) {
+	apply := patch.MergePatch
+	switch req.Type {
+	case PatchTypeMerge:
+	case PatchTypeJSON:
+		apply = patch.JSONPatch
+	default:
+		return nil, acberrors.ValidationError("unsupported patch type")
+	}
+
+	c, err := m.mutate(ctx, contextID, req.ExpectedRevision, func(claims *auth.JWTClaims, c *models.Context) error {
+		payload, err := apply(c.Payload, req.Patch)
+		if err != nil {
+			return patchError(err)
+		}
+		c.Payload = payload
+		c.Checksum = calculateChecksum(payload)
+		return nil
+	})
+	return m.markStale(ctx, c, req.MarkDescendantsStale, err)
+}
+
+// patchError maps patch failures to API errors: a malformed patch is a
+// validation error, a patch that does not fit the payload is a conflict
+func patchError(err error) error {
+	if errors.Is(err, patch.ErrInvalidPatch) {
+		return acberrors.ValidationError(err.Error()).WithError(err)
+	}
+	return acberrors.Conflict(err.Error()).WithError(err)
+}
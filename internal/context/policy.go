@@ -0,0 +1,165 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+)
+
+// WithLifecyclePolicies enforces the lifecycle policies configured per
+// context type in store on every create, update and delete
+func WithLifecyclePolicies(store storage.LifecyclePolicyStore) Option {
+	return func(m *Manager) {
+		m.policies = store
+	}
+}
+
+// typeConfig holds what admins configured for a context type. Either part is
+// nil when the type has none.
+type typeConfig struct {
+	expiration *models.ExpirationConfig
+	policy     *models.LifecyclePolicy
+}
+
+// loadTypeConfig loads the expiration defaults and lifecycle policy of a
+// context type
+func (m *Manager) loadTypeConfig(ctx context.Context, tenantID, contextType string) (*typeConfig, error) {
+	expiration, err := m.expirationDefaults(ctx, tenantID, contextType)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := m.lifecyclePolicy(ctx, tenantID, contextType)
+	if err != nil {
+		return nil, err
+	}
+	return &typeConfig{expiration: expiration, policy: policy}, nil
+}
+
+// LifecyclePolicy returns the lifecycle policy of a context type in the
+// caller's tenant
+func (m *Manager) LifecyclePolicy(ctx context.Context, contextType string) (*models.LifecyclePolicy, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.policies == nil {
+		return nil, acberrors.NotFound("lifecycle policies are not enabled")
+	}
+	p, err := m.policies.Get(ctx, claims.TenantID, contextType)
+	if storage.IsNotFound(err) {
+		return nil, acberrors.NotFound(fmt.Sprintf("context type %s has no lifecycle policy", contextType)).WithError(err)
+	}
+	return p, err
+}
+
+// ListLifecyclePolicies returns the lifecycle policies of the caller's tenant
+func (m *Manager) ListLifecyclePolicies(ctx context.Context) ([]*models.LifecyclePolicy, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.policies == nil {
+		return []*models.LifecyclePolicy{}, nil
+	}
+	return m.policies.List(ctx, claims.TenantID)
+}
+
+// SetLifecyclePolicy creates or replaces the lifecycle policy of a context
+// type in the caller's tenant. Only tenant admins may change policies. They
+// apply from the next write of each context: existing contexts are not
+// rechecked until they are updated.
+func (m *Manager) SetLifecyclePolicy(ctx context.Context, p *models.LifecyclePolicy) error {
+	claims, err := m.authorizePolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := p.Validate(); err != nil {
+		return acberrors.ValidationError(err.Error()).WithError(err)
+	}
+
+	p.TenantID = claims.TenantID
+	return m.policies.Set(ctx, p)
+}
+
+// DeleteLifecyclePolicy removes the lifecycle policy of a context type in the
+// caller's tenant. Only tenant admins may remove policies.
+func (m *Manager) DeleteLifecyclePolicy(ctx context.Context, contextType string) error {
+	claims, err := m.authorizePolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	err = m.policies.Delete(ctx, claims.TenantID, contextType)
+	if storage.IsNotFound(err) {
+		return acberrors.NotFound(fmt.Sprintf("context type %s has no lifecycle policy", contextType)).WithError(err)
+	}
+	return err
+}
+
+// authorizePolicyAdmin checks that the caller may change lifecycle policies
+func (m *Manager) authorizePolicyAdmin(ctx context.Context) (*auth.JWTClaims, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.HasRole(auth.RoleAdmin) {
+		return nil, acberrors.Forbidden("only admins may configure lifecycle policies")
+	}
+	if m.policies == nil {
+		return nil, acberrors.ServiceUnavailable("lifecycle policies are not enabled")
+	}
+	return claims, nil
+}
+
+// lifecyclePolicy returns the lifecycle policy of a context type, or nil when
+// it has none
+func (m *Manager) lifecyclePolicy(ctx context.Context, tenantID, contextType string) (*models.LifecyclePolicy, error) {
+	if m.policies == nil {
+		return nil, nil
+	}
+	p, err := m.policies.Get(ctx, tenantID, contextType)
+	if storage.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lifecycle policy: %w", err)
+	}
+	return p, nil
+}
+
+// checkPolicy returns a validation error listing every way c breaks policy
+func checkPolicy(policy *models.LifecyclePolicy, c *models.Context) error {
+	if policy == nil {
+		return nil
+	}
+	if violations := policy.Violations(c); len(violations) > 0 {
+		return policyError(c.Type, violations)
+	}
+	return nil
+}
+
+// authorizeDelete returns the error to report when the caller may not delete
+// c. Only the owner may, unless the policy of its type lets every agent that
+// may write it delete it too.
+func authorizeDelete(claims *auth.JWTClaims, c *models.Context, policy *models.LifecyclePolicy) error {
+	if policy != nil && policy.NonOwnerDelete {
+		return authorizeWrite(claims, c)
+	}
+	return authorizeOwner(claims, c)
+}
+
+// pruneRevisions drops the revisions of c beyond those its policy keeps. The
+// write has already succeeded, so failures are only logged and the next write
+// prunes again.
+func (m *Manager) pruneRevisions(ctx context.Context, policy *models.LifecyclePolicy, c *models.Context) {
+	if policy == nil || policy.MaxRevisions == 0 || c.Revision <= policy.MaxRevisions {
+		return
+	}
+	if _, err := m.store.PruneRevisions(ctx, c.ID, policy.MaxRevisions); err != nil {
+		log.Printf("context manager: failed to prune revisions of %s: %v", c.ID, err)
+	}
+}
@@ -0,0 +1,185 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockPolicyStore keeps lifecycle policies in memory
+type mockPolicyStore struct {
+	policies map[string]*models.LifecyclePolicy
+}
+
+func (s *mockPolicyStore) Get(ctx context.Context, tenantID, contextType string) (*models.LifecyclePolicy, error) {
+	p, ok := s.policies[tenantID+"/"+contextType]
+	if !ok {
+		return nil, fmt.Errorf("no lifecycle policy: %w", storage.ErrNotFound)
+	}
+	return p, nil
+}
+
+func (s *mockPolicyStore) List(ctx context.Context, tenantID string) ([]*models.LifecyclePolicy, error) {
+	var policies []*models.LifecyclePolicy
+	for _, p := range s.policies {
+		if p.TenantID == tenantID {
+			policies = append(policies, p)
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ContextType < policies[j].ContextType })
+	return policies, nil
+}
+
+func (s *mockPolicyStore) Set(ctx context.Context, p *models.LifecyclePolicy) error {
+	s.policies[p.TenantID+"/"+p.ContextType] = p
+	return nil
+}
+
+func (s *mockPolicyStore) Delete(ctx context.Context, tenantID, contextType string) error {
+	if _, ok := s.policies[tenantID+"/"+contextType]; !ok {
+		return fmt.Errorf("no lifecycle policy: %w", storage.ErrNotFound)
+	}
+	delete(s.policies, tenantID+"/"+contextType)
+	return nil
+}
+
+func newPolicyManager(t *testing.T, p *models.LifecyclePolicy) (*Manager, *MockContextStore) {
+	store := NewMockContextStore()
+	mgr := NewManager(store, WithLifecyclePolicies(&mockPolicyStore{policies: map[string]*models.LifecyclePolicy{}}))
+	p.ContextType = "session"
+	require.NoError(t, mgr.SetLifecyclePolicy(callerCtx("admin", string(auth.RoleAdmin)), p))
+	return mgr, store
+}
+
+func TestManager_LifecyclePolicyDefaultsAndLimits(t *testing.T) {
+	mgr, _ := newPolicyManager(t, &models.LifecyclePolicy{
+		DefaultTTL:     2 * time.Hour,
+		MaxTTL:         4 * time.Hour,
+		MaxPayloadSize: 8,
+		AllowedScopes:  []models.ContextScope{models.ScopePrivate, models.ScopeGroup},
+	})
+
+	c := createSession(t, mgr, &CreateRequest{})
+	assert.Equal(t, 2*time.Hour, c.TTL)
+
+	_, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "session", AgentID: "agent-1", TenantID: "default",
+		Payload:       []byte("far too long"),
+		TTL:           8 * time.Hour,
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+	})
+	require.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	var policyErr *PolicyError
+	require.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, "session", policyErr.ContextType)
+	assert.Len(t, policyErr.Violations, 3)
+
+	// Updates are held to the same limits
+	_, err = mgr.Update(callerCtx("agent-1"), c.ID, &UpdateRequest{TTL: 5 * time.Hour})
+	assert.True(t, errors.As(err, &policyErr))
+	_, err = mgr.Update(callerCtx("agent-1"), c.ID, &UpdateRequest{Payload: []byte("123456789")})
+	assert.True(t, errors.As(err, &policyErr))
+	_, err = mgr.Update(callerCtx("agent-1"), c.ID, &UpdateRequest{Payload: []byte("12345678"), TTL: 4 * time.Hour})
+	assert.NoError(t, err)
+
+	// Other types are unconstrained
+	other, err := mgr.Create(context.Background(), &CreateRequest{
+		Type: "notes", AgentID: "agent-1", TenantID: "default", Payload: []byte("far too long"),
+		AccessControl: models.AccessControl{Scope: models.ScopePublic},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, other.TTL)
+}
+
+func TestManager_LifecyclePolicyCapsDefaultTTL(t *testing.T) {
+	store := NewMockContextStore()
+	expirations := &mockExpirationStore{configs: map[string]*models.ExpirationConfig{
+		"default/session": {TenantID: "default", ContextType: "session", TTL: 48 * time.Hour},
+	}}
+	policies := &mockPolicyStore{policies: map[string]*models.LifecyclePolicy{
+		"default/session": {TenantID: "default", ContextType: "session", MaxTTL: time.Hour},
+	}}
+	mgr := NewManager(store, WithExpiration(expirations, nil), WithLifecyclePolicies(policies))
+
+	c := createSession(t, mgr, &CreateRequest{})
+	assert.Equal(t, time.Hour, c.TTL)
+}
+
+func TestManager_LifecyclePolicyPrunesRevisions(t *testing.T) {
+	mgr, store := newPolicyManager(t, &models.LifecyclePolicy{MaxRevisions: 2})
+	ctx := callerCtx("agent-1")
+	c := createSession(t, mgr, &CreateRequest{})
+	for _, payload := range []string{"v2", "v3", "v4"} {
+		_, err := mgr.Update(ctx, c.ID, &UpdateRequest{Payload: []byte(payload)})
+		require.NoError(t, err)
+	}
+
+	revisions, err := store.ListRevisions(context.Background(), c.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 4, revisions[0].Revision)
+	_, err = mgr.GetRevision(ctx, c.ID, 1)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+}
+
+func TestManager_LifecyclePolicyNonOwnerDelete(t *testing.T) {
+	mgr, _ := newPolicyManager(t, &models.LifecyclePolicy{NonOwnerDelete: true})
+	shared := models.AccessControl{Scope: models.ScopeGroup, AllowedIDs: []string{"agent-2"}}
+
+	c := createSession(t, mgr, &CreateRequest{})
+	_, err := mgr.Update(callerCtx("agent-1"), c.ID, &UpdateRequest{AccessControl: shared})
+	require.NoError(t, err)
+	require.NoError(t, mgr.Delete(callerCtx("agent-2"), c.ID))
+
+	// Agents that may only read still may not delete
+	c = createSession(t, mgr, &CreateRequest{})
+	_, err = mgr.Update(callerCtx("agent-1"), c.ID, &UpdateRequest{AccessControl: models.AccessControl{Scope: models.ScopePublic}})
+	require.NoError(t, err)
+	err = mgr.Delete(callerCtx("agent-2"), c.ID)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+
+	// Without the policy only the owner may delete
+	plain := NewManager(NewMockContextStore())
+	c = createSession(t, plain, &CreateRequest{})
+	_, err = plain.Update(callerCtx("agent-1"), c.ID, &UpdateRequest{AccessControl: shared})
+	require.NoError(t, err)
+	results, err := plain.BatchDelete(callerCtx("agent-2"), models.BatchPartial, []string{c.ID})
+	require.NoError(t, err)
+	assert.True(t, acberrors.Is(results[0].Err, acberrors.ErrorCodeForbidden))
+}
+
+func TestManager_LifecyclePolicyAdministration(t *testing.T) {
+	mgr, _ := newPolicyManager(t, &models.LifecyclePolicy{MaxRevisions: 10})
+	admin := callerCtx("admin", string(auth.RoleAdmin))
+
+	p, err := mgr.LifecyclePolicy(callerCtx("agent-1"), "session")
+	require.NoError(t, err)
+	assert.Equal(t, "default", p.TenantID)
+	policies, err := mgr.ListLifecyclePolicies(callerCtx("agent-1"))
+	require.NoError(t, err)
+	assert.Len(t, policies, 1)
+
+	err = mgr.SetLifecyclePolicy(callerCtx("agent-1"), &models.LifecyclePolicy{ContextType: "notes"})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+	err = mgr.SetLifecyclePolicy(admin, &models.LifecyclePolicy{ContextType: "notes", DefaultTTL: 2 * time.Hour, MaxTTL: time.Hour})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+
+	require.NoError(t, mgr.DeleteLifecyclePolicy(admin, "session"))
+	err = mgr.DeleteLifecyclePolicy(admin, "session")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	_, err = mgr.LifecyclePolicy(admin, "session")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+
+	err = NewManager(NewMockContextStore()).SetLifecyclePolicy(admin, &models.LifecyclePolicy{ContextType: "notes"})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeServiceUnavailable))
+}
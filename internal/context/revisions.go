@@ -0,0 +1,159 @@
+package context
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+)
+
+// ListRevisions returns the revision history of a context, newest first.
+// The caller must be able to read the current context, and as with
+// GetRevision only revisions readable under their own access control are
+// listed.
+func (m *Manager) ListRevisions(ctx context.Context, contextID string) ([]*models.ContextRevision, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current, err := m.Get(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := m.store.ListRevisions(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+
+	readable := make([]*models.ContextRevision, 0, len(revisions))
+	for _, r := range revisions {
+		if authorizeRead(claims, revisionView(current, r)) == nil {
+			readable = append(readable, r)
+		}
+	}
+	return readable, nil
+}
+
+// GetRevision returns the context as it was at the given revision. Access is
+// decided by the access control stored with that revision, so narrowing or
+// widening access later does not change who may read earlier revisions.
+func (m *Manager) GetRevision(ctx context.Context, contextID string, revision int) (*models.Context, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current, err := m.load(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+	r, err := m.loadRevision(ctx, contextID, revision)
+	if err != nil {
+		// Callers without access learn nothing about the history
+		if authErr := authorizeRead(claims, current); authErr != nil {
+			return nil, authErr
+		}
+		return nil, err
+	}
+
+	c := revisionView(current, r)
+	if err := authorizeRead(claims, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Restore writes the content of an old revision as a new revision.
+// History is never rewritten; restoring a revision whose access control
+// differs from the current one requires ownership.
+func (m *Manager) Restore(ctx context.Context, contextID string, revision int) (*models.Context, error) {
+	return m.mutate(ctx, contextID, 0, func(claims *auth.JWTClaims, c *models.Context) error {
+		r, err := m.loadRevision(ctx, contextID, revision)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(r.AccessControl, c.AccessControl) {
+			if err := authorizeOwner(claims, c); err != nil {
+				return err
+			}
+		}
+		applyRevision(c, r)
+		return nil
+	})
+}
+
+// Diff compares two revisions of a context. A zero to selects the current
+// revision and a zero from selects the revision before to.
+func (m *Manager) Diff(ctx context.Context, contextID string, from, to int) (*Diff, error) {
+	current, err := m.Get(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == 0 {
+		to = current.Revision
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	if from < 1 {
+		return nil, acberrors.ValidationError("no earlier revision to compare against")
+	}
+
+	a, err := m.loadRevision(ctx, contextID, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := m.loadRevision(ctx, contextID, to)
+	if err != nil {
+		return nil, err
+	}
+	// As with GetRevision, both revisions must be readable under their own
+	// access control
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range []*models.ContextRevision{a, b} {
+		if err := authorizeRead(claims, revisionView(current, r)); err != nil {
+			return nil, err
+		}
+	}
+	return diffRevisions(a, b), nil
+}
+
+// loadRevision fetches a revision, translating storage misses into NOT_FOUND
+func (m *Manager) loadRevision(ctx context.Context, contextID string, revision int) (*models.ContextRevision, error) {
+	if revision < 1 {
+		return nil, acberrors.ValidationError("revision must be a positive integer")
+	}
+	r, err := m.store.GetRevision(ctx, contextID, revision)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return nil, acberrors.NotFound("revision not found").WithError(err)
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// revisionView returns current as it was at revision r
+func revisionView(current *models.Context, r *models.ContextRevision) *models.Context {
+	c := *current
+	applyRevision(&c, r)
+	c.Revision = r.Revision
+	c.UpdatedBy = r.AuthorID
+	return &c
+}
+
+// applyRevision copies the versioned content of r onto c
+func applyRevision(c *models.Context, r *models.ContextRevision) {
+	c.Payload = r.Payload
+	c.PayloadRef = r.PayloadRef
+	c.Metadata = r.Metadata
+	c.Version = r.Version
+	c.AccessControl = r.AccessControl
+	c.Checksum = r.Checksum
+}
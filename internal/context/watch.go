@@ -0,0 +1,95 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/acb/internal/constants"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+)
+
+// WithEventHub wakes hub after every write made through the manager, so reads
+// waiting on this replica see the write without a round trip through the
+// database. Writes on other replicas arrive through the hub's notifier.
+func WithEventHub(hub *EventHub) Option {
+	return func(m *Manager) {
+		m.hub = hub
+	}
+}
+
+// notify wakes the readers on this replica waiting on a context after a
+// write to it
+func (m *Manager) notify(tenantID, contextID string) {
+	if m.hub != nil {
+		m.hub.Notify(storage.EventSignal{TenantID: tenantID, ContextID: contextID})
+	}
+}
+
+// WaitForRevision returns a context the caller may read once its revision
+// exceeds after, holding the call open for up to timeout (zero means
+// DefaultContextWaitTimeout). If the timeout passes or ctx is done first, the
+// current revision is returned instead, so callers compare its Revision with
+// after. A context that does not exist, or is deleted or expires while the
+// call waits, is reported as not found.
+//
+// Waiters wake on signals of the event hub for the context and on a poll
+// interval in case a signal is lost; without a hub they only poll.
+func (m *Manager) WaitForRevision(ctx context.Context, contextID string, after int, timeout time.Duration) (*models.Context, error) {
+	claims, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if after < 0 {
+		return nil, acberrors.ValidationError("revision to wait past must not be negative")
+	}
+	if timeout == 0 {
+		timeout = constants.DefaultContextWaitTimeout * time.Second
+	}
+	if timeout < 0 || timeout > constants.MaxContextWaitTimeout*time.Second {
+		return nil, acberrors.ValidationError(fmt.Sprintf("wait timeout must be between 0 and %d seconds", constants.MaxContextWaitTimeout))
+	}
+
+	// Subscribe before the first read so a write in between still wakes us
+	var wake <-chan struct{}
+	if m.hub != nil {
+		ch, cancel := m.hub.Subscribe(claims.TenantID, contextID)
+		defer cancel()
+		wake = ch
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(time.Duration(constants.ContextStreamPollInterval) * time.Second)
+	defer poll.Stop()
+
+	var current *models.Context
+	for {
+		c, err := m.load(ctx, contextID)
+		if err != nil {
+			if current != nil && ctx.Err() != nil {
+				return current, nil
+			}
+			return nil, err
+		}
+		if err := authorizeRead(claims, c); err != nil {
+			return nil, err
+		}
+		current = c
+		if c.Revision > after {
+			m.touchRead(ctx, c)
+			return c, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return current, nil
+		case <-deadline.C:
+			m.touchRead(ctx, current)
+			return current, nil
+		case <-wake:
+		case <-poll.C:
+		}
+	}
+}
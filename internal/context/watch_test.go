@@ -0,0 +1,115 @@
+package context
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedContextStore serializes the reads and writes that waiters and
+// writers make concurrently
+type lockedContextStore struct {
+	storage.ContextStore
+	mu sync.Mutex
+}
+
+func (s *lockedContextStore) Get(ctx context.Context, contextID string) (*models.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ContextStore.Get(ctx, contextID)
+}
+
+func (s *lockedContextStore) Update(ctx context.Context, c *models.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ContextStore.Update(ctx, c)
+}
+
+func (s *lockedContextStore) DeleteAtRevision(ctx context.Context, contextID string, revision int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ContextStore.DeleteAtRevision(ctx, contextID, revision)
+}
+
+func newWatchManager(t *testing.T) (*Manager, *models.Context) {
+	mgr := NewManager(&lockedContextStore{ContextStore: NewMockContextStore()}, WithEventHub(NewEventHub()))
+	return mgr, createSession(t, mgr, &CreateRequest{})
+}
+
+// waitAsync runs WaitForRevision in the background and returns its outcome
+func waitAsync(mgr *Manager, contextID string, after int) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		c, err := mgr.WaitForRevision(callerCtx("agent-1"), contextID, after, 10*time.Second)
+		if err == nil && c.Revision <= after {
+			err = context.DeadlineExceeded
+		}
+		done <- err
+	}()
+	return done
+}
+
+func TestManager_WaitForRevisionWakesOnWrite(t *testing.T) {
+	mgr, c := newWatchManager(t)
+
+	// A newer revision is returned at once
+	got, err := mgr.WaitForRevision(callerCtx("agent-1"), c.ID, 0, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Revision)
+
+	done := waitAsync(mgr, c.ID, 1)
+	time.Sleep(20 * time.Millisecond)
+	_, err = mgr.Update(callerCtx("agent-1"), c.ID, &UpdateRequest{Payload: []byte("v2")})
+	require.NoError(t, err)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("waiter was not woken by the write")
+	}
+}
+
+func TestManager_WaitForRevisionTimesOut(t *testing.T) {
+	mgr, c := newWatchManager(t)
+
+	started := time.Now()
+	got, err := mgr.WaitForRevision(callerCtx("agent-1"), c.ID, 1, 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Revision)
+	assert.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond)
+}
+
+func TestManager_WaitForRevisionEndsOnDelete(t *testing.T) {
+	mgr, c := newWatchManager(t)
+
+	done := waitAsync(mgr, c.ID, 1)
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, mgr.Delete(callerCtx("agent-1"), c.ID))
+
+	select {
+	case err := <-done:
+		assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	case <-time.After(2 * time.Second):
+		t.Fatal("waiter was not woken by the delete")
+	}
+}
+
+func TestManager_WaitForRevisionValidation(t *testing.T) {
+	mgr, c := newWatchManager(t)
+
+	_, err := mgr.WaitForRevision(callerCtx("agent-1"), c.ID, -1, time.Second)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	_, err = mgr.WaitForRevision(callerCtx("agent-1"), c.ID, 1, time.Hour)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	// Contexts the caller may not read are reported as missing
+	_, err = mgr.WaitForRevision(callerCtx("agent-2"), c.ID, 1, time.Second)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+}
@@ -0,0 +1,226 @@
+package encryption
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/google/uuid"
+)
+
+// activeKeyRefresh is how long a tenant's active key is used before it is
+// looked up again, so keys issued by a rotation on another process are
+// picked up
+const activeKeyRefresh = time.Minute
+
+// Keyring implements storage.PayloadCipher with envelope encryption: each
+// tenant's data is sealed with AES-GCM under a data key of its own, and data
+// keys are stored wrapped by a master key held by a KeyProvider. Unwrapped
+// data keys are kept in memory only.
+type Keyring struct {
+	store    storage.DataKeyStore
+	provider KeyProvider
+	now      func() time.Time
+
+	mu     sync.Mutex
+	aeads  map[string]cipher.AEAD // Unwrapped data keys by ID
+	active map[string]activeKey   // Active data key by tenant
+}
+
+type activeKey struct {
+	id      string
+	fetched time.Time
+}
+
+// RotationResult summarises a key rotation
+type RotationResult struct {
+	Rewrapped      int // Data keys re-wrapped with the current master key
+	DataKeysIssued int // Tenants given a new active data key
+}
+
+// NewKeyring creates a new keyring
+func NewKeyring(store storage.DataKeyStore, provider KeyProvider) *Keyring {
+	return &Keyring{
+		store:    store,
+		provider: provider,
+		now:      time.Now,
+		aeads:    make(map[string]cipher.AEAD),
+		active:   make(map[string]activeKey),
+	}
+}
+
+// ActiveKey returns the ID of the tenant's active data key, issuing the
+// tenant's first key if it has none
+func (r *Keyring) ActiveKey(ctx context.Context, tenantID string) (string, error) {
+	r.mu.Lock()
+	cached, ok := r.active[tenantID]
+	r.mu.Unlock()
+	if ok && r.now().Sub(cached.fetched) < activeKeyRefresh {
+		return cached.id, nil
+	}
+
+	k, err := r.store.GetActive(ctx, tenantID)
+	if storage.IsNotFound(err) {
+		k, err = r.issue(ctx, tenantID)
+		if storage.IsConflict(err) {
+			// Another process issued the tenant's first key
+			k, err = r.store.GetActive(ctx, tenantID)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	// Unwrap now so a key the provider cannot unwrap is never used to seal
+	if _, err := r.unwrap(ctx, k); err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.active[tenantID] = activeKey{id: k.ID, fetched: r.now()}
+	r.mu.Unlock()
+	return k.ID, nil
+}
+
+// Seal encrypts plaintext with a data key, authenticating aad
+func (r *Keyring) Seal(ctx context.Context, keyID string, plaintext, aad []byte) ([]byte, error) {
+	aead, err := r.aead(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return sealAEAD(aead, plaintext, aad)
+}
+
+// Open decrypts ciphertext sealed with a data key, verifying aad
+func (r *Keyring) Open(ctx context.Context, keyID string, ciphertext, aad []byte) ([]byte, error) {
+	aead, err := r.aead(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return openAEAD(aead, ciphertext, aad)
+}
+
+// Rotate re-wraps every data key not wrapped with the provider's current
+// master key, so retired master keys can be removed once it returns. With
+// issueDataKeys, every tenant also gets a new active data key; rows sealed
+// with an older key stay readable and are re-encrypted when next written.
+func (r *Keyring) Rotate(ctx context.Context, issueDataKeys bool) (*RotationResult, error) {
+	keys, err := r.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data keys: %w", err)
+	}
+
+	result := &RotationResult{}
+	current := r.provider.CurrentKeyID()
+	for _, k := range keys {
+		if k.MasterKeyID == current {
+			continue
+		}
+		dataKey, err := r.provider.Unwrap(ctx, k.MasterKeyID, k.WrappedKey)
+		if err != nil {
+			return result, fmt.Errorf("failed to unwrap data key %s: %w", k.ID, err)
+		}
+		wrapped, masterKeyID, err := r.provider.Wrap(ctx, dataKey)
+		if err != nil {
+			return result, fmt.Errorf("failed to wrap data key %s: %w", k.ID, err)
+		}
+		err = r.store.Rewrap(ctx, k.ID, k.MasterKeyID, masterKeyID, wrapped)
+		if storage.IsConflict(err) {
+			// Re-wrapped by a concurrent rotation
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to re-wrap data key %s: %w", k.ID, err)
+		}
+		result.Rewrapped++
+	}
+
+	if issueDataKeys {
+		for _, k := range keys {
+			if !k.Active {
+				continue
+			}
+			_, err := r.issue(ctx, k.TenantID)
+			if storage.IsConflict(err) {
+				continue
+			}
+			if err != nil {
+				return result, fmt.Errorf("failed to issue data key for tenant %s: %w", k.TenantID, err)
+			}
+			result.DataKeysIssued++
+		}
+
+		r.mu.Lock()
+		r.active = make(map[string]activeKey)
+		r.mu.Unlock()
+	}
+	return result, nil
+}
+
+// issue generates a data key and stores it as the tenant's active key
+func (r *Keyring) issue(ctx context.Context, tenantID string) (*models.DataKey, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, masterKeyID, err := r.provider.Wrap(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	k := &models.DataKey{
+		ID:          uuid.New().String(),
+		TenantID:    tenantID,
+		MasterKeyID: masterKeyID,
+		WrappedKey:  wrapped,
+		CreatedAt:   r.now(),
+	}
+	if err := r.store.Create(ctx, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// aead returns the cipher for a data key, unwrapping it on first use
+func (r *Keyring) aead(ctx context.Context, keyID string) (cipher.AEAD, error) {
+	r.mu.Lock()
+	aead, ok := r.aeads[keyID]
+	r.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	k, err := r.store.Get(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data key %s: %w", keyID, err)
+	}
+	return r.unwrap(ctx, k)
+}
+
+// unwrap unwraps k and caches its cipher
+func (r *Keyring) unwrap(ctx context.Context, k *models.DataKey) (cipher.AEAD, error) {
+	r.mu.Lock()
+	aead, ok := r.aeads[k.ID]
+	r.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	dataKey, err := r.provider.Unwrap(ctx, k.MasterKeyID, k.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key %s: %w", k.ID, err)
+	}
+	aead, err = newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.aeads[k.ID] = aead
+	r.mu.Unlock()
+	return aead, nil
+}
@@ -0,0 +1,179 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryKeyStore is an in-memory storage.DataKeyStore
+type memoryKeyStore struct {
+	mu   sync.Mutex
+	keys []*models.DataKey
+}
+
+func (s *memoryKeyStore) Create(_ context.Context, k *models.DataKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.keys {
+		if existing.TenantID == k.TenantID {
+			existing.Active = false
+		}
+	}
+	copied := *k
+	copied.Active = true
+	s.keys = append(s.keys, &copied)
+	k.Active = true
+	return nil
+}
+
+func (s *memoryKeyStore) Get(_ context.Context, keyID string) (*models.DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.keys {
+		if k.ID == keyID {
+			copied := *k
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("data key not found: %w", storage.ErrNotFound)
+}
+
+func (s *memoryKeyStore) GetActive(_ context.Context, tenantID string) (*models.DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.keys {
+		if k.TenantID == tenantID && k.Active {
+			copied := *k
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("no active data key: %w", storage.ErrNotFound)
+}
+
+func (s *memoryKeyStore) List(_ context.Context) ([]*models.DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]*models.DataKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		copied := *k
+		keys = append(keys, &copied)
+	}
+	return keys, nil
+}
+
+func (s *memoryKeyStore) Rewrap(_ context.Context, keyID, oldMasterKeyID, masterKeyID string, wrapped []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.keys {
+		if k.ID == keyID && k.MasterKeyID == oldMasterKeyID {
+			k.MasterKeyID = masterKeyID
+			k.WrappedKey = wrapped
+			return nil
+		}
+	}
+	return fmt.Errorf("data key moved: %w", storage.ErrConflict)
+}
+
+func newTestProvider(t *testing.T, path, keyID string) *FileKeyProvider {
+	t.Helper()
+	require.NoError(t, AddFileKey(path, keyID))
+	provider, err := NewFileKeyProvider(path)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestKeyring_SealOpen(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryKeyStore{}
+	keyring := NewKeyring(store, newTestProvider(t, filepath.Join(t.TempDir(), "keys.json"), "k1"))
+
+	keyID, err := keyring.ActiveKey(ctx, "tenant-a")
+	require.NoError(t, err)
+	again, err := keyring.ActiveKey(ctx, "tenant-a")
+	require.NoError(t, err)
+	assert.Equal(t, keyID, again)
+	other, err := keyring.ActiveKey(ctx, "tenant-b")
+	require.NoError(t, err)
+	assert.NotEqual(t, keyID, other, "tenants share a data key")
+
+	sealed, err := keyring.Seal(ctx, keyID, []byte("secret"), []byte("ctx-1"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "secret")
+
+	opened, err := keyring.Open(ctx, keyID, sealed, []byte("ctx-1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), opened)
+
+	_, err = keyring.Open(ctx, keyID, sealed, []byte("ctx-2"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+	_, err = keyring.Open(ctx, other, sealed, []byte("ctx-1"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// Data keys are stored wrapped and unwrap in a fresh process
+	fresh := NewKeyring(store, keyring.provider)
+	opened, err = fresh.Open(ctx, keyID, sealed, []byte("ctx-1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), opened)
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store := &memoryKeyStore{}
+	keyring := NewKeyring(store, newTestProvider(t, path, "k1"))
+
+	oldKey, err := keyring.ActiveKey(ctx, "tenant-a")
+	require.NoError(t, err)
+	sealed, err := keyring.Seal(ctx, oldKey, []byte("secret"), nil)
+	require.NoError(t, err)
+
+	// A new master key is added and servers restart with it before rotating
+	provider := newTestProvider(t, path, "k2")
+	server := NewKeyring(store, provider)
+	cached, err := server.ActiveKey(ctx, "tenant-a")
+	require.NoError(t, err)
+	assert.Equal(t, oldKey, cached)
+
+	rotator := NewKeyring(store, provider)
+	result, err := rotator.Rotate(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, &RotationResult{Rewrapped: 1, DataKeysIssued: 1}, result)
+
+	keys, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	for _, k := range keys {
+		assert.Equal(t, "k2", k.MasterKeyID)
+	}
+
+	// Rotating again has nothing to re-wrap
+	result, err = rotator.Rotate(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, &RotationResult{}, result)
+
+	// Old rows stay readable once the retired master key is removed
+	onlyNew, err := NewFileKeyProvider(path)
+	require.NoError(t, err)
+	delete(onlyNew.keys, "k1")
+	opened, err := NewKeyring(store, onlyNew).Open(ctx, oldKey, sealed, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), opened)
+
+	// Running servers seal with the new data key once their cache refreshes
+	cached, err = server.ActiveKey(ctx, "tenant-a")
+	require.NoError(t, err)
+	assert.Equal(t, oldKey, cached)
+	server.now = func() time.Time { return time.Now().Add(activeKeyRefresh) }
+	newKey, err := server.ActiveKey(ctx, "tenant-a")
+	require.NoError(t, err)
+	assert.NotEqual(t, oldKey, newKey)
+}
@@ -0,0 +1,158 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// KeySize is the size in bytes of master and data keys (AES-256)
+const KeySize = 32
+
+// ErrUnknownMasterKey is returned (wrapped) when a data key is wrapped with a
+// master key the provider does not hold
+var ErrUnknownMasterKey = errors.New("unknown master key")
+
+// KeyProvider wraps and unwraps data keys with master keys it never releases.
+// Implementations may hold the master keys locally or delegate to a KMS.
+type KeyProvider interface {
+	// CurrentKeyID names the master key Wrap uses
+	CurrentKeyID() string
+	// Wrap encrypts a data key with the current master key and returns the
+	// ID of that key
+	Wrap(ctx context.Context, dataKey []byte) (wrapped []byte, keyID string, err error)
+	// Unwrap decrypts a data key wrapped with the named master key
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// keyFile is the JSON layout of a master key file
+type keyFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"` // Base64-encoded keys by ID
+}
+
+// FileKeyProvider implements KeyProvider with master keys read from a local
+// JSON file. It is meant for development and tests: in production the master
+// keys should stay in a KMS.
+type FileKeyProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewFileKeyProvider loads the master keys in path
+func NewFileKeyProvider(path string) (*FileKeyProvider, error) {
+	f, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if f.Current == "" {
+		return nil, fmt.Errorf("master key file %s names no current key", path)
+	}
+
+	p := &FileKeyProvider{current: f.Current, keys: make(map[string][]byte, len(f.Keys))}
+	for id, encoded := range f.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %s is not valid base64: %w", id, err)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("master key %s must be %d bytes, got %d", id, KeySize, len(key))
+		}
+		p.keys[id] = key
+	}
+	if _, ok := p.keys[p.current]; !ok {
+		return nil, fmt.Errorf("current master key %s is not in %s", p.current, path)
+	}
+	return p, nil
+}
+
+// CurrentKeyID names the master key new data keys are wrapped with
+func (p *FileKeyProvider) CurrentKeyID() string {
+	return p.current
+}
+
+// Wrap encrypts a data key with the current master key
+func (p *FileKeyProvider) Wrap(_ context.Context, dataKey []byte) ([]byte, string, error) {
+	wrapped, err := seal(p.keys[p.current], dataKey, []byte(p.current))
+	if err != nil {
+		return nil, "", err
+	}
+	return wrapped, p.current, nil
+}
+
+// Unwrap decrypts a data key wrapped with the named master key
+func (p *FileKeyProvider) Unwrap(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMasterKey, keyID)
+	}
+	dataKey, err := open(key, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+// fileMu serialises updates to master key files made by this process
+var fileMu sync.Mutex
+
+// AddFileKey generates a master key named keyID, adds it to the key file at
+// path (creating the file if needed) and makes it the current key. Keys
+// already in the file are kept so existing data keys can still be unwrapped.
+func AddFileKey(path, keyID string) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	f, err := readKeyFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		f, err = &keyFile{Keys: make(map[string]string)}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := f.Keys[keyID]; ok {
+		return fmt.Errorf("master key %s already exists in %s", keyID, path)
+	}
+
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate master key: %w", err)
+	}
+	f.Keys[keyID] = base64.StdEncoding.EncodeToString(key)
+	f.Current = keyID
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write a sibling file and rename it into place so a crash never leaves
+	// a truncated key file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write master key file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write master key file: %w", err)
+	}
+	return nil
+}
+
+func readKeyFile(path string) (*keyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+	var f keyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse master key file %s: %w", path, err)
+	}
+	if f.Keys == nil {
+		f.Keys = make(map[string]string)
+	}
+	return &f, nil
+}
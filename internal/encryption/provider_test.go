@@ -0,0 +1,71 @@
+package encryption
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileKeyProvider_WrapUnwrap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "master-keys.json")
+	require.NoError(t, AddFileKey(path, "k1"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	provider, err := NewFileKeyProvider(path)
+	require.NoError(t, err)
+	assert.Equal(t, "k1", provider.CurrentKeyID())
+
+	ctx := context.Background()
+	dataKey := make([]byte, KeySize)
+	dataKey[0] = 42
+	wrapped, keyID, err := provider.Wrap(ctx, dataKey)
+	require.NoError(t, err)
+	assert.Equal(t, "k1", keyID)
+	assert.NotContains(t, string(wrapped), string(dataKey))
+
+	// Adding a key makes it current but keeps older keys usable
+	require.NoError(t, AddFileKey(path, "k2"))
+	assert.Error(t, AddFileKey(path, "k2"))
+	provider, err = NewFileKeyProvider(path)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", provider.CurrentKeyID())
+
+	unwrapped, err := provider.Unwrap(ctx, "k1", wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+
+	// A wrapped key is bound to the master key that wrapped it
+	_, err = provider.Unwrap(ctx, "k2", wrapped)
+	assert.ErrorIs(t, err, ErrDecrypt)
+	_, err = provider.Unwrap(ctx, "k3", wrapped)
+	assert.ErrorIs(t, err, ErrUnknownMasterKey)
+}
+
+func TestNewFileKeyProvider_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"no current":      `{"keys":{"k1":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}`,
+		"missing current": `{"current":"k2","keys":{"k1":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}`,
+		"short key":       `{"current":"k1","keys":{"k1":"AAAA"}}`,
+		"not base64":      `{"current":"k1","keys":{"k1":"!!"}}`,
+		"not json":        `current=k1`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".json")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			_, err := NewFileKeyProvider(path)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := NewFileKeyProvider(filepath.Join(dir, "absent.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -16,6 +16,8 @@ const (
 	ErrorCodeUnauthorized       ErrorCode = "UNAUTHORIZED"
 	ErrorCodeForbidden          ErrorCode = "FORBIDDEN"
 	ErrorCodeNotFound           ErrorCode = "NOT_FOUND"
+	ErrorCodeConflict           ErrorCode = "CONFLICT"
+	ErrorCodePreconditionFailed ErrorCode = "PRECONDITION_FAILED"
 	ErrorCodeValidationError    ErrorCode = "VALIDATION_ERROR"
 	ErrorCodeRateLimitExceeded  ErrorCode = "RATE_LIMIT_EXCEEDED"
 	ErrorCodeInternalError      ErrorCode = "INTERNAL_ERROR"
@@ -84,6 +86,14 @@ func NotFound(message string) *ACBError {
 	return NewACBError(ErrorCodeNotFound, message)
 }
 
+func Conflict(message string) *ACBError {
+	return NewACBError(ErrorCodeConflict, message)
+}
+
+func PreconditionFailed(message string) *ACBError {
+	return NewACBError(ErrorCodePreconditionFailed, message)
+}
+
 func ValidationError(message string) *ACBError {
 	return NewACBError(ErrorCodeValidationError, message)
 }
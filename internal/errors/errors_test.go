@@ -38,6 +38,12 @@ func TestConvenienceErrors(t *testing.T) {
 	if NotFound("n").Code != ErrorCodeNotFound {
 		t.Fatal("notfound code mismatch")
 	}
+	if Conflict("c").Code != ErrorCodeConflict {
+		t.Fatal("conflict code mismatch")
+	}
+	if PreconditionFailed("p").Code != ErrorCodePreconditionFailed {
+		t.Fatal("precondition failed code mismatch")
+	}
 	if ValidationError("v").Code != ErrorCodeValidationError {
 		t.Fatal("validation code mismatch")
 	}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -33,6 +34,84 @@ const (
 	ScopeShared  ContextScope = "shared"  // Explicitly shared with specific agents
 )
 
+// SchemaCompatibility controls which changes a new schema version of a
+// context type may make relative to the latest registered version
+type SchemaCompatibility string
+
+const (
+	CompatibilityBackward SchemaCompatibility = "backward" // New version accepts payloads valid under the previous one (default)
+	CompatibilityForward  SchemaCompatibility = "forward"  // Previous version accepts payloads valid under the new one
+	CompatibilityFull     SchemaCompatibility = "full"     // Both backward and forward
+	CompatibilityNone     SchemaCompatibility = "none"     // No compatibility checks
+)
+
+// SimilarityMetric scores how alike two embeddings are
+type SimilarityMetric string
+
+const (
+	MetricCosine SimilarityMetric = "cosine" // Cosine of the angle between the vectors (default)
+	MetricDot    SimilarityMetric = "dot"    // Dot product, for embeddings whose magnitude carries meaning
+)
+
+// ContextEventType identifies an entry in the context event log
+type ContextEventType string
+
+const (
+	ContextEventCreated  ContextEventType = "context.created"  // The context was shared
+	ContextEventUpdated  ContextEventType = "context.updated"  // The context was updated to a new revision
+	ContextEventDeleted  ContextEventType = "context.deleted"  // The context was deleted by a client and can be restored until purged
+	ContextEventRestored ContextEventType = "context.restored" // A deleted context was restored
+	ContextEventExpired  ContextEventType = "context.expired"  // The context outlived its TTL and was removed
+	ContextEventStale    ContextEventType = "context.stale"    // A context it derives from was updated
+)
+
+// LineageDirection selects which way a lineage walk follows derivation edges
+type LineageDirection string
+
+const (
+	LineageAncestors   LineageDirection = "ancestors"   // Contexts the context was derived from, transitively
+	LineageDescendants LineageDirection = "descendants" // Contexts derived from the context, transitively
+)
+
+// BatchMode controls how a batch request treats items that fail
+type BatchMode string
+
+const (
+	BatchAtomic  BatchMode = "atomic"  // Any failing item fails the batch and nothing is written (default)
+	BatchPartial BatchMode = "partial" // Items succeed or fail independently
+)
+
+// ExpirationPolicy controls what moves the expiry of a context forward
+type ExpirationPolicy string
+
+const (
+	ExpirationFixed          ExpirationPolicy = "fixed"            // Only updates that set a TTL move the expiry (default)
+	ExpirationSlidingOnRead  ExpirationPolicy = "sliding_on_read"  // Every read or write moves the expiry to TTL from then
+	ExpirationSlidingOnWrite ExpirationPolicy = "sliding_on_write" // Every write moves the expiry to TTL from then
+)
+
+// ConflictPolicy controls how an import treats records whose ID already
+// exists
+type ConflictPolicy string
+
+const (
+	ConflictFail      ConflictPolicy = "fail"      // Abort the import, writing nothing (default)
+	ConflictSkip      ConflictPolicy = "skip"      // Keep the existing record
+	ConflictOverwrite ConflictPolicy = "overwrite" // Replace the existing record if the tenant owns it
+)
+
+// ImportOutcome is what an import did with one record
+type ImportOutcome string
+
+const (
+	ImportCreated     ImportOutcome = "created"
+	ImportOverwritten ImportOutcome = "overwritten"
+	ImportSkipped     ImportOutcome = "skipped"
+)
+
+// DefaultRelation is the relation of a derivation link that names none
+const DefaultRelation = "derived_from"
+
 // Agent represents an autonomous agent in the system
 type Agent struct {
 	ID           string            `json:"id" db:"id"`                     // Unique agent identifier
@@ -43,7 +122,8 @@ type Agent struct {
 	Status       AgentStatus       `json:"status" db:"status"`             // Current status
 	TenantID     string            `json:"tenant_id" db:"tenant_id"`       // Tenant ownership
 	CreatedAt    time.Time         `json:"created_at" db:"created_at"`
-	LastSeen     time.Time         `json:"last_seen" db:"last_seen"` // Last heartbeat
+	LastSeen     time.Time         `json:"last_seen" db:"last_seen"`             // Last heartbeat
+	DeletedAt    *time.Time        `json:"deleted_at,omitempty" db:"deleted_at"` // When the agent was deleted; set only on deleted agents
 }
 
 // StorageRef points to large payloads in object storage (Phase 2+)
@@ -74,8 +154,200 @@ type Context struct {
 	TTL           time.Duration     `json:"ttl" db:"ttl"`                       // Time to live
 	AccessControl AccessControl     `json:"access_control" db:"access_control"` // Who can access
 	CreatedAt     time.Time         `json:"created_at" db:"created_at"`
-	ExpiresAt     time.Time         `json:"expires_at" db:"expires_at"`       // Auto-delete after this
-	Checksum      string            `json:"checksum,omitempty" db:"checksum"` // SHA-256 for integrity
+	ExpiresAt     time.Time         `json:"expires_at" db:"expires_at"`                  // Auto-delete after this
+	Checksum      string            `json:"checksum,omitempty" db:"checksum"`            // SHA-256 for integrity
+	Revision      int               `json:"revision" db:"revision"`                      // Current revision number, starting at 1
+	UpdatedBy     string            `json:"updated_by,omitempty" db:"updated_by"`        // Agent that produced the current revision
+	Embedding     []float32         `json:"embedding,omitempty" db:"embedding"`          // Vector for similarity search, sized by the type's EmbeddingConfig
+	DerivedFrom   []ContextLink     `json:"derived_from,omitempty"`                      // Contexts this one was derived from
+	Stale         bool              `json:"stale,omitempty" db:"stale"`                  // A context it derives from changed after it was last written
+	Expiration    ExpirationPolicy  `json:"expiration,omitempty" db:"expiration_policy"` // What moves ExpiresAt; fixed when empty
+	MaxLifetime   time.Duration     `json:"max_lifetime,omitempty" db:"max_lifetime"`    // Expiry never moves past CreatedAt plus this; zero means no cap
+	DeletedAt     *time.Time        `json:"deleted_at,omitempty" db:"deleted_at"`        // When the context was deleted; set only on deleted contexts
+}
+
+// ContextLink declares that a context was derived from a parent context
+type ContextLink struct {
+	ParentID string `json:"parent_id"`
+	Relation string `json:"relation"` // How the context was derived, e.g. "summarizes"; DefaultRelation when empty
+}
+
+// LineageEdge is a derivation edge between two contexts
+type LineageEdge struct {
+	ChildID  string `json:"child_id"`
+	ParentID string `json:"parent_id"`
+	Relation string `json:"relation"`
+}
+
+// LineageNode is a context reached by a lineage walk
+type LineageNode struct {
+	*Context
+	Depth int `json:"depth"` // Edges between the context and the start of the walk
+}
+
+// Lineage is the result of walking the derivation edges of a context
+type Lineage struct {
+	ContextID string           `json:"context_id"`
+	Direction LineageDirection `json:"direction"`
+	Depth     int              `json:"depth"`     // Maximum depth walked
+	Nodes     []*LineageNode   `json:"nodes"`     // Contexts reached, nearest first; excludes the start
+	Edges     []*LineageEdge   `json:"edges"`     // Edges between the start and the nodes
+	Truncated bool             `json:"truncated"` // Whether the walk stopped at the node limit
+}
+
+// ContextRevision is an immutable snapshot of a context taken on every write
+type ContextRevision struct {
+	ContextID     string            `json:"context_id" db:"context_id"`
+	Revision      int               `json:"revision" db:"revision"`
+	Payload       []byte            `json:"payload,omitempty"`
+	PayloadRef    *StorageRef       `json:"payload_ref,omitempty"`
+	Metadata      map[string]string `json:"metadata" db:"metadata"`
+	Version       string            `json:"version" db:"version"`
+	AccessControl AccessControl     `json:"access_control" db:"access_control"`
+	Checksum      string            `json:"checksum,omitempty" db:"checksum"`
+	AuthorID      string            `json:"author_id" db:"author_id"` // Agent that made the change
+	CreatedAt     time.Time         `json:"created_at" db:"created_at"`
+}
+
+// ContextRecord is a context with its revision history, as exported in a
+// tenant archive
+type ContextRecord struct {
+	*Context
+	Revisions []*ContextRevision `json:"revisions"` // Newest first
+}
+
+// ImportCounts counts the outcomes of the imported records of one kind
+type ImportCounts struct {
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+}
+
+// Add counts one outcome
+func (c *ImportCounts) Add(outcome ImportOutcome) {
+	switch outcome {
+	case ImportCreated:
+		c.Created++
+	case ImportOverwritten:
+		c.Overwritten++
+	case ImportSkipped:
+		c.Skipped++
+	}
+}
+
+// ImportSummary reports the outcome of a tenant import
+type ImportSummary struct {
+	SourceTenantID string       `json:"source_tenant_id"` // Tenant the archive was exported from
+	TenantID       string       `json:"tenant_id"`        // Tenant the records were imported into
+	Agents         ImportCounts `json:"agents"`
+	Contexts       ImportCounts `json:"contexts"`
+}
+
+// ContextSearchResult is a context matching a full-text search
+type ContextSearchResult struct {
+	*Context
+	Rank    float64 `json:"rank"`              // Relevance to the query; higher is better
+	Snippet string  `json:"snippet,omitempty"` // Matching text with query terms wrapped in <mark> tags
+}
+
+// ContextMatch is a context returned by a similarity search
+type ContextMatch struct {
+	*Context
+	Score float64 `json:"score"` // Similarity of the context's embedding to the query vector
+}
+
+// EmbeddingConfig declares the embeddings of a context type. Contexts of the
+// type may only carry embeddings of the declared dimension.
+type EmbeddingConfig struct {
+	TenantID    string           `json:"tenant_id" db:"tenant_id"`
+	ContextType string           `json:"context_type" db:"context_type"`
+	Dimension   int              `json:"dimension" db:"dimension"`
+	Metric      SimilarityMetric `json:"metric" db:"metric"` // Metric the type is indexed for
+	UpdatedAt   time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// ExpirationConfig is the expiration applied to contexts of a type that do
+// not set their own
+type ExpirationConfig struct {
+	TenantID    string           `json:"tenant_id" db:"tenant_id"`
+	ContextType string           `json:"context_type" db:"context_type"`
+	Policy      ExpirationPolicy `json:"policy" db:"policy"`
+	TTL         time.Duration    `json:"ttl" db:"ttl"`                   // Zero keeps the server default
+	MaxLifetime time.Duration    `json:"max_lifetime" db:"max_lifetime"` // Zero means no cap
+	UpdatedAt   time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// LifecyclePolicy constrains the contexts of a type in a tenant. Zero limits
+// are not enforced.
+type LifecyclePolicy struct {
+	TenantID       string         `json:"tenant_id" db:"tenant_id"`
+	ContextType    string         `json:"context_type" db:"context_type"`
+	DefaultTTL     time.Duration  `json:"default_ttl" db:"default_ttl"`           // TTL of contexts created without one
+	MaxTTL         time.Duration  `json:"max_ttl" db:"max_ttl"`                   // Longest TTL a context may ask for
+	MaxPayloadSize int            `json:"max_payload_size" db:"max_payload_size"` // Bytes
+	MaxRevisions   int            `json:"max_revisions" db:"max_revisions"`       // Revisions kept; older ones are pruned on write
+	AllowedScopes  []ContextScope `json:"allowed_scopes,omitempty" db:"allowed_scopes"`
+	NonOwnerDelete bool           `json:"non_owner_delete" db:"non_owner_delete"` // Agents that may write a context may also delete it
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// ContextLease grants one agent exclusive write access to a context until it
+// expires. Every lease granted gets a higher fencing token than the last, so
+// writes made under a lease that has since been lost can be told apart.
+type ContextLease struct {
+	ContextID    string        `json:"context_id" db:"context_id"`
+	TenantID     string        `json:"tenant_id" db:"tenant_id"`
+	HolderID     string        `json:"holder_id" db:"holder_id"` // Agent holding the lease
+	FencingToken int64         `json:"fencing_token" db:"fencing_token"`
+	TTL          time.Duration `json:"ttl" db:"ttl"` // Length of the lease from acquisition or the last renewal
+	AcquiredAt   time.Time     `json:"acquired_at" db:"acquired_at"`
+	ExpiresAt    time.Time     `json:"expires_at" db:"expires_at"`
+}
+
+// Embedding is the embedding of a context
+type Embedding struct {
+	ContextID string
+	Vector    []float32
+}
+
+// ContextEvent is an entry in the per-tenant context event log. IDs increase
+// monotonically, so a consumer resumes by asking for events after the last ID
+// it has seen.
+type ContextEvent struct {
+	ID            int64             `json:"id" db:"id"`
+	Type          ContextEventType  `json:"type" db:"type"`
+	TenantID      string            `json:"tenant_id" db:"tenant_id"`
+	ContextID     string            `json:"context_id" db:"context_id"`
+	ContextType   string            `json:"context_type" db:"context_type"`
+	AgentID       string            `json:"agent_id" db:"agent_id"` // Owner of the context
+	Revision      int               `json:"revision" db:"revision"`
+	Metadata      map[string]string `json:"metadata,omitempty" db:"metadata"` // Context metadata when the event was recorded
+	AccessControl AccessControl     `json:"-" db:"access_control"`            // Who may see the event
+	OccurredAt    time.Time         `json:"occurred_at" db:"occurred_at"`
+}
+
+// Schema is a registered JSON Schema document for the payloads of a context
+// type. Versions of a type are numbered from 1 and are immutable.
+type Schema struct {
+	ID          string          `json:"id"`
+	TenantID    string          `json:"tenant_id" db:"tenant_id"`
+	ContextType string          `json:"context_type" db:"context_type"` // Context type the schema applies to
+	Version     int             `json:"version" db:"version"`           // Version within the context type
+	Document    json.RawMessage `json:"schema" db:"document"`           // JSON Schema document
+	Description string          `json:"description,omitempty" db:"description"`
+	CreatedBy   string          `json:"created_by" db:"created_by"` // Agent that registered the schema
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// DataKey is a tenant's key for encrypting context payloads at rest. It is
+// only ever stored wrapped (encrypted) by a master key.
+type DataKey struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id" db:"tenant_id"`
+	MasterKeyID string    `json:"master_key_id" db:"master_key_id"` // Master key WrappedKey is encrypted with
+	WrappedKey  []byte    `json:"-" db:"wrapped_key"`
+	Active      bool      `json:"active" db:"active"` // Whether new data is encrypted with this key
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
 // Message represents communication between agents
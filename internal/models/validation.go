@@ -3,21 +3,38 @@ package models
 import (
 	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
 )
 
 var (
-	ErrInvalidAgentID     = errors.New("invalid agent ID")
-	ErrInvalidAgentType   = errors.New("invalid agent type")
-	ErrInvalidContextID   = errors.New("invalid context ID")
-	ErrInvalidContextType = errors.New("invalid context type")
-	ErrPayloadTooLarge    = errors.New("payload too large for direct context")
-	ErrInvalidScope       = errors.New("invalid context scope")
-	ErrInvalidMessageType = errors.New("invalid message type")
+	ErrInvalidAgentID       = errors.New("invalid agent ID")
+	ErrInvalidAgentType     = errors.New("invalid agent type")
+	ErrInvalidContextID     = errors.New("invalid context ID")
+	ErrInvalidContextType   = errors.New("invalid context type")
+	ErrPayloadTooLarge      = errors.New("payload too large for direct context")
+	ErrInvalidScope         = errors.New("invalid context scope")
+	ErrInvalidMessageType   = errors.New("invalid message type")
+	ErrInvalidCompatibility = errors.New("invalid schema compatibility")
+	ErrInvalidEventType     = errors.New("invalid context event type")
+	ErrInvalidMetric        = errors.New("invalid similarity metric")
+	ErrInvalidEmbedding     = errors.New("invalid embedding")
+	ErrInvalidLineage       = errors.New("invalid lineage")
+	ErrInvalidDirection     = errors.New("invalid lineage direction")
+	ErrInvalidBatchMode     = errors.New("invalid batch mode")
+	ErrInvalidConflict      = errors.New("invalid conflict policy")
+	ErrInvalidExpiration    = errors.New("invalid expiration")
+	ErrInvalidPolicy        = errors.New("invalid lifecycle policy")
 )
 
 const (
-	MaxDirectContextSize = 1 * 1024 * 1024 // 1MB
+	MaxDirectContextSize  = 1 * 1024 * 1024 // 1MB
+	MaxEmbeddingDimension = 4096
+	MaxContextParents     = 64
+	MaxRelationLength     = 64
 )
 
 // ValidateAgent validates an Agent struct
@@ -48,6 +65,61 @@ func (c *Context) Validate() error {
 	if err := c.AccessControl.Validate(); err != nil {
 		return err
 	}
+	if c.Embedding != nil {
+		if err := ValidateEmbedding(c.Embedding); err != nil {
+			return err
+		}
+	}
+	if err := ValidateLinks(c.ID, c.DerivedFrom); err != nil {
+		return err
+	}
+	if c.Expiration != "" {
+		if err := c.Expiration.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.MaxLifetime < 0 {
+		return fmt.Errorf("%w: max lifetime cannot be negative", ErrInvalidExpiration)
+	}
+	return nil
+}
+
+// ValidateEmbedding checks that v has between 1 and MaxEmbeddingDimension
+// finite components
+func ValidateEmbedding(v []float32) error {
+	if len(v) == 0 || len(v) > MaxEmbeddingDimension {
+		return fmt.Errorf("%w: dimension %d is not between 1 and %d", ErrInvalidEmbedding, len(v), MaxEmbeddingDimension)
+	}
+	for i, x := range v {
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return fmt.Errorf("%w: component %d is not finite", ErrInvalidEmbedding, i)
+		}
+	}
+	return nil
+}
+
+// ValidateLinks checks the derivation links of the context with the given
+// ID: at most MaxContextParents, none to the context itself and no duplicate
+// parent and relation pairs. Empty relations must already have been
+// defaulted.
+func ValidateLinks(contextID string, links []ContextLink) error {
+	if len(links) > MaxContextParents {
+		return fmt.Errorf("%w: %d parents exceed max %d", ErrInvalidLineage, len(links), MaxContextParents)
+	}
+	seen := make(map[ContextLink]bool, len(links))
+	for _, l := range links {
+		switch {
+		case l.ParentID == "":
+			return fmt.Errorf("%w: parent ID cannot be empty", ErrInvalidLineage)
+		case l.ParentID == contextID:
+			return fmt.Errorf("%w: context cannot derive from itself", ErrInvalidLineage)
+		case l.Relation == "" || len(l.Relation) > MaxRelationLength || strings.ContainsFunc(l.Relation, unicode.IsSpace):
+			return fmt.Errorf("%w: relation %q must be 1 to %d characters without spaces", ErrInvalidLineage, l.Relation, MaxRelationLength)
+		case seen[l]:
+			return fmt.Errorf("%w: duplicate link to %s", ErrInvalidLineage, l.ParentID)
+		}
+		seen[l] = true
+	}
 	return nil
 }
 
@@ -102,3 +174,164 @@ func (c *Context) CalculateExpiration() time.Time {
 	}
 	return time.Now().Add(c.TTL)
 }
+
+// Deadline returns the time the expiry of c can never move past, or the
+// zero time when its lifetime is not capped
+func (c *Context) Deadline() time.Time {
+	if c.MaxLifetime <= 0 {
+		return time.Time{}
+	}
+	return c.CreatedAt.Add(c.MaxLifetime)
+}
+
+// ExpiryFrom returns the expiry of c when extended by its TTL from now,
+// capped at its deadline
+func (c *Context) ExpiryFrom(now time.Time) time.Time {
+	expiresAt := now.Add(c.TTL)
+	if deadline := c.Deadline(); !deadline.IsZero() && expiresAt.After(deadline) {
+		return deadline
+	}
+	return expiresAt
+}
+
+// Sliding reports whether writes to c move its expiry
+func (c *Context) Sliding() bool {
+	return c.Expiration == ExpirationSlidingOnRead || c.Expiration == ExpirationSlidingOnWrite
+}
+
+// Validate checks that p is a known expiration policy
+func (p ExpirationPolicy) Validate() error {
+	switch p {
+	case ExpirationFixed, ExpirationSlidingOnRead, ExpirationSlidingOnWrite:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown policy %q", ErrInvalidExpiration, p)
+	}
+}
+
+// Validate checks an expiration configuration
+func (c *ExpirationConfig) Validate() error {
+	if c.ContextType == "" {
+		return fmt.Errorf("%w: context type cannot be empty", ErrInvalidContextType)
+	}
+	if err := c.Policy.Validate(); err != nil {
+		return err
+	}
+	if c.TTL < 0 || c.MaxLifetime < 0 {
+		return fmt.Errorf("%w: ttl and max lifetime cannot be negative", ErrInvalidExpiration)
+	}
+	if c.MaxLifetime > 0 && c.TTL > c.MaxLifetime {
+		return fmt.Errorf("%w: ttl exceeds max lifetime", ErrInvalidExpiration)
+	}
+	return nil
+}
+
+// Validate checks a lifecycle policy
+func (p *LifecyclePolicy) Validate() error {
+	if p.ContextType == "" {
+		return fmt.Errorf("%w: context type cannot be empty", ErrInvalidContextType)
+	}
+	if p.DefaultTTL < 0 || p.MaxTTL < 0 || p.MaxPayloadSize < 0 || p.MaxRevisions < 0 {
+		return fmt.Errorf("%w: limits cannot be negative", ErrInvalidPolicy)
+	}
+	if p.MaxTTL > 0 && p.DefaultTTL > p.MaxTTL {
+		return fmt.Errorf("%w: default ttl exceeds max ttl", ErrInvalidPolicy)
+	}
+	if p.MaxPayloadSize > MaxDirectContextSize {
+		return fmt.Errorf("%w: max payload size exceeds %d", ErrInvalidPolicy, MaxDirectContextSize)
+	}
+	for _, scope := range p.AllowedScopes {
+		switch scope {
+		case ScopePublic, ScopePrivate, ScopeGroup, ScopeShared:
+		default:
+			return fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+// Violations returns how c breaks the policy, empty when it complies
+func (p *LifecyclePolicy) Violations(c *Context) []string {
+	var violations []string
+	if p.MaxTTL > 0 && c.TTL > p.MaxTTL {
+		violations = append(violations, fmt.Sprintf("ttl %s exceeds the maximum of %s", c.TTL, p.MaxTTL))
+	}
+	if p.MaxPayloadSize > 0 && len(c.Payload) > p.MaxPayloadSize {
+		violations = append(violations, fmt.Sprintf("payload of %d bytes exceeds the maximum of %d", len(c.Payload), p.MaxPayloadSize))
+	}
+	if len(p.AllowedScopes) > 0 && !slices.Contains(p.AllowedScopes, c.AccessControl.Scope) {
+		violations = append(violations, fmt.Sprintf("scope %s is not allowed", c.AccessControl.Scope))
+	}
+	return violations
+}
+
+// Validate checks that c is a known compatibility mode
+func (c SchemaCompatibility) Validate() error {
+	switch c {
+	case CompatibilityBackward, CompatibilityForward, CompatibilityFull, CompatibilityNone:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidCompatibility, c)
+	}
+}
+
+// Validate checks that m is a known similarity metric
+func (m SimilarityMetric) Validate() error {
+	switch m {
+	case MetricCosine, MetricDot:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidMetric, m)
+	}
+}
+
+// Validate checks that c declares a usable dimension and metric
+func (c *EmbeddingConfig) Validate() error {
+	if c.ContextType == "" {
+		return fmt.Errorf("%w: context type cannot be empty", ErrInvalidContextType)
+	}
+	if c.Dimension <= 0 || c.Dimension > MaxEmbeddingDimension {
+		return fmt.Errorf("%w: dimension %d is not between 1 and %d", ErrInvalidEmbedding, c.Dimension, MaxEmbeddingDimension)
+	}
+	return c.Metric.Validate()
+}
+
+// Validate checks that d is a known lineage direction
+func (d LineageDirection) Validate() error {
+	switch d {
+	case LineageAncestors, LineageDescendants:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidDirection, d)
+	}
+}
+
+// Validate checks that m is a known batch mode
+func (m BatchMode) Validate() error {
+	switch m {
+	case BatchAtomic, BatchPartial:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidBatchMode, m)
+	}
+}
+
+// Validate checks that p is a known conflict policy
+func (p ConflictPolicy) Validate() error {
+	switch p {
+	case ConflictFail, ConflictSkip, ConflictOverwrite:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidConflict, p)
+	}
+}
+
+// Validate checks that t is a known context event type
+func (t ContextEventType) Validate() error {
+	switch t {
+	case ContextEventCreated, ContextEventUpdated, ContextEventDeleted, ContextEventRestored, ContextEventExpired, ContextEventStale:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidEventType, t)
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+	"math"
 	"testing"
 	"time"
 )
@@ -108,6 +110,26 @@ func TestContext_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "embedding with NaN",
+			context: &Context{
+				Type:          "user-profile",
+				AgentID:       "agent-1",
+				Embedding:     []float32{0.5, float32(math.NaN())},
+				AccessControl: AccessControl{Scope: ScopePublic},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty embedding",
+			context: &Context{
+				Type:          "user-profile",
+				AgentID:       "agent-1",
+				Embedding:     []float32{},
+				AccessControl: AccessControl{Scope: ScopePublic},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -276,3 +298,109 @@ func TestContext_CalculateExpiration(t *testing.T) {
 		t.Error("CalculateExpiration() should return future time")
 	}
 }
+
+func TestEmbeddingConfig_Validate(t *testing.T) {
+	valid := &EmbeddingConfig{ContextType: "memory", Dimension: 384, Metric: MetricCosine}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+	for _, cfg := range []*EmbeddingConfig{
+		{Dimension: 384, Metric: MetricCosine},
+		{ContextType: "memory", Dimension: 0, Metric: MetricDot},
+		{ContextType: "memory", Dimension: MaxEmbeddingDimension + 1, Metric: MetricDot},
+		{ContextType: "memory", Dimension: 384, Metric: "euclidean"},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", cfg)
+		}
+	}
+}
+
+func TestValidateLinks(t *testing.T) {
+	valid := []ContextLink{{ParentID: "p1", Relation: DefaultRelation}, {ParentID: "p1", Relation: "summarizes"}, {ParentID: "p2", Relation: "summarizes"}}
+	if err := ValidateLinks("c1", valid); err != nil {
+		t.Fatalf("expected valid links, got %v", err)
+	}
+	for name, links := range map[string][]ContextLink{
+		"self":           {{ParentID: "c1", Relation: DefaultRelation}},
+		"empty parent":   {{Relation: DefaultRelation}},
+		"empty relation": {{ParentID: "p1"}},
+		"spaced":         {{ParentID: "p1", Relation: "derived from"}},
+		"duplicate":      {{ParentID: "p1", Relation: "summarizes"}, {ParentID: "p1", Relation: "summarizes"}},
+		"too many":       make([]ContextLink, MaxContextParents+1),
+	} {
+		if err := ValidateLinks("c1", links); !errors.Is(err, ErrInvalidLineage) {
+			t.Errorf("%s: expected ErrInvalidLineage, got %v", name, err)
+		}
+	}
+}
+
+func TestContext_ExpiryFrom(t *testing.T) {
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := &Context{CreatedAt: created, TTL: time.Hour}
+	if got := c.ExpiryFrom(created.Add(5 * time.Hour)); !got.Equal(created.Add(6 * time.Hour)) {
+		t.Errorf("uncapped expiry: got %v", got)
+	}
+
+	c.MaxLifetime = 2 * time.Hour
+	if got := c.ExpiryFrom(created.Add(30 * time.Minute)); !got.Equal(created.Add(90 * time.Minute)) {
+		t.Errorf("expiry before the cap: got %v", got)
+	}
+	if got := c.ExpiryFrom(created.Add(90 * time.Minute)); !got.Equal(c.Deadline()) {
+		t.Errorf("expected expiry capped at %v, got %v", c.Deadline(), got)
+	}
+}
+
+func TestExpirationConfig_Validate(t *testing.T) {
+	valid := &ExpirationConfig{ContextType: "session", Policy: ExpirationSlidingOnRead, TTL: time.Hour, MaxLifetime: 24 * time.Hour}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+	for _, cfg := range []*ExpirationConfig{
+		{Policy: ExpirationFixed},
+		{ContextType: "session", Policy: "sliding"},
+		{ContextType: "session", Policy: ExpirationFixed, TTL: -time.Second},
+		{ContextType: "session", Policy: ExpirationFixed, TTL: 2 * time.Hour, MaxLifetime: time.Hour},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", cfg)
+		}
+	}
+	c := &Context{Type: "session", AgentID: "a", AccessControl: AccessControl{Scope: ScopePublic}, Expiration: "sliding"}
+	if err := c.Validate(); !errors.Is(err, ErrInvalidExpiration) {
+		t.Errorf("expected ErrInvalidExpiration, got %v", err)
+	}
+}
+
+func TestLifecyclePolicy(t *testing.T) {
+	p := &LifecyclePolicy{
+		ContextType:    "session",
+		DefaultTTL:     time.Hour,
+		MaxTTL:         24 * time.Hour,
+		MaxPayloadSize: 4,
+		AllowedScopes:  []ContextScope{ScopePrivate, ScopeGroup},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("expected valid policy, got %v", err)
+	}
+	for _, invalid := range []*LifecyclePolicy{
+		{},
+		{ContextType: "session", MaxRevisions: -1},
+		{ContextType: "session", DefaultTTL: 2 * time.Hour, MaxTTL: time.Hour},
+		{ContextType: "session", MaxPayloadSize: MaxDirectContextSize + 1},
+		{ContextType: "session", AllowedScopes: []ContextScope{"team"}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+
+	ok := &Context{TTL: time.Hour, Payload: []byte("abcd"), AccessControl: AccessControl{Scope: ScopePrivate}}
+	if v := p.Violations(ok); len(v) != 0 {
+		t.Errorf("expected no violations, got %v", v)
+	}
+	bad := &Context{TTL: 48 * time.Hour, Payload: []byte("abcde"), AccessControl: AccessControl{Scope: ScopePublic}}
+	if v := p.Violations(bad); len(v) != 3 {
+		t.Errorf("expected three violations, got %v", v)
+	}
+}
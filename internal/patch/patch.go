@@ -0,0 +1,88 @@
+// Package patch applies JSON Merge Patch (RFC 7386) and JSON Patch (RFC 6902)
+// documents to JSON payloads.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")                 // The patch document itself is malformed
+	ErrNotJSON      = errors.New("target is not a JSON document") // The payload being patched is not JSON
+	ErrPathNotFound = errors.New("path not found")                // An operation refers to a missing location
+	ErrTestFailed   = errors.New("test operation failed")         // A JSON Patch test operation did not match
+)
+
+// MergePatch applies an RFC 7386 merge patch to target. An empty target is
+// treated as null.
+func MergePatch(target, patch []byte) ([]byte, error) {
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	doc, err := decodeTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	return encode(mergeValue(doc, p))
+}
+
+// mergeValue implements the MergePatch algorithm from RFC 7386 section 2
+func mergeValue(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{}, len(p))
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+			continue
+		}
+		t[name] = mergeValue(t[name], value)
+	}
+	return t
+}
+
+// decodeTarget decodes the document being patched; empty means null
+func decodeTarget(target []byte) (interface{}, error) {
+	if len(bytes.TrimSpace(target)) == 0 {
+		return nil, nil
+	}
+	doc, err := decode(target)
+	if err != nil {
+		return nil, ErrNotJSON
+	}
+	return doc, nil
+}
+
+// decode parses a single JSON value, preserving number formatting
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+// encode serialises a document without HTML escaping
+func encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
@@ -0,0 +1,147 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/acb/internal/constants"
+	"github.com/acb/internal/storage"
+	"github.com/acb/internal/worker"
+)
+
+// reaperLockName is the cluster-wide lock held while a replica sweeps
+const reaperLockName = "acb:registry:reaper"
+
+// ReaperConfig configures the agent liveness reaper
+type ReaperConfig struct {
+	Interval     time.Duration // Time between sweeps
+	OfflineAfter time.Duration // Mark agents offline after this long without a heartbeat
+	DeleteAfter  time.Duration // Delete offline agents after this long without a heartbeat (0 disables)
+	PurgeAfter   time.Duration // Permanently remove deleted agents this long after deletion (0 disables)
+}
+
+// DefaultReaperConfig returns the reaper configuration derived from constants
+func DefaultReaperConfig() ReaperConfig {
+	return ReaperConfig{
+		Interval:     time.Duration(constants.AgentSweepInterval) * time.Second,
+		OfflineAfter: time.Duration(constants.AgentTimeoutThreshold) * time.Second,
+		PurgeAfter:   time.Duration(constants.DefaultRestoreWindow) * time.Second,
+	}
+}
+
+// Validate validates a ReaperConfig
+func (c ReaperConfig) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("sweep interval must be positive")
+	}
+	if c.OfflineAfter <= 0 {
+		return fmt.Errorf("offline threshold must be positive")
+	}
+	if c.DeleteAfter < 0 {
+		return fmt.Errorf("delete grace period cannot be negative")
+	}
+	if c.DeleteAfter > 0 && c.DeleteAfter < c.OfflineAfter {
+		return fmt.Errorf("delete grace period %s is shorter than offline threshold %s", c.DeleteAfter, c.OfflineAfter)
+	}
+	if c.PurgeAfter < 0 {
+		return fmt.Errorf("purge delay cannot be negative")
+	}
+	return nil
+}
+
+// SweepResult reports what a single sweep did
+type SweepResult struct {
+	MarkedOffline int
+	Deleted       int
+	Purged        int  // Deleted agents permanently removed
+	Skipped       bool // Another replica held the lock
+}
+
+// Reaper periodically marks agents without recent heartbeats offline,
+// optionally deletes agents that have been offline for too long and purges
+// agents deleted longer ago than PurgeAfter
+type Reaper struct {
+	store  storage.AgentStore
+	locker storage.Locker
+	config ReaperConfig
+	now    func() time.Time
+}
+
+// NewReaper creates a new liveness reaper. A nil locker means every
+// replica sweeps independently.
+func NewReaper(store storage.AgentStore, locker storage.Locker, config ReaperConfig) (*Reaper, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid reaper config: %w", err)
+	}
+	return &Reaper{
+		store:  store,
+		locker: locker,
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+// WithClock overrides the time source used to compute thresholds
+func (r *Reaper) WithClock(now func() time.Time) *Reaper {
+	r.now = now
+	return r
+}
+
+// Sweep runs a single liveness pass
+func (r *Reaper) Sweep(ctx context.Context) (*SweepResult, error) {
+	unlock, acquired, err := worker.TryLock(ctx, r.locker, reaperLockName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire reaper lock: %w", err)
+	}
+	if !acquired {
+		return &SweepResult{Skipped: true}, nil
+	}
+	defer unlock()
+
+	now := r.now()
+	result := &SweepResult{}
+
+	marked, err := r.store.MarkOffline(ctx, now.Add(-r.config.OfflineAfter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark agents offline: %w", err)
+	}
+	result.MarkedOffline = marked
+
+	if r.config.DeleteAfter > 0 {
+		deleted, err := r.store.DeleteOffline(ctx, now.Add(-r.config.DeleteAfter))
+		if err != nil {
+			return result, fmt.Errorf("failed to delete offline agents: %w", err)
+		}
+		result.Deleted = deleted
+	}
+
+	if r.config.PurgeAfter > 0 {
+		purged, err := r.store.PurgeDeleted(ctx, now.Add(-r.config.PurgeAfter))
+		if err != nil {
+			return result, fmt.Errorf("failed to purge deleted agents: %w", err)
+		}
+		result.Purged = purged
+	}
+
+	return result, nil
+}
+
+// Run sweeps on every interval until ctx is cancelled. Errors and panics
+// in a sweep are logged and the next sweep still runs.
+func (r *Reaper) Run(ctx context.Context) {
+	worker.Run(ctx, "agent reaper", r.config.Interval, func(ctx context.Context) error {
+		result, err := r.Sweep(ctx)
+		if err != nil {
+			return err
+		}
+		if result.MarkedOffline > 0 || result.Deleted > 0 {
+			log.Printf("agent reaper: marked %d agents offline, deleted %d", result.MarkedOffline, result.Deleted)
+		}
+		if result.Purged > 0 {
+			log.Printf("agent reaper: purged %d deleted agents past the restore window", result.Purged)
+		}
+		return nil
+	})
+}
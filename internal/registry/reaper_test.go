@@ -0,0 +1,244 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockLocker is an in-process Locker shared by simulated replicas
+type mockLocker struct {
+	mu     sync.Mutex
+	held   map[string]bool
+	err    error
+	grants int
+}
+
+func newMockLocker() *mockLocker {
+	return &mockLocker{held: make(map[string]bool)}
+}
+
+func (l *mockLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return nil, false, l.err
+	}
+	if l.held[name] {
+		return nil, false, nil
+	}
+	l.held[name] = true
+	l.grants++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, name)
+	}, true, nil
+}
+
+// fakeClock is a manually advanced time source
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func seedAgent(store *MockAgentStore, id string, status models.AgentStatus, lastSeen time.Time) {
+	store.agents[id] = &models.Agent{
+		ID:       id,
+		Type:     "ml",
+		Status:   status,
+		TenantID: "default",
+		LastSeen: lastSeen,
+	}
+}
+
+func TestReaperConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ReaperConfig
+		wantErr bool
+	}{
+		{"defaults", DefaultReaperConfig(), false},
+		{"zero interval", ReaperConfig{OfflineAfter: time.Minute}, true},
+		{"zero offline threshold", ReaperConfig{Interval: time.Second}, true},
+		{"negative delete", ReaperConfig{Interval: time.Second, OfflineAfter: time.Minute, DeleteAfter: -time.Second}, true},
+		{"delete shorter than offline", ReaperConfig{Interval: time.Second, OfflineAfter: time.Minute, DeleteAfter: time.Second}, true},
+		{"delete enabled", ReaperConfig{Interval: time.Second, OfflineAfter: time.Minute, DeleteAfter: time.Hour}, false},
+		{"negative purge", ReaperConfig{Interval: time.Second, OfflineAfter: time.Minute, PurgeAfter: -time.Second}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestReaper_Sweep_MarksSilentAgentsOffline(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMockAgentStore()
+	seedAgent(store, "fresh", models.AgentStatusOnline, clock.Now().Add(-10*time.Second))
+	seedAgent(store, "silent", models.AgentStatusOnline, clock.Now().Add(-2*time.Minute))
+	seedAgent(store, "never-seen", models.AgentStatusUnknown, clock.Now().Add(-5*time.Minute))
+
+	reaper, err := NewReaper(store, nil, ReaperConfig{Interval: time.Second, OfflineAfter: 90 * time.Second})
+	require.NoError(t, err)
+	reaper.WithClock(clock.Now)
+
+	result, err := reaper.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.MarkedOffline)
+	assert.Equal(t, 0, result.Deleted)
+	assert.Equal(t, models.AgentStatusOnline, store.agents["fresh"].Status)
+	assert.Equal(t, models.AgentStatusOffline, store.agents["silent"].Status)
+	assert.Equal(t, models.AgentStatusOffline, store.agents["never-seen"].Status)
+
+	// Fresh agent goes silent once the clock passes the threshold
+	clock.Advance(2 * time.Minute)
+	result, err = reaper.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.MarkedOffline)
+	assert.Equal(t, models.AgentStatusOffline, store.agents["fresh"].Status)
+}
+
+func TestReaper_Sweep_DeletesAfterGracePeriod(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMockAgentStore()
+	seedAgent(store, "recent", models.AgentStatusOnline, clock.Now().Add(-2*time.Minute))
+	seedAgent(store, "long-dead", models.AgentStatusOffline, clock.Now().Add(-2*time.Hour))
+
+	reaper, err := NewReaper(store, nil, ReaperConfig{
+		Interval:     time.Second,
+		OfflineAfter: 90 * time.Second,
+		DeleteAfter:  time.Hour,
+	})
+	require.NoError(t, err)
+	reaper.WithClock(clock.Now)
+
+	result, err := reaper.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.MarkedOffline)
+	assert.Equal(t, 1, result.Deleted)
+	assert.Contains(t, store.agents, "recent")
+	assert.NotContains(t, store.agents, "long-dead")
+
+	clock.Advance(time.Hour)
+	result, err = reaper.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Deleted)
+	assert.Empty(t, store.agents)
+}
+
+func TestReaper_Sweep_PurgesDeletedAgents(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMockAgentStore()
+	seedAgent(store, "recent", models.AgentStatusOffline, clock.Now().Add(-2*time.Hour))
+	seedAgent(store, "old", models.AgentStatusOffline, clock.Now().Add(-2*time.Hour))
+	store.softDelete("recent")
+	store.softDelete("old")
+	store.deleted["recent"].DeletedAt = timePtr(clock.Now().Add(-time.Hour))
+	store.deleted["old"].DeletedAt = timePtr(clock.Now().Add(-3 * time.Hour))
+
+	reaper, err := NewReaper(store, nil, ReaperConfig{
+		Interval:     time.Second,
+		OfflineAfter: 90 * time.Second,
+		PurgeAfter:   2 * time.Hour,
+	})
+	require.NoError(t, err)
+	reaper.WithClock(clock.Now)
+
+	result, err := reaper.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Purged)
+	assert.Contains(t, store.deleted, "recent")
+	assert.NotContains(t, store.deleted, "old")
+}
+
+func TestReaper_Sweep_SkipsWhenLockHeld(t *testing.T) {
+	store := NewMockAgentStore()
+	seedAgent(store, "silent", models.AgentStatusOnline, time.Now().Add(-time.Hour))
+
+	locker := newMockLocker()
+	unlock, acquired, err := locker.TryLock(context.Background(), reaperLockName)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	reaper, err := NewReaper(store, locker, DefaultReaperConfig())
+	require.NoError(t, err)
+
+	result, err := reaper.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.True(t, result.Skipped)
+	assert.Equal(t, models.AgentStatusOnline, store.agents["silent"].Status)
+
+	unlock()
+	result, err = reaper.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.False(t, result.Skipped)
+	assert.Equal(t, 1, result.MarkedOffline)
+
+	// Lock must be released after the sweep
+	assert.Empty(t, locker.held)
+}
+
+func TestReaper_Sweep_LockError(t *testing.T) {
+	locker := newMockLocker()
+	locker.err = errors.New("database unavailable")
+
+	reaper, err := NewReaper(NewMockAgentStore(), locker, DefaultReaperConfig())
+	require.NoError(t, err)
+
+	_, err = reaper.Sweep(context.Background())
+	assert.Error(t, err)
+}
+
+func TestReaper_Run_StopsOnCancel(t *testing.T) {
+	store := NewMockAgentStore()
+	locker := newMockLocker()
+
+	reaper, err := NewReaper(store, locker, ReaperConfig{Interval: time.Millisecond, OfflineAfter: time.Minute})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reaper.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		locker.mu.Lock()
+		defer locker.mu.Unlock()
+		return locker.grants >= 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reaper did not stop after cancel")
+	}
+}
+
+func TestNewReaper_InvalidConfig(t *testing.T) {
+	_, err := NewReaper(NewMockAgentStore(), nil, ReaperConfig{})
+	assert.Error(t, err)
+}
@@ -2,26 +2,43 @@ package registry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/acb/internal/auth"
+	"github.com/acb/internal/constants"
+	acberrors "github.com/acb/internal/errors"
 	"github.com/acb/internal/models"
 	"github.com/acb/internal/storage"
 )
 
 // Service provides agent registry operations
 type Service struct {
-	store storage.AgentStore
-	// cache storage.AgentCache // Will be implemented later
+	store         storage.AgentStore
+	restoreWindow time.Duration // How long deleted agents can be restored
 }
 
 // NewService creates a new registry service
 func NewService(store storage.AgentStore) *Service {
 	return &Service{
-		store: store,
+		store:         store,
+		restoreWindow: constants.DefaultRestoreWindow * time.Second,
 	}
 }
 
+// WithRestoreWindow sets how long deleted agents can be restored. It should
+// match the delay after which the reaper purges them.
+func (s *Service) WithRestoreWindow(window time.Duration) *Service {
+	s.restoreWindow = window
+	return s
+}
+
+// RestoreWindow returns how long deleted agents can be restored
+func (s *Service) RestoreWindow() time.Duration {
+	return s.restoreWindow
+}
+
 // Register registers a new agent
 func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*models.Agent, error) {
 	agent := &models.Agent{
@@ -48,16 +65,164 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*models.A
 	return agent, nil
 }
 
+// RegisterResult is the outcome of one agent of a batch registration
+type RegisterResult struct {
+	Agent *models.Agent
+	Err   error
+}
+
+// RegisterBatch registers agents in one transaction, validating each as
+// Register does. In atomic mode the first failing agent fails the batch with
+// a *storage.BatchError and nothing is registered; in partial mode each
+// agent succeeds or fails alone. IDs already taken are reported as conflicts.
+func (s *Service) RegisterBatch(ctx context.Context, mode models.BatchMode, reqs []*RegisterRequest) ([]*RegisterResult, error) {
+	if err := mode.Validate(); err != nil {
+		return nil, acberrors.ValidationError(err.Error())
+	}
+	if len(reqs) == 0 || len(reqs) > constants.MaxBatchItems {
+		return nil, acberrors.ValidationError(fmt.Sprintf("batch must have between 1 and %d items", constants.MaxBatchItems))
+	}
+
+	results := make([]*RegisterResult, len(reqs))
+	var pending []*models.Agent
+	var indexes []int
+	seen := make(map[string]bool, len(reqs))
+	for i, req := range reqs {
+		agent := &models.Agent{
+			ID:           req.ID,
+			Type:         req.Type,
+			Location:     req.Location,
+			Capabilities: req.Capabilities,
+			Metadata:     req.Metadata,
+			Status:       models.AgentStatusUnknown,
+			TenantID:     req.TenantID,
+			CreatedAt:    time.Now(),
+			LastSeen:     time.Now(),
+		}
+		err := agent.Validate()
+		switch {
+		case err != nil:
+			err = acberrors.ValidationError(fmt.Sprintf("validation failed: %v", err)).WithError(err)
+		case seen[agent.ID]:
+			err = acberrors.ValidationError(fmt.Sprintf("agent %s appears more than once in the batch", agent.ID))
+		}
+		seen[agent.ID] = true
+		if err != nil {
+			if mode == models.BatchAtomic {
+				return nil, &storage.BatchError{Index: i, Err: err}
+			}
+			results[i] = &RegisterResult{Err: err}
+			continue
+		}
+		results[i] = &RegisterResult{Agent: agent}
+		pending = append(pending, agent)
+		indexes = append(indexes, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	errs, err := s.store.CreateBatch(ctx, pending, mode == models.BatchAtomic)
+	var batchErr *storage.BatchError
+	if errors.As(err, &batchErr) {
+		return nil, &storage.BatchError{Index: indexes[batchErr.Index], Err: registerError(pending[batchErr.Index].ID, batchErr.Err)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agents: %w", err)
+	}
+	for j, agent := range pending {
+		if errs[j] != nil {
+			results[indexes[j]] = &RegisterResult{Err: registerError(agent.ID, errs[j])}
+			continue
+		}
+		agent.Status = models.AgentStatusOnline
+	}
+	return results, nil
+}
+
+// registerError translates a failed store create, reporting an ID that is
+// already taken as a conflict
+func registerError(agentID string, err error) error {
+	if storage.IsConflict(err) {
+		return acberrors.Conflict(fmt.Sprintf("agent with ID %s already exists", agentID)).WithError(err)
+	}
+	return fmt.Errorf("failed to create agent: %w", err)
+}
+
 // Get retrieves an agent by ID
 func (s *Service) Get(ctx context.Context, agentID string) (*models.Agent, error) {
 	return s.store.Get(ctx, agentID)
 }
 
-// Unregister removes an agent
+// Unregister deletes an agent. It can be restored within the restore window.
 func (s *Service) Unregister(ctx context.Context, agentID string) error {
 	return s.store.Delete(ctx, agentID)
 }
 
+// Restore brings back a deleted agent within the restore window. Only the
+// agent itself or an admin of its tenant may restore it.
+func (s *Service) Restore(ctx context.Context, agentID string) (*models.Agent, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, acberrors.Unauthorized("caller identity required")
+	}
+
+	agent, err := s.store.GetDeleted(ctx, agentID)
+	if storage.IsNotFound(err) {
+		return nil, acberrors.NotFound("deleted agent not found").WithError(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if agent.TenantID != claims.TenantID {
+		return nil, acberrors.NotFound("deleted agent not found")
+	}
+	if claims.AgentID != agent.ID && !claims.HasRole(auth.RoleAdmin) {
+		return nil, acberrors.Forbidden("only the agent itself or an admin may restore it")
+	}
+	if !s.restorable(agent.DeletedAt) {
+		return nil, acberrors.NotFound(fmt.Sprintf("agent %s was deleted more than %s ago and can no longer be restored", agentID, s.restoreWindow))
+	}
+
+	if err := s.store.Restore(ctx, agentID); storage.IsNotFound(err) {
+		return nil, acberrors.NotFound("deleted agent not found").WithError(err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to restore agent: %w", err)
+	}
+	return s.store.Get(ctx, agentID)
+}
+
+// ListDeleted returns up to limit agents of the caller's tenant that were
+// deleted within the restore window, most recently deleted first. Only admins
+// may list them.
+func (s *Service) ListDeleted(ctx context.Context, limit int) ([]*models.Agent, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, acberrors.Unauthorized("caller identity required")
+	}
+	if !claims.HasRole(auth.RoleAdmin) {
+		return nil, acberrors.Forbidden("only admins may list deleted agents")
+	}
+
+	deleted, err := s.store.ListDeleted(ctx, claims.TenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted agents: %w", err)
+	}
+	restorable := make([]*models.Agent, 0, len(deleted))
+	for _, agent := range deleted {
+		if s.restorable(agent.DeletedAt) {
+			restorable = append(restorable, agent)
+		}
+	}
+	return restorable, nil
+}
+
+// restorable reports whether an agent deleted at deletedAt is still within
+// the restore window
+func (s *Service) restorable(deletedAt *time.Time) bool {
+	return deletedAt != nil && time.Since(*deletedAt) < s.restoreWindow
+}
+
 // Heartbeat updates agent's last seen timestamp
 func (s *Service) Heartbeat(ctx context.Context, agentID string) error {
 	return s.store.UpdateLastSeen(ctx, agentID)
@@ -68,6 +233,11 @@ func (s *Service) Discover(ctx context.Context, filters *storage.AgentFilters) (
 	return s.store.List(ctx, filters)
 }
 
+// Count returns the number of agents matching filters
+func (s *Service) Count(ctx context.Context, filters *storage.AgentFilters) (int, error) {
+	return s.store.Count(ctx, filters)
+}
+
 // RegisterRequest contains agent registration data
 type RegisterRequest struct {
 	ID           string
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
 	"github.com/acb/internal/models"
 	"github.com/acb/internal/storage"
 	"github.com/stretchr/testify/assert"
@@ -20,15 +23,26 @@ var (
 
 // MockAgentStore is a mock implementation for testing
 type MockAgentStore struct {
-	agents map[string]*models.Agent
+	agents  map[string]*models.Agent
+	deleted map[string]*models.Agent
 }
 
 func NewMockAgentStore() *MockAgentStore {
 	return &MockAgentStore{
-		agents: make(map[string]*models.Agent),
+		agents:  make(map[string]*models.Agent),
+		deleted: make(map[string]*models.Agent),
 	}
 }
 
+// softDelete moves an agent to the deleted set
+func (m *MockAgentStore) softDelete(agentID string) {
+	agent := m.agents[agentID]
+	now := time.Now()
+	agent.DeletedAt = &now
+	m.deleted[agentID] = agent
+	delete(m.agents, agentID)
+}
+
 func (m *MockAgentStore) Create(ctx context.Context, agent *models.Agent) error {
 	if _, exists := m.agents[agent.ID]; exists {
 		return fmt.Errorf("agent with ID %s already exists: %w", agent.ID, ErrDuplicate)
@@ -37,6 +51,24 @@ func (m *MockAgentStore) Create(ctx context.Context, agent *models.Agent) error
 	return nil
 }
 
+func (m *MockAgentStore) CreateBatch(ctx context.Context, agents []*models.Agent, atomic bool) ([]error, error) {
+	errs := make([]error, len(agents))
+	for i, agent := range agents {
+		if _, exists := m.agents[agent.ID]; exists {
+			errs[i] = fmt.Errorf("agent with ID %s already exists: %w", agent.ID, storage.ErrConflict)
+			if atomic {
+				return nil, &storage.BatchError{Index: i, Err: errs[i]}
+			}
+		}
+	}
+	for i, agent := range agents {
+		if errs[i] == nil {
+			m.agents[agent.ID] = agent
+		}
+	}
+	return errs, nil
+}
+
 func (m *MockAgentStore) Get(ctx context.Context, agentID string) (*models.Agent, error) {
 	agent, exists := m.agents[agentID]
 	if !exists {
@@ -57,7 +89,7 @@ func (m *MockAgentStore) Delete(ctx context.Context, agentID string) error {
 	if _, exists := m.agents[agentID]; !exists {
 		return fmt.Errorf("agent not found: %w", ErrNotFound)
 	}
-	delete(m.agents, agentID)
+	m.softDelete(agentID)
 	return nil
 }
 
@@ -75,6 +107,11 @@ func (m *MockAgentStore) List(ctx context.Context, filters *storage.AgentFilters
 	return agents, nil
 }
 
+func (m *MockAgentStore) Count(ctx context.Context, filters *storage.AgentFilters) (int, error) {
+	agents, err := m.List(ctx, filters)
+	return len(agents), err
+}
+
 func (m *MockAgentStore) UpdateLastSeen(ctx context.Context, agentID string) error {
 	agent, exists := m.agents[agentID]
 	if !exists {
@@ -85,6 +122,72 @@ func (m *MockAgentStore) UpdateLastSeen(ctx context.Context, agentID string) err
 	return nil
 }
 
+func (m *MockAgentStore) MarkOffline(ctx context.Context, lastSeenBefore time.Time) (int, error) {
+	count := 0
+	for _, agent := range m.agents {
+		if agent.Status != models.AgentStatusOffline && agent.LastSeen.Before(lastSeenBefore) {
+			agent.Status = models.AgentStatusOffline
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockAgentStore) DeleteOffline(ctx context.Context, lastSeenBefore time.Time) (int, error) {
+	count := 0
+	for id, agent := range m.agents {
+		if agent.Status == models.AgentStatusOffline && agent.LastSeen.Before(lastSeenBefore) {
+			m.softDelete(id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockAgentStore) GetDeleted(ctx context.Context, agentID string) (*models.Agent, error) {
+	agent, exists := m.deleted[agentID]
+	if !exists {
+		return nil, fmt.Errorf("deleted agent not found: %w", storage.ErrNotFound)
+	}
+	return agent, nil
+}
+
+func (m *MockAgentStore) ListDeleted(ctx context.Context, tenantID string, limit int) ([]*models.Agent, error) {
+	var agents []*models.Agent
+	for _, agent := range m.deleted {
+		if agent.TenantID == tenantID {
+			agents = append(agents, agent)
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].DeletedAt.After(*agents[j].DeletedAt) })
+	if len(agents) > limit {
+		agents = agents[:limit]
+	}
+	return agents, nil
+}
+
+func (m *MockAgentStore) Restore(ctx context.Context, agentID string) error {
+	agent, exists := m.deleted[agentID]
+	if !exists {
+		return fmt.Errorf("deleted agent not found: %w", storage.ErrNotFound)
+	}
+	agent.DeletedAt = nil
+	m.agents[agentID] = agent
+	delete(m.deleted, agentID)
+	return nil
+}
+
+func (m *MockAgentStore) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	count := 0
+	for id, agent := range m.deleted {
+		if agent.DeletedAt.Before(before) {
+			delete(m.deleted, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
 func TestService_Register(t *testing.T) {
 	mockStore := NewMockAgentStore()
 	service := NewService(mockStore)
@@ -119,6 +222,47 @@ func TestService_Register_Duplicate(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestService_RegisterBatch(t *testing.T) {
+	mockStore := NewMockAgentStore()
+	service := NewService(mockStore)
+	ctx := context.Background()
+
+	results, err := service.RegisterBatch(ctx, models.BatchAtomic, []*RegisterRequest{
+		{ID: "planner", Type: "llm", TenantID: "default"},
+		{ID: "critic", Type: "llm", TenantID: "default"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "critic", results[1].Agent.ID)
+	assert.Equal(t, models.AgentStatusOnline, results[1].Agent.Status)
+	assert.Len(t, mockStore.agents, 2)
+
+	// A taken ID fails an atomic batch without registering the others
+	_, err = service.RegisterBatch(ctx, models.BatchAtomic, []*RegisterRequest{
+		{ID: "writer", Type: "llm", TenantID: "default"},
+		{ID: "planner", Type: "llm", TenantID: "default"},
+	})
+	var batchErr *storage.BatchError
+	require.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, 1, batchErr.Index)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeConflict))
+	assert.NotContains(t, mockStore.agents, "writer")
+
+	// Partial batches register the valid agents
+	results, err = service.RegisterBatch(ctx, models.BatchPartial, []*RegisterRequest{
+		{ID: "writer", Type: "llm", TenantID: "default"},
+		{ID: "", Type: "llm", TenantID: "default"},
+		{ID: "writer", Type: "llm", TenantID: "default"},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.True(t, acberrors.Is(results[1].Err, acberrors.ErrorCodeValidationError))
+	assert.True(t, acberrors.Is(results[2].Err, acberrors.ErrorCodeValidationError))
+	assert.Contains(t, mockStore.agents, "writer")
+
+	_, err = service.RegisterBatch(ctx, models.BatchAtomic, nil)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+}
+
 func TestService_Get(t *testing.T) {
 	mockStore := NewMockAgentStore()
 	service := NewService(mockStore)
@@ -196,3 +340,63 @@ func TestService_Discover(t *testing.T) {
 	require.NoError(t, err)
 	assert.GreaterOrEqual(t, len(agents), 3)
 }
+
+func callerCtx(agentID string, roles ...string) context.Context {
+	return auth.ContextWithClaims(context.Background(), &auth.JWTClaims{AgentID: agentID, TenantID: "default", Roles: roles})
+}
+
+func TestService_Restore(t *testing.T) {
+	mockStore := NewMockAgentStore()
+	service := NewService(mockStore)
+
+	for _, id := range []string{"agent-1", "agent-2"} {
+		_, err := service.Register(context.Background(), &RegisterRequest{ID: id, Type: "ml", TenantID: "default"})
+		require.NoError(t, err)
+		require.NoError(t, service.Unregister(context.Background(), id))
+	}
+
+	// Only the agent itself or an admin may restore it
+	_, err := service.Restore(callerCtx("agent-2"), "agent-1")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+	agent, err := service.Restore(callerCtx("agent-1"), "agent-1")
+	require.NoError(t, err)
+	assert.Nil(t, agent.DeletedAt)
+	_, err = service.Get(context.Background(), "agent-1")
+	require.NoError(t, err)
+
+	// Agents of other tenants and live agents are reported as missing
+	other := auth.ContextWithClaims(context.Background(), &auth.JWTClaims{AgentID: "admin", TenantID: "other", Roles: []string{string(auth.RoleAdmin)}})
+	_, err = service.Restore(other, "agent-2")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+	_, err = service.Restore(callerCtx("agent-1"), "agent-1")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+
+	// Past the restore window the agent is gone for good
+	mockStore.deleted["agent-2"].DeletedAt = timePtr(time.Now().Add(-2 * time.Hour))
+	_, err = service.WithRestoreWindow(time.Hour).Restore(callerCtx("admin", string(auth.RoleAdmin)), "agent-2")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeNotFound))
+}
+
+func TestService_ListDeleted(t *testing.T) {
+	mockStore := NewMockAgentStore()
+	service := NewService(mockStore).WithRestoreWindow(time.Hour)
+
+	for _, id := range []string{"agent-1", "agent-2"} {
+		_, err := service.Register(context.Background(), &RegisterRequest{ID: id, Type: "ml", TenantID: "default"})
+		require.NoError(t, err)
+		require.NoError(t, service.Unregister(context.Background(), id))
+	}
+	mockStore.deleted["agent-1"].DeletedAt = timePtr(time.Now().Add(-2 * time.Hour))
+
+	_, err := service.ListDeleted(callerCtx("agent-2"), 10)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+
+	agents, err := service.ListDeleted(callerCtx("admin", string(auth.RoleAdmin)), 10)
+	require.NoError(t, err)
+	require.Len(t, agents, 1)
+	assert.Equal(t, "agent-2", agents[0].ID)
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
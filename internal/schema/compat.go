@@ -0,0 +1,450 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/acb/internal/models"
+)
+
+// Compatibility is decided structurally: a change is breaking when a payload
+// accepted by the writer schema may be rejected by the reader schema. For
+// backward compatibility the previous version is the writer and the new
+// version the reader; forward compatibility swaps them and full checks both.
+//
+// Properties that the writer schema does not declare are assumed absent, so
+// adding an optional property is never breaking. Subschemas combined with
+// allOf, anyOf, oneOf or not are only accepted when unchanged (or, for an
+// allOf reader, when every branch accepts the writer); anything the checker
+// cannot prove is reported as UNVERIFIABLE.
+
+// ChangeKind classifies a breaking change
+type ChangeKind string
+
+const (
+	ChangeTypeNarrowed         ChangeKind = "TYPE_NARROWED"                    // A JSON type is no longer accepted
+	ChangeValueRestricted      ChangeKind = "VALUE_RESTRICTED"                 // An enum or const value is no longer accepted
+	ChangeRequiredAdded        ChangeKind = "REQUIRED_PROPERTY_ADDED"          // A property became required
+	ChangePropertyRemoved      ChangeKind = "PROPERTY_REMOVED"                 // A property was removed while additional properties are disallowed
+	ChangeAdditionalRestricted ChangeKind = "ADDITIONAL_PROPERTIES_RESTRICTED" // Undeclared properties are no longer accepted
+	ChangeRangeNarrowed        ChangeKind = "RANGE_NARROWED"                   // A numeric, length, item or property count bound is stricter
+	ChangePatternChanged       ChangeKind = "PATTERN_CHANGED"                  // A string pattern was added or changed
+	ChangeConstraintAdded      ChangeKind = "CONSTRAINT_ADDED"                 // Another assertion (uniqueItems, contains, ...) was added
+	ChangeUnverifiable         ChangeKind = "UNVERIFIABLE"                     // Compatibility cannot be proven
+)
+
+// Direction names the check that found a breaking change
+type Direction string
+
+const (
+	DirectionBackward Direction = "backward" // New version reading payloads of the previous one
+	DirectionForward  Direction = "forward"  // Previous version reading payloads of the new one
+)
+
+// BreakingChange describes one incompatibility between two schema versions.
+// Location is a JSON Pointer into the schema, e.g. "#/properties/status".
+type BreakingChange struct {
+	Direction Direction  `json:"direction"`
+	Kind      ChangeKind `json:"kind"`
+	Location  string     `json:"location"`
+	Message   string     `json:"message"`
+}
+
+// CheckCompatibility returns the changes from previous to next that break the
+// given compatibility mode, ordered by location
+func CheckCompatibility(mode models.SchemaCompatibility, previous, next *Schema) []BreakingChange {
+	var changes []BreakingChange
+	if mode == models.CompatibilityBackward || mode == models.CompatibilityFull {
+		changes = append(changes, compare(DirectionBackward, previous, next)...)
+	}
+	if mode == models.CompatibilityForward || mode == models.CompatibilityFull {
+		changes = append(changes, compare(DirectionForward, next, previous)...)
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Location < changes[j].Location })
+	return changes
+}
+
+// compare reports why payloads valid under writer might be rejected by reader
+func compare(direction Direction, writer, reader *Schema) []BreakingChange {
+	c := &comparer{direction: direction, seen: make(map[[2]*node]bool)}
+	c.subset(writer.root, reader.root, "#")
+	return c.changes
+}
+
+// comparer accumulates breaking changes for one direction
+type comparer struct {
+	direction Direction
+	changes   []BreakingChange
+	seen      map[[2]*node]bool // Pairs already compared, so recursive schemas terminate
+}
+
+func (c *comparer) add(kind ChangeKind, loc, format string, args ...interface{}) {
+	c.changes = append(c.changes, BreakingChange{Direction: c.direction, Kind: kind, Location: loc, Message: fmt.Sprintf(format, args...)})
+}
+
+var acceptAll = func() *node { t := true; return &node{always: &t} }()
+
+// subset checks that every value accepted by a is accepted by b
+func (c *comparer) subset(a, b *node, loc string) {
+	a, b = deref(a), deref(b)
+	if c.seen[[2]*node{a, b}] {
+		return
+	}
+	c.seen[[2]*node{a, b}] = true
+
+	switch {
+	case rejectsAll(a) || acceptsAll(b):
+		return
+	case rejectsAll(b):
+		c.add(ChangeConstraintAdded, loc, "no value is accepted any more")
+		return
+	case acceptsAll(a):
+		c.add(ChangeConstraintAdded, loc, "any value was accepted, now constrained")
+		return
+	case !a.hasRef && !b.hasRef && reflect.DeepEqual(a.src, b.src):
+		return
+	}
+
+	// A writer limited to a fixed set of values is compatible exactly when the
+	// reader accepts each of them
+	if values, ok := finiteValues(a); ok {
+		for _, v := range values {
+			if !valid(b, v, "") {
+				c.add(ChangeValueRestricted, loc, "value %s is no longer accepted", render(v))
+			}
+		}
+		return
+	}
+
+	if a.ref != nil || b.ref != nil || a.allOf != nil || a.anyOf != nil || a.oneOf != nil || a.not != nil ||
+		b.anyOf != nil || b.oneOf != nil || b.not != nil {
+		c.add(ChangeUnverifiable, loc, "changed subschemas using $ref, allOf, anyOf, oneOf or not cannot be verified")
+		return
+	}
+	for i, branch := range b.allOf {
+		c.subset(a, branch, fmt.Sprintf("%s/allOf/%d", loc, i))
+	}
+
+	types := possibleTypes(a)
+	if b.types != nil || b.hasConst || b.enum != nil {
+		for _, t := range types {
+			if !typeAllowed(t, b) {
+				c.add(ChangeTypeNarrowed, loc, "type %s is no longer accepted", t)
+			}
+		}
+	}
+	if b.hasConst {
+		c.add(ChangeValueRestricted, loc, "value must now be %s", render(b.constVal))
+	} else if b.enum != nil {
+		c.add(ChangeValueRestricted, loc, "value must now be one of %s", render(b.enum))
+	}
+
+	has := func(t string) bool {
+		for _, x := range types {
+			if x == t || (t == "number" && x == "integer") {
+				return typeAllowed(x, b)
+			}
+		}
+		return false
+	}
+	if has("object") {
+		c.object(a, b, loc)
+	}
+	if has("array") {
+		c.array(a, b, loc)
+	}
+	if has("string") {
+		c.string(a, b, loc)
+	}
+	if has("number") {
+		c.number(a, b, loc)
+	}
+}
+
+func (c *comparer) object(a, b *node, loc string) {
+	aRequired := stringSet(a.required)
+	for _, name := range b.required {
+		if !aRequired[name] {
+			c.add(ChangeRequiredAdded, loc+"/properties/"+escape(name), "property %q became required", name)
+		}
+	}
+	for prop, deps := range b.dependentRequired {
+		aDeps := stringSet(a.dependentRequired[prop])
+		for _, name := range deps {
+			if !aDeps[name] && !aRequired[name] {
+				c.add(ChangeRequiredAdded, loc+"/dependentRequired/"+escape(prop), "property %q became required when %q is present", name, prop)
+			}
+		}
+	}
+	c.countBounds(ChangeRangeNarrowed, loc, "properties", a.minProperties, a.maxProperties, b.minProperties, b.maxProperties)
+
+	aAdditional := a.additional
+	if aAdditional == nil {
+		aAdditional = acceptAll
+	}
+
+	for _, name := range sortedKeys(b.properties) {
+		at := loc + "/properties/" + escape(name)
+		if ap, ok := a.properties[name]; ok {
+			c.subset(ap, b.properties[name], at)
+		} else if a.additional != nil {
+			// The writer allowed the property through additionalProperties
+			c.subset(a.additional, b.properties[name], at)
+		}
+	}
+
+	for _, name := range sortedKeys(a.properties) {
+		if _, ok := b.properties[name]; ok || rejectsAll(deref(a.properties[name])) {
+			continue
+		}
+		at := loc + "/properties/" + escape(name)
+		if ps := matchingPatterns(b, name); len(ps) > 0 {
+			for _, p := range ps {
+				c.subset(a.properties[name], p, at)
+			}
+			continue
+		}
+		if b.additional == nil {
+			continue
+		}
+		if rejectsAll(deref(b.additional)) {
+			c.add(ChangePropertyRemoved, at, "property %q was removed and additional properties are not allowed", name)
+			continue
+		}
+		c.subset(a.properties[name], b.additional, at)
+	}
+
+	for re, bs := range b.patternProperties {
+		at := loc + "/patternProperties/" + escape(re.String())
+		if as := patternNamed(a, re.String()); as != nil {
+			c.subset(as, bs, at)
+		} else {
+			c.subset(aAdditional, bs, at)
+		}
+	}
+
+	if b.additional != nil {
+		at := loc + "/additionalProperties"
+		if rejectsAll(deref(b.additional)) {
+			if !rejectsAll(deref(aAdditional)) {
+				c.add(ChangeAdditionalRestricted, at, "additional properties are no longer allowed")
+			}
+		} else {
+			c.subset(aAdditional, b.additional, at)
+		}
+	}
+}
+
+func (c *comparer) array(a, b *node, loc string) {
+	if b.items != nil {
+		items := a.items
+		if items == nil {
+			items = acceptAll
+		}
+		c.subset(items, b.items, loc+"/items")
+	}
+	c.countBounds(ChangeRangeNarrowed, loc, "items", a.minItems, a.maxItems, b.minItems, b.maxItems)
+	if b.uniqueItems && !a.uniqueItems {
+		c.add(ChangeConstraintAdded, loc+"/uniqueItems", "array items must now be unique")
+	}
+	if b.contains != nil {
+		if a.contains == nil {
+			c.add(ChangeConstraintAdded, loc+"/contains", "arrays must now contain a matching item")
+		} else {
+			c.subset(a.contains, b.contains, loc+"/contains")
+		}
+	}
+}
+
+func (c *comparer) string(a, b *node, loc string) {
+	c.countBounds(ChangeRangeNarrowed, loc, "characters", a.minLength, a.maxLength, b.minLength, b.maxLength)
+	if b.pattern != nil && (a.pattern == nil || a.pattern.String() != b.pattern.String()) {
+		c.add(ChangePatternChanged, loc+"/pattern", "strings must now match %q", b.pattern.String())
+	}
+}
+
+func (c *comparer) number(a, b *node, loc string) {
+	aLow, aLowExcl := lowerBound(a)
+	bLow, bLowExcl := lowerBound(b)
+	if bLow != nil && (aLow == nil || aLow.value.Cmp(bLow.value) < 0 || (aLow.value.Cmp(bLow.value) == 0 && bLowExcl && !aLowExcl)) {
+		c.add(ChangeRangeNarrowed, loc, "minimum raised to %s%s", exclusivity(bLowExcl), bLow.text)
+	}
+	aHigh, aHighExcl := upperBound(a)
+	bHigh, bHighExcl := upperBound(b)
+	if bHigh != nil && (aHigh == nil || aHigh.value.Cmp(bHigh.value) > 0 || (aHigh.value.Cmp(bHigh.value) == 0 && bHighExcl && !aHighExcl)) {
+		c.add(ChangeRangeNarrowed, loc, "maximum lowered to %s%s", exclusivity(bHighExcl), bHigh.text)
+	}
+	if b.multipleOf != nil && (a.multipleOf == nil || !new(big.Rat).Quo(a.multipleOf.value, b.multipleOf.value).IsInt()) {
+		c.add(ChangeConstraintAdded, loc+"/multipleOf", "numbers must now be a multiple of %s", b.multipleOf.text)
+	}
+}
+
+// countBounds reports a stricter minimum or maximum count
+func (c *comparer) countBounds(kind ChangeKind, loc, unit string, aMin, aMax, bMin, bMax *int) {
+	if bMin != nil && *bMin > 0 && (aMin == nil || *aMin < *bMin) {
+		c.add(kind, loc, "at least %d %s now required", *bMin, unit)
+	}
+	if bMax != nil && (aMax == nil || *aMax > *bMax) {
+		c.add(kind, loc, "at most %d %s now allowed", *bMax, unit)
+	}
+}
+
+// deref follows references of nodes that consist of nothing but a $ref
+func deref(n *node) *node {
+	for i := 0; i < 32 && n.ref != nil && n.keywords == 1; i++ {
+		n = n.ref
+	}
+	return n
+}
+
+func acceptsAll(n *node) bool {
+	return (n.always != nil && *n.always) || (n.always == nil && n.keywords == 0)
+}
+
+func rejectsAll(n *node) bool {
+	return n.always != nil && !*n.always
+}
+
+// finiteValues returns the values n accepts when it is limited by const or enum
+func finiteValues(n *node) ([]interface{}, bool) {
+	var candidates []interface{}
+	switch {
+	case n.hasConst:
+		candidates = []interface{}{n.constVal}
+	case n.enum != nil:
+		candidates = n.enum
+	default:
+		return nil, false
+	}
+	var values []interface{}
+	for _, v := range candidates {
+		if valid(n, v, "") {
+			values = append(values, v)
+		}
+	}
+	return values, true
+}
+
+var allTypes = []string{"null", "boolean", "object", "array", "number", "string"}
+
+// possibleTypes returns the JSON types n may accept
+func possibleTypes(n *node) []string {
+	if n.types == nil {
+		return allTypes
+	}
+	return n.types
+}
+
+// typeAllowed reports whether some value of type t may satisfy the type, enum
+// and const keywords of n
+func typeAllowed(t string, n *node) bool {
+	if n.types != nil && !hasTypeName(n.types, t) {
+		return false
+	}
+	if n.hasConst {
+		return typeMatches(typeOf(n.constVal), t)
+	}
+	if n.enum != nil {
+		for _, v := range n.enum {
+			if typeMatches(typeOf(v), t) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func hasTypeName(types []string, t string) bool {
+	for _, x := range types {
+		if x == t || (x == "number" && t == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeMatches reports whether a value of type actual can be of type t
+func typeMatches(actual, t string) bool {
+	return actual == t || (actual == "integer" && t == "number")
+}
+
+func lowerBound(n *node) (*bound, bool) {
+	return tighter(n.minimum, n.exclusiveMinimum, 1)
+}
+
+func upperBound(n *node) (*bound, bool) {
+	return tighter(n.maximum, n.exclusiveMaximum, -1)
+}
+
+// tighter picks the stricter of an inclusive and an exclusive bound; sign is
+// 1 for lower bounds and -1 for upper bounds. It reports whether the chosen
+// bound is exclusive.
+func tighter(inclusive, exclusive *bound, sign int) (*bound, bool) {
+	switch {
+	case exclusive == nil:
+		return inclusive, false
+	case inclusive == nil:
+		return exclusive, true
+	case exclusive.value.Cmp(inclusive.value)*sign >= 0:
+		return exclusive, true
+	default:
+		return inclusive, false
+	}
+}
+
+func exclusivity(exclusive bool) string {
+	if exclusive {
+		return "> "
+	}
+	return ""
+}
+
+func matchingPatterns(n *node, name string) []*node {
+	var out []*node
+	for re, s := range n.patternProperties {
+		if re.MatchString(name) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func patternNamed(n *node, pattern string) *node {
+	for re, s := range n.patternProperties {
+		if re.String() == pattern {
+			return s
+		}
+	}
+	return nil
+}
+
+func stringSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, s := range list {
+		set[s] = true
+	}
+	return set
+}
+
+func sortedKeys(m map[string]*node) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// render formats a decoded JSON value for messages
+func render(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(string(b))
+}
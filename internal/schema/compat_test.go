@@ -0,0 +1,147 @@
+package schema
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/acb/internal/models"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     models.SchemaCompatibility
+		previous string
+		next     string
+		want     []string // "direction kind location"
+	}{
+		{
+			name:     "optional property added",
+			mode:     models.CompatibilityFull,
+			previous: `{"type":"object","properties":{"id":{"type":"string"}}}`,
+			next:     `{"type":"object","properties":{"id":{"type":"string"},"note":{"type":"string"}}}`,
+		},
+		{
+			name:     "required property added",
+			mode:     models.CompatibilityBackward,
+			previous: `{"type":"object","properties":{"id":{"type":"string"}}}`,
+			next:     `{"type":"object","required":["id"],"properties":{"id":{"type":"string"}}}`,
+			want:     []string{"backward REQUIRED_PROPERTY_ADDED #/properties/id"},
+		},
+		{
+			name:     "required property added is forward compatible",
+			mode:     models.CompatibilityForward,
+			previous: `{"type":"object","properties":{"id":{"type":"string"}}}`,
+			next:     `{"type":"object","required":["id"],"properties":{"id":{"type":"string"}}}`,
+		},
+		{
+			name:     "property retyped",
+			mode:     models.CompatibilityFull,
+			previous: `{"type":"object","properties":{"count":{"type":"integer"}}}`,
+			next:     `{"type":"object","properties":{"count":{"type":"string"}}}`,
+			want: []string{
+				"backward TYPE_NARROWED #/properties/count",
+				"forward TYPE_NARROWED #/properties/count",
+			},
+		},
+		{
+			name:     "integer widened to number",
+			mode:     models.CompatibilityBackward,
+			previous: `{"type":"object","properties":{"count":{"type":"integer"}}}`,
+			next:     `{"type":"object","properties":{"count":{"type":"number"}}}`,
+		},
+		{
+			name:     "enum narrowed",
+			mode:     models.CompatibilityBackward,
+			previous: `{"enum":["open","closed","archived"]}`,
+			next:     `{"enum":["open","closed"]}`,
+			want:     []string{"backward VALUE_RESTRICTED #"},
+		},
+		{
+			name:     "enum widened",
+			mode:     models.CompatibilityBackward,
+			previous: `{"enum":["open","closed"]}`,
+			next:     `{"type":"string","enum":["open","closed","archived"]}`,
+		},
+		{
+			name:     "property removed from closed object",
+			mode:     models.CompatibilityBackward,
+			previous: `{"type":"object","additionalProperties":false,"properties":{"id":{"type":"string"},"legacy":{"type":"string"}}}`,
+			next:     `{"type":"object","additionalProperties":false,"properties":{"id":{"type":"string"}}}`,
+			want:     []string{"backward PROPERTY_REMOVED #/properties/legacy"},
+		},
+		{
+			name:     "property removed from open object",
+			mode:     models.CompatibilityBackward,
+			previous: `{"type":"object","properties":{"id":{"type":"string"},"legacy":{"type":"string"}}}`,
+			next:     `{"type":"object","properties":{"id":{"type":"string"}}}`,
+		},
+		{
+			name:     "additional properties disallowed",
+			mode:     models.CompatibilityBackward,
+			previous: `{"type":"object","properties":{"id":{}}}`,
+			next:     `{"type":"object","properties":{"id":{}},"additionalProperties":false}`,
+			want:     []string{"backward ADDITIONAL_PROPERTIES_RESTRICTED #/additionalProperties"},
+		},
+		{
+			name:     "range narrowed",
+			mode:     models.CompatibilityFull,
+			previous: `{"type":"array","maxItems":10,"items":{"type":"integer","minimum":0,"maximum":10}}`,
+			next:     `{"type":"array","maxItems":5,"items":{"type":"integer","minimum":0,"exclusiveMaximum":20}}`,
+			want: []string{
+				"backward RANGE_NARROWED #",
+				"forward RANGE_NARROWED #/items",
+			},
+		},
+		{
+			name:     "pattern added",
+			mode:     models.CompatibilityBackward,
+			previous: `{"type":"string","maxLength":10}`,
+			next:     `{"type":"string","maxLength":10,"pattern":"^[a-z]+$"}`,
+			want:     []string{"backward PATTERN_CHANGED #/pattern"},
+		},
+		{
+			name:     "changed combinator",
+			mode:     models.CompatibilityBackward,
+			previous: `{"properties":{"v":{"anyOf":[{"type":"string"},{"type":"integer"}]}}}`,
+			next:     `{"properties":{"v":{"anyOf":[{"type":"string"},{"type":"boolean"}]}}}`,
+			want:     []string{"backward UNVERIFIABLE #/properties/v"},
+		},
+		{
+			name:     "unchanged recursive schema",
+			mode:     models.CompatibilityFull,
+			previous: `{"$defs":{"node":{"type":"object","properties":{"children":{"type":"array","items":{"$ref":"#/$defs/node"}}}}},"$ref":"#/$defs/node"}`,
+			next:     `{"$defs":{"node":{"type":"object","properties":{"children":{"type":"array","items":{"$ref":"#/$defs/node"}}}}},"$ref":"#/$defs/node"}`,
+		},
+		{
+			name:     "none accepts anything",
+			mode:     models.CompatibilityNone,
+			previous: `{"type":"object"}`,
+			next:     `{"type":"string"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous, err := Compile([]byte(tt.previous))
+			if err != nil {
+				t.Fatalf("compile previous: %v", err)
+			}
+			next, err := Compile([]byte(tt.next))
+			if err != nil {
+				t.Fatalf("compile next: %v", err)
+			}
+
+			var got []string
+			for _, change := range CheckCompatibility(tt.mode, previous, next) {
+				if change.Message == "" {
+					t.Errorf("change without message: %+v", change)
+				}
+				got = append(got, fmt.Sprintf("%s %s %s", change.Direction, change.Kind, change.Location))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,784 @@
+// Package schema validates context payloads against versioned JSON Schema
+// documents registered per context type.
+//
+// The validator implements the commonly used subset of JSON Schema (draft
+// 2020-12): type, enum, const, the object, array, string and numeric
+// assertions, allOf/anyOf/oneOf/not and local $ref. Annotation keywords such
+// as title, description and format are accepted and ignored; any other
+// keyword is rejected when the schema is compiled so that a schema never
+// silently validates less than its author intended.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidSchema is returned (wrapped) when a schema document cannot be compiled
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// Violation is a single validation failure. Path is a JSON Pointer
+// (RFC 6901) into the validated document; the empty string is the root.
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Schema is a compiled JSON Schema document
+type Schema struct {
+	root *node
+}
+
+// annotations are keywords that do not affect validation
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "$anchor": true,
+	"$defs": true, "definitions": true,
+	"title": true, "description": true, "default": true, "examples": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true,
+	"format": true, "contentEncoding": true, "contentMediaType": true,
+}
+
+// node is a compiled schema or subschema
+type node struct {
+	always   *bool       // Boolean schema: true accepts everything, false nothing
+	src      interface{} // Decoded source, used to short-circuit comparisons
+	keywords int         // Number of validation keywords; zero accepts everything
+	hasRef   bool        // Whether the source contains a $ref at any depth
+	ref      *node
+
+	types    []string
+	enum     []interface{}
+	constVal interface{}
+	hasConst bool
+
+	properties        map[string]*node
+	patternProperties map[*regexp.Regexp]*node
+	additional        *node
+	required          []string
+	dependentRequired map[string][]string
+	minProperties     *int
+	maxProperties     *int
+
+	items       *node
+	contains    *node
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum          *bound
+	maximum          *bound
+	exclusiveMinimum *bound
+	exclusiveMaximum *bound
+	multipleOf       *bound
+
+	allOf []*node
+	anyOf []*node
+	oneOf []*node
+	not   *node
+}
+
+// bound is a numeric keyword value, kept exact for comparison and in its
+// original form for messages
+type bound struct {
+	value *big.Rat
+	text  string
+}
+
+// Compile parses and compiles a JSON Schema document
+func Compile(doc []byte) (*Schema, error) {
+	root, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	c := &compiler{root: root, refs: make(map[string]*node)}
+	n, err := c.compile(root, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if inPlaceCycle(n) {
+		return nil, fmt.Errorf("%w: $ref cycle applies a schema to itself without descending into the document", ErrInvalidSchema)
+	}
+	return &Schema{root: n}, nil
+}
+
+// Validate checks a JSON document against the schema and returns every
+// violation found, ordered by path. A nil result means the document is valid.
+func (s *Schema) Validate(doc []byte) []Violation {
+	v, err := decode(doc)
+	if err != nil {
+		return []Violation{{Path: "", Message: "document is not valid JSON"}}
+	}
+	var violations []Violation
+	s.root.validate(v, "", &violations)
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	return violations
+}
+
+// compiler resolves local references while compiling a document
+type compiler struct {
+	root interface{}
+	refs map[string]*node
+}
+
+func (c *compiler) compile(v interface{}, at string) (*node, error) {
+	if b, ok := v.(bool); ok {
+		return &node{always: &b}, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object or boolean", location(at))
+	}
+
+	n := &node{src: v, hasRef: containsRef(v)}
+	for key, val := range obj {
+		if annotations[key] {
+			continue
+		}
+		if err := c.keyword(n, key, val, at+"/"+escape(key)); err != nil {
+			return nil, err
+		}
+		n.keywords++
+	}
+	return n, nil
+}
+
+func (c *compiler) keyword(n *node, key string, val interface{}, at string) error {
+	var err error
+	switch key {
+	case "$ref":
+		n.ref, err = c.resolve(val, at)
+	case "type":
+		n.types, err = typeList(val, at)
+	case "enum":
+		list, ok := val.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an array", location(at))
+		}
+		n.enum = list
+	case "const":
+		n.constVal, n.hasConst = val, true
+	case "properties":
+		n.properties, err = c.schemaMap(val, at)
+	case "patternProperties":
+		var schemas map[string]*node
+		if schemas, err = c.schemaMap(val, at); err != nil {
+			return err
+		}
+		n.patternProperties = make(map[*regexp.Regexp]*node, len(schemas))
+		for pattern, s := range schemas {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %v", location(at), pattern, err)
+			}
+			n.patternProperties[re] = s
+		}
+	case "additionalProperties":
+		n.additional, err = c.compile(val, at)
+	case "required":
+		n.required, err = stringList(val, at)
+	case "dependentRequired":
+		deps, ok := val.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an object", location(at))
+		}
+		n.dependentRequired = make(map[string][]string, len(deps))
+		for prop, list := range deps {
+			if n.dependentRequired[prop], err = stringList(list, at+"/"+escape(prop)); err != nil {
+				return err
+			}
+		}
+	case "minProperties":
+		n.minProperties, err = count(val, at)
+	case "maxProperties":
+		n.maxProperties, err = count(val, at)
+	case "items":
+		n.items, err = c.compile(val, at)
+	case "contains":
+		n.contains, err = c.compile(val, at)
+	case "minItems":
+		n.minItems, err = count(val, at)
+	case "maxItems":
+		n.maxItems, err = count(val, at)
+	case "uniqueItems":
+		b, ok := val.(bool)
+		if !ok {
+			return fmt.Errorf("%s: must be a boolean", location(at))
+		}
+		n.uniqueItems = b
+	case "minLength":
+		n.minLength, err = count(val, at)
+	case "maxLength":
+		n.maxLength, err = count(val, at)
+	case "pattern":
+		s, ok := val.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", location(at))
+		}
+		if n.pattern, err = regexp.Compile(s); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %v", location(at), err)
+		}
+	case "minimum":
+		n.minimum, err = number(val, at)
+	case "maximum":
+		n.maximum, err = number(val, at)
+	case "exclusiveMinimum":
+		n.exclusiveMinimum, err = number(val, at)
+	case "exclusiveMaximum":
+		n.exclusiveMaximum, err = number(val, at)
+	case "multipleOf":
+		if n.multipleOf, err = number(val, at); err == nil && n.multipleOf.value.Sign() <= 0 {
+			err = fmt.Errorf("%s: must be greater than 0", location(at))
+		}
+	case "allOf":
+		n.allOf, err = c.schemaList(val, at)
+	case "anyOf":
+		n.anyOf, err = c.schemaList(val, at)
+	case "oneOf":
+		n.oneOf, err = c.schemaList(val, at)
+	case "not":
+		n.not, err = c.compile(val, at)
+	default:
+		return fmt.Errorf("%s: unsupported keyword %q", location(at), key)
+	}
+	return err
+}
+
+// resolve compiles the target of a local reference ("#" or "#/json/pointer").
+// Targets are compiled once, so recursive schemas terminate.
+func (c *compiler) resolve(val interface{}, at string) (*node, error) {
+	ref, ok := val.(string)
+	if !ok || !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("%s: only local references (#/...) are supported", location(at))
+	}
+	pointer := ref[1:]
+	if n, ok := c.refs[pointer]; ok {
+		return n, nil
+	}
+
+	target := c.root
+	if pointer != "" {
+		if !strings.HasPrefix(pointer, "/") {
+			return nil, fmt.Errorf("%s: invalid reference %q", location(at), ref)
+		}
+		for _, token := range strings.Split(pointer[1:], "/") {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+			obj, ok := target.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: unresolvable reference %q", location(at), ref)
+			}
+			if target, ok = obj[token]; !ok {
+				return nil, fmt.Errorf("%s: unresolvable reference %q", location(at), ref)
+			}
+		}
+	}
+
+	// Register a placeholder before compiling so self-references resolve to it
+	n := &node{}
+	c.refs[pointer] = n
+	compiled, err := c.compile(target, pointer)
+	if err != nil {
+		return nil, err
+	}
+	*n = *compiled
+	return n, nil
+}
+
+// inPlaceCycle reports whether a schema reachable from root applies itself
+// to the same value again through $ref, allOf, anyOf, oneOf or not.
+// Validating against such a schema would never terminate.
+func inPlaceCycle(root *node) bool {
+	// state marks nodes on the current in-place path (1) and nodes checked (2)
+	state := map[*node]int{}
+	var visit func(n *node) bool
+	visit = func(n *node) bool {
+		switch state[n] {
+		case 1:
+			return true
+		case 2:
+			return false
+		}
+		state[n] = 1
+		for _, child := range n.inPlace() {
+			if visit(child) {
+				return true
+			}
+		}
+		state[n] = 2
+		return false
+	}
+
+	seen := map[*node]bool{}
+	pending := []*node{root}
+	for len(pending) > 0 {
+		n := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		if visit(n) {
+			return true
+		}
+		pending = append(pending, n.inPlace()...)
+		pending = append(pending, n.descendants()...)
+	}
+	return false
+}
+
+// inPlace returns the subschemas applied to the same value as n
+func (n *node) inPlace() []*node {
+	var out []*node
+	for _, child := range append(append(append([]*node{n.ref, n.not}, n.allOf...), n.anyOf...), n.oneOf...) {
+		if child != nil {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+// descendants returns the subschemas applied to parts of the value
+func (n *node) descendants() []*node {
+	var out []*node
+	for _, child := range []*node{n.additional, n.items, n.contains} {
+		if child != nil {
+			out = append(out, child)
+		}
+	}
+	for _, child := range n.properties {
+		out = append(out, child)
+	}
+	for _, child := range n.patternProperties {
+		out = append(out, child)
+	}
+	return out
+}
+
+func (c *compiler) schemaMap(val interface{}, at string) (map[string]*node, error) {
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an object", location(at))
+	}
+	out := make(map[string]*node, len(obj))
+	for k, v := range obj {
+		n, err := c.compile(v, at+"/"+escape(k))
+		if err != nil {
+			return nil, err
+		}
+		out[k] = n
+	}
+	return out, nil
+}
+
+func (c *compiler) schemaList(val interface{}, at string) ([]*node, error) {
+	list, ok := val.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%s: must be a non-empty array", location(at))
+	}
+	out := make([]*node, len(list))
+	for i, v := range list {
+		n, err := c.compile(v, at+"/"+strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+var knownTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+func typeList(val interface{}, at string) ([]string, error) {
+	if s, ok := val.(string); ok {
+		val = []interface{}{s}
+	}
+	types, err := stringList(val, at)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		if !knownTypes[t] {
+			return nil, fmt.Errorf("%s: unknown type %q", location(at), t)
+		}
+	}
+	return types, nil
+}
+
+func stringList(val interface{}, at string) ([]string, error) {
+	list, ok := val.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an array of strings", location(at))
+	}
+	out := make([]string, len(list))
+	for i, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: must be an array of strings", location(at))
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+func count(val interface{}, at string) (*int, error) {
+	b, err := number(val, at)
+	if err != nil {
+		return nil, err
+	}
+	r := b.value
+	if !r.IsInt() || r.Sign() < 0 || !r.Num().IsInt64() {
+		return nil, fmt.Errorf("%s: must be a non-negative integer", location(at))
+	}
+	n := int(r.Num().Int64())
+	return &n, nil
+}
+
+func number(val interface{}, at string) (*bound, error) {
+	r, ok := toRat(val)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a number", location(at))
+	}
+	return &bound{value: r, text: val.(json.Number).String()}, nil
+}
+
+// validate appends the violations of v at path
+func (n *node) validate(v interface{}, path string, out *[]Violation) {
+	if n.always != nil {
+		if !*n.always {
+			*out = append(*out, Violation{Path: path, Message: "no value is allowed here"})
+		}
+		return
+	}
+	if n.ref != nil {
+		n.ref.validate(v, path, out)
+	}
+
+	if len(n.types) > 0 && !hasType(v, n.types) {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(n.types, " or "), typeOf(v))})
+		return
+	}
+	if n.enum != nil && !contains(n.enum, v) {
+		*out = append(*out, Violation{Path: path, Message: "value is not one of the allowed values"})
+	}
+	if n.hasConst && !equal(n.constVal, v) {
+		*out = append(*out, Violation{Path: path, Message: "value does not match the required constant"})
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		n.validateObject(val, path, out)
+	case []interface{}:
+		n.validateArray(val, path, out)
+	case string:
+		n.validateString(val, path, out)
+	case json.Number:
+		n.validateNumber(val, path, out)
+	}
+
+	for _, s := range n.allOf {
+		s.validate(v, path, out)
+	}
+	if n.anyOf != nil && matching(n.anyOf, v, path) == 0 {
+		*out = append(*out, Violation{Path: path, Message: "value does not match any of the allowed schemas"})
+	}
+	if n.oneOf != nil {
+		if m := matching(n.oneOf, v, path); m != 1 {
+			*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("value must match exactly one schema, matched %d", m)})
+		}
+	}
+	if n.not != nil && valid(n.not, v, path) {
+		*out = append(*out, Violation{Path: path, Message: "value matches a disallowed schema"})
+	}
+}
+
+func (n *node) validateObject(obj map[string]interface{}, path string, out *[]Violation) {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			*out = append(*out, Violation{Path: path + "/" + escape(name), Message: "required property is missing"})
+		}
+	}
+	for prop, deps := range n.dependentRequired {
+		if _, ok := obj[prop]; !ok {
+			continue
+		}
+		for _, name := range deps {
+			if _, ok := obj[name]; !ok {
+				*out = append(*out, Violation{Path: path + "/" + escape(name), Message: fmt.Sprintf("required when %q is present", prop)})
+			}
+		}
+	}
+	if n.minProperties != nil && len(obj) < *n.minProperties {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("must have at least %d properties", *n.minProperties)})
+	}
+	if n.maxProperties != nil && len(obj) > *n.maxProperties {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("must have at most %d properties", *n.maxProperties)})
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		child := path + "/" + escape(k)
+		matched := false
+		if s, ok := n.properties[k]; ok {
+			matched = true
+			s.validate(obj[k], child, out)
+		}
+		for re, s := range n.patternProperties {
+			if re.MatchString(k) {
+				matched = true
+				s.validate(obj[k], child, out)
+			}
+		}
+		if !matched && n.additional != nil {
+			if n.additional.always != nil && !*n.additional.always {
+				*out = append(*out, Violation{Path: child, Message: "additional property is not allowed"})
+				continue
+			}
+			n.additional.validate(obj[k], child, out)
+		}
+	}
+}
+
+func (n *node) validateArray(arr []interface{}, path string, out *[]Violation) {
+	if n.minItems != nil && len(arr) < *n.minItems {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("must have at least %d items", *n.minItems)})
+	}
+	if n.maxItems != nil && len(arr) > *n.maxItems {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("must have at most %d items", *n.maxItems)})
+	}
+	if n.uniqueItems {
+	outer:
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if equal(arr[i], arr[j]) {
+					*out = append(*out, Violation{Path: path + "/" + strconv.Itoa(i), Message: fmt.Sprintf("duplicates item %d", j)})
+					break outer
+				}
+			}
+		}
+	}
+	if n.items != nil {
+		for i, item := range arr {
+			n.items.validate(item, path+"/"+strconv.Itoa(i), out)
+		}
+	}
+	if n.contains != nil {
+		found := false
+		for i, item := range arr {
+			if valid(n.contains, item, path+"/"+strconv.Itoa(i)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*out = append(*out, Violation{Path: path, Message: "no item matches the required schema"})
+		}
+	}
+}
+
+func (n *node) validateString(s, path string, out *[]Violation) {
+	length := utf8.RuneCountInString(s)
+	if n.minLength != nil && length < *n.minLength {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("must be at least %d characters", *n.minLength)})
+	}
+	if n.maxLength != nil && length > *n.maxLength {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("must be at most %d characters", *n.maxLength)})
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("does not match pattern %q", n.pattern.String())})
+	}
+}
+
+func (n *node) validateNumber(num json.Number, path string, out *[]Violation) {
+	r, ok := toRat(num)
+	if !ok {
+		return
+	}
+	if n.minimum != nil && r.Cmp(n.minimum.value) < 0 {
+		*out = append(*out, Violation{Path: path, Message: "must be >= " + n.minimum.text})
+	}
+	if n.maximum != nil && r.Cmp(n.maximum.value) > 0 {
+		*out = append(*out, Violation{Path: path, Message: "must be <= " + n.maximum.text})
+	}
+	if n.exclusiveMinimum != nil && r.Cmp(n.exclusiveMinimum.value) <= 0 {
+		*out = append(*out, Violation{Path: path, Message: "must be > " + n.exclusiveMinimum.text})
+	}
+	if n.exclusiveMaximum != nil && r.Cmp(n.exclusiveMaximum.value) >= 0 {
+		*out = append(*out, Violation{Path: path, Message: "must be < " + n.exclusiveMaximum.text})
+	}
+	if n.multipleOf != nil && !new(big.Rat).Quo(r, n.multipleOf.value).IsInt() {
+		*out = append(*out, Violation{Path: path, Message: "must be a multiple of " + n.multipleOf.text})
+	}
+}
+
+// valid reports whether v satisfies n
+func valid(n *node, v interface{}, path string) bool {
+	var violations []Violation
+	n.validate(v, path, &violations)
+	return len(violations) == 0
+}
+
+// matching counts the schemas v satisfies
+func matching(schemas []*node, v interface{}, path string) int {
+	m := 0
+	for _, s := range schemas {
+		if valid(s, v, path) {
+			m++
+		}
+	}
+	return m
+}
+
+func hasType(v interface{}, types []string) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type name of a decoded value
+func typeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		if r, ok := toRat(val); ok && r.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func contains(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if equal(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares decoded JSON values, treating numbers by value
+func equal(a, b interface{}) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		ar, aok := toRat(av)
+		br, bok := toRat(bv)
+		return aok && bok && ar.Cmp(br) == 0
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			w, ok := bv[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func toRat(v interface{}) (*big.Rat, bool) {
+	num, ok := v.(json.Number)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetString(string(num))
+}
+
+// decode parses a single JSON value, preserving number precision
+func decode(doc []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+// containsRef reports whether a decoded schema uses $ref anywhere
+func containsRef(v interface{}) bool {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if _, ok := val["$ref"]; ok {
+			return true
+		}
+		for _, child := range val {
+			if containsRef(child) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, child := range val {
+			if containsRef(child) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// location names a schema position in error messages
+func location(at string) string {
+	if at == "" {
+		return "schema root"
+	}
+	return at
+}
+
+// escape escapes a key for use as a JSON Pointer reference token
+func escape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
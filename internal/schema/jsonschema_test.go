@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const taskSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "Task",
+	"type": "object",
+	"required": ["id", "status"],
+	"properties": {
+		"id": {"type": "string", "minLength": 1},
+		"status": {"enum": ["pending", "running", "done"]},
+		"priority": {"type": "integer", "minimum": 1, "maximum": 5},
+		"progress": {"type": "number", "exclusiveMaximum": 1, "multipleOf": 0.05},
+		"tags": {"type": "array", "items": {"type": "string", "pattern": "^[a-z-]+$"}, "uniqueItems": true, "maxItems": 3},
+		"owner": {"$ref": "#/$defs/agent"},
+		"subtasks": {"type": "array", "items": {"$ref": "#"}}
+	},
+	"additionalProperties": false,
+	"$defs": {
+		"agent": {"type": "object", "required": ["id"], "properties": {"id": {"type": "string"}}}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := Compile([]byte(taskSchema))
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		want []Violation
+	}{
+		{"valid", `{"id":"t1","status":"done","priority":3,"progress":0.25,"tags":["a","b-c"],"owner":{"id":"a1"}}`, nil},
+		{"numbers compare by value", `{"id":"t1","status":"done","priority":3.0,"progress":0.95}`, nil},
+		{"recursive reference", `{"id":"t1","status":"done","subtasks":[{"id":"t2","status":"pending","subtasks":[]}]}`, nil},
+		{"missing required", `{"status":"done"}`, []Violation{{"/id", "required property is missing"}}},
+		{"wrong root type", `[1]`, []Violation{{"", "expected object, got array"}}},
+		{"not JSON", `{`, []Violation{{"", "document is not valid JSON"}}},
+		{"enum", `{"id":"t1","status":"lost"}`, []Violation{{"/status", "value is not one of the allowed values"}}},
+		{"integer", `{"id":"t1","status":"done","priority":2.5}`, []Violation{{"/priority", "expected integer, got number"}}},
+		{"maximum", `{"id":"t1","status":"done","priority":6}`, []Violation{{"/priority", "must be <= 5"}}},
+		{"exclusive maximum", `{"id":"t1","status":"done","progress":1}`, []Violation{{"/progress", "must be < 1"}}},
+		{"multiple of", `{"id":"t1","status":"done","progress":0.33}`, []Violation{{"/progress", "must be a multiple of 0.05"}}},
+		{"min length", `{"id":"","status":"done"}`, []Violation{{"/id", "must be at least 1 characters"}}},
+		{"additional property", `{"id":"t1","status":"done","color":"red"}`, []Violation{{"/color", "additional property is not allowed"}}},
+		{"array items", `{"id":"t1","status":"done","tags":["ok","Not OK"]}`, []Violation{{"/tags/1", `does not match pattern "^[a-z-]+$"`}}},
+		{"unique items", `{"id":"t1","status":"done","tags":["a","a"]}`, []Violation{{"/tags/1", "duplicates item 0"}}},
+		{"max items", `{"id":"t1","status":"done","tags":["a","b","c","d"]}`, []Violation{{"/tags", "must have at most 3 items"}}},
+		{"reference", `{"id":"t1","status":"done","owner":{}}`, []Violation{{"/owner/id", "required property is missing"}}},
+		{"recursive violation", `{"id":"t1","status":"done","subtasks":[{"id":"t2"}]}`, []Violation{{"/subtasks/0/status", "required property is missing"}}},
+		{"several violations ordered by path", `{"status":"lost","priority":0}`, []Violation{
+			{"/id", "required property is missing"},
+			{"/priority", "must be >= 1"},
+			{"/status", "value is not one of the allowed values"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.Validate([]byte(tt.doc))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Validate(%s)\n got  %+v\n want %+v", tt.doc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_Combinators(t *testing.T) {
+	tests := []struct {
+		schema string
+		doc    string
+		valid  bool
+	}{
+		{`{"anyOf":[{"type":"string"},{"type":"integer"}]}`, `7`, true},
+		{`{"anyOf":[{"type":"string"},{"type":"integer"}]}`, `true`, false},
+		{`{"oneOf":[{"type":"number"},{"type":"integer"}]}`, `1.5`, true},
+		{`{"oneOf":[{"type":"number"},{"type":"integer"}]}`, `1`, false},
+		{`{"allOf":[{"minimum":1},{"maximum":2}]}`, `3`, false},
+		{`{"not":{"type":"null"}}`, `null`, false},
+		{`{"const":{"a":[1,2]}}`, `{"a":[1.0,2]}`, true},
+		{`{"type":["string","null"]}`, `null`, true},
+		{`{"contains":{"const":"x"}}`, `["a","x"]`, true},
+		{`{"contains":{"const":"x"}}`, `["a"]`, false},
+		{`{"patternProperties":{"^n_":{"type":"number"}},"additionalProperties":false}`, `{"n_a":1}`, true},
+		{`{"patternProperties":{"^n_":{"type":"number"}},"additionalProperties":false}`, `{"n_a":"1"}`, false},
+		{`{"dependentRequired":{"card":["billing"]}}`, `{"card":1}`, false},
+		{`{"minProperties":1}`, `{}`, false},
+		{`{"maxLength":2}`, `"héé"`, false},
+		{`{"maxLength":3}`, `"héé"`, true},
+		{`true`, `{"anything":1}`, true},
+		{`false`, `1`, false},
+	}
+
+	for _, tt := range tests {
+		s, err := Compile([]byte(tt.schema))
+		if err != nil {
+			t.Fatalf("compile %s: %v", tt.schema, err)
+		}
+		if got := len(s.Validate([]byte(tt.doc))) == 0; got != tt.valid {
+			t.Errorf("schema %s, doc %s: valid = %v, want %v", tt.schema, tt.doc, got, tt.valid)
+		}
+	}
+}
+
+func TestCompile_RecursiveReferences(t *testing.T) {
+	// References that descend into the document are fine, also through allOf
+	for _, doc := range []string{
+		`{"allOf":[{"properties":{"next":{"$ref":"#"}}}]}`,
+		`{"$defs":{"a":{"items":{"$ref":"#/$defs/b"}},"b":{"anyOf":[{"$ref":"#/$defs/a"},{"type":"null"}]}},"$ref":"#/$defs/a"}`,
+	} {
+		s, err := Compile([]byte(doc))
+		if err != nil {
+			t.Fatalf("Compile(%s) = %v", doc, err)
+		}
+		s.Validate([]byte(`{"next":{"next":{}}}`))
+		s.Validate([]byte(`[[null,[]]]`))
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []string{
+		`{`,
+		`"string"`,
+		`{"type":"text"}`,
+		`{"minLength":-1}`,
+		`{"maxItems":1.5}`,
+		`{"pattern":"("}`,
+		`{"multipleOf":0}`,
+		`{"required":"id"}`,
+		`{"anyOf":[]}`,
+		`{"$ref":"https://example.com/schema"}`,
+		`{"$ref":"#/$defs/missing"}`,
+		`{"properties":{"a":{"if":{"type":"string"}}}}`,
+		`{"$ref":"#"}`,
+		`{"$defs":{"a":{"$ref":"#/$defs/a"}},"$ref":"#/$defs/a"}`,
+		`{"$defs":{"a":{"anyOf":[{"$ref":"#/$defs/b"}]},"b":{"not":{"$ref":"#/$defs/a"}}},"properties":{"x":{"$ref":"#/$defs/a"}}}`,
+	}
+	for _, doc := range tests {
+		if _, err := Compile([]byte(doc)); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("Compile(%s) = %v, want ErrInvalidSchema", doc, err)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/google/uuid"
+)
+
+// ValidationError reports a payload that does not satisfy its schema
+type ValidationError struct {
+	SchemaID   string
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("%q: %s", v.Path, v.Message)
+	}
+	return fmt.Sprintf("payload does not match schema %s: %s", e.SchemaID, strings.Join(msgs, "; "))
+}
+
+// DefaultCompatibility applies to context types without a configured mode
+const DefaultCompatibility = models.CompatibilityBackward
+
+// CompatibilityReport is the outcome of checking a schema against the latest
+// registered version of its context type
+type CompatibilityReport struct {
+	ContextType     string                     `json:"context_type"`
+	Compatibility   models.SchemaCompatibility `json:"compatibility"`
+	LatestVersion   int                        `json:"latest_version"` // Zero when the type has no versions yet
+	Compatible      bool                       `json:"compatible"`
+	BreakingChanges []BreakingChange           `json:"breaking_changes"`
+}
+
+// IncompatibleError reports a schema rejected by its compatibility mode
+type IncompatibleError struct {
+	Report *CompatibilityReport
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("schema is not %s compatible with version %d of %s: %d breaking changes",
+		e.Report.Compatibility, e.Report.LatestVersion, e.Report.ContextType, len(e.Report.BreakingChanges))
+}
+
+// Registry manages versioned payload schemas and validates contexts against them
+type Registry struct {
+	store storage.SchemaStore
+
+	mu       sync.RWMutex
+	compiled map[string]*Schema // Schema versions are immutable, so compiled documents are cached by ID
+}
+
+// NewRegistry creates a new schema registry
+func NewRegistry(store storage.SchemaStore) *Registry {
+	return &Registry{
+		store:    store,
+		compiled: make(map[string]*Schema),
+	}
+}
+
+// Register compiles a schema document and stores it as the next version of
+// its context type in the caller's tenant. A document that breaks the
+// compatibility mode of the type is rejected with a CONFLICT wrapping an
+// *IncompatibleError.
+func (r *Registry) Register(ctx context.Context, req *RegisterRequest) (*models.Schema, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, acberrors.Unauthorized("caller identity required")
+	}
+	compiled, report, err := r.check(ctx, claims.TenantID, req.ContextType, req.Document, "")
+	if err != nil {
+		return nil, err
+	}
+	if !report.Compatible {
+		return nil, acberrors.Conflict("schema breaks compatibility with the latest version").WithError(&IncompatibleError{Report: report})
+	}
+
+	sc := &models.Schema{
+		ID:          uuid.New().String(),
+		TenantID:    claims.TenantID,
+		ContextType: req.ContextType,
+		Version:     report.LatestVersion + 1,
+		Document:    req.Document,
+		Description: req.Description,
+		CreatedBy:   claims.AgentID,
+		CreatedAt:   time.Now(),
+	}
+	if err := r.store.Create(ctx, sc); err != nil {
+		if storage.IsConflict(err) {
+			return nil, acberrors.Conflict("another version was registered concurrently; check compatibility again").WithError(err)
+		}
+		return nil, fmt.Errorf("failed to register schema: %w", err)
+	}
+
+	r.cache(sc.ID, compiled)
+	return sc, nil
+}
+
+// Check reports whether a schema document could be registered for its
+// context type without storing it. req.Compatibility overrides the mode
+// configured for the type when set.
+func (r *Registry) Check(ctx context.Context, req *CheckRequest) (*CompatibilityReport, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, acberrors.Unauthorized("caller identity required")
+	}
+	_, report, err := r.check(ctx, claims.TenantID, req.ContextType, req.Document, req.Compatibility)
+	return report, err
+}
+
+// Compatibility returns the compatibility mode of a context type in the
+// caller's tenant, falling back to DefaultCompatibility
+func (r *Registry) Compatibility(ctx context.Context, contextType string) (models.SchemaCompatibility, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return "", acberrors.Unauthorized("caller identity required")
+	}
+	return r.compatibility(ctx, claims.TenantID, contextType)
+}
+
+// SetCompatibility configures the compatibility mode of a context type.
+// Only tenant admins may change it.
+func (r *Registry) SetCompatibility(ctx context.Context, contextType string, mode models.SchemaCompatibility) error {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return acberrors.Unauthorized("caller identity required")
+	}
+	if !claims.HasRole(auth.RoleAdmin) {
+		return acberrors.Forbidden("only admins may change schema compatibility")
+	}
+	if contextType == "" {
+		return acberrors.ValidationError("context_type is required")
+	}
+	if err := mode.Validate(); err != nil {
+		return acberrors.ValidationError(err.Error()).WithError(err)
+	}
+	return r.store.SetCompatibility(ctx, claims.TenantID, contextType, mode)
+}
+
+// check compiles doc and compares it with the latest version of contextType
+// under mode, or the configured mode when mode is empty
+func (r *Registry) check(ctx context.Context, tenantID, contextType string, doc []byte, mode models.SchemaCompatibility) (*Schema, *CompatibilityReport, error) {
+	if contextType == "" {
+		return nil, nil, acberrors.ValidationError("context_type is required")
+	}
+	compiled, err := Compile(doc)
+	if err != nil {
+		return nil, nil, acberrors.ValidationError(err.Error()).WithError(err)
+	}
+
+	if mode == "" {
+		if mode, err = r.compatibility(ctx, tenantID, contextType); err != nil {
+			return nil, nil, err
+		}
+	} else if err := mode.Validate(); err != nil {
+		return nil, nil, acberrors.ValidationError(err.Error()).WithError(err)
+	}
+
+	report := &CompatibilityReport{
+		ContextType:     contextType,
+		Compatibility:   mode,
+		Compatible:      true,
+		BreakingChanges: []BreakingChange{},
+	}
+	latest, err := r.store.GetLatest(ctx, tenantID, contextType)
+	if storage.IsNotFound(err) {
+		return compiled, report, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	report.LatestVersion = latest.Version
+
+	if mode != models.CompatibilityNone {
+		previous, err := r.compile(latest)
+		if err != nil {
+			return nil, nil, err
+		}
+		if changes := CheckCompatibility(mode, previous, compiled); len(changes) > 0 {
+			report.Compatible = false
+			report.BreakingChanges = changes
+		}
+	}
+	return compiled, report, nil
+}
+
+// compatibility returns the configured or default mode of a context type
+func (r *Registry) compatibility(ctx context.Context, tenantID, contextType string) (models.SchemaCompatibility, error) {
+	mode, err := r.store.GetCompatibility(ctx, tenantID, contextType)
+	if err != nil {
+		return "", err
+	}
+	if mode == "" {
+		mode = DefaultCompatibility
+	}
+	return mode, nil
+}
+
+// Get retrieves a schema in the caller's tenant
+func (r *Registry) Get(ctx context.Context, schemaID string) (*models.Schema, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, acberrors.Unauthorized("caller identity required")
+	}
+	return r.load(ctx, claims.TenantID, schemaID)
+}
+
+// List retrieves the schemas in the caller's tenant, newest version first
+func (r *Registry) List(ctx context.Context, filters *storage.SchemaFilters) ([]*models.Schema, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, acberrors.Unauthorized("caller identity required")
+	}
+
+	scoped := storage.SchemaFilters{Limit: 100}
+	if filters != nil {
+		scoped = *filters
+	}
+	scoped.TenantID = claims.TenantID
+	return r.store.List(ctx, &scoped)
+}
+
+// Delete removes a schema version. Only the registering agent or a tenant
+// admin may delete it, and only while no context references it.
+func (r *Registry) Delete(ctx context.Context, schemaID string) error {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return acberrors.Unauthorized("caller identity required")
+	}
+	sc, err := r.load(ctx, claims.TenantID, schemaID)
+	if err != nil {
+		return err
+	}
+	if sc.CreatedBy != claims.AgentID && !claims.HasRole(auth.RoleAdmin) {
+		return acberrors.Forbidden("only the registering agent may delete this schema")
+	}
+
+	err = r.store.Delete(ctx, schemaID)
+	switch {
+	case storage.IsConflict(err):
+		return acberrors.Conflict("schema is referenced by contexts").WithError(err)
+	case storage.IsNotFound(err):
+		return acberrors.NotFound("schema not found").WithError(err)
+	case err != nil:
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.compiled, schemaID)
+	r.mu.Unlock()
+	return nil
+}
+
+// ValidatePayload checks the payload of c against the schema it references.
+// Contexts without a SchemaID, or whose payload lives in object storage, are
+// not validated. Violations are reported as a VALIDATION_ERROR wrapping a
+// *ValidationError.
+func (r *Registry) ValidatePayload(ctx context.Context, c *models.Context) error {
+	if c.SchemaID == "" || (len(c.Payload) == 0 && c.PayloadRef != nil) {
+		return nil
+	}
+
+	sc, err := r.load(ctx, c.TenantID, c.SchemaID)
+	if err != nil {
+		if acberrors.Is(err, acberrors.ErrorCodeNotFound) {
+			return acberrors.ValidationError(fmt.Sprintf("unknown schema_id %q", c.SchemaID))
+		}
+		return err
+	}
+	if sc.ContextType != c.Type {
+		return acberrors.ValidationError(fmt.Sprintf("schema %s applies to context type %q, not %q", sc.ID, sc.ContextType, c.Type))
+	}
+
+	compiled, err := r.compile(sc)
+	if err != nil {
+		return err
+	}
+	if violations := compiled.Validate(c.Payload); len(violations) > 0 {
+		verr := &ValidationError{SchemaID: sc.ID, Violations: violations}
+		return acberrors.ValidationError("payload does not match schema").WithError(verr)
+	}
+	return nil
+}
+
+// load fetches a schema, hiding schemas of other tenants
+func (r *Registry) load(ctx context.Context, tenantID, schemaID string) (*models.Schema, error) {
+	sc, err := r.store.Get(ctx, schemaID)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return nil, acberrors.NotFound("schema not found").WithError(err)
+		}
+		return nil, err
+	}
+	if sc.TenantID != tenantID {
+		return nil, acberrors.NotFound("schema not found")
+	}
+	return sc, nil
+}
+
+// compile returns the cached compiled form of sc, compiling it on first use
+func (r *Registry) compile(sc *models.Schema) (*Schema, error) {
+	r.mu.RLock()
+	compiled, ok := r.compiled[sc.ID]
+	r.mu.RUnlock()
+	if ok {
+		return compiled, nil
+	}
+
+	compiled, err := Compile(sc.Document)
+	if err != nil {
+		return nil, fmt.Errorf("stored schema %s: %w", sc.ID, err)
+	}
+	r.cache(sc.ID, compiled)
+	return compiled, nil
+}
+
+func (r *Registry) cache(schemaID string, compiled *Schema) {
+	r.mu.Lock()
+	r.compiled[schemaID] = compiled
+	r.mu.Unlock()
+}
+
+// RegisterRequest contains schema registration data
+type RegisterRequest struct {
+	ContextType string
+	Document    []byte
+	Description string
+}
+
+// CheckRequest contains a schema to check without registering it
+type CheckRequest struct {
+	ContextType   string
+	Document      []byte
+	Compatibility models.SchemaCompatibility // Overrides the configured mode when set
+}
@@ -0,0 +1,311 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+)
+
+// memorySchemaStore is an in-memory SchemaStore
+type memorySchemaStore struct {
+	mu            sync.Mutex
+	schemas       map[string]*models.Schema
+	referenced    map[string]bool // Schema IDs that contexts still reference
+	compatibility map[string]models.SchemaCompatibility
+}
+
+func newMemorySchemaStore() *memorySchemaStore {
+	return &memorySchemaStore{
+		schemas:       make(map[string]*models.Schema),
+		referenced:    make(map[string]bool),
+		compatibility: make(map[string]models.SchemaCompatibility),
+	}
+}
+
+func (m *memorySchemaStore) Create(ctx context.Context, s *models.Schema) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	next := 1
+	if latest := m.latest(s.TenantID, s.ContextType); latest != nil {
+		next = latest.Version + 1
+	}
+	if s.Version != 0 && s.Version != next {
+		return fmt.Errorf("version %d is not the next version: %w", s.Version, storage.ErrConflict)
+	}
+	s.Version = next
+	cp := *s
+	m.schemas[s.ID] = &cp
+	return nil
+}
+
+func (m *memorySchemaStore) GetLatest(ctx context.Context, tenantID, contextType string) (*models.Schema, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	latest := m.latest(tenantID, contextType)
+	if latest == nil {
+		return nil, fmt.Errorf("no schema registered for %s: %w", contextType, storage.ErrNotFound)
+	}
+	cp := *latest
+	return &cp, nil
+}
+
+func (m *memorySchemaStore) latest(tenantID, contextType string) *models.Schema {
+	var latest *models.Schema
+	for _, s := range m.schemas {
+		if s.TenantID == tenantID && s.ContextType == contextType && (latest == nil || s.Version > latest.Version) {
+			latest = s
+		}
+	}
+	return latest
+}
+
+func (m *memorySchemaStore) GetCompatibility(ctx context.Context, tenantID, contextType string) (models.SchemaCompatibility, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.compatibility[tenantID+"/"+contextType], nil
+}
+
+func (m *memorySchemaStore) SetCompatibility(ctx context.Context, tenantID, contextType string, mode models.SchemaCompatibility) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compatibility[tenantID+"/"+contextType] = mode
+	return nil
+}
+
+func (m *memorySchemaStore) Get(ctx context.Context, schemaID string) (*models.Schema, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.schemas[schemaID]
+	if !ok {
+		return nil, fmt.Errorf("schema not found: %w", storage.ErrNotFound)
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (m *memorySchemaStore) List(ctx context.Context, filters *storage.SchemaFilters) ([]*models.Schema, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*models.Schema
+	for _, s := range m.schemas {
+		if s.TenantID == filters.TenantID && (filters.ContextType == "" || s.ContextType == filters.ContextType) {
+			cp := *s
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (m *memorySchemaStore) Delete(ctx context.Context, schemaID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.schemas[schemaID]; !ok {
+		return fmt.Errorf("schema not found: %w", storage.ErrNotFound)
+	}
+	if m.referenced[schemaID] {
+		return fmt.Errorf("schema %s is referenced by contexts: %w", schemaID, storage.ErrConflict)
+	}
+	delete(m.schemas, schemaID)
+	return nil
+}
+
+func callerCtx(tenantID, agentID string, roles ...string) context.Context {
+	return auth.ContextWithClaims(context.Background(), &auth.JWTClaims{AgentID: agentID, TenantID: tenantID, Roles: roles})
+}
+
+func TestRegistry_RegisterVersions(t *testing.T) {
+	r := NewRegistry(newMemorySchemaStore())
+	ctx := callerCtx("t1", "a1")
+
+	v1, err := r.Register(ctx, &RegisterRequest{ContextType: "task", Document: []byte(`{"type":"object"}`)})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	v2, err := r.Register(ctx, &RegisterRequest{ContextType: "task", Document: []byte(`{"type":"object","properties":{"id":{"type":"string"}}}`)})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if v1.Version != 1 || v2.Version != 2 || v2.TenantID != "t1" || v2.CreatedBy != "a1" {
+		t.Fatalf("unexpected versions %+v %+v", v1, v2)
+	}
+
+	if _, err := r.Register(ctx, &RegisterRequest{ContextType: "task", Document: []byte(`{"type":"text"}`)}); !acberrors.Is(err, acberrors.ErrorCodeValidationError) {
+		t.Fatalf("expected validation error for invalid schema, got %v", err)
+	}
+	if _, err := r.Register(ctx, &RegisterRequest{Document: []byte(`{}`)}); !acberrors.Is(err, acberrors.ErrorCodeValidationError) {
+		t.Fatalf("expected validation error for missing context type, got %v", err)
+	}
+	if _, err := r.Register(context.Background(), &RegisterRequest{ContextType: "task", Document: []byte(`{}`)}); !acberrors.Is(err, acberrors.ErrorCodeUnauthorized) {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+
+	// Schemas are invisible to other tenants
+	if _, err := r.Get(callerCtx("t2", "a9"), v1.ID); !acberrors.Is(err, acberrors.ErrorCodeNotFound) {
+		t.Fatalf("expected not found across tenants, got %v", err)
+	}
+	list, err := r.List(callerCtx("t2", "a9"), &storage.SchemaFilters{ContextType: "task"})
+	if err != nil || len(list) != 0 {
+		t.Fatalf("expected no schemas for other tenant, got %d, %v", len(list), err)
+	}
+}
+
+func TestRegistry_Delete(t *testing.T) {
+	store := newMemorySchemaStore()
+	r := NewRegistry(store)
+	owner := callerCtx("t1", "a1")
+
+	sc, err := r.Register(owner, &RegisterRequest{ContextType: "task", Document: []byte(`{}`)})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	if err := r.Delete(callerCtx("t1", "a2"), sc.ID); !acberrors.Is(err, acberrors.ErrorCodeForbidden) {
+		t.Fatalf("expected forbidden for another agent, got %v", err)
+	}
+
+	store.referenced[sc.ID] = true
+	if err := r.Delete(owner, sc.ID); !acberrors.Is(err, acberrors.ErrorCodeConflict) {
+		t.Fatalf("expected conflict while referenced, got %v", err)
+	}
+
+	store.referenced[sc.ID] = false
+	if err := r.Delete(callerCtx("t1", "admin", string(auth.RoleAdmin)), sc.ID); err != nil {
+		t.Fatalf("admin delete: %v", err)
+	}
+	if err := r.Delete(owner, sc.ID); !acberrors.Is(err, acberrors.ErrorCodeNotFound) {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestRegistry_ValidatePayload(t *testing.T) {
+	r := NewRegistry(newMemorySchemaStore())
+	sc, err := r.Register(callerCtx("t1", "a1"), &RegisterRequest{
+		ContextType: "task",
+		Document:    []byte(`{"type":"object","required":["id"],"properties":{"id":{"type":"string"},"priority":{"maximum":5}}}`),
+	})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	ctx := context.Background()
+	task := func(payload string) *models.Context {
+		return &models.Context{Type: "task", TenantID: "t1", SchemaID: sc.ID, Payload: []byte(payload)}
+	}
+
+	if err := r.ValidatePayload(ctx, task(`{"id":"x","priority":1}`)); err != nil {
+		t.Fatalf("valid payload: %v", err)
+	}
+
+	err = r.ValidatePayload(ctx, task(`{"priority":9}`))
+	var invalid *ValidationError
+	if !acberrors.Is(err, acberrors.ErrorCodeValidationError) || !errors.As(err, &invalid) {
+		t.Fatalf("expected schema validation error, got %v", err)
+	}
+	want := []Violation{{"/id", "required property is missing"}, {"/priority", "must be <= 5"}}
+	if invalid.SchemaID != sc.ID || fmt.Sprint(invalid.Violations) != fmt.Sprint(want) {
+		t.Fatalf("unexpected violations %+v", invalid)
+	}
+
+	// Contexts without a schema are not validated
+	if err := r.ValidatePayload(ctx, &models.Context{Type: "task", TenantID: "t1", Payload: []byte("not json")}); err != nil {
+		t.Fatalf("unvalidated payload: %v", err)
+	}
+
+	wrongType := task(`{"id":"x"}`)
+	wrongType.Type = "note"
+	if err := r.ValidatePayload(ctx, wrongType); !acberrors.Is(err, acberrors.ErrorCodeValidationError) {
+		t.Fatalf("expected validation error for mismatched type, got %v", err)
+	}
+
+	otherTenant := task(`{"id":"x"}`)
+	otherTenant.TenantID = "t2"
+	if err := r.ValidatePayload(ctx, otherTenant); !acberrors.Is(err, acberrors.ErrorCodeValidationError) {
+		t.Fatalf("expected validation error for another tenant's schema, got %v", err)
+	}
+}
+
+func TestRegistry_Compatibility(t *testing.T) {
+	store := newMemorySchemaStore()
+	r := NewRegistry(store)
+	ctx := callerCtx("t1", "a1")
+	admin := callerCtx("t1", "admin", string(auth.RoleAdmin))
+
+	if _, err := r.Register(ctx, &RegisterRequest{ContextType: "task", Document: []byte(`{"type":"object","properties":{"id":{"type":"string"}}}`)}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if mode, err := r.Compatibility(ctx, "task"); err != nil || mode != DefaultCompatibility {
+		t.Fatalf("expected default mode, got %q, %v", mode, err)
+	}
+
+	// Making a property required breaks backward compatibility
+	breaking := []byte(`{"type":"object","required":["id"],"properties":{"id":{"type":"string"}}}`)
+	_, err := r.Register(ctx, &RegisterRequest{ContextType: "task", Document: breaking})
+	var incompatible *IncompatibleError
+	if !acberrors.Is(err, acberrors.ErrorCodeConflict) || !errors.As(err, &incompatible) {
+		t.Fatalf("expected incompatible conflict, got %v", err)
+	}
+	report := incompatible.Report
+	if report.Compatible || report.LatestVersion != 1 || report.Compatibility != models.CompatibilityBackward ||
+		len(report.BreakingChanges) != 1 || report.BreakingChanges[0].Kind != ChangeRequiredAdded {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	// The dry run reports the same without storing anything, and may
+	// override the mode
+	report, err = r.Check(ctx, &CheckRequest{ContextType: "task", Document: breaking})
+	if err != nil || report.Compatible || len(report.BreakingChanges) != 1 {
+		t.Fatalf("unexpected check result %+v, %v", report, err)
+	}
+	report, err = r.Check(ctx, &CheckRequest{ContextType: "task", Document: breaking, Compatibility: models.CompatibilityForward})
+	if err != nil || !report.Compatible || report.Compatibility != models.CompatibilityForward {
+		t.Fatalf("unexpected forward check result %+v, %v", report, err)
+	}
+	if _, err := r.Check(ctx, &CheckRequest{ContextType: "task", Document: breaking, Compatibility: "sideways"}); !acberrors.Is(err, acberrors.ErrorCodeValidationError) {
+		t.Fatalf("expected validation error for unknown mode, got %v", err)
+	}
+	report, err = r.Check(ctx, &CheckRequest{ContextType: "note", Document: breaking})
+	if err != nil || !report.Compatible || report.LatestVersion != 0 {
+		t.Fatalf("first version should always be compatible, got %+v, %v", report, err)
+	}
+	if len(store.schemas) != 1 {
+		t.Fatalf("dry run stored a schema")
+	}
+
+	// Only admins configure the mode
+	if err := r.SetCompatibility(ctx, "task", models.CompatibilityForward); !acberrors.Is(err, acberrors.ErrorCodeForbidden) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+	if err := r.SetCompatibility(admin, "task", "sideways"); !acberrors.Is(err, acberrors.ErrorCodeValidationError) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if err := r.SetCompatibility(admin, "task", models.CompatibilityForward); err != nil {
+		t.Fatalf("set compatibility: %v", err)
+	}
+	v2, err := r.Register(ctx, &RegisterRequest{ContextType: "task", Document: breaking})
+	if err != nil || v2.Version != 2 {
+		t.Fatalf("forward compatible register: %+v, %v", v2, err)
+	}
+
+	// Dropping the requirement again is backward but not forward compatible
+	if _, err := r.Register(ctx, &RegisterRequest{ContextType: "task", Document: []byte(`{"type":"object"}`)}); !acberrors.Is(err, acberrors.ErrorCodeConflict) {
+		t.Fatalf("expected conflict under forward mode, got %v", err)
+	}
+	if err := r.SetCompatibility(admin, "task", models.CompatibilityNone); err != nil {
+		t.Fatalf("set compatibility: %v", err)
+	}
+	if _, err := r.Register(ctx, &RegisterRequest{ContextType: "task", Document: []byte(`{"type":"string"}`)}); err != nil {
+		t.Fatalf("register without checks: %v", err)
+	}
+
+	// Modes are per tenant
+	if mode, err := r.Compatibility(callerCtx("t2", "a9"), "task"); err != nil || mode != DefaultCompatibility {
+		t.Fatalf("expected default mode for other tenant, got %q, %v", mode, err)
+	}
+}
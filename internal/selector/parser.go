@@ -0,0 +1,208 @@
+package selector
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidSelector = errors.New("invalid selector")
+)
+
+const (
+	// MaxRequirements bounds the size of the SQL generated for a selector
+	MaxRequirements = 32
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNot       // !
+	tokenEquals    // = or ==
+	tokenNotEquals // !=
+	tokenOpenParen
+	tokenCloseParen
+	tokenComma
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// Parse parses a selector such as "env=prod,tier in (gold,silver),!deprecated"
+func Parse(input string) (Selector, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	var sel Selector
+	if p.peek().kind == tokenEOF {
+		return sel, nil
+	}
+
+	for {
+		req, err := p.parseRequirement()
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, req)
+		if len(sel) > MaxRequirements {
+			return nil, fmt.Errorf("%w: more than %d requirements", ErrInvalidSelector, MaxRequirements)
+		}
+
+		switch tok := p.next(); tok.kind {
+		case tokenEOF:
+			return sel, nil
+		case tokenComma:
+			continue
+		default:
+			return nil, p.errorf(tok, "expected ',' or end of selector")
+		}
+	}
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at position %d", ErrInvalidSelector, fmt.Sprintf(format, args...), tok.pos)
+}
+
+func (p *parser) parseRequirement() (Requirement, error) {
+	tok := p.next()
+	if tok.kind == tokenNot {
+		key := p.next()
+		if key.kind != tokenIdent {
+			return Requirement{}, p.errorf(key, "expected key after '!'")
+		}
+		return Requirement{Key: key.value, Operator: OpDoesNotExist}, nil
+	}
+	if tok.kind != tokenIdent {
+		return Requirement{}, p.errorf(tok, "expected key")
+	}
+	key := tok.value
+
+	switch op := p.peek(); {
+	case op.kind == tokenEOF || op.kind == tokenComma:
+		return Requirement{Key: key, Operator: OpExists}, nil
+	case op.kind == tokenEquals || op.kind == tokenNotEquals:
+		p.next()
+		operator := OpEquals
+		if op.kind == tokenNotEquals {
+			operator = OpNotEquals
+		}
+		value := ""
+		if v := p.peek(); v.kind == tokenIdent {
+			value = p.next().value
+		} else if v.kind != tokenEOF && v.kind != tokenComma {
+			return Requirement{}, p.errorf(v, "expected value")
+		}
+		return Requirement{Key: key, Operator: operator, Values: []string{value}}, nil
+	case op.kind == tokenIdent && (op.value == string(OpIn) || op.value == string(OpNotIn)):
+		p.next()
+		values, err := p.parseValueSet()
+		if err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: key, Operator: Operator(op.value), Values: values}, nil
+	default:
+		return Requirement{}, p.errorf(op, "unexpected token after key %q", key)
+	}
+}
+
+func (p *parser) parseValueSet() ([]string, error) {
+	if tok := p.next(); tok.kind != tokenOpenParen {
+		return nil, p.errorf(tok, "expected '('")
+	}
+
+	var values []string
+	for {
+		tok := p.next()
+		if tok.kind != tokenIdent {
+			return nil, p.errorf(tok, "expected value")
+		}
+		values = append(values, tok.value)
+
+		switch sep := p.next(); sep.kind {
+		case tokenComma:
+			continue
+		case tokenCloseParen:
+			return values, nil
+		default:
+			return nil, p.errorf(sep, "expected ',' or ')'")
+		}
+	}
+}
+
+// lex splits the input into tokens
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		ch := input[i]
+		switch {
+		case ch == ' ' || ch == '\t':
+			i++
+		case ch == ',':
+			tokens = append(tokens, token{kind: tokenComma, pos: i})
+			i++
+		case ch == '(':
+			tokens = append(tokens, token{kind: tokenOpenParen, pos: i})
+			i++
+		case ch == ')':
+			tokens = append(tokens, token{kind: tokenCloseParen, pos: i})
+			i++
+		case ch == '!':
+			if i+1 < len(input) && input[i+1] == '=' {
+				tokens = append(tokens, token{kind: tokenNotEquals, pos: i})
+				i += 2
+			} else {
+				tokens = append(tokens, token{kind: tokenNot, pos: i})
+				i++
+			}
+		case ch == '=':
+			tokens = append(tokens, token{kind: tokenEquals, pos: i})
+			if i+1 < len(input) && input[i+1] == '=' {
+				i += 2
+			} else {
+				i++
+			}
+		case isIdentChar(ch):
+			start := i
+			for i < len(input) && isIdentChar(input[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: input[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q at position %d", ErrInvalidSelector, ch, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// isIdentChar reports whether ch may appear in a key or value
+func isIdentChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' ||
+		ch >= 'A' && ch <= 'Z' ||
+		ch >= '0' && ch <= '9' ||
+		ch == '-' || ch == '_' || ch == '.' || ch == '/' || ch == ':'
+}
@@ -0,0 +1,99 @@
+package selector
+
+import (
+	"sort"
+	"strings"
+)
+
+// Operator represents a selector requirement operator
+type Operator string
+
+const (
+	OpEquals       Operator = "="
+	OpNotEquals    Operator = "!="
+	OpIn           Operator = "in"
+	OpNotIn        Operator = "notin"
+	OpExists       Operator = "exists"
+	OpDoesNotExist Operator = "!"
+)
+
+// Requirement is a single condition on a metadata key
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string // One value for = and !=, one or more for in/notin, none for exists/!
+}
+
+// Selector is a conjunction of requirements; an empty selector matches everything
+type Selector []Requirement
+
+// Empty reports whether the selector has no requirements
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+// Matches evaluates the selector against a metadata map.
+// As in Kubernetes, != and notin also match when the key is absent.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches evaluates a single requirement against a metadata map
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case OpEquals:
+		return ok && value == r.Values[0]
+	case OpNotEquals:
+		return !ok || value != r.Values[0]
+	case OpIn:
+		return ok && contains(r.Values, value)
+	case OpNotIn:
+		return !ok || !contains(r.Values, value)
+	case OpExists:
+		return ok
+	case OpDoesNotExist:
+		return !ok
+	default:
+		return false
+	}
+}
+
+// String renders the requirement in selector syntax
+func (r Requirement) String() string {
+	switch r.Operator {
+	case OpExists:
+		return r.Key
+	case OpDoesNotExist:
+		return "!" + r.Key
+	case OpIn, OpNotIn:
+		values := append([]string(nil), r.Values...)
+		sort.Strings(values)
+		return r.Key + " " + string(r.Operator) + " (" + strings.Join(values, ",") + ")"
+	default:
+		return r.Key + string(r.Operator) + r.Values[0]
+	}
+}
+
+// String renders the selector in canonical syntax
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func contains(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
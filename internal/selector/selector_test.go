@@ -0,0 +1,130 @@
+package selector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Selector
+	}{
+		{"empty", "", nil},
+		{"whitespace", "   ", nil},
+		{"equals", "env=prod", Selector{{Key: "env", Operator: OpEquals, Values: []string{"prod"}}}},
+		{"double equals", "env==prod", Selector{{Key: "env", Operator: OpEquals, Values: []string{"prod"}}}},
+		{"not equals", "env != prod", Selector{{Key: "env", Operator: OpNotEquals, Values: []string{"prod"}}}},
+		{"empty value", "env=", Selector{{Key: "env", Operator: OpEquals, Values: []string{""}}}},
+		{"exists", "gpu", Selector{{Key: "gpu", Operator: OpExists}}},
+		{"does not exist", "!deprecated", Selector{{Key: "deprecated", Operator: OpDoesNotExist}}},
+		{"in", "tier in (gold, silver)", Selector{{Key: "tier", Operator: OpIn, Values: []string{"gold", "silver"}}}},
+		{"notin", "region notin (eu-west-1)", Selector{{Key: "region", Operator: OpNotIn, Values: []string{"eu-west-1"}}}},
+		{"prefixed key", "acb.io/team=ml", Selector{{Key: "acb.io/team", Operator: OpEquals, Values: []string{"ml"}}}},
+		{
+			"combined",
+			"env=prod,tier in (gold,silver),!deprecated",
+			Selector{
+				{Key: "env", Operator: OpEquals, Values: []string{"prod"}},
+				{Key: "tier", Operator: OpIn, Values: []string{"gold", "silver"}},
+				{Key: "deprecated", Operator: OpDoesNotExist},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	inputs := []string{
+		"env=prod,",
+		",env=prod",
+		"!",
+		"!=prod",
+		"env prod",
+		"tier in gold",
+		"tier in ()",
+		"tier in (gold",
+		"tier in (gold,)",
+		"env=prod extra",
+		"env=(prod)",
+		"env=prod;drop",
+		"env='prod'",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrInvalidSelector))
+		})
+	}
+}
+
+func TestParse_TooManyRequirements(t *testing.T) {
+	input := "k"
+	for i := 0; i < MaxRequirements; i++ {
+		input += ",k"
+	}
+	_, err := Parse(input)
+	assert.ErrorIs(t, err, ErrInvalidSelector)
+}
+
+func TestSelector_Matches(t *testing.T) {
+	labels := map[string]string{"env": "prod", "tier": "gold"}
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env=dev", false},
+		{"env!=dev", true},
+		{"missing!=x", true},
+		{"tier in (gold,silver)", true},
+		{"tier in (bronze)", false},
+		{"tier notin (bronze)", true},
+		{"missing notin (bronze)", true},
+		{"tier notin (gold)", false},
+		{"env", true},
+		{"missing", false},
+		{"!missing", true},
+		{"!env", false},
+		{"env=prod,tier in (gold,silver),!deprecated", true},
+		{"env=prod,deprecated", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			sel, err := Parse(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, sel.Matches(labels))
+		})
+	}
+
+	sel, err := Parse("env=prod")
+	require.NoError(t, err)
+	assert.False(t, sel.Matches(nil))
+}
+
+func TestSelector_String(t *testing.T) {
+	sel, err := Parse("env == prod, tier in (silver,gold), !deprecated, gpu, region!=eu")
+	require.NoError(t, err)
+	assert.Equal(t, "env=prod,tier in (gold,silver),!deprecated,gpu,region!=eu", sel.String())
+
+	// Canonical form round-trips
+	again, err := Parse(sel.String())
+	require.NoError(t, err)
+	assert.Equal(t, sel.String(), again.String())
+}
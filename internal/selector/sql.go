@@ -0,0 +1,53 @@
+package selector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SQL compiles the selector into a parameterised boolean expression over a
+// JSONB column holding a flat string map. Placeholders start at argIndex; the
+// returned nextIndex is the first placeholder number not used. Keys and values
+// are always passed as arguments, never interpolated. Equality uses containment
+// (@>) so it can be served by a GIN index on the column.
+func (s Selector) SQL(column string, argIndex int) (clause string, args []interface{}, nextIndex int) {
+	if s.Empty() {
+		return "TRUE", nil, argIndex
+	}
+
+	conditions := make([]string, 0, len(s))
+	for _, r := range s {
+		field := fmt.Sprintf("(%s ->> $%d)", column, argIndex)
+		args = append(args, r.Key)
+		argIndex++
+
+		var cond string
+		switch r.Operator {
+		case OpEquals:
+			cond = fmt.Sprintf("%s @> jsonb_build_object($%d::text, $%d::text)", column, argIndex-1, argIndex)
+			args = append(args, r.Values[0])
+			argIndex++
+		case OpNotEquals:
+			cond = fmt.Sprintf("%s IS DISTINCT FROM $%d", field, argIndex)
+			args = append(args, r.Values[0])
+			argIndex++
+		case OpIn:
+			cond = fmt.Sprintf("%s = ANY($%d::text[])", field, argIndex)
+			args = append(args, r.Values)
+			argIndex++
+		case OpNotIn:
+			cond = fmt.Sprintf("(%s IS NULL OR NOT (%s = ANY($%d::text[])))", field, field, argIndex)
+			args = append(args, r.Values)
+			argIndex++
+		case OpExists:
+			cond = field + " IS NOT NULL"
+		case OpDoesNotExist:
+			cond = field + " IS NULL"
+		default:
+			cond = "FALSE"
+		}
+		conditions = append(conditions, cond)
+	}
+
+	return "(" + strings.Join(conditions, " AND ") + ")", args, argIndex
+}
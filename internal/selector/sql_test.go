@@ -0,0 +1,62 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector_SQL(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		start  int
+		clause string
+		args   []interface{}
+		next   int
+	}{
+		{
+			name:   "empty",
+			input:  "",
+			start:  3,
+			clause: "TRUE",
+			next:   3,
+		},
+		{
+			name:   "equals and not equals",
+			input:  "env=prod,region!=eu",
+			start:  1,
+			clause: "(metadata @> jsonb_build_object($1::text, $2::text) AND (metadata ->> $3) IS DISTINCT FROM $4)",
+			args:   []interface{}{"env", "prod", "region", "eu"},
+			next:   5,
+		},
+		{
+			name:   "set operators",
+			input:  "tier in (gold,silver),zone notin (a)",
+			start:  2,
+			clause: "((metadata ->> $2) = ANY($3::text[]) AND ((metadata ->> $4) IS NULL OR NOT ((metadata ->> $4) = ANY($5::text[]))))",
+			args:   []interface{}{"tier", []string{"gold", "silver"}, "zone", []string{"a"}},
+			next:   6,
+		},
+		{
+			name:   "existence",
+			input:  "gpu,!deprecated",
+			start:  1,
+			clause: "((metadata ->> $1) IS NOT NULL AND (metadata ->> $2) IS NULL)",
+			args:   []interface{}{"gpu", "deprecated"},
+			next:   3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := Parse(tt.input)
+			require.NoError(t, err)
+			clause, args, next := sel.SQL("metadata", tt.start)
+			assert.Equal(t, tt.clause, clause)
+			assert.Equal(t, tt.args, args)
+			assert.Equal(t, tt.next, next)
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/acb/internal/constants"
+	"github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/acb/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Batch handlers. Each batch runs in one transaction. In atomic mode (the
+// default) a failing item fails the request with its own status and index;
+// in partial mode the response reports the outcome of every item.
+
+// maxBatchBodyBytes bounds the request body of a batch. Payloads travel
+// base64 encoded, a third larger than the bytes MaxBatchPayloadBytes caps.
+const maxBatchBodyBytes = 2 * constants.MaxBatchPayloadBytes
+
+// batchItem is the outcome of one item of a batch response
+type batchItem struct {
+	Index   int             `json:"index"`
+	Status  int             `json:"status"`
+	Context *models.Context `json:"context,omitempty"`
+	Agent   *models.Agent   `json:"agent,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// failedItem reports an item that failed with err
+func failedItem(index int, err error, fallback int) batchItem {
+	return batchItem{Index: index, Status: statusForError(err, fallback), Error: errorMessage(err)}
+}
+
+// contextBatch dispatches POST /contexts:batchCreate, :batchGet and
+// :batchDelete
+func (s *HTTPServer) contextBatch(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	switch c.Param("action") {
+	case ":batchCreate":
+		s.batchCreateContexts(c)
+	case ":batchGet":
+		s.batchGetContexts(c)
+	case ":batchDelete":
+		s.batchDeleteContexts(c)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown context action"})
+	}
+}
+
+// agentBatch dispatches POST /agents:batchRegister
+func (s *HTTPServer) agentBatch(c *gin.Context) {
+	if s.registrySvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "registry service unavailable"})
+		return
+	}
+	switch c.Param("action") {
+	case ":batchRegister":
+		s.batchRegisterAgents(c)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown agent action"})
+	}
+}
+
+func (s *HTTPServer) batchCreateContexts(c *gin.Context) {
+	var req struct {
+		Mode     models.BatchMode    `json:"mode"`
+		Contexts []createContextBody `json:"contexts" binding:"required,dive"`
+	}
+	if !bindBatch(c, &req) {
+		return
+	}
+
+	agentID, _ := c.Get("agent_id")
+	tenantID, _ := c.Get("tenant_id")
+	reqs := make([]*context.CreateRequest, len(req.Contexts))
+	for i := range req.Contexts {
+		reqs[i] = req.Contexts[i].request(agentID.(string), tenantID.(string))
+	}
+
+	results, err := s.contextMgr.BatchCreate(c.Request.Context(), batchMode(req.Mode), reqs)
+	if err != nil {
+		respondBatchError(c, err, http.StatusBadRequest)
+		return
+	}
+	items := make([]batchItem, len(results))
+	for i, r := range results {
+		if r.Err != nil {
+			items[i] = failedItem(i, r.Err, http.StatusBadRequest)
+			continue
+		}
+		items[i] = batchItem{Index: i, Status: http.StatusCreated, Context: r.Context}
+	}
+	c.JSON(http.StatusOK, gin.H{"results": items})
+}
+
+func (s *HTTPServer) batchGetContexts(c *gin.Context) {
+	var req struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+	if !bindBatch(c, &req) {
+		return
+	}
+
+	results, err := s.contextMgr.BatchGet(c.Request.Context(), req.IDs)
+	if err != nil {
+		respondBatchError(c, err, http.StatusInternalServerError)
+		return
+	}
+	items := make([]batchItem, len(results))
+	for i, r := range results {
+		if r.Err != nil {
+			items[i] = failedItem(i, r.Err, http.StatusInternalServerError)
+			continue
+		}
+		items[i] = batchItem{Index: i, Status: http.StatusOK, Context: r.Context}
+	}
+	c.JSON(http.StatusOK, gin.H{"results": items})
+}
+
+func (s *HTTPServer) batchDeleteContexts(c *gin.Context) {
+	var req struct {
+		Mode models.BatchMode `json:"mode"`
+		IDs  []string         `json:"ids" binding:"required"`
+	}
+	if !bindBatch(c, &req) {
+		return
+	}
+
+	results, err := s.contextMgr.BatchDelete(c.Request.Context(), batchMode(req.Mode), req.IDs)
+	if err != nil {
+		respondBatchError(c, err, http.StatusInternalServerError)
+		return
+	}
+	items := make([]batchItem, len(results))
+	for i, r := range results {
+		if r.Err != nil {
+			items[i] = failedItem(i, r.Err, http.StatusInternalServerError)
+			continue
+		}
+		items[i] = batchItem{Index: i, Status: http.StatusNoContent}
+	}
+	c.JSON(http.StatusOK, gin.H{"results": items})
+}
+
+func (s *HTTPServer) batchRegisterAgents(c *gin.Context) {
+	var req struct {
+		Mode   models.BatchMode           `json:"mode"`
+		Agents []registry.RegisterRequest `json:"agents" binding:"required"`
+	}
+	if !bindBatch(c, &req) {
+		return
+	}
+
+	tenantID, _ := c.Get("tenant_id")
+	tenantIDStr, ok := tenantID.(string)
+	if !ok || tenantIDStr == "" {
+		tenantIDStr = "default" // MVP: single tenant
+	}
+	reqs := make([]*registry.RegisterRequest, len(req.Agents))
+	for i := range req.Agents {
+		req.Agents[i].TenantID = tenantIDStr
+		reqs[i] = &req.Agents[i]
+	}
+
+	results, err := s.registrySvc.RegisterBatch(c.Request.Context(), batchMode(req.Mode), reqs)
+	if err != nil {
+		respondBatchError(c, err, http.StatusBadRequest)
+		return
+	}
+	items := make([]batchItem, len(results))
+	for i, r := range results {
+		if r.Err != nil {
+			items[i] = failedItem(i, r.Err, http.StatusBadRequest)
+			continue
+		}
+		items[i] = batchItem{Index: i, Status: http.StatusCreated, Agent: r.Agent}
+	}
+	c.JSON(http.StatusOK, gin.H{"results": items})
+}
+
+// bindBatch binds the JSON body of a batch request, rejecting bodies over
+// maxBatchBodyBytes. It writes the error response and returns false when the
+// body cannot be bound.
+func bindBatch(c *gin.Context, req any) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchBodyBytes)
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "batch request body is too large"})
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	return false
+}
+
+// batchMode defaults an omitted mode to atomic
+func batchMode(mode models.BatchMode) models.BatchMode {
+	if mode == "" {
+		return models.BatchAtomic
+	}
+	return mode
+}
+
+// respondBatchError writes the error of a batch that failed as a whole. When
+// an item failed an atomic batch, the response carries that item's status
+// and error, using itemFallback for errors without a code, and its index.
+func respondBatchError(c *gin.Context, err error, itemFallback int) {
+	var batchErr *storage.BatchError
+	if !errors.As(err, &batchErr) {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	body := errorBody(batchErr.Err)
+	body["index"] = batchErr.Index
+	c.JSON(statusForError(batchErr.Err, itemFallback), body)
+}
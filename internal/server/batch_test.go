@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/registry"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBatchHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	contextStore := &inMemoryContextStore{}
+	agentStore := &inMemoryAgentStore{}
+	httpSrv := NewHTTPServer("8080", registry.NewService(agentStore),
+		ctxmgr.NewManager(contextStore), jwtManager, auth.NewRBAC())
+
+	do := func(path, agentID string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		_ = json.NewEncoder(&buf).Encode(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, &buf)
+		req.Header.Set("Authorization", authHeaderFor(t, jwtManager, agentID))
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+	type result struct {
+		Index   int    `json:"index"`
+		Status  int    `json:"status"`
+		Error   string `json:"error"`
+		Context *struct {
+			ID string `json:"id"`
+		} `json:"context"`
+		Agent *struct {
+			ID string `json:"id"`
+		} `json:"agent"`
+	}
+	decode := func(w *httptest.ResponseRecorder) []result {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Results []result `json:"results"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Results
+	}
+	note := func(scope string) map[string]any {
+		return map[string]any{"type": "note", "payload": []byte(`{}`), "access_control": map[string]any{"scope": scope}}
+	}
+
+	// An atomic batch creates every context
+	results := decode(do("/api/v1/contexts:batchCreate", "agent-1", map[string]any{
+		"contexts": []any{note("public"), note("private")},
+	}))
+	if len(results) != 2 || results[0].Status != http.StatusCreated || results[1].Context == nil {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	public, private := results[0].Context.ID, results[1].Context.ID
+	if len(contextStore.m) != 2 {
+		t.Fatalf("expected 2 stored contexts, got %d", len(contextStore.m))
+	}
+
+	// One invalid item fails an atomic batch, reporting its index
+	w := do("/api/v1/contexts:batchCreate", "agent-1", map[string]any{
+		"contexts": []any{note("public"), note("galaxy")},
+	})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"index":1`) {
+		t.Fatalf("expected 400 at index 1, got %d %s", w.Code, w.Body.String())
+	}
+	if len(contextStore.m) != 2 {
+		t.Fatalf("atomic batch must not write, have %d contexts", len(contextStore.m))
+	}
+
+	// In partial mode the valid items are still created
+	results = decode(do("/api/v1/contexts:batchCreate", "agent-1", map[string]any{
+		"mode": "partial", "contexts": []any{note("public"), note("galaxy")},
+	}))
+	if results[0].Status != http.StatusCreated || results[1].Status != http.StatusBadRequest || results[1].Error == "" {
+		t.Fatalf("unexpected partial results %+v", results)
+	}
+
+	for name, body := range map[string]any{
+		"bad mode":      map[string]any{"mode": "eventual", "contexts": []any{note("public")}},
+		"empty":         map[string]any{"contexts": []any{}},
+		"missing field": map[string]any{"contexts": []any{map[string]any{"type": "note"}}},
+	} {
+		if w := do("/api/v1/contexts:batchCreate", "agent-1", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s expected 400, got %d", name, w.Code)
+		}
+	}
+	if w := do("/api/v1/contexts:batchMerge", "agent-1", map[string]any{}); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown action expected 404, got %d", w.Code)
+	}
+
+	// Contexts another agent may not read are reported as not found
+	results = decode(do("/api/v1/contexts:batchGet", "agent-2", map[string]any{"ids": []string{public, private, "missing"}}))
+	if results[0].Status != http.StatusOK || results[0].Context.ID != public ||
+		results[1].Status != http.StatusNotFound || results[2].Status != http.StatusNotFound {
+		t.Fatalf("unexpected get results %+v", results)
+	}
+
+	// Only the owner may delete, and an atomic batch deletes nothing when one
+	// item is forbidden
+	w = do("/api/v1/contexts:batchDelete", "agent-2", map[string]any{"ids": []string{public}})
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), `"index":0`) {
+		t.Fatalf("expected 403 at index 0, got %d %s", w.Code, w.Body.String())
+	}
+	results = decode(do("/api/v1/contexts:batchDelete", "agent-1", map[string]any{"mode": "partial", "ids": []string{public, "missing"}}))
+	if results[0].Status != http.StatusNoContent || results[1].Status != http.StatusNotFound {
+		t.Fatalf("unexpected delete results %+v", results)
+	}
+	if _, ok := contextStore.m[public]; ok {
+		t.Fatalf("expected %s to be deleted", public)
+	}
+
+	// Agents register in batches, duplicates reported as conflicts
+	results = decode(do("/api/v1/agents:batchRegister", "agent-1", map[string]any{
+		"agents": []any{map[string]any{"id": "planner", "type": "llm"}, map[string]any{"id": "critic", "type": "llm"}},
+	}))
+	if results[0].Status != http.StatusCreated || results[1].Agent == nil || results[1].Agent.ID != "critic" {
+		t.Fatalf("unexpected register results %+v", results)
+	}
+	w = do("/api/v1/agents:batchRegister", "agent-1", map[string]any{
+		"agents": []any{map[string]any{"id": "writer", "type": "llm"}, map[string]any{"id": "planner", "type": "llm"}},
+	})
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"index":1`) {
+		t.Fatalf("expected 409 at index 1, got %d %s", w.Code, w.Body.String())
+	}
+	if _, ok := agentStore.m["writer"]; ok {
+		t.Fatalf("atomic batch must not register writer")
+	}
+}
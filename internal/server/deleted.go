@@ -0,0 +1,127 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/acb/internal/constants"
+	"github.com/gin-gonic/gin"
+)
+
+// Soft delete handlers. Deleted contexts and agents can be restored with
+// POST /contexts/{id}:restore and /agents/{id}:restore until the restore
+// window passes and they are purged; tenant admins list them under /deleted.
+
+// splitAction splits a path segment such as "{id}:restore" into the id and
+// the action, which keeps its leading colon
+func splitAction(segment string) (string, string) {
+	i := strings.LastIndex(segment, ":")
+	if i <= 0 {
+		return segment, ""
+	}
+	return segment[:i], segment[i:]
+}
+
+// deletedLimit parses the limit of a deleted items listing
+func deletedLimit(c *gin.Context) (int, error) {
+	value := c.Query("limit")
+	if value == "" {
+		return constants.DefaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > constants.MaxPageLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", constants.MaxPageLimit)
+	}
+	return limit, nil
+}
+
+// contextAction dispatches POST /contexts/{id}:restore
+func (s *HTTPServer) contextAction(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	contextID, action := splitAction(c.Param("context_id"))
+	switch action {
+	case ":restore":
+		ctx, err := s.contextMgr.RestoreDeleted(c.Request.Context(), contextID)
+		if err != nil {
+			respondContextError(c, err, http.StatusInternalServerError)
+			return
+		}
+		c.Header("ETag", etag(ctx.Revision))
+		c.JSON(http.StatusOK, gin.H{"context": ctx})
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown context action"})
+	}
+}
+
+func (s *HTTPServer) listDeletedContexts(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	limit, err := deletedLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contexts, err := s.contextMgr.ListDeleted(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"contexts":       contexts,
+		"count":          len(contexts),
+		"restore_window": int(s.contextMgr.RestoreWindow().Seconds()),
+	})
+}
+
+// agentAction dispatches POST /agents/{id}:restore
+func (s *HTTPServer) agentAction(c *gin.Context) {
+	if s.registrySvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "registry service unavailable"})
+		return
+	}
+	agentID, action := splitAction(c.Param("agent_id"))
+	switch action {
+	case ":restore":
+		agent, err := s.registrySvc.Restore(c.Request.Context(), agentID)
+		if err != nil {
+			respondError(c, err, http.StatusInternalServerError)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"agent": agent})
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown agent action"})
+	}
+}
+
+func (s *HTTPServer) listDeletedAgents(c *gin.Context) {
+	if s.registrySvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "registry service unavailable"})
+		return
+	}
+	limit, err := deletedLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agents, err := s.registrySvc.ListDeleted(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"agents":         agents,
+		"count":          len(agents),
+		"restore_window": int(s.registrySvc.RestoreWindow().Seconds()),
+	})
+}
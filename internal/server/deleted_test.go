@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/registry"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSoftDeleteHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}),
+		ctxmgr.NewManager(&inMemoryContextStore{}), jwtManager, auth.NewRBAC())
+
+	adminToken, err := jwtManager.GenerateAccessToken("admin-1", "default", []string{"admin"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	do := func(method, path, authorization string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", authorization)
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+	agent := authHeaderFor(t, jwtManager, "agent-1")
+	admin := "Bearer " + adminToken
+
+	w := do("POST", "/api/v1/contexts", agent, map[string]any{
+		"type": "note", "payload": []byte(`{}`), "access_control": map[string]any{"scope": "private"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Context struct {
+			ID string `json:"id"`
+		} `json:"context"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	id := created.Context.ID
+
+	// Deleted contexts disappear from reads until restored
+	if w := do("DELETE", "/api/v1/contexts/"+id, agent, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/api/v1/contexts/"+id, agent, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("get deleted expected 404, got %d", w.Code)
+	}
+	if w := do("GET", "/api/v1/contexts/deleted", agent, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("agent listing expected 403, got %d", w.Code)
+	}
+	if w := do("GET", "/api/v1/contexts/deleted?limit=0", admin, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid limit expected 400, got %d", w.Code)
+	}
+	w = do("GET", "/api/v1/contexts/deleted", admin, nil)
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"count":1`)) || !bytes.Contains(w.Body.Bytes(), []byte(`"restore_window":604800`)) {
+		t.Fatalf("list deleted: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := do("POST", "/api/v1/contexts/"+id+":undelete", agent, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown action expected 404, got %d", w.Code)
+	}
+	w = do("POST", "/api/v1/contexts/"+id+":restore", agent, nil)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == "" {
+		t.Fatalf("restore: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/api/v1/contexts/"+id, agent, nil); w.Code != http.StatusOK {
+		t.Fatalf("get restored: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("POST", "/api/v1/contexts/"+id+":restore", agent, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("restoring a live context expected 404, got %d", w.Code)
+	}
+
+	// Agents are restored by themselves or an admin
+	if w := do("POST", "/api/v1/agents", agent, map[string]any{"id": "agent-2", "type": "ml"}); w.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("DELETE", "/api/v1/agents/agent-2", agent, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("unregister: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/api/v1/agents/agent-2", agent, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("get deleted agent expected 404, got %d", w.Code)
+	}
+	if w := do("POST", "/api/v1/agents/agent-2:restore", agent, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("restore by another agent expected 403, got %d", w.Code)
+	}
+	w = do("GET", "/api/v1/agents/deleted", admin, nil)
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"count":1`)) {
+		t.Fatalf("list deleted agents: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("POST", "/api/v1/agents/agent-2:restore", admin, nil); w.Code != http.StatusOK {
+		t.Fatalf("restore agent: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/api/v1/agents/agent-2", agent, nil); w.Code != http.StatusOK {
+		t.Fatalf("get restored agent: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/selector"
+	"github.com/gin-gonic/gin"
+)
+
+// Embedding and similarity search handlers
+
+func (s *HTTPServer) searchSimilarContexts(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	var req struct {
+		Type     string                  `json:"type" binding:"required"`
+		Vector   []float32               `json:"vector" binding:"required"`
+		TopK     int                     `json:"top_k"`
+		Metric   models.SimilarityMetric `json:"metric"`
+		Exact    bool                    `json:"exact"`
+		AgentID  string                  `json:"agent_id"`
+		Selector string                  `json:"selector"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sel, err := selector.Parse(req.Selector)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	matches, err := s.contextMgr.SearchSimilar(c.Request.Context(), &context.SimilarityRequest{
+		Type:     req.Type,
+		Vector:   req.Vector,
+		TopK:     req.TopK,
+		Metric:   req.Metric,
+		Exact:    req.Exact,
+		AgentID:  req.AgentID,
+		Selector: sel,
+	})
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"contexts": matches,
+		"total":    len(matches),
+	})
+}
+
+func (s *HTTPServer) getEmbeddingConfig(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	cfg, err := s.contextMgr.EmbeddingConfig(c.Request.Context(), c.Param("context_type"))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"embedding": cfg})
+}
+
+func (s *HTTPServer) setEmbeddingConfig(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	var req struct {
+		Dimension int                     `json:"dimension" binding:"required"`
+		Metric    models.SimilarityMetric `json:"metric"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg := &models.EmbeddingConfig{
+		ContextType: c.Param("context_type"),
+		Dimension:   req.Dimension,
+		Metric:      req.Metric,
+	}
+	if err := s.contextMgr.SetEmbeddingConfig(c.Request.Context(), cfg); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"embedding": cfg})
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/acb/internal/storage"
+	"github.com/acb/internal/vector"
+	"github.com/gin-gonic/gin"
+)
+
+// inMemoryEmbeddingStore serves the embeddings of the contexts in an
+// inMemoryContextStore
+type inMemoryEmbeddingStore struct {
+	contexts *inMemoryContextStore
+	configs  map[string]*models.EmbeddingConfig
+}
+
+func (s *inMemoryEmbeddingStore) GetConfig(ctx context.Context, tenantID, contextType string) (*models.EmbeddingConfig, error) {
+	if cfg, ok := s.configs[tenantID+"/"+contextType]; ok {
+		return cfg, nil
+	}
+	return nil, fmt.Errorf("no embedding configuration: %w", storage.ErrNotFound)
+}
+func (s *inMemoryEmbeddingStore) SetConfig(ctx context.Context, cfg *models.EmbeddingConfig) error {
+	if s.configs == nil {
+		s.configs = map[string]*models.EmbeddingConfig{}
+	}
+	s.configs[cfg.TenantID+"/"+cfg.ContextType] = cfg
+	return nil
+}
+func (s *inMemoryEmbeddingStore) List(ctx context.Context, f *storage.ContextFilters) ([]*models.Embedding, error) {
+	var res []*models.Embedding
+	for _, c := range s.contexts.m {
+		if c.Embedding != nil && c.Type == f.Type && (f.IDs == nil || slices.Contains(f.IDs, c.ID)) {
+			res = append(res, &models.Embedding{ContextID: c.ID, Vector: c.Embedding})
+		}
+	}
+	return res, nil
+}
+
+func TestEmbeddingHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	contextStore := &inMemoryContextStore{}
+	embeddings := &inMemoryEmbeddingStore{contexts: contextStore}
+	index := ctxmgr.NewEmbeddingIndex(contextStore, embeddings, vector.DefaultHNSWConfig())
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}),
+		ctxmgr.NewManager(contextStore, ctxmgr.WithEmbeddings(embeddings, index)), jwtManager, auth.NewRBAC())
+
+	adminToken, err := jwtManager.GenerateAccessToken("admin-1", "default", []string{"admin"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	do := func(method, path, authorization string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", authorization)
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+	agent := authHeaderFor(t, jwtManager, "agent-1")
+	admin := "Bearer " + adminToken
+
+	// Only admins declare the embeddings of a type
+	if w := do("PUT", "/api/v1/embeddings/memory", agent, map[string]any{"dimension": 2}); w.Code != http.StatusForbidden {
+		t.Fatalf("agent config expected 403, got %d", w.Code)
+	}
+	if w := do("PUT", "/api/v1/embeddings/memory", admin, map[string]any{"dimension": 2, "metric": "cosine"}); w.Code != http.StatusOK {
+		t.Fatalf("config: %d %s", w.Code, w.Body.String())
+	}
+	w := do("GET", "/api/v1/embeddings/memory", agent, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get config: %d %s", w.Code, w.Body.String())
+	}
+	var cfg struct {
+		Embedding models.EmbeddingConfig `json:"embedding"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &cfg)
+	if cfg.Embedding.Dimension != 2 || cfg.Embedding.Metric != models.MetricCosine {
+		t.Fatalf("unexpected config %s", w.Body.String())
+	}
+	if w := do("GET", "/api/v1/embeddings/notes", agent, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("undeclared type expected 404, got %d", w.Code)
+	}
+
+	for id, embedding := range map[string][]float32{"east": {1, 0}, "north": {0, 1}, "north-east": {1, 1}} {
+		body := map[string]any{"type": "memory", "payload": []byte(id), "access_control": map[string]any{"scope": "public"}, "embedding": embedding}
+		if w := do("POST", "/api/v1/contexts", agent, body); w.Code != http.StatusCreated {
+			t.Fatalf("create %s: %d %s", id, w.Code, w.Body.String())
+		}
+	}
+	body := map[string]any{"type": "memory", "payload": []byte("x"), "access_control": map[string]any{"scope": "public"}, "embedding": []float32{1, 0, 0}}
+	if w := do("POST", "/api/v1/contexts", agent, body); w.Code != http.StatusBadRequest {
+		t.Fatalf("wrong dimension expected 400, got %d", w.Code)
+	}
+
+	w = do("POST", "/api/v1/contexts/search", agent, map[string]any{"type": "memory", "vector": []float32{1, 0.1}, "top_k": 2})
+	if w.Code != http.StatusOK {
+		t.Fatalf("search: %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Contexts []struct {
+			Payload   []byte    `json:"payload"`
+			Score     float64   `json:"score"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"contexts"`
+		Total int `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 2 || string(resp.Contexts[0].Payload) != "east" || string(resp.Contexts[1].Payload) != "north-east" {
+		t.Fatalf("unexpected matches %s", w.Body.String())
+	}
+	if resp.Contexts[0].Score <= resp.Contexts[1].Score || len(resp.Contexts[0].Embedding) != 2 {
+		t.Fatalf("unexpected scores or embedding %+v", resp.Contexts)
+	}
+
+	for name, search := range map[string]map[string]any{
+		"missing vector":   {"type": "memory"},
+		"wrong dimension":  {"type": "memory", "vector": []float32{1}},
+		"bad selector":     {"type": "memory", "vector": []float32{1, 0}, "selector": "env in ("},
+		"undeclared type":  {"type": "notes", "vector": []float32{1, 0}},
+		"top_k over limit": {"type": "memory", "vector": []float32{1, 0}, "top_k": 1000},
+	} {
+		if w := do("POST", "/api/v1/contexts/search", agent, search); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s expected 400, got %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	ctxmgr "github.com/acb/internal/context"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/schema"
+	"github.com/gin-gonic/gin"
+)
+
+// statusForError maps ACB error codes to HTTP status codes, using fallback
+// for errors that carry no code
+func statusForError(err error, fallback int) int {
+	var acbErr *acberrors.ACBError
+	if !errors.As(err, &acbErr) {
+		return fallback
+	}
+
+	switch acbErr.Code {
+	case acberrors.ErrorCodeUnauthorized:
+		return http.StatusUnauthorized
+	case acberrors.ErrorCodeForbidden:
+		return http.StatusForbidden
+	case acberrors.ErrorCodeNotFound:
+		return http.StatusNotFound
+	case acberrors.ErrorCodeConflict:
+		return http.StatusConflict
+	case acberrors.ErrorCodePreconditionFailed:
+		return http.StatusPreconditionFailed
+	case acberrors.ErrorCodeValidationError:
+		return http.StatusBadRequest
+	case acberrors.ErrorCodeRateLimitExceeded:
+		return http.StatusTooManyRequests
+	case acberrors.ErrorCodeServiceUnavailable:
+		return http.StatusServiceUnavailable
+	case acberrors.ErrorCodeInternalError:
+		return http.StatusInternalServerError
+	default:
+		return fallback
+	}
+}
+
+// respondError writes err as a JSON error response. Schema validation
+// failures also list every violation with the payload path it applies to,
+// incompatible schema versions include the breaking-change report,
+// lifecycle policy failures list every limit the context breaks and writes
+// to a context leased to another agent name the holder.
+func respondError(c *gin.Context, err error, fallback int) {
+	c.JSON(statusForError(err, fallback), errorBody(err))
+}
+
+// errorBody builds the JSON body respondError writes for err
+func errorBody(err error) gin.H {
+	body := gin.H{"error": errorMessage(err)}
+	var invalid *schema.ValidationError
+	if errors.As(err, &invalid) {
+		body["schema_id"] = invalid.SchemaID
+		body["violations"] = invalid.Violations
+	}
+	var incompatible *schema.IncompatibleError
+	if errors.As(err, &incompatible) {
+		body["compatibility"] = incompatible.Report.Compatibility
+		body["latest_version"] = incompatible.Report.LatestVersion
+		body["breaking_changes"] = incompatible.Report.BreakingChanges
+	}
+	var policy *ctxmgr.PolicyError
+	if errors.As(err, &policy) {
+		body["policy"] = policy.ContextType
+		body["policy_violations"] = policy.Violations
+	}
+	var leased *ctxmgr.LeaseError
+	if errors.As(err, &leased) {
+		body["holder_id"] = leased.Lease.HolderID
+		body["lease_expires_at"] = leased.Lease.ExpiresAt
+	}
+	return body
+}
+
+// errorMessage returns the message reported to clients for err: that of its
+// ACB error, if any
+func errorMessage(err error) string {
+	var acbErr *acberrors.ACBError
+	if errors.As(err, &acbErr) {
+		return acbErr.Message
+	}
+	return err.Error()
+}
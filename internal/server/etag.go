@@ -0,0 +1,52 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	contextmgr "github.com/acb/internal/context"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// etag renders a context revision as a strong entity tag
+func etag(revision int) string {
+	return `"` + strconv.Itoa(revision) + `"`
+}
+
+// parseIfMatch returns the revision required by an If-Match header value.
+// An absent header or "*" imposes no revision and yields zero.
+func parseIfMatch(header string) (int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.HasPrefix(header, "W/") {
+		return 0, fmt.Errorf("If-Match requires a strong entity tag")
+	}
+	if strings.Contains(header, ",") {
+		return 0, fmt.Errorf("If-Match must name a single entity tag")
+	}
+
+	revision, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || revision < 1 {
+		return 0, fmt.Errorf("invalid If-Match entity tag %s", header)
+	}
+	return revision, nil
+}
+
+// respondContextError writes err like respondError, adding the current ETag
+// when a conditional write lost to a newer revision. Any If-Match fails on a
+// context that does not exist, so that is reported as a failed precondition.
+func respondContextError(c *gin.Context, err error, fallback int) {
+	if c.GetHeader("If-Match") != "" && acberrors.Is(err, acberrors.ErrorCodeNotFound) {
+		err = acberrors.PreconditionFailed("If-Match failed: context does not exist").WithError(err)
+	}
+	var conflict *contextmgr.ConflictError
+	if errors.As(err, &conflict) && conflict.CurrentRevision > 0 {
+		c.Header("ETag", etag(conflict.CurrentRevision))
+	}
+	respondError(c, err, fallback)
+}
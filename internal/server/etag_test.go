@@ -0,0 +1,33 @@
+package server
+
+import "testing"
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"*", 0, false},
+		{`"3"`, 3, false},
+		{" 12 ", 12, false},
+		{`W/"3"`, 0, true},
+		{`"1", "2"`, 0, true},
+		{`"0"`, 0, true},
+		{`"abc"`, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseIfMatch(tt.header)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseIfMatch(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Fatalf("parseIfMatch(%q) = %d, want %d", tt.header, got, tt.want)
+		}
+	}
+
+	if got := etag(7); got != `"7"` {
+		t.Fatalf("etag(7) = %s", got)
+	}
+}
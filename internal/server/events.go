@@ -0,0 +1,211 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acb/internal/constants"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/selector"
+	"github.com/acb/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Context event log handlers
+
+// eventStreamRetry is the reconnection delay suggested to stream clients
+const eventStreamRetry = 3 * time.Second
+
+// listContextEvents returns logged context events after the ID given in
+// "after". Consumers poll with the returned next_after to follow the log,
+// or ask for text/event-stream to have events pushed as they are logged.
+func (s *HTTPServer) listContextEvents(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	filters, err := eventFiltersFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		s.streamContextEvents(c, filters)
+		return
+	}
+
+	events, err := s.contextMgr.ListEvents(c.Request.Context(), filters)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	next := filters.AfterID
+	if len(events) > 0 {
+		next = events[len(events)-1].ID
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"events":     events,
+		"next_after": next,
+	})
+}
+
+// streamContextEvents serves the event log as Server-Sent Events. Each event
+// carries its log ID, so a client that reconnects with Last-Event-ID (or
+// "after") resumes where it left off as long as the events are retained.
+// Without either the stream starts at the head of the log, whose position is
+// sent first. Streams read the log whenever the event hub signals a write on
+// any replica for the caller's tenant, and on a poll interval in case a
+// signal is lost.
+func (s *HTTPServer) streamContextEvents(c *gin.Context, filters *storage.EventFilters) {
+	ctx := c.Request.Context()
+
+	resumed := c.Query("after") != ""
+	if value := c.GetHeader("Last-Event-ID"); value != "" {
+		after, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || after < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID must be a non-negative event ID"})
+			return
+		}
+		filters.AfterID = after
+		resumed = true
+	}
+
+	first, last, err := s.contextMgr.EventBounds(ctx)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	if !resumed {
+		filters.AfterID = last
+	}
+
+	var wake <-chan struct{}
+	if s.eventHub != nil {
+		ch, cancel := s.eventHub.Subscribe(c.GetString("tenant_id"), "")
+		defer cancel()
+		wake = ch
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // Stop reverse proxies from buffering the stream
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n", eventStreamRetry.Milliseconds())
+	if !resumed && filters.AfterID > 0 {
+		// An ID without data sets the client's resume position without
+		// dispatching, so a reconnect before the first event misses nothing
+		fmt.Fprintf(c.Writer, "id: %d\n", filters.AfterID)
+	}
+	fmt.Fprint(c.Writer, "\n")
+	if resumed && first > filters.AfterID+1 {
+		// Events after the client's position were pruned; it must resync
+		writeStreamEvent(c, 0, "reset", gin.H{"oldest_id": first, "after": filters.AfterID})
+	}
+	c.Writer.Flush()
+
+	poll := time.NewTicker(time.Duration(constants.ContextStreamPollInterval) * time.Second)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(time.Duration(constants.ContextStreamHeartbeat) * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		// Drain everything logged since the last read before waiting again
+		for {
+			events, err := s.contextMgr.ListEvents(ctx, filters)
+			if err != nil {
+				if ctx.Err() == nil {
+					writeStreamEvent(c, 0, "error", gin.H{"error": err.Error()})
+					c.Writer.Flush()
+				}
+				return
+			}
+			for _, e := range events {
+				writeStreamEvent(c, e.ID, string(e.Type), e)
+				filters.AfterID = e.ID
+			}
+			c.Writer.Flush()
+			if len(events) < filters.Limit {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.streams.Done():
+			return
+		case <-wake:
+		case <-poll.C:
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeStreamEvent writes one Server-Sent Event. An ID of 0 is omitted so
+// control events do not move the client's resume position.
+func writeStreamEvent(c *gin.Context, id int64, event string, data any) {
+	body, _ := json.Marshal(data)
+	if id > 0 {
+		fmt.Fprintf(c.Writer, "id: %d\n", id)
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, body)
+}
+
+// eventFiltersFromQuery parses the event log query parameters shared by
+// polling and streaming consumers. "type" filters by event type and
+// "context_type" by the type of the context.
+func eventFiltersFromQuery(c *gin.Context) (*storage.EventFilters, error) {
+	if _, ok := c.GetQuery("event"); ok {
+		return nil, fmt.Errorf("event is not a filter; use type for event types and context_type for context types")
+	}
+	filters := &storage.EventFilters{
+		ContextType: c.Query("context_type"),
+		AgentID:     c.Query("agent_id"),
+		Limit:       constants.DefaultPageLimit,
+	}
+
+	if value := c.Query("after"); value != "" {
+		after, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || after < 0 {
+			return nil, fmt.Errorf("after must be a non-negative event ID")
+		}
+		filters.AfterID = after
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > constants.MaxPageLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", constants.MaxPageLimit)
+		}
+		filters.Limit = limit
+	}
+	for _, value := range c.QueryArray("type") {
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t == "" {
+				continue
+			}
+			eventType := models.ContextEventType(t)
+			if err := eventType.Validate(); err != nil {
+				return nil, err
+			}
+			filters.Types = append(filters.Types, eventType)
+		}
+	}
+
+	sel, err := selector.Parse(c.Query("selector"))
+	if err != nil {
+		return nil, err
+	}
+	filters.Selector = sel
+	return filters, nil
+}
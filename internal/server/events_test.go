@@ -0,0 +1,275 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/gin-gonic/gin"
+)
+
+func TestContextEvents_Expiry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	contextStore := &inMemoryContextStore{}
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}), ctxmgr.NewManager(contextStore), jwtManager, auth.NewRBAC())
+
+	do := func(method, path, agentID string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", authHeaderFor(t, jwtManager, agentID))
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(scope string) string {
+		w := do("POST", "/api/v1/contexts", "agent-1", map[string]any{
+			"type":           "task",
+			"payload":        []byte(`{}`),
+			"access_control": map[string]any{"scope": scope},
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create: %d %s", w.Code, w.Body.String())
+		}
+		var created struct {
+			Context models.Context `json:"context"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &created)
+		return created.Context.ID
+	}
+
+	public, private := create("public"), create("private")
+	for _, id := range []string{public, private} {
+		contextStore.m[id].ExpiresAt = time.Now().Add(-time.Second)
+	}
+
+	// Expired contexts are gone for readers before the janitor runs
+	if w := do("GET", "/api/v1/contexts/"+public, "agent-1", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expired context expected 404, got %d", w.Code)
+	}
+
+	janitor, err := ctxmgr.NewJanitor(contextStore, nil, ctxmgr.DefaultJanitorConfig())
+	if err != nil {
+		t.Fatalf("janitor: %v", err)
+	}
+	if result, err := janitor.Sweep(context.Background()); err != nil || result.Deleted != 2 {
+		t.Fatalf("sweep: %+v, %v", result, err)
+	}
+
+	type page struct {
+		Events    []models.ContextEvent `json:"events"`
+		NextAfter int64                 `json:"next_after"`
+	}
+	list := func(query, agentID string) page {
+		w := do("GET", "/api/v1/contexts/events"+query, agentID, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list events%s: %d %s", query, w.Code, w.Body.String())
+		}
+		var p page
+		_ = json.Unmarshal(w.Body.Bytes(), &p)
+		return p
+	}
+
+	owner := list("?type=context.expired", "agent-1")
+	if len(owner.Events) != 2 || owner.NextAfter != owner.Events[1].ID {
+		t.Fatalf("unexpected owner events %+v", owner)
+	}
+	for _, e := range owner.Events {
+		if e.Type != models.ContextEventExpired || e.ContextType != "task" {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	}
+
+	// Other agents only see expiries of contexts they could read
+	other := list("?type=context.expired&context_type=task", "agent-2")
+	if len(other.Events) != 1 || other.Events[0].ContextID != public {
+		t.Fatalf("unexpected events for other agent %+v", other)
+	}
+
+	// Resuming after the last seen ID returns nothing new
+	if resumed := list("?type=context.expired&after="+strconv.FormatInt(owner.NextAfter, 10), "agent-1"); len(resumed.Events) != 0 || resumed.NextAfter != owner.NextAfter {
+		t.Fatalf("unexpected resumed page %+v", resumed)
+	}
+
+	for _, query := range []string{"?after=-1", "?after=x", "?limit=0", "?type=context.renamed", "?type=task", "?event=context.expired", "?selector=env%20in"} {
+		if w := do("GET", "/api/v1/contexts/events"+query, "agent-1", nil); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+type sseFrame struct {
+	id    string
+	event string
+	data  string
+}
+
+// openEventStream connects to the context event stream and delivers its
+// dispatched events until the test ends
+func openEventStream(t *testing.T, baseURL, authHeader, query, lastEventID string) <-chan sseFrame {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/v1/contexts/events"+query, nil)
+	req.Header.Set("Authorization", authHeader)
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("open stream: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	frames := make(chan sseFrame, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(frames)
+		scanner := bufio.NewScanner(resp.Body)
+		var frame sseFrame
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if frame.event != "" {
+					frames <- frame
+				}
+				frame = sseFrame{}
+			case strings.HasPrefix(line, "id: "):
+				frame.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				frame.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				frame.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return frames
+}
+
+func nextFrame(t *testing.T, frames <-chan sseFrame) sseFrame {
+	t.Helper()
+	select {
+	case frame, ok := <-frames:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return frame
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for stream event")
+	}
+	return sseFrame{}
+}
+
+func TestContextEvents_Stream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	contextStore := &inMemoryContextStore{}
+	hub := ctxmgr.NewEventHub()
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}), ctxmgr.NewManager(contextStore, ctxmgr.WithEventHub(hub)), jwtManager, auth.NewRBAC(), WithEventHub(hub))
+	ts := httptest.NewServer(httpSrv.router)
+	t.Cleanup(ts.Close) // Runs after the streams opened below are cancelled
+
+	do := func(method, path string, body any) map[string]any {
+		var buf bytes.Buffer
+		_ = json.NewEncoder(&buf).Encode(body)
+		req, _ := http.NewRequest(method, ts.URL+path, &buf)
+		req.Header.Set("Authorization", authHeaderFor(t, jwtManager, "agent-1"))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		if resp.StatusCode >= 300 {
+			t.Fatalf("%s %s: %d %v", method, path, resp.StatusCode, out)
+		}
+		return out
+	}
+	create := func(scope, env string) string {
+		out := do("POST", "/api/v1/contexts", map[string]any{
+			"type":           "task",
+			"payload":        []byte(`{}`),
+			"metadata":       map[string]string{"env": env},
+			"access_control": map[string]any{"scope": scope},
+		})
+		return out["context"].(map[string]any)["id"].(string)
+	}
+
+	// Streams start at the head of the log: earlier events are not replayed
+	create("public", "prod")
+	query := "?type=context.created,context.updated&selector=env%3Dprod"
+	frames := openEventStream(t, ts.URL, authHeaderFor(t, jwtManager, "agent-2"), query, "")
+
+	create("private", "prod") // Not readable by agent-2
+	create("public", "dev")   // Selector does not match
+	public := create("public", "prod")
+	do("PUT", "/api/v1/contexts/"+public, map[string]any{"payload": []byte(`{"v":2}`)})
+	do("DELETE", "/api/v1/contexts/"+public, nil) // Event type not requested
+
+	created := nextFrame(t, frames)
+	var event models.ContextEvent
+	if err := json.Unmarshal([]byte(created.data), &event); err != nil {
+		t.Fatalf("decode %q: %v", created.data, err)
+	}
+	if created.event != "context.created" || event.ContextID != public || created.id != strconv.FormatInt(event.ID, 10) {
+		t.Fatalf("unexpected first event %+v", created)
+	}
+	updated := nextFrame(t, frames)
+	if updated.event != "context.updated" {
+		t.Fatalf("unexpected second event %+v", updated)
+	}
+
+	// Reconnecting with Last-Event-ID replays what came after it
+	resumed := openEventStream(t, ts.URL, authHeaderFor(t, jwtManager, "agent-2"), query, created.id)
+	if frame := nextFrame(t, resumed); frame.id != updated.id {
+		t.Fatalf("resumed stream replayed %+v, want %s", frame, updated.id)
+	}
+
+	// Resuming from before the retained log tells the client to resync
+	contextStore.eventsMu.Lock()
+	contextStore.events = contextStore.events[3:]
+	contextStore.eventsMu.Unlock()
+	reset := openEventStream(t, ts.URL, authHeaderFor(t, jwtManager, "agent-2"), query, "1")
+	if frame := nextFrame(t, reset); frame.event != "reset" || frame.id != "" {
+		t.Fatalf("expected reset, got %+v", frame)
+	}
+	if frame := nextFrame(t, reset); frame.id != created.id {
+		t.Fatalf("expected retained events after reset, got %+v", frame)
+	}
+
+	// Bad resume positions are rejected before the stream starts
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/contexts/events", nil)
+	req.Header.Set("Authorization", authHeaderFor(t, jwtManager, "agent-2"))
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Last-Event-ID", "abc")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("bad resume: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad Last-Event-ID expected 400, got %d", resp.StatusCode)
+	}
+}
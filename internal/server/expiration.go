@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *HTTPServer) getExpirationConfig(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	cfg, err := s.contextMgr.ExpirationConfig(c.Request.Context(), c.Param("context_type"))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"expiration": cfg})
+}
+
+func (s *HTTPServer) setExpirationConfig(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	var req struct {
+		Policy      models.ExpirationPolicy `json:"policy"`
+		TTL         int                     `json:"ttl"`          // Seconds
+		MaxLifetime int                     `json:"max_lifetime"` // Seconds
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg := &models.ExpirationConfig{
+		ContextType: c.Param("context_type"),
+		Policy:      req.Policy,
+		TTL:         time.Duration(req.TTL) * time.Second,
+		MaxLifetime: time.Duration(req.MaxLifetime) * time.Second,
+	}
+	if err := s.contextMgr.SetExpirationConfig(c.Request.Context(), cfg); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"expiration": cfg})
+}
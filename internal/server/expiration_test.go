@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/acb/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// inMemoryExpirationStore keeps expiration defaults in a map
+type inMemoryExpirationStore struct {
+	configs map[string]*models.ExpirationConfig
+}
+
+func (s *inMemoryExpirationStore) GetConfig(ctx context.Context, tenantID, contextType string) (*models.ExpirationConfig, error) {
+	if cfg, ok := s.configs[tenantID+"/"+contextType]; ok {
+		return cfg, nil
+	}
+	return nil, fmt.Errorf("no expiration configuration: %w", storage.ErrNotFound)
+}
+func (s *inMemoryExpirationStore) SetConfig(ctx context.Context, cfg *models.ExpirationConfig) error {
+	s.configs[cfg.TenantID+"/"+cfg.ContextType] = cfg
+	return nil
+}
+
+func TestExpirationHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	contextStore := &inMemoryContextStore{}
+	expirations := &inMemoryExpirationStore{configs: map[string]*models.ExpirationConfig{}}
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}),
+		ctxmgr.NewManager(contextStore, ctxmgr.WithExpiration(expirations, nil)), jwtManager, auth.NewRBAC())
+
+	adminToken, err := jwtManager.GenerateAccessToken("admin-1", "default", []string{"admin"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	do := func(method, path, authorization string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", authorization)
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+	agent := authHeaderFor(t, jwtManager, "agent-1")
+	admin := "Bearer " + adminToken
+
+	// Only admins set the defaults of a type, and they must be consistent
+	defaults := map[string]any{"policy": "sliding_on_read", "ttl": 1800, "max_lifetime": 86400}
+	if w := do("PUT", "/api/v1/expirations/session", agent, defaults); w.Code != http.StatusForbidden {
+		t.Fatalf("agent config expected 403, got %d", w.Code)
+	}
+	if w := do("PUT", "/api/v1/expirations/session", admin, map[string]any{"policy": "sliding"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown policy expected 400, got %d", w.Code)
+	}
+	if w := do("PUT", "/api/v1/expirations/session", admin, defaults); w.Code != http.StatusOK {
+		t.Fatalf("config: %d %s", w.Code, w.Body.String())
+	}
+	w := do("GET", "/api/v1/expirations/session", agent, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get config: %d %s", w.Code, w.Body.String())
+	}
+	var cfg struct {
+		Expiration models.ExpirationConfig `json:"expiration"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &cfg)
+	if cfg.Expiration.Policy != models.ExpirationSlidingOnRead || cfg.Expiration.TTL != 30*time.Minute {
+		t.Fatalf("unexpected config %s", w.Body.String())
+	}
+	if w := do("GET", "/api/v1/expirations/notes", agent, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unconfigured type expected 404, got %d", w.Code)
+	}
+
+	// Contexts take the defaults of their type unless they set their own
+	create := func(body map[string]any) *models.Context {
+		t.Helper()
+		body["type"] = "session"
+		body["payload"] = []byte("turns")
+		body["access_control"] = map[string]any{"scope": "public"}
+		w := do("POST", "/api/v1/contexts", agent, body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create: %d %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Context models.Context `json:"context"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return &resp.Context
+	}
+	if c := create(map[string]any{}); c.Expiration != models.ExpirationSlidingOnRead || c.MaxLifetime != 24*time.Hour {
+		t.Fatalf("expected the type defaults, got %s %s", c.Expiration, c.MaxLifetime)
+	}
+	c := create(map[string]any{"expiration": "sliding_on_write", "max_lifetime": 3600})
+	if c.Expiration != models.ExpirationSlidingOnWrite || c.MaxLifetime != time.Hour {
+		t.Fatalf("expected the requested expiration, got %s %s", c.Expiration, c.MaxLifetime)
+	}
+	if w := do("PUT", "/api/v1/contexts/"+c.ID, agent, map[string]any{"expiration": "forever"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown policy expected 400, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do("PUT", "/api/v1/contexts/"+c.ID, agent, map[string]any{"expiration": "fixed"}); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -1,12 +1,16 @@
 package server
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/acb/internal/context"
 	"github.com/acb/internal/models"
 	"github.com/acb/internal/registry"
+	"github.com/acb/internal/selector"
 	"github.com/acb/internal/storage"
 	"github.com/gin-gonic/gin"
 )
@@ -47,12 +51,49 @@ func (s *HTTPServer) listAgents(c *gin.Context) {
 		return
 	}
 	filters := &storage.AgentFilters{
-		Type:     c.Query("type"),
-		Location: c.Query("location"),
-		Status:   models.AgentStatus(c.Query("status")),
-		Limit:    100,
-		Offset:   0,
+		Type:            c.Query("type"),
+		Location:        c.Query("location"),
+		Capabilities:    splitQueryList(c.QueryArray("capability")),
+		CapabilityMatch: storage.CapabilityMatch(c.DefaultQuery("match", string(storage.CapabilityMatchAll))),
+		Status:          models.AgentStatus(c.Query("status")),
+		OrderBy:         storage.AgentOrder(c.DefaultQuery("sort", string(storage.AgentOrderCreated))),
+	}
+	page, err := pageQueryFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch filters.CapabilityMatch {
+	case storage.CapabilityMatchAll, storage.CapabilityMatchAny:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "match must be 'all' or 'any'"})
+		return
+	}
+
+	// Liveness changes with every heartbeat, so that ranking is served as a
+	// single page rather than paged by keyset
+	switch filters.OrderBy {
+	case storage.AgentOrderCreated:
+		filters.Limit = page.limit + 1
+		filters.After = page.after
+	case storage.AgentOrderLiveness:
+		if page.after != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor is only supported with sort=created"})
+			return
+		}
+		filters.Limit = page.limit
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be 'created' or 'liveness'"})
+		return
+	}
+
+	sel, err := selector.Parse(c.Query("selector"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	filters.Selector = sel
 
 	tenantID, _ := c.Get("tenant_id")
 	if tenantIDStr, ok := tenantID.(string); ok {
@@ -66,11 +107,23 @@ func (s *HTTPServer) listAgents(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"agents": agents,
-		"total":  len(agents),
+	agents, next := trimPage(agents, page.limit, func(a *models.Agent) *storage.Cursor {
+		return storage.CursorAt(a.CreatedAt, a.ID)
 	})
+
+	resp := gin.H{"agents": agents}
+	if next != "" {
+		resp["next_cursor"] = next
+	}
+	if page.includeTotal {
+		total, err := s.registrySvc.Count(c.Request.Context(), filters)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		resp["total"] = total
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 func (s *HTTPServer) getAgent(c *gin.Context) {
@@ -112,20 +165,51 @@ func (s *HTTPServer) sendHeartbeat(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"acknowledged": true})
 }
 
+// createContextBody is the JSON body of a context create
+type createContextBody struct {
+	Type          string                  `json:"type" binding:"required"`
+	Payload       []byte                  `json:"payload" binding:"required"`
+	Metadata      map[string]string       `json:"metadata"`
+	Version       string                  `json:"version"`
+	SchemaID      string                  `json:"schema_id"`
+	AccessControl models.AccessControl    `json:"access_control" binding:"required"`
+	TTL           int                     `json:"ttl"`
+	Expiration    models.ExpirationPolicy `json:"expiration"`
+	MaxLifetime   int                     `json:"max_lifetime"` // Seconds
+	Embedding     []float32               `json:"embedding"`
+	DerivedFrom   []models.ContextLink    `json:"derived_from"`
+}
+
+// request builds the create request of a context shared by an agent
+func (b *createContextBody) request(agentID, tenantID string) *context.CreateRequest {
+	req := &context.CreateRequest{
+		Type:          b.Type,
+		AgentID:       agentID,
+		TenantID:      tenantID,
+		Payload:       b.Payload,
+		Metadata:      b.Metadata,
+		Version:       b.Version,
+		SchemaID:      b.SchemaID,
+		AccessControl: b.AccessControl,
+		Expiration:    b.Expiration,
+		Embedding:     b.Embedding,
+		DerivedFrom:   b.DerivedFrom,
+	}
+	if b.TTL > 0 {
+		req.TTL = time.Duration(b.TTL) * time.Second
+	}
+	if b.MaxLifetime > 0 {
+		req.MaxLifetime = time.Duration(b.MaxLifetime) * time.Second
+	}
+	return req
+}
+
 func (s *HTTPServer) createContext(c *gin.Context) {
 	if s.contextMgr == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
 		return
 	}
-	var req struct {
-		Type          string               `json:"type" binding:"required"`
-		Payload       []byte               `json:"payload" binding:"required"`
-		Metadata      map[string]string    `json:"metadata"`
-		Version       string               `json:"version"`
-		AccessControl models.AccessControl `json:"access_control" binding:"required"`
-		TTL           int                  `json:"ttl"`
-	}
-
+	var req createContextBody
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -134,26 +218,13 @@ func (s *HTTPServer) createContext(c *gin.Context) {
 	agentID, _ := c.Get("agent_id")
 	tenantID, _ := c.Get("tenant_id")
 
-	createReq := &context.CreateRequest{
-		Type:          req.Type,
-		AgentID:       agentID.(string),
-		TenantID:      tenantID.(string),
-		Payload:       req.Payload,
-		Metadata:      req.Metadata,
-		Version:       req.Version,
-		AccessControl: req.AccessControl,
-	}
-
-	if req.TTL > 0 {
-		createReq.TTL = time.Duration(req.TTL) * time.Second
-	}
-
-	ctx, err := s.contextMgr.Create(c.Request.Context(), createReq)
+	ctx, err := s.contextMgr.Create(c.Request.Context(), req.request(agentID.(string), tenantID.(string)))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
+	c.Header("ETag", etag(ctx.Revision))
 	c.JSON(http.StatusCreated, gin.H{"context": ctx})
 }
 
@@ -165,9 +236,19 @@ func (s *HTTPServer) listContexts(c *gin.Context) {
 	filters := &storage.ContextFilters{
 		Type:    c.Query("type"),
 		AgentID: c.Query("agent_id"),
-		Limit:   100,
-		Offset:  0,
 	}
+	page, err := pageQueryFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sel, err := selector.Parse(c.Query("selector"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filters.Selector = sel
 
 	tenantID, _ := c.Get("tenant_id")
 	if tenantIDStr, ok := tenantID.(string); ok {
@@ -176,16 +257,50 @@ func (s *HTTPServer) listContexts(c *gin.Context) {
 		filters.TenantID = "default"
 	}
 
+	// A full-text query returns matches ranked by relevance, each with a
+	// highlighted snippet. Ranked results are not paged by keyset.
+	if query, ok := c.GetQuery("q"); ok {
+		if page.after != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor is not supported with q"})
+			return
+		}
+		filters.Limit = page.limit
+		results, err := s.contextMgr.Search(c.Request.Context(), query, filters)
+		if err != nil {
+			respondError(c, err, http.StatusInternalServerError)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"contexts": results,
+			"total":    len(results),
+		})
+		return
+	}
+
+	filters.Limit = page.limit + 1
+	filters.After = page.after
 	contexts, err := s.contextMgr.List(c.Request.Context(), filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"contexts": contexts,
-		"total":    len(contexts),
+	contexts, next := trimPage(contexts, page.limit, func(ctx *models.Context) *storage.Cursor {
+		return storage.CursorAt(ctx.CreatedAt, ctx.ID)
 	})
+
+	resp := gin.H{"contexts": contexts}
+	if next != "" {
+		resp["next_cursor"] = next
+	}
+	if page.includeTotal {
+		total, err := s.contextMgr.Count(c.Request.Context(), filters)
+		if err != nil {
+			respondError(c, err, http.StatusInternalServerError)
+			return
+		}
+		resp["total"] = total
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 func (s *HTTPServer) getContext(c *gin.Context) {
@@ -194,12 +309,29 @@ func (s *HTTPServer) getContext(c *gin.Context) {
 		return
 	}
 	contextID := c.Param("context_id")
-	ctx, err := s.contextMgr.Get(c.Request.Context(), contextID)
+	if c.Query("wait_for_version") != "" {
+		s.waitForContext(c, contextID)
+		return
+	}
+
+	var ctx *models.Context
+	var err error
+	if value := c.Query("revision"); value != "" {
+		revision, perr := parseRevision(value)
+		if perr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": perr.Error()})
+			return
+		}
+		ctx, err = s.contextMgr.GetRevision(c.Request.Context(), contextID, revision)
+	} else {
+		ctx, err = s.contextMgr.Get(c.Request.Context(), contextID)
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "context not found"})
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
+	c.Header("ETag", etag(ctx.Revision))
 	c.JSON(http.StatusOK, gin.H{"context": ctx})
 }
 
@@ -210,11 +342,15 @@ func (s *HTTPServer) updateContext(c *gin.Context) {
 	}
 	contextID := c.Param("context_id")
 	var req struct {
-		Payload       []byte               `json:"payload"`
-		Metadata      map[string]string    `json:"metadata"`
-		Version       string               `json:"version"`
-		AccessControl models.AccessControl `json:"access_control"`
-		TTL           int                  `json:"ttl"`
+		Payload       []byte                  `json:"payload"`
+		Metadata      map[string]string       `json:"metadata"`
+		Version       string                  `json:"version"`
+		SchemaID      string                  `json:"schema_id"`
+		AccessControl models.AccessControl    `json:"access_control"`
+		TTL           int                     `json:"ttl"`
+		Expiration    models.ExpirationPolicy `json:"expiration"`
+		Embedding     []float32               `json:"embedding"`
+		DerivedFrom   []models.ContextLink    `json:"derived_from"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -222,11 +358,28 @@ func (s *HTTPServer) updateContext(c *gin.Context) {
 		return
 	}
 
+	expected, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	markStale, err := parseMarkStale(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	updateReq := &context.UpdateRequest{
-		Payload:       req.Payload,
-		Metadata:      req.Metadata,
-		Version:       req.Version,
-		AccessControl: req.AccessControl,
+		Payload:              req.Payload,
+		Metadata:             req.Metadata,
+		Version:              req.Version,
+		SchemaID:             req.SchemaID,
+		AccessControl:        req.AccessControl,
+		Expiration:           req.Expiration,
+		Embedding:            req.Embedding,
+		DerivedFrom:          req.DerivedFrom,
+		ExpectedRevision:     expected,
+		MarkDescendantsStale: markStale,
 	}
 
 	if req.TTL > 0 {
@@ -234,11 +387,70 @@ func (s *HTTPServer) updateContext(c *gin.Context) {
 	}
 
 	ctx, err := s.contextMgr.Update(c.Request.Context(), contextID, updateReq)
+	if err != nil {
+		respondContextError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.Header("ETag", etag(ctx.Revision))
+	c.JSON(http.StatusOK, gin.H{"context": ctx})
+}
+
+// patchContentTypes maps PATCH media types to patch formats
+var patchContentTypes = map[string]context.PatchType{
+	"application/merge-patch+json": context.PatchTypeMerge,
+	"application/json-patch+json":  context.PatchTypeJSON,
+}
+
+func (s *HTTPServer) patchContext(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	patchType, ok := patchContentTypes[c.ContentType()]
+	if !ok {
+		c.Header("Accept-Patch", "application/merge-patch+json, application/json-patch+json")
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "content type must be application/merge-patch+json or application/json-patch+json"})
+		return
+	}
+
+	expected, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	markStale, err := parseMarkStale(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, models.MaxDirectContextSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(body) > models.MaxDirectContextSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "patch document too large"})
+		return
+	}
+
+	ctx, err := s.contextMgr.Patch(c.Request.Context(), c.Param("context_id"), &context.PatchRequest{
+		Type:                 patchType,
+		Patch:                body,
+		ExpectedRevision:     expected,
+		MarkDescendantsStale: markStale,
+	})
+	if errors.Is(err, models.ErrPayloadTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondContextError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("ETag", etag(ctx.Revision))
 	c.JSON(http.StatusOK, gin.H{"context": ctx})
 }
 
@@ -248,10 +460,29 @@ func (s *HTTPServer) deleteContext(c *gin.Context) {
 		return
 	}
 	contextID := c.Param("context_id")
-	if err := s.contextMgr.Delete(c.Request.Context(), contextID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "context not found"})
+	expected, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.contextMgr.DeleteAtRevision(c.Request.Context(), contextID, expected); err != nil {
+		respondContextError(c, err, http.StatusInternalServerError)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// splitQueryList flattens repeated and comma-separated query values
+func splitQueryList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/acb/internal/auth"
 	contextmgr "github.com/acb/internal/context"
 	"github.com/acb/internal/registry"
+	"github.com/acb/internal/schema"
+	"github.com/acb/internal/storage"
+	"github.com/acb/internal/transfer"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,9 +21,56 @@ type HTTPServer struct {
 	router      *gin.Engine
 	registrySvc *registry.Service
 	contextMgr  *contextmgr.Manager
+	schemas     *schema.Registry
+	transfer    *transfer.Service
+	eventHub    *contextmgr.EventHub
+	caches      []namedCache
 	jwtManager  *auth.JWTManager
 	rbac        *auth.RBAC
 	port        string
+
+	// streams is cancelled on shutdown to end open event streams
+	streams     context.Context
+	stopStreams context.CancelFunc
+}
+
+// Option configures optional components of the HTTP server
+type Option func(*HTTPServer)
+
+// WithSchemaRegistry serves the schema registry under /api/v1/schemas
+func WithSchemaRegistry(r *schema.Registry) Option {
+	return func(s *HTTPServer) {
+		s.schemas = r
+	}
+}
+
+// WithTransfer serves tenant export and import under /api/v1/tenant
+func WithTransfer(svc *transfer.Service) Option {
+	return func(s *HTTPServer) {
+		s.transfer = svc
+	}
+}
+
+// WithEventHub wakes context event streams as soon as events are logged
+// rather than on the next poll
+func WithEventHub(hub *contextmgr.EventHub) Option {
+	return func(s *HTTPServer) {
+		s.eventHub = hub
+	}
+}
+
+// namedCache labels the lookup counters of a cached store in /metrics
+type namedCache struct {
+	name  string
+	stats func() storage.CacheStats
+}
+
+// WithCacheStats reports the hit and miss counters of a cached store under
+// the given name in /metrics
+func WithCacheStats(name string, stats func() storage.CacheStats) Option {
+	return func(s *HTTPServer) {
+		s.caches = append(s.caches, namedCache{name: name, stats: stats})
+	}
 }
 
 // NewHTTPServer creates a new HTTP server
@@ -29,6 +80,7 @@ func NewHTTPServer(
 	contextMgr *contextmgr.Manager,
 	jwtManager *auth.JWTManager,
 	rbac *auth.RBAC,
+	opts ...Option,
 ) *HTTPServer {
 	router := gin.Default()
 
@@ -46,6 +98,10 @@ func NewHTTPServer(
 		rbac:        rbac,
 		port:        port,
 	}
+	srv.streams, srv.stopStreams = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(srv)
+	}
 
 	srv.setupRoutes()
 	return srv
@@ -68,24 +124,87 @@ func (s *HTTPServer) setupRoutes() {
 		protected := v1.Group("")
 		protected.Use(auth.AuthMiddleware(s.jwtManager))
 		{
+			// Batch routes: /agents:batchRegister and
+			// /contexts:batchCreate, :batchGet and :batchDelete
+			protected.POST("/agents:action", s.agentBatch)
+			protected.POST("/contexts:action", s.contextBatch)
+
 			// Agent routes
 			agents := protected.Group("/agents")
 			{
 				agents.POST("", s.registerAgent)
 				agents.GET("", s.listAgents)
+				agents.GET("/deleted", s.listDeletedAgents)
 				agents.GET("/:agent_id", s.getAgent)
+				agents.POST("/:agent_id", s.agentAction)
 				agents.DELETE("/:agent_id", s.unregisterAgent)
 				agents.POST("/:agent_id/heartbeat", s.sendHeartbeat)
 			}
 
 			// Context routes
 			contexts := protected.Group("/contexts")
+			contexts.Use(fencingTokenMiddleware())
 			{
 				contexts.POST("", s.createContext)
 				contexts.GET("", s.listContexts)
+				contexts.GET("/events", s.listContextEvents)
+				contexts.POST("/search", s.searchSimilarContexts)
+				contexts.GET("/deleted", s.listDeletedContexts)
 				contexts.GET("/:context_id", s.getContext)
+				contexts.POST("/:context_id", s.contextAction)
 				contexts.PUT("/:context_id", s.updateContext)
+				contexts.PATCH("/:context_id", s.patchContext)
 				contexts.DELETE("/:context_id", s.deleteContext)
+				contexts.GET("/:context_id/revisions", s.listContextRevisions)
+				contexts.POST("/:context_id/revisions/:revision/restore", s.restoreContextRevision)
+				contexts.GET("/:context_id/diff", s.diffContextRevisions)
+				contexts.GET("/:context_id/lineage", s.getContextLineage)
+				contexts.POST("/:context_id/lease", s.acquireContextLease)
+				contexts.GET("/:context_id/lease", s.getContextLease)
+				contexts.PUT("/:context_id/lease", s.renewContextLease)
+				contexts.DELETE("/:context_id/lease", s.releaseContextLease)
+			}
+
+			// Embedding configuration routes
+			embeddings := protected.Group("/embeddings")
+			{
+				embeddings.GET("/:context_type", s.getEmbeddingConfig)
+				embeddings.PUT("/:context_type", s.setEmbeddingConfig)
+			}
+
+			// Expiration defaults routes
+			expirations := protected.Group("/expirations")
+			{
+				expirations.GET("/:context_type", s.getExpirationConfig)
+				expirations.PUT("/:context_type", s.setExpirationConfig)
+			}
+
+			// Lifecycle policy routes (tenant admins change them)
+			policies := protected.Group("/policies")
+			{
+				policies.GET("", s.listLifecyclePolicies)
+				policies.GET("/:context_type", s.getLifecyclePolicy)
+				policies.PUT("/:context_type", s.setLifecyclePolicy)
+				policies.DELETE("/:context_type", s.deleteLifecyclePolicy)
+			}
+
+			// Tenant export and import routes (tenant admins)
+			tenant := protected.Group("/tenant")
+			{
+				tenant.GET("/export", s.exportTenant)
+				tenant.POST("/import", s.importTenant)
+			}
+
+			// Schema registry routes
+			schemas := protected.Group("/schemas")
+			{
+				schemas.POST("", s.registerSchema)
+				schemas.GET("", s.listSchemas)
+				schemas.POST("/check", s.checkSchema)
+				schemas.GET("/compatibility/:context_type", s.getSchemaCompatibility)
+				schemas.PUT("/compatibility/:context_type", s.setSchemaCompatibility)
+				schemas.GET("/:schema_id", s.getSchema)
+				schemas.DELETE("/:schema_id", s.deleteSchema)
 			}
 		}
 	}
@@ -97,6 +216,8 @@ func (s *HTTPServer) Start(ctx context.Context) error {
 		Addr:    ":" + s.port,
 		Handler: s.router,
 	}
+	// Shutdown waits for open requests, so long-lived streams must end first
+	srv.RegisterOnShutdown(s.stopStreams)
 
 	go func() {
 		<-ctx.Done()
@@ -120,15 +241,33 @@ func (s *HTTPServer) healthCheck(c *gin.Context) {
 
 // metrics handles metrics requests
 func (s *HTTPServer) metrics(c *gin.Context) {
-	c.String(http.StatusOK, "# Metrics placeholder\n")
+	var b strings.Builder
+	if len(s.caches) > 0 {
+		b.WriteString("# HELP acb_cache_lookups_total Cache lookups by cache and result.\n")
+		b.WriteString("# TYPE acb_cache_lookups_total counter\n")
+		for _, cache := range s.caches {
+			stats := cache.stats()
+			fmt.Fprintf(&b, "acb_cache_lookups_total{cache=%q,result=\"hit\"} %d\n", cache.name, stats.Hits)
+			fmt.Fprintf(&b, "acb_cache_lookups_total{cache=%q,result=\"negative_hit\"} %d\n", cache.name, stats.NegativeHits)
+			fmt.Fprintf(&b, "acb_cache_lookups_total{cache=%q,result=\"miss\"} %d\n", cache.name, stats.Misses)
+		}
+		b.WriteString("# HELP acb_cache_errors_total Cache reads and writes that failed.\n")
+		b.WriteString("# TYPE acb_cache_errors_total counter\n")
+		for _, cache := range s.caches {
+			fmt.Fprintf(&b, "acb_cache_errors_total{cache=%q} %d\n", cache.name, cache.stats().Errors)
+		}
+	}
+	c.Header("Content-Type", "text/plain; version=0.0.4")
+	c.String(http.StatusOK, b.String())
 }
 
 // Middleware functions
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, Last-Event-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/acb/internal/auth"
+	"github.com/acb/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -48,6 +49,32 @@ func TestHTTPServer_Metrics(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestHTTPServer_MetricsCacheStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	httpSrv := NewHTTPServer(
+		"8080",
+		nil,
+		nil,
+		auth.NewJWTManager("test-secret"),
+		auth.NewRBAC(),
+		WithCacheStats("contexts", func() storage.CacheStats {
+			return storage.CacheStats{Hits: 7, NegativeHits: 2, Misses: 3, Errors: 1}
+		}),
+	)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	httpSrv.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `acb_cache_lookups_total{cache="contexts",result="hit"} 7`)
+	assert.Contains(t, body, `acb_cache_lookups_total{cache="contexts",result="negative_hit"} 2`)
+	assert.Contains(t, body, `acb_cache_lookups_total{cache="contexts",result="miss"} 3`)
+	assert.Contains(t, body, `acb_cache_errors_total{cache="contexts"} 1`)
+}
+
 func TestHTTPServer_Login(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
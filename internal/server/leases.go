@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	contextmgr "github.com/acb/internal/context"
+	"github.com/gin-gonic/gin"
+)
+
+// fencingTokenHeader carries the fencing token a write is made under
+const fencingTokenHeader = "X-Fencing-Token"
+
+// fencingTokenMiddleware attaches the fencing token of the X-Fencing-Token
+// header, if any, to the request context, so that writes made under a lease
+// that has since been lost are rejected
+func fencingTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(fencingTokenHeader)
+		if header == "" {
+			c.Next()
+			return
+		}
+		token, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || token < 1 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid " + fencingTokenHeader + " header " + header})
+			return
+		}
+		c.Request = c.Request.WithContext(contextmgr.WithFencingToken(c.Request.Context(), token))
+		c.Next()
+	}
+}
+
+func (s *HTTPServer) acquireContextLease(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	var req struct {
+		HolderID string `json:"holder_id"` // Defaults to the caller
+		TTL      int    `json:"ttl"`       // Seconds
+	}
+
+	// Every field is optional, so an empty body takes the defaults
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lease, err := s.contextMgr.AcquireLease(c.Request.Context(), c.Param("context_id"), req.HolderID, time.Duration(req.TTL)*time.Second)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"lease": lease})
+}
+
+func (s *HTTPServer) getContextLease(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	lease, err := s.contextMgr.Lease(c.Request.Context(), c.Param("context_id"))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lease": lease})
+}
+
+func (s *HTTPServer) renewContextLease(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	var req struct {
+		FencingToken int64 `json:"fencing_token" binding:"required"`
+		TTL          int   `json:"ttl"` // Seconds
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lease, err := s.contextMgr.RenewLease(c.Request.Context(), c.Param("context_id"), req.FencingToken, time.Duration(req.TTL)*time.Second)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lease": lease})
+}
+
+func (s *HTTPServer) releaseContextLease(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	var token int64
+	if raw := c.Query("fencing_token"); raw != "" {
+		var err error
+		if token, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fencing_token " + raw})
+			return
+		}
+	}
+
+	if err := s.contextMgr.ReleaseLease(c.Request.Context(), c.Param("context_id"), token); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
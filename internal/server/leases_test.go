@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/acb/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// inMemoryLeaseStore keeps leases in a map, drawing tokens from a counter
+type inMemoryLeaseStore struct {
+	leases    map[string]*models.ContextLease
+	lastToken int64
+}
+
+func (s *inMemoryLeaseStore) Acquire(ctx context.Context, l *models.ContextLease) error {
+	if _, err := s.Get(ctx, l.ContextID); err == nil {
+		return fmt.Errorf("already leased: %w", storage.ErrConflict)
+	}
+	s.lastToken++
+	l.FencingToken = s.lastToken
+	l.AcquiredAt = time.Now()
+	l.ExpiresAt = l.AcquiredAt.Add(l.TTL)
+	cp := *l
+	s.leases[l.ContextID] = &cp
+	return nil
+}
+func (s *inMemoryLeaseStore) Get(ctx context.Context, contextID string) (*models.ContextLease, error) {
+	if l, ok := s.leases[contextID]; ok && l.ExpiresAt.After(time.Now()) {
+		cp := *l
+		return &cp, nil
+	}
+	return nil, fmt.Errorf("not leased: %w", storage.ErrNotFound)
+}
+func (s *inMemoryLeaseStore) Renew(ctx context.Context, contextID string, token int64, ttl time.Duration) (*models.ContextLease, error) {
+	l, err := s.Get(ctx, contextID)
+	if err != nil || l.FencingToken != token {
+		return nil, fmt.Errorf("no longer held: %w", storage.ErrNotFound)
+	}
+	l.TTL, l.ExpiresAt = ttl, time.Now().Add(ttl)
+	s.leases[contextID] = l
+	return l, nil
+}
+func (s *inMemoryLeaseStore) Release(ctx context.Context, contextID string, token int64) error {
+	if l, err := s.Get(ctx, contextID); err != nil || l.FencingToken != token {
+		return fmt.Errorf("no longer held: %w", storage.ErrNotFound)
+	}
+	delete(s.leases, contextID)
+	return nil
+}
+
+func TestContextLeaseHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	leases := &inMemoryLeaseStore{leases: map[string]*models.ContextLease{}}
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}),
+		ctxmgr.NewManager(&inMemoryContextStore{}, ctxmgr.WithLeases(leases)), jwtManager, auth.NewRBAC())
+
+	do := func(method, path, authorization, fencingToken string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", authorization)
+		req.Header.Set("Content-Type", "application/json")
+		if fencingToken != "" {
+			req.Header.Set("X-Fencing-Token", fencingToken)
+		}
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+	owner := authHeaderFor(t, jwtManager, "agent-1")
+	worker := authHeaderFor(t, jwtManager, "agent-2")
+
+	w := do("POST", "/api/v1/contexts", owner, "", map[string]any{
+		"type": "task", "payload": []byte("todo"),
+		"access_control": map[string]any{"scope": "group", "allowed_ids": []string{"agent-2"}},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Context models.Context `json:"context"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	base := "/api/v1/contexts/" + created.Context.ID
+
+	// An empty body leases the context to the caller for the default TTL
+	w = do("POST", base+"/lease", worker, "", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("acquire: %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Lease models.ContextLease `json:"lease"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Lease.HolderID != "agent-2" || resp.Lease.TTL != 30*time.Second || resp.Lease.FencingToken == 0 {
+		t.Fatalf("unexpected lease %s", w.Body.String())
+	}
+	token := strconv.FormatInt(resp.Lease.FencingToken, 10)
+
+	// Other agents are told who holds the lease
+	if w := do("POST", base+"/lease", owner, "", map[string]any{"ttl": 60}); w.Code != http.StatusConflict {
+		t.Fatalf("second acquire expected 409, got %d", w.Code)
+	}
+	w = do("PUT", base, owner, "", map[string]any{"payload": []byte("done")})
+	var rejected struct {
+		HolderID string `json:"holder_id"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &rejected)
+	if w.Code != http.StatusConflict || rejected.HolderID != "agent-2" {
+		t.Fatalf("non-holder write expected 409 naming the holder, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do("DELETE", base, owner, "", nil); w.Code != http.StatusConflict {
+		t.Fatalf("non-holder delete expected 409, got %d", w.Code)
+	}
+
+	// The holder writes under its token and renews the lease
+	if w := do("PUT", base, worker, token, map[string]any{"payload": []byte("doing")}); w.Code != http.StatusOK {
+		t.Fatalf("holder write: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("PUT", base, worker, "abc", map[string]any{"payload": []byte("doing")}); w.Code != http.StatusBadRequest {
+		t.Fatalf("malformed token expected 400, got %d", w.Code)
+	}
+	if w := do("PUT", base+"/lease", worker, "", map[string]any{"fencing_token": resp.Lease.FencingToken, "ttl": 120}); w.Code != http.StatusOK {
+		t.Fatalf("renew: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", base+"/lease", owner, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("get lease: %d %s", w.Code, w.Body.String())
+	}
+
+	// After release, writes under the old token are rejected
+	if w := do("DELETE", base+"/lease?fencing_token="+token, worker, "", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("release: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("PUT", base, worker, token, map[string]any{"payload": []byte("late")}); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale token expected 412, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do("PUT", base, owner, "", map[string]any{"payload": []byte("reviewed")}); w.Code != http.StatusOK {
+		t.Fatalf("write after release: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", base+"/lease", owner, "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("released lease expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/acb/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Context lineage handlers
+
+func (s *HTTPServer) getContextLineage(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	direction := models.LineageDirection(c.DefaultQuery("direction", string(models.LineageAncestors)))
+	var depth int
+	if value := c.Query("depth"); value != "" {
+		var err error
+		if depth, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "depth must be an integer"})
+			return
+		}
+	}
+
+	lineage, err := s.contextMgr.Lineage(c.Request.Context(), c.Param("context_id"), direction, depth)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lineage": lineage})
+}
+
+// parseMarkStale parses the mark_stale query parameter of context writes
+func parseMarkStale(c *gin.Context) (bool, error) {
+	value := c.Query("mark_stale")
+	if value == "" {
+		return false, nil
+	}
+	markStale, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("mark_stale must be true or false")
+	}
+	return markStale, nil
+}
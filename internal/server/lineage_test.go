@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/gin-gonic/gin"
+)
+
+func TestContextLineageHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	contextStore := &inMemoryContextStore{}
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}),
+		ctxmgr.NewManager(contextStore), jwtManager, auth.NewRBAC())
+
+	do := func(method, path, contentType string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", authHeaderFor(t, jwtManager, "agent-1"))
+		req.Header.Set("Content-Type", contentType)
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(payload string, parents ...string) string {
+		t.Helper()
+		var links []map[string]string
+		for _, p := range parents {
+			links = append(links, map[string]string{"parent_id": p, "relation": "summarizes"})
+		}
+		body := map[string]any{"type": "note", "payload": []byte(`{"text":"` + payload + `"}`), "access_control": map[string]any{"scope": "public"}, "derived_from": links}
+		w := do("POST", "/api/v1/contexts", "application/json", body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create %s: %d %s", payload, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Context models.Context `json:"context"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Context.ID
+	}
+
+	transcript := create("transcript")
+	summary := create("summary", transcript)
+	plan := create("plan", summary)
+	if w := do("POST", "/api/v1/contexts", "application/json", map[string]any{"type": "note", "payload": []byte(`{}`),
+		"access_control": map[string]any{"scope": "public"}, "derived_from": []map[string]string{{"parent_id": "missing"}}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown parent expected 400, got %d", w.Code)
+	}
+
+	w := do("GET", "/api/v1/contexts/"+plan+"/lineage?depth=5", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("lineage: %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Lineage models.Lineage `json:"lineage"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Lineage.Direction != models.LineageAncestors || len(resp.Lineage.Nodes) != 2 || len(resp.Lineage.Edges) != 2 {
+		t.Fatalf("unexpected lineage %s", w.Body.String())
+	}
+	if resp.Lineage.Nodes[0].ID != summary || resp.Lineage.Nodes[1].ID != transcript || resp.Lineage.Nodes[1].Depth != 2 {
+		t.Fatalf("unexpected nodes %s", w.Body.String())
+	}
+	if resp.Lineage.Edges[0].Relation != "summarizes" {
+		t.Fatalf("unexpected edges %s", w.Body.String())
+	}
+
+	for name, query := range map[string]string{
+		"bad direction": "?direction=sideways",
+		"bad depth":     "?depth=deep",
+		"too deep":      "?depth=100",
+	} {
+		if w := do("GET", "/api/v1/contexts/"+plan+"/lineage"+query, "", nil); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s expected 400, got %d", name, w.Code)
+		}
+	}
+	if w := do("GET", "/api/v1/contexts/missing/lineage", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("missing context expected 404, got %d", w.Code)
+	}
+
+	// Updating a parent with mark_stale flags its descendants
+	if w := do("PUT", "/api/v1/contexts/"+transcript+"?mark_stale=maybe", "application/json", map[string]any{"payload": []byte(`{}`)}); w.Code != http.StatusBadRequest {
+		t.Fatalf("bad mark_stale expected 400, got %d", w.Code)
+	}
+	if w := do("PUT", "/api/v1/contexts/"+transcript+"?mark_stale=true", "application/json", map[string]any{"payload": []byte(`{"text":"v2"}`)}); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	if !contextStore.m[summary].Stale || !contextStore.m[plan].Stale {
+		t.Fatalf("expected descendants to be stale")
+	}
+	if w := do("PATCH", "/api/v1/contexts/"+summary, "application/merge-patch+json", map[string]any{"text": "v2"}); w.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", w.Code, w.Body.String())
+	}
+	if contextStore.m[summary].Stale || !contextStore.m[plan].Stale {
+		t.Fatalf("expected the patched context to be fresh")
+	}
+
+	// A context cannot derive from itself
+	if w := do("PUT", "/api/v1/contexts/"+transcript, "application/json", map[string]any{"derived_from": []map[string]string{{"parent_id": transcript}}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("self link expected 400, got %d", w.Code)
+	}
+}
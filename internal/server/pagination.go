@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/acb/internal/constants"
+	"github.com/acb/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// pageQuery holds the pagination parameters of the agent and context lists
+type pageQuery struct {
+	limit        int
+	after        *storage.Cursor
+	includeTotal bool
+}
+
+// pageQueryFromRequest parses limit, cursor and include_total. Cursors are
+// the opaque next_cursor values of earlier pages.
+func pageQueryFromRequest(c *gin.Context) (*pageQuery, error) {
+	page := &pageQuery{limit: constants.DefaultPageLimit}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > constants.MaxPageLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", constants.MaxPageLimit)
+		}
+		page.limit = limit
+	}
+	if value := c.Query("cursor"); value != "" {
+		after, err := storage.DecodeCursor(value)
+		if err != nil {
+			return nil, fmt.Errorf("cursor is not a next_cursor from a previous page")
+		}
+		page.after = after
+	}
+	if value := c.Query("include_total"); value != "" {
+		includeTotal, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("include_total must be true or false")
+		}
+		page.includeTotal = includeTotal
+	}
+	return page, nil
+}
+
+// trimPage cuts a page fetched with one item beyond limit back to limit. It
+// returns the cursor of the following page, empty when this page is the last.
+func trimPage[T any](items []T, limit int, position func(T) *storage.Cursor) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	return items, position(items[limit-1]).Encode()
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/gin-gonic/gin"
+)
+
+func TestListPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	agentStore := &inMemoryAgentStore{m: map[string]*models.Agent{}}
+	contextStore := &inMemoryContextStore{m: map[string]*models.Context{}}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 5; i++ {
+		id := fmt.Sprintf("item-%d", i)
+		// item-4 and item-5 share a timestamp, so the id breaks the tie
+		at := created.Add(time.Duration(min(i, 4)) * time.Minute)
+		agentStore.m[id] = &models.Agent{ID: id, TenantID: "default", CreatedAt: at}
+		contextStore.m[id] = &models.Context{ID: id, Type: "note", TenantID: "default", AgentID: "agent-1", CreatedAt: at,
+			AccessControl: models.AccessControl{Scope: models.ScopePublic}}
+	}
+	httpSrv := NewHTTPServer("8080", registry.NewService(agentStore), ctxmgr.NewManager(contextStore), jwtManager, auth.NewRBAC())
+	hdr := authHeaderFor(t, jwtManager, "agent-1")
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", hdr)
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+	type page struct {
+		Agents     []models.Agent   `json:"agents"`
+		Contexts   []models.Context `json:"contexts"`
+		NextCursor string           `json:"next_cursor"`
+		Total      *int             `json:"total"`
+	}
+	walk := func(path string) ([]string, []page) {
+		t.Helper()
+		var ids []string
+		var pages []page
+		for cursor := ""; ; {
+			w := get(path + "&cursor=" + cursor)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: %d %s", path, w.Code, w.Body.String())
+			}
+			var p page
+			_ = json.Unmarshal(w.Body.Bytes(), &p)
+			for _, a := range p.Agents {
+				ids = append(ids, a.ID)
+			}
+			for _, c := range p.Contexts {
+				ids = append(ids, c.ID)
+			}
+			pages = append(pages, p)
+			if cursor = p.NextCursor; cursor == "" {
+				return ids, pages
+			}
+		}
+	}
+	want := fmt.Sprint([]string{"item-5", "item-4", "item-3", "item-2", "item-1"})
+
+	for _, path := range []string{"/api/v1/agents?limit=2", "/api/v1/contexts?limit=2"} {
+		ids, pages := walk(path)
+		if fmt.Sprint(ids) != want || len(pages) != 3 {
+			t.Fatalf("%s walked %v in %d pages", path, ids, len(pages))
+		}
+		if pages[0].Total != nil {
+			t.Fatalf("%s reported a total without include_total", path)
+		}
+		_, pages = walk(path + "&include_total=true")
+		if pages[2].Total == nil || *pages[2].Total != 5 {
+			t.Fatalf("%s expected a total of 5", path)
+		}
+	}
+
+	// A page that ends exactly at the last item has no next cursor
+	if ids, pages := walk("/api/v1/contexts?limit=5"); len(ids) != 5 || len(pages) != 1 {
+		t.Fatalf("expected a single page, got %d", len(pages))
+	}
+	if f := contextStore.lastFilters; f.Limit != 6 || f.VisibleTo != "agent-1" {
+		t.Fatalf("unexpected filters %+v", f)
+	}
+
+	for name, path := range map[string]string{
+		"limit too large":    "/api/v1/contexts?limit=1001",
+		"limit zero":         "/api/v1/agents?limit=0",
+		"garbage cursor":     "/api/v1/contexts?cursor=!!!",
+		"bad include_total":  "/api/v1/agents?include_total=maybe",
+		"cursor with search": "/api/v1/contexts?q=invoice&cursor=" + pagesCursor(t, get),
+		"cursor by liveness": "/api/v1/agents?sort=liveness&cursor=" + pagesCursor(t, get),
+	} {
+		if w := get(path); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s expected 400, got %d", name, w.Code)
+		}
+	}
+}
+
+// pagesCursor returns a valid cursor from the first page of contexts
+func pagesCursor(t *testing.T, get func(string) *httptest.ResponseRecorder) string {
+	t.Helper()
+	var p struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	_ = json.Unmarshal(get("/api/v1/contexts?limit=1").Body.Bytes(), &p)
+	if p.NextCursor == "" {
+		t.Fatalf("expected a next cursor")
+	}
+	return p.NextCursor
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *HTTPServer) listLifecyclePolicies(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	policies, err := s.contextMgr.ListLifecyclePolicies(c.Request.Context())
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policies": policies, "count": len(policies)})
+}
+
+func (s *HTTPServer) getLifecyclePolicy(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	policy, err := s.contextMgr.LifecyclePolicy(c.Request.Context(), c.Param("context_type"))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policy": policy})
+}
+
+func (s *HTTPServer) setLifecyclePolicy(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	var req struct {
+		DefaultTTL     int                   `json:"default_ttl"` // Seconds
+		MaxTTL         int                   `json:"max_ttl"`     // Seconds
+		MaxPayloadSize int                   `json:"max_payload_size"`
+		MaxRevisions   int                   `json:"max_revisions"`
+		AllowedScopes  []models.ContextScope `json:"allowed_scopes"`
+		NonOwnerDelete bool                  `json:"non_owner_delete"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy := &models.LifecyclePolicy{
+		ContextType:    c.Param("context_type"),
+		DefaultTTL:     time.Duration(req.DefaultTTL) * time.Second,
+		MaxTTL:         time.Duration(req.MaxTTL) * time.Second,
+		MaxPayloadSize: req.MaxPayloadSize,
+		MaxRevisions:   req.MaxRevisions,
+		AllowedScopes:  req.AllowedScopes,
+		NonOwnerDelete: req.NonOwnerDelete,
+	}
+	if err := s.contextMgr.SetLifecyclePolicy(c.Request.Context(), policy); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policy": policy})
+}
+
+func (s *HTTPServer) deleteLifecyclePolicy(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	if err := s.contextMgr.DeleteLifecyclePolicy(c.Request.Context(), c.Param("context_type")); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
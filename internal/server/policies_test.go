@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/acb/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// inMemoryPolicyStore keeps lifecycle policies in a map
+type inMemoryPolicyStore struct {
+	policies map[string]*models.LifecyclePolicy
+}
+
+func (s *inMemoryPolicyStore) Get(ctx context.Context, tenantID, contextType string) (*models.LifecyclePolicy, error) {
+	if p, ok := s.policies[tenantID+"/"+contextType]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("no lifecycle policy: %w", storage.ErrNotFound)
+}
+func (s *inMemoryPolicyStore) List(ctx context.Context, tenantID string) ([]*models.LifecyclePolicy, error) {
+	policies := []*models.LifecyclePolicy{}
+	for _, p := range s.policies {
+		if p.TenantID == tenantID {
+			policies = append(policies, p)
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ContextType < policies[j].ContextType })
+	return policies, nil
+}
+func (s *inMemoryPolicyStore) Set(ctx context.Context, p *models.LifecyclePolicy) error {
+	s.policies[p.TenantID+"/"+p.ContextType] = p
+	return nil
+}
+func (s *inMemoryPolicyStore) Delete(ctx context.Context, tenantID, contextType string) error {
+	if _, ok := s.policies[tenantID+"/"+contextType]; !ok {
+		return fmt.Errorf("no lifecycle policy: %w", storage.ErrNotFound)
+	}
+	delete(s.policies, tenantID+"/"+contextType)
+	return nil
+}
+
+func TestLifecyclePolicyHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	policies := &inMemoryPolicyStore{policies: map[string]*models.LifecyclePolicy{}}
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}),
+		ctxmgr.NewManager(&inMemoryContextStore{}, ctxmgr.WithLifecyclePolicies(policies)), jwtManager, auth.NewRBAC())
+
+	adminToken, err := jwtManager.GenerateAccessToken("admin-1", "default", []string{"admin"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	do := func(method, path, authorization string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", authorization)
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+	agent := authHeaderFor(t, jwtManager, "agent-1")
+	admin := "Bearer " + adminToken
+
+	// Only admins set the policy of a type, and it must be consistent
+	policy := map[string]any{"default_ttl": 3600, "max_ttl": 7200, "max_payload_size": 16, "allowed_scopes": []string{"private", "group"}}
+	if w := do("PUT", "/api/v1/policies/session", agent, policy); w.Code != http.StatusForbidden {
+		t.Fatalf("agent policy expected 403, got %d", w.Code)
+	}
+	if w := do("PUT", "/api/v1/policies/session", admin, map[string]any{"default_ttl": 7200, "max_ttl": 3600}); w.Code != http.StatusBadRequest {
+		t.Fatalf("inconsistent policy expected 400, got %d", w.Code)
+	}
+	if w := do("PUT", "/api/v1/policies/session", admin, policy); w.Code != http.StatusOK {
+		t.Fatalf("policy: %d %s", w.Code, w.Body.String())
+	}
+	w := do("GET", "/api/v1/policies/session", agent, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get policy: %d %s", w.Code, w.Body.String())
+	}
+	var got struct {
+		Policy models.LifecyclePolicy `json:"policy"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Policy.MaxTTL != 2*time.Hour || len(got.Policy.AllowedScopes) != 2 {
+		t.Fatalf("unexpected policy %s", w.Body.String())
+	}
+	if w := do("GET", "/api/v1/policies", agent, nil); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"count":1`)) {
+		t.Fatalf("list policies: %d %s", w.Code, w.Body.String())
+	}
+
+	// Contexts breaking the policy are rejected with every violation listed
+	w = do("POST", "/api/v1/contexts", agent, map[string]any{
+		"type": "session", "payload": []byte("far more than sixteen bytes"), "ttl": 86400,
+		"access_control": map[string]any{"scope": "public"},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("violating create expected 400, got %d %s", w.Code, w.Body.String())
+	}
+	var rejected struct {
+		Policy     string   `json:"policy"`
+		Violations []string `json:"policy_violations"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &rejected)
+	if rejected.Policy != "session" || len(rejected.Violations) != 3 {
+		t.Fatalf("unexpected rejection %s", w.Body.String())
+	}
+	w = do("POST", "/api/v1/contexts", agent, map[string]any{
+		"type": "session", "payload": []byte("turns"), "access_control": map[string]any{"scope": "private"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Context models.Context `json:"context"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Context.TTL != time.Hour {
+		t.Fatalf("expected the policy default ttl, got %s", created.Context.TTL)
+	}
+
+	if w := do("DELETE", "/api/v1/policies/session", admin, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete policy: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/api/v1/policies/session", agent, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("deleted policy expected 404, got %d", w.Code)
+	}
+}
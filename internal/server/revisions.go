@@ -0,0 +1,87 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Context revision history handlers
+
+func (s *HTTPServer) listContextRevisions(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	revisions, err := s.contextMgr.ListRevisions(c.Request.Context(), c.Param("context_id"))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+		"total":     len(revisions),
+	})
+}
+
+func (s *HTTPServer) restoreContextRevision(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+	revision, err := parseRevision(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, err := s.contextMgr.Restore(c.Request.Context(), c.Param("context_id"), revision)
+	if err != nil {
+		respondContextError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("ETag", etag(ctx.Revision))
+	c.JSON(http.StatusOK, gin.H{"context": ctx})
+}
+
+func (s *HTTPServer) diffContextRevisions(c *gin.Context) {
+	if s.contextMgr == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "context manager unavailable"})
+		return
+	}
+
+	var from, to int
+	for name, dst := range map[string]*int{"from": &from, "to": &to} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		revision, err := parseRevision(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s: %v", name, err)})
+			return
+		}
+		*dst = revision
+	}
+
+	diff, err := s.contextMgr.Diff(c.Request.Context(), c.Param("context_id"), from, to)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"diff": diff})
+}
+
+// parseRevision parses a positive revision number
+func parseRevision(value string) (int, error) {
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 1 {
+		return 0, fmt.Errorf("revision must be a positive integer")
+	}
+	return revision, nil
+}
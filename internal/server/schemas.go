@@ -0,0 +1,171 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/schema"
+	"github.com/acb/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Schema registry handlers
+
+func (s *HTTPServer) registerSchema(c *gin.Context) {
+	if s.schemas == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "schema registry unavailable"})
+		return
+	}
+	var req struct {
+		ContextType string          `json:"context_type" binding:"required"`
+		Schema      json.RawMessage `json:"schema" binding:"required"`
+		Description string          `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sc, err := s.schemas.Register(c.Request.Context(), &schema.RegisterRequest{
+		ContextType: req.ContextType,
+		Document:    req.Schema,
+		Description: req.Description,
+	})
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"schema": sc})
+}
+
+func (s *HTTPServer) listSchemas(c *gin.Context) {
+	if s.schemas == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "schema registry unavailable"})
+		return
+	}
+	filters := &storage.SchemaFilters{
+		ContextType: c.Query("context_type"),
+		Limit:       100,
+	}
+	for name, dst := range map[string]*int{"limit": &filters.Limit, "offset": &filters.Offset} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a non-negative integer"})
+			return
+		}
+		*dst = n
+	}
+
+	schemas, err := s.schemas.List(c.Request.Context(), filters)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schemas": schemas,
+		"total":   len(schemas),
+	})
+}
+
+func (s *HTTPServer) getSchema(c *gin.Context) {
+	if s.schemas == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "schema registry unavailable"})
+		return
+	}
+	sc, err := s.schemas.Get(c.Request.Context(), c.Param("schema_id"))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schema": sc})
+}
+
+func (s *HTTPServer) deleteSchema(c *gin.Context) {
+	if s.schemas == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "schema registry unavailable"})
+		return
+	}
+	if err := s.schemas.Delete(c.Request.Context(), c.Param("schema_id")); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (s *HTTPServer) checkSchema(c *gin.Context) {
+	if s.schemas == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "schema registry unavailable"})
+		return
+	}
+	var req struct {
+		ContextType   string                     `json:"context_type" binding:"required"`
+		Schema        json.RawMessage            `json:"schema" binding:"required"`
+		Compatibility models.SchemaCompatibility `json:"compatibility"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := s.schemas.Check(c.Request.Context(), &schema.CheckRequest{
+		ContextType:   req.ContextType,
+		Document:      req.Schema,
+		Compatibility: req.Compatibility,
+	})
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (s *HTTPServer) getSchemaCompatibility(c *gin.Context) {
+	if s.schemas == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "schema registry unavailable"})
+		return
+	}
+	contextType := c.Param("context_type")
+	mode, err := s.schemas.Compatibility(c.Request.Context(), contextType)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"context_type": contextType, "compatibility": mode})
+}
+
+func (s *HTTPServer) setSchemaCompatibility(c *gin.Context) {
+	if s.schemas == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "schema registry unavailable"})
+		return
+	}
+	var req struct {
+		Compatibility models.SchemaCompatibility `json:"compatibility" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contextType := c.Param("context_type")
+	if err := s.schemas.SetCompatibility(c.Request.Context(), contextType, req.Compatibility); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"context_type": contextType, "compatibility": req.Compatibility})
+}
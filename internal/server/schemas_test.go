@@ -0,0 +1,282 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/acb/internal/schema"
+	"github.com/acb/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+type inMemorySchemaStore struct {
+	m             map[string]*models.Schema
+	compatibility map[string]models.SchemaCompatibility
+	contexts      *inMemoryContextStore // Consulted for the delete guard
+}
+
+func (s *inMemorySchemaStore) Create(ctx context.Context, sc *models.Schema) error {
+	if s.m == nil {
+		s.m = map[string]*models.Schema{}
+	}
+	next := 1
+	if latest, err := s.GetLatest(ctx, sc.TenantID, sc.ContextType); err == nil {
+		next = latest.Version + 1
+	}
+	if sc.Version != 0 && sc.Version != next {
+		return fmt.Errorf("version %d is not the next version: %w", sc.Version, storage.ErrConflict)
+	}
+	sc.Version = next
+	cp := *sc
+	s.m[sc.ID] = &cp
+	return nil
+}
+func (s *inMemorySchemaStore) GetLatest(ctx context.Context, tenantID, contextType string) (*models.Schema, error) {
+	var latest *models.Schema
+	for _, sc := range s.m {
+		if sc.TenantID == tenantID && sc.ContextType == contextType && (latest == nil || sc.Version > latest.Version) {
+			latest = sc
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no schema registered for %s: %w", contextType, storage.ErrNotFound)
+	}
+	cp := *latest
+	return &cp, nil
+}
+func (s *inMemorySchemaStore) GetCompatibility(ctx context.Context, tenantID, contextType string) (models.SchemaCompatibility, error) {
+	return s.compatibility[tenantID+"/"+contextType], nil
+}
+func (s *inMemorySchemaStore) SetCompatibility(ctx context.Context, tenantID, contextType string, mode models.SchemaCompatibility) error {
+	if s.compatibility == nil {
+		s.compatibility = map[string]models.SchemaCompatibility{}
+	}
+	s.compatibility[tenantID+"/"+contextType] = mode
+	return nil
+}
+func (s *inMemorySchemaStore) Get(ctx context.Context, id string) (*models.Schema, error) {
+	if sc, ok := s.m[id]; ok {
+		cp := *sc
+		return &cp, nil
+	}
+	return nil, fmt.Errorf("schema not found: %w", storage.ErrNotFound)
+}
+func (s *inMemorySchemaStore) List(ctx context.Context, f *storage.SchemaFilters) ([]*models.Schema, error) {
+	out := []*models.Schema{}
+	for _, sc := range s.m {
+		if sc.TenantID == f.TenantID && (f.ContextType == "" || sc.ContextType == f.ContextType) {
+			cp := *sc
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+func (s *inMemorySchemaStore) Delete(ctx context.Context, id string) error {
+	if _, ok := s.m[id]; !ok {
+		return fmt.Errorf("schema not found: %w", storage.ErrNotFound)
+	}
+	for _, c := range s.contexts.m {
+		if c.SchemaID == id {
+			return fmt.Errorf("schema %s is referenced by contexts: %w", id, storage.ErrConflict)
+		}
+	}
+	delete(s.m, id)
+	return nil
+}
+
+func TestSchemaHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	contextStore := &inMemoryContextStore{}
+	schemas := schema.NewRegistry(&inMemorySchemaStore{contexts: contextStore})
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}),
+		ctxmgr.NewManager(contextStore, ctxmgr.WithPayloadValidator(schemas)),
+		jwtManager, auth.NewRBAC(), WithSchemaRegistry(schemas))
+
+	do := func(method, path, agentID string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", authHeaderFor(t, jwtManager, agentID))
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/v1/schemas", "agent-1", map[string]any{
+		"context_type": "task",
+		"schema":       json.RawMessage(`{"type":"object","required":["id"],"properties":{"id":{"type":"string"},"priority":{"type":"integer","maximum":5}}}`),
+		"description":  "tasks",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", w.Code, w.Body.String())
+	}
+	var registered struct {
+		Schema models.Schema `json:"schema"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &registered)
+	if registered.Schema.Version != 1 || registered.Schema.ContextType != "task" {
+		t.Fatalf("unexpected schema %+v", registered.Schema)
+	}
+	schemaPath := "/api/v1/schemas/" + registered.Schema.ID
+
+	if w = do("POST", "/api/v1/schemas", "agent-1", map[string]any{"context_type": "task", "schema": map[string]any{"type": "text"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid schema expected 400, got %d", w.Code)
+	}
+	if w = do("GET", schemaPath, "agent-2", nil); w.Code != http.StatusOK {
+		t.Fatalf("get: %d", w.Code)
+	}
+	if w = do("GET", "/api/v1/schemas?context_type=task", "agent-2", nil); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"total":1`)) {
+		t.Fatalf("list: %d %s", w.Code, w.Body.String())
+	}
+
+	// Payloads are validated against the referenced schema
+	newContext := func(payload string) map[string]any {
+		return map[string]any{
+			"type":           "task",
+			"schema_id":      registered.Schema.ID,
+			"payload":        []byte(payload),
+			"access_control": map[string]any{"scope": "public"},
+		}
+	}
+	w = do("POST", "/api/v1/contexts", "agent-1", newContext(`{"priority":7}`))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid payload expected 400, got %d", w.Code)
+	}
+	var invalid struct {
+		SchemaID   string             `json:"schema_id"`
+		Violations []schema.Violation `json:"violations"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &invalid)
+	want := []schema.Violation{{Path: "/id", Message: "required property is missing"}, {Path: "/priority", Message: "must be <= 5"}}
+	if invalid.SchemaID != registered.Schema.ID || fmt.Sprint(invalid.Violations) != fmt.Sprint(want) {
+		t.Fatalf("unexpected validation response %s", w.Body.String())
+	}
+
+	w = do("POST", "/api/v1/contexts", "agent-1", newContext(`{"id":"t1","priority":2}`))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("valid payload: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Context models.Context `json:"context"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if w = do("PUT", "/api/v1/contexts/"+created.Context.ID, "agent-1", map[string]any{"payload": []byte(`{"id":1}`)}); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid update expected 400, got %d", w.Code)
+	}
+
+	// Only the registering agent may delete, and only once unreferenced
+	if w = do("DELETE", schemaPath, "agent-2", nil); w.Code != http.StatusForbidden {
+		t.Fatalf("delete by other agent expected 403, got %d", w.Code)
+	}
+	if w = do("DELETE", schemaPath, "agent-1", nil); w.Code != http.StatusConflict {
+		t.Fatalf("delete while referenced expected 409, got %d", w.Code)
+	}
+	if w = do("DELETE", "/api/v1/contexts/"+created.Context.ID, "agent-1", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete context: %d", w.Code)
+	}
+	if w = do("DELETE", schemaPath, "agent-1", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete schema: %d %s", w.Code, w.Body.String())
+	}
+	if w = do("GET", schemaPath, "agent-1", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("get deleted schema expected 404, got %d", w.Code)
+	}
+}
+
+func TestSchemaCompatibilityHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	contextStore := &inMemoryContextStore{}
+	schemas := schema.NewRegistry(&inMemorySchemaStore{contexts: contextStore})
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}), ctxmgr.NewManager(contextStore),
+		jwtManager, auth.NewRBAC(), WithSchemaRegistry(schemas))
+
+	adminToken, err := jwtManager.GenerateAccessToken("admin-1", "default", []string{"admin"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	do := func(method, path, authorization string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", authorization)
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+	agent := authHeaderFor(t, jwtManager, "agent-1")
+	admin := "Bearer " + adminToken
+
+	v1 := map[string]any{"context_type": "task", "schema": json.RawMessage(`{"type":"object","properties":{"status":{"enum":["open","done","archived"]}}}`)}
+	if w := do("POST", "/api/v1/schemas", agent, v1); w.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", w.Code, w.Body.String())
+	}
+
+	// The dry run reports breaking changes with 200 and stores nothing
+	v2 := map[string]any{"context_type": "task", "schema": json.RawMessage(`{"type":"object","properties":{"status":{"enum":["open","done"]}}}`)}
+	w := do("POST", "/api/v1/schemas/check", agent, v2)
+	if w.Code != http.StatusOK {
+		t.Fatalf("check: %d %s", w.Code, w.Body.String())
+	}
+	var report schema.CompatibilityReport
+	_ = json.Unmarshal(w.Body.Bytes(), &report)
+	if report.Compatible || report.LatestVersion != 1 || report.Compatibility != models.CompatibilityBackward ||
+		len(report.BreakingChanges) != 1 || report.BreakingChanges[0].Location != "#/properties/status" {
+		t.Fatalf("unexpected report %s", w.Body.String())
+	}
+	if w = do("POST", "/api/v1/schemas/check", agent, map[string]any{"context_type": "task", "schema": map[string]any{"type": 1}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("check of invalid schema expected 400, got %d", w.Code)
+	}
+
+	// Registering the breaking version is rejected with the same report
+	w = do("POST", "/api/v1/schemas", agent, v2)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("incompatible register expected 409, got %d %s", w.Code, w.Body.String())
+	}
+	var rejected struct {
+		Compatibility   string                  `json:"compatibility"`
+		LatestVersion   int                     `json:"latest_version"`
+		BreakingChanges []schema.BreakingChange `json:"breaking_changes"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &rejected)
+	if rejected.Compatibility != "backward" || rejected.LatestVersion != 1 || len(rejected.BreakingChanges) != 1 ||
+		rejected.BreakingChanges[0].Kind != schema.ChangeValueRestricted {
+		t.Fatalf("unexpected conflict response %s", w.Body.String())
+	}
+
+	// Admins may relax the mode of the type
+	modePath := "/api/v1/schemas/compatibility/task"
+	if w = do("GET", modePath, agent, nil); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"compatibility":"backward"`)) {
+		t.Fatalf("get mode: %d %s", w.Code, w.Body.String())
+	}
+	if w = do("PUT", modePath, agent, map[string]any{"compatibility": "none"}); w.Code != http.StatusForbidden {
+		t.Fatalf("set mode by agent expected 403, got %d", w.Code)
+	}
+	if w = do("PUT", modePath, admin, map[string]any{"compatibility": "sideways"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown mode expected 400, got %d", w.Code)
+	}
+	if w = do("PUT", modePath, admin, map[string]any{"compatibility": "forward"}); w.Code != http.StatusOK {
+		t.Fatalf("set mode: %d %s", w.Code, w.Body.String())
+	}
+	if w = do("GET", modePath, agent, nil); !bytes.Contains(w.Body.Bytes(), []byte(`"compatibility":"forward"`)) {
+		t.Fatalf("mode not updated: %s", w.Body.String())
+	}
+	if w = do("POST", "/api/v1/schemas", agent, v2); w.Code != http.StatusCreated || !bytes.Contains(w.Body.Bytes(), []byte(`"version":2`)) {
+		t.Fatalf("register under forward mode: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -7,6 +7,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -18,7 +23,11 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-type inMemoryAgentStore struct{ m map[string]*models.Agent }
+type inMemoryAgentStore struct {
+	m           map[string]*models.Agent
+	deleted     map[string]*models.Agent
+	lastFilters *storage.AgentFilters
+}
 
 func (s *inMemoryAgentStore) Create(ctx context.Context, a *models.Agent) error {
 	if s.m == nil {
@@ -31,6 +40,23 @@ func (s *inMemoryAgentStore) Create(ctx context.Context, a *models.Agent) error
 	s.m[a.ID] = &cp
 	return nil
 }
+func (s *inMemoryAgentStore) CreateBatch(ctx context.Context, agents []*models.Agent, atomic bool) ([]error, error) {
+	errs := make([]error, len(agents))
+	for i, a := range agents {
+		if _, ok := s.m[a.ID]; ok {
+			errs[i] = fmt.Errorf("agent with ID %s already exists: %w", a.ID, storage.ErrConflict)
+			if atomic {
+				return nil, &storage.BatchError{Index: i, Err: errs[i]}
+			}
+		}
+	}
+	for i, a := range agents {
+		if errs[i] == nil {
+			_ = s.Create(ctx, a)
+		}
+	}
+	return errs, nil
+}
 func (s *inMemoryAgentStore) Get(ctx context.Context, id string) (*models.Agent, error) {
 	if a, ok := s.m[id]; ok {
 		cp := *a
@@ -47,17 +73,40 @@ func (s *inMemoryAgentStore) Update(ctx context.Context, a *models.Agent) error
 	return nil
 }
 func (s *inMemoryAgentStore) Delete(ctx context.Context, id string) error {
+	a, ok := s.m[id]
+	if !ok {
+		return nil
+	}
+	if s.deleted == nil {
+		s.deleted = map[string]*models.Agent{}
+	}
+	now := time.Now()
+	a.DeletedAt = &now
+	s.deleted[id] = a
 	delete(s.m, id)
 	return nil
 }
 func (s *inMemoryAgentStore) List(ctx context.Context, f *storage.AgentFilters) ([]*models.Agent, error) {
+	s.lastFilters = f
 	res := []*models.Agent{}
 	for _, a := range s.m {
+		if f.After != nil && !listedAfter(a.CreatedAt, a.ID, f.After) {
+			continue
+		}
 		cp := *a
 		res = append(res, &cp)
 	}
+	sort.Slice(res, func(i, j int) bool {
+		return listedAfter(res[j].CreatedAt, res[j].ID, storage.CursorAt(res[i].CreatedAt, res[i].ID))
+	})
+	if f.Limit > 0 && len(res) > f.Limit {
+		res = res[:f.Limit]
+	}
 	return res, nil
 }
+func (s *inMemoryAgentStore) Count(ctx context.Context, f *storage.AgentFilters) (int, error) {
+	return len(s.m), nil
+}
 func (s *inMemoryAgentStore) UpdateLastSeen(ctx context.Context, id string) error {
 	if a, ok := s.m[id]; ok {
 		a.LastSeen = time.Now()
@@ -66,48 +115,384 @@ func (s *inMemoryAgentStore) UpdateLastSeen(ctx context.Context, id string) erro
 	}
 	return fmt.Errorf("agent not found")
 }
+func (s *inMemoryAgentStore) MarkOffline(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
+func (s *inMemoryAgentStore) DeleteOffline(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
+func (s *inMemoryAgentStore) GetDeleted(ctx context.Context, id string) (*models.Agent, error) {
+	if a, ok := s.deleted[id]; ok {
+		cp := *a
+		return &cp, nil
+	}
+	return nil, fmt.Errorf("deleted agent not found: %w", storage.ErrNotFound)
+}
+func (s *inMemoryAgentStore) ListDeleted(ctx context.Context, tenantID string, limit int) ([]*models.Agent, error) {
+	res := []*models.Agent{}
+	for _, a := range s.deleted {
+		if a.TenantID == tenantID {
+			cp := *a
+			res = append(res, &cp)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].DeletedAt.After(*res[j].DeletedAt) })
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+func (s *inMemoryAgentStore) Restore(ctx context.Context, id string) error {
+	a, ok := s.deleted[id]
+	if !ok {
+		return fmt.Errorf("deleted agent not found: %w", storage.ErrNotFound)
+	}
+	a.DeletedAt = nil
+	s.m[id] = a
+	delete(s.deleted, id)
+	return nil
+}
+func (s *inMemoryAgentStore) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	n := 0
+	for id, a := range s.deleted {
+		if a.DeletedAt.Before(before) {
+			delete(s.deleted, id)
+			n++
+		}
+	}
+	return n, nil
+}
 
-type inMemoryContextStore struct{ m map[string]*models.Context }
+// listedAfter reports whether a row comes after cursor in newest-first keyset
+// order, as the Postgres stores page
+func listedAfter(createdAt time.Time, id string, cursor *storage.Cursor) bool {
+	if !createdAt.Equal(cursor.CreatedAt) {
+		return createdAt.Before(cursor.CreatedAt)
+	}
+	return id < cursor.ID
+}
+
+type inMemoryContextStore struct {
+	m           map[string]*models.Context
+	deleted     map[string]*models.Context
+	revisions   map[string][]*models.ContextRevision
+	eventsMu    sync.Mutex // Change streams read the log while tests write
+	events      []*models.ContextEvent
+	eventSeq    int64
+	lastFilters *storage.ContextFilters
+}
+
+func (s *inMemoryContextStore) recordEvent(eventType models.ContextEventType, c *models.Context) *models.ContextEvent {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	s.eventSeq++
+	e := &models.ContextEvent{ID: s.eventSeq, Type: eventType, TenantID: c.TenantID, ContextID: c.ID, ContextType: c.Type,
+		AgentID: c.AgentID, Revision: c.Revision, Metadata: c.Metadata, AccessControl: c.AccessControl, OccurredAt: time.Now()}
+	s.events = append(s.events, e)
+	return e
+}
+
+func (s *inMemoryContextStore) recordRevision(c *models.Context) {
+	if s.revisions == nil {
+		s.revisions = map[string][]*models.ContextRevision{}
+	}
+	c.Revision = len(s.revisions[c.ID]) + 1
+	s.revisions[c.ID] = append(s.revisions[c.ID], &models.ContextRevision{
+		ContextID: c.ID, Revision: c.Revision, Payload: c.Payload, Metadata: c.Metadata,
+		Version: c.Version, AccessControl: c.AccessControl, Checksum: c.Checksum,
+		AuthorID: c.UpdatedBy, CreatedAt: time.Now(),
+	})
+}
 
 func (s *inMemoryContextStore) Create(ctx context.Context, c *models.Context) error {
 	if s.m == nil {
 		s.m = map[string]*models.Context{}
 	}
+	if _, ok := s.deleted[c.ID]; ok {
+		delete(s.deleted, c.ID)
+		delete(s.revisions, c.ID)
+	}
+	s.recordRevision(c)
 	cp := *c
 	s.m[c.ID] = &cp
+	s.recordEvent(models.ContextEventCreated, c)
 	return nil
 }
+func (s *inMemoryContextStore) CreateBatch(ctx context.Context, contexts []*models.Context, atomic bool) ([]error, error) {
+	errs := make([]error, len(contexts))
+	for i, c := range contexts {
+		if _, ok := s.m[c.ID]; ok {
+			errs[i] = fmt.Errorf("context with ID %s already exists: %w", c.ID, storage.ErrConflict)
+			if atomic {
+				return nil, &storage.BatchError{Index: i, Err: errs[i]}
+			}
+		}
+	}
+	for i, c := range contexts {
+		if errs[i] == nil {
+			_ = s.Create(ctx, c)
+		}
+	}
+	return errs, nil
+}
 func (s *inMemoryContextStore) Get(ctx context.Context, id string) (*models.Context, error) {
 	if c, ok := s.m[id]; ok {
 		cp := *c
 		return &cp, nil
 	}
-	return nil, fmt.Errorf("context not found")
+	return nil, fmt.Errorf("context not found: %w", storage.ErrNotFound)
 }
 func (s *inMemoryContextStore) Update(ctx context.Context, c *models.Context) error {
-	if _, ok := s.m[c.ID]; !ok {
-		return fmt.Errorf("context not found")
+	stored, ok := s.m[c.ID]
+	if !ok {
+		return fmt.Errorf("context not found: %w", storage.ErrNotFound)
 	}
+	if c.Revision != 0 && c.Revision != stored.Revision {
+		return fmt.Errorf("context is at revision %d: %w", stored.Revision, storage.ErrConflict)
+	}
+	s.recordRevision(c)
 	cp := *c
 	s.m[c.ID] = &cp
+	s.recordEvent(models.ContextEventUpdated, c)
 	return nil
 }
 func (s *inMemoryContextStore) Delete(ctx context.Context, id string) error {
-	if _, ok := s.m[id]; !ok {
-		return fmt.Errorf("context not found")
+	return s.DeleteAtRevision(ctx, id, 0)
+}
+func (s *inMemoryContextStore) DeleteAtRevision(ctx context.Context, id string, revision int) error {
+	stored, ok := s.m[id]
+	if !ok {
+		return fmt.Errorf("context not found: %w", storage.ErrNotFound)
+	}
+	if revision != 0 && revision != stored.Revision {
+		return fmt.Errorf("context is at revision %d: %w", stored.Revision, storage.ErrConflict)
+	}
+	if s.deleted == nil {
+		s.deleted = map[string]*models.Context{}
 	}
+	now := time.Now()
+	stored.DeletedAt = &now
+	s.deleted[id] = stored
 	delete(s.m, id)
+	s.recordEvent(models.ContextEventDeleted, stored)
 	return nil
 }
+func (s *inMemoryContextStore) DeleteBatch(ctx context.Context, ids []string, atomic bool) ([]error, error) {
+	errs := make([]error, len(ids))
+	for i, id := range ids {
+		if _, ok := s.m[id]; !ok {
+			errs[i] = fmt.Errorf("context not found: %w", storage.ErrNotFound)
+			if atomic {
+				return nil, &storage.BatchError{Index: i, Err: errs[i]}
+			}
+		}
+	}
+	for i, id := range ids {
+		if errs[i] == nil {
+			errs[i] = s.DeleteAtRevision(ctx, id, 0)
+		}
+	}
+	return errs, nil
+}
 func (s *inMemoryContextStore) List(ctx context.Context, f *storage.ContextFilters) ([]*models.Context, error) {
+	s.lastFilters = f
 	res := []*models.Context{}
 	for _, c := range s.m {
+		if f.IDs != nil && !slices.Contains(f.IDs, c.ID) {
+			continue
+		}
+		if f.After != nil && !listedAfter(c.CreatedAt, c.ID, f.After) {
+			continue
+		}
 		cp := *c
 		res = append(res, &cp)
 	}
+	sort.Slice(res, func(i, j int) bool {
+		return listedAfter(res[j].CreatedAt, res[j].ID, storage.CursorAt(res[i].CreatedAt, res[i].ID))
+	})
+	if f.Limit > 0 && len(res) > f.Limit {
+		res = res[:f.Limit]
+	}
+	return res, nil
+}
+func (s *inMemoryContextStore) Count(ctx context.Context, f *storage.ContextFilters) (int, error) {
+	s.lastFilters = f
+	return len(s.m), nil
+}
+func (s *inMemoryContextStore) Search(ctx context.Context, query string, f *storage.ContextFilters) ([]*models.ContextSearchResult, error) {
+	s.lastFilters = f
+	res := []*models.ContextSearchResult{}
+	for _, c := range s.m {
+		if i := strings.Index(string(c.Payload), query); i >= 0 {
+			cp := *c
+			snippet := string(c.Payload[:i]) + "<mark>" + query + "</mark>" + string(c.Payload[i+len(query):])
+			res = append(res, &models.ContextSearchResult{Context: &cp, Rank: 0.5, Snippet: snippet})
+		}
+	}
+	return res, nil
+}
+func (s *inMemoryContextStore) DeleteExpired(ctx context.Context, before time.Time, limit int) ([]*models.ContextEvent, error) {
+	var events []*models.ContextEvent
+	for id, c := range s.m {
+		if len(events) == limit {
+			break
+		}
+		if !c.ExpiresAt.IsZero() && c.ExpiresAt.Before(before) {
+			delete(s.m, id)
+			events = append(events, s.recordEvent(models.ContextEventExpired, c))
+		}
+	}
+	return events, nil
+}
+func (s *inMemoryContextStore) ListEvents(ctx context.Context, f *storage.EventFilters) ([]*models.ContextEvent, error) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	res := []*models.ContextEvent{}
+	for _, e := range s.events {
+		visible := f.VisibleTo == "" || e.AgentID == f.VisibleTo || e.AccessControl.Scope == models.ScopePublic
+		typed := len(f.Types) == 0
+		for _, t := range f.Types {
+			typed = typed || e.Type == t
+		}
+		matches := (f.ContextType == "" || e.ContextType == f.ContextType) && (f.AgentID == "" || e.AgentID == f.AgentID) && f.Selector.Matches(e.Metadata)
+		if e.TenantID == f.TenantID && e.ID > f.AfterID && visible && typed && matches && (f.Limit == 0 || len(res) < f.Limit) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+func (s *inMemoryContextStore) PruneEvents(ctx context.Context, before time.Time, limit int) (int, error) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	pruned := 0
+	for pruned < len(s.events) && pruned < limit && s.events[pruned].OccurredAt.Before(before) {
+		pruned++
+	}
+	s.events = s.events[pruned:]
+	return pruned, nil
+}
+func (s *inMemoryContextStore) EventBounds(ctx context.Context) (int64, int64, error) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	if len(s.events) == 0 {
+		return 0, 0, nil
+	}
+	return s.events[0].ID, s.events[len(s.events)-1].ID, nil
+}
+func (s *inMemoryContextStore) ListRevisions(ctx context.Context, id string) ([]*models.ContextRevision, error) {
+	revs := s.revisions[id]
+	if len(revs) == 0 {
+		return nil, fmt.Errorf("context not found: %w", storage.ErrNotFound)
+	}
+	out := []*models.ContextRevision{}
+	for i := len(revs) - 1; i >= 0 && revs[i] != nil; i-- {
+		out = append(out, revs[i])
+	}
+	return out, nil
+}
+func (s *inMemoryContextStore) GetRevision(ctx context.Context, id string, revision int) (*models.ContextRevision, error) {
+	revs := s.revisions[id]
+	if revision < 1 || revision > len(revs) || revs[revision-1] == nil {
+		return nil, fmt.Errorf("revision not found: %w", storage.ErrNotFound)
+	}
+	return revs[revision-1], nil
+}
+func (s *inMemoryContextStore) PruneRevisions(ctx context.Context, id string, keep int) (int, error) {
+	revs := s.revisions[id]
+	pruned := 0
+	for i := 0; i < len(revs)-keep; i++ {
+		if revs[i] != nil {
+			revs[i] = nil
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (s *inMemoryContextStore) ListLinks(ctx context.Context, ids []string, direction models.LineageDirection) ([]*models.LineageEdge, error) {
+	var edges []*models.LineageEdge
+	for _, c := range s.m {
+		for _, l := range c.DerivedFrom {
+			from := c.ID
+			if direction == models.LineageDescendants {
+				from = l.ParentID
+			}
+			if slices.Contains(ids, from) {
+				edges = append(edges, &models.LineageEdge{ChildID: c.ID, ParentID: l.ParentID, Relation: l.Relation})
+			}
+		}
+	}
+	return edges, nil
+}
+func (s *inMemoryContextStore) MarkStale(ctx context.Context, id string) ([]string, error) {
+	var marked []string
+	for frontier := []string{id}; len(frontier) > 0; {
+		edges, _ := s.ListLinks(ctx, frontier, models.LineageDescendants)
+		frontier = nil
+		for _, e := range edges {
+			if c := s.m[e.ChildID]; !c.Stale {
+				c.Stale = true
+				marked = append(marked, c.ID)
+				frontier = append(frontier, c.ID)
+			}
+		}
+	}
+	return marked, nil
+}
+
+func (s *inMemoryContextStore) Touch(ctx context.Context, expiries map[string]time.Time) (int, error) {
+	n := 0
+	for id, expiresAt := range expiries {
+		if c, ok := s.m[id]; ok && !c.IsExpired() && c.ExpiresAt.Before(expiresAt) {
+			c.ExpiresAt = expiresAt
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *inMemoryContextStore) GetDeleted(ctx context.Context, id string) (*models.Context, error) {
+	if c, ok := s.deleted[id]; ok {
+		cp := *c
+		return &cp, nil
+	}
+	return nil, fmt.Errorf("deleted context not found: %w", storage.ErrNotFound)
+}
+func (s *inMemoryContextStore) ListDeleted(ctx context.Context, tenantID string, limit int) ([]*models.Context, error) {
+	res := []*models.Context{}
+	for _, c := range s.deleted {
+		if c.TenantID == tenantID {
+			cp := *c
+			res = append(res, &cp)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].DeletedAt.After(*res[j].DeletedAt) })
+	if len(res) > limit {
+		res = res[:limit]
+	}
 	return res, nil
 }
-func (s *inMemoryContextStore) DeleteExpired(ctx context.Context) (int, error) { return 0, nil }
+func (s *inMemoryContextStore) Restore(ctx context.Context, id string) error {
+	c, ok := s.deleted[id]
+	if !ok {
+		return fmt.Errorf("deleted context not found: %w", storage.ErrNotFound)
+	}
+	c.DeletedAt = nil
+	s.m[id] = c
+	delete(s.deleted, id)
+	s.recordEvent(models.ContextEventRestored, c)
+	return nil
+}
+func (s *inMemoryContextStore) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int, error) {
+	n := 0
+	for id, c := range s.deleted {
+		if n < limit && c.DeletedAt.Before(before) {
+			delete(s.deleted, id)
+			n++
+		}
+	}
+	return n, nil
+}
 
 func makeServerForHandlersTest(t *testing.T) *HTTPServer {
 	t.Helper()
@@ -120,7 +505,12 @@ func makeServerForHandlersTest(t *testing.T) *HTTPServer {
 
 func authHeader(t *testing.T, jwt *auth.JWTManager) string {
 	t.Helper()
-	tok, err := jwt.GenerateAccessToken("agent-1", "default", []string{"agent-full"})
+	return authHeaderFor(t, jwt, "agent-1")
+}
+
+func authHeaderFor(t *testing.T, jwt *auth.JWTManager, agentID string) string {
+	t.Helper()
+	tok, err := jwt.GenerateAccessToken(agentID, "default", []string{"agent-full"})
 	if err != nil {
 		t.Fatalf("token error: %v", err)
 	}
@@ -394,3 +784,459 @@ func TestAgentHandlers_CRUD(t *testing.T) {
 		t.Fatalf("get after delete expected 404, got %d", w.Code)
 	}
 }
+
+func TestListAgents_CapabilityFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	store := &inMemoryAgentStore{}
+	httpSrv := NewHTTPServer("8080", registry.NewService(store), nil, jwtManager, auth.NewRBAC())
+	hdr := authHeader(t, jwtManager)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/agents?capability=summarize,translate&capability=ocr&match=any&sort=liveness", nil)
+	req.Header.Set("Authorization", hdr)
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	f := store.lastFilters
+	if got := f.CapabilityList(); len(got) != 3 || got[0] != "summarize" || got[2] != "ocr" {
+		t.Fatalf("unexpected capabilities: %v", got)
+	}
+	if f.CapabilityMatch != storage.CapabilityMatchAny || f.OrderBy != storage.AgentOrderLiveness {
+		t.Fatalf("unexpected match/order: %s/%s", f.CapabilityMatch, f.OrderBy)
+	}
+
+	// defaults
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/agents?capability=summarize", nil)
+	req.Header.Set("Authorization", hdr)
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if store.lastFilters.CapabilityMatch != storage.CapabilityMatchAll || store.lastFilters.OrderBy != storage.AgentOrderCreated {
+		t.Fatalf("unexpected defaults: %+v", store.lastFilters)
+	}
+
+	// invalid match and sort
+	for _, q := range []string{"match=some", "sort=random"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/v1/agents?"+q, nil)
+		req.Header.Set("Authorization", hdr)
+		httpSrv.router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s expected 400, got %d", q, w.Code)
+		}
+	}
+}
+
+func TestListHandlers_Selector(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	agentStore := &inMemoryAgentStore{}
+	contextStore := &inMemoryContextStore{}
+	httpSrv := NewHTTPServer("8080", registry.NewService(agentStore), ctxmgr.NewManager(contextStore), jwtManager, auth.NewRBAC())
+	hdr := authHeader(t, jwtManager)
+
+	query := "?selector=" + url.QueryEscape("env=prod,tier in (gold,silver),!deprecated")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/agents"+query, nil)
+	req.Header.Set("Authorization", hdr)
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list agents expected 200, got %d", w.Code)
+	}
+	if got := agentStore.lastFilters.Selector.String(); got != "env=prod,tier in (gold,silver),!deprecated" {
+		t.Fatalf("unexpected agent selector: %s", got)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/contexts"+query, nil)
+	req.Header.Set("Authorization", hdr)
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list contexts expected 200, got %d", w.Code)
+	}
+	if len(contextStore.lastFilters.Selector) != 3 {
+		t.Fatalf("unexpected context selector: %v", contextStore.lastFilters.Selector)
+	}
+
+	// invalid selectors
+	for _, path := range []string{"/api/v1/agents", "/api/v1/contexts"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", path+"?selector="+url.QueryEscape("tier in (gold"), nil)
+		req.Header.Set("Authorization", hdr)
+		httpSrv.router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s invalid selector expected 400, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestListContexts_Search(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	contextStore := &inMemoryContextStore{m: map[string]*models.Context{
+		"c1": {ID: "c1", Type: "note", AgentID: "agent-1", TenantID: "default", Payload: []byte("quarterly invoice")},
+		"c2": {ID: "c2", Type: "note", AgentID: "agent-1", TenantID: "default", Payload: []byte("shipping label")},
+	}}
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}), ctxmgr.NewManager(contextStore), jwtManager, auth.NewRBAC())
+	hdr := authHeaderFor(t, jwtManager, "agent-1")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/contexts?type=note&q=invoice", nil)
+	req.Header.Set("Authorization", hdr)
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("search expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Contexts []struct {
+			ID      string  `json:"id"`
+			Rank    float64 `json:"rank"`
+			Snippet string  `json:"snippet"`
+		} `json:"contexts"`
+		Total int `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 1 || len(resp.Contexts) != 1 || resp.Contexts[0].ID != "c1" {
+		t.Fatalf("unexpected results: %s", w.Body.String())
+	}
+	if resp.Contexts[0].Rank != 0.5 || resp.Contexts[0].Snippet != "quarterly <mark>invoice</mark>" {
+		t.Fatalf("unexpected rank or snippet: %+v", resp.Contexts[0])
+	}
+	if f := contextStore.lastFilters; f.Type != "note" || f.TenantID != "default" || f.VisibleTo != "agent-1" {
+		t.Fatalf("search not scoped to the caller: %+v", f)
+	}
+
+	// An empty query is rejected rather than listing everything
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/contexts?q=", nil)
+	req.Header.Set("Authorization", hdr)
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("empty query expected 400, got %d", w.Code)
+	}
+}
+
+func TestContextHandlers_AccessControl(t *testing.T) {
+	httpSrv := makeServerForHandlersTest(t)
+	owner := authHeaderFor(t, httpSrv.jwtManager, "agent-1")
+	other := authHeaderFor(t, httpSrv.jwtManager, "agent-2")
+
+	create := func(scope string) string {
+		body, _ := json.Marshal(map[string]any{
+			"type":           "doc",
+			"payload":        []byte("p"),
+			"access_control": map[string]any{"scope": scope},
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/contexts", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", owner)
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create expected 201, got %d", w.Code)
+		}
+		var resp map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp["context"].(map[string]any)["id"].(string)
+	}
+	publicID := create("public")
+	privateID := create("private")
+
+	// public context is readable by others but not writable
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/contexts/"+publicID, nil)
+	req.Header.Set("Authorization", other)
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("public get expected 200, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/v1/contexts/"+publicID, bytes.NewBufferString(`{"version":"2"}`))
+	req.Header.Set("Authorization", other)
+	req.Header.Set("Content-Type", "application/json")
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("public update expected 403, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/contexts/"+publicID, nil)
+	req.Header.Set("Authorization", other)
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("public delete expected 403, got %d", w.Code)
+	}
+
+	// private context does not exist as far as others can tell
+	for _, method := range []string{"GET", "DELETE"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(method, "/api/v1/contexts/"+privateID, nil)
+		req.Header.Set("Authorization", other)
+		httpSrv.router.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("private %s expected 404, got %d", method, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/contexts/"+privateID, nil)
+	req.Header.Set("Authorization", owner)
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("owner get expected 200, got %d", w.Code)
+	}
+}
+
+func TestContextHandlers_Revisions(t *testing.T) {
+	httpSrv := makeServerForHandlersTest(t)
+	hdr := authHeader(t, httpSrv.jwtManager)
+
+	do := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Authorization", hdr)
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"type":           "doc",
+		"payload":        []byte(`{"n":1}`),
+		"access_control": map[string]any{"scope": "private"},
+	})
+	w := do("POST", "/api/v1/contexts", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create expected 201, got %d", w.Code)
+	}
+	var created struct {
+		Context models.Context `json:"context"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	base := "/api/v1/contexts/" + created.Context.ID
+
+	body, _ = json.Marshal(map[string]any{"payload": []byte(`{"n":2}`)})
+	if w = do("PUT", base, body); w.Code != http.StatusOK {
+		t.Fatalf("update expected 200, got %d", w.Code)
+	}
+
+	w = do("GET", base+"/revisions", nil)
+	var list struct {
+		Revisions []models.ContextRevision `json:"revisions"`
+		Total     int                      `json:"total"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.Total != 2 || list.Revisions[0].Revision != 2 {
+		t.Fatalf("list revisions: code=%d body=%s", w.Code, w.Body.String())
+	}
+
+	w = do("GET", base+"?revision=1", nil)
+	var got struct {
+		Context models.Context `json:"context"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != http.StatusOK || string(got.Context.Payload) != `{"n":1}` || got.Context.Revision != 1 {
+		t.Fatalf("get revision: code=%d body=%s", w.Code, w.Body.String())
+	}
+
+	for _, path := range []string{base + "?revision=0", base + "?revision=x", base + "/diff?from=abc"} {
+		if w = do("GET", path, nil); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s expected 400, got %d", path, w.Code)
+		}
+	}
+	if w = do("GET", base+"?revision=7", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("missing revision expected 404, got %d", w.Code)
+	}
+
+	w = do("GET", base+"/diff?from=1&to=2", nil)
+	var diff struct {
+		Diff struct {
+			PayloadFormat string `json:"payload_format"`
+			Changes       []struct {
+				Op   string `json:"op"`
+				Path string `json:"path"`
+			} `json:"changes"`
+		} `json:"diff"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &diff)
+	if w.Code != http.StatusOK || diff.Diff.PayloadFormat != "json" || len(diff.Diff.Changes) != 1 || diff.Diff.Changes[0].Path != "/payload/n" {
+		t.Fatalf("diff: code=%d body=%s", w.Code, w.Body.String())
+	}
+
+	w = do("POST", base+"/revisions/1/restore", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != http.StatusOK || got.Context.Revision != 3 || string(got.Context.Payload) != `{"n":1}` {
+		t.Fatalf("restore: code=%d body=%s", w.Code, w.Body.String())
+	}
+
+	// history is hidden from agents that cannot read the context
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", base+"/revisions", nil)
+	req.Header.Set("Authorization", authHeaderFor(t, httpSrv.jwtManager, "agent-2"))
+	httpSrv.router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("foreign revisions expected 404, got %d", w.Code)
+	}
+}
+
+func TestContextHandlers_ConditionalWrites(t *testing.T) {
+	httpSrv := makeServerForHandlersTest(t)
+	hdr := authHeader(t, httpSrv.jwtManager)
+
+	do := func(method, path, ifMatch string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Authorization", hdr)
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"type":           "doc",
+		"payload":        []byte("v1"),
+		"access_control": map[string]any{"scope": "private"},
+	})
+	w := do("POST", "/api/v1/contexts", "", body)
+	if w.Code != http.StatusCreated || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("create: code=%d etag=%q", w.Code, w.Header().Get("ETag"))
+	}
+	var created struct {
+		Context models.Context `json:"context"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	path := "/api/v1/contexts/" + created.Context.ID
+
+	if w = do("GET", path, "", nil); w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("get etag = %q", w.Header().Get("ETag"))
+	}
+
+	update, _ := json.Marshal(map[string]any{"payload": []byte("v2")})
+	w = do("PUT", path, `"1"`, update)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("conditional update: code=%d etag=%q", w.Code, w.Header().Get("ETag"))
+	}
+
+	// A writer holding the old ETag loses
+	w = do("PUT", path, `"1"`, update)
+	if w.Code != http.StatusPreconditionFailed || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("stale update: code=%d etag=%q", w.Code, w.Header().Get("ETag"))
+	}
+	if w = do("DELETE", path, `"1"`, nil); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale delete expected 412, got %d", w.Code)
+	}
+	if w = do("PUT", path, `W/"2"`, update); w.Code != http.StatusBadRequest {
+		t.Fatalf("weak etag expected 400, got %d", w.Code)
+	}
+
+	if w = do("DELETE", path, `"2"`, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("conditional delete expected 204, got %d", w.Code)
+	}
+
+	// Any If-Match fails once the context is gone
+	for _, ifMatch := range []string{`"2"`, "*"} {
+		if w = do("PUT", path, ifMatch, update); w.Code != http.StatusPreconditionFailed {
+			t.Fatalf("update of missing context with If-Match %s expected 412, got %d", ifMatch, w.Code)
+		}
+	}
+	if w = do("DELETE", path, `"2"`, nil); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("delete of missing context with If-Match expected 412, got %d", w.Code)
+	}
+	if w = do("DELETE", path, "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unconditional delete of missing context expected 404, got %d", w.Code)
+	}
+}
+
+func TestContextHandlers_Patch(t *testing.T) {
+	httpSrv := makeServerForHandlersTest(t)
+	hdr := authHeader(t, httpSrv.jwtManager)
+
+	do := func(method, path, contentType, ifMatch string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Authorization", hdr)
+		req.Header.Set("Content-Type", contentType)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"type":           "doc",
+		"payload":        []byte(`{"status":"pending","steps":["plan"]}`),
+		"access_control": map[string]any{"scope": "private"},
+	})
+	w := do("POST", "/api/v1/contexts", "application/json", "", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Context models.Context `json:"context"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	path := "/api/v1/contexts/" + created.Context.ID
+
+	payload := func(w *httptest.ResponseRecorder) string {
+		var resp struct {
+			Context models.Context `json:"context"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return string(resp.Context.Payload)
+	}
+
+	w = do("PATCH", path, "application/merge-patch+json", `"1"`, []byte(`{"status":"running","owner":"a1"}`))
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("merge patch: code=%d etag=%q body=%s", w.Code, w.Header().Get("ETag"), w.Body.String())
+	}
+	if got := payload(w); got != `{"owner":"a1","status":"running","steps":["plan"]}` {
+		t.Fatalf("merge patch payload = %s", got)
+	}
+
+	w = do("PATCH", path, "application/json-patch+json", "", []byte(`[{"op":"test","path":"/status","value":"running"},{"op":"add","path":"/steps/-","value":"act"}]`))
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+		t.Fatalf("json patch: code=%d body=%s", w.Code, w.Body.String())
+	}
+	if got := payload(w); got != `{"owner":"a1","status":"running","steps":["plan","act"]}` {
+		t.Fatalf("json patch payload = %s", got)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		ifMatch     string
+		body        string
+		want        int
+	}{
+		{"unsupported media type", "application/json", "", `{"status":"done"}`, http.StatusUnsupportedMediaType},
+		{"failed test operation", "application/json-patch+json", "", `[{"op":"test","path":"/status","value":"pending"}]`, http.StatusConflict},
+		{"missing path", "application/json-patch+json", "", `[{"op":"replace","path":"/missing","value":1}]`, http.StatusConflict},
+		{"stale revision", "application/merge-patch+json", `"1"`, `{"status":"done"}`, http.StatusPreconditionFailed},
+		{"invalid patch document", "application/json-patch+json", "", `{"op":"add"}`, http.StatusBadRequest},
+		{"unknown operation", "application/json-patch+json", "", `[{"op":"frobnicate","path":"/status"}]`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do("PATCH", path, tt.contentType, tt.ifMatch, []byte(tt.body)); w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Failed patches leave the context untouched
+	if w = do("GET", path, "application/json", "", nil); w.Header().Get("ETag") != `"3"` {
+		t.Fatalf("etag after failed patches = %q", w.Header().Get("ETag"))
+	}
+}
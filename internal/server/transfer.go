@@ -0,0 +1,78 @@
+package server
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/acb/internal/constants"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/transfer"
+	"github.com/gin-gonic/gin"
+)
+
+// exportTenant streams the caller's tenant as an archive. Errors after the
+// first bytes are sent can no longer change the status, so the archive is
+// cut short instead; its missing manifest makes it fail to import.
+func (s *HTTPServer) exportTenant(c *gin.Context) {
+	if s.transfer == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "transfer service unavailable"})
+		return
+	}
+	tenantID, err := transfer.CallerTenant(c.Request.Context())
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	format := transfer.Format(c.DefaultQuery("format", string(transfer.FormatNDJSON)))
+	if err := format.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": tenantID + "." + string(format),
+	}))
+	c.Status(http.StatusOK)
+	if _, err := s.transfer.Export(c.Request.Context(), c.Writer, tenantID, format); err != nil {
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			respondError(c, err, http.StatusInternalServerError)
+			return
+		}
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// importTenant imports an archive into the caller's tenant, remapping the
+// tenant it was exported from. on_conflict selects how existing IDs are
+// treated: fail (the default), skip or overwrite.
+func (s *HTTPServer) importTenant(c *gin.Context) {
+	if s.transfer == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "transfer service unavailable"})
+		return
+	}
+	tenantID, err := transfer.CallerTenant(c.Request.Context())
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, constants.MaxImportArchiveBytes)
+	summary, err := s.transfer.Import(c.Request.Context(), c.Request.Body, transfer.ImportOptions{
+		TenantID:   tenantID,
+		OnConflict: models.ConflictPolicy(c.Query("on_conflict")),
+	})
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "archive is too large"})
+		return
+	}
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
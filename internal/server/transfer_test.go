@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/acb/internal/transfer"
+	"github.com/gin-gonic/gin"
+)
+
+// recordingImportStore records the records of the last import
+type recordingImportStore struct {
+	agents   []*models.Agent
+	contexts []*models.ContextRecord
+	policy   models.ConflictPolicy
+}
+
+func (s *recordingImportStore) Import(_ context.Context, agents []*models.Agent, contexts []*models.ContextRecord, policy models.ConflictPolicy) (*models.ImportSummary, error) {
+	s.agents, s.contexts, s.policy = agents, contexts, policy
+	return &models.ImportSummary{
+		Agents:   models.ImportCounts{Created: len(agents)},
+		Contexts: models.ImportCounts{Created: len(contexts)},
+	}, nil
+}
+
+func TestTenantTransferHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	now := time.Now()
+	agentStore := &inMemoryAgentStore{m: map[string]*models.Agent{
+		"planner": {ID: "planner", Type: "llm", TenantID: "default", CreatedAt: now},
+	}}
+	contextStore := &inMemoryContextStore{m: map[string]*models.Context{
+		"ctx-1": {ID: "ctx-1", Type: "note", AgentID: "planner", TenantID: "default", CreatedAt: now,
+			AccessControl: models.AccessControl{Scope: models.ScopePublic}, Revision: 1},
+	}}
+	importer := &recordingImportStore{}
+	httpSrv := NewHTTPServer("8080", registry.NewService(agentStore), ctxmgr.NewManager(contextStore),
+		jwtManager, auth.NewRBAC(), WithTransfer(transfer.NewService(agentStore, contextStore, importer)))
+
+	adminToken, err := jwtManager.GenerateAccessToken("admin-1", "default", []string{"admin"})
+	if err != nil {
+		t.Fatalf("token error: %v", err)
+	}
+	admin := "Bearer " + adminToken
+	do := func(method, path, authz string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", authz)
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Only tenant admins may export or import
+	if w := do("GET", "/api/v1/tenant/export", authHeaderFor(t, jwtManager, "planner"), nil); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/api/v1/tenant/export?format=zip", admin, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d", w.Code)
+	}
+
+	for _, format := range []string{"ndjson", "tar"} {
+		w := do("GET", "/api/v1/tenant/export?format="+format, admin, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s export expected 200, got %d %s", format, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Header().Get("Content-Disposition"), "default."+format) {
+			t.Fatalf("unexpected disposition %q", w.Header().Get("Content-Disposition"))
+		}
+		archive := w.Body.Bytes()
+
+		w = do("POST", "/api/v1/tenant/import?on_conflict=skip", admin, archive)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s import expected 200, got %d %s", format, w.Code, w.Body.String())
+		}
+		var summary models.ImportSummary
+		if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if summary.Contexts.Created != 1 || summary.SourceTenantID != "default" || importer.policy != models.ConflictSkip {
+			t.Fatalf("unexpected %s import %+v", format, summary)
+		}
+		if len(importer.contexts) != 1 || importer.contexts[0].ID != "ctx-1" {
+			t.Fatalf("unexpected imported contexts %+v", importer.contexts)
+		}
+	}
+
+	// Archives that fail verification are rejected
+	if w := do("POST", "/api/v1/tenant/import", admin, []byte(`{"kind":"agent","data":{}}`)); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid archive, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do("POST", "/api/v1/tenant/import?on_conflict=merge", admin, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown policy, got %d", w.Code)
+	}
+}
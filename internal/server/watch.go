@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// waitForContext serves a context read with wait_for_version=N. The request
+// is held open until the context's revision exceeds N, it is deleted, or the
+// timeout passes (a duration such as 30s, or seconds; 30s by default). A newer
+// revision is returned like any read; when the wait ends without one, the
+// response is 304 Not Modified with the ETag of the current revision and the
+// client simply asks again. Server shutdown ends open waits the same way.
+func (s *HTTPServer) waitForContext(c *gin.Context, contextID string) {
+	after, err := strconv.Atoi(c.Query("wait_for_version"))
+	if err != nil || after < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "wait_for_version must be a non-negative revision"})
+		return
+	}
+	if c.Query("revision") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "revision and wait_for_version cannot be combined"})
+		return
+	}
+	var timeout time.Duration
+	if value := c.Query("timeout"); value != "" {
+		if timeout, err = parseWaitTimeout(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be a duration such as 30s"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	stop := context.AfterFunc(s.streams, cancel)
+	defer stop()
+
+	cx, err := s.contextMgr.WaitForRevision(ctx, contextID, after, timeout)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("ETag", etag(cx.Revision))
+	if cx.Revision <= after {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"context": cx})
+}
+
+// parseWaitTimeout parses a wait timeout given as a duration or in seconds
+func parseWaitTimeout(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acb/internal/auth"
+	ctxmgr "github.com/acb/internal/context"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/registry"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetContextWaitForVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := auth.NewJWTManager("test-secret")
+	httpSrv := NewHTTPServer("8080", registry.NewService(&inMemoryAgentStore{}),
+		ctxmgr.NewManager(&inMemoryContextStore{}), jwtManager, auth.NewRBAC())
+	authorization := authHeaderFor(t, jwtManager, "agent-1")
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", authorization)
+		req.Header.Set("Content-Type", "application/json")
+		httpSrv.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/v1/contexts", map[string]any{
+		"type": "task", "payload": []byte("todo"), "access_control": map[string]any{"scope": "private"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Context models.Context `json:"context"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	base := "/api/v1/contexts/" + created.Context.ID
+
+	// A newer revision is returned at once
+	if w := do("GET", base+"?wait_for_version=0", nil); w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("expected revision 1 at once, got %d %s", w.Code, w.Body.String())
+	}
+
+	// A wait that times out reports the current revision unchanged
+	w = do("GET", base+"?wait_for_version=1&timeout=50ms", nil)
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != `"1"` || w.Body.Len() != 0 {
+		t.Fatalf("expected 304 at revision 1, got %d %s", w.Code, w.Body.String())
+	}
+
+	for _, query := range []string{"wait_for_version=-1", "wait_for_version=x", "wait_for_version=1&timeout=soon", "wait_for_version=1&timeout=1h", "wait_for_version=1&revision=1"} {
+		if w := do("GET", base+"?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+
+	// Deleted contexts end the wait as not found
+	if w := do("DELETE", base, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", base+"?wait_for_version=1&timeout=1", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BatchError reports the item that failed an all-or-nothing batch write.
+// Nothing in the batch was written.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// execer is satisfied by both the pool and a transaction
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// writeBatch calls write for items 0 to n-1 in one transaction. When atomic
+// the first failure rolls back the whole batch and is returned as a
+// *BatchError. Otherwise each item runs under its own savepoint, so a failure
+// only undoes that item, and the per-item errors are returned.
+func writeBatch(ctx context.Context, pool *pgxpool.Pool, n int, atomic bool, write func(tx pgx.Tx, i int) error) ([]error, error) {
+	errs := make([]error, n)
+	err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		for i := 0; i < n; i++ {
+			if atomic {
+				if err := write(tx, i); err != nil {
+					return &BatchError{Index: i, Err: err}
+				}
+				continue
+			}
+			errs[i] = pgx.BeginFunc(ctx, tx, func(savepoint pgx.Tx) error {
+				return write(savepoint, i)
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
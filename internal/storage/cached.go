@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/acb/internal/models"
+)
+
+// CacheStats counts the lookups served by a cached store
+type CacheStats struct {
+	Hits         uint64 `json:"hits"`          // Served from a cached object
+	NegativeHits uint64 `json:"negative_hits"` // Served from a cached miss
+	Misses       uint64 `json:"misses"`        // Read through to the store
+	Errors       uint64 `json:"errors"`        // Cache reads and writes that failed
+}
+
+type cacheCounters struct {
+	hits, negativeHits, misses, errors atomic.Uint64
+}
+
+func (c *cacheCounters) stats() CacheStats {
+	return CacheStats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Errors:       c.errors.Load(),
+	}
+}
+
+// lookup classifies the result of a cache read. It reports whether the
+// result can be served without reading the store.
+func (c *cacheCounters) lookup(err error) bool {
+	switch {
+	case err == nil:
+		c.hits.Add(1)
+		return true
+	case errors.Is(err, ErrNotFound):
+		c.negativeHits.Add(1)
+		return true
+	case errors.Is(err, ErrCacheMiss):
+		c.misses.Add(1)
+	default:
+		// An unavailable cache degrades to reading the store
+		c.errors.Add(1)
+		c.misses.Add(1)
+	}
+	return false
+}
+
+func (c *cacheCounters) record(err error) {
+	if err != nil {
+		c.errors.Add(1)
+	}
+}
+
+// CachedAgentStore decorates an AgentStore with a read-through cache. Creates
+// are written through; updates, heartbeats, deletes and restores invalidate
+// the entry. Agents the reaper marks offline or deletes in bulk are not
+// invalidated one by one, so ttl bounds how long a cached agent can lag such
+// a change.
+type CachedAgentStore struct {
+	AgentStore
+	cache       AgentCache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	counters    cacheCounters
+}
+
+// NewCachedAgentStore creates a new cached agent store. Agents are cached for
+// ttl and lookups of unknown agents for negativeTTL.
+func NewCachedAgentStore(store AgentStore, cache AgentCache, ttl, negativeTTL time.Duration) *CachedAgentStore {
+	return &CachedAgentStore{
+		AgentStore:  store,
+		cache:       cache,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// Stats returns the cache lookup counters
+func (s *CachedAgentStore) Stats() CacheStats {
+	return s.counters.stats()
+}
+
+// Create creates an agent and caches it
+func (s *CachedAgentStore) Create(ctx context.Context, agent *models.Agent) error {
+	if err := s.AgentStore.Create(ctx, agent); err != nil {
+		return err
+	}
+	s.counters.record(s.cache.Set(ctx, agent, s.ttl))
+	return nil
+}
+
+// CreateBatch creates agents and caches those created
+func (s *CachedAgentStore) CreateBatch(ctx context.Context, agents []*models.Agent, atomic bool) ([]error, error) {
+	errs, err := s.AgentStore.CreateBatch(ctx, agents, atomic)
+	for i, itemErr := range errs {
+		if itemErr == nil {
+			s.counters.record(s.cache.Set(ctx, agents[i], s.ttl))
+		}
+	}
+	return errs, err
+}
+
+// Get retrieves an agent from the cache, reading through to the store on a miss
+func (s *CachedAgentStore) Get(ctx context.Context, agentID string) (*models.Agent, error) {
+	agent, err := s.cache.Get(ctx, agentID)
+	if s.counters.lookup(err) {
+		return agent, err
+	}
+
+	agent, err = s.AgentStore.Get(ctx, agentID)
+	switch {
+	case err == nil:
+		s.counters.record(s.cache.Set(ctx, agent, s.ttl))
+	case IsNotFound(err):
+		s.counters.record(s.cache.SetMissing(ctx, agentID, s.negativeTTL))
+	}
+	return agent, err
+}
+
+// Update updates an agent and invalidates its cache entry
+func (s *CachedAgentStore) Update(ctx context.Context, agent *models.Agent) error {
+	defer s.invalidate(ctx, agent.ID)
+	return s.AgentStore.Update(ctx, agent)
+}
+
+// Delete deletes an agent and invalidates its cache entry
+func (s *CachedAgentStore) Delete(ctx context.Context, agentID string) error {
+	defer s.invalidate(ctx, agentID)
+	return s.AgentStore.Delete(ctx, agentID)
+}
+
+// Restore restores a deleted agent and invalidates its cache entry, which may
+// hold the agent as missing
+func (s *CachedAgentStore) Restore(ctx context.Context, agentID string) error {
+	defer s.invalidate(ctx, agentID)
+	return s.AgentStore.Restore(ctx, agentID)
+}
+
+// UpdateLastSeen records a heartbeat and invalidates the agent's cache entry
+func (s *CachedAgentStore) UpdateLastSeen(ctx context.Context, agentID string) error {
+	defer s.invalidate(ctx, agentID)
+	return s.AgentStore.UpdateLastSeen(ctx, agentID)
+}
+
+// invalidate runs after the write whether or not it failed, since a failed
+// write may still have been committed, and even if ctx was cancelled
+func (s *CachedAgentStore) invalidate(ctx context.Context, agentID string) {
+	s.counters.record(s.cache.Delete(context.WithoutCancel(ctx), agentID))
+}
+
+// invalidationGuard is how long an invalidated context entry stops
+// read-through fills. It must exceed the time a read takes between loading a
+// context from the store and filling the cache with it.
+const invalidationGuard = 10 * time.Second
+
+// CachedContextStore decorates a ContextStore with a read-through cache.
+// Creates are written through; updates, deletes, including expiry sweeps, and
+// restores invalidate the entry. Entries never outlive the context's expiry.
+type CachedContextStore struct {
+	ContextStore
+	cache       ContextCache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	counters    cacheCounters
+	now         func() time.Time
+}
+
+// NewCachedContextStore creates a new cached context store. Contexts are
+// cached for ttl or until they expire, whichever is sooner, and lookups of
+// unknown contexts for negativeTTL.
+func NewCachedContextStore(store ContextStore, cache ContextCache, ttl, negativeTTL time.Duration) *CachedContextStore {
+	return &CachedContextStore{
+		ContextStore: store,
+		cache:        cache,
+		ttl:          ttl,
+		negativeTTL:  negativeTTL,
+		now:          time.Now,
+	}
+}
+
+// Stats returns the cache lookup counters
+func (s *CachedContextStore) Stats() CacheStats {
+	return s.counters.stats()
+}
+
+// Create creates a context and caches it
+func (s *CachedContextStore) Create(ctx context.Context, c *models.Context) error {
+	if err := s.ContextStore.Create(ctx, c); err != nil {
+		return err
+	}
+	s.set(ctx, c)
+	return nil
+}
+
+// CreateBatch creates contexts and caches those created
+func (s *CachedContextStore) CreateBatch(ctx context.Context, contexts []*models.Context, atomic bool) ([]error, error) {
+	errs, err := s.ContextStore.CreateBatch(ctx, contexts, atomic)
+	for i, itemErr := range errs {
+		if itemErr == nil {
+			s.set(ctx, contexts[i])
+		}
+	}
+	return errs, err
+}
+
+// Get retrieves a context from the cache, reading through to the store on a miss
+func (s *CachedContextStore) Get(ctx context.Context, contextID string) (*models.Context, error) {
+	c, err := s.cache.Get(ctx, contextID)
+	if s.counters.lookup(err) {
+		return c, err
+	}
+
+	c, err = s.ContextStore.Get(ctx, contextID)
+	switch {
+	case err == nil:
+		if ttl := s.cacheTTL(c); ttl > 0 {
+			s.counters.record(s.cache.Fill(ctx, c, ttl))
+		}
+	case IsNotFound(err):
+		s.counters.record(s.cache.SetMissing(ctx, contextID, s.negativeTTL))
+	}
+	return c, err
+}
+
+// Update updates a context and invalidates its cache entry
+func (s *CachedContextStore) Update(ctx context.Context, c *models.Context) error {
+	defer s.invalidate(ctx, c.ID)
+	return s.ContextStore.Update(ctx, c)
+}
+
+// Delete deletes a context and invalidates its cache entry
+func (s *CachedContextStore) Delete(ctx context.Context, contextID string) error {
+	defer s.invalidate(ctx, contextID)
+	return s.ContextStore.Delete(ctx, contextID)
+}
+
+// DeleteAtRevision deletes a context at a revision and invalidates its cache
+// entry
+func (s *CachedContextStore) DeleteAtRevision(ctx context.Context, contextID string, revision int) error {
+	defer s.invalidate(ctx, contextID)
+	return s.ContextStore.DeleteAtRevision(ctx, contextID, revision)
+}
+
+// DeleteBatch deletes contexts and invalidates their cache entries
+func (s *CachedContextStore) DeleteBatch(ctx context.Context, contextIDs []string, atomic bool) ([]error, error) {
+	for _, id := range contextIDs {
+		defer s.invalidate(ctx, id)
+	}
+	return s.ContextStore.DeleteBatch(ctx, contextIDs, atomic)
+}
+
+// Restore restores a deleted context and invalidates its cache entry, which
+// may hold the context as missing
+func (s *CachedContextStore) Restore(ctx context.Context, contextID string) error {
+	defer s.invalidate(ctx, contextID)
+	return s.ContextStore.Restore(ctx, contextID)
+}
+
+// DeleteExpired deletes expired contexts and invalidates their cache entries
+func (s *CachedContextStore) DeleteExpired(ctx context.Context, before time.Time, limit int) ([]*models.ContextEvent, error) {
+	events, err := s.ContextStore.DeleteExpired(ctx, before, limit)
+	for _, e := range events {
+		s.invalidate(ctx, e.ContextID)
+	}
+	return events, err
+}
+
+// MarkStale marks the descendants of a context stale and invalidates their
+// cache entries
+func (s *CachedContextStore) MarkStale(ctx context.Context, contextID string) ([]string, error) {
+	ids, err := s.ContextStore.MarkStale(ctx, contextID)
+	for _, id := range ids {
+		s.invalidate(ctx, id)
+	}
+	return ids, err
+}
+
+// Touch extends the expiry of contexts and invalidates their cache entries
+func (s *CachedContextStore) Touch(ctx context.Context, expiries map[string]time.Time) (int, error) {
+	n, err := s.ContextStore.Touch(ctx, expiries)
+	for id := range expiries {
+		s.invalidate(ctx, id)
+	}
+	return n, err
+}
+
+// set caches c until it expires, capped at the configured TTL. Contexts that
+// have already expired are not cached.
+func (s *CachedContextStore) set(ctx context.Context, c *models.Context) {
+	if ttl := s.cacheTTL(c); ttl > 0 {
+		s.counters.record(s.cache.Set(ctx, c, ttl))
+	}
+}
+
+// cacheTTL returns how long c may be cached, which is not positive once it
+// has expired
+func (s *CachedContextStore) cacheTTL(c *models.Context) time.Duration {
+	ttl := s.ttl
+	if !c.ExpiresAt.IsZero() {
+		if remaining := c.ExpiresAt.Sub(s.now()); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	return ttl
+}
+
+// invalidate runs after the write whether or not it failed, since a failed
+// write may still have been committed, and even if ctx was cancelled. The
+// guard it leaves keeps reads that loaded the context before the write from
+// caching it afterwards.
+func (s *CachedContextStore) invalidate(ctx context.Context, contextID string) {
+	s.counters.record(s.cache.Invalidate(context.WithoutCancel(ctx), contextID, invalidationGuard))
+}
+
+// CachedImportStore decorates an ImportStore so that imports invalidate the
+// entries of the cached agent and context stores, including cached misses
+// of the records it creates
+type CachedImportStore struct {
+	ImportStore
+	agents   *CachedAgentStore
+	contexts *CachedContextStore
+}
+
+// NewCachedImportStore creates an import store invalidating the caches of
+// agents and contexts
+func NewCachedImportStore(store ImportStore, agents *CachedAgentStore, contexts *CachedContextStore) *CachedImportStore {
+	return &CachedImportStore{ImportStore: store, agents: agents, contexts: contexts}
+}
+
+// Import writes an archive and invalidates the cache entry of every record
+// in it
+func (s *CachedImportStore) Import(ctx context.Context, agents []*models.Agent, contexts []*models.ContextRecord, policy models.ConflictPolicy) (*models.ImportSummary, error) {
+	for _, agent := range agents {
+		defer s.agents.invalidate(ctx, agent.ID)
+	}
+	for _, rec := range contexts {
+		defer s.contexts.invalidate(ctx, rec.ID)
+	}
+	return s.ImportStore.Import(ctx, agents, contexts, policy)
+}
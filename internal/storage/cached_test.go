@@ -0,0 +1,490 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapCache is an in-memory AgentCache and ContextCache recording TTLs.
+// Guards left by Invalidate never expire.
+type mapCache struct {
+	entries map[string]any // nil value marks a cached miss
+	ttls    map[string]time.Duration
+	guards  map[string]time.Duration
+	err     error
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{entries: make(map[string]any), ttls: make(map[string]time.Duration), guards: make(map[string]time.Duration)}
+}
+
+func (m *mapCache) lookup(id string) (any, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	v, ok := m.entries[id]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	if v == nil {
+		return nil, fmt.Errorf("cached as missing: %w", ErrNotFound)
+	}
+	return v, nil
+}
+
+func (m *mapCache) store(id string, v any, ttl time.Duration) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.entries[id] = v
+	m.ttls[id] = ttl
+	delete(m.guards, id)
+	return nil
+}
+
+// add stores v unless id has an entry or a guard
+func (m *mapCache) add(id string, v any, ttl time.Duration) error {
+	_, cached := m.entries[id]
+	_, guarded := m.guards[id]
+	if cached || guarded {
+		return m.err
+	}
+	return m.store(id, v, ttl)
+}
+
+func (m *mapCache) setMissing(id string, ttl time.Duration) error {
+	return m.add(id, nil, ttl)
+}
+
+func (m *mapCache) Delete(_ context.Context, id string) error {
+	delete(m.entries, id)
+	return m.err
+}
+
+func (m *mapCache) Invalidate(_ context.Context, id string, ttl time.Duration) error {
+	delete(m.entries, id)
+	m.guards[id] = ttl
+	return m.err
+}
+
+type mapAgentCache struct{ *mapCache }
+
+func (m mapAgentCache) Get(_ context.Context, id string) (*models.Agent, error) {
+	v, err := m.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	agent := *v.(*models.Agent)
+	return &agent, nil
+}
+
+func (m mapAgentCache) Set(_ context.Context, agent *models.Agent, ttl time.Duration) error {
+	copied := *agent
+	return m.store(agent.ID, &copied, ttl)
+}
+
+func (m mapAgentCache) SetMissing(_ context.Context, id string, ttl time.Duration) error {
+	return m.setMissing(id, ttl)
+}
+
+type mapContextCache struct{ *mapCache }
+
+func (m mapContextCache) Get(_ context.Context, id string) (*models.Context, error) {
+	v, err := m.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	c := *v.(*models.Context)
+	return &c, nil
+}
+
+func (m mapContextCache) Set(_ context.Context, c *models.Context, ttl time.Duration) error {
+	copied := *c
+	return m.store(c.ID, &copied, ttl)
+}
+
+func (m mapContextCache) Fill(_ context.Context, c *models.Context, ttl time.Duration) error {
+	copied := *c
+	return m.add(c.ID, &copied, ttl)
+}
+
+func (m mapContextCache) SetMissing(_ context.Context, id string, ttl time.Duration) error {
+	return m.setMissing(id, ttl)
+}
+
+// countingAgentStore serves agents from a map and counts reads
+type countingAgentStore struct {
+	AgentStore
+	agents map[string]*models.Agent
+	gets   int
+}
+
+func (s *countingAgentStore) Create(_ context.Context, agent *models.Agent) error {
+	s.agents[agent.ID] = agent
+	return nil
+}
+
+func (s *countingAgentStore) Get(_ context.Context, id string) (*models.Agent, error) {
+	s.gets++
+	agent, ok := s.agents[id]
+	if !ok {
+		return nil, fmt.Errorf("agent not found: %w", ErrNotFound)
+	}
+	copied := *agent
+	return &copied, nil
+}
+
+func (s *countingAgentStore) UpdateLastSeen(_ context.Context, id string) error {
+	if _, ok := s.agents[id]; !ok {
+		return fmt.Errorf("agent not found: %w", ErrNotFound)
+	}
+	s.agents[id].Status = models.AgentStatusOnline
+	return nil
+}
+
+// countingContextStore serves contexts from a map and counts reads
+type countingContextStore struct {
+	ContextStore
+	contexts map[string]*models.Context
+	deleted  map[string]*models.Context
+	gets     int
+}
+
+func (s *countingContextStore) Create(_ context.Context, c *models.Context) error {
+	s.contexts[c.ID] = c
+	return nil
+}
+
+// CreateBatch creates the contexts that are not already stored
+func (s *countingContextStore) CreateBatch(_ context.Context, contexts []*models.Context, _ bool) ([]error, error) {
+	errs := make([]error, len(contexts))
+	for i, c := range contexts {
+		if _, ok := s.contexts[c.ID]; ok {
+			errs[i] = fmt.Errorf("context %s exists: %w", c.ID, ErrConflict)
+			continue
+		}
+		s.contexts[c.ID] = c
+	}
+	return errs, nil
+}
+
+func (s *countingContextStore) DeleteBatch(_ context.Context, ids []string, _ bool) ([]error, error) {
+	for _, id := range ids {
+		delete(s.contexts, id)
+	}
+	return make([]error, len(ids)), nil
+}
+
+func (s *countingContextStore) Get(_ context.Context, id string) (*models.Context, error) {
+	s.gets++
+	c, ok := s.contexts[id]
+	if !ok {
+		return nil, fmt.Errorf("context not found: %w", ErrNotFound)
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (s *countingContextStore) Update(_ context.Context, c *models.Context) error {
+	if c.Revision != s.contexts[c.ID].Revision {
+		return fmt.Errorf("revision mismatch: %w", ErrConflict)
+	}
+	c.Revision++
+	s.contexts[c.ID] = c
+	return nil
+}
+
+func (s *countingContextStore) DeleteExpired(_ context.Context, before time.Time, _ int) ([]*models.ContextEvent, error) {
+	var events []*models.ContextEvent
+	for id, c := range s.contexts {
+		if c.ExpiresAt.Before(before) {
+			delete(s.contexts, id)
+			events = append(events, &models.ContextEvent{ContextID: id, Type: models.ContextEventExpired})
+		}
+	}
+	return events, nil
+}
+
+// Restore moves a deleted context back among the stored ones
+func (s *countingContextStore) Restore(_ context.Context, id string) error {
+	c, ok := s.deleted[id]
+	if !ok {
+		return fmt.Errorf("deleted context not found: %w", ErrNotFound)
+	}
+	delete(s.deleted, id)
+	s.contexts[id] = c
+	return nil
+}
+
+// MarkStale marks every other context stale
+func (s *countingContextStore) MarkStale(_ context.Context, contextID string) ([]string, error) {
+	var ids []string
+	for id, c := range s.contexts {
+		if id != contextID {
+			c.Stale = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Touch extends the expiry of stored contexts
+func (s *countingContextStore) Touch(_ context.Context, expiries map[string]time.Time) (int, error) {
+	n := 0
+	for id, expiresAt := range expiries {
+		if c, ok := s.contexts[id]; ok {
+			c.ExpiresAt = expiresAt
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestCachedAgentStore_ReadThroughAndInvalidate(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingAgentStore{agents: make(map[string]*models.Agent)}
+	cache := newMapCache()
+	store := NewCachedAgentStore(backing, mapAgentCache{cache}, time.Minute, 5*time.Second)
+
+	require.NoError(t, store.Create(ctx, &models.Agent{ID: "agent-1", Status: models.AgentStatusUnknown}))
+
+	// Written through on create, so the first read is a hit
+	agent, err := store.Get(ctx, "agent-1")
+	require.NoError(t, err)
+	assert.Equal(t, "agent-1", agent.ID)
+	assert.Equal(t, 0, backing.gets)
+
+	// A heartbeat invalidates, so the next read sees the new status
+	require.NoError(t, store.UpdateLastSeen(ctx, "agent-1"))
+	agent, err = store.Get(ctx, "agent-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.AgentStatusOnline, agent.Status)
+	assert.Equal(t, 1, backing.gets)
+
+	// Unknown agents are cached as missing
+	for i := 0; i < 2; i++ {
+		_, err = store.Get(ctx, "ghost")
+		assert.True(t, IsNotFound(err))
+	}
+	assert.Equal(t, 2, backing.gets)
+	assert.Equal(t, 5*time.Second, cache.ttls["ghost"])
+
+	assert.Equal(t, CacheStats{Hits: 1, NegativeHits: 1, Misses: 2}, store.Stats())
+}
+
+func TestCachedContextStore_ReadThroughAndInvalidate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	backing := &countingContextStore{contexts: make(map[string]*models.Context)}
+	cache := newMapCache()
+	store := NewCachedContextStore(backing, mapContextCache{cache}, 5*time.Minute, 5*time.Second)
+	store.now = func() time.Time { return now }
+
+	// TTLs are capped at the context's expiry
+	require.NoError(t, store.Create(ctx, &models.Context{ID: "short", Revision: 1, ExpiresAt: now.Add(time.Minute)}))
+	require.NoError(t, store.Create(ctx, &models.Context{ID: "long", Revision: 1, ExpiresAt: now.Add(time.Hour)}))
+	assert.Equal(t, time.Minute, cache.ttls["short"])
+	assert.Equal(t, 5*time.Minute, cache.ttls["long"])
+
+	// Updates invalidate, so the next read goes to the store
+	c, err := store.Get(ctx, "long")
+	require.NoError(t, err)
+	require.NoError(t, store.Update(ctx, c))
+	_, cached := cache.entries["long"]
+	assert.False(t, cached)
+	c, err = store.Get(ctx, "long")
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Revision)
+	assert.Equal(t, 1, backing.gets)
+
+	// A failed compare-and-swap still invalidates
+	stale := *c
+	stale.Revision = 1
+	err = store.Update(ctx, &stale)
+	assert.True(t, IsConflict(err))
+	_, cached = cache.entries["long"]
+	assert.False(t, cached)
+
+	// Expired contexts are never cached and are invalidated when swept
+	backing.contexts["expired"] = &models.Context{ID: "expired", ExpiresAt: now.Add(-time.Second)}
+	_, err = store.Get(ctx, "expired")
+	require.NoError(t, err)
+	_, cached = cache.entries["expired"]
+	assert.False(t, cached)
+
+	events, err := store.DeleteExpired(ctx, now.Add(2*time.Minute), 100)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+	_, cached = cache.entries["short"]
+	assert.False(t, cached)
+
+	// Contexts marked stale are invalidated
+	_, err = store.Get(ctx, "long")
+	require.NoError(t, err)
+	ids, err := store.MarkStale(ctx, "parent")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"long"}, ids)
+	c, err = store.Get(ctx, "long")
+	require.NoError(t, err)
+	assert.True(t, c.Stale)
+
+	// Touched contexts are invalidated, so reads see the extended expiry
+	n, err := store.Touch(ctx, map[string]time.Time{"long": now.Add(2 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	c, err = store.Get(ctx, "long")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(2*time.Hour), c.ExpiresAt)
+
+	// A miss cached before a create does not hide the created context
+	_, err = store.Get(ctx, "new")
+	assert.True(t, IsNotFound(err))
+	require.NoError(t, store.Create(ctx, &models.Context{ID: "new", Revision: 1, ExpiresAt: now.Add(time.Hour)}))
+	_, err = store.Get(ctx, "new")
+	require.NoError(t, err)
+}
+
+// racingContextStore runs beforeReturn after a read has loaded a context
+// but before it is returned
+type racingContextStore struct {
+	*countingContextStore
+	beforeReturn func()
+}
+
+func (s *racingContextStore) Get(ctx context.Context, id string) (*models.Context, error) {
+	c, err := s.countingContextStore.Get(ctx, id)
+	if s.beforeReturn != nil {
+		s.beforeReturn()
+		s.beforeReturn = nil
+	}
+	return c, err
+}
+
+func TestCachedContextStore_ReadRacingUpdate(t *testing.T) {
+	ctx := context.Background()
+	backing := &racingContextStore{countingContextStore: &countingContextStore{contexts: map[string]*models.Context{
+		"ctx-1": {ID: "ctx-1", Revision: 1, ExpiresAt: time.Now().Add(time.Hour)},
+	}}}
+	cache := newMapCache()
+	store := NewCachedContextStore(backing, mapContextCache{cache}, time.Minute, time.Second)
+
+	// An update lands between the read-through and its fill
+	backing.beforeReturn = func() {
+		c := *backing.contexts["ctx-1"]
+		require.NoError(t, store.Update(ctx, &c))
+	}
+	c, err := store.Get(ctx, "ctx-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.Revision)
+	assert.Equal(t, invalidationGuard, cache.guards["ctx-1"])
+
+	// The revision read before the update was not cached
+	c, err = store.Get(ctx, "ctx-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Revision)
+}
+
+func TestCachedContextStore_RestoreInvalidatesMissing(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingContextStore{
+		contexts: make(map[string]*models.Context),
+		deleted:  map[string]*models.Context{"ctx-1": {ID: "ctx-1", Revision: 1}},
+	}
+	cache := newMapCache()
+	store := NewCachedContextStore(backing, mapContextCache{cache}, 5*time.Minute, 5*time.Second)
+
+	// A deleted context is cached as missing like any unknown ID
+	_, err := store.Get(ctx, "ctx-1")
+	assert.True(t, IsNotFound(err))
+	_, err = store.Get(ctx, "ctx-1")
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, 1, backing.gets)
+
+	// Restoring drops the cached miss
+	require.NoError(t, store.Restore(ctx, "ctx-1"))
+	c, err := store.Get(ctx, "ctx-1")
+	require.NoError(t, err)
+	assert.Equal(t, "ctx-1", c.ID)
+	assert.Equal(t, 2, backing.gets)
+}
+
+func TestCachedContextStore_Batch(t *testing.T) {
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+	backing := &countingContextStore{contexts: map[string]*models.Context{
+		"existing": {ID: "existing", Revision: 3, ExpiresAt: expires},
+	}}
+	cache := newMapCache()
+	store := NewCachedContextStore(backing, mapContextCache{cache}, time.Minute, time.Second)
+
+	// Only the contexts that were created are written through
+	errs, err := store.CreateBatch(ctx, []*models.Context{
+		{ID: "a", Revision: 1, ExpiresAt: expires},
+		{ID: "existing", Revision: 1, ExpiresAt: expires},
+	}, false)
+	require.NoError(t, err)
+	assert.NoError(t, errs[0])
+	assert.True(t, IsConflict(errs[1]))
+	_, cached := cache.entries["a"]
+	assert.True(t, cached)
+	c, err := store.Get(ctx, "existing")
+	require.NoError(t, err)
+	assert.Equal(t, 3, c.Revision)
+
+	// Deletes invalidate every entry
+	_, err = store.DeleteBatch(ctx, []string{"a", "existing"}, true)
+	require.NoError(t, err)
+	assert.Empty(t, cache.entries)
+}
+
+// stubImportStore counts imports
+type stubImportStore struct{ imports int }
+
+func (s *stubImportStore) Import(_ context.Context, _ []*models.Agent, _ []*models.ContextRecord, _ models.ConflictPolicy) (*models.ImportSummary, error) {
+	s.imports++
+	return &models.ImportSummary{}, nil
+}
+
+func TestCachedImportStore(t *testing.T) {
+	ctx := context.Background()
+	agentCache, contextCache := newMapCache(), newMapCache()
+	agents := NewCachedAgentStore(&countingAgentStore{agents: make(map[string]*models.Agent)}, mapAgentCache{agentCache}, time.Minute, time.Second)
+	contexts := NewCachedContextStore(&countingContextStore{contexts: make(map[string]*models.Context)}, mapContextCache{contextCache}, time.Minute, time.Second)
+	backing := &stubImportStore{}
+	store := NewCachedImportStore(backing, agents, contexts)
+
+	// Cached records and cached misses are both invalidated
+	require.NoError(t, agentCache.store("planner", &models.Agent{ID: "planner"}, time.Minute))
+	require.NoError(t, contextCache.setMissing("ctx-1", time.Second))
+	_, err := store.Import(ctx,
+		[]*models.Agent{{ID: "planner"}},
+		[]*models.ContextRecord{{Context: &models.Context{ID: "ctx-1"}}},
+		models.ConflictOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, 1, backing.imports)
+	assert.Empty(t, agentCache.entries)
+	assert.Empty(t, contextCache.entries)
+}
+
+func TestCachedContextStore_CacheUnavailable(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingContextStore{contexts: map[string]*models.Context{
+		"ctx-1": {ID: "ctx-1", ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+	cache := newMapCache()
+	cache.err = errors.New("connection refused")
+	store := NewCachedContextStore(backing, mapContextCache{cache}, time.Minute, time.Second)
+
+	// Reads degrade to the store and failures are counted
+	c, err := store.Get(ctx, "ctx-1")
+	require.NoError(t, err)
+	assert.Equal(t, "ctx-1", c.ID)
+	assert.Equal(t, CacheStats{Misses: 1, Errors: 2}, store.Stats())
+}
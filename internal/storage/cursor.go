@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a page cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a keyset position in a list ordered newest first by created_at,
+// ties broken by descending id. A page starting after a cursor is stable
+// under concurrent inserts, unlike an offset.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// CursorAt returns the cursor positioned at a listed row
+func CursorAt(createdAt time.Time, id string) *Cursor {
+	return &Cursor{CreatedAt: createdAt, ID: id}
+}
+
+// Encode returns the opaque form of the cursor handed to API clients.
+// Timestamps keep the microsecond precision Postgres stores.
+func (c *Cursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + ":" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor produced by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return nil, ErrInvalidCursor
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: time.UnixMicro(usec).UTC(), ID: id}, nil
+}
+
+// keysetClause restricts a list ordered by created_at DESC, id DESC to the
+// rows after c, starting at placeholder argIndex. It returns the condition
+// prefixed with AND, its args and the next free placeholder.
+func keysetClause(c *Cursor, argIndex int) (string, []interface{}, int) {
+	if c == nil {
+		return "", nil, argIndex
+	}
+	clause := fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", argIndex, argIndex+1)
+	return clause, []interface{}{c.CreatedAt, c.ID}, argIndex + 2
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC)
+	encoded := CursorAt(created, "ctx:with:colons").Encode()
+
+	decoded, err := DecodeCursor(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, "ctx:with:colons", decoded.ID)
+	// Postgres keeps microseconds, so the cursor does too
+	assert.True(t, decoded.CreatedAt.Equal(created.Truncate(time.Microsecond)))
+
+	for _, bad := range []string{"not base64!", "bm8tc2VwYXJhdG9y", "eDpjdHgtMQ", "MTIzOg"} {
+		_, err := DecodeCursor(bad)
+		assert.ErrorIs(t, err, ErrInvalidCursor, bad)
+	}
+}
+
+func TestKeysetClause(t *testing.T) {
+	clause, args, next := keysetClause(nil, 3)
+	assert.Empty(t, clause)
+	assert.Empty(t, args)
+	assert.Equal(t, 3, next)
+
+	created := time.Unix(1700000000, 0)
+	clause, args, next = keysetClause(CursorAt(created, "agent-9"), 3)
+	assert.Equal(t, " AND (created_at, id) < ($3, $4)", clause)
+	assert.Equal(t, []interface{}{created, "agent-9"}, args)
+	assert.Equal(t, 5, next)
+}
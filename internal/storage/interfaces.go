@@ -5,27 +5,89 @@ import (
 	"time"
 
 	"github.com/acb/internal/models"
+	"github.com/acb/internal/selector"
 )
 
 // AgentStore defines the interface for agent storage operations
 type AgentStore interface {
 	Create(ctx context.Context, agent *models.Agent) error
+	// CreateBatch creates agents in one transaction. When atomic any failure
+	// rolls back the batch and is returned as a *BatchError; otherwise the
+	// error of each agent is returned, nil for those created.
+	CreateBatch(ctx context.Context, agents []*models.Agent, atomic bool) ([]error, error)
 	Get(ctx context.Context, agentID string) (*models.Agent, error)
 	Update(ctx context.Context, agent *models.Agent) error
+	// Delete and DeleteOffline mark agents deleted rather than removing
+	// them. Deleted agents are hidden from every method but those below
+	// until they are restored or purged. Create replaces a deleted agent of
+	// the same tenant.
 	Delete(ctx context.Context, agentID string) error
 	List(ctx context.Context, filters *AgentFilters) ([]*models.Agent, error)
+	// Count returns how many agents match filters, ignoring After, Limit
+	// and Offset
+	Count(ctx context.Context, filters *AgentFilters) (int, error)
 	UpdateLastSeen(ctx context.Context, agentID string) error
+	MarkOffline(ctx context.Context, lastSeenBefore time.Time) (int, error)
+	DeleteOffline(ctx context.Context, lastSeenBefore time.Time) (int, error)
+
+	// GetDeleted returns a deleted agent, ErrNotFound unless it is deleted
+	GetDeleted(ctx context.Context, agentID string) (*models.Agent, error)
+	// ListDeleted returns up to limit deleted agents of a tenant, most
+	// recently deleted first
+	ListDeleted(ctx context.Context, tenantID string, limit int) ([]*models.Agent, error)
+	// Restore clears the deletion of an agent, ErrNotFound unless it is
+	// deleted
+	Restore(ctx context.Context, agentID string) error
+	// PurgeDeleted permanently removes agents deleted before the given time
+	// and returns how many it removed
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
 }
 
 // AgentFilters contains filters for listing agents
 type AgentFilters struct {
-	Type       string
-	Location   string
-	Capability string
-	Status     models.AgentStatus
-	TenantID   string
-	Limit      int
-	Offset     int
+	Type            string
+	Location        string
+	Capability      string   // Single capability (shorthand for Capabilities)
+	Capabilities    []string // Capabilities to match according to CapabilityMatch
+	CapabilityMatch CapabilityMatch
+	Status          models.AgentStatus
+	TenantID        string
+	Selector        selector.Selector // Metadata label selector
+	OrderBy         AgentOrder
+	After           *Cursor // Only agents after this position; ignored by AgentOrderLiveness
+	Limit           int
+	Offset          int
+}
+
+// CapabilityMatch controls how capability filters are matched
+type CapabilityMatch string
+
+const (
+	CapabilityMatchAll CapabilityMatch = "all" // Agent must have every capability (default)
+	CapabilityMatchAny CapabilityMatch = "any" // Agent must have at least one capability
+)
+
+// AgentOrder controls the ordering of listed agents
+type AgentOrder string
+
+const (
+	AgentOrderCreated  AgentOrder = "created"  // Newest registrations first (default)
+	AgentOrderLiveness AgentOrder = "liveness" // Online agents first, then most recently seen
+)
+
+// CapabilityList returns the de-duplicated capabilities to match,
+// folding the single Capability field into the list
+func (f *AgentFilters) CapabilityList() []string {
+	seen := make(map[string]bool, len(f.Capabilities)+1)
+	var caps []string
+	for _, c := range append([]string{f.Capability}, f.Capabilities...) {
+		if c == "" || seen[c] {
+			continue
+		}
+		seen[c] = true
+		caps = append(caps, c)
+	}
+	return caps
 }
 
 // ContextStore defines the interface for context storage operations
@@ -35,28 +97,281 @@ type ContextStore interface {
 	Update(ctx context.Context, c *models.Context) error
 	Delete(ctx context.Context, contextID string) error
 	List(ctx context.Context, filters *ContextFilters) ([]*models.Context, error)
-	DeleteExpired(ctx context.Context) (int, error)
+	// Count returns how many contexts match filters, ignoring After, Limit
+	// and Offset
+	Count(ctx context.Context, filters *ContextFilters) (int, error)
+
+	// Search returns contexts whose payload or metadata match a full-text
+	// query, most relevant first, each with a highlighted snippet. filters
+	// apply as in List. Encrypted payloads and sensitive metadata are not
+	// searchable.
+	Search(ctx context.Context, query string, filters *ContextFilters) ([]*models.ContextSearchResult, error)
+
+	// DeleteExpired deletes up to limit contexts that expired before the
+	// given time, oldest first, and records a context.expired event for each
+	// in the same transaction. It returns the recorded events.
+	DeleteExpired(ctx context.Context, before time.Time, limit int) ([]*models.ContextEvent, error)
+	// Create, Update and DeleteAtRevision log context.created,
+	// context.updated and context.deleted events in the same transaction as
+	// the write. ListEvents returns logged events in ID order.
+	ListEvents(ctx context.Context, filters *EventFilters) ([]*models.ContextEvent, error)
+
+	// PruneEvents deletes up to limit events recorded before the given time,
+	// bounding how far back a consumer can resume
+	PruneEvents(ctx context.Context, before time.Time, limit int) (int, error)
+
+	// EventBounds returns the oldest retained event ID and the newest ID
+	// ListEvents can return, both zero when the log is empty
+	EventBounds(ctx context.Context) (first, last int64, err error)
+
+	// DeleteAtRevision deletes only if the context is at the given revision
+	// (0 means any) and returns ErrConflict otherwise. Update applies the same
+	// compare-and-swap using c.Revision. Update, DeleteAtRevision and
+	// DeleteBatch check the LeaseGuard carried by ctx, if any, in the same
+	// transaction as the write and fail with ErrLeased when it does not match.
+	DeleteAtRevision(ctx context.Context, contextID string, revision int) error
+
+	// Delete, DeleteAtRevision and DeleteBatch mark contexts deleted rather
+	// than removing them, keeping their revisions and lineage and dropping
+	// any lease. Deleted contexts are hidden from every method but those
+	// below until they are restored or purged. Create replaces a deleted
+	// context of the same tenant.
+	// GetDeleted returns a deleted context, ErrNotFound unless it is deleted.
+	GetDeleted(ctx context.Context, contextID string) (*models.Context, error)
+	// ListDeleted returns up to limit deleted contexts of a tenant, most
+	// recently deleted first
+	ListDeleted(ctx context.Context, tenantID string, limit int) ([]*models.Context, error)
+	// Restore clears the deletion of a context and logs a context.restored
+	// event in the same transaction. It returns ErrNotFound unless the
+	// context is deleted.
+	Restore(ctx context.Context, contextID string) error
+	// PurgeDeleted permanently removes up to limit contexts deleted before
+	// the given time, oldest deletion first, and returns how many it removed
+	PurgeDeleted(ctx context.Context, before time.Time, limit int) (int, error)
+
+	// CreateBatch and DeleteBatch write many contexts in one transaction,
+	// logging the same events as Create and Delete. When atomic any failure
+	// rolls back the batch and is returned as a *BatchError; otherwise the
+	// error of each item is returned, nil for those written.
+	CreateBatch(ctx context.Context, contexts []*models.Context, atomic bool) ([]error, error)
+	DeleteBatch(ctx context.Context, contextIDs []string, atomic bool) ([]error, error)
+
+	// Create and Update record an immutable revision of every write.
+	// ListRevisions returns them newest first.
+	ListRevisions(ctx context.Context, contextID string) ([]*models.ContextRevision, error)
+	GetRevision(ctx context.Context, contextID string, revision int) (*models.ContextRevision, error)
+	// PruneRevisions deletes the revisions of a context older than its keep
+	// newest and returns how many it deleted
+	PruneRevisions(ctx context.Context, contextID string, keep int) (int, error)
+
+	// Create and Update store c.DerivedFrom as lineage edges and fail with
+	// ErrCycle if a parent already derives from the context, which on create
+	// happens when its ID is that of a purged context.
+	// ListLinks returns the edges from the given contexts to their parents
+	// (LineageAncestors) or their children (LineageDescendants).
+	ListLinks(ctx context.Context, contextIDs []string, direction models.LineageDirection) ([]*models.LineageEdge, error)
+
+	// MarkStale flags every context derived from the given one, directly or
+	// transitively, as stale and logs a context.stale event for each that
+	// was not already. It returns the IDs of the newly stale contexts.
+	MarkStale(ctx context.Context, contextID string) ([]string, error)
+
+	// Touch moves the expiry of each context forward to the given time in
+	// one statement, leaving contexts that expired or already expire later
+	// untouched. It does not record a revision or event and returns how many
+	// contexts it extended.
+	Touch(ctx context.Context, expiries map[string]time.Time) (int, error)
 }
 
 // ContextFilters contains filters for listing contexts
 type ContextFilters struct {
-	Type     string
-	AgentID  string
-	TenantID string
-	Limit    int
-	Offset   int
+	Type      string
+	AgentID   string
+	TenantID  string
+	Selector  selector.Selector // Metadata label selector
+	VisibleTo string            // When set, only contexts this agent may read under their access control
+	IDs       []string          // When non-nil, only contexts with these IDs
+	After     *Cursor           // Only contexts after this position; ignored by Search
+	Limit     int
+	Offset    int
+}
+
+// EventFilters contains filters for listing context events
+type EventFilters struct {
+	TenantID    string
+	AfterID     int64                     // Only events with a greater ID
+	Types       []models.ContextEventType // Empty matches every type
+	ContextType string
+	AgentID     string            // Owner of the context
+	Selector    selector.Selector // Context metadata label selector
+	VisibleTo   string            // When set, only events for contexts this agent may read
+	Limit       int
+}
+
+// ImportStore writes the records of a tenant archive
+type ImportStore interface {
+	// Import writes agents and contexts, each context with its revision
+	// history, in one transaction. A record whose ID is already taken is
+	// kept, replaced or fails the import with ErrConflict according to
+	// policy; records of another tenant are never replaced. Every imported
+	// context logs a created event, or an updated event when it replaced one.
+	Import(ctx context.Context, agents []*models.Agent, contexts []*models.ContextRecord, policy models.ConflictPolicy) (*models.ImportSummary, error)
 }
 
-// AgentCache defines interface for agent caching
+// SchemaStore defines the interface for schema registry storage
+type SchemaStore interface {
+	// Create stores s as the next version of its context type and sets
+	// s.Version. When s.Version is already set the write is a compare-and-swap
+	// that fails with ErrConflict unless it is the next version.
+	Create(ctx context.Context, s *models.Schema) error
+	Get(ctx context.Context, schemaID string) (*models.Schema, error)
+	// GetLatest returns the highest version of a context type
+	GetLatest(ctx context.Context, tenantID, contextType string) (*models.Schema, error)
+	List(ctx context.Context, filters *SchemaFilters) ([]*models.Schema, error)
+	// Delete fails with ErrConflict while contexts still reference the schema
+	Delete(ctx context.Context, schemaID string) error
+
+	// GetCompatibility returns the configured mode of a context type, or ""
+	// when none has been set
+	GetCompatibility(ctx context.Context, tenantID, contextType string) (models.SchemaCompatibility, error)
+	SetCompatibility(ctx context.Context, tenantID, contextType string, mode models.SchemaCompatibility) error
+}
+
+// EmbeddingStore persists the embedding configuration of context types and
+// serves the embeddings stored with contexts
+type EmbeddingStore interface {
+	// GetConfig returns the embedding configuration of a context type,
+	// ErrNotFound when none has been declared
+	GetConfig(ctx context.Context, tenantID, contextType string) (*models.EmbeddingConfig, error)
+	// SetConfig declares the embedding configuration of a context type. It
+	// fails with ErrConflict if contexts of the type already carry embeddings
+	// of another dimension.
+	SetConfig(ctx context.Context, cfg *models.EmbeddingConfig) error
+	// List returns the embeddings of the contexts matching filters that have
+	// one. Limit and Offset are ignored.
+	List(ctx context.Context, filters *ContextFilters) ([]*models.Embedding, error)
+}
+
+// ExpirationStore persists the expiration defaults of context types
+type ExpirationStore interface {
+	// GetConfig returns the expiration defaults of a context type,
+	// ErrNotFound when none have been configured
+	GetConfig(ctx context.Context, tenantID, contextType string) (*models.ExpirationConfig, error)
+	SetConfig(ctx context.Context, cfg *models.ExpirationConfig) error
+}
+
+// LifecyclePolicyStore persists the lifecycle policies of context types
+type LifecyclePolicyStore interface {
+	// Get returns the policy of a context type, ErrNotFound when none has
+	// been set
+	Get(ctx context.Context, tenantID, contextType string) (*models.LifecyclePolicy, error)
+	// List returns the policies of a tenant ordered by context type
+	List(ctx context.Context, tenantID string) ([]*models.LifecyclePolicy, error)
+	// Set creates or replaces the policy of a context type
+	Set(ctx context.Context, p *models.LifecyclePolicy) error
+	// Delete removes the policy of a context type, ErrNotFound when none
+	// has been set
+	Delete(ctx context.Context, tenantID, contextType string) error
+}
+
+// LeaseStore persists exclusive leases on contexts. An expired lease is never
+// returned and may be taken over by the next acquisition. Fencing tokens must
+// increase with every lease granted, across restarts.
+type LeaseStore interface {
+	// Acquire takes a lease on l.ContextID for l.HolderID lasting l.TTL and
+	// sets its fencing token and times. It fails with ErrConflict while
+	// another unexpired lease is held.
+	Acquire(ctx context.Context, l *models.ContextLease) error
+	// Get returns the unexpired lease on a context, ErrNotFound when none is
+	// held
+	Get(ctx context.Context, contextID string) (*models.ContextLease, error)
+	// Renew extends the lease with the given fencing token to ttl from now.
+	// It fails with ErrNotFound once that lease has expired or been released.
+	Renew(ctx context.Context, contextID string, token int64, ttl time.Duration) (*models.ContextLease, error)
+	// Release ends the lease with the given fencing token, ErrNotFound when
+	// it is no longer held
+	Release(ctx context.Context, contextID string, token int64) error
+}
+
+// SchemaFilters contains filters for listing schemas
+type SchemaFilters struct {
+	TenantID    string
+	ContextType string
+	Limit       int
+	Offset      int
+}
+
+// DataKeyStore persists wrapped tenant data keys. Keys are never deleted,
+// since rows encrypted with a retired key stay readable until rewritten.
+type DataKeyStore interface {
+	// Create stores k as the tenant's active key, retiring the previous one.
+	// It fails with ErrConflict if another key was activated concurrently.
+	Create(ctx context.Context, k *models.DataKey) error
+	Get(ctx context.Context, keyID string) (*models.DataKey, error)
+	GetActive(ctx context.Context, tenantID string) (*models.DataKey, error)
+	List(ctx context.Context) ([]*models.DataKey, error)
+	// Rewrap replaces the wrapping of a key, failing with ErrConflict unless
+	// it is still wrapped with oldMasterKeyID
+	Rewrap(ctx context.Context, keyID, oldMasterKeyID, masterKeyID string, wrapped []byte) error
+}
+
+// PayloadCipher encrypts context payloads and sensitive metadata at rest
+// with per-tenant data keys
+type PayloadCipher interface {
+	// ActiveKey returns the ID of the key new data of a tenant is sealed with
+	ActiveKey(ctx context.Context, tenantID string) (string, error)
+	Seal(ctx context.Context, keyID string, plaintext, aad []byte) ([]byte, error)
+	Open(ctx context.Context, keyID string, ciphertext, aad []byte) ([]byte, error)
+}
+
+// AgentCache defines interface for agent caching. Get returns ErrCacheMiss
+// when nothing is cached and ErrNotFound when the agent is cached as missing.
 type AgentCache interface {
 	Get(ctx context.Context, agentID string) (*models.Agent, error)
 	Set(ctx context.Context, agent *models.Agent, ttl time.Duration) error
+	// SetMissing caches that the agent does not exist, unless an entry for
+	// it was written in the meantime
+	SetMissing(ctx context.Context, agentID string, ttl time.Duration) error
 	Delete(ctx context.Context, agentID string) error
 }
 
-// ContextCache defines interface for context caching
+// ContextCache defines interface for context caching. Get returns
+// ErrCacheMiss when nothing is cached and ErrNotFound when the context is
+// cached as missing.
 type ContextCache interface {
 	Get(ctx context.Context, contextID string) (*models.Context, error)
 	Set(ctx context.Context, c *models.Context, ttl time.Duration) error
-	Delete(ctx context.Context, contextID string) error
+	// Fill caches a context read from the store, unless an entry for it was
+	// written in the meantime, including the guard left by Invalidate
+	Fill(ctx context.Context, c *models.Context, ttl time.Duration) error
+	// SetMissing caches that the context does not exist, unless an entry for
+	// it was written in the meantime
+	SetMissing(ctx context.Context, contextID string, ttl time.Duration) error
+	// Invalidate replaces the entry of a context with a guard that lookups
+	// treat as a miss and that stops Fill and SetMissing for ttl, so a read
+	// that raced with the write cannot cache what it read before it
+	Invalidate(ctx context.Context, contextID string, ttl time.Duration) error
+}
+
+// EventSignal names what context events may have been logged for. An empty
+// ContextID covers every context of the tenant, and an empty TenantID every
+// tenant.
+type EventSignal struct {
+	TenantID  string `json:"tenant_id"`
+	ContextID string `json:"context_id"`
+}
+
+// EventNotifier signals that context events may have been logged by any
+// replica sharing the store
+type EventNotifier interface {
+	// Listen calls notify after each signal until ctx is cancelled or the
+	// connection fails
+	Listen(ctx context.Context, notify func(EventSignal)) error
+}
+
+// Locker provides cluster-wide mutual exclusion for background jobs
+type Locker interface {
+	// TryLock attempts to take the named lock without blocking. When acquired
+	// is true the caller must call unlock once the work is done.
+	TryLock(ctx context.Context, name string) (unlock func(), acquired bool, err error)
 }
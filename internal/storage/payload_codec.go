@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/acb/internal/constants"
+	"github.com/acb/internal/models"
+)
+
+// PayloadEncoding records how a payload is stored
+type PayloadEncoding string
+
+const (
+	PayloadEncodingIdentity PayloadEncoding = "identity" // Stored as-is
+	PayloadEncodingGzip     PayloadEncoding = "gzip"     // Stored gzip-compressed
+)
+
+// PayloadCodec compresses payloads before they are stored. Only the stored
+// bytes are encoded: checksums and size limits apply to the payload as the
+// caller sees it.
+type PayloadCodec struct {
+	// Threshold is the smallest payload, in bytes, worth compressing. Zero
+	// disables compression.
+	Threshold int
+	// Level is the gzip compression level
+	Level int
+}
+
+// DefaultPayloadCodec returns the codec used unless one is configured
+func DefaultPayloadCodec() PayloadCodec {
+	return PayloadCodec{
+		Threshold: constants.PayloadCompressionThreshold,
+		Level:     gzip.DefaultCompression,
+	}
+}
+
+// Validate checks the codec configuration
+func (c PayloadCodec) Validate() error {
+	if c.Threshold < 0 {
+		return fmt.Errorf("payload compression threshold must not be negative")
+	}
+	if c.Level < gzip.HuffmanOnly || c.Level > gzip.BestCompression {
+		return fmt.Errorf("invalid gzip compression level %d", c.Level)
+	}
+	return nil
+}
+
+// Encode returns payload as it should be stored. Payloads below the
+// threshold, or that do not shrink, are stored as-is.
+func (c PayloadCodec) Encode(payload []byte) ([]byte, PayloadEncoding, error) {
+	if c.Threshold == 0 || len(payload) < c.Threshold {
+		return payload, PayloadEncodingIdentity, nil
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, c.Level)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return nil, "", fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if buf.Len() >= len(payload) {
+		return payload, PayloadEncodingIdentity, nil
+	}
+	return buf.Bytes(), PayloadEncodingGzip, nil
+}
+
+// decodePayload reverses Encode. Decoded payloads are bounded by the direct
+// context size limit, so a corrupt row cannot expand without limit.
+func decodePayload(data []byte, encoding PayloadEncoding) ([]byte, error) {
+	switch encoding {
+	case PayloadEncodingIdentity, "":
+		return data, nil
+	case PayloadEncodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress payload: %w", err)
+		}
+		defer r.Close()
+		payload, err := io.ReadAll(io.LimitReader(r, models.MaxDirectContextSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress payload: %w", err)
+		}
+		if len(payload) > models.MaxDirectContextSize {
+			return nil, fmt.Errorf("%w: decompressed payload exceeds max %d", models.ErrPayloadTooLarge, models.MaxDirectContextSize)
+		}
+		return payload, nil
+	default:
+		return nil, fmt.Errorf("unknown payload encoding %q", encoding)
+	}
+}
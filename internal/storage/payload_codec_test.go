@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/acb/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadCodec_RoundTrip(t *testing.T) {
+	codec := PayloadCodec{Threshold: 64, Level: gzip.DefaultCompression}
+
+	tests := []struct {
+		name     string
+		payload  []byte
+		encoding PayloadEncoding
+	}{
+		{"below threshold", []byte(`{"a":1}`), PayloadEncodingIdentity},
+		{"compressible", []byte(strings.Repeat(`{"key":"value"},`, 100)), PayloadEncodingGzip},
+		{"incompressible", incompressible(256), PayloadEncodingIdentity},
+		{"empty", nil, PayloadEncodingIdentity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, encoding, err := codec.Encode(tt.payload)
+			require.NoError(t, err)
+			assert.Equal(t, tt.encoding, encoding)
+			if encoding == PayloadEncodingGzip {
+				assert.Less(t, len(data), len(tt.payload))
+			}
+
+			decoded, err := decodePayload(data, encoding)
+			require.NoError(t, err)
+			assert.Equal(t, tt.payload, decoded)
+		})
+	}
+}
+
+func TestPayloadCodec_Disabled(t *testing.T) {
+	payload := []byte(strings.Repeat("x", 4096))
+	data, encoding, err := PayloadCodec{}.Encode(payload)
+	require.NoError(t, err)
+	assert.Equal(t, PayloadEncodingIdentity, encoding)
+	assert.Equal(t, payload, data)
+}
+
+func TestPayloadCodec_Validate(t *testing.T) {
+	assert.NoError(t, DefaultPayloadCodec().Validate())
+	assert.Error(t, PayloadCodec{Threshold: -1}.Validate())
+	assert.Error(t, PayloadCodec{Threshold: 1024, Level: 10}.Validate())
+}
+
+func TestDecodePayload_Errors(t *testing.T) {
+	_, err := decodePayload([]byte("data"), "brotli")
+	assert.Error(t, err)
+
+	_, err = decodePayload([]byte("not gzip"), PayloadEncodingGzip)
+	assert.Error(t, err)
+
+	// A stored payload never decodes past the direct context limit
+	bomb, encoding, err := PayloadCodec{Threshold: 1, Level: gzip.BestCompression}.Encode(make([]byte, models.MaxDirectContextSize+1))
+	require.NoError(t, err)
+	_, err = decodePayload(bomb, encoding)
+	assert.ErrorIs(t, err, models.ErrPayloadTooLarge)
+}
+
+// incompressible returns n bytes that gzip cannot shrink
+func incompressible(n int) []byte {
+	var buf bytes.Buffer
+	x := uint32(2463534242)
+	for buf.Len() < n {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		buf.WriteByte(byte(x))
+	}
+	return buf.Bytes()
+}
@@ -11,6 +11,22 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrNotFound is returned (wrapped) when a requested row does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned (wrapped) when a write conflicts with the current
+// state of a row, such as a compare-and-swap at a stale revision or deleting
+// a row that is still referenced
+var ErrConflict = errors.New("conflict")
+
+// ErrLeased is returned (wrapped) when a write carries a lease guard that the
+// lease currently held on the context does not match
+var ErrLeased = errors.New("leased")
+
+// ErrCycle is returned (wrapped) when a write would make a context derive
+// from itself through its lineage
+var ErrCycle = errors.New("lineage cycle")
+
 // PostgresStore wraps PostgreSQL connection pool
 type PostgresStore struct {
 	pool *pgxpool.Pool
@@ -57,10 +73,35 @@ func IsUniqueViolation(err error) bool {
 	return false
 }
 
+// isForeignKeyViolation checks if error is a foreign key violation
+func isForeignKeyViolation(err error) bool {
+	if pgErr, ok := err.(*pgconn.PgError); ok {
+		return pgErr.Code == "23503" // foreign_key_violation
+	}
+	return false
+}
+
+// isDeadlock checks if error is a deadlock Postgres broke by aborting the
+// transaction
+func isDeadlock(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40P01" // deadlock_detected
+}
+
 // IsNotFound checks if error indicates not found
 func IsNotFound(err error) bool {
 	if pgErr, ok := err.(*pgconn.PgError); ok {
 		return pgErr.Code == "02000" // no_data_found
 	}
-	return errors.Is(err, pgx.ErrNoRows)
+	return errors.Is(err, pgx.ErrNoRows) || errors.Is(err, ErrNotFound)
+}
+
+// IsConflict checks if error indicates a compare-and-swap conflict
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsLeased checks if error indicates a write stopped by a lease
+func IsLeased(err error) bool {
+	return errors.Is(err, ErrLeased)
 }
@@ -11,6 +11,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// agentColumns is the column list scanned by scanAgent
+const agentColumns = `id, type, location, capabilities, metadata, status, tenant_id, created_at, last_seen, deleted_at`
+
 // PostgresAgentStore implements AgentStore using PostgreSQL
 type PostgresAgentStore struct {
 	pool *pgxpool.Pool
@@ -23,6 +26,20 @@ func NewPostgresAgentStore(pool *pgxpool.Pool) *PostgresAgentStore {
 
 // Create inserts a new agent
 func (s *PostgresAgentStore) Create(ctx context.Context, agent *models.Agent) error {
+	return insertAgent(ctx, s.pool, agent)
+}
+
+// CreateBatch registers agents in one transaction. Errors are reported as by
+// PostgresContextStore.CreateBatch.
+func (s *PostgresAgentStore) CreateBatch(ctx context.Context, agents []*models.Agent, atomic bool) ([]error, error) {
+	return writeBatch(ctx, s.pool, len(agents), atomic, func(tx pgx.Tx, i int) error {
+		return insertAgent(ctx, tx, agents[i])
+	})
+}
+
+// insertAgent inserts an agent through the pool or a transaction, replacing
+// a deleted agent of the same tenant with the same ID
+func insertAgent(ctx context.Context, db execer, agent *models.Agent) error {
 	if err := agent.Validate(); err != nil {
 		return err
 	}
@@ -38,9 +55,14 @@ func (s *PostgresAgentStore) Create(ctx context.Context, agent *models.Agent) er
 	query := `
 		INSERT INTO agents (id, type, location, capabilities, metadata, status, tenant_id, created_at, last_seen)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE
+		SET type = EXCLUDED.type, location = EXCLUDED.location, capabilities = EXCLUDED.capabilities,
+			metadata = EXCLUDED.metadata, status = EXCLUDED.status, created_at = EXCLUDED.created_at,
+			last_seen = EXCLUDED.last_seen, deleted_at = NULL
+		WHERE agents.deleted_at IS NOT NULL AND agents.tenant_id = EXCLUDED.tenant_id
 	`
 
-	_, err := s.pool.Exec(ctx, query,
+	result, err := db.Exec(ctx, query,
 		agent.ID,
 		agent.Type,
 		agent.Location,
@@ -51,50 +73,27 @@ func (s *PostgresAgentStore) Create(ctx context.Context, agent *models.Agent) er
 		agent.CreatedAt,
 		agent.LastSeen,
 	)
-
-	if IsUniqueViolation(err) {
-		return fmt.Errorf("agent with ID %s already exists", agent.ID)
+	if err != nil {
+		return err
 	}
-	return err
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("agent with ID %s already exists: %w", agent.ID, ErrConflict)
+	}
+	return nil
 }
 
 // Get retrieves an agent by ID
 func (s *PostgresAgentStore) Get(ctx context.Context, agentID string) (*models.Agent, error) {
-	query := `
-		SELECT id, type, location, capabilities, metadata, status, tenant_id, created_at, last_seen
-		FROM agents
-		WHERE id = $1
-	`
-
-	var agent models.Agent
-	var capabilities []string
-	var metadataJSON []byte
-	var statusStr string
-
-	err := s.pool.QueryRow(ctx, query, agentID).Scan(
-		&agent.ID,
-		&agent.Type,
-		&agent.Location,
-		&capabilities,
-		&metadataJSON,
-		&statusStr,
-		&agent.TenantID,
-		&agent.CreatedAt,
-		&agent.LastSeen,
-	)
+	query := `SELECT ` + agentColumns + ` FROM agents WHERE id = $1 AND deleted_at IS NULL`
 
+	agent, err := scanAgent(s.pool.QueryRow(ctx, query, agentID))
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("agent not found: %w", err)
+		return nil, fmt.Errorf("agent not found: %w", ErrNotFound)
 	}
 	if err != nil {
 		return nil, err
 	}
-
-	agent.Status = models.AgentStatus(statusStr)
-	agent.Capabilities = capabilities
-	_ = json.Unmarshal(metadataJSON, &agent.Metadata)
-
-	return &agent, nil
+	return agent, nil
 }
 
 // Update updates an agent
@@ -114,7 +113,7 @@ func (s *PostgresAgentStore) Update(ctx context.Context, agent *models.Agent) er
 	query := `
 		UPDATE agents
 		SET type = $2, location = $3, capabilities = $4, metadata = $5, status = $6, last_seen = $7
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	result, err := s.pool.Exec(ctx, query,
@@ -128,34 +127,233 @@ func (s *PostgresAgentStore) Update(ctx context.Context, agent *models.Agent) er
 	)
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("agent not found")
+		return fmt.Errorf("agent not found: %w", ErrNotFound)
 	}
 	return err
 }
 
-// Delete removes an agent
+// Delete marks an agent deleted
 func (s *PostgresAgentStore) Delete(ctx context.Context, agentID string) error {
-	query := `DELETE FROM agents WHERE id = $1`
+	query := `UPDATE agents SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
 	result, err := s.pool.Exec(ctx, query, agentID)
 	if err != nil {
 		return err
 	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("agent not found")
+		return fmt.Errorf("agent not found: %w", ErrNotFound)
 	}
 	return nil
 }
 
+// Count returns the number of agents matching filters, ignoring the page
+func (s *PostgresAgentStore) Count(ctx context.Context, filters *AgentFilters) (int, error) {
+	query, args := buildAgentCountQuery(filters)
+	var count int
+	if err := s.pool.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 // List retrieves agents with filters
 func (s *PostgresAgentStore) List(ctx context.Context, filters *AgentFilters) ([]*models.Agent, error) {
-	query := `SELECT id, type, location, capabilities, metadata, status, tenant_id, created_at, last_seen FROM agents WHERE 1=1`
-	args := []interface{}{}
-	argIndex := 1
+	query, args := buildAgentListQuery(filters)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return collectAgents(rows)
+}
 
+// UpdateLastSeen updates the agent's last seen timestamp
+func (s *PostgresAgentStore) UpdateLastSeen(ctx context.Context, agentID string) error {
+	query := `
+		UPDATE agents
+		SET last_seen = $1, status = 'online'
+		WHERE id = $2 AND deleted_at IS NULL
+	`
+	result, err := s.pool.Exec(ctx, query, time.Now(), agentID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("agent not found: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// MarkOffline marks agents whose last heartbeat is older than lastSeenBefore as offline
+func (s *PostgresAgentStore) MarkOffline(ctx context.Context, lastSeenBefore time.Time) (int, error) {
+	query := `
+		UPDATE agents
+		SET status = 'offline'
+		WHERE status <> 'offline' AND last_seen < $1 AND deleted_at IS NULL
+	`
+	result, err := s.pool.Exec(ctx, query, lastSeenBefore)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// DeleteOffline marks offline agents whose last heartbeat is older than
+// lastSeenBefore deleted
+func (s *PostgresAgentStore) DeleteOffline(ctx context.Context, lastSeenBefore time.Time) (int, error) {
+	query := `UPDATE agents SET deleted_at = NOW() WHERE status = 'offline' AND last_seen < $1 AND deleted_at IS NULL`
+	result, err := s.pool.Exec(ctx, query, lastSeenBefore)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// GetDeleted retrieves a deleted agent by ID
+func (s *PostgresAgentStore) GetDeleted(ctx context.Context, agentID string) (*models.Agent, error) {
+	query := `SELECT ` + agentColumns + ` FROM agents WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	agent, err := scanAgent(s.pool.QueryRow(ctx, query, agentID))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("deleted agent not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return agent, nil
+}
+
+// ListDeleted retrieves the deleted agents of a tenant, most recently deleted
+// first
+func (s *PostgresAgentStore) ListDeleted(ctx context.Context, tenantID string, limit int) ([]*models.Agent, error) {
+	query := `SELECT ` + agentColumns + ` FROM agents
+		WHERE tenant_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := s.pool.Query(ctx, query, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectAgents(rows)
+}
+
+// Restore clears the deletion of an agent
+func (s *PostgresAgentStore) Restore(ctx context.Context, agentID string) error {
+	query := `UPDATE agents SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+	result, err := s.pool.Exec(ctx, query, agentID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("deleted agent not found: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes agents deleted before the given time
+func (s *PostgresAgentStore) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM agents WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	result, err := s.pool.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// scanAgent scans a row selected with agentColumns
+func scanAgent(row rowScanner) (*models.Agent, error) {
+	var agent models.Agent
+	var capabilities []string
+	var metadataJSON []byte
+	var statusStr string
+
+	err := row.Scan(
+		&agent.ID,
+		&agent.Type,
+		&agent.Location,
+		&capabilities,
+		&metadataJSON,
+		&statusStr,
+		&agent.TenantID,
+		&agent.CreatedAt,
+		&agent.LastSeen,
+		&agent.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	agent.Status = models.AgentStatus(statusStr)
+	agent.Capabilities = capabilities
+	_ = json.Unmarshal(metadataJSON, &agent.Metadata)
+	return &agent, nil
+}
+
+// collectAgents scans and closes rows selected with agentColumns
+func collectAgents(rows pgx.Rows) ([]*models.Agent, error) {
+	defer rows.Close()
+
+	var agents []*models.Agent
+	for rows.Next() {
+		agent, err := scanAgent(rows)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, agent)
+	}
+	return agents, rows.Err()
+}
+
+// buildAgentListQuery builds the parameterised SELECT used by List
+func buildAgentListQuery(filters *AgentFilters) (string, []interface{}) {
 	if filters == nil {
 		filters = &AgentFilters{Limit: 100}
 	}
 
+	where, args, argIndex := agentFilterClause(filters, 1)
+	query := `SELECT ` + agentColumns + ` FROM agents WHERE deleted_at IS NULL` + where
+
+	if filters.OrderBy == AgentOrderLiveness {
+		query += " ORDER BY (status = 'online') DESC, last_seen DESC, created_at DESC, id DESC"
+	} else {
+		keyset, keysetArgs, next := keysetClause(filters.After, argIndex)
+		query += keyset + " ORDER BY created_at DESC, id DESC"
+		args = append(args, keysetArgs...)
+		argIndex = next
+	}
+
+	if filters.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argIndex)
+		args = append(args, filters.Limit)
+		argIndex++
+	}
+
+	if filters.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argIndex)
+		args = append(args, filters.Offset)
+	}
+
+	return query, args
+}
+
+// buildAgentCountQuery builds the parameterised COUNT used by Count
+func buildAgentCountQuery(filters *AgentFilters) (string, []interface{}) {
+	if filters == nil {
+		filters = &AgentFilters{}
+	}
+	where, args, _ := agentFilterClause(filters, 1)
+	return `SELECT COUNT(*) FROM agents WHERE deleted_at IS NULL` + where, args
+}
+
+// agentFilterClause builds the conditions List and Count share, starting at
+// placeholder argIndex. It returns the conditions, each prefixed with AND,
+// their args and the next free placeholder.
+func agentFilterClause(filters *AgentFilters, argIndex int) (string, []interface{}, int) {
+	query := ""
+	args := []interface{}{}
+
 	if filters.TenantID != "" {
 		query += fmt.Sprintf(" AND tenant_id = $%d", argIndex)
 		args = append(args, filters.TenantID)
@@ -180,69 +378,23 @@ func (s *PostgresAgentStore) List(ctx context.Context, filters *AgentFilters) ([
 		argIndex++
 	}
 
-	query += " ORDER BY created_at DESC"
-
-	if filters.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argIndex)
-		args = append(args, filters.Limit)
+	// Array containment (@>) and overlap (&&) are served by the GIN index on capabilities
+	if caps := filters.CapabilityList(); len(caps) > 0 {
+		operator := "@>"
+		if filters.CapabilityMatch == CapabilityMatchAny {
+			operator = "&&"
+		}
+		query += fmt.Sprintf(" AND capabilities %s $%d::text[]", operator, argIndex)
+		args = append(args, caps)
 		argIndex++
 	}
 
-	if filters.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argIndex)
-		args = append(args, filters.Offset)
-	}
-
-	rows, err := s.pool.Query(ctx, query, args...)
-	if err != nil {
-		return nil, err
+	if !filters.Selector.Empty() {
+		clause, selectorArgs, next := filters.Selector.SQL("metadata", argIndex)
+		query += " AND " + clause
+		args = append(args, selectorArgs...)
+		argIndex = next
 	}
-	defer rows.Close()
 
-	var agents []*models.Agent
-	for rows.Next() {
-		var agent models.Agent
-		var capabilities []string
-		var metadataJSON []byte
-		var statusStr string
-
-		err := rows.Scan(
-			&agent.ID,
-			&agent.Type,
-			&agent.Location,
-			&capabilities,
-			&metadataJSON,
-			&statusStr,
-			&agent.TenantID,
-			&agent.CreatedAt,
-			&agent.LastSeen,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		agent.Status = models.AgentStatus(statusStr)
-		agent.Capabilities = capabilities
-		_ = json.Unmarshal(metadataJSON, &agent.Metadata)
-		agents = append(agents, &agent)
-	}
-
-	return agents, rows.Err()
-}
-
-// UpdateLastSeen updates the agent's last seen timestamp
-func (s *PostgresAgentStore) UpdateLastSeen(ctx context.Context, agentID string) error {
-	query := `
-		UPDATE agents
-		SET last_seen = $1, status = 'online'
-		WHERE id = $2
-	`
-	result, err := s.pool.Exec(ctx, query, time.Now(), agentID)
-	if err != nil {
-		return err
-	}
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("agent not found")
-	}
-	return nil
+	return query, args, argIndex
 }
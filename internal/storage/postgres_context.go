@@ -12,29 +12,122 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// contextColumns is the column list scanned by scanContext
+const contextColumns = `id, type, agent_id, tenant_id, payload, payload_encoding, payload_key_id, encrypted_metadata, payload_ref, metadata, version, schema_id, ttl_seconds, access_control, created_at, expires_at, checksum, revision, updated_by, embedding, derived_from, stale, expiration_policy, max_lifetime_seconds, deleted_at`
+
+// eventColumns is the column list scanned by scanEvent
+const eventColumns = `id, type, tenant_id, context_id, context_type, agent_id, revision, metadata, access_control, occurred_at`
+
+// committedEvents restricts the event log to rows written before the oldest
+// transaction still in flight. IDs are allocated before commit, so without it
+// a consumer could advance past an ID whose transaction commits later. The
+// cost is that any long-running transaction on the database, not only event
+// writers, holds back every consumer until it ends; deployments should bound
+// them with idle_in_transaction_session_timeout and statement_timeout.
+const committedEvents = `txid < pg_snapshot_xmin(pg_current_snapshot())`
+
+// revisionColumns is the column list scanned by scanRevision
+const revisionColumns = `context_id, revision, payload, payload_encoding, payload_key_id, encrypted_metadata, payload_ref, metadata, version, access_control, checksum, author_id, created_at`
+
+// rowScanner is satisfied by pgx.Row and pgx.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 // PostgresContextStore implements ContextStore using PostgreSQL
 type PostgresContextStore struct {
-	pool *pgxpool.Pool
+	pool      *pgxpool.Pool
+	codec     PayloadCodec
+	cipher    PayloadCipher
+	sensitive map[string]bool // Metadata keys encrypted along with the payload
+}
+
+// PostgresContextOption configures a PostgresContextStore
+type PostgresContextOption func(*PostgresContextStore)
+
+// WithPayloadCodec sets how payloads are compressed when stored. Payloads
+// already stored are read whatever codec wrote them.
+func WithPayloadCodec(codec PayloadCodec) PostgresContextOption {
+	return func(s *PostgresContextStore) {
+		s.codec = codec
+	}
+}
+
+// WithEncryption encrypts payloads at rest with cipher, along with the values
+// of the sensitiveMetadata keys. Sensitive metadata is not stored in the
+// clear, so label selectors and event filters cannot match on it. Rows
+// written before encryption was enabled stay readable and are encrypted when
+// next written.
+func WithEncryption(cipher PayloadCipher, sensitiveMetadata ...string) PostgresContextOption {
+	return func(s *PostgresContextStore) {
+		s.cipher = cipher
+		s.sensitive = make(map[string]bool, len(sensitiveMetadata))
+		for _, key := range sensitiveMetadata {
+			s.sensitive[key] = true
+		}
+	}
 }
 
 // NewPostgresContextStore creates a new PostgreSQL context store
-func NewPostgresContextStore(pool *pgxpool.Pool) *PostgresContextStore {
-	return &PostgresContextStore{pool: pool}
+func NewPostgresContextStore(pool *pgxpool.Pool, opts ...PostgresContextOption) *PostgresContextStore {
+	s := &PostgresContextStore{pool: pool, codec: DefaultPayloadCodec()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// Create inserts a new context
+// Create inserts a new context together with its first revision
 func (s *PostgresContextStore) Create(ctx context.Context, c *models.Context) error {
-	if err := c.Validate(); err != nil {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return s.insert(ctx, tx, c)
+	})
+}
+
+// CreateBatch inserts contexts in one transaction. When atomic any failure
+// rolls back the batch and is returned as a *BatchError; otherwise the error
+// of each context is returned, nil for those created.
+func (s *PostgresContextStore) CreateBatch(ctx context.Context, contexts []*models.Context, atomic bool) ([]error, error) {
+	return writeBatch(ctx, s.pool, len(contexts), atomic, func(tx pgx.Tx, i int) error {
+		return s.insert(ctx, tx, contexts[i])
+	})
+}
+
+// insert writes c, its lineage edges, first revision and created event in tx.
+// A deleted context of the same tenant with the same ID is purged first.
+func (s *PostgresContextStore) insert(ctx context.Context, tx pgx.Tx, c *models.Context) error {
+	c.Revision = 1
+	if _, err := tx.Exec(ctx, `DELETE FROM contexts WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NOT NULL`, c.ID, c.TenantID); err != nil {
+		return err
+	}
+	payload, err := s.insertRow(ctx, tx, c)
+	if err != nil {
+		return err
+	}
+	if err := insertRevision(ctx, tx, c, payload, c.CreatedAt); err != nil {
 		return err
 	}
+	return insertEvent(ctx, tx, models.ContextEventCreated, c, payload.metadata)
+}
+
+// insertRow validates c and writes its row at revision c.Revision, with its
+// lineage edges, in tx. It returns the payload as stored.
+func (s *PostgresContextStore) insertRow(ctx context.Context, tx pgx.Tx, c *models.Context) (storedPayload, error) {
+	if err := c.Validate(); err != nil {
+		return storedPayload{}, err
+	}
 
-	metadataJSON, _ := json.Marshal(c.Metadata)
+	payload, err := s.sealPayload(ctx, c)
+	if err != nil {
+		return storedPayload{}, err
+	}
+	metadataJSON, _ := json.Marshal(payload.metadata)
 	accessControlJSON, _ := json.Marshal(c.AccessControl)
 	payloadRefJSON, _ := json.Marshal(c.PayloadRef)
 
 	query := `
-		INSERT INTO contexts (id, type, agent_id, tenant_id, payload, payload_ref, metadata, version, schema_id, ttl_seconds, access_control, created_at, expires_at, checksum)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		INSERT INTO contexts (id, type, agent_id, tenant_id, payload, payload_encoding, payload_key_id, encrypted_metadata, payload_ref, metadata, version, schema_id, ttl_seconds, access_control, created_at, expires_at, checksum, revision, updated_by, search_vector, embedding, derived_from, stale, expiration_policy, max_lifetime_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, context_search_vector($10::jsonb, $20), $21, $22, $23, $24, $25)
 	`
 
 	ttlSeconds := int(c.TTL.Seconds())
@@ -47,12 +140,19 @@ func (s *PostgresContextStore) Create(ctx context.Context, c *models.Context) er
 		expiresAt = c.CalculateExpiration()
 	}
 
-	_, err := s.pool.Exec(ctx, query,
+	if c.UpdatedBy == "" {
+		c.UpdatedBy = c.AgentID
+	}
+
+	_, err = tx.Exec(ctx, query,
 		c.ID,
 		c.Type,
 		c.AgentID,
 		c.TenantID,
-		c.Payload,
+		payload.data,
+		string(payload.encoding),
+		payload.keyID,
+		payload.encryptedMetadata,
 		payloadRefJSON,
 		metadataJSON,
 		c.Version,
@@ -62,35 +162,598 @@ func (s *PostgresContextStore) Create(ctx context.Context, c *models.Context) er
 		c.CreatedAt,
 		expiresAt,
 		c.Checksum,
+		c.Revision,
+		c.UpdatedBy,
+		s.searchText(c.Payload),
+		c.Embedding,
+		linksJSON(c.DerivedFrom),
+		c.Stale,
+		expirationPolicy(c.Expiration),
+		int(c.MaxLifetime.Seconds()),
 	)
-
 	if IsUniqueViolation(err) {
-		return fmt.Errorf("context with ID %s already exists", c.ID)
+		return storedPayload{}, fmt.Errorf("context with ID %s already exists: %w", c.ID, ErrConflict)
 	}
-	return err
+	if err != nil {
+		return storedPayload{}, err
+	}
+	if err := insertLinks(ctx, tx, c); err != nil {
+		return storedPayload{}, err
+	}
+	return payload, nil
 }
 
 // Get retrieves a context by ID
 func (s *PostgresContextStore) Get(ctx context.Context, contextID string) (*models.Context, error) {
+	query := `SELECT ` + contextColumns + ` FROM contexts WHERE id = $1 AND deleted_at IS NULL`
+
+	c, err := s.scanContext(ctx, s.pool.QueryRow(ctx, query, contextID))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("context not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update updates a context and records the result as a new revision.
+// When c.Revision is non-zero the write is a compare-and-swap: it fails with
+// ErrConflict unless the stored context is still at that revision. On success
+// c.Revision holds the new revision number.
+func (s *PostgresContextStore) Update(ctx context.Context, c *models.Context) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	payload, err := s.sealPayload(ctx, c)
+	if err != nil {
+		return err
+	}
+	metadataJSON, _ := json.Marshal(payload.metadata)
+	accessControlJSON, _ := json.Marshal(c.AccessControl)
+	payloadRefJSON, _ := json.Marshal(c.PayloadRef)
+
+	ttlSeconds := int(c.TTL.Seconds())
+	if ttlSeconds == 0 {
+		ttlSeconds = 86400
+	}
+
+	expiresAt := c.ExpiresAt
+	if expiresAt.IsZero() && c.TTL > 0 {
+		expiresAt = c.CalculateExpiration()
+	}
+
+	if c.UpdatedBy == "" {
+		c.UpdatedBy = c.AgentID
+	}
+
 	query := `
-		SELECT id, type, agent_id, tenant_id, payload, payload_ref, metadata, version, schema_id, ttl_seconds, access_control, created_at, expires_at, checksum
-		FROM contexts
-		WHERE id = $1
+		UPDATE contexts
+		SET payload = $2, payload_ref = $3, metadata = $4, version = $5, ttl_seconds = $6, access_control = $7, expires_at = $8, checksum = $9,
+			revision = revision + 1, updated_by = $10, schema_id = $12, payload_encoding = $13,
+			payload_key_id = $14, encrypted_metadata = $15, search_vector = context_search_vector($4::jsonb, $16),
+			embedding = $17, derived_from = $18, stale = $19, expiration_policy = $20, max_lifetime_seconds = $21
+		WHERE id = $1 AND deleted_at IS NULL AND ($11::int = 0 OR revision = $11)
+		RETURNING revision
 	`
 
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if err := checkLeaseGuard(ctx, tx, c.ID); err != nil {
+			return err
+		}
+		var revision int
+		err := tx.QueryRow(ctx, query,
+			c.ID,
+			payload.data,
+			payloadRefJSON,
+			metadataJSON,
+			c.Version,
+			ttlSeconds,
+			accessControlJSON,
+			expiresAt,
+			c.Checksum,
+			c.UpdatedBy,
+			c.Revision,
+			c.SchemaID,
+			string(payload.encoding),
+			payload.keyID,
+			payload.encryptedMetadata,
+			s.searchText(c.Payload),
+			c.Embedding,
+			linksJSON(c.DerivedFrom),
+			c.Stale,
+			expirationPolicy(c.Expiration),
+			int(c.MaxLifetime.Seconds()),
+		).Scan(&revision)
+		if err == pgx.ErrNoRows {
+			return revisionMismatch(ctx, tx, c.ID, c.Revision)
+		}
+		if err != nil {
+			return err
+		}
+		if err := replaceLinks(ctx, tx, c); err != nil {
+			return err
+		}
+		c.Revision = revision
+		if err := insertRevision(ctx, tx, c, payload, time.Now()); err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, models.ContextEventUpdated, c, payload.metadata)
+	})
+}
+
+// Delete marks a context deleted
+func (s *PostgresContextStore) Delete(ctx context.Context, contextID string) error {
+	return s.DeleteAtRevision(ctx, contextID, 0)
+}
+
+// DeleteAtRevision marks a context deleted only if it is still at the given
+// revision, failing with ErrConflict otherwise. Revision 0 deletes
+// unconditionally. The deletion is logged as a context.deleted event.
+func (s *PostgresContextStore) DeleteAtRevision(ctx context.Context, contextID string, revision int) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return deleteAtRevision(ctx, tx, contextID, revision)
+	})
+}
+
+// DeleteBatch marks contexts deleted unconditionally in one transaction, logging a
+// context.deleted event for each. Errors are reported as by CreateBatch.
+func (s *PostgresContextStore) DeleteBatch(ctx context.Context, contextIDs []string, atomic bool) ([]error, error) {
+	return writeBatch(ctx, s.pool, len(contextIDs), atomic, func(tx pgx.Tx, i int) error {
+		return deleteAtRevision(ctx, tx, contextIDs[i], 0)
+	})
+}
+
+// deleteAtRevision marks a context deleted in tx, releases any lease on it and
+// logs the deletion
+func deleteAtRevision(ctx context.Context, tx pgx.Tx, contextID string, revision int) error {
+	if err := checkLeaseGuard(ctx, tx, contextID); err != nil {
+		return err
+	}
+	query := `
+		WITH deleted AS (
+			UPDATE contexts SET deleted_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL AND ($2::int = 0 OR revision = $2)
+			RETURNING id, type, agent_id, tenant_id, revision, metadata, access_control
+		),
+		released AS (
+			DELETE FROM context_leases WHERE context_id IN (SELECT id FROM deleted)
+		)
+		INSERT INTO context_events (type, tenant_id, context_id, context_type, agent_id, revision, metadata, access_control, occurred_at)
+		SELECT $3, tenant_id, id, type, agent_id, revision, metadata, access_control, NOW() FROM deleted
+	`
+	result, err := tx.Exec(ctx, query, contextID, revision, string(models.ContextEventDeleted))
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return revisionMismatch(ctx, tx, contextID, revision)
+	}
+	return nil
+}
+
+// revisionMismatch explains why a conditional write touched no rows
+func revisionMismatch(ctx context.Context, tx pgx.Tx, contextID string, expected int) error {
+	var current int
+	err := tx.QueryRow(ctx, `SELECT revision FROM contexts WHERE id = $1 AND deleted_at IS NULL`, contextID).Scan(&current)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("context not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("context %s is at revision %d, expected %d: %w", contextID, current, expected, ErrConflict)
+}
+
+// List retrieves contexts with filters
+func (s *PostgresContextStore) List(ctx context.Context, filters *ContextFilters) ([]*models.Context, error) {
+	query, args := buildContextListQuery(filters)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contexts []*models.Context
+	for rows.Next() {
+		c, err := s.scanContext(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, c)
+	}
+
+	return contexts, rows.Err()
+}
+
+// Count returns the number of contexts matching filters, ignoring the page
+func (s *PostgresContextStore) Count(ctx context.Context, filters *ContextFilters) (int, error) {
+	query, args := buildContextCountQuery(filters)
+	var count int
+	if err := s.pool.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteExpired deletes up to limit contexts that expired before the given
+// time and logs a context.expired event for each. Rows locked by a concurrent
+// sweep are skipped rather than waited on. Deleted contexts are left to
+// PurgeDeleted.
+func (s *PostgresContextStore) DeleteExpired(ctx context.Context, before time.Time, limit int) ([]*models.ContextEvent, error) {
+	query := `
+		WITH expired AS (
+			DELETE FROM contexts
+			WHERE id IN (
+				SELECT id FROM contexts
+				WHERE expires_at IS NOT NULL AND expires_at < $1 AND deleted_at IS NULL
+				ORDER BY expires_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, type, agent_id, tenant_id, revision, metadata, access_control
+		)
+		INSERT INTO context_events (type, tenant_id, context_id, context_type, agent_id, revision, metadata, access_control, occurred_at)
+		SELECT $3, tenant_id, id, type, agent_id, revision, metadata, access_control, NOW() FROM expired
+		RETURNING ` + eventColumns
+
+	rows, err := s.pool.Query(ctx, query, before, limit, string(models.ContextEventExpired))
+	if err != nil {
+		return nil, err
+	}
+	return collectEvents(rows)
+}
+
+// GetDeleted retrieves a deleted context by ID
+func (s *PostgresContextStore) GetDeleted(ctx context.Context, contextID string) (*models.Context, error) {
+	query := `SELECT ` + contextColumns + ` FROM contexts WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	c, err := s.scanContext(ctx, s.pool.QueryRow(ctx, query, contextID))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("deleted context not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ListDeleted retrieves the deleted contexts of a tenant, most recently
+// deleted first
+func (s *PostgresContextStore) ListDeleted(ctx context.Context, tenantID string, limit int) ([]*models.Context, error) {
+	query := `SELECT ` + contextColumns + ` FROM contexts
+		WHERE tenant_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := s.pool.Query(ctx, query, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contexts []*models.Context
+	for rows.Next() {
+		c, err := s.scanContext(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, c)
+	}
+	return contexts, rows.Err()
+}
+
+// Restore clears the deletion of a context and logs a context.restored event
+func (s *PostgresContextStore) Restore(ctx context.Context, contextID string) error {
+	query := `
+		WITH restored AS (
+			UPDATE contexts SET deleted_at = NULL
+			WHERE id = $1 AND deleted_at IS NOT NULL
+			RETURNING id, type, agent_id, tenant_id, revision, metadata, access_control
+		)
+		INSERT INTO context_events (type, tenant_id, context_id, context_type, agent_id, revision, metadata, access_control, occurred_at)
+		SELECT $2, tenant_id, id, type, agent_id, revision, metadata, access_control, NOW() FROM restored
+	`
+	result, err := s.pool.Exec(ctx, query, contextID, string(models.ContextEventRestored))
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("deleted context not found: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes up to limit contexts deleted before the
+// given time. Their revisions, lineage edges and leases go with them through
+// the cascading foreign keys. Rows locked by a concurrent purge are skipped.
+func (s *PostgresContextStore) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int, error) {
+	query := `
+		DELETE FROM contexts
+		WHERE id IN (
+			SELECT id FROM contexts
+			WHERE deleted_at IS NOT NULL AND deleted_at < $1
+			ORDER BY deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+	result, err := s.pool.Exec(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// ListEvents returns logged context events matching filters, oldest first
+func (s *PostgresContextStore) ListEvents(ctx context.Context, filters *EventFilters) ([]*models.ContextEvent, error) {
+	query, args := buildEventListQuery(filters)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return collectEvents(rows)
+}
+
+// PruneEvents deletes up to limit events recorded before the given time,
+// oldest first, and returns how many were deleted
+func (s *PostgresContextStore) PruneEvents(ctx context.Context, before time.Time, limit int) (int, error) {
+	query := `
+		DELETE FROM context_events
+		WHERE id IN (
+			SELECT id FROM context_events
+			WHERE occurred_at < $1
+			ORDER BY id
+			LIMIT $2
+		)
+	`
+	result, err := s.pool.Exec(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// EventBounds returns the oldest retained event ID and the newest event ID
+// ListEvents can return, or zeros when the log is empty
+func (s *PostgresContextStore) EventBounds(ctx context.Context) (int64, int64, error) {
+	query := `
+		SELECT
+			COALESCE((SELECT MIN(id) FROM context_events), 0),
+			COALESCE((SELECT MAX(id) FROM context_events WHERE ` + committedEvents + `), 0)
+	`
+	var first, last int64
+	if err := s.pool.QueryRow(ctx, query).Scan(&first, &last); err != nil {
+		return 0, 0, err
+	}
+	return first, last, nil
+}
+
+// ListRevisions returns the revision history of a context, newest first
+func (s *PostgresContextStore) ListRevisions(ctx context.Context, contextID string) ([]*models.ContextRevision, error) {
+	query := `SELECT ` + revisionColumns + ` FROM context_revisions WHERE context_id = $1 ORDER BY revision DESC`
+
+	rows, err := s.pool.Query(ctx, query, contextID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*models.ContextRevision
+	for rows.Next() {
+		r, err := s.scanRevision(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("context not found: %w", ErrNotFound)
+	}
+	return revisions, nil
+}
+
+// GetRevision retrieves a single revision of a context
+func (s *PostgresContextStore) GetRevision(ctx context.Context, contextID string, revision int) (*models.ContextRevision, error) {
+	query := `SELECT ` + revisionColumns + ` FROM context_revisions WHERE context_id = $1 AND revision = $2`
+
+	r, err := s.scanRevision(ctx, s.pool.QueryRow(ctx, query, contextID, revision))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("revision %d of context %s not found: %w", revision, contextID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// storedPayload is the payload and metadata of a context as stored in a row
+type storedPayload struct {
+	data              []byte
+	encoding          PayloadEncoding
+	keyID             *string           // Data key the row is sealed with; nil in plaintext
+	metadata          map[string]string // Metadata stored in the clear
+	encryptedMetadata []byte            // Sealed JSON of the sensitive metadata
+}
+
+// payloadAAD binds a sealed field to its context, so ciphertext copied to
+// another row or field fails to open
+func payloadAAD(contextID, field string) []byte {
+	return []byte("acb/context/" + contextID + "/" + field)
+}
+
+// sealPayload compresses c.Payload with the store's codec and, when
+// encryption is enabled, seals it and the sensitive metadata with the
+// tenant's active data key. c.Checksum stays the checksum of the payload as
+// given.
+func (s *PostgresContextStore) sealPayload(ctx context.Context, c *models.Context) (storedPayload, error) {
+	data, encoding, err := s.codec.Encode(c.Payload)
+	if err != nil {
+		return storedPayload{}, err
+	}
+	stored := storedPayload{data: data, encoding: encoding, metadata: c.Metadata}
+	if s.cipher == nil {
+		return stored, nil
+	}
+
+	keyID, err := s.cipher.ActiveKey(ctx, c.TenantID)
+	if err != nil {
+		return storedPayload{}, fmt.Errorf("failed to get data key: %w", err)
+	}
+	stored.keyID = &keyID
+	if len(data) > 0 {
+		if stored.data, err = s.cipher.Seal(ctx, keyID, data, payloadAAD(c.ID, "payload")); err != nil {
+			return storedPayload{}, fmt.Errorf("failed to encrypt payload: %w", err)
+		}
+	}
+
+	sensitive := make(map[string]string)
+	stored.metadata = make(map[string]string, len(c.Metadata))
+	for key, value := range c.Metadata {
+		if s.sensitive[key] {
+			sensitive[key] = value
+		} else {
+			stored.metadata[key] = value
+		}
+	}
+	if len(sensitive) > 0 {
+		plaintext, _ := json.Marshal(sensitive)
+		if stored.encryptedMetadata, err = s.cipher.Seal(ctx, keyID, plaintext, payloadAAD(c.ID, "metadata")); err != nil {
+			return storedPayload{}, fmt.Errorf("failed to encrypt metadata: %w", err)
+		}
+	}
+	return stored, nil
+}
+
+// openPayload reverses sealPayload, returning the payload and the sensitive
+// metadata of a row
+func (s *PostgresContextStore) openPayload(ctx context.Context, contextID string, stored storedPayload) ([]byte, map[string]string, error) {
+	data := stored.data
+	var sensitive map[string]string
+	if stored.keyID != nil {
+		if s.cipher == nil {
+			return nil, nil, fmt.Errorf("context %s is encrypted but no key provider is configured", contextID)
+		}
+		var err error
+		if len(data) > 0 {
+			if data, err = s.cipher.Open(ctx, *stored.keyID, data, payloadAAD(contextID, "payload")); err != nil {
+				return nil, nil, fmt.Errorf("failed to decrypt payload of context %s: %w", contextID, err)
+			}
+		}
+		if len(stored.encryptedMetadata) > 0 {
+			plaintext, err := s.cipher.Open(ctx, *stored.keyID, stored.encryptedMetadata, payloadAAD(contextID, "metadata"))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to decrypt metadata of context %s: %w", contextID, err)
+			}
+			if err := json.Unmarshal(plaintext, &sensitive); err != nil {
+				return nil, nil, fmt.Errorf("failed to unmarshal encrypted metadata: %w", err)
+			}
+		}
+	}
+
+	payload, err := decodePayload(data, stored.encoding)
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload, sensitive, nil
+}
+
+// mergeMetadata adds the decrypted sensitive metadata to the clear metadata
+func mergeMetadata(metadata, sensitive map[string]string) map[string]string {
+	if len(sensitive) == 0 {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]string, len(sensitive))
+	}
+	for key, value := range sensitive {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// insertRevision snapshots c, with its payload already sealed, as revision
+// c.Revision
+func insertRevision(ctx context.Context, tx pgx.Tx, c *models.Context, payload storedPayload, at time.Time) error {
+	metadataJSON, _ := json.Marshal(payload.metadata)
+	accessControlJSON, _ := json.Marshal(c.AccessControl)
+	payloadRefJSON, _ := json.Marshal(c.PayloadRef)
+
+	query := `
+		INSERT INTO context_revisions (context_id, revision, payload, payload_encoding, payload_key_id, encrypted_metadata, payload_ref, metadata, version, access_control, checksum, author_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+	_, err := tx.Exec(ctx, query,
+		c.ID,
+		c.Revision,
+		payload.data,
+		string(payload.encoding),
+		payload.keyID,
+		payload.encryptedMetadata,
+		payloadRefJSON,
+		metadataJSON,
+		c.Version,
+		accessControlJSON,
+		c.Checksum,
+		c.UpdatedBy,
+		at,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
+
+// insertEvent logs an event for c in the transaction that changed it. Only
+// the metadata stored in the clear is copied to the event.
+func insertEvent(ctx context.Context, tx pgx.Tx, eventType models.ContextEventType, c *models.Context, metadata map[string]string) error {
+	metadataJSON, _ := json.Marshal(metadata)
+	accessControlJSON, _ := json.Marshal(c.AccessControl)
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO context_events (type, tenant_id, context_id, context_type, agent_id, revision, metadata, access_control, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+	`,
+		string(eventType),
+		c.TenantID,
+		c.ID,
+		c.Type,
+		c.AgentID,
+		c.Revision,
+		metadataJSON,
+		accessControlJSON,
+	)
+	return err
+}
+
+// scanContext scans a row selected with contextColumns and opens its payload
+func (s *PostgresContextStore) scanContext(ctx context.Context, row rowScanner) (*models.Context, error) {
 	var c models.Context
+	var stored storedPayload
 	var payloadRefJSON []byte
 	var metadataJSON []byte
 	var accessControlJSON []byte
 	var ttlSeconds int
 	var expiresAt sql.NullTime
+	var updatedBy sql.NullString
+	var derivedFromJSON []byte
+	var maxLifetimeSeconds int
 
-	err := s.pool.QueryRow(ctx, query, contextID).Scan(
+	err := row.Scan(
 		&c.ID,
 		&c.Type,
 		&c.AgentID,
 		&c.TenantID,
-		&c.Payload,
+		&stored.data,
+		&stored.encoding,
+		&stored.keyID,
+		&stored.encryptedMetadata,
 		&payloadRefJSON,
 		&metadataJSON,
 		&c.Version,
@@ -100,19 +763,25 @@ func (s *PostgresContextStore) Get(ctx context.Context, contextID string) (*mode
 		&c.CreatedAt,
 		&expiresAt,
 		&c.Checksum,
+		&c.Revision,
+		&updatedBy,
+		&c.Embedding,
+		&derivedFromJSON,
+		&c.Stale,
+		&c.Expiration,
+		&maxLifetimeSeconds,
+		&c.DeletedAt,
 	)
-
-	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("context not found: %w", err)
-	}
 	if err != nil {
 		return nil, err
 	}
 
 	c.TTL = time.Duration(ttlSeconds) * time.Second
+	c.MaxLifetime = time.Duration(maxLifetimeSeconds) * time.Second
 	if expiresAt.Valid {
 		c.ExpiresAt = expiresAt.Time
 	}
+	c.UpdatedBy = updatedBy.String
 
 	if err := json.Unmarshal(metadataJSON, &c.Metadata); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
@@ -120,80 +789,135 @@ func (s *PostgresContextStore) Get(ctx context.Context, contextID string) (*mode
 	if err := json.Unmarshal(accessControlJSON, &c.AccessControl); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal access_control: %w", err)
 	}
+
+	payload, sensitive, err := s.openPayload(ctx, c.ID, stored)
+	if err != nil {
+		return nil, err
+	}
+	c.Payload = payload
+	c.Metadata = mergeMetadata(c.Metadata, sensitive)
 	if len(payloadRefJSON) > 0 {
 		if err := json.Unmarshal(payloadRefJSON, &c.PayloadRef); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal payload_ref: %w", err)
 		}
 	}
+	if len(derivedFromJSON) > 0 {
+		if err := json.Unmarshal(derivedFromJSON, &c.DerivedFrom); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal derived_from: %w", err)
+		}
+	}
 
 	return &c, nil
 }
 
-// Update updates a context
-func (s *PostgresContextStore) Update(ctx context.Context, c *models.Context) error {
-	if err := c.Validate(); err != nil {
-		return err
-	}
+// scanRevision scans a row selected with revisionColumns and opens its
+// payload
+func (s *PostgresContextStore) scanRevision(ctx context.Context, row rowScanner) (*models.ContextRevision, error) {
+	var r models.ContextRevision
+	var stored storedPayload
+	var payloadRefJSON []byte
+	var metadataJSON []byte
+	var accessControlJSON []byte
+	var checksum sql.NullString
 
-	metadataJSON, _ := json.Marshal(c.Metadata)
-	accessControlJSON, _ := json.Marshal(c.AccessControl)
-	payloadRefJSON, _ := json.Marshal(c.PayloadRef)
+	err := row.Scan(
+		&r.ContextID,
+		&r.Revision,
+		&stored.data,
+		&stored.encoding,
+		&stored.keyID,
+		&stored.encryptedMetadata,
+		&payloadRefJSON,
+		&metadataJSON,
+		&r.Version,
+		&accessControlJSON,
+		&checksum,
+		&r.AuthorID,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	r.Checksum = checksum.String
 
-	ttlSeconds := int(c.TTL.Seconds())
-	if ttlSeconds == 0 {
-		ttlSeconds = 86400
+	if err := json.Unmarshal(metadataJSON, &r.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	if err := json.Unmarshal(accessControlJSON, &r.AccessControl); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal access_control: %w", err)
 	}
 
-	expiresAt := c.ExpiresAt
-	if expiresAt.IsZero() && c.TTL > 0 {
-		expiresAt = c.CalculateExpiration()
+	payload, sensitive, err := s.openPayload(ctx, r.ContextID, stored)
+	if err != nil {
+		return nil, err
+	}
+	r.Payload = payload
+	r.Metadata = mergeMetadata(r.Metadata, sensitive)
+	if len(payloadRefJSON) > 0 {
+		if err := json.Unmarshal(payloadRefJSON, &r.PayloadRef); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload_ref: %w", err)
+		}
 	}
 
-	query := `
-		UPDATE contexts
-		SET payload = $2, payload_ref = $3, metadata = $4, version = $5, ttl_seconds = $6, access_control = $7, expires_at = $8, checksum = $9
-		WHERE id = $1
-	`
+	return &r, nil
+}
 
-	result, err := s.pool.Exec(ctx, query,
-		c.ID,
-		c.Payload,
-		payloadRefJSON,
-		metadataJSON,
-		c.Version,
-		ttlSeconds,
-		accessControlJSON,
-		expiresAt,
-		c.Checksum,
+// scanEvent scans a row selected with eventColumns
+func scanEvent(row rowScanner) (*models.ContextEvent, error) {
+	var e models.ContextEvent
+	var metadataJSON, accessControlJSON []byte
+
+	err := row.Scan(
+		&e.ID,
+		&e.Type,
+		&e.TenantID,
+		&e.ContextID,
+		&e.ContextType,
+		&e.AgentID,
+		&e.Revision,
+		&metadataJSON,
+		&accessControlJSON,
+		&e.OccurredAt,
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("context not found")
+	if len(metadataJSON) > 0 {
+		if err := json.Unmarshal(metadataJSON, &e.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
 	}
-	return err
-}
 
-// Delete removes a context
-func (s *PostgresContextStore) Delete(ctx context.Context, contextID string) error {
-	query := `DELETE FROM contexts WHERE id = $1`
-	result, err := s.pool.Exec(ctx, query, contextID)
-	if err != nil {
-		return err
+	if err := json.Unmarshal(accessControlJSON, &e.AccessControl); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal access_control: %w", err)
 	}
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("context not found")
+	return &e, nil
+}
+
+// collectEvents scans and closes rows selected with eventColumns
+func collectEvents(rows pgx.Rows) ([]*models.ContextEvent, error) {
+	defer rows.Close()
+
+	var events []*models.ContextEvent
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
 	}
-	return nil
+	return events, rows.Err()
 }
 
-// List retrieves contexts with filters
-func (s *PostgresContextStore) List(ctx context.Context, filters *ContextFilters) ([]*models.Context, error) {
-	query := `SELECT id, type, agent_id, tenant_id, payload, payload_ref, metadata, version, schema_id, ttl_seconds, access_control, created_at, expires_at, checksum FROM contexts WHERE 1=1`
+// buildEventListQuery builds the parameterised SELECT used by ListEvents
+func buildEventListQuery(filters *EventFilters) (string, []interface{}) {
+	query := `SELECT ` + eventColumns + ` FROM context_events WHERE ` + committedEvents
 	args := []interface{}{}
 	argIndex := 1
 
 	if filters == nil {
-		filters = &ContextFilters{Limit: 100}
+		filters = &EventFilters{Limit: 100}
 	}
 
 	if filters.TenantID != "" {
@@ -202,9 +926,25 @@ func (s *PostgresContextStore) List(ctx context.Context, filters *ContextFilters
 		argIndex++
 	}
 
-	if filters.Type != "" {
-		query += fmt.Sprintf(" AND type = $%d", argIndex)
-		args = append(args, filters.Type)
+	if filters.AfterID > 0 {
+		query += fmt.Sprintf(" AND id > $%d", argIndex)
+		args = append(args, filters.AfterID)
+		argIndex++
+	}
+
+	if len(filters.Types) > 0 {
+		types := make([]string, len(filters.Types))
+		for i, t := range filters.Types {
+			types[i] = string(t)
+		}
+		query += fmt.Sprintf(" AND type = ANY($%d)", argIndex)
+		args = append(args, types)
+		argIndex++
+	}
+
+	if filters.ContextType != "" {
+		query += fmt.Sprintf(" AND context_type = $%d", argIndex)
+		args = append(args, filters.ContextType)
 		argIndex++
 	}
 
@@ -214,83 +954,130 @@ func (s *PostgresContextStore) List(ctx context.Context, filters *ContextFilters
 		argIndex++
 	}
 
-	query += " ORDER BY created_at DESC"
+	if !filters.Selector.Empty() {
+		clause, selectorArgs, next := filters.Selector.SQL("metadata", argIndex)
+		query += " AND " + clause
+		args = append(args, selectorArgs...)
+		argIndex = next
+	}
+
+	if filters.VisibleTo != "" {
+		query += " AND " + visibleToClause(argIndex)
+		args = append(args, filters.VisibleTo)
+		argIndex++
+	}
+
+	query += " ORDER BY id"
 
 	if filters.Limit > 0 {
 		query += fmt.Sprintf(" LIMIT $%d", argIndex)
 		args = append(args, filters.Limit)
+	}
+
+	return query, args
+}
+
+// visibleToClause restricts rows to those whose agent_id and access_control
+// let the agent bound to placeholder argIndex read them
+func visibleToClause(argIndex int) string {
+	return fmt.Sprintf(`(agent_id = $%d OR access_control->>'scope' = 'public'`+
+		` OR (access_control->>'scope' IN ('group', 'shared') AND access_control->'allowed_ids' @> jsonb_build_array($%d::text)))`,
+		argIndex, argIndex)
+}
+
+// buildContextListQuery builds the parameterised SELECT used by List
+func buildContextListQuery(filters *ContextFilters) (string, []interface{}) {
+	if filters == nil {
+		filters = &ContextFilters{Limit: 100}
+	}
+
+	where, args, argIndex := contextFilterClause(filters, 1)
+	keyset, keysetArgs, argIndex := keysetClause(filters.After, argIndex)
+	query := `SELECT ` + contextColumns + ` FROM contexts WHERE 1=1` + where + keyset
+	query += " ORDER BY created_at DESC, id DESC"
+
+	page, pageArgs := pageClause(filters, argIndex)
+	args = append(args, keysetArgs...)
+	return query + page, append(args, pageArgs...)
+}
+
+// buildContextCountQuery builds the parameterised COUNT used by Count
+func buildContextCountQuery(filters *ContextFilters) (string, []interface{}) {
+	if filters == nil {
+		filters = &ContextFilters{}
+	}
+	where, args, _ := contextFilterClause(filters, 1)
+	return `SELECT COUNT(*) FROM contexts WHERE 1=1` + where, args
+}
+
+// contextFilterClause builds the conditions List and Search share, starting
+// at placeholder argIndex. It returns the conditions, each prefixed with
+// AND, their args and the next free placeholder.
+func contextFilterClause(filters *ContextFilters, argIndex int) (string, []interface{}, int) {
+	query := ""
+	args := []interface{}{}
+
+	if filters.TenantID != "" {
+		query += fmt.Sprintf(" AND tenant_id = $%d", argIndex)
+		args = append(args, filters.TenantID)
 		argIndex++
 	}
 
-	if filters.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argIndex)
-		args = append(args, filters.Offset)
+	if filters.Type != "" {
+		query += fmt.Sprintf(" AND type = $%d", argIndex)
+		args = append(args, filters.Type)
+		argIndex++
 	}
 
-	rows, err := s.pool.Query(ctx, query, args...)
-	if err != nil {
-		return nil, err
+	if filters.AgentID != "" {
+		query += fmt.Sprintf(" AND agent_id = $%d", argIndex)
+		args = append(args, filters.AgentID)
+		argIndex++
 	}
-	defer rows.Close()
 
-	var contexts []*models.Context
-	for rows.Next() {
-		var c models.Context
-		var payloadRefJSON []byte
-		var metadataJSON []byte
-		var accessControlJSON []byte
-		var ttlSeconds int
-		var expiresAt sql.NullTime
-
-		err := rows.Scan(
-			&c.ID,
-			&c.Type,
-			&c.AgentID,
-			&c.TenantID,
-			&c.Payload,
-			&payloadRefJSON,
-			&metadataJSON,
-			&c.Version,
-			&c.SchemaID,
-			&ttlSeconds,
-			&accessControlJSON,
-			&c.CreatedAt,
-			&expiresAt,
-			&c.Checksum,
-		)
-		if err != nil {
-			return nil, err
-		}
+	if filters.VisibleTo != "" {
+		query += " AND " + visibleToClause(argIndex)
+		args = append(args, filters.VisibleTo)
+		argIndex++
+	}
 
-		c.TTL = time.Duration(ttlSeconds) * time.Second
-		if expiresAt.Valid {
-			c.ExpiresAt = expiresAt.Time
-		}
+	if filters.IDs != nil {
+		query += fmt.Sprintf(" AND id = ANY($%d)", argIndex)
+		args = append(args, filters.IDs)
+		argIndex++
+	}
 
-		if err := json.Unmarshal(metadataJSON, &c.Metadata); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
-		}
-		if err := json.Unmarshal(accessControlJSON, &c.AccessControl); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal access_control: %w", err)
-		}
-		if len(payloadRefJSON) > 0 {
-			if err := json.Unmarshal(payloadRefJSON, &c.PayloadRef); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal payload_ref: %w", err)
-			}
-		}
+	// Expired contexts are hidden until the janitor removes them, deleted
+	// ones until they are restored or purged
+	query += " AND (expires_at IS NULL OR expires_at > NOW())"
+	query += " AND deleted_at IS NULL"
 
-		contexts = append(contexts, &c)
+	if !filters.Selector.Empty() {
+		clause, selectorArgs, next := filters.Selector.SQL("metadata", argIndex)
+		query += " AND " + clause
+		args = append(args, selectorArgs...)
+		argIndex = next
 	}
 
-	return contexts, rows.Err()
+	return query, args, argIndex
 }
 
-// DeleteExpired removes expired contexts
-func (s *PostgresContextStore) DeleteExpired(ctx context.Context) (int, error) {
-	query := `DELETE FROM contexts WHERE expires_at IS NOT NULL AND expires_at < NOW()`
-	result, err := s.pool.Exec(ctx, query)
-	if err != nil {
-		return 0, err
+// pageClause builds the LIMIT and OFFSET of filters starting at placeholder
+// argIndex
+func pageClause(filters *ContextFilters, argIndex int) (string, []interface{}) {
+	query := ""
+	args := []interface{}{}
+
+	if filters.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argIndex)
+		args = append(args, filters.Limit)
+		argIndex++
 	}
-	return int(result.RowsAffected()), nil
+
+	if filters.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argIndex)
+		args = append(args, filters.Offset)
+	}
+
+	return query, args
 }
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/acb/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresEmbeddingStore implements EmbeddingStore using PostgreSQL
+type PostgresEmbeddingStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresEmbeddingStore creates a new PostgreSQL embedding store
+func NewPostgresEmbeddingStore(pool *pgxpool.Pool) *PostgresEmbeddingStore {
+	return &PostgresEmbeddingStore{pool: pool}
+}
+
+// GetConfig returns the embedding configuration of a context type
+func (s *PostgresEmbeddingStore) GetConfig(ctx context.Context, tenantID, contextType string) (*models.EmbeddingConfig, error) {
+	query := `
+		SELECT tenant_id, context_type, dimension, metric, updated_at
+		FROM embedding_configs WHERE tenant_id = $1 AND context_type = $2
+	`
+
+	var cfg models.EmbeddingConfig
+	err := s.pool.QueryRow(ctx, query, tenantID, contextType).Scan(&cfg.TenantID, &cfg.ContextType, &cfg.Dimension, &cfg.Metric, &cfg.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("no embedding configuration for context type %s: %w", contextType, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// SetConfig declares the embedding configuration of a context type
+func (s *PostgresEmbeddingStore) SetConfig(ctx context.Context, cfg *models.EmbeddingConfig) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var mismatched int
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM contexts
+			WHERE tenant_id = $1 AND type = $2 AND embedding IS NOT NULL AND cardinality(embedding) <> $3
+		`, cfg.TenantID, cfg.ContextType, cfg.Dimension).Scan(&mismatched)
+		if err != nil {
+			return err
+		}
+		if mismatched > 0 {
+			return fmt.Errorf("%d contexts of type %s have embeddings of another dimension: %w", mismatched, cfg.ContextType, ErrConflict)
+		}
+
+		return tx.QueryRow(ctx, `
+			INSERT INTO embedding_configs (tenant_id, context_type, dimension, metric, updated_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			ON CONFLICT (tenant_id, context_type) DO UPDATE
+			SET dimension = EXCLUDED.dimension, metric = EXCLUDED.metric, updated_at = EXCLUDED.updated_at
+			RETURNING updated_at
+		`, cfg.TenantID, cfg.ContextType, cfg.Dimension, string(cfg.Metric)).Scan(&cfg.UpdatedAt)
+	})
+}
+
+// List returns the embeddings of the contexts matching filters
+func (s *PostgresEmbeddingStore) List(ctx context.Context, filters *ContextFilters) ([]*models.Embedding, error) {
+	if filters == nil {
+		filters = &ContextFilters{}
+	}
+	where, args, _ := contextFilterClause(filters, 1)
+	query := `SELECT id, embedding FROM contexts WHERE embedding IS NOT NULL` + where
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var embeddings []*models.Embedding
+	for rows.Next() {
+		var e models.Embedding
+		if err := rows.Scan(&e.ContextID, &e.Vector); err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, &e)
+	}
+	return embeddings, rows.Err()
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// expirationPolicy returns the policy stored for p, contexts without one
+// being fixed
+func expirationPolicy(p models.ExpirationPolicy) string {
+	if p == "" {
+		return string(models.ExpirationFixed)
+	}
+	return string(p)
+}
+
+// Touch extends the expiry of many contexts with a single UPDATE
+func (s *PostgresContextStore) Touch(ctx context.Context, expiries map[string]time.Time) (int, error) {
+	if len(expiries) == 0 {
+		return 0, nil
+	}
+	ids := make([]string, 0, len(expiries))
+	times := make([]time.Time, 0, len(expiries))
+	for id, expiresAt := range expiries {
+		ids = append(ids, id)
+		times = append(times, expiresAt)
+	}
+
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE contexts c SET expires_at = t.expires_at
+		FROM unnest($1::text[], $2::timestamptz[]) AS t(id, expires_at)
+		WHERE c.id = t.id AND c.deleted_at IS NULL AND c.expires_at > NOW() AND c.expires_at < t.expires_at
+	`, ids, times)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// PostgresExpirationStore implements ExpirationStore using PostgreSQL
+type PostgresExpirationStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresExpirationStore creates a new PostgreSQL expiration store
+func NewPostgresExpirationStore(pool *pgxpool.Pool) *PostgresExpirationStore {
+	return &PostgresExpirationStore{pool: pool}
+}
+
+// GetConfig returns the expiration defaults of a context type
+func (s *PostgresExpirationStore) GetConfig(ctx context.Context, tenantID, contextType string) (*models.ExpirationConfig, error) {
+	query := `
+		SELECT tenant_id, context_type, policy, ttl_seconds, max_lifetime_seconds, updated_at
+		FROM expiration_configs WHERE tenant_id = $1 AND context_type = $2
+	`
+
+	var cfg models.ExpirationConfig
+	var ttlSeconds, maxLifetimeSeconds int
+	err := s.pool.QueryRow(ctx, query, tenantID, contextType).Scan(&cfg.TenantID, &cfg.ContextType, &cfg.Policy, &ttlSeconds, &maxLifetimeSeconds, &cfg.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("no expiration configuration for context type %s: %w", contextType, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg.TTL = time.Duration(ttlSeconds) * time.Second
+	cfg.MaxLifetime = time.Duration(maxLifetimeSeconds) * time.Second
+	return &cfg, nil
+}
+
+// SetConfig sets the expiration defaults of a context type. Existing
+// contexts keep the policy they were created with.
+func (s *PostgresExpirationStore) SetConfig(ctx context.Context, cfg *models.ExpirationConfig) error {
+	return s.pool.QueryRow(ctx, `
+		INSERT INTO expiration_configs (tenant_id, context_type, policy, ttl_seconds, max_lifetime_seconds, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (tenant_id, context_type) DO UPDATE
+		SET policy = EXCLUDED.policy, ttl_seconds = EXCLUDED.ttl_seconds,
+			max_lifetime_seconds = EXCLUDED.max_lifetime_seconds, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, cfg.TenantID, cfg.ContextType, string(cfg.Policy), int(cfg.TTL.Seconds()), int(cfg.MaxLifetime.Seconds())).Scan(&cfg.UpdatedAt)
+}
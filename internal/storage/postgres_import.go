@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/acb/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresImportStore implements ImportStore on the agent and context tables
+type PostgresImportStore struct {
+	pool     *pgxpool.Pool
+	contexts *PostgresContextStore // Seals payloads as the store that serves them
+}
+
+// NewPostgresImportStore creates a new PostgreSQL import store writing
+// payloads as contexts does
+func NewPostgresImportStore(pool *pgxpool.Pool, contexts *PostgresContextStore) *PostgresImportStore {
+	return &PostgresImportStore{pool: pool, contexts: contexts}
+}
+
+// Import writes an archive in one transaction. Counts in the returned
+// summary are by outcome; its tenant fields are left to the caller.
+func (s *PostgresImportStore) Import(ctx context.Context, agents []*models.Agent, contexts []*models.ContextRecord, policy models.ConflictPolicy) (*models.ImportSummary, error) {
+	summary := &models.ImportSummary{}
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		for _, agent := range agents {
+			outcome, err := s.importAgent(ctx, tx, agent, policy)
+			if err != nil {
+				return err
+			}
+			summary.Agents.Add(outcome)
+		}
+		for _, rec := range contexts {
+			outcome, err := s.importContext(ctx, tx, rec, policy)
+			if err != nil {
+				return err
+			}
+			summary.Contexts.Add(outcome)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (s *PostgresImportStore) importAgent(ctx context.Context, tx pgx.Tx, agent *models.Agent, policy models.ConflictPolicy) (models.ImportOutcome, error) {
+	outcome, err := claimID(ctx, tx, "agent", agent.ID, agent.TenantID, policy)
+	if err != nil || outcome == models.ImportSkipped {
+		return outcome, err
+	}
+	if outcome == models.ImportOverwritten {
+		if _, err := tx.Exec(ctx, `DELETE FROM agents WHERE id = $1`, agent.ID); err != nil {
+			return "", err
+		}
+	}
+	return outcome, insertAgent(ctx, tx, agent)
+}
+
+// importContext writes a context at its exported revision together with its
+// revision history. Replacing a context drops its history and lineage edges
+// through the cascading foreign keys.
+func (s *PostgresImportStore) importContext(ctx context.Context, tx pgx.Tx, rec *models.ContextRecord, policy models.ConflictPolicy) (models.ImportOutcome, error) {
+	c := rec.Context
+	outcome, err := claimID(ctx, tx, "context", c.ID, c.TenantID, policy)
+	if err != nil || outcome == models.ImportSkipped {
+		return outcome, err
+	}
+	eventType := models.ContextEventCreated
+	if outcome == models.ImportOverwritten {
+		if _, err := tx.Exec(ctx, `DELETE FROM contexts WHERE id = $1`, c.ID); err != nil {
+			return "", err
+		}
+		eventType = models.ContextEventUpdated
+	}
+
+	payload, err := s.contexts.insertRow(ctx, tx, c)
+	if err != nil {
+		return "", err
+	}
+	if len(rec.Revisions) == 0 {
+		if err := insertRevision(ctx, tx, c, payload, c.CreatedAt); err != nil {
+			return "", err
+		}
+	}
+	for _, r := range rec.Revisions {
+		snapshot := &models.Context{
+			ID:            c.ID,
+			TenantID:      c.TenantID,
+			Payload:       r.Payload,
+			PayloadRef:    r.PayloadRef,
+			Metadata:      r.Metadata,
+			Version:       r.Version,
+			AccessControl: r.AccessControl,
+			Checksum:      r.Checksum,
+			Revision:      r.Revision,
+			UpdatedBy:     r.AuthorID,
+		}
+		sealed, err := s.contexts.sealPayload(ctx, snapshot)
+		if err != nil {
+			return "", err
+		}
+		if err := insertRevision(ctx, tx, snapshot, sealed, r.CreatedAt); err != nil {
+			return "", err
+		}
+	}
+	return outcome, insertEvent(ctx, tx, eventType, c, payload.metadata)
+}
+
+// claimID locks the row holding id in the table of the given kind, if any,
+// and decides under policy what importing a record of tenantID with that ID
+// does. A deleted record of the same tenant is purged so the import takes its
+// place as a new one.
+func claimID(ctx context.Context, tx pgx.Tx, kind, id, tenantID string, policy models.ConflictPolicy) (models.ImportOutcome, error) {
+	var owner string
+	var deleted bool
+	err := tx.QueryRow(ctx, `SELECT tenant_id, deleted_at IS NOT NULL FROM `+kind+`s WHERE id = $1 FOR UPDATE`, id).Scan(&owner, &deleted)
+	if err == pgx.ErrNoRows {
+		return models.ImportCreated, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if deleted && owner == tenantID {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+kind+`s WHERE id = $1`, id); err != nil {
+			return "", err
+		}
+		return models.ImportCreated, nil
+	}
+	switch {
+	case policy == models.ConflictSkip:
+		return models.ImportSkipped, nil
+	case owner != tenantID:
+		return "", fmt.Errorf("%s ID %s is taken by another tenant: %w", kind, id, ErrConflict)
+	case policy == models.ConflictOverwrite:
+		return models.ImportOverwritten, nil
+	default:
+		return "", fmt.Errorf("%s with ID %s already exists: %w", kind, id, ErrConflict)
+	}
+}
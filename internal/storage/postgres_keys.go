@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/acb/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// dataKeyColumns is the column list scanned by scanDataKey
+const dataKeyColumns = `id, tenant_id, master_key_id, wrapped_key, active, created_at`
+
+// PostgresDataKeyStore implements DataKeyStore using PostgreSQL
+type PostgresDataKeyStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresDataKeyStore creates a new PostgreSQL data key store
+func NewPostgresDataKeyStore(pool *pgxpool.Pool) *PostgresDataKeyStore {
+	return &PostgresDataKeyStore{pool: pool}
+}
+
+// Create stores k as the tenant's active key and retires the previous one in
+// the same transaction. A concurrent activation for the same tenant violates
+// the unique active key index and is reported as ErrConflict.
+func (s *PostgresDataKeyStore) Create(ctx context.Context, k *models.DataKey) error {
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `UPDATE data_keys SET active = FALSE WHERE tenant_id = $1 AND active`, k.TenantID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO data_keys (id, tenant_id, master_key_id, wrapped_key, active, created_at)
+			VALUES ($1, $2, $3, $4, TRUE, $5)
+		`, k.ID, k.TenantID, k.MasterKeyID, k.WrappedKey, k.CreatedAt)
+		return err
+	})
+	if IsUniqueViolation(err) {
+		return fmt.Errorf("data key for tenant %s was activated concurrently: %w", k.TenantID, ErrConflict)
+	}
+	if err != nil {
+		return err
+	}
+	k.Active = true
+	return nil
+}
+
+// Get retrieves a data key by ID
+func (s *PostgresDataKeyStore) Get(ctx context.Context, keyID string) (*models.DataKey, error) {
+	query := `SELECT ` + dataKeyColumns + ` FROM data_keys WHERE id = $1`
+
+	k, err := scanDataKey(s.pool.QueryRow(ctx, query, keyID))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("data key not found: %w", ErrNotFound)
+	}
+	return k, err
+}
+
+// GetActive retrieves the active data key of a tenant
+func (s *PostgresDataKeyStore) GetActive(ctx context.Context, tenantID string) (*models.DataKey, error) {
+	query := `SELECT ` + dataKeyColumns + ` FROM data_keys WHERE tenant_id = $1 AND active`
+
+	k, err := scanDataKey(s.pool.QueryRow(ctx, query, tenantID))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("no active data key for tenant %s: %w", tenantID, ErrNotFound)
+	}
+	return k, err
+}
+
+// List retrieves every data key, oldest first
+func (s *PostgresDataKeyStore) List(ctx context.Context) ([]*models.DataKey, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+dataKeyColumns+` FROM data_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*models.DataKey
+	for rows.Next() {
+		k, err := scanDataKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Rewrap replaces the wrapping of a key if it is still wrapped with
+// oldMasterKeyID
+func (s *PostgresDataKeyStore) Rewrap(ctx context.Context, keyID, oldMasterKeyID, masterKeyID string, wrapped []byte) error {
+	result, err := s.pool.Exec(ctx, `
+		UPDATE data_keys SET master_key_id = $3, wrapped_key = $4
+		WHERE id = $1 AND master_key_id = $2
+	`, keyID, oldMasterKeyID, masterKeyID, wrapped)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("data key %s is no longer wrapped with %s: %w", keyID, oldMasterKeyID, ErrConflict)
+	}
+	return nil
+}
+
+// scanDataKey scans a row selected with dataKeyColumns
+func scanDataKey(row rowScanner) (*models.DataKey, error) {
+	var k models.DataKey
+	err := row.Scan(&k.ID, &k.TenantID, &k.MasterKeyID, &k.WrappedKey, &k.Active, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LeaseGuard identifies the writer of a context write made while leases are
+// enforced. The write only goes through if no unexpired lease is held on the
+// context by another agent and, when FencingToken is non-zero, the lease with
+// that token is still held.
+type LeaseGuard struct {
+	HolderID     string
+	FencingToken int64
+}
+
+// leaseGuardKey is the context key for the lease guard of a write
+type leaseGuardKey struct{}
+
+// WithLeaseGuard returns a copy of ctx whose context writes are checked
+// against guard
+func WithLeaseGuard(ctx context.Context, guard LeaseGuard) context.Context {
+	return context.WithValue(ctx, leaseGuardKey{}, guard)
+}
+
+// LeaseGuardFromContext returns the lease guard carried by ctx
+func LeaseGuardFromContext(ctx context.Context) (LeaseGuard, bool) {
+	guard, ok := ctx.Value(leaseGuardKey{}).(LeaseGuard)
+	return guard, ok
+}
+
+// checkLeaseGuard checks the lease guard of ctx against the lease held on a
+// context, locking that lease until tx ends so it cannot be released or taken
+// over before the write commits
+func checkLeaseGuard(ctx context.Context, tx pgx.Tx, contextID string) error {
+	guard, ok := LeaseGuardFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var holderID string
+	var token int64
+	err := tx.QueryRow(ctx, `
+		SELECT holder_id, fencing_token FROM context_leases
+		WHERE context_id = $1 AND expires_at > NOW()
+		FOR SHARE
+	`, contextID).Scan(&holderID, &token)
+	if err == pgx.ErrNoRows {
+		if guard.FencingToken != 0 {
+			return fmt.Errorf("lease %d on context %s is no longer held: %w", guard.FencingToken, contextID, ErrLeased)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if holderID != guard.HolderID {
+		return fmt.Errorf("context %s is leased to %s: %w", contextID, holderID, ErrLeased)
+	}
+	if guard.FencingToken != 0 && token != guard.FencingToken {
+		return fmt.Errorf("lease %d on context %s is no longer held: %w", guard.FencingToken, contextID, ErrLeased)
+	}
+	return nil
+}
+
+// leaseColumns is the column list scanned by scanLease
+const leaseColumns = `context_id, tenant_id, holder_id, fencing_token, ttl_seconds, acquired_at, expires_at`
+
+// PostgresLeaseStore implements LeaseStore using PostgreSQL. Expiry is judged
+// by the database clock, so replicas with skewed clocks agree on it.
+type PostgresLeaseStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresLeaseStore creates a new PostgreSQL lease store
+func NewPostgresLeaseStore(pool *pgxpool.Pool) *PostgresLeaseStore {
+	return &PostgresLeaseStore{pool: pool}
+}
+
+// Acquire inserts a lease, or takes over an expired one, drawing the fencing
+// token from the context_lease_fencing_tokens sequence
+func (s *PostgresLeaseStore) Acquire(ctx context.Context, l *models.ContextLease) error {
+	query := `
+		INSERT INTO context_leases (context_id, tenant_id, holder_id, fencing_token, ttl_seconds, acquired_at, expires_at)
+		VALUES ($1, $2, $3, nextval('context_lease_fencing_tokens'), $4, NOW(), NOW() + $4 * INTERVAL '1 second')
+		ON CONFLICT (context_id) DO UPDATE
+		SET tenant_id = EXCLUDED.tenant_id, holder_id = EXCLUDED.holder_id, fencing_token = EXCLUDED.fencing_token,
+			ttl_seconds = EXCLUDED.ttl_seconds, acquired_at = EXCLUDED.acquired_at, expires_at = EXCLUDED.expires_at
+		WHERE context_leases.expires_at <= NOW()
+		RETURNING fencing_token, acquired_at, expires_at
+	`
+
+	err := s.pool.QueryRow(ctx, query, l.ContextID, l.TenantID, l.HolderID, int(l.TTL.Seconds())).Scan(&l.FencingToken, &l.AcquiredAt, &l.ExpiresAt)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("context %s is already leased: %w", l.ContextID, ErrConflict)
+	}
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("context not found: %w", ErrNotFound)
+	}
+	return err
+}
+
+// Get returns the unexpired lease on a context
+func (s *PostgresLeaseStore) Get(ctx context.Context, contextID string) (*models.ContextLease, error) {
+	query := `SELECT ` + leaseColumns + ` FROM context_leases WHERE context_id = $1 AND expires_at > NOW()`
+
+	l, err := scanLease(s.pool.QueryRow(ctx, query, contextID))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("context %s is not leased: %w", contextID, ErrNotFound)
+	}
+	return l, err
+}
+
+// Renew moves the expiry of an unexpired lease to ttl from now
+func (s *PostgresLeaseStore) Renew(ctx context.Context, contextID string, token int64, ttl time.Duration) (*models.ContextLease, error) {
+	query := `
+		UPDATE context_leases SET ttl_seconds = $3, expires_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE context_id = $1 AND fencing_token = $2 AND expires_at > NOW()
+		RETURNING ` + leaseColumns
+
+	l, err := scanLease(s.pool.QueryRow(ctx, query, contextID, token, int(ttl.Seconds())))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("lease %d on context %s is no longer held: %w", token, contextID, ErrNotFound)
+	}
+	return l, err
+}
+
+// Release deletes an unexpired lease
+func (s *PostgresLeaseStore) Release(ctx context.Context, contextID string, token int64) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM context_leases WHERE context_id = $1 AND fencing_token = $2 AND expires_at > NOW()`, contextID, token)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("lease %d on context %s is no longer held: %w", token, contextID, ErrNotFound)
+	}
+	return nil
+}
+
+// scanLease scans a row of leaseColumns
+func scanLease(row pgx.Row) (*models.ContextLease, error) {
+	var l models.ContextLease
+	var ttlSeconds int
+	if err := row.Scan(&l.ContextID, &l.TenantID, &l.HolderID, &l.FencingToken, &ttlSeconds, &l.AcquiredAt, &l.ExpiresAt); err != nil {
+		return nil, err
+	}
+	l.TTL = time.Duration(ttlSeconds) * time.Second
+	return &l, nil
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lifecycleColumns is the column list scanned by scanLifecyclePolicy
+const lifecycleColumns = `tenant_id, context_type, default_ttl_seconds, max_ttl_seconds, max_payload_bytes, max_revisions, allowed_scopes, non_owner_delete, updated_at`
+
+// PruneRevisions deletes all but the keep newest revisions of a context
+func (s *PostgresContextStore) PruneRevisions(ctx context.Context, contextID string, keep int) (int, error) {
+	tag, err := s.pool.Exec(ctx, `
+		DELETE FROM context_revisions
+		WHERE context_id = $1 AND revision <= (SELECT revision FROM contexts WHERE id = $1) - $2
+	`, contextID, keep)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// PostgresLifecyclePolicyStore implements LifecyclePolicyStore using PostgreSQL
+type PostgresLifecyclePolicyStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresLifecyclePolicyStore creates a new PostgreSQL lifecycle policy store
+func NewPostgresLifecyclePolicyStore(pool *pgxpool.Pool) *PostgresLifecyclePolicyStore {
+	return &PostgresLifecyclePolicyStore{pool: pool}
+}
+
+// Get returns the lifecycle policy of a context type
+func (s *PostgresLifecyclePolicyStore) Get(ctx context.Context, tenantID, contextType string) (*models.LifecyclePolicy, error) {
+	query := `SELECT ` + lifecycleColumns + ` FROM lifecycle_policies WHERE tenant_id = $1 AND context_type = $2`
+
+	p, err := scanLifecyclePolicy(s.pool.QueryRow(ctx, query, tenantID, contextType))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("no lifecycle policy for context type %s: %w", contextType, ErrNotFound)
+	}
+	return p, err
+}
+
+// List returns the lifecycle policies of a tenant
+func (s *PostgresLifecyclePolicyStore) List(ctx context.Context, tenantID string) ([]*models.LifecyclePolicy, error) {
+	query := `SELECT ` + lifecycleColumns + ` FROM lifecycle_policies WHERE tenant_id = $1 ORDER BY context_type`
+
+	rows, err := s.pool.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []*models.LifecyclePolicy{}
+	for rows.Next() {
+		p, err := scanLifecyclePolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+// Set creates or replaces the lifecycle policy of a context type. Existing
+// contexts are checked against it on their next write.
+func (s *PostgresLifecyclePolicyStore) Set(ctx context.Context, p *models.LifecyclePolicy) error {
+	scopes := make([]string, len(p.AllowedScopes))
+	for i, scope := range p.AllowedScopes {
+		scopes[i] = string(scope)
+	}
+	return s.pool.QueryRow(ctx, `
+		INSERT INTO lifecycle_policies (tenant_id, context_type, default_ttl_seconds, max_ttl_seconds, max_payload_bytes, max_revisions, allowed_scopes, non_owner_delete, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (tenant_id, context_type) DO UPDATE
+		SET default_ttl_seconds = EXCLUDED.default_ttl_seconds, max_ttl_seconds = EXCLUDED.max_ttl_seconds,
+			max_payload_bytes = EXCLUDED.max_payload_bytes, max_revisions = EXCLUDED.max_revisions,
+			allowed_scopes = EXCLUDED.allowed_scopes, non_owner_delete = EXCLUDED.non_owner_delete,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, p.TenantID, p.ContextType, int(p.DefaultTTL.Seconds()), int(p.MaxTTL.Seconds()), p.MaxPayloadSize, p.MaxRevisions,
+		scopes, p.NonOwnerDelete).Scan(&p.UpdatedAt)
+}
+
+// Delete removes the lifecycle policy of a context type
+func (s *PostgresLifecyclePolicyStore) Delete(ctx context.Context, tenantID, contextType string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM lifecycle_policies WHERE tenant_id = $1 AND context_type = $2`, tenantID, contextType)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no lifecycle policy for context type %s: %w", contextType, ErrNotFound)
+	}
+	return nil
+}
+
+// scanLifecyclePolicy scans a row of lifecycleColumns
+func scanLifecyclePolicy(row pgx.Row) (*models.LifecyclePolicy, error) {
+	var p models.LifecyclePolicy
+	var defaultTTLSeconds, maxTTLSeconds int
+	var scopes []string
+	err := row.Scan(&p.TenantID, &p.ContextType, &defaultTTLSeconds, &maxTTLSeconds, &p.MaxPayloadSize, &p.MaxRevisions,
+		&scopes, &p.NonOwnerDelete, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.DefaultTTL = time.Duration(defaultTTLSeconds) * time.Second
+	p.MaxTTL = time.Duration(maxTTLSeconds) * time.Second
+	for _, scope := range scopes {
+		p.AllowedScopes = append(p.AllowedScopes, models.ContextScope(scope))
+	}
+	return &p, nil
+}
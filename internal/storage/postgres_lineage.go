@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/acb/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// descendantsCTE selects the IDs of every context derived from $1, directly
+// or transitively. UNION discards rows already found, so it terminates even
+// if the edges contain a cycle.
+const descendantsCTE = `
+	WITH RECURSIVE descendants(id) AS (
+		SELECT child_id FROM context_lineage WHERE parent_id = $1
+		UNION
+		SELECT l.child_id FROM context_lineage l JOIN descendants d ON l.parent_id = d.id
+	)`
+
+// ancestorsCTE selects the IDs in $1 and of every context they derive from,
+// directly or transitively
+const ancestorsCTE = `
+	WITH RECURSIVE ancestors(id) AS (
+		SELECT unnest($1::text[])
+		UNION
+		SELECT l.parent_id FROM context_lineage l JOIN ancestors a ON l.child_id = a.id
+	)`
+
+// ListLinks returns the lineage edges from contextIDs to their parents or
+// children
+func (s *PostgresContextStore) ListLinks(ctx context.Context, contextIDs []string, direction models.LineageDirection) ([]*models.LineageEdge, error) {
+	if err := direction.Validate(); err != nil {
+		return nil, err
+	}
+	column := "child_id"
+	if direction == models.LineageDescendants {
+		column = "parent_id"
+	}
+	query := `SELECT child_id, parent_id, relation FROM context_lineage WHERE ` + column + ` = ANY($1) ORDER BY child_id, parent_id, relation`
+
+	rows, err := s.pool.Query(ctx, query, contextIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edges []*models.LineageEdge
+	for rows.Next() {
+		var e models.LineageEdge
+		if err := rows.Scan(&e.ChildID, &e.ParentID, &e.Relation); err != nil {
+			return nil, err
+		}
+		edges = append(edges, &e)
+	}
+	return edges, rows.Err()
+}
+
+// MarkStale flags the descendants of a context as stale and logs a
+// context.stale event for each newly stale one, in one statement. Deleted
+// descendants are left as they are.
+func (s *PostgresContextStore) MarkStale(ctx context.Context, contextID string) ([]string, error) {
+	query := descendantsCTE + `,
+	marked AS (
+		UPDATE contexts SET stale = TRUE
+		WHERE id IN (SELECT id FROM descendants) AND id <> $1 AND NOT stale AND deleted_at IS NULL
+		RETURNING id, type, agent_id, tenant_id, revision, metadata, access_control
+	),
+	logged AS (
+		INSERT INTO context_events (type, tenant_id, context_id, context_type, agent_id, revision, metadata, access_control, occurred_at)
+		SELECT $2, tenant_id, id, type, agent_id, revision, metadata, access_control, NOW() FROM marked
+	)
+	SELECT id FROM marked`
+
+	rows, err := s.pool.Query(ctx, query, contextID, string(models.ContextEventStale))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// insertLinks stores the lineage edges of a new context. Edges naming a
+// purged context as parent outlive it, so a context created under the ID of
+// a purged one may already have descendants; it is then checked for cycles
+// like an update.
+func insertLinks(ctx context.Context, tx pgx.Tx, c *models.Context) error {
+	if len(c.DerivedFrom) == 0 {
+		return nil
+	}
+	var derived bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM context_lineage WHERE parent_id = $1)`, c.ID).Scan(&derived); err != nil {
+		return err
+	}
+	if derived {
+		if err := checkCycle(ctx, tx, c); err != nil {
+			return err
+		}
+	}
+	return writeLinks(ctx, tx, c)
+}
+
+// replaceLinks replaces the lineage edges of an updated context, failing
+// with ErrCycle if one of its new parents derives from it
+func replaceLinks(ctx context.Context, tx pgx.Tx, c *models.Context) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM context_lineage WHERE child_id = $1`, c.ID); err != nil {
+		return err
+	}
+	if len(c.DerivedFrom) == 0 {
+		return nil
+	}
+	if err := checkCycle(ctx, tx, c); err != nil {
+		return err
+	}
+	return writeLinks(ctx, tx, c)
+}
+
+// checkCycle fails with ErrCycle if one of the parents of c derives from it
+func checkCycle(ctx context.Context, tx pgx.Tx, c *models.Context) error {
+	parents := make([]string, len(c.DerivedFrom))
+	for i, l := range c.DerivedFrom {
+		parents[i] = l.ParentID
+	}
+	// Lock the new parents and their ancestors before the check. A concurrent
+	// update that would close a cycle through them writes one of these rows,
+	// so the two serialize and the later one sees the other's edges. If both
+	// already hold a row the other needs, the deadlock victim is retried.
+	if _, err := tx.Exec(ctx, ancestorsCTE+`
+		SELECT id FROM contexts WHERE id IN (SELECT id FROM ancestors) ORDER BY id FOR UPDATE
+	`, parents); err != nil {
+		if isDeadlock(err) {
+			return fmt.Errorf("lineage of %s changed concurrently: %w", c.ID, ErrConflict)
+		}
+		return err
+	}
+	var descendant string
+	err := tx.QueryRow(ctx, descendantsCTE+`
+		SELECT id FROM descendants WHERE id = ANY($2) LIMIT 1
+	`, c.ID, parents).Scan(&descendant)
+	if err == nil {
+		return fmt.Errorf("context %s already derives from %s: %w", descendant, c.ID, ErrCycle)
+	}
+	if err != pgx.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+// writeLinks inserts the lineage edges of c
+func writeLinks(ctx context.Context, tx pgx.Tx, c *models.Context) error {
+	parents := make([]string, len(c.DerivedFrom))
+	relations := make([]string, len(c.DerivedFrom))
+	for i, l := range c.DerivedFrom {
+		parents[i], relations[i] = l.ParentID, l.Relation
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO context_lineage (tenant_id, child_id, parent_id, relation)
+		SELECT $1, $2, parent_id, relation FROM unnest($3::text[], $4::text[]) AS l(parent_id, relation)
+	`, c.TenantID, c.ID, parents, relations)
+	if err != nil {
+		return fmt.Errorf("failed to record lineage: %w", err)
+	}
+	return nil
+}
+
+// linksJSON encodes derivation links for the derived_from column, NULL when
+// there are none
+func linksJSON(links []models.ContextLink) []byte {
+	if len(links) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(links)
+	return data
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// contextEventsChannel is notified by a trigger on context_events
+const contextEventsChannel = "acb_context_events"
+
+// PostgresEventNotifier implements EventNotifier with LISTEN/NOTIFY, so a
+// write on any replica wakes the subscribers of every replica
+type PostgresEventNotifier struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresEventNotifier creates a new PostgreSQL event notifier
+func NewPostgresEventNotifier(pool *pgxpool.Pool) *PostgresEventNotifier {
+	return &PostgresEventNotifier{pool: pool}
+}
+
+// Listen holds a dedicated connection listening on the context events
+// channel. notify is also called for every tenant once the connection is
+// listening, since events may have been logged while no one was.
+func (n *PostgresEventNotifier) Listen(ctx context.Context, notify func(EventSignal)) error {
+	pooled, err := n.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// A listening session must not be handed back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+contextEventsChannel); err != nil {
+		return fmt.Errorf("failed to listen for context events: %w", err)
+	}
+	notify(EventSignal{})
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to wait for context events: %w", err)
+		}
+		notify(parseEventSignal(notification.Payload))
+	}
+}
+
+// parseEventSignal decodes a notification payload. Payloads that do not
+// decode, such as the empty ones sent before migration 023, signal every
+// tenant.
+func parseEventSignal(payload string) EventSignal {
+	var signal EventSignal
+	if err := json.Unmarshal([]byte(payload), &signal); err != nil {
+		return EventSignal{}
+	}
+	return signal
+}
@@ -0,0 +1,14 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEventSignal(t *testing.T) {
+	assert.Equal(t, EventSignal{TenantID: "default", ContextID: "ctx-1"}, parseEventSignal(`{"tenant_id":"default","context_id":"ctx-1"}`))
+	assert.Equal(t, EventSignal{TenantID: "default"}, parseEventSignal(`{"tenant_id":"default"}`))
+	// Payloads of the trigger before migration 023 wake every tenant
+	assert.Equal(t, EventSignal{}, parseEventSignal(""))
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/acb/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// schemaColumns is the column list scanned by scanSchema
+const schemaColumns = `id, tenant_id, context_type, version, document, description, created_by, created_at`
+
+// PostgresSchemaStore implements SchemaStore using PostgreSQL
+type PostgresSchemaStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresSchemaStore creates a new PostgreSQL schema store
+func NewPostgresSchemaStore(pool *pgxpool.Pool) *PostgresSchemaStore {
+	return &PostgresSchemaStore{pool: pool}
+}
+
+// Create inserts s as the next version of its context type. Registrations
+// for the same type are serialised with a transaction-scoped advisory lock
+// so concurrent writers never compute the same version. A non-zero s.Version
+// must be the next version or ErrConflict is returned.
+func (s *PostgresSchemaStore) Create(ctx context.Context, sc *models.Schema) error {
+	query := `
+		INSERT INTO schemas (id, tenant_id, context_type, version, document, description, created_by, created_at)
+		SELECT $1, $2, $3, COALESCE(MAX(version), 0) + 1, $4, $5, $6, $7
+		FROM schemas
+		WHERE tenant_id = $2 AND context_type = $3
+		HAVING $8::int = 0 OR COALESCE(MAX(version), 0) + 1 = $8
+		RETURNING version
+	`
+
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "schemas/"+sc.TenantID+"/"+sc.ContextType); err != nil {
+			return err
+		}
+		err := tx.QueryRow(ctx, query,
+			sc.ID,
+			sc.TenantID,
+			sc.ContextType,
+			[]byte(sc.Document),
+			sc.Description,
+			sc.CreatedBy,
+			sc.CreatedAt,
+			sc.Version,
+		).Scan(&sc.Version)
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("version %d of %s is not the next version: %w", sc.Version, sc.ContextType, ErrConflict)
+		}
+		return err
+	})
+
+	if IsUniqueViolation(err) {
+		return fmt.Errorf("schema with ID %s already exists", sc.ID)
+	}
+	return err
+}
+
+// Get retrieves a schema by ID
+func (s *PostgresSchemaStore) Get(ctx context.Context, schemaID string) (*models.Schema, error) {
+	query := `SELECT ` + schemaColumns + ` FROM schemas WHERE id = $1`
+
+	sc, err := scanSchema(s.pool.QueryRow(ctx, query, schemaID))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("schema not found: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// GetLatest returns the highest version registered for a context type
+func (s *PostgresSchemaStore) GetLatest(ctx context.Context, tenantID, contextType string) (*models.Schema, error) {
+	query := `SELECT ` + schemaColumns + ` FROM schemas WHERE tenant_id = $1 AND context_type = $2 ORDER BY version DESC LIMIT 1`
+
+	sc, err := scanSchema(s.pool.QueryRow(ctx, query, tenantID, contextType))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("no schema registered for %s: %w", contextType, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// List retrieves schemas matching filters, newest version first
+func (s *PostgresSchemaStore) List(ctx context.Context, filters *SchemaFilters) ([]*models.Schema, error) {
+	query := `SELECT ` + schemaColumns + ` FROM schemas WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+
+	if filters == nil {
+		filters = &SchemaFilters{Limit: 100}
+	}
+
+	if filters.TenantID != "" {
+		query += fmt.Sprintf(" AND tenant_id = $%d", argIndex)
+		args = append(args, filters.TenantID)
+		argIndex++
+	}
+
+	if filters.ContextType != "" {
+		query += fmt.Sprintf(" AND context_type = $%d", argIndex)
+		args = append(args, filters.ContextType)
+		argIndex++
+	}
+
+	query += " ORDER BY context_type, version DESC"
+
+	if filters.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argIndex)
+		args = append(args, filters.Limit)
+		argIndex++
+	}
+
+	if filters.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argIndex)
+		args = append(args, filters.Offset)
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []*models.Schema
+	for rows.Next() {
+		sc, err := scanSchema(rows)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, sc)
+	}
+	return schemas, rows.Err()
+}
+
+// Delete removes a schema that no context references. Deleted contexts hold
+// on to their schema until they are purged, so that restoring one never
+// revives a dangling schema reference.
+func (s *PostgresSchemaStore) Delete(ctx context.Context, schemaID string) error {
+	query := `
+		DELETE FROM schemas
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM contexts WHERE schema_id = $1)
+	`
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, schemaID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() > 0 {
+			return nil
+		}
+
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schemas WHERE id = $1)`, schemaID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("schema not found: %w", ErrNotFound)
+		}
+		return fmt.Errorf("schema %s is referenced by contexts: %w", schemaID, ErrConflict)
+	})
+}
+
+// GetCompatibility returns the compatibility mode configured for a context type
+func (s *PostgresSchemaStore) GetCompatibility(ctx context.Context, tenantID, contextType string) (models.SchemaCompatibility, error) {
+	query := `SELECT compatibility FROM schema_compatibility WHERE tenant_id = $1 AND context_type = $2`
+
+	var mode string
+	err := s.pool.QueryRow(ctx, query, tenantID, contextType).Scan(&mode)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return models.SchemaCompatibility(mode), nil
+}
+
+// SetCompatibility configures the compatibility mode of a context type
+func (s *PostgresSchemaStore) SetCompatibility(ctx context.Context, tenantID, contextType string, mode models.SchemaCompatibility) error {
+	query := `
+		INSERT INTO schema_compatibility (tenant_id, context_type, compatibility, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (tenant_id, context_type) DO UPDATE SET compatibility = EXCLUDED.compatibility, updated_at = EXCLUDED.updated_at
+	`
+	_, err := s.pool.Exec(ctx, query, tenantID, contextType, string(mode))
+	return err
+}
+
+// scanSchema scans a row selected with schemaColumns
+func scanSchema(row rowScanner) (*models.Schema, error) {
+	var sc models.Schema
+	var document []byte
+	var description sql.NullString
+
+	err := row.Scan(
+		&sc.ID,
+		&sc.TenantID,
+		&sc.ContextType,
+		&sc.Version,
+		&document,
+		&description,
+		&sc.CreatedBy,
+		&sc.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.Document = document
+	sc.Description = description.String
+	return &sc, nil
+}
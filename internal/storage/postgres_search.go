@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/acb/internal/constants"
+	"github.com/acb/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// headlineOptions configures the snippets returned by Search
+const headlineOptions = `StartSel=<mark>, StopSel=</mark>, MinWords=10, MaxWords=30, MaxFragments=2, FragmentDelimiter=" ... "`
+
+// Search returns contexts matching a full-text query, most relevant first.
+// The query uses web search syntax: quoted phrases, OR, and - to exclude a
+// term. Metadata matches rank above payload matches.
+func (s *PostgresContextStore) Search(ctx context.Context, query string, filters *ContextFilters) ([]*models.ContextSearchResult, error) {
+	sqlQuery, args := buildContextSearchQuery(query, filters)
+
+	rows, err := s.pool.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.ContextSearchResult
+	for rows.Next() {
+		result := &models.ContextSearchResult{}
+		c, err := s.scanContext(ctx, extraColumns{row: rows, dest: []any{&result.Rank}})
+		if err != nil {
+			return nil, err
+		}
+		result.Context = c
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.highlight(ctx, query, results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// highlight sets the snippet of each result, generated from the same text
+// the search vector was built from
+func (s *PostgresContextStore) highlight(ctx context.Context, query string, results []*models.ContextSearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	docs := make([]string, len(results))
+	for i, result := range results {
+		docs[i] = s.searchDocument(result.Context)
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT ts_headline('simple', doc, websearch_to_tsquery('simple', $1), $3)
+		FROM unnest($2::text[]) WITH ORDINALITY AS d(doc, n)
+		ORDER BY n
+	`, query, docs, headlineOptions)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next() && i < len(results); i++ {
+		if err := rows.Scan(&results[i].Snippet); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ReindexSearch builds the search vector of up to limit contexts that have
+// none, returning how many it indexed. Rows written before search was
+// enabled are backfilled by migration, except compressed ones, whose payload
+// can only be decoded here. Rows locked by a concurrent reindex are skipped.
+func (s *PostgresContextStore) ReindexSearch(ctx context.Context, limit int) (int, error) {
+	indexed := 0
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		// Encrypted payloads are not indexed, so their metadata is enough
+		result, err := tx.Exec(ctx, `
+			UPDATE contexts SET search_vector = context_search_vector(metadata, NULL)
+			WHERE id IN (
+				SELECT id FROM contexts
+				WHERE search_vector IS NULL AND payload_key_id IS NOT NULL
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+		`, limit)
+		if err != nil {
+			return err
+		}
+		indexed = int(result.RowsAffected())
+		if indexed >= limit {
+			return nil
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT `+contextColumns+` FROM contexts
+			WHERE search_vector IS NULL AND payload_key_id IS NULL
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		`, limit-indexed)
+		if err != nil {
+			return err
+		}
+		var contexts []*models.Context
+		for rows.Next() {
+			c, err := s.scanContext(ctx, rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			contexts = append(contexts, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, c := range contexts {
+			metadataJSON, _ := json.Marshal(c.Metadata)
+			_, err := tx.Exec(ctx, `
+				UPDATE contexts SET search_vector = context_search_vector($2::jsonb, $3) WHERE id = $1
+			`, c.ID, metadataJSON, searchText(c.Payload))
+			if err != nil {
+				return err
+			}
+		}
+		indexed += len(contexts)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return indexed, nil
+}
+
+// searchText returns the payload text of c to index, or nil when the
+// payload is not indexed because it is encrypted at rest
+func (s *PostgresContextStore) searchText(payload []byte) *string {
+	if s.cipher != nil {
+		return nil
+	}
+	return searchText(payload)
+}
+
+// searchDocument renders the metadata and payload text indexed for c, for
+// ts_headline to pick snippets from
+func (s *PostgresContextStore) searchDocument(c *models.Context) string {
+	keys := make([]string, 0, len(c.Metadata))
+	for key := range c.Metadata {
+		if s.cipher != nil && s.sensitive[key] {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var doc strings.Builder
+	for _, key := range keys {
+		doc.WriteString(key + ": " + c.Metadata[key] + "\n")
+	}
+	if text := s.searchText(c.Payload); text != nil {
+		doc.WriteString(*text)
+	}
+	return doc.String()
+}
+
+// searchText returns the payload text to index, truncated to
+// MaxSearchTextSize, or nil for payloads that are empty or not text.
+// context_search_vector indexes JSON by its keys, strings and numbers and
+// other text as prose.
+func searchText(payload []byte) *string {
+	if len(payload) == 0 || !utf8.Valid(payload) || bytes.IndexByte(payload, 0) >= 0 {
+		return nil
+	}
+	if len(payload) > constants.MaxSearchTextSize {
+		end := constants.MaxSearchTextSize
+		for end > 0 && !utf8.RuneStart(payload[end]) {
+			end--
+		}
+		payload = payload[:end]
+	}
+	text := string(payload)
+	return &text
+}
+
+// buildContextSearchQuery builds the parameterised SELECT used by Search. The
+// rank is selected after contextColumns.
+func buildContextSearchQuery(query string, filters *ContextFilters) (string, []interface{}) {
+	if filters == nil {
+		filters = &ContextFilters{Limit: 100}
+	}
+
+	where, args, argIndex := contextFilterClause(filters, 2)
+	sqlQuery := `SELECT ` + contextColumns + `, ts_rank_cd(search_vector, q)::float8 AS rank` +
+		` FROM contexts, websearch_to_tsquery('simple', $1) AS q` +
+		` WHERE search_vector @@ q` + where
+	sqlQuery += " ORDER BY rank DESC, created_at DESC"
+
+	page, pageArgs := pageClause(filters, argIndex)
+	return sqlQuery + page, append(append([]interface{}{query}, args...), pageArgs...)
+}
+
+// extraColumns scans the columns selected after those a scan function
+// expects into dest
+type extraColumns struct {
+	row  rowScanner
+	dest []any
+}
+
+func (r extraColumns) Scan(dest ...any) error {
+	return r.row.Scan(append(dest, r.dest...)...)
+}
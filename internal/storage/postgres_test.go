@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -41,7 +42,14 @@ func TestIsUniqueViolation(t *testing.T) {
 func TestIsNotFound(t *testing.T) {
 	// Test pgx.ErrNoRows
 	assert.True(t, IsNotFound(pgx.ErrNoRows))
+	assert.True(t, IsNotFound(fmt.Errorf("context not found: %w", ErrNotFound)))
 
 	// Test other error
 	assert.False(t, IsNotFound(nil))
 }
+
+func TestIsConflict(t *testing.T) {
+	assert.True(t, IsConflict(fmt.Errorf("context is at revision 3: %w", ErrConflict)))
+	assert.False(t, IsConflict(ErrNotFound))
+	assert.False(t, IsConflict(nil))
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/acb/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrCacheMiss is returned by cache lookups when nothing is cached for a key
+var ErrCacheMiss = errors.New("cache miss")
+
+// missingEntry is cached for IDs known not to exist, and invalidatedEntry
+// guards recently invalidated IDs against stale read-through fills. Neither
+// is valid JSON, so they cannot be mistaken for a cached object.
+const (
+	missingEntry     = "-"
+	invalidatedEntry = "~"
+)
+
+const (
+	agentCachePrefix   = "acb:cache:agent:"
+	contextCachePrefix = "acb:cache:context:"
+)
+
+// redisClient is the subset of the Redis client the caches use
+type redisClient interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// redisCache stores JSON documents under a key prefix
+type redisCache struct {
+	client redisClient
+	prefix string
+}
+
+// get decodes the entry for id into out. It returns ErrCacheMiss when there
+// is none and ErrNotFound when id is cached as missing.
+func (c *redisCache) get(ctx context.Context, id string, out any) error {
+	data, err := c.client.Get(ctx, c.prefix+id).Bytes()
+	if err == redis.Nil {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+	switch string(data) {
+	case missingEntry:
+		return fmt.Errorf("cached as missing: %w", ErrNotFound)
+	case invalidatedEntry:
+		return ErrCacheMiss
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+	return nil
+}
+
+func (c *redisCache) set(ctx context.Context, id string, value any, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	return c.client.Set(ctx, c.prefix+id, data, ttl).Err()
+}
+
+// fill only writes when the key is unset, so a read-through that raced with
+// a write cannot replace the guard left by invalidate
+func (c *redisCache) fill(ctx context.Context, id string, value any, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	return c.client.SetNX(ctx, c.prefix+id, data, ttl).Err()
+}
+
+// setMissing only writes when the key is unset, so a lookup that raced with
+// a create cannot hide the created object behind a stale miss
+func (c *redisCache) setMissing(ctx context.Context, id string, ttl time.Duration) error {
+	return c.client.SetNX(ctx, c.prefix+id, missingEntry, ttl).Err()
+}
+
+func (c *redisCache) delete(ctx context.Context, id string) error {
+	return c.client.Del(ctx, c.prefix+id).Err()
+}
+
+// invalidate replaces the entry for id with a guard that expires after ttl
+func (c *redisCache) invalidate(ctx context.Context, id string, ttl time.Duration) error {
+	return c.client.Set(ctx, c.prefix+id, invalidatedEntry, ttl).Err()
+}
+
+// RedisAgentCache implements AgentCache using Redis
+type RedisAgentCache struct {
+	cache redisCache
+}
+
+// NewRedisAgentCache creates a new Redis agent cache
+func NewRedisAgentCache(store *RedisStore) *RedisAgentCache {
+	return &RedisAgentCache{cache: redisCache{client: store.client, prefix: agentCachePrefix}}
+}
+
+// Get retrieves a cached agent
+func (c *RedisAgentCache) Get(ctx context.Context, agentID string) (*models.Agent, error) {
+	var agent models.Agent
+	if err := c.cache.get(ctx, agentID, &agent); err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// Set caches an agent
+func (c *RedisAgentCache) Set(ctx context.Context, agent *models.Agent, ttl time.Duration) error {
+	return c.cache.set(ctx, agent.ID, agent, ttl)
+}
+
+// SetMissing caches that an agent does not exist
+func (c *RedisAgentCache) SetMissing(ctx context.Context, agentID string, ttl time.Duration) error {
+	return c.cache.setMissing(ctx, agentID, ttl)
+}
+
+// Delete removes a cached agent
+func (c *RedisAgentCache) Delete(ctx context.Context, agentID string) error {
+	return c.cache.delete(ctx, agentID)
+}
+
+// RedisContextCache implements ContextCache using Redis
+type RedisContextCache struct {
+	cache  redisCache
+	cipher PayloadCipher
+}
+
+// RedisContextCacheOption configures a RedisContextCache
+type RedisContextCacheOption func(*RedisContextCache)
+
+// WithCacheEncryption seals cached contexts with the tenant's active data
+// key, so payloads and sensitive metadata encrypted at rest in Postgres are
+// never written to Redis in plaintext
+func WithCacheEncryption(cipher PayloadCipher) RedisContextCacheOption {
+	return func(c *RedisContextCache) {
+		c.cipher = cipher
+	}
+}
+
+// sealedEntry is a cached context sealed with a data key
+type sealedEntry struct {
+	KeyID  string `json:"key_id"`
+	Sealed []byte `json:"sealed"`
+}
+
+// NewRedisContextCache creates a new Redis context cache
+func NewRedisContextCache(store *RedisStore, opts ...RedisContextCacheOption) *RedisContextCache {
+	return newRedisContextCache(store.client, opts...)
+}
+
+func newRedisContextCache(client redisClient, opts ...RedisContextCacheOption) *RedisContextCache {
+	c := &RedisContextCache{cache: redisCache{client: client, prefix: contextCachePrefix}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get retrieves a cached context
+func (c *RedisContextCache) Get(ctx context.Context, contextID string) (*models.Context, error) {
+	var cx models.Context
+	if c.cipher == nil {
+		if err := c.cache.get(ctx, contextID, &cx); err != nil {
+			return nil, err
+		}
+		return &cx, nil
+	}
+
+	var entry sealedEntry
+	if err := c.cache.get(ctx, contextID, &entry); err != nil {
+		return nil, err
+	}
+	data, err := c.cipher.Open(ctx, entry.KeyID, entry.Sealed, payloadAAD(contextID, "cache"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache entry: %w", err)
+	}
+	if err := json.Unmarshal(data, &cx); err != nil {
+		return nil, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+	return &cx, nil
+}
+
+// Set caches a context
+func (c *RedisContextCache) Set(ctx context.Context, cx *models.Context, ttl time.Duration) error {
+	entry, err := c.entry(ctx, cx)
+	if err != nil {
+		return err
+	}
+	return c.cache.set(ctx, cx.ID, entry, ttl)
+}
+
+// Fill caches a context read from the store unless its key is set
+func (c *RedisContextCache) Fill(ctx context.Context, cx *models.Context, ttl time.Duration) error {
+	entry, err := c.entry(ctx, cx)
+	if err != nil {
+		return err
+	}
+	return c.cache.fill(ctx, cx.ID, entry, ttl)
+}
+
+// entry returns what is cached for a context: the context itself, or a
+// sealedEntry when cached contexts are encrypted
+func (c *RedisContextCache) entry(ctx context.Context, cx *models.Context) (any, error) {
+	if c.cipher == nil {
+		return cx, nil
+	}
+
+	data, err := json.Marshal(cx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	keyID, err := c.cipher.ActiveKey(ctx, cx.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data key: %w", err)
+	}
+	sealed, err := c.cipher.Seal(ctx, keyID, data, payloadAAD(cx.ID, "cache"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal cache entry: %w", err)
+	}
+	return sealedEntry{KeyID: keyID, Sealed: sealed}, nil
+}
+
+// SetMissing caches that a context does not exist
+func (c *RedisContextCache) SetMissing(ctx context.Context, contextID string, ttl time.Duration) error {
+	return c.cache.setMissing(ctx, contextID, ttl)
+}
+
+// Invalidate replaces a cached context with a guard against stale fills
+func (c *RedisContextCache) Invalidate(ctx context.Context, contextID string, ttl time.Duration) error {
+	return c.cache.invalidate(ctx, contextID, ttl)
+}
@@ -2,9 +2,14 @@ package storage
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/acb/internal/models"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -87,3 +92,148 @@ func TestRedisStore_Increment(t *testing.T) {
 	// Cleanup
 	_ = store.Delete(ctx, "test-counter")
 }
+
+func TestRedisContextCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	store, err := NewRedisStore("localhost:6379", "")
+	require.NoError(t, err)
+	defer store.Close()
+
+	ctx := context.Background()
+	cache := NewRedisContextCache(store)
+	defer store.Delete(ctx, contextCachePrefix+"cache-test")
+
+	_, err = cache.Get(ctx, "cache-test")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	require.NoError(t, cache.SetMissing(ctx, "cache-test", time.Minute))
+	_, err = cache.Get(ctx, "cache-test")
+	assert.True(t, IsNotFound(err))
+
+	c := &models.Context{ID: "cache-test", Payload: []byte(`{"a":1}`), Revision: 3}
+	require.NoError(t, cache.Set(ctx, c, time.Minute))
+	// A miss never overwrites a cached object
+	require.NoError(t, cache.SetMissing(ctx, "cache-test", time.Minute))
+	got, err := cache.Get(ctx, "cache-test")
+	require.NoError(t, err)
+	assert.Equal(t, c.Payload, got.Payload)
+	assert.Equal(t, 3, got.Revision)
+
+	require.NoError(t, cache.Invalidate(ctx, "cache-test", time.Minute))
+	_, err = cache.Get(ctx, "cache-test")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+// memoryRedis is a redisClient that keeps raw values in memory, so tests can
+// inspect exactly what would be written to Redis
+type memoryRedis struct {
+	values map[string]string
+}
+
+func (r *memoryRedis) Get(ctx context.Context, key string) *redis.StringCmd {
+	value, ok := r.values[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (r *memoryRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	r.values[key] = fmt.Sprint(value)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (r *memoryRedis) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if _, ok := r.values[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	r.Set(ctx, key, value, expiration)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (r *memoryRedis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	for _, key := range keys {
+		delete(r.values, key)
+	}
+	return redis.NewIntResult(int64(len(keys)), nil)
+}
+
+// reverseCipher is a PayloadCipher whose sealed data does not contain the
+// plaintext
+type reverseCipher struct{ prefixCipher }
+
+func (c reverseCipher) Seal(ctx context.Context, keyID string, plaintext, aad []byte) ([]byte, error) {
+	sealed, err := c.prefixCipher.Seal(ctx, keyID, plaintext, aad)
+	slices.Reverse(sealed)
+	return sealed, err
+}
+
+func (c reverseCipher) Open(ctx context.Context, keyID string, ciphertext, aad []byte) ([]byte, error) {
+	opened := slices.Clone(ciphertext)
+	slices.Reverse(opened)
+	return c.prefixCipher.Open(ctx, keyID, opened, aad)
+}
+
+func TestRedisContextCache_Encrypted(t *testing.T) {
+	ctx := context.Background()
+	client := &memoryRedis{values: map[string]string{}}
+	cache := newRedisContextCache(client, WithCacheEncryption(reverseCipher{}))
+
+	c := &models.Context{ID: "ctx-1", TenantID: "default", Payload: []byte("top secret payload"),
+		Metadata: map[string]string{"patient": "jane doe"}, Revision: 2}
+	require.NoError(t, cache.Set(ctx, c, time.Minute))
+
+	raw := client.values[contextCachePrefix+"ctx-1"]
+	require.NotEmpty(t, raw)
+	for _, plaintext := range []string{"top secret payload", base64.StdEncoding.EncodeToString(c.Payload), "jane doe"} {
+		assert.NotContains(t, raw, plaintext)
+	}
+
+	got, err := cache.Get(ctx, "ctx-1")
+	require.NoError(t, err)
+	assert.Equal(t, c.Payload, got.Payload)
+	assert.Equal(t, c.Metadata, got.Metadata)
+	assert.Equal(t, 2, got.Revision)
+
+	// Entries sealed for another context do not open
+	client.values[contextCachePrefix+"ctx-2"] = raw
+	_, err = cache.Get(ctx, "ctx-2")
+	assert.Error(t, err)
+
+	// Misses are still cached without a key
+	require.NoError(t, cache.SetMissing(ctx, "ctx-3", time.Minute))
+	_, err = cache.Get(ctx, "ctx-3")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestRedisContextCache_InvalidateGuardsFills(t *testing.T) {
+	ctx := context.Background()
+	client := &memoryRedis{values: map[string]string{}}
+	cache := newRedisContextCache(client)
+	stale := &models.Context{ID: "ctx-1", Payload: []byte("v1"), Revision: 1}
+	current := &models.Context{ID: "ctx-1", Payload: []byte("v2"), Revision: 2}
+
+	// A read that loaded revision 1 before an update cannot cache it after
+	// the update invalidated the entry
+	require.NoError(t, cache.Fill(ctx, stale, time.Minute))
+	require.NoError(t, cache.Invalidate(ctx, "ctx-1", time.Second))
+	_, err := cache.Get(ctx, "ctx-1")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	require.NoError(t, cache.Fill(ctx, stale, time.Minute))
+	require.NoError(t, cache.SetMissing(ctx, "ctx-1", time.Minute))
+	_, err = cache.Get(ctx, "ctx-1")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	// Writes replace the guard, and fills never replace an entry
+	require.NoError(t, cache.Set(ctx, current, time.Minute))
+	require.NoError(t, cache.Fill(ctx, stale, time.Minute))
+	got, err := cache.Get(ctx, "ctx-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Revision)
+}
@@ -0,0 +1,378 @@
+package transfer
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/acb/internal/models"
+)
+
+// ErrInvalidArchive is returned when an archive is malformed, truncated or
+// does not match its manifest
+var ErrInvalidArchive = errors.New("invalid archive")
+
+// Format is the encoding of a tenant archive
+type Format string
+
+const (
+	FormatNDJSON Format = "ndjson" // One JSON record per line, the manifest last
+	FormatTar    Format = "tar"    // One JSON file per record, manifest.json last
+)
+
+// Validate checks that f is a known archive format
+func (f Format) Validate() error {
+	switch f {
+	case FormatNDJSON, FormatTar:
+		return nil
+	default:
+		return fmt.Errorf("unknown archive format %q", f)
+	}
+}
+
+// ContentType returns the media type of archives in format f
+func (f Format) ContentType() string {
+	if f == FormatTar {
+		return "application/x-tar"
+	}
+	return "application/x-ndjson"
+}
+
+// ManifestVersion is the archive layout written by Export
+const ManifestVersion = 1
+
+// Archive sections, in the order they are written
+const (
+	sectionAgents   = "agents"
+	sectionContexts = "contexts"
+)
+
+// manifestFile names the manifest entry of a tar archive
+const manifestFile = "manifest.json"
+
+// Manifest describes an archive. Each checksum is the SHA-256 of the JSON of
+// every record of a section, each followed by a newline, in archive order.
+type Manifest struct {
+	Version    int               `json:"version"`
+	TenantID   string            `json:"tenant_id"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Counts     map[string]int    `json:"counts"`
+	Checksums  map[string]string `json:"checksums"`
+}
+
+// ndjsonKinds maps the record kinds of NDJSON lines to their sections
+var ndjsonKinds = map[string]string{
+	"agent":   sectionAgents,
+	"context": sectionContexts,
+}
+
+// ndjsonLine is one line of an NDJSON archive
+type ndjsonLine struct {
+	Kind string          `json:"kind"` // "agent", "context" or "manifest"
+	Data json.RawMessage `json:"data"`
+}
+
+// digests accumulates the counts and checksums of the sections of an archive
+type digests struct {
+	counts map[string]int
+	hashes map[string]hash.Hash
+}
+
+func newDigests() *digests {
+	return &digests{counts: make(map[string]int), hashes: make(map[string]hash.Hash)}
+}
+
+func (d *digests) add(section string, data []byte) {
+	h, ok := d.hashes[section]
+	if !ok {
+		h = sha256.New()
+		d.hashes[section] = h
+	}
+	h.Write(data)
+	h.Write([]byte("\n"))
+	d.counts[section]++
+}
+
+// checksums returns the checksum of every section, including empty ones
+func (d *digests) checksums() map[string]string {
+	sums := make(map[string]string, 2)
+	for _, section := range []string{sectionAgents, sectionContexts} {
+		h, ok := d.hashes[section]
+		if !ok {
+			h = sha256.New()
+		}
+		sums[section] = "sha256:" + hex.EncodeToString(h.Sum(nil))
+	}
+	return sums
+}
+
+// archiveWriter writes the records of an archive, then its manifest
+type archiveWriter interface {
+	record(section, id string, data []byte) error
+	manifest(m *Manifest) error
+}
+
+func newArchiveWriter(w io.Writer, format Format, modTime time.Time) archiveWriter {
+	if format == FormatTar {
+		return &tarWriter{tw: tar.NewWriter(w), modTime: modTime}
+	}
+	return &ndjsonWriter{w: bufio.NewWriter(w)}
+}
+
+type ndjsonWriter struct {
+	w *bufio.Writer
+}
+
+func (a *ndjsonWriter) record(section, _ string, data []byte) error {
+	kind := strings.TrimSuffix(section, "s")
+	return a.line(kind, data)
+}
+
+func (a *ndjsonWriter) manifest(m *Manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := a.line("manifest", data); err != nil {
+		return err
+	}
+	return a.w.Flush()
+}
+
+// line writes data verbatim, so that its checksum holds when read back
+func (a *ndjsonWriter) line(kind string, data []byte) error {
+	if _, err := fmt.Fprintf(a.w, `{"kind":%q,"data":`, kind); err != nil {
+		return err
+	}
+	if _, err := a.w.Write(data); err != nil {
+		return err
+	}
+	_, err := a.w.WriteString("}\n")
+	return err
+}
+
+type tarWriter struct {
+	tw      *tar.Writer
+	modTime time.Time
+}
+
+func (a *tarWriter) record(section, id string, data []byte) error {
+	return a.file(section+"/"+url.PathEscape(id)+".json", data)
+}
+
+func (a *tarWriter) manifest(m *Manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := a.file(manifestFile, data); err != nil {
+		return err
+	}
+	return a.tw.Close()
+}
+
+func (a *tarWriter) file(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  a.modTime,
+		Typeflag: tar.TypeReg,
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := a.tw.Write(data)
+	return err
+}
+
+// archive is a decoded tenant archive
+type archive struct {
+	manifest *Manifest
+	agents   []*models.Agent
+	contexts []*models.ContextRecord
+}
+
+// readArchive decodes an NDJSON or tar archive, telling them apart by the
+// tar header magic, and verifies it against its manifest
+func readArchive(r io.Reader) (*archive, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(262)
+	a := &archive{}
+	d := newDigests()
+	var err error
+	if len(head) == 262 && bytes.HasPrefix(head[257:], []byte("ustar")) {
+		err = a.readTar(br, d)
+	} else {
+		err = a.readNDJSON(br, d)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := a.verify(d); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *archive) readNDJSON(r io.Reader, d *digests) error {
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var l ndjsonLine
+		err := dec.Decode(&l)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: line %d: %w", ErrInvalidArchive, line, err)
+		}
+		if l.Kind == "manifest" {
+			err = a.setManifest(l.Data)
+		} else if section, ok := ndjsonKinds[l.Kind]; ok {
+			err = a.add(section, l.Data, d)
+		} else {
+			err = fmt.Errorf("%w: unknown record kind %q", ErrInvalidArchive, l.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+func (a *archive) readTar(r io.Reader, d *digests) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidArchive, hdr.Name, err)
+		}
+		switch section := path.Dir(hdr.Name); {
+		case hdr.Name == manifestFile:
+			err = a.setManifest(data)
+		case section == sectionAgents || section == sectionContexts:
+			err = a.add(section, data, d)
+		default:
+			err = fmt.Errorf("%w: unexpected file", ErrInvalidArchive)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+	}
+}
+
+func (a *archive) setManifest(data []byte) error {
+	if a.manifest != nil {
+		return fmt.Errorf("%w: more than one manifest", ErrInvalidArchive)
+	}
+	a.manifest = &Manifest{}
+	if err := json.Unmarshal(data, a.manifest); err != nil {
+		return fmt.Errorf("%w: manifest: %v", ErrInvalidArchive, err)
+	}
+	return nil
+}
+
+// add decodes and validates a record of the given section
+func (a *archive) add(section string, data []byte, d *digests) error {
+	if a.manifest != nil {
+		return fmt.Errorf("%w: record after the manifest", ErrInvalidArchive)
+	}
+	d.add(section, data)
+	if section == sectionAgents {
+		var agent models.Agent
+		if err := json.Unmarshal(data, &agent); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if err := agent.Validate(); err != nil {
+			return fmt.Errorf("%w: agent %s: %v", ErrInvalidArchive, agent.ID, err)
+		}
+		a.agents = append(a.agents, &agent)
+		return nil
+	}
+
+	var rec models.ContextRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if rec.Context == nil || rec.ID == "" {
+		return fmt.Errorf("%w: context ID cannot be empty", ErrInvalidArchive)
+	}
+	if err := rec.Validate(); err != nil {
+		return fmt.Errorf("%w: context %s: %v", ErrInvalidArchive, rec.ID, err)
+	}
+	seen := make(map[int]bool, len(rec.Revisions))
+	for _, r := range rec.Revisions {
+		if r == nil || r.ContextID != rec.ID || r.Revision < 1 || seen[r.Revision] {
+			return fmt.Errorf("%w: context %s has an invalid revision history", ErrInvalidArchive, rec.ID)
+		}
+		seen[r.Revision] = true
+	}
+	a.contexts = append(a.contexts, &rec)
+	return nil
+}
+
+// verify checks the records read against the manifest: its version, counts
+// and checksums, the tenant of every record and that IDs are unique
+func (a *archive) verify(d *digests) error {
+	m := a.manifest
+	if m == nil {
+		return fmt.Errorf("%w: no manifest, the archive may be truncated", ErrInvalidArchive)
+	}
+	if m.Version != ManifestVersion {
+		return fmt.Errorf("%w: unsupported manifest version %d", ErrInvalidArchive, m.Version)
+	}
+	if m.TenantID == "" {
+		return fmt.Errorf("%w: manifest has no tenant", ErrInvalidArchive)
+	}
+	sums := d.checksums()
+	for _, section := range []string{sectionAgents, sectionContexts} {
+		if m.Counts[section] != d.counts[section] {
+			return fmt.Errorf("%w: manifest lists %d %s, archive has %d", ErrInvalidArchive, m.Counts[section], section, d.counts[section])
+		}
+		if m.Checksums[section] != sums[section] {
+			return fmt.Errorf("%w: checksum mismatch for %s", ErrInvalidArchive, section)
+		}
+	}
+
+	agentIDs := make(map[string]bool, len(a.agents))
+	for _, agent := range a.agents {
+		if agent.TenantID != m.TenantID {
+			return fmt.Errorf("%w: agent %s belongs to tenant %s, not %s", ErrInvalidArchive, agent.ID, agent.TenantID, m.TenantID)
+		}
+		if agentIDs[agent.ID] {
+			return fmt.Errorf("%w: agent %s appears more than once", ErrInvalidArchive, agent.ID)
+		}
+		agentIDs[agent.ID] = true
+	}
+	contextIDs := make(map[string]bool, len(a.contexts))
+	for _, rec := range a.contexts {
+		if rec.TenantID != m.TenantID {
+			return fmt.Errorf("%w: context %s belongs to tenant %s, not %s", ErrInvalidArchive, rec.ID, rec.TenantID, m.TenantID)
+		}
+		if contextIDs[rec.ID] {
+			return fmt.Errorf("%w: context %s appears more than once", ErrInvalidArchive, rec.ID)
+		}
+		contextIDs[rec.ID] = true
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+)
+
+// exportPageSize is how many agents or contexts Export reads per query
+const exportPageSize = 500
+
+// Service exports the agents and contexts of a tenant to archives and
+// imports archives, possibly into another tenant or environment
+type Service struct {
+	agents   storage.AgentStore
+	contexts storage.ContextStore
+	importer storage.ImportStore
+	now      func() time.Time
+}
+
+// NewService creates a new transfer service
+func NewService(agents storage.AgentStore, contexts storage.ContextStore, importer storage.ImportStore) *Service {
+	return &Service{agents: agents, contexts: contexts, importer: importer, now: time.Now}
+}
+
+// ImportOptions controls an import
+type ImportOptions struct {
+	TenantID   string                // Tenant to import into; the archive's tenant when empty
+	OnConflict models.ConflictPolicy // How to treat existing IDs; ConflictFail when empty
+}
+
+// CallerTenant returns the tenant of the caller, who must administer it to
+// export or import it
+func CallerTenant(ctx context.Context) (string, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return "", acberrors.Unauthorized("caller identity required")
+	}
+	if !claims.HasRole(auth.RoleAdmin) {
+		return "", acberrors.Forbidden("only admins may export or import a tenant")
+	}
+	return claims.TenantID, nil
+}
+
+// Export streams every agent and unexpired context of a tenant to w, each
+// context with its revision history and access control. The manifest is
+// written last, so an archive cut short fails to import.
+func (s *Service) Export(ctx context.Context, w io.Writer, tenantID string, format Format) (*Manifest, error) {
+	if err := format.Validate(); err != nil {
+		return nil, acberrors.ValidationError(err.Error()).WithError(err)
+	}
+	m := &Manifest{Version: ManifestVersion, TenantID: tenantID, ExportedAt: s.now().UTC()}
+	aw := newArchiveWriter(w, format, m.ExportedAt)
+	d := newDigests()
+	write := func(section, id string, record any) error {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		d.add(section, data)
+		return aw.record(section, id, data)
+	}
+
+	filters := &storage.AgentFilters{TenantID: tenantID, Limit: exportPageSize}
+	for {
+		agents, err := s.agents.List(ctx, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list agents: %w", err)
+		}
+		for _, agent := range agents {
+			if err := write(sectionAgents, agent.ID, agent); err != nil {
+				return nil, err
+			}
+		}
+		if len(agents) < exportPageSize {
+			break
+		}
+		last := agents[len(agents)-1]
+		filters.After = storage.CursorAt(last.CreatedAt, last.ID)
+	}
+
+	contextFilters := &storage.ContextFilters{TenantID: tenantID, Limit: exportPageSize}
+	for {
+		contexts, err := s.contexts.List(ctx, contextFilters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list contexts: %w", err)
+		}
+		for _, c := range contexts {
+			revisions, err := s.contexts.ListRevisions(ctx, c.ID)
+			if err != nil && !storage.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to list revisions of context %s: %w", c.ID, err)
+			}
+			if err := write(sectionContexts, c.ID, &models.ContextRecord{Context: c, Revisions: revisions}); err != nil {
+				return nil, err
+			}
+		}
+		if len(contexts) < exportPageSize {
+			break
+		}
+		last := contexts[len(contexts)-1]
+		contextFilters.After = storage.CursorAt(last.CreatedAt, last.ID)
+	}
+
+	m.Counts = map[string]int{sectionAgents: d.counts[sectionAgents], sectionContexts: d.counts[sectionContexts]}
+	m.Checksums = d.checksums()
+	if err := aw.manifest(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Import reads and verifies a whole archive, then writes its records to
+// opts.TenantID in one transaction. Nothing is written if the archive is
+// invalid or, under ConflictFail, if any ID is already taken.
+func (s *Service) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*models.ImportSummary, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = models.ConflictFail
+	}
+	if err := opts.OnConflict.Validate(); err != nil {
+		return nil, acberrors.ValidationError(err.Error()).WithError(err)
+	}
+
+	a, err := readArchive(r)
+	if err != nil {
+		return nil, acberrors.ValidationError(err.Error()).WithError(err)
+	}
+	tenantID := opts.TenantID
+	if tenantID == "" {
+		tenantID = a.manifest.TenantID
+	}
+	for _, agent := range a.agents {
+		agent.TenantID = tenantID
+	}
+	for _, rec := range a.contexts {
+		rec.TenantID = tenantID
+	}
+
+	summary, err := s.importer.Import(ctx, a.agents, a.contexts, opts.OnConflict)
+	if storage.IsConflict(err) {
+		return nil, acberrors.Conflict(err.Error()).WithError(err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to import archive: %w", err)
+	}
+	summary.SourceTenantID = a.manifest.TenantID
+	summary.TenantID = tenantID
+	return summary, nil
+}
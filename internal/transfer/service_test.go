@@ -0,0 +1,196 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acb/internal/auth"
+	acberrors "github.com/acb/internal/errors"
+	"github.com/acb/internal/models"
+	"github.com/acb/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgentStore lists agents; other AgentStore methods are not used
+type fakeAgentStore struct {
+	storage.AgentStore
+	agents []*models.Agent
+}
+
+func (s *fakeAgentStore) List(_ context.Context, f *storage.AgentFilters) ([]*models.Agent, error) {
+	var res []*models.Agent
+	for _, a := range s.agents {
+		if a.TenantID == f.TenantID {
+			res = append(res, a)
+		}
+	}
+	return res, nil
+}
+
+// fakeContextStore lists contexts and their revisions
+type fakeContextStore struct {
+	storage.ContextStore
+	contexts  []*models.Context
+	revisions map[string][]*models.ContextRevision
+}
+
+func (s *fakeContextStore) List(_ context.Context, f *storage.ContextFilters) ([]*models.Context, error) {
+	var res []*models.Context
+	for _, c := range s.contexts {
+		if c.TenantID == f.TenantID {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (s *fakeContextStore) ListRevisions(_ context.Context, id string) ([]*models.ContextRevision, error) {
+	return s.revisions[id], nil
+}
+
+// fakeImportStore records imports, treating the IDs in taken as existing
+type fakeImportStore struct {
+	agents   []*models.Agent
+	contexts []*models.ContextRecord
+	taken    map[string]bool
+}
+
+func (s *fakeImportStore) Import(_ context.Context, agents []*models.Agent, contexts []*models.ContextRecord, policy models.ConflictPolicy) (*models.ImportSummary, error) {
+	summary := &models.ImportSummary{}
+	for _, rec := range contexts {
+		if !s.taken[rec.ID] {
+			summary.Contexts.Add(models.ImportCreated)
+			continue
+		}
+		switch policy {
+		case models.ConflictSkip:
+			summary.Contexts.Add(models.ImportSkipped)
+		case models.ConflictOverwrite:
+			summary.Contexts.Add(models.ImportOverwritten)
+		default:
+			return nil, fmt.Errorf("context with ID %s already exists: %w", rec.ID, storage.ErrConflict)
+		}
+	}
+	summary.Agents.Created = len(agents)
+	s.agents, s.contexts = agents, contexts
+	return summary, nil
+}
+
+func newTestService() (*Service, *fakeImportStore) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	agents := &fakeAgentStore{agents: []*models.Agent{
+		{ID: "planner", Type: "llm", TenantID: "acme", CreatedAt: now},
+		{ID: "stranger", Type: "llm", TenantID: "other", CreatedAt: now},
+	}}
+	note := &models.Context{
+		ID:            "ctx-1",
+		Type:          "note",
+		AgentID:       "planner",
+		TenantID:      "acme",
+		Payload:       []byte("v2"),
+		AccessControl: models.AccessControl{Scope: models.ScopeGroup, AllowedIDs: []string{"critic"}},
+		CreatedAt:     now,
+		Revision:      2,
+	}
+	contexts := &fakeContextStore{
+		contexts: []*models.Context{note},
+		revisions: map[string][]*models.ContextRevision{"ctx-1": {
+			{ContextID: "ctx-1", Revision: 2, Payload: []byte("v2"), AccessControl: note.AccessControl},
+			{ContextID: "ctx-1", Revision: 1, Payload: []byte("v1"), AccessControl: note.AccessControl},
+		}},
+	}
+	importer := &fakeImportStore{taken: map[string]bool{}}
+	svc := NewService(agents, contexts, importer)
+	svc.now = func() time.Time { return now }
+	return svc, importer
+}
+
+func TestService_RoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatNDJSON, FormatTar} {
+		svc, importer := newTestService()
+		ctx := context.Background()
+
+		var buf bytes.Buffer
+		m, err := svc.Export(ctx, &buf, "acme", format)
+		require.NoError(t, err, format)
+		assert.Equal(t, map[string]int{"agents": 1, "contexts": 1}, m.Counts, format)
+
+		// Records are remapped to the target tenant with their history intact
+		summary, err := svc.Import(ctx, &buf, ImportOptions{TenantID: "acme-staging"})
+		require.NoError(t, err, format)
+		assert.Equal(t, "acme", summary.SourceTenantID, format)
+		assert.Equal(t, "acme-staging", summary.TenantID, format)
+		assert.Equal(t, 1, summary.Contexts.Created, format)
+		require.Len(t, importer.agents, 1, format)
+		assert.Equal(t, "acme-staging", importer.agents[0].TenantID, format)
+		rec := importer.contexts[0]
+		assert.Equal(t, "acme-staging", rec.TenantID, format)
+		assert.Equal(t, 2, rec.Revision, format)
+		assert.Equal(t, []string{"critic"}, rec.AccessControl.AllowedIDs, format)
+		require.Len(t, rec.Revisions, 2, format)
+		assert.Equal(t, []byte("v1"), rec.Revisions[1].Payload, format)
+	}
+}
+
+func TestService_ImportRejectsInvalidArchives(t *testing.T) {
+	svc, importer := newTestService()
+	ctx := context.Background()
+	var buf bytes.Buffer
+	_, err := svc.Export(ctx, &buf, "acme", FormatNDJSON)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(buf.String(), "\n")
+	require.Len(t, lines, 4) // Agent, context, manifest and the empty tail
+
+	for name, archive := range map[string]string{
+		"truncated":       lines[0] + lines[1],
+		"tampered":        lines[0] + strings.Replace(lines[1], `"note"`, `"memo"`, 1) + lines[2],
+		"missing record":  lines[1] + lines[2],
+		"unknown kind":    `{"kind":"schema","data":{}}` + "\n" + lines[2],
+		"record trailing": lines[0] + lines[2] + lines[1],
+		"not an archive":  "hello",
+	} {
+		_, err := svc.Import(ctx, strings.NewReader(archive), ImportOptions{})
+		assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError), name)
+	}
+	_, err = svc.Import(ctx, strings.NewReader(buf.String()), ImportOptions{OnConflict: "merge"})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	_, err = svc.Export(ctx, &bytes.Buffer{}, "acme", "zip")
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeValidationError))
+	assert.Nil(t, importer.contexts)
+}
+
+func TestService_ImportConflicts(t *testing.T) {
+	svc, importer := newTestService()
+	ctx := context.Background()
+	var buf bytes.Buffer
+	_, err := svc.Export(ctx, &buf, "acme", FormatTar)
+	require.NoError(t, err)
+	importer.taken["ctx-1"] = true
+
+	_, err = svc.Import(ctx, bytes.NewReader(buf.Bytes()), ImportOptions{})
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeConflict))
+
+	summary, err := svc.Import(ctx, bytes.NewReader(buf.Bytes()), ImportOptions{OnConflict: models.ConflictSkip})
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportCounts{Skipped: 1}, summary.Contexts)
+	assert.Equal(t, "acme", summary.TenantID)
+}
+
+func TestCallerTenant(t *testing.T) {
+	_, err := CallerTenant(context.Background())
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeUnauthorized))
+
+	member := auth.ContextWithClaims(context.Background(), &auth.JWTClaims{AgentID: "planner", TenantID: "acme"})
+	_, err = CallerTenant(member)
+	assert.True(t, acberrors.Is(err, acberrors.ErrorCodeForbidden))
+
+	admin := auth.ContextWithClaims(context.Background(), &auth.JWTClaims{AgentID: "ops", TenantID: "acme", Roles: []string{"admin"}})
+	tenantID, err := CallerTenant(admin)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenantID)
+}
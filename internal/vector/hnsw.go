@@ -0,0 +1,367 @@
+package vector
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// maxLevel caps the layers of an HNSW graph; with M=16 a node reaches layer
+// 16 with probability 16^-16
+const maxLevel = 16
+
+// HNSWConfig tunes an HNSW graph
+type HNSWConfig struct {
+	M              int   // Neighbours kept per node on upper layers; twice as many on the bottom layer
+	EfConstruction int   // Candidates considered when linking a new node
+	EfSearch       int   // Candidates considered by Search unless the caller asks for more
+	Seed           int64 // Seeds level assignment, so graphs built in the same order are identical
+}
+
+// DefaultHNSWConfig returns the parameters recommended by the HNSW paper for
+// recall above 0.95 on typical embeddings
+func DefaultHNSWConfig() HNSWConfig {
+	return HNSWConfig{
+		M:              16,
+		EfConstruction: 200,
+		EfSearch:       64,
+		Seed:           1,
+	}
+}
+
+// Validate checks that the config can build a graph
+func (c HNSWConfig) Validate() error {
+	if c.M < 2 {
+		return errors.New("M must be at least 2")
+	}
+	if c.EfConstruction < c.M {
+		return errors.New("ef construction must be at least M")
+	}
+	if c.EfSearch <= 0 {
+		return errors.New("ef search must be positive")
+	}
+	return nil
+}
+
+// HNSW is a hierarchical navigable small world graph (Malkov & Yashunin,
+// 2016) for approximate nearest neighbour search. It is safe for concurrent
+// use. Removed vectors stay in the graph as tombstones that searches route
+// through but never return, until they outnumber live vectors and the graph
+// is rebuilt.
+type HNSW struct {
+	cfg       HNSWConfig
+	sim       Similarity
+	levelMult float64
+	rng       *rand.Rand
+
+	mu       sync.RWMutex
+	nodes    []*hnswNode
+	ids      map[string]int32 // Live node by ID
+	entry    int32            // Entry point on the top layer; -1 when empty
+	top      int              // Level of the entry point
+	removed  int              // Tombstoned nodes
+	rebuilds int
+}
+
+type hnswNode struct {
+	id      string
+	vector  []float32
+	friends [][]int32 // Neighbours on each layer the node is in
+	removed bool
+}
+
+// candidate is a node with its similarity to the vector being searched for
+type candidate struct {
+	node  int32
+	score float32
+}
+
+// NewHNSW creates an empty graph scoring vectors with sim, which must be
+// symmetric. For cosine similarity, add normalized vectors and use Dot.
+func NewHNSW(sim Similarity, cfg HNSWConfig) *HNSW {
+	return &HNSW{
+		cfg:       cfg,
+		sim:       sim,
+		levelMult: 1 / math.Log(float64(cfg.M)),
+		rng:       rand.New(rand.NewSource(cfg.Seed)),
+		ids:       make(map[string]int32),
+		entry:     -1,
+	}
+}
+
+// Len returns the number of vectors in the graph
+func (g *HNSW) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.ids)
+}
+
+// Add inserts the vector of id, replacing any vector it had. The graph keeps
+// a reference to v, which must not be modified afterwards.
+func (g *HNSW) Add(id string, v []float32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if old, ok := g.ids[id]; ok {
+		g.remove(old)
+	}
+	g.insert(id, v)
+	g.compact()
+}
+
+// Remove deletes the vector of id, reporting whether it was present
+func (g *HNSW) Remove(id string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	node, ok := g.ids[id]
+	if !ok {
+		return false
+	}
+	g.remove(node)
+	g.compact()
+	return true
+}
+
+// Search returns up to k vectors most similar to query, most similar first.
+// ef widens the search beyond the configured EfSearch, trading speed for
+// recall; it is raised to k if smaller.
+func (g *HNSW) Search(query []float32, k, ef int) []Hit {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.entry < 0 || k <= 0 {
+		return nil
+	}
+	ef = max(ef, g.cfg.EfSearch, k)
+
+	ep := candidate{node: g.entry, score: g.sim(query, g.nodes[g.entry].vector)}
+	for level := g.top; level > 0; level-- {
+		ep = g.greedy(query, ep, level)
+	}
+
+	var hits []Hit
+	for _, c := range g.searchLayer(query, ep, ef, 0) {
+		n := g.nodes[c.node]
+		if n.removed {
+			continue
+		}
+		hits = append(hits, Hit{ID: n.id, Score: c.score})
+		if len(hits) == k {
+			break
+		}
+	}
+	return hits
+}
+
+// insert links a new node into every layer up to a random level
+func (g *HNSW) insert(id string, v []float32) {
+	level := g.randomLevel()
+	idx := int32(len(g.nodes))
+	n := &hnswNode{id: id, vector: v, friends: make([][]int32, level+1)}
+	g.nodes = append(g.nodes, n)
+	g.ids[id] = idx
+	if g.entry < 0 {
+		g.entry, g.top = idx, level
+		return
+	}
+
+	ep := candidate{node: g.entry, score: g.sim(v, g.nodes[g.entry].vector)}
+	for l := g.top; l > level; l-- {
+		ep = g.greedy(v, ep, l)
+	}
+	for l := min(level, g.top); l >= 0; l-- {
+		candidates := g.searchLayer(v, ep, g.cfg.EfConstruction, l)
+		for _, friend := range g.selectNeighbours(candidates, g.cfg.M) {
+			n.friends[l] = append(n.friends[l], friend.node)
+			g.link(friend.node, idx, l)
+		}
+		ep = candidates[0]
+	}
+	if level > g.top {
+		g.entry, g.top = idx, level
+	}
+}
+
+// remove tombstones a node
+func (g *HNSW) remove(idx int32) {
+	n := g.nodes[idx]
+	n.removed = true
+	delete(g.ids, n.id)
+	g.removed++
+}
+
+// compact rebuilds the graph from its live nodes once tombstones outnumber
+// them, since searches slow down and lose recall routing through removed
+// nodes
+func (g *HNSW) compact() {
+	if len(g.ids) == 0 {
+		g.nodes, g.entry, g.top, g.removed = nil, -1, 0, 0
+		return
+	}
+	if g.removed <= len(g.ids) || g.removed < 64 {
+		return
+	}
+	live := make([]*hnswNode, 0, len(g.ids))
+	for _, n := range g.nodes {
+		if !n.removed {
+			live = append(live, n)
+		}
+	}
+	g.nodes, g.entry, g.top, g.removed = nil, -1, 0, 0
+	g.ids = make(map[string]int32, len(live))
+	for _, n := range live {
+		g.insert(n.id, n.vector)
+	}
+	g.rebuilds++
+}
+
+// randomLevel draws a node's top layer from an exponentially decaying
+// distribution
+func (g *HNSW) randomLevel() int {
+	level := int(-math.Log(1-g.rng.Float64()) * g.levelMult)
+	return min(level, maxLevel)
+}
+
+// greedy walks layer level towards query from ep, returning the most similar
+// node reached
+func (g *HNSW) greedy(query []float32, ep candidate, level int) candidate {
+	for changed := true; changed; {
+		changed = false
+		for _, friend := range g.friendsAt(ep.node, level) {
+			if score := g.sim(query, g.nodes[friend].vector); score > ep.score {
+				ep = candidate{node: friend, score: score}
+				changed = true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns up to ef nodes of layer level most similar to query,
+// most similar first, found by best-first search from ep
+func (g *HNSW) searchLayer(query []float32, ep candidate, ef, level int) []candidate {
+	visited := map[int32]bool{ep.node: true}
+	frontier := &candidateHeap{best: true}
+	found := &candidateHeap{}
+	heap.Push(frontier, ep)
+	heap.Push(found, ep)
+
+	for frontier.Len() > 0 {
+		c := heap.Pop(frontier).(candidate)
+		if found.Len() >= ef && c.score < found.items[0].score {
+			break
+		}
+		for _, friend := range g.friendsAt(c.node, level) {
+			if visited[friend] {
+				continue
+			}
+			visited[friend] = true
+			score := g.sim(query, g.nodes[friend].vector)
+			if found.Len() < ef || score > found.items[0].score {
+				heap.Push(frontier, candidate{node: friend, score: score})
+				heap.Push(found, candidate{node: friend, score: score})
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+
+	result := found.items
+	sort.Slice(result, func(i, j int) bool { return result[i].score > result[j].score })
+	return result
+}
+
+// selectNeighbours picks up to m of candidates, given most similar first, with
+// the heuristic of the HNSW paper: a candidate more similar to an already
+// selected neighbour than to the new node is skipped, which keeps links
+// spread across clusters. Skipped candidates fill any remaining slots.
+func (g *HNSW) selectNeighbours(candidates []candidate, m int) []candidate {
+	if len(candidates) <= m {
+		return candidates
+	}
+	selected := make([]candidate, 0, m)
+	var skipped []candidate
+	for _, c := range candidates {
+		if len(selected) == m {
+			break
+		}
+		diverse := true
+		for _, s := range selected {
+			if g.sim(g.nodes[c.node].vector, g.nodes[s.node].vector) > c.score {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, c)
+		} else {
+			skipped = append(skipped, c)
+		}
+	}
+	for _, c := range skipped {
+		if len(selected) == m {
+			break
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+// link adds to as a neighbour of from on layer level, dropping from's least
+// suitable neighbours if it now has too many
+func (g *HNSW) link(from, to int32, level int) {
+	n := g.nodes[from]
+	n.friends[level] = append(n.friends[level], to)
+	limit := g.cfg.M
+	if level == 0 {
+		limit = 2 * g.cfg.M
+	}
+	if len(n.friends[level]) <= limit {
+		return
+	}
+
+	candidates := make([]candidate, len(n.friends[level]))
+	for i, friend := range n.friends[level] {
+		candidates[i] = candidate{node: friend, score: g.sim(n.vector, g.nodes[friend].vector)}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	kept := g.selectNeighbours(candidates, limit)
+	n.friends[level] = n.friends[level][:0]
+	for _, c := range kept {
+		n.friends[level] = append(n.friends[level], c.node)
+	}
+}
+
+// friendsAt returns the neighbours of a node on a layer, nil when the node is
+// not in that layer
+func (g *HNSW) friendsAt(node int32, level int) []int32 {
+	friends := g.nodes[node].friends
+	if level >= len(friends) {
+		return nil
+	}
+	return friends[level]
+}
+
+// candidateHeap is a heap of candidates by score: a min-heap, or a max-heap
+// when best is set
+type candidateHeap struct {
+	items []candidate
+	best  bool
+}
+
+func (h *candidateHeap) Len() int { return len(h.items) }
+func (h *candidateHeap) Less(i, j int) bool {
+	if h.best {
+		return h.items[i].score > h.items[j].score
+	}
+	return h.items[i].score < h.items[j].score
+}
+func (h *candidateHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *candidateHeap) Push(x any)    { h.items = append(h.items, x.(candidate)) }
+func (h *candidateHeap) Pop() any {
+	x := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return x
+}
@@ -0,0 +1,112 @@
+// Package vector implements in-process nearest neighbour search over dense
+// float32 vectors: exact search by brute force and approximate search with an
+// HNSW graph.
+package vector
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Similarity scores how alike two vectors of equal length are; higher is
+// more similar
+type Similarity func(a, b []float32) float32
+
+// Dot returns the dot product of a and b
+func Dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// Cosine returns the cosine of the angle between a and b, or 0 when either
+// is the zero vector
+func Cosine(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+// Normalize returns a copy of v scaled to unit length. The dot product of
+// normalized vectors is their cosine similarity. The zero vector is returned
+// as is.
+func Normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	out := make([]float32, len(v))
+	if norm == 0 {
+		copy(out, v)
+		return out
+	}
+	scale := 1 / math.Sqrt(norm)
+	for i, x := range v {
+		out[i] = float32(float64(x) * scale)
+	}
+	return out
+}
+
+// Item is a vector to search
+type Item struct {
+	ID     string
+	Vector []float32
+}
+
+// Hit is a search result
+type Hit struct {
+	ID    string
+	Score float32
+}
+
+// Exact returns the k items most similar to query, most similar first, by
+// scoring every item
+func Exact(query []float32, items []Item, k int, sim Similarity) []Hit {
+	if k <= 0 {
+		return nil
+	}
+	best := &hitHeap{}
+	for _, item := range items {
+		score := sim(query, item.Vector)
+		if best.Len() < k {
+			heap.Push(best, Hit{ID: item.ID, Score: score})
+		} else if score > (*best)[0].Score {
+			(*best)[0] = Hit{ID: item.ID, Score: score}
+			heap.Fix(best, 0)
+		}
+	}
+	return best.sorted()
+}
+
+// hitHeap is a min-heap of hits by score, so the least similar of the best k
+// is at the root
+type hitHeap []Hit
+
+func (h hitHeap) Len() int           { return len(h) }
+func (h hitHeap) Less(i, j int) bool { return h[i].Score < h[j].Score }
+func (h hitHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *hitHeap) Push(x any)        { *h = append(*h, x.(Hit)) }
+func (h *hitHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// sorted empties the heap, returning its hits most similar first
+func (h *hitHeap) sorted() []Hit {
+	hits := make([]Hit, h.Len())
+	for i := len(hits) - 1; i >= 0; i-- {
+		hits[i] = heap.Pop(h).(Hit)
+	}
+	return hits
+}
@@ -0,0 +1,159 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomItems(rng *rand.Rand, n, dim int) []Item {
+	items := make([]Item, n)
+	for i := range items {
+		v := make([]float32, dim)
+		for j := range v {
+			v[j] = float32(rng.NormFloat64())
+		}
+		items[i] = Item{ID: fmt.Sprintf("item-%d", i), Vector: Normalize(v)}
+	}
+	return items
+}
+
+func TestSimilarity(t *testing.T) {
+	a := []float32{3, 4}
+	b := []float32{4, 3}
+	if got := Dot(a, b); got != 24 {
+		t.Fatalf("Dot = %v", got)
+	}
+	if got := Cosine(a, b); math.Abs(float64(got)-0.96) > 1e-6 {
+		t.Fatalf("Cosine = %v", got)
+	}
+	if got := Cosine(a, []float32{0, 0}); got != 0 {
+		t.Fatalf("Cosine with zero vector = %v", got)
+	}
+	n := Normalize(a)
+	if math.Abs(float64(n[0])-0.6) > 1e-6 || math.Abs(float64(n[1])-0.8) > 1e-6 || a[0] != 3 {
+		t.Fatalf("Normalize = %v, input %v", n, a)
+	}
+}
+
+func TestExact(t *testing.T) {
+	items := []Item{
+		{ID: "east", Vector: []float32{1, 0}},
+		{ID: "north", Vector: []float32{0, 1}},
+		{ID: "north-east", Vector: []float32{1, 1}},
+		{ID: "west", Vector: []float32{-1, 0}},
+	}
+	hits := Exact([]float32{1, 0.2}, items, 2, Cosine)
+	if len(hits) != 2 || hits[0].ID != "east" || hits[1].ID != "north-east" {
+		t.Fatalf("unexpected hits %+v", hits)
+	}
+	if hits := Exact([]float32{1, 0}, items, 10, Dot); len(hits) != 4 || hits[3].ID != "west" {
+		t.Fatalf("unexpected hits %+v", hits)
+	}
+	if hits := Exact([]float32{1, 0}, items, 0, Dot); hits != nil {
+		t.Fatalf("expected no hits, got %+v", hits)
+	}
+}
+
+func TestHNSW_Recall(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	items := randomItems(rng, 2000, 32)
+	g := NewHNSW(Dot, DefaultHNSWConfig())
+	for _, item := range items {
+		g.Add(item.ID, item.Vector)
+	}
+	if g.Len() != len(items) {
+		t.Fatalf("Len = %d", g.Len())
+	}
+
+	const k, queries = 10, 50
+	found := 0
+	for _, q := range randomItems(rng, queries, 32) {
+		want := map[string]bool{}
+		for _, hit := range Exact(q.Vector, items, k, Dot) {
+			want[hit.ID] = true
+		}
+		hits := g.Search(q.Vector, k, 0)
+		if len(hits) != k {
+			t.Fatalf("expected %d hits, got %d", k, len(hits))
+		}
+		for i, hit := range hits {
+			if want[hit.ID] {
+				found++
+			}
+			if i > 0 && hit.Score > hits[i-1].Score {
+				t.Fatalf("hits not ordered: %+v", hits)
+			}
+		}
+	}
+	if recall := float64(found) / (k * queries); recall < 0.95 {
+		t.Fatalf("recall %.3f below 0.95", recall)
+	}
+}
+
+func TestHNSW_ReplaceAndRemove(t *testing.T) {
+	g := NewHNSW(Dot, DefaultHNSWConfig())
+	if hits := g.Search([]float32{1, 0}, 1, 0); hits != nil {
+		t.Fatalf("empty graph returned %+v", hits)
+	}
+
+	g.Add("a", []float32{1, 0})
+	g.Add("b", []float32{0, 1})
+	if hits := g.Search([]float32{1, 0}, 1, 0); hits[0].ID != "a" {
+		t.Fatalf("unexpected hits %+v", hits)
+	}
+
+	// Replacing a vector moves it
+	g.Add("a", []float32{0, -1})
+	if hits := g.Search([]float32{1, 0}, 2, 0); len(hits) != 2 || hits[0].ID != "b" || g.Len() != 2 {
+		t.Fatalf("unexpected hits after replace %+v", hits)
+	}
+
+	if !g.Remove("b") || g.Remove("b") {
+		t.Fatal("expected b to be removed exactly once")
+	}
+	if hits := g.Search([]float32{0, 1}, 2, 0); len(hits) != 1 || hits[0].ID != "a" {
+		t.Fatalf("removed vector returned: %+v", hits)
+	}
+
+	g.Remove("a")
+	if g.Len() != 0 || g.Search([]float32{1, 0}, 1, 0) != nil {
+		t.Fatal("expected empty graph")
+	}
+}
+
+func TestHNSW_CompactsTombstones(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	items := randomItems(rng, 300, 8)
+	g := NewHNSW(Dot, DefaultHNSWConfig())
+	for _, item := range items {
+		g.Add(item.ID, item.Vector)
+	}
+	// The graph is rebuilt once tombstones outnumber live vectors
+	for _, item := range items[:151] {
+		g.Remove(item.ID)
+	}
+	if g.rebuilds != 1 || g.removed != 0 || len(g.nodes) != g.Len() {
+		t.Fatalf("expected one rebuild, got %d with %d nodes and %d removed", g.rebuilds, len(g.nodes), g.removed)
+	}
+
+	live := items[151:]
+	for _, q := range randomItems(rng, 10, 8) {
+		want := Exact(q.Vector, live, 1, Dot)[0]
+		if hits := g.Search(q.Vector, 1, 100); hits[0].ID != want.ID {
+			t.Fatalf("expected %s, got %+v", want.ID, hits)
+		}
+	}
+}
+
+func TestHNSWConfig_Validate(t *testing.T) {
+	if err := DefaultHNSWConfig().Validate(); err != nil {
+		t.Fatalf("default config invalid: %v", err)
+	}
+	for _, cfg := range []HNSWConfig{{M: 1, EfConstruction: 10, EfSearch: 10}, {M: 16, EfConstruction: 8, EfSearch: 10}, {M: 16, EfConstruction: 200}} {
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("expected %+v to be invalid", cfg)
+		}
+	}
+}
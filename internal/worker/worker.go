@@ -0,0 +1,51 @@
+// Package worker runs the periodic background jobs of the server, such as
+// the agent reaper and the context janitor.
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/acb/internal/storage"
+)
+
+// Run calls job on every interval until ctx is cancelled. Errors and panics
+// of a run are logged under name and the next run still happens.
+func Run(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			Once(ctx, name, job)
+		}
+	}
+}
+
+// Once runs job a single time, logging its error or panic under name. Errors
+// after ctx is cancelled are expected and not logged.
+func Once(ctx context.Context, name string, job func(context.Context) error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("%s: recovered from panic: %v", name, rec)
+		}
+	}()
+
+	if err := job(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("%s: %v", name, err)
+	}
+}
+
+// TryLock takes the named cluster-wide lock without blocking, so that only
+// one replica runs a job at a time. A nil locker always grants the lock and
+// every replica runs the job.
+func TryLock(ctx context.Context, locker storage.Locker, name string) (unlock func(), acquired bool, err error) {
+	if locker == nil {
+		return func() {}, true, nil
+	}
+	return locker.TryLock(ctx, name)
+}
@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_SurvivesFailingRuns(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Run(ctx, "test worker", time.Millisecond, func(context.Context) error {
+			switch runs.Add(1) {
+			case 1:
+				panic("boom")
+			case 2:
+				return errors.New("store unavailable")
+			}
+			return nil
+		})
+	}()
+
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop after cancel")
+	}
+}
+
+func TestTryLock_NilLocker(t *testing.T) {
+	unlock, acquired, err := TryLock(context.Background(), nil, "job")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	unlock()
+}
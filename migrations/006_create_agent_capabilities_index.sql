@@ -0,0 +1,4 @@
+-- Migration: 006_create_agent_capabilities_index.sql
+-- GIN index for capability-based discovery (@> all-of, && any-of)
+
+CREATE INDEX IF NOT EXISTS idx_agents_capabilities ON agents USING GIN (capabilities);
@@ -0,0 +1,5 @@
+-- Migration: 007_create_metadata_indexes.sql
+-- GIN indexes for label selectors over agent and context metadata
+
+CREATE INDEX IF NOT EXISTS idx_agents_metadata ON agents USING GIN (metadata);
+CREATE INDEX IF NOT EXISTS idx_contexts_metadata ON contexts USING GIN (metadata);
@@ -0,0 +1,27 @@
+-- Migration: 008_create_context_revisions_table.sql
+-- Adds immutable revision history for contexts
+
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS updated_by VARCHAR(255);
+
+CREATE TABLE IF NOT EXISTS context_revisions (
+    context_id VARCHAR(255) NOT NULL REFERENCES contexts(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    payload BYTEA,
+    payload_ref JSONB,
+    metadata JSONB,
+    version VARCHAR(50) NOT NULL,
+    access_control JSONB NOT NULL,
+    checksum VARCHAR(64), -- SHA-256
+    author_id VARCHAR(255) NOT NULL, -- Agent that made the change
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (context_id, revision),
+    CONSTRAINT context_revisions_revision_check CHECK (revision > 0)
+);
+
+-- Seed revision 1 for contexts created before history was tracked
+INSERT INTO context_revisions (context_id, revision, payload, payload_ref, metadata, version, access_control, checksum, author_id, created_at)
+SELECT id, revision, payload, payload_ref, metadata, version, access_control, checksum, agent_id, created_at
+FROM contexts
+ON CONFLICT (context_id, revision) DO NOTHING;
@@ -0,0 +1,19 @@
+-- Migration: 009_create_schemas_table.sql
+-- Creates the schema registry for context payloads
+
+CREATE TABLE IF NOT EXISTS schemas (
+    id VARCHAR(255) PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL DEFAULT 'default',
+    context_type VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    document JSONB NOT NULL, -- JSON Schema document
+    description TEXT,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT schemas_version_check CHECK (version > 0),
+    CONSTRAINT schemas_type_version_unique UNIQUE (tenant_id, context_type, version)
+);
+
+-- Contexts reference schemas by ID; lookups for the delete guard
+CREATE INDEX IF NOT EXISTS idx_contexts_schema_id ON contexts(schema_id) WHERE schema_id IS NOT NULL AND schema_id != '';
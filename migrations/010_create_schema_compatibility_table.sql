@@ -0,0 +1,12 @@
+-- Migration: 010_create_schema_compatibility_table.sql
+-- Stores the schema compatibility mode of each context type
+
+CREATE TABLE IF NOT EXISTS schema_compatibility (
+    tenant_id VARCHAR(255) NOT NULL DEFAULT 'default',
+    context_type VARCHAR(100) NOT NULL,
+    compatibility VARCHAR(20) NOT NULL, -- backward, forward, full, none
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (tenant_id, context_type),
+    CONSTRAINT schema_compatibility_mode_check CHECK (compatibility IN ('backward', 'forward', 'full', 'none'))
+);
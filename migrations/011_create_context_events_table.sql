@@ -0,0 +1,16 @@
+-- Migration: 011_create_context_events_table.sql
+-- Append-only log of context lifecycle events (context.expired, ...)
+
+CREATE TABLE IF NOT EXISTS context_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    tenant_id VARCHAR(255) NOT NULL DEFAULT 'default',
+    context_id VARCHAR(255) NOT NULL, -- Not a foreign key: the context may be gone
+    context_type VARCHAR(100) NOT NULL,
+    agent_id VARCHAR(255) NOT NULL,
+    revision INTEGER NOT NULL,
+    access_control JSONB NOT NULL, -- Visibility of the context when the event was recorded
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_context_events_tenant_id ON context_events(tenant_id, id);
@@ -0,0 +1,25 @@
+-- Migration: 012_add_context_event_stream.sql
+-- Extends the context event log for change streams: metadata for selector
+-- filtering, the writing transaction for in-order delivery, and a NOTIFY so
+-- every replica wakes its subscribers when events are logged
+
+ALTER TABLE context_events ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+-- Events are only delivered once no older transaction is still in flight,
+-- so a consumer never skips an ID that commits late
+ALTER TABLE context_events ADD COLUMN IF NOT EXISTS txid XID8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX IF NOT EXISTS idx_context_events_occurred_at ON context_events(occurred_at);
+
+CREATE OR REPLACE FUNCTION notify_context_events() RETURNS trigger AS $$
+BEGIN
+    -- Identical notifications within a transaction are folded into one
+    PERFORM pg_notify('acb_context_events', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS context_events_notify ON context_events;
+CREATE TRIGGER context_events_notify
+    AFTER INSERT ON context_events
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_context_events();
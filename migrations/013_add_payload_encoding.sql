@@ -0,0 +1,14 @@
+-- Migration: 013_add_payload_encoding.sql
+-- Records how each stored payload is encoded so large payloads can be kept
+-- compressed. Existing rows were stored as-is.
+
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS payload_encoding VARCHAR(16) NOT NULL DEFAULT 'identity';
+ALTER TABLE context_revisions ADD COLUMN IF NOT EXISTS payload_encoding VARCHAR(16) NOT NULL DEFAULT 'identity';
+
+ALTER TABLE contexts DROP CONSTRAINT IF EXISTS contexts_payload_encoding_check;
+ALTER TABLE contexts ADD CONSTRAINT contexts_payload_encoding_check
+    CHECK (payload_encoding IN ('identity', 'gzip'));
+
+ALTER TABLE context_revisions DROP CONSTRAINT IF EXISTS context_revisions_payload_encoding_check;
+ALTER TABLE context_revisions ADD CONSTRAINT context_revisions_payload_encoding_check
+    CHECK (payload_encoding IN ('identity', 'gzip'));
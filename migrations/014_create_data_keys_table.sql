@@ -0,0 +1,24 @@
+-- Migration: 014_create_data_keys_table.sql
+-- Adds envelope encryption of context payloads: per-tenant data keys, stored
+-- wrapped by a master key, and the key each context row is encrypted with
+
+CREATE TABLE IF NOT EXISTS data_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    master_key_id VARCHAR(255) NOT NULL, -- Master key the data key is wrapped with
+    wrapped_key BYTEA NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- New data of a tenant is encrypted with its single active key
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_keys_active_tenant ON data_keys(tenant_id) WHERE active;
+CREATE INDEX IF NOT EXISTS idx_data_keys_master_key_id ON data_keys(master_key_id);
+
+-- A NULL key ID means the row is stored in plaintext
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS payload_key_id VARCHAR(64) REFERENCES data_keys(id);
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS encrypted_metadata BYTEA;
+ALTER TABLE context_revisions ADD COLUMN IF NOT EXISTS payload_key_id VARCHAR(64) REFERENCES data_keys(id);
+ALTER TABLE context_revisions ADD COLUMN IF NOT EXISTS encrypted_metadata BYTEA;
+
+CREATE INDEX IF NOT EXISTS idx_contexts_payload_key_id ON contexts(payload_key_id) WHERE payload_key_id IS NOT NULL;
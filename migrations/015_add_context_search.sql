@@ -0,0 +1,43 @@
+-- Migration: 015_add_context_search.sql
+-- Adds full-text search over context payloads and metadata. The search
+-- vector is written with every create and update; payloads encrypted at rest
+-- are not indexed, and neither is metadata stored encrypted.
+
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+CREATE INDEX IF NOT EXISTS idx_contexts_search_vector ON contexts USING GIN(search_vector);
+
+-- Metadata keys and values rank above payload text. JSON payloads index their
+-- keys, strings and numbers; other text payloads are indexed as prose.
+CREATE OR REPLACE FUNCTION context_search_vector(metadata JSONB, payload TEXT) RETURNS TSVECTOR AS $$
+DECLARE
+    vector TSVECTOR := setweight(jsonb_to_tsvector('simple', COALESCE(metadata, '{}'::jsonb), '["key", "string"]'), 'A');
+BEGIN
+    IF payload IS NULL OR payload = '' THEN
+        RETURN vector;
+    END IF;
+    BEGIN
+        RETURN vector || setweight(jsonb_to_tsvector('simple', payload::jsonb, '["key", "string", "numeric"]'), 'B');
+    EXCEPTION WHEN invalid_text_representation THEN
+        RETURN vector || setweight(to_tsvector('simple', payload), 'B');
+    END;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Backfill rows stored as plain text. Rows that are not valid UTF-8 are
+-- indexed by metadata only; compressed rows are left for
+-- `acb-cli contexts reindex`, which can decompress them.
+CREATE OR REPLACE FUNCTION context_payload_text(payload BYTEA) RETURNS TEXT AS $$
+BEGIN
+    RETURN left(convert_from(payload, 'UTF8'), 262144);
+EXCEPTION WHEN OTHERS THEN
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+UPDATE contexts
+SET search_vector = context_search_vector(
+    metadata,
+    CASE WHEN payload_key_id IS NULL THEN context_payload_text(payload) END
+)
+WHERE search_vector IS NULL
+  AND (payload_encoding = 'identity' OR payload_key_id IS NOT NULL);
@@ -0,0 +1,19 @@
+-- Migration: 016_add_context_embeddings.sql
+-- Stores agent-supplied embeddings for similarity search and the embedding
+-- dimension declared for each context type. Nearest neighbour search runs
+-- in-process, so no vector extension is needed.
+
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS embedding REAL[];
+CREATE INDEX IF NOT EXISTS idx_contexts_embedded ON contexts(tenant_id, type) WHERE embedding IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS embedding_configs (
+    tenant_id VARCHAR(255) NOT NULL DEFAULT 'default',
+    context_type VARCHAR(100) NOT NULL,
+    dimension INTEGER NOT NULL,
+    metric VARCHAR(20) NOT NULL DEFAULT 'cosine', -- cosine, dot
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (tenant_id, context_type),
+    CONSTRAINT embedding_configs_dimension_check CHECK (dimension BETWEEN 1 AND 4096),
+    CONSTRAINT embedding_configs_metric_check CHECK (metric IN ('cosine', 'dot'))
+);
@@ -0,0 +1,20 @@
+-- Migration: 017_create_context_lineage_table.sql
+-- Records which contexts each context was derived from. The declared links
+-- are kept on the context for reads; the edge table serves lineage walks and
+-- stale marking in both directions.
+
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS derived_from JSONB;
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS stale BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS context_lineage (
+    tenant_id VARCHAR(255) NOT NULL DEFAULT 'default',
+    child_id VARCHAR(255) NOT NULL REFERENCES contexts(id) ON DELETE CASCADE,
+    parent_id VARCHAR(255) NOT NULL, -- Not a foreign key: provenance outlives the parent
+    relation VARCHAR(64) NOT NULL DEFAULT 'derived_from',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (child_id, parent_id, relation),
+    CONSTRAINT context_lineage_self_check CHECK (child_id <> parent_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_context_lineage_parent_id ON context_lineage(parent_id);
@@ -0,0 +1,7 @@
+-- Migration: 018_add_keyset_pagination_indexes.sql
+-- Agent and context lists page by keyset on (created_at, id), newest first.
+-- These indexes let a page start at its cursor instead of scanning the rows
+-- of every earlier page.
+
+CREATE INDEX IF NOT EXISTS idx_agents_tenant_created_id ON agents(tenant_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_contexts_tenant_created_id ON contexts(tenant_id, created_at DESC, id DESC);
@@ -0,0 +1,24 @@
+-- Migration: 019_add_context_expiration.sql
+-- Adds per-context expiration policies. Sliding contexts have their expiry
+-- moved forward by reads or writes, never past created_at plus
+-- max_lifetime_seconds. Defaults for contexts that do not set a policy are
+-- configured per context type.
+
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS expiration_policy VARCHAR(20) NOT NULL DEFAULT 'fixed';
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS max_lifetime_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE contexts DROP CONSTRAINT IF EXISTS contexts_expiration_policy_check;
+ALTER TABLE contexts ADD CONSTRAINT contexts_expiration_policy_check
+    CHECK (expiration_policy IN ('fixed', 'sliding_on_read', 'sliding_on_write'));
+
+CREATE TABLE IF NOT EXISTS expiration_configs (
+    tenant_id VARCHAR(255) NOT NULL DEFAULT 'default',
+    context_type VARCHAR(100) NOT NULL,
+    policy VARCHAR(20) NOT NULL DEFAULT 'fixed', -- fixed, sliding_on_read, sliding_on_write
+    ttl_seconds INTEGER NOT NULL DEFAULT 0,
+    max_lifetime_seconds INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (tenant_id, context_type),
+    CONSTRAINT expiration_configs_policy_check CHECK (policy IN ('fixed', 'sliding_on_read', 'sliding_on_write')),
+    CONSTRAINT expiration_configs_lifetime_check CHECK (ttl_seconds >= 0 AND max_lifetime_seconds >= 0)
+);
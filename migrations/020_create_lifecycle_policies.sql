@@ -0,0 +1,22 @@
+-- Migration: 020_create_lifecycle_policies.sql
+-- Admin-managed lifecycle policies per tenant and context type: default and
+-- maximum TTL, maximum payload size, how many revisions are kept, which
+-- access scopes contexts may use and whether non-owners may delete them.
+-- Zero limits and an empty scope list are not enforced.
+
+CREATE TABLE IF NOT EXISTS lifecycle_policies (
+    tenant_id VARCHAR(255) NOT NULL DEFAULT 'default',
+    context_type VARCHAR(100) NOT NULL,
+    default_ttl_seconds INTEGER NOT NULL DEFAULT 0,
+    max_ttl_seconds INTEGER NOT NULL DEFAULT 0,
+    max_payload_bytes INTEGER NOT NULL DEFAULT 0,
+    max_revisions INTEGER NOT NULL DEFAULT 0,
+    allowed_scopes TEXT[] NOT NULL DEFAULT '{}',
+    non_owner_delete BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (tenant_id, context_type),
+    CONSTRAINT lifecycle_policies_limits_check CHECK (
+        default_ttl_seconds >= 0 AND max_ttl_seconds >= 0 AND max_payload_bytes >= 0 AND max_revisions >= 0
+    )
+);
@@ -0,0 +1,19 @@
+-- Migration: 021_create_context_leases.sql
+-- Exclusive leases on contexts. Fencing tokens are drawn from a sequence so
+-- that they keep increasing across leases and server restarts. An expired
+-- lease is ignored and overwritten by the next acquisition; leases go away
+-- with their context.
+
+CREATE SEQUENCE IF NOT EXISTS context_lease_fencing_tokens;
+
+CREATE TABLE IF NOT EXISTS context_leases (
+    context_id VARCHAR(255) PRIMARY KEY REFERENCES contexts(id) ON DELETE CASCADE,
+    tenant_id VARCHAR(255) NOT NULL DEFAULT 'default',
+    holder_id VARCHAR(255) NOT NULL,
+    fencing_token BIGINT NOT NULL,
+    ttl_seconds INTEGER NOT NULL CHECK (ttl_seconds > 0),
+    acquired_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_context_leases_holder_id ON context_leases(holder_id);
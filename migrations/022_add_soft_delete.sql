@@ -0,0 +1,11 @@
+-- Migration: 022_add_soft_delete.sql
+-- Deleting a context or agent marks it with deleted_at instead of removing
+-- the row. Marked rows are hidden from normal reads, can be restored within
+-- the restore window and are purged once it has passed. The partial indexes
+-- serve the purge and the listing of deleted items.
+
+ALTER TABLE contexts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_contexts_deleted_at ON contexts(tenant_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_agents_deleted_at ON agents(tenant_id, deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,29 @@
+-- Migration: 023_notify_context_event_targets.sql
+-- Context event notifications name the tenant and context they concern, so
+-- replicas only wake the streams of that tenant and the reads waiting on that
+-- context. Statements logging events for many contexts, such as expiry
+-- sweeps, notify each tenant once instead.
+
+CREATE OR REPLACE FUNCTION notify_context_events() RETURNS trigger AS $$
+DECLARE
+    target RECORD;
+BEGIN
+    -- Identical notifications within a transaction are folded into one
+    IF (SELECT COUNT(DISTINCT context_id) FROM inserted) > 100 THEN
+        FOR target IN SELECT DISTINCT tenant_id FROM inserted LOOP
+            PERFORM pg_notify('acb_context_events', json_build_object('tenant_id', target.tenant_id)::text);
+        END LOOP;
+    ELSE
+        FOR target IN SELECT DISTINCT tenant_id, context_id FROM inserted LOOP
+            PERFORM pg_notify('acb_context_events', json_build_object('tenant_id', target.tenant_id, 'context_id', target.context_id)::text);
+        END LOOP;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS context_events_notify ON context_events;
+CREATE TRIGGER context_events_notify
+    AFTER INSERT ON context_events
+    REFERENCING NEW TABLE AS inserted
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_context_events();
@@ -0,0 +1,99 @@
+package acb
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/acb/internal/models"
+)
+
+// BatchResult is the outcome of one item of a batch request. Status is the
+// HTTP status the item would have had as a single request.
+type BatchResult struct {
+	Index   int             `json:"index"`
+	Status  int             `json:"status"`
+	Context *models.Context `json:"context,omitempty"` // Created or retrieved context
+	Agent   *models.Agent   `json:"agent,omitempty"`   // Registered agent
+	Error   string          `json:"error,omitempty"`
+}
+
+// OK reports whether the item succeeded
+func (r *BatchResult) OK() bool {
+	return r.Status >= 200 && r.Status < 300
+}
+
+// BatchCreateContexts shares up to 100 contexts in one transaction. In
+// BatchAtomic mode a failing item creates nothing and is returned as a
+// *BatchError; in BatchPartial mode every item reports its own outcome.
+func (c *Client) BatchCreateContexts(ctx context.Context, mode BatchMode, reqs []*ShareContextRequest) ([]*BatchResult, error) {
+	type item struct {
+		Type          string               `json:"type"`
+		Payload       []byte               `json:"payload"`
+		Metadata      map[string]string    `json:"metadata,omitempty"`
+		AccessControl models.AccessControl `json:"access_control"`
+		TTL           int                  `json:"ttl,omitempty"`
+		Expiration    ExpirationPolicy     `json:"expiration,omitempty"`
+		MaxLifetime   int                  `json:"max_lifetime,omitempty"`
+		Embedding     []float32            `json:"embedding,omitempty"`
+		DerivedFrom   []ContextLink        `json:"derived_from,omitempty"`
+	}
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		items[i] = item{
+			Type:          req.Type,
+			Payload:       req.Payload,
+			Metadata:      req.Metadata,
+			AccessControl: req.AccessControl,
+			TTL:           int(req.TTL.Seconds()),
+			Expiration:    req.Expiration,
+			MaxLifetime:   int(req.MaxLifetime.Seconds()),
+			Embedding:     req.Embedding,
+			DerivedFrom:   req.DerivedFrom,
+		}
+	}
+	body := struct {
+		Mode     BatchMode `json:"mode,omitempty"`
+		Contexts []item    `json:"contexts"`
+	}{Mode: mode, Contexts: items}
+	return c.batch(ctx, "/contexts:batchCreate", body)
+}
+
+// BatchGetContexts retrieves up to 100 contexts. Contexts that do not exist
+// or that the caller may not read are reported with status 404.
+func (c *Client) BatchGetContexts(ctx context.Context, contextIDs []string) ([]*BatchResult, error) {
+	body := struct {
+		IDs []string `json:"ids"`
+	}{IDs: contextIDs}
+	return c.batch(ctx, "/contexts:batchGet", body)
+}
+
+// BatchDeleteContexts deletes up to 100 contexts the caller owns in one
+// transaction. Failures are reported as by BatchCreateContexts.
+func (c *Client) BatchDeleteContexts(ctx context.Context, mode BatchMode, contextIDs []string) ([]*BatchResult, error) {
+	body := struct {
+		Mode BatchMode `json:"mode,omitempty"`
+		IDs  []string  `json:"ids"`
+	}{Mode: mode, IDs: contextIDs}
+	return c.batch(ctx, "/contexts:batchDelete", body)
+}
+
+// BatchRegisterAgents registers up to 100 agents in one transaction.
+// Failures are reported as by BatchCreateContexts.
+func (c *Client) BatchRegisterAgents(ctx context.Context, mode BatchMode, reqs []*RegisterAgentRequest) ([]*BatchResult, error) {
+	body := struct {
+		Mode   BatchMode               `json:"mode,omitempty"`
+		Agents []*RegisterAgentRequest `json:"agents"`
+	}{Mode: mode, Agents: reqs}
+	return c.batch(ctx, "/agents:batchRegister", body)
+}
+
+// batch posts a batch request and returns its per-item results
+func (c *Client) batch(ctx context.Context, path string, body interface{}) ([]*BatchResult, error) {
+	var resp struct {
+		Results []*BatchResult `json:"results"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, path, nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
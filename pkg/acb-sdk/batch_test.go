@@ -0,0 +1,94 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBatchCreateContexts(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/contexts:batchCreate" {
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": []map[string]any{
+			{"index": 0, "status": 201, "context": map[string]any{"id": "ctx-1"}},
+			{"index": 1, "status": 400, "error": "invalid context scope"},
+		}})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	results, err := c.BatchCreateContexts(context.Background(), BatchPartial, []*ShareContextRequest{
+		{Type: "note", Payload: []byte("a"), AccessControl: AccessControl{Scope: ScopePublic}, TTL: time.Minute},
+		{Type: "note", Payload: []byte("b"), AccessControl: AccessControl{Scope: "galaxy"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !results[0].OK() || results[0].Context.ID != "ctx-1" || results[1].OK() || results[1].Error == "" {
+		t.Fatalf("unexpected results %+v %+v", results[0], results[1])
+	}
+	items := body["contexts"].([]any)
+	if body["mode"] != "partial" || len(items) != 2 || items[0].(map[string]any)["ttl"] != float64(60) {
+		t.Fatalf("unexpected body %v", body)
+	}
+}
+
+func TestBatchAtomicFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/agents:batchRegister" {
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+		}
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "agent with ID planner already exists", "index": 1})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	_, err := c.BatchRegisterAgents(context.Background(), BatchAtomic, []*RegisterAgentRequest{
+		{ID: "writer", Type: "llm"},
+		{ID: "planner", Type: "llm"},
+	})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 {
+		t.Fatalf("expected a batch error at index 1, got %v", err)
+	}
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+}
+
+func TestBatchGetAndDeleteContexts(t *testing.T) {
+	var paths []string
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, body)
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": []map[string]any{{"index": 0, "status": 404, "error": "context not found"}}})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	results, err := c.BatchGetContexts(context.Background(), []string{"missing"})
+	if err != nil || len(results) != 1 || results[0].Status != http.StatusNotFound {
+		t.Fatalf("unexpected get results %v %v", results, err)
+	}
+	if _, err := c.BatchDeleteContexts(context.Background(), "", []string{"missing"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paths[0] != "/api/v1/contexts:batchGet" || paths[1] != "/api/v1/contexts:batchDelete" {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+	if _, ok := bodies[1]["mode"]; ok {
+		t.Fatalf("an empty mode should be omitted: %v", bodies[1])
+	}
+}
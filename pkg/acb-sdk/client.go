@@ -96,12 +96,6 @@ func (c *Client) ShareContext(ctx context.Context, contextType string, payload [
 	return fmt.Errorf("not implemented")
 }
 
-// GetContext retrieves a context
-func (c *Client) GetContext(ctx context.Context, contextID string) (*models.Context, error) {
-	// TODO: Implement HTTP call
-	return nil, fmt.Errorf("not implemented")
-}
-
 // SendTo sends a message to a specific agent
 func (c *Client) SendTo(ctx context.Context, toAgentID string, topic string, payload interface{}) error {
 	// TODO: Implement HTTP call
@@ -140,6 +134,15 @@ func WithTTL(ttl time.Duration) ContextOption {
 	}
 }
 
+// WithExpiration sets the expiration policy of the context and caps how long
+// sliding can keep it alive; zero leaves the type's default
+func WithExpiration(policy ExpirationPolicy, maxLifetime time.Duration) ContextOption {
+	return func(req *ShareContextRequest) {
+		req.Expiration = policy
+		req.MaxLifetime = maxLifetime
+	}
+}
+
 // WithMetadata sets context metadata
 func WithMetadata(metadata map[string]string) ContextOption {
 	return func(req *ShareContextRequest) {
@@ -147,6 +150,22 @@ func WithMetadata(metadata map[string]string) ContextOption {
 	}
 }
 
+// WithEmbedding attaches an embedding vector for similarity search. The
+// context type must declare the vector's dimension.
+func WithEmbedding(embedding []float32) ContextOption {
+	return func(req *ShareContextRequest) {
+		req.Embedding = embedding
+	}
+}
+
+// WithDerivedFrom records the contexts the shared context was derived from.
+// An empty relation means "derived_from".
+func WithDerivedFrom(links ...ContextLink) ContextOption {
+	return func(req *ShareContextRequest) {
+		req.DerivedFrom = links
+	}
+}
+
 // ShareContextRequest contains context sharing data
 type ShareContextRequest struct {
 	Type          string
@@ -154,4 +173,8 @@ type ShareContextRequest struct {
 	Metadata      map[string]string
 	AccessControl models.AccessControl
 	TTL           time.Duration
+	Expiration    ExpirationPolicy
+	MaxLifetime   time.Duration
+	Embedding     []float32
+	DerivedFrom   []ContextLink
 }
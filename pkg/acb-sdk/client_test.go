@@ -37,9 +37,6 @@ func TestClientNotImplementedAPIs(t *testing.T) {
 	if err := c.ShareContext(context.Background(), "t", []byte("x")); err == nil {
 		t.Fatal("expected error for ShareContext")
 	}
-	if _, err := c.GetContext(context.Background(), "id"); err == nil {
-		t.Fatal("expected error for GetContext")
-	}
 	if err := c.SendTo(context.Background(), "to", "topic", 1); err == nil {
 		t.Fatal("expected error for SendTo")
 	}
@@ -0,0 +1,177 @@
+package acb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acb/internal/models"
+)
+
+// WriteOption configures a context write
+type WriteOption func(http.Header)
+
+// IfRevision makes a write conditional on the context still being at the
+// given revision (sent as If-Match). If another agent has written in the
+// meantime the call fails with a *ConflictError.
+func IfRevision(revision int) WriteOption {
+	return func(h http.Header) {
+		h.Set("If-Match", `"`+strconv.Itoa(revision)+`"`)
+	}
+}
+
+// WithFencingToken makes a write under the lease with the given fencing token
+// (sent as X-Fencing-Token). Once that lease has expired or been released the
+// write fails with ErrConflict instead of overwriting the next holder's work.
+func WithFencingToken(token int64) WriteOption {
+	return func(h http.Header) {
+		h.Set("X-Fencing-Token", strconv.FormatInt(token, 10))
+	}
+}
+
+// UpdateContextRequest contains context update data; zero fields are left unchanged
+type UpdateContextRequest struct {
+	Payload       []byte
+	Metadata      map[string]string
+	Version       string
+	AccessControl *models.AccessControl
+	TTL           time.Duration
+	Expiration    ExpirationPolicy // Replaces the expiration policy
+	Embedding     []float32        // Replaces the embedding; its type must declare the dimension
+	DerivedFrom   []ContextLink    // Replaces the parents when non-nil; empty removes them
+
+	// MarkDescendantsStale flags every context derived from this one,
+	// directly or transitively, as stale once the update is applied
+	MarkDescendantsStale bool
+}
+
+// GetContext retrieves a context. Its Revision can be passed to IfRevision
+// to make a subsequent write conditional.
+func (c *Client) GetContext(ctx context.Context, contextID string) (*models.Context, error) {
+	var resp struct {
+		Context *models.Context `json:"context"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/contexts/"+url.PathEscape(contextID), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Context, nil
+}
+
+// GetContextRevision retrieves a context as it was at the given revision
+func (c *Client) GetContextRevision(ctx context.Context, contextID string, revision int) (*models.Context, error) {
+	var resp struct {
+		Context *models.Context `json:"context"`
+	}
+	q := url.Values{"revision": {strconv.Itoa(revision)}}
+	if err := c.doJSON(ctx, http.MethodGet, "/contexts/"+url.PathEscape(contextID), q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Context, nil
+}
+
+// UpdateContext updates a context and returns the new revision
+func (c *Client) UpdateContext(ctx context.Context, contextID string, req *UpdateContextRequest, opts ...WriteOption) (*models.Context, error) {
+	body := struct {
+		Payload       []byte                `json:"payload,omitempty"`
+		Metadata      map[string]string     `json:"metadata,omitempty"`
+		Version       string                `json:"version,omitempty"`
+		AccessControl *models.AccessControl `json:"access_control,omitempty"`
+		TTL           int                   `json:"ttl,omitempty"`
+		Expiration    ExpirationPolicy      `json:"expiration,omitempty"`
+		Embedding     []float32             `json:"embedding,omitempty"`
+		DerivedFrom   []ContextLink         `json:"derived_from"` // null leaves the parents unchanged
+	}{
+		Payload:       req.Payload,
+		Metadata:      req.Metadata,
+		Version:       req.Version,
+		AccessControl: req.AccessControl,
+		TTL:           int(req.TTL.Seconds()),
+		Expiration:    req.Expiration,
+		Embedding:     req.Embedding,
+		DerivedFrom:   req.DerivedFrom,
+	}
+	var query url.Values
+	if req.MarkDescendantsStale {
+		query = url.Values{"mark_stale": {"true"}}
+	}
+
+	var resp struct {
+		Context *models.Context `json:"context"`
+	}
+	httpResp, err := c.do(ctx, http.MethodPut, "/contexts/"+url.PathEscape(contextID), query, body, writeHeader(opts))
+	if err := decodeResponse(httpResp, err, &resp); err != nil {
+		return nil, asConflict(err, contextID)
+	}
+	return resp.Context, nil
+}
+
+// PatchOperation is a single JSON Patch (RFC 6902) operation
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value"` // Ignored by remove, move and copy
+}
+
+// MergePatchContext applies a JSON Merge Patch (RFC 7386) to the payload of
+// a context. Fields set to nil in patch are removed from the payload.
+func (c *Client) MergePatchContext(ctx context.Context, contextID string, patch interface{}, opts ...WriteOption) (*models.Context, error) {
+	return c.patchContext(ctx, contextID, "application/merge-patch+json", patch, opts)
+}
+
+// JSONPatchContext applies a JSON Patch (RFC 6902) to the payload of a
+// context. The operations are applied atomically; a failing "test" operation
+// is reported as ErrConflict.
+func (c *Client) JSONPatchContext(ctx context.Context, contextID string, ops []PatchOperation, opts ...WriteOption) (*models.Context, error) {
+	return c.patchContext(ctx, contextID, "application/json-patch+json", ops, opts)
+}
+
+func (c *Client) patchContext(ctx context.Context, contextID, contentType string, patch interface{}, opts []WriteOption) (*models.Context, error) {
+	header := writeHeader(opts)
+	header.Set("Content-Type", contentType)
+
+	var resp struct {
+		Context *models.Context `json:"context"`
+	}
+	err := c.doJSONWithHeader(ctx, http.MethodPatch, "/contexts/"+url.PathEscape(contextID), patch, header, &resp)
+	if err != nil {
+		return nil, asConflict(err, contextID)
+	}
+	return resp.Context, nil
+}
+
+// DeleteContext deletes a context
+func (c *Client) DeleteContext(ctx context.Context, contextID string, opts ...WriteOption) error {
+	err := c.doJSONWithHeader(ctx, http.MethodDelete, "/contexts/"+url.PathEscape(contextID), nil, writeHeader(opts), nil)
+	return asConflict(err, contextID)
+}
+
+func writeHeader(opts []WriteOption) http.Header {
+	h := http.Header{}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// asConflict fills in the context ID of a conflict reported by the server
+func asConflict(err error, contextID string) error {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		conflict.ContextID = contextID
+	}
+	return err
+}
+
+// revisionFromETag parses the revision carried in a strong ETag
+func revisionFromETag(tag string) int {
+	revision, err := strconv.Atoi(strings.Trim(strings.TrimSpace(tag), `"`))
+	if err != nil {
+		return 0
+	}
+	return revision
+}
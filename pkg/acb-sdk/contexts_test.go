@@ -0,0 +1,110 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConditionalContextWrites(t *testing.T) {
+	revision := 2
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/contexts/ctx-1" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		if r.Method == http.MethodGet {
+			_ = json.NewEncoder(w).Encode(map[string]any{"context": map[string]any{"id": "ctx-1", "revision": revision}})
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && match != `"2"` {
+			w.Header().Set("ETag", `"2"`)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "context ctx-1 is at revision 2, expected 1"})
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["version"] != "2" || body["ttl"] != float64(60) {
+				t.Fatalf("unexpected body %v", body)
+			}
+			revision++
+			_ = json.NewEncoder(w).Encode(map[string]any{"context": map[string]any{"id": "ctx-1", "revision": revision}})
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	got, err := c.GetContext(context.Background(), "ctx-1")
+	if err != nil || got.Revision != 2 {
+		t.Fatalf("get: %+v, %v", got, err)
+	}
+
+	updated, err := c.UpdateContext(context.Background(), "ctx-1", &UpdateContextRequest{Version: "2", TTL: time.Minute}, IfRevision(got.Revision))
+	if err != nil || updated.Revision != 3 {
+		t.Fatalf("update: %+v, %v", updated, err)
+	}
+
+	_, err = c.UpdateContext(context.Background(), "ctx-1", &UpdateContextRequest{Version: "2", TTL: time.Minute}, IfRevision(1))
+	var conflict *ConflictError
+	if !stdErrors.As(err, &conflict) || !stdErrors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	if conflict.ContextID != "ctx-1" || conflict.CurrentRevision != 2 {
+		t.Fatalf("unexpected conflict %+v", conflict)
+	}
+
+	if err := c.DeleteContext(context.Background(), "ctx-1", IfRevision(1)); !stdErrors.Is(err, ErrConflict) {
+		t.Fatalf("expected delete conflict, got %v", err)
+	}
+	if err := c.DeleteContext(context.Background(), "ctx-1", IfRevision(2)); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+}
+
+func TestPatchContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/api/v1/contexts/ctx-1" {
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch r.Header.Get("Content-Type") {
+		case "application/merge-patch+json":
+			if r.Header.Get("If-Match") != `"3"` {
+				t.Fatalf("missing If-Match")
+			}
+			if m, ok := body.(map[string]any); !ok || m["status"] != "done" {
+				t.Fatalf("unexpected merge patch %v", body)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"context": map[string]any{"id": "ctx-1", "revision": 4}})
+		case "application/json-patch+json":
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "test operation failed"})
+		default:
+			t.Fatalf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	got, err := c.MergePatchContext(context.Background(), "ctx-1", map[string]any{"status": "done"}, IfRevision(3))
+	if err != nil || got.Revision != 4 {
+		t.Fatalf("merge patch: %+v, %v", got, err)
+	}
+
+	_, err = c.JSONPatchContext(context.Background(), "ctx-1", []PatchOperation{
+		{Op: "test", Path: "/status", Value: "pending"},
+		{Op: "replace", Path: "/status", Value: "done"},
+	})
+	if !stdErrors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package acb
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/acb/internal/models"
+)
+
+// RestoreDeletedContext brings back a deleted context within the server's
+// restore window, as it was when deleted. It requires the same access as the
+// delete did; contexts past the window are reported as not found.
+func (c *Client) RestoreDeletedContext(ctx context.Context, contextID string) (*models.Context, error) {
+	var resp struct {
+		Context *models.Context `json:"context"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/contexts/"+url.PathEscape(contextID)+":restore", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Context, nil
+}
+
+// RestoreAgent brings back a deleted agent within the server's restore
+// window. Only the agent itself or an admin may restore it.
+func (c *Client) RestoreAgent(ctx context.Context, agentID string) (*models.Agent, error) {
+	var resp struct {
+		Agent *models.Agent `json:"agent"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/agents/"+url.PathEscape(agentID)+":restore", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Agent, nil
+}
+
+// ListDeletedContexts returns the contexts of the caller's tenant that can
+// still be restored, most recently deleted first. Only WithLimit applies.
+// Requires the admin role.
+func (c *Client) ListDeletedContexts(ctx context.Context, opts ...ListOption) ([]*models.Context, error) {
+	var resp struct {
+		Contexts []*models.Context `json:"contexts"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/contexts/deleted", buildListQuery(opts), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Contexts, nil
+}
+
+// ListDeletedAgents returns the agents of the caller's tenant that can still
+// be restored, most recently deleted first. Only WithLimit applies. Requires
+// the admin role.
+func (c *Client) ListDeletedAgents(ctx context.Context, opts ...ListOption) ([]*models.Agent, error) {
+	var resp struct {
+		Agents []*models.Agent `json:"agents"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/agents/deleted", buildListQuery(opts), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Agents, nil
+}
@@ -0,0 +1,54 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSoftDelete(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/contexts/ctx-1:restore":
+			_ = json.NewEncoder(w).Encode(map[string]any{"context": map[string]any{"id": "ctx-1", "revision": 3}})
+		case "POST /api/v1/contexts/ctx-2:restore":
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "deleted context not found"})
+		case "POST /api/v1/agents/agent-1:restore":
+			_ = json.NewEncoder(w).Encode(map[string]any{"agent": map[string]any{"id": "agent-1"}})
+		case "GET /api/v1/contexts/deleted":
+			if r.URL.Query().Get("limit") != "10" {
+				t.Fatalf("unexpected query %s", r.URL.RawQuery)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"contexts": []any{map[string]any{"id": "ctx-2", "deleted_at": "2026-01-01T00:00:00Z"}}, "count": 1})
+		case "GET /api/v1/agents/deleted":
+			_ = json.NewEncoder(w).Encode(map[string]any{"agents": []any{}, "count": 0})
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	ctx := context.Background()
+	restored, err := c.RestoreDeletedContext(ctx, "ctx-1")
+	if err != nil || restored.Revision != 3 {
+		t.Fatalf("unexpected context %+v, %v", restored, err)
+	}
+	if _, err := c.RestoreDeletedContext(ctx, "ctx-2"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if agent, err := c.RestoreAgent(ctx, "agent-1"); err != nil || agent.ID != "agent-1" {
+		t.Fatalf("unexpected agent %+v, %v", agent, err)
+	}
+	contexts, err := c.ListDeletedContexts(ctx, WithLimit(10))
+	if err != nil || len(contexts) != 1 || contexts[0].DeletedAt == nil {
+		t.Fatalf("unexpected contexts %v, %v", contexts, err)
+	}
+	if agents, err := c.ListDeletedAgents(ctx); err != nil || len(agents) != 0 {
+		t.Fatalf("unexpected agents %v, %v", agents, err)
+	}
+}
@@ -0,0 +1,59 @@
+package acb
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// SimilarityQuery is a nearest neighbour search over the embeddings of one
+// context type
+type SimilarityQuery struct {
+	Type     string           `json:"type"`
+	Vector   []float32        `json:"vector"`
+	TopK     int              `json:"top_k,omitempty"`    // Defaults to 10, at most 100
+	Metric   SimilarityMetric `json:"metric,omitempty"`   // Defaults to the metric the type is configured for
+	Exact    bool             `json:"exact,omitempty"`    // Score every candidate instead of using the index
+	AgentID  string           `json:"agent_id,omitempty"` // Only contexts created by this agent
+	Selector string           `json:"selector,omitempty"` // Only contexts whose metadata matches
+}
+
+// SearchSimilarContexts returns the contexts the caller may read whose
+// embeddings are most similar to query.Vector, most similar first
+func (c *Client) SearchSimilarContexts(ctx context.Context, query *SimilarityQuery) ([]*ContextMatch, error) {
+	var resp struct {
+		Contexts []*ContextMatch `json:"contexts"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/contexts/search", nil, query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Contexts, nil
+}
+
+// GetEmbeddingConfig returns the embedding dimension and metric declared for
+// a context type
+func (c *Client) GetEmbeddingConfig(ctx context.Context, contextType string) (*EmbeddingConfig, error) {
+	var resp struct {
+		Embedding *EmbeddingConfig `json:"embedding"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/embeddings/"+url.PathEscape(contextType), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Embedding, nil
+}
+
+// SetEmbeddingConfig declares the embedding dimension and metric of a context
+// type; an empty metric means cosine. Requires the admin role.
+func (c *Client) SetEmbeddingConfig(ctx context.Context, contextType string, dimension int, metric SimilarityMetric) (*EmbeddingConfig, error) {
+	body := struct {
+		Dimension int              `json:"dimension"`
+		Metric    SimilarityMetric `json:"metric,omitempty"`
+	}{Dimension: dimension, Metric: metric}
+	var resp struct {
+		Embedding *EmbeddingConfig `json:"embedding"`
+	}
+	if err := c.doJSON(ctx, http.MethodPut, "/embeddings/"+url.PathEscape(contextType), nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Embedding, nil
+}
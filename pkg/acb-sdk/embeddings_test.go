@@ -0,0 +1,67 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchSimilarContexts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/contexts/search" {
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["type"] != "memory" || body["top_k"] != float64(3) || body["selector"] != "env=prod" || len(body["vector"].([]any)) != 2 {
+			t.Fatalf("unexpected body %v", body)
+		}
+		if _, ok := body["metric"]; ok {
+			t.Fatalf("empty metric sent: %v", body)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"contexts": []map[string]any{{"id": "ctx-1", "type": "memory", "score": 0.93}},
+			"total":    1,
+		})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	matches, err := c.SearchSimilarContexts(context.Background(), &SimilarityQuery{Type: "memory", Vector: []float32{0.6, 0.8}, TopK: 3, Selector: "env=prod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].ID != "ctx-1" || matches[0].Score != 0.93 {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+}
+
+func TestEmbeddingConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/embeddings/memory" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		config := map[string]any{"context_type": "memory", "dimension": 384, "metric": "dot"}
+		if r.Method == http.MethodPut {
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["dimension"] != float64(384) || body["metric"] != "dot" {
+				t.Fatalf("unexpected body %v", body)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"embedding": config})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	cfg, err := c.SetEmbeddingConfig(context.Background(), "memory", 384, MetricDot)
+	if err != nil || cfg.Dimension != 384 || cfg.Metric != MetricDot {
+		t.Fatalf("set: %+v, %v", cfg, err)
+	}
+	cfg, err = c.GetEmbeddingConfig(context.Background(), "memory")
+	if err != nil || cfg.ContextType != "memory" {
+		t.Fatalf("get: %+v, %v", cfg, err)
+	}
+}
@@ -6,10 +6,46 @@ import "fmt"
 var (
 	ErrConnectionFailed = fmt.Errorf("connection failed")
 	ErrUnauthorized     = fmt.Errorf("unauthorized")
+	ErrForbidden        = fmt.Errorf("forbidden")
 	ErrNotFound         = fmt.Errorf("not found")
 	ErrValidationFailed = fmt.Errorf("validation failed")
+	ErrConflict         = fmt.Errorf("conflict")
 )
 
+// ConflictError is returned when a conditional write is rejected because the
+// context has moved past the revision the caller read. Re-read the context
+// and retry against CurrentRevision.
+type ConflictError struct {
+	ContextID       string
+	CurrentRevision int // Taken from the response ETag; zero if not reported
+}
+
+func (e *ConflictError) Error() string {
+	if e.CurrentRevision == 0 {
+		return fmt.Sprintf("context %s was modified concurrently", e.ContextID)
+	}
+	return fmt.Sprintf("context %s was modified concurrently (now at revision %d)", e.ContextID, e.CurrentRevision)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// BatchError is returned when one item fails an atomic batch request, which
+// then writes nothing. Err describes the failure of the item at Index.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
 // SDKError represents an SDK error
 type SDKError struct {
 	Code    string
@@ -0,0 +1,38 @@
+package acb
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// GetExpirationConfig returns the expiration defaults of a context type
+func (c *Client) GetExpirationConfig(ctx context.Context, contextType string) (*ExpirationConfig, error) {
+	var resp struct {
+		Expiration *ExpirationConfig `json:"expiration"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/expirations/"+url.PathEscape(contextType), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Expiration, nil
+}
+
+// SetExpirationConfig sets the expiration policy, TTL and max lifetime that
+// contexts of a type get when they do not set their own. A zero TTL keeps the
+// server default and a zero max lifetime means no cap. Requires the admin
+// role.
+func (c *Client) SetExpirationConfig(ctx context.Context, contextType string, policy ExpirationPolicy, ttl, maxLifetime time.Duration) (*ExpirationConfig, error) {
+	body := struct {
+		Policy      ExpirationPolicy `json:"policy,omitempty"`
+		TTL         int              `json:"ttl,omitempty"`
+		MaxLifetime int              `json:"max_lifetime,omitempty"`
+	}{Policy: policy, TTL: int(ttl.Seconds()), MaxLifetime: int(maxLifetime.Seconds())}
+	var resp struct {
+		Expiration *ExpirationConfig `json:"expiration"`
+	}
+	if err := c.doJSON(ctx, http.MethodPut, "/expirations/"+url.PathEscape(contextType), nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Expiration, nil
+}
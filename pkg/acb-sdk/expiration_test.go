@@ -0,0 +1,42 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExpirationConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/expirations/session" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		if r.Method == http.MethodPut {
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["policy"] != "sliding_on_read" || body["ttl"] != float64(1800) || body["max_lifetime"] != float64(86400) {
+				t.Fatalf("unexpected body %v", body)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"expiration": map[string]any{
+			"context_type": "session",
+			"policy":       "sliding_on_read",
+			"ttl":          int64(30 * time.Minute),
+			"max_lifetime": int64(24 * time.Hour),
+		}})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	cfg, err := c.SetExpirationConfig(context.Background(), "session", ExpirationSlidingOnRead, 30*time.Minute, 24*time.Hour)
+	if err != nil || cfg.Policy != ExpirationSlidingOnRead || cfg.MaxLifetime != 24*time.Hour {
+		t.Fatalf("unexpected config %+v, %v", cfg, err)
+	}
+	cfg, err = c.GetExpirationConfig(context.Background(), "session")
+	if err != nil || cfg.TTL != 30*time.Minute {
+		t.Fatalf("unexpected config %+v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,140 @@
+package acb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// apiPrefix is the path prefix for versioned API routes
+const apiPrefix = "/api/v1"
+
+// doJSON performs an authenticated API request and decodes the JSON response into out
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.do(ctx, method, path, query, body, nil)
+	return decodeResponse(resp, err, out)
+}
+
+// doJSONWithHeader is doJSON with extra request headers
+func (c *Client) doJSONWithHeader(ctx context.Context, method, path string, body interface{}, header http.Header, out interface{}) error {
+	resp, err := c.do(ctx, method, path, nil, body, header)
+	return decodeResponse(resp, err, out)
+}
+
+// decodeResponse decodes the JSON body of a successful response into out
+func decodeResponse(resp *http.Response, err error, out interface{}) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return NewSDKError("DECODE_ERROR", "failed to decode response").WithError(err)
+	}
+	return nil
+}
+
+// do performs an authenticated API request and returns the response when the
+// status is 2xx; any other status is converted into an SDKError
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, header http.Header) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, query, body, header)
+	if err != nil {
+		return nil, err
+	}
+	return send(c.httpClient, req)
+}
+
+// newRequest builds an authenticated API request
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}, header http.Header) (*http.Request, error) {
+	u := c.endpoint + apiPrefix + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, NewSDKError("ENCODE_ERROR", "failed to encode request").WithError(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, NewSDKError("REQUEST_ERROR", "failed to build request").WithError(err)
+	}
+	for key, values := range header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// send performs req with client, converting non-2xx responses into SDKErrors
+func send(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, NewSDKError("CONNECTION_FAILED", "request failed").WithError(fmt.Errorf("%w: %v", ErrConnectionFailed, err))
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, errorFromResponse(resp)
+	}
+	return resp, nil
+}
+
+// errorFromResponse maps a non-2xx response to an SDKError wrapping a sentinel
+// error. Errors of an item that failed an atomic batch are wrapped in a
+// BatchError.
+func errorFromResponse(resp *http.Response) error {
+	var payload struct {
+		Error string `json:"error"`
+		Index *int   `json:"index"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&payload)
+	message := payload.Error
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+
+	err := statusError(resp, message)
+	if payload.Index != nil {
+		return &BatchError{Index: *payload.Index, Err: err}
+	}
+	return err
+}
+
+// statusError maps the status of a non-2xx response to an SDKError
+func statusError(resp *http.Response, message string) error {
+	switch resp.StatusCode {
+	case http.StatusBadRequest:
+		return NewSDKError("VALIDATION_ERROR", message).WithError(ErrValidationFailed)
+	case http.StatusUnauthorized:
+		return NewSDKError("UNAUTHORIZED", message).WithError(ErrUnauthorized)
+	case http.StatusForbidden:
+		return NewSDKError("FORBIDDEN", message).WithError(ErrForbidden)
+	case http.StatusNotFound:
+		return NewSDKError("NOT_FOUND", message).WithError(ErrNotFound)
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		conflict := &ConflictError{CurrentRevision: revisionFromETag(resp.Header.Get("ETag"))}
+		return NewSDKError("CONFLICT", message).WithError(conflict)
+	default:
+		return NewSDKError(fmt.Sprintf("HTTP_%d", resp.StatusCode), message)
+	}
+}
@@ -0,0 +1,60 @@
+package acb
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// AcquireLease leases a context to the caller for ttl, zero meaning the
+// server default. While the lease is held only the caller may write the
+// context; other agents get ErrConflict, as does acquiring a context that is
+// already leased. Pass the fencing token of the lease to writes with
+// WithFencingToken.
+func (c *Client) AcquireLease(ctx context.Context, contextID string, ttl time.Duration) (*Lease, error) {
+	body := struct {
+		TTL int `json:"ttl,omitempty"`
+	}{TTL: int(ttl.Seconds())}
+	var resp struct {
+		Lease *Lease `json:"lease"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/contexts/"+url.PathEscape(contextID)+"/lease", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Lease, nil
+}
+
+// GetLease returns the lease held on a context, ErrNotFound when none is
+func (c *Client) GetLease(ctx context.Context, contextID string) (*Lease, error) {
+	var resp struct {
+		Lease *Lease `json:"lease"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/contexts/"+url.PathEscape(contextID)+"/lease", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Lease, nil
+}
+
+// RenewLease extends a lease to ttl from now. It fails with ErrConflict once
+// the lease has expired; the context must then be acquired again.
+func (c *Client) RenewLease(ctx context.Context, lease *Lease, ttl time.Duration) (*Lease, error) {
+	body := struct {
+		FencingToken int64 `json:"fencing_token"`
+		TTL          int   `json:"ttl,omitempty"`
+	}{FencingToken: lease.FencingToken, TTL: int(ttl.Seconds())}
+	var resp struct {
+		Lease *Lease `json:"lease"`
+	}
+	if err := c.doJSON(ctx, http.MethodPut, "/contexts/"+url.PathEscape(lease.ContextID)+"/lease", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Lease, nil
+}
+
+// ReleaseLease ends a lease so that other agents may write the context
+func (c *Client) ReleaseLease(ctx context.Context, lease *Lease) error {
+	query := url.Values{"fencing_token": {strconv.FormatInt(lease.FencingToken, 10)}}
+	return c.doJSON(ctx, http.MethodDelete, "/contexts/"+url.PathEscape(lease.ContextID)+"/lease", query, nil, nil)
+}
@@ -0,0 +1,64 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLeases(t *testing.T) {
+	lease := map[string]any{"context_id": "task-1", "holder_id": "agent-1", "fencing_token": 7, "ttl": int64(time.Minute)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/contexts/task-1" && r.Method == http.MethodDelete:
+			if r.Header.Get("X-Fencing-Token") != "7" {
+				t.Fatalf("expected the fencing token, got %q", r.Header.Get("X-Fencing-Token"))
+			}
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "context task-1 is leased to agent-2"})
+		case r.URL.Path != "/api/v1/contexts/task-1/lease":
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		case r.Method == http.MethodDelete:
+			if r.URL.Query().Get("fencing_token") != "7" {
+				t.Fatalf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPut:
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["fencing_token"] != float64(7) || body["ttl"] != float64(120) {
+				t.Fatalf("unexpected body %v", body)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"lease": lease})
+		default:
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusCreated)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"lease": lease})
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	ctx := context.Background()
+	l, err := c.AcquireLease(ctx, "task-1", time.Minute)
+	if err != nil || l.FencingToken != 7 || l.TTL != time.Minute {
+		t.Fatalf("unexpected lease %+v, %v", l, err)
+	}
+	if l, err = c.GetLease(ctx, "task-1"); err != nil || l.HolderID != "agent-1" {
+		t.Fatalf("unexpected lease %+v, %v", l, err)
+	}
+	if _, err := c.RenewLease(ctx, l, 2*time.Minute); err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	if err := c.DeleteContext(ctx, "task-1", WithFencingToken(l.FencingToken)); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if err := c.ReleaseLease(ctx, l); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package acb
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetLineage walks the contexts a context was derived from (LineageAncestors)
+// or that were derived from it (LineageDescendants), up to depth levels. A
+// zero depth uses the server default. Contexts the caller may not read are
+// left out of the walk.
+func (c *Client) GetLineage(ctx context.Context, contextID string, direction LineageDirection, depth int) (*Lineage, error) {
+	q := url.Values{}
+	if direction != "" {
+		q.Set("direction", string(direction))
+	}
+	if depth > 0 {
+		q.Set("depth", strconv.Itoa(depth))
+	}
+	var resp struct {
+		Lineage *Lineage `json:"lineage"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/contexts/"+url.PathEscape(contextID)+"/lineage", q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Lineage, nil
+}
@@ -0,0 +1,70 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetLineage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/v1/contexts/ctx-1/lineage" {
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if got := r.URL.Query(); got.Get("direction") != "descendants" || got.Get("depth") != "2" {
+			t.Fatalf("unexpected query %v", got)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"lineage": map[string]any{
+			"context_id": "ctx-1",
+			"direction":  "descendants",
+			"depth":      2,
+			"nodes":      []map[string]any{{"id": "ctx-2", "depth": 1, "stale": true}},
+			"edges":      []map[string]any{{"child_id": "ctx-2", "parent_id": "ctx-1", "relation": "summarizes"}},
+		}})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	lineage, err := c.GetLineage(context.Background(), "ctx-1", LineageDescendants, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lineage.Nodes) != 1 || lineage.Nodes[0].ID != "ctx-2" || !lineage.Nodes[0].Stale || lineage.Nodes[0].Depth != 1 {
+		t.Fatalf("unexpected nodes: %+v", lineage.Nodes)
+	}
+	if len(lineage.Edges) != 1 || lineage.Edges[0].Relation != "summarizes" {
+		t.Fatalf("unexpected edges: %+v", lineage.Edges)
+	}
+}
+
+func TestUpdateContextLineage(t *testing.T) {
+	var bodies []map[string]any
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		queries = append(queries, r.URL.RawQuery)
+		_ = json.NewEncoder(w).Encode(map[string]any{"context": map[string]any{"id": "ctx-1"}})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	ctx := context.Background()
+	if _, err := c.UpdateContext(ctx, "ctx-1", &UpdateContextRequest{Payload: []byte("{}"), MarkDescendantsStale: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.UpdateContext(ctx, "ctx-1", &UpdateContextRequest{DerivedFrom: []ContextLink{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Unset parents are sent as null and left unchanged; empty ones are removed
+	if queries[0] != "mark_stale=true" || bodies[0]["derived_from"] != nil {
+		t.Fatalf("unexpected first request %q %v", queries[0], bodies[0])
+	}
+	if queries[1] != "" || len(bodies[1]["derived_from"].([]any)) != 0 {
+		t.Fatalf("unexpected second request %q %v", queries[1], bodies[1])
+	}
+}
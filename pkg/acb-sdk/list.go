@@ -0,0 +1,247 @@
+package acb
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/acb/internal/models"
+)
+
+// ListOption is a function that configures a list query
+type ListOption func(url.Values)
+
+// WithSelector filters by a metadata label selector, e.g. "env=prod,tier in (gold,silver),!deprecated"
+func WithSelector(selector string) ListOption {
+	return func(q url.Values) {
+		q.Set("selector", selector)
+	}
+}
+
+// WithType filters agents and contexts by type; use WithContextType for
+// context events
+func WithType(t string) ListOption {
+	return func(q url.Values) {
+		q.Set("type", t)
+	}
+}
+
+// WithEventTypes filters context events by type, e.g. ContextEventExpired
+func WithEventTypes(types ...models.ContextEventType) ListOption {
+	return func(q url.Values) {
+		names := make([]string, len(types))
+		for i, t := range types {
+			names[i] = string(t)
+		}
+		q.Set("type", strings.Join(names, ","))
+	}
+}
+
+// WithContextType filters context events by the type of their context
+func WithContextType(t string) ListOption {
+	return func(q url.Values) {
+		q.Set("context_type", t)
+	}
+}
+
+// WithCapabilities filters agents by capability; all capabilities must match
+// unless WithAnyCapability is also given
+func WithCapabilities(capabilities ...string) ListOption {
+	return func(q url.Values) {
+		q.Set("capability", strings.Join(capabilities, ","))
+	}
+}
+
+// WithAnyCapability matches agents having at least one of the requested capabilities
+func WithAnyCapability() ListOption {
+	return func(q url.Values) {
+		q.Set("match", "any")
+	}
+}
+
+// WithLivenessRanking orders agents online first, then by most recent heartbeat
+func WithLivenessRanking() ListOption {
+	return func(q url.Values) {
+		q.Set("sort", "liveness")
+	}
+}
+
+// WithLimit sets the page size of agent and context lists, at most 1000
+func WithLimit(limit int) ListOption {
+	return func(q url.Values) {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+}
+
+// WithCursor starts a list at the page a previous NextCursor points to
+func WithCursor(cursor string) ListOption {
+	return func(q url.Values) {
+		q.Set("cursor", cursor)
+	}
+}
+
+// WithTotal asks for the number of items matching the filters across all
+// pages, at the cost of an extra count query
+func WithTotal() ListOption {
+	return func(q url.Values) {
+		q.Set("include_total", "true")
+	}
+}
+
+// AgentPage is one page of an agent list
+type AgentPage struct {
+	Agents     []*models.Agent `json:"agents"`
+	NextCursor string          `json:"next_cursor"` // Empty on the last page
+	Total      int             `json:"total"`       // Only set when requested with WithTotal
+}
+
+// ContextPage is one page of a context list
+type ContextPage struct {
+	Contexts   []*models.Context `json:"contexts"`
+	NextCursor string            `json:"next_cursor"` // Empty on the last page
+	Total      int               `json:"total"`       // Only set when requested with WithTotal
+}
+
+// ListAgents discovers agents matching the given options. It returns the
+// first page only; use ListAgentsPage or Agents to see them all.
+func (c *Client) ListAgents(ctx context.Context, opts ...ListOption) ([]*models.Agent, error) {
+	page, err := c.ListAgentsPage(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return page.Agents, nil
+}
+
+// ListAgentsPage returns one page of the agents matching the given options,
+// newest first. Pass its NextCursor to WithCursor for the following page.
+func (c *Client) ListAgentsPage(ctx context.Context, opts ...ListOption) (*AgentPage, error) {
+	var page AgentPage
+	if err := c.doJSON(ctx, http.MethodGet, "/agents", buildListQuery(opts), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Agents iterates over every agent matching the given options, fetching
+// pages as needed. Iteration stops at the first error, which is yielded
+// with a nil agent. WithLivenessRanking lists a single page.
+func (c *Client) Agents(ctx context.Context, opts ...ListOption) iter.Seq2[*models.Agent, error] {
+	return func(yield func(*models.Agent, error) bool) {
+		for cursor := ""; ; {
+			page, err := c.ListAgentsPage(ctx, pageOptions(opts, cursor)...)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, a := range page.Agents {
+				if !yield(a, nil) {
+					return
+				}
+			}
+			if cursor = page.NextCursor; cursor == "" {
+				return
+			}
+		}
+	}
+}
+
+// ListContexts lists contexts matching the given options. It returns the
+// first page only; use ListContextsPage or Contexts to see them all.
+func (c *Client) ListContexts(ctx context.Context, opts ...ListOption) ([]*models.Context, error) {
+	page, err := c.ListContextsPage(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return page.Contexts, nil
+}
+
+// ListContextsPage returns one page of the contexts matching the given
+// options, newest first. Pass its NextCursor to WithCursor for the
+// following page.
+func (c *Client) ListContextsPage(ctx context.Context, opts ...ListOption) (*ContextPage, error) {
+	var page ContextPage
+	if err := c.doJSON(ctx, http.MethodGet, "/contexts", buildListQuery(opts), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Contexts iterates over every context matching the given options, fetching
+// pages as needed. Iteration stops at the first error, which is yielded
+// with a nil context.
+func (c *Client) Contexts(ctx context.Context, opts ...ListOption) iter.Seq2[*models.Context, error] {
+	return func(yield func(*models.Context, error) bool) {
+		for cursor := ""; ; {
+			page, err := c.ListContextsPage(ctx, pageOptions(opts, cursor)...)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, item := range page.Contexts {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if cursor = page.NextCursor; cursor == "" {
+				return
+			}
+		}
+	}
+}
+
+// pageOptions returns opts positioned at cursor, or unchanged for the first
+// page
+func pageOptions(opts []ListOption, cursor string) []ListOption {
+	if cursor == "" {
+		return opts
+	}
+	return append(opts[:len(opts):len(opts)], WithCursor(cursor))
+}
+
+// SearchContexts runs a full-text query over the payloads and metadata of
+// the contexts the caller may read, most relevant first. The query accepts
+// web search syntax: "quoted phrases", OR, and -excluded terms. WithType and
+// WithSelector narrow the search. Snippets wrap matching terms in <mark>
+// tags and are not HTML-escaped.
+func (c *Client) SearchContexts(ctx context.Context, query string, opts ...ListOption) ([]*SearchResult, error) {
+	q := buildListQuery(opts)
+	q.Set("q", query)
+	var resp struct {
+		Contexts []*SearchResult `json:"contexts"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/contexts", q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Contexts, nil
+}
+
+// ListContextEvents returns logged context events after the given event ID,
+// oldest first, together with the ID to pass as after on the next call.
+// WithEventTypes filters by event type; WithContextType and WithSelector
+// filter by the context's type and metadata. SubscribeEvents delivers the same events
+// as they happen.
+func (c *Client) ListContextEvents(ctx context.Context, after int64, opts ...ListOption) ([]*models.ContextEvent, int64, error) {
+	q := buildListQuery(opts)
+	if after > 0 {
+		q.Set("after", strconv.FormatInt(after, 10))
+	}
+	var resp struct {
+		Events    []*models.ContextEvent `json:"events"`
+		NextAfter int64                  `json:"next_after"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/contexts/events", q, nil, &resp); err != nil {
+		return nil, after, err
+	}
+	return resp.Events, resp.NextAfter, nil
+}
+
+func buildListQuery(opts []ListOption) url.Values {
+	q := url.Values{}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
@@ -0,0 +1,210 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListAgents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/agents" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Fatalf("missing bearer token")
+		}
+		q := r.URL.Query()
+		if q.Get("selector") != "env=prod,!deprecated" {
+			t.Fatalf("unexpected selector %q", q.Get("selector"))
+		}
+		if q.Get("capability") != "summarize,translate" || q.Get("match") != "any" || q.Get("sort") != "liveness" {
+			t.Fatalf("unexpected capability query %v", q)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"agents": []map[string]any{{"id": "agent-1", "type": "ml"}},
+			"total":  1,
+		})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL), WithCredentials("token"))
+	agents, err := c.ListAgents(context.Background(),
+		WithSelector("env=prod,!deprecated"),
+		WithCapabilities("summarize", "translate"),
+		WithAnyCapability(),
+		WithLivenessRanking(),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(agents) != 1 || agents[0].ID != "agent-1" {
+		t.Fatalf("unexpected agents: %+v", agents)
+	}
+}
+
+func TestListContexts_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("selector") {
+		case "bad":
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid selector"})
+		case "":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"contexts": []map[string]any{{"id": "ctx-1", "type": "greeting"}},
+			})
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+
+	contexts, err := c.ListContexts(context.Background(), WithSelector("env=prod"), WithType("greeting"))
+	if err != nil || len(contexts) != 1 || contexts[0].ID != "ctx-1" {
+		t.Fatalf("unexpected result: %+v, %v", contexts, err)
+	}
+
+	_, err = c.ListContexts(context.Background(), WithSelector("bad"))
+	if !stdErrors.Is(err, ErrValidationFailed) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	var sdkErr *SDKError
+	if !stdErrors.As(err, &sdkErr) || sdkErr.Message != "invalid selector" {
+		t.Fatalf("expected server message, got %v", err)
+	}
+
+	_, err = c.ListContexts(context.Background())
+	if !stdErrors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	}
+}
+
+func TestSearchContexts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/contexts" || r.URL.Query().Get("q") != `"quarterly invoice"` || r.URL.Query().Get("type") != "note" {
+			t.Fatalf("unexpected request %s", r.URL)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"contexts": []map[string]any{{"id": "ctx-1", "type": "note", "rank": 0.8, "snippet": "<mark>quarterly</mark> <mark>invoice</mark>"}},
+			"total":    1,
+		})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	results, err := c.SearchContexts(context.Background(), `"quarterly invoice"`, WithType("note"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "ctx-1" || results[0].Rank != 0.8 || results[0].Snippet != "<mark>quarterly</mark> <mark>invoice</mark>" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestListContextEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/contexts/events" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		q := r.URL.Query()
+		if q.Get("after") != "41" || q.Get("type") != "context.expired,context.deleted" || q.Get("context_type") != "task" {
+			t.Fatalf("unexpected query %v", q)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"events":     []map[string]any{{"id": 42, "type": "context.expired", "context_id": "ctx-1"}},
+			"next_after": 42,
+		})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL), WithCredentials("token"))
+	events, next, err := c.ListContextEvents(context.Background(), 41, WithEventTypes(ContextEventExpired, ContextEventDeleted), WithContextType("task"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].ContextID != "ctx-1" || next != 42 {
+		t.Fatalf("unexpected events %+v, next %d", events, next)
+	}
+}
+
+func TestContextsIterator(t *testing.T) {
+	pages := map[string]map[string]any{
+		"":   {"contexts": []map[string]any{{"id": "ctx-3"}, {"id": "ctx-2"}}, "next_cursor": "c2", "total": 3},
+		"c2": {"contexts": []map[string]any{{"id": "ctx-1"}}, "total": 3},
+	}
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		q := r.URL.Query()
+		if q.Get("limit") != "2" || q.Get("type") != "note" {
+			t.Fatalf("unexpected query %v", q)
+		}
+		page, ok := pages[q.Get("cursor")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	var ids []string
+	for item, err := range c.Contexts(context.Background(), WithType("note"), WithLimit(2)) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, item.ID)
+	}
+	if len(ids) != 3 || ids[0] != "ctx-3" || ids[2] != "ctx-1" || requests != 2 {
+		t.Fatalf("unexpected iteration %v after %d requests", ids, requests)
+	}
+
+	// Breaking out early does not fetch further pages
+	requests = 0
+	for range c.Contexts(context.Background(), WithType("note"), WithLimit(2)) {
+		break
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+
+	page, err := c.ListContextsPage(context.Background(), WithType("note"), WithLimit(2), WithCursor("c2"), WithTotal())
+	if err != nil || len(page.Contexts) != 1 || page.NextCursor != "" || page.Total != 3 {
+		t.Fatalf("unexpected page %+v, %v", page, err)
+	}
+
+	for _, err := range c.Contexts(context.Background(), WithType("note"), WithLimit(2), WithCursor("bad")) {
+		if !stdErrors.Is(err, ErrValidationFailed) {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+	}
+}
+
+func TestAgentsIterator(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") == "" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"agents": []map[string]any{{"id": "agent-2"}}, "next_cursor": "a2"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"agents": []map[string]any{{"id": "agent-1"}}})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	var ids []string
+	for a, err := range c.Agents(context.Background()) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, a.ID)
+	}
+	if len(ids) != 2 || ids[1] != "agent-1" {
+		t.Fatalf("unexpected agents %v", ids)
+	}
+}
@@ -0,0 +1,64 @@
+package acb
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// GetLifecyclePolicy returns the lifecycle policy of a context type
+func (c *Client) GetLifecyclePolicy(ctx context.Context, contextType string) (*LifecyclePolicy, error) {
+	var resp struct {
+		Policy *LifecyclePolicy `json:"policy"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/policies/"+url.PathEscape(contextType), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Policy, nil
+}
+
+// ListLifecyclePolicies returns the lifecycle policies of the caller's tenant
+func (c *Client) ListLifecyclePolicies(ctx context.Context) ([]*LifecyclePolicy, error) {
+	var resp struct {
+		Policies []*LifecyclePolicy `json:"policies"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/policies", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Policies, nil
+}
+
+// SetLifecyclePolicy creates or replaces the lifecycle policy of
+// p.ContextType. Zero limits are not enforced and an empty AllowedScopes
+// allows every scope. Creates and updates that break the policy fail with
+// ErrValidationFailed. Requires the admin role.
+func (c *Client) SetLifecyclePolicy(ctx context.Context, p *LifecyclePolicy) (*LifecyclePolicy, error) {
+	body := struct {
+		DefaultTTL     int            `json:"default_ttl,omitempty"`
+		MaxTTL         int            `json:"max_ttl,omitempty"`
+		MaxPayloadSize int            `json:"max_payload_size,omitempty"`
+		MaxRevisions   int            `json:"max_revisions,omitempty"`
+		AllowedScopes  []ContextScope `json:"allowed_scopes,omitempty"`
+		NonOwnerDelete bool           `json:"non_owner_delete,omitempty"`
+	}{
+		DefaultTTL:     int(p.DefaultTTL.Seconds()),
+		MaxTTL:         int(p.MaxTTL.Seconds()),
+		MaxPayloadSize: p.MaxPayloadSize,
+		MaxRevisions:   p.MaxRevisions,
+		AllowedScopes:  p.AllowedScopes,
+		NonOwnerDelete: p.NonOwnerDelete,
+	}
+	var resp struct {
+		Policy *LifecyclePolicy `json:"policy"`
+	}
+	if err := c.doJSON(ctx, http.MethodPut, "/policies/"+url.PathEscape(p.ContextType), nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Policy, nil
+}
+
+// DeleteLifecyclePolicy removes the lifecycle policy of a context type.
+// Requires the admin role.
+func (c *Client) DeleteLifecyclePolicy(ctx context.Context, contextType string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/policies/"+url.PathEscape(contextType), nil, nil, nil)
+}
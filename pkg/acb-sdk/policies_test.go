@@ -0,0 +1,64 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLifecyclePolicies(t *testing.T) {
+	policy := map[string]any{
+		"context_type":     "session",
+		"default_ttl":      int64(time.Hour),
+		"max_ttl":          int64(24 * time.Hour),
+		"max_revisions":    10,
+		"allowed_scopes":   []string{"private"},
+		"non_owner_delete": true,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/policies":
+			_ = json.NewEncoder(w).Encode(map[string]any{"policies": []any{policy}, "count": 1})
+		case r.URL.Path != "/api/v1/policies/session":
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			if r.Method == http.MethodPut {
+				var body map[string]any
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				if body["default_ttl"] != float64(3600) || body["max_ttl"] != float64(86400) || body["non_owner_delete"] != true {
+					t.Fatalf("unexpected body %v", body)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"policy": policy})
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	ctx := context.Background()
+	p, err := c.SetLifecyclePolicy(ctx, &LifecyclePolicy{
+		ContextType:    "session",
+		DefaultTTL:     time.Hour,
+		MaxTTL:         24 * time.Hour,
+		MaxRevisions:   10,
+		AllowedScopes:  []ContextScope{ScopePrivate},
+		NonOwnerDelete: true,
+	})
+	if err != nil || p.MaxTTL != 24*time.Hour || !p.NonOwnerDelete {
+		t.Fatalf("unexpected policy %+v, %v", p, err)
+	}
+	if p, err = c.GetLifecyclePolicy(ctx, "session"); err != nil || p.MaxRevisions != 10 {
+		t.Fatalf("unexpected policy %+v, %v", p, err)
+	}
+	if policies, err := c.ListLifecyclePolicies(ctx); err != nil || len(policies) != 1 {
+		t.Fatalf("unexpected policies %v, %v", policies, err)
+	}
+	if err := c.DeleteLifecyclePolicy(ctx, "session"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+}
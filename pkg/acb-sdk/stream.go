@@ -79,30 +79,3 @@ func (r *Request) Wait() (*models.Message, error) {
 	// TODO: Implement request-reply pattern
 	return nil, fmt.Errorf("request-reply not fully implemented")
 }
-
-// Subscribe represents a subscription
-type Subscribe struct {
-	client *Client
-	topic  string
-}
-
-// Subscribe subscribes to context updates
-func (c *Client) Subscribe(topic string, handler func(context.Context, *models.Context) error) *Subscribe {
-	// TODO: Implement subscription
-	return &Subscribe{
-		client: c,
-		topic:  topic,
-	}
-}
-
-// Unsubscribe unsubscribes from updates
-func (s *Subscribe) Unsubscribe() error {
-	// TODO: Implement unsubscribe
-	return fmt.Errorf("unsubscribe not implemented")
-}
-
-// WithFilter sets a filter for subscription
-func (s *Subscribe) WithFilter(key, value string) *Subscribe {
-	// TODO: Implement filter
-	return s
-}
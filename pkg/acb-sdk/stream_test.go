@@ -21,7 +21,7 @@ func TestStreamBuilderChain(t *testing.T) {
 	}
 }
 
-func TestRequestAndSubscribe(t *testing.T) {
+func TestRequest(t *testing.T) {
 	c := NewClient()
 	req := c.Request(context.Background(), "agent", "topic", 1)
 	if req == nil {
@@ -30,15 +30,4 @@ func TestRequestAndSubscribe(t *testing.T) {
 	if _, err := req.Wait(); err == nil {
 		t.Fatal("expected Wait() error in MVP")
 	}
-
-	sub := c.Subscribe("topic", func(ctx context.Context, cxt *Context) error { return nil })
-	if sub == nil {
-		t.Fatal("expected non-nil subscribe")
-	}
-	if sub.WithFilter("k", "v") == nil {
-		t.Fatal("expected WithFilter to return self")
-	}
-	if err := sub.Unsubscribe(); err == nil {
-		t.Fatal("expected Unsubscribe error in MVP")
-	}
 }
@@ -0,0 +1,327 @@
+package acb
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acb/internal/models"
+)
+
+const (
+	subscribeMinBackoff = time.Second
+	subscribeMaxBackoff = 30 * time.Second
+)
+
+// Subscribe is a live subscription to context events streamed by the server.
+// After a dropped connection it reconnects with the ID of the last event it
+// handled, so retained events are delivered at least once and in order.
+type Subscribe struct {
+	client  *Client
+	topic   string
+	query   url.Values
+	handler func(context.Context, *ContextEvent) error
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu      sync.Mutex
+	filters map[string]string
+	lastID  int64
+	retry   time.Duration
+	err     error
+}
+
+// handlerError marks an error returned by the subscription's handler
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string { return e.err.Error() }
+func (e *handlerError) Unwrap() error { return e.err }
+
+// Subscribe calls handler with every context of type topic as it is created
+// or updated, as of that revision. Contexts deleted before they can be read
+// are skipped; use SubscribeEvents to observe deletions and expiries. Call
+// Unsubscribe to stop.
+func (c *Client) Subscribe(topic string, handler func(context.Context, *models.Context) error) *Subscribe {
+	opts := []ListOption{WithEventTypes(ContextEventCreated, ContextEventUpdated)}
+	if topic != "" {
+		opts = append(opts, WithContextType(topic))
+	}
+
+	sub := c.SubscribeEvents(context.Background(), func(ctx context.Context, e *ContextEvent) error {
+		cx, err := c.GetContextRevision(ctx, e.ContextID, e.Revision)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return handler(ctx, cx)
+	}, opts...)
+	sub.topic = topic
+	return sub
+}
+
+// SubscribeEvents streams context events matching opts (WithEventTypes,
+// WithContextType, WithSelector) to handler, starting with the first event logged
+// after the call. The subscription ends when ctx is cancelled, Unsubscribe
+// is called, the server rejects it, or handler returns an error; connection
+// failures are retried with backoff.
+func (c *Client) SubscribeEvents(ctx context.Context, handler func(context.Context, *ContextEvent) error, opts ...ListOption) *Subscribe {
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &Subscribe{
+		client:  c,
+		query:   buildListQuery(opts),
+		handler: handler,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		filters: make(map[string]string),
+		retry:   subscribeMinBackoff,
+	}
+	go sub.run(ctx)
+	return sub
+}
+
+// WithFilter only delivers events for contexts whose metadata has key set to
+// value. Filters apply immediately and are sent to the server on the next
+// reconnect.
+func (s *Subscribe) WithFilter(key, value string) *Subscribe {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filters[key] = value
+	return s
+}
+
+// Done is closed once the subscription has ended
+func (s *Subscribe) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error that ended the subscription, or nil while it runs
+// and after Unsubscribe
+func (s *Subscribe) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Unsubscribe stops the subscription and waits for the handler to return.
+// It returns the error that ended the subscription earlier, if any.
+func (s *Subscribe) Unsubscribe() error {
+	s.cancel()
+	<-s.done
+	return s.Err()
+}
+
+// run keeps the subscription connected until it is cancelled or fails
+// permanently
+func (s *Subscribe) run(ctx context.Context) {
+	defer close(s.done)
+	backoff := subscribeMinBackoff
+
+	for {
+		connected, err := s.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if permanent(err) {
+			var herr *handlerError
+			if errors.As(err, &herr) {
+				err = herr.err
+			}
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			return
+		}
+
+		delay := backoff
+		if connected {
+			// The server was reachable: wait only as long as it asked
+			s.mu.Lock()
+			delay = s.retry
+			s.mu.Unlock()
+			backoff = subscribeMinBackoff
+		} else if backoff *= 2; backoff > subscribeMaxBackoff {
+			backoff = subscribeMaxBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// permanent reports whether err should end the subscription rather than
+// trigger a reconnect
+func permanent(err error) bool {
+	var herr *handlerError
+	if errors.As(err, &herr) {
+		return !errors.Is(herr.err, ErrConnectionFailed)
+	}
+	return errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrForbidden) ||
+		errors.Is(err, ErrValidationFailed) || errors.Is(err, ErrNotFound)
+}
+
+// stream holds one connection to the event stream, dispatching events until
+// it fails. connected reports whether the server accepted the connection.
+func (s *Subscribe) stream(ctx context.Context) (connected bool, err error) {
+	header := http.Header{"Accept": {"text/event-stream"}}
+	query := s.connectQuery()
+	s.mu.Lock()
+	if s.lastID > 0 {
+		header.Set("Last-Event-ID", strconv.FormatInt(s.lastID, 10))
+	}
+	s.mu.Unlock()
+
+	req, err := s.client.newRequest(ctx, http.MethodGet, "/contexts/events", query, nil, header)
+	if err != nil {
+		return false, err
+	}
+	// The stream outlives any per-request timeout configured on the client
+	streamClient := *s.client.httpClient
+	streamClient.Timeout = 0
+	resp, err := send(&streamClient, req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	var id, event string
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return true, NewSDKError("CONNECTION_FAILED", "event stream closed").WithError(fmt.Errorf("%w: %v", ErrConnectionFailed, err))
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if err := s.dispatch(ctx, id, event, strings.Join(data, "\n")); err != nil {
+				return true, err
+			}
+			id, event, data = "", "", nil
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			id = value
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				s.mu.Lock()
+				s.retry = time.Duration(ms) * time.Millisecond
+				s.mu.Unlock()
+			}
+		}
+	}
+}
+
+// dispatch handles one received event and records its ID as the resume
+// position once it has been handled
+func (s *Subscribe) dispatch(ctx context.Context, id, event, data string) error {
+	switch event {
+	case "", "reset":
+		// A bare ID sets the resume position; a reset means events after the
+		// last one handled were pruned and delivery continues from the oldest
+	case "error":
+		return NewSDKError("STREAM_ERROR", data)
+	default:
+		var e ContextEvent
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return NewSDKError("DECODE_ERROR", "failed to decode event").WithError(err)
+		}
+		if s.matches(&e) {
+			if err := s.handler(ctx, &e); err != nil {
+				return &handlerError{err: err}
+			}
+		}
+	}
+
+	if id != "" {
+		if value, err := strconv.ParseInt(id, 10, 64); err == nil {
+			s.mu.Lock()
+			s.lastID = value
+			s.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// matches applies the WithFilter metadata filters to e
+func (s *Subscribe) matches(e *ContextEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, value := range s.filters {
+		if e.Metadata[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// connectQuery returns the stream query with WithFilter filters folded into
+// the label selector. Filters the selector syntax cannot express are only
+// applied by matches.
+func (s *Subscribe) connectQuery() url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := url.Values{}
+	for key, values := range s.query {
+		query[key] = values
+	}
+	if len(s.filters) == 0 {
+		return query
+	}
+
+	requirements := make([]string, 0, len(s.filters)+1)
+	if existing := query.Get("selector"); existing != "" {
+		requirements = append(requirements, existing)
+	}
+	keys := make([]string, 0, len(s.filters))
+	for key := range s.filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if selectorSafe(key) && selectorSafe(s.filters[key]) {
+			requirements = append(requirements, key+"="+s.filters[key])
+		}
+	}
+	if len(requirements) > 0 {
+		query.Set("selector", strings.Join(requirements, ","))
+	}
+	return query
+}
+
+// selectorSafe reports whether v can be written as a selector key or value
+func selectorSafe(v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, r := range v {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:", r)) {
+			return false
+		}
+	}
+	return true
+}
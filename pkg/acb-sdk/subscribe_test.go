@@ -0,0 +1,149 @@
+package acb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSubscribeEvents_ResumesAfterDisconnect(t *testing.T) {
+	release := make(chan struct{})
+	connections := make(chan *http.Request, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/contexts/events" || r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("unexpected request %s %v", r.URL, r.Header)
+		}
+		connections <- r
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		if r.Header.Get("Last-Event-ID") == "" {
+			fmt.Fprint(w, "retry: 10\nid: 5\n\n")
+			w.(http.Flusher).Flush()
+			<-release
+			fmt.Fprint(w, "id: 6\nevent: context.created\ndata: {\"id\":6,\"type\":\"context.created\",\"context_id\":\"a\",\"metadata\":{\"env\":\"prod\"}}\n\n")
+			fmt.Fprint(w, ": keep-alive\n\n")
+			fmt.Fprint(w, "id: 7\nevent: context.created\ndata: {\"id\":7,\"type\":\"context.created\",\"context_id\":\"b\",\"metadata\":{\"env\":\"dev\"}}\n\n")
+			return // Drop the connection
+		}
+		fmt.Fprint(w, "id: 8\nevent: context.updated\ndata: {\"id\":8,\"type\":\"context.updated\",\"context_id\":\"a\",\"metadata\":{\"env\":\"prod\"}}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	handled := make(chan *ContextEvent, 4)
+	c := NewClient(WithEndpoint(srv.URL), WithCredentials("token"))
+	sub := c.SubscribeEvents(context.Background(), func(ctx context.Context, e *ContextEvent) error {
+		handled <- e
+		return nil
+	}, WithEventTypes(ContextEventCreated, ContextEventUpdated), WithContextType("task"))
+	sub.WithFilter("env", "prod")
+
+	first := <-connections
+	if q := first.URL.Query(); q.Get("type") != "context.created,context.updated" || q.Get("context_type") != "task" {
+		t.Fatalf("unexpected query %v", q)
+	}
+	close(release)
+
+	for _, want := range []int64{6, 8} {
+		select {
+		case e := <-handled:
+			if e.ID != want || e.ContextID != "a" {
+				t.Fatalf("handled %+v, want event %d", e, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for event %d", want)
+		}
+	}
+
+	// The reconnect resumes after the last event seen and sends the filter
+	second := <-connections
+	if id := second.Header.Get("Last-Event-ID"); id != "7" {
+		t.Fatalf("reconnected with Last-Event-ID %q, want 7", id)
+	}
+	if selector := second.URL.Query().Get("selector"); selector != "env=prod" {
+		t.Fatalf("reconnected with selector %q", selector)
+	}
+
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+}
+
+func TestSubscribeEvents_EndsOnPermanentErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 1\nevent: context.deleted\ndata: {\"id\":1,\"type\":\"context.deleted\"}\n\n")
+	}))
+	defer srv.Close()
+
+	wait := func(sub *Subscribe) error {
+		select {
+		case <-sub.Done():
+		case <-time.After(2 * time.Second):
+			t.Fatal("subscription did not end")
+		}
+		return sub.Unsubscribe()
+	}
+
+	rejected := NewClient(WithEndpoint(srv.URL)).SubscribeEvents(context.Background(), func(context.Context, *ContextEvent) error { return nil })
+	if err := wait(rejected); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+
+	handlerErr := errors.New("boom")
+	failing := NewClient(WithEndpoint(srv.URL), WithCredentials("token")).SubscribeEvents(context.Background(), func(context.Context, *ContextEvent) error { return handlerErr })
+	if err := wait(failing); !errors.Is(err, handlerErr) {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+}
+
+func TestSubscribe_DeliversContextRevisions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/contexts/events":
+			if q := r.URL.Query(); q.Get("context_type") != "greetings" || q.Get("type") != "context.created,context.updated" {
+				t.Errorf("unexpected query %v", q)
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "id: 1\nevent: context.created\ndata: {\"id\":1,\"type\":\"context.created\",\"context_id\":\"gone\",\"revision\":1}\n\n")
+			fmt.Fprint(w, "id: 2\nevent: context.updated\ndata: {\"id\":2,\"type\":\"context.updated\",\"context_id\":\"ctx-1\",\"revision\":2}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "/api/v1/contexts/ctx-1":
+			if r.URL.Query().Get("revision") != "2" {
+				t.Errorf("unexpected revision %q", r.URL.Query().Get("revision"))
+			}
+			fmt.Fprint(w, `{"context":{"id":"ctx-1","revision":2,"payload":"aGk="}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	received := make(chan *Context, 1)
+	c := NewClient(WithEndpoint(srv.URL), WithCredentials("token"))
+	sub := c.Subscribe("greetings", func(ctx context.Context, cx *Context) error {
+		received <- cx
+		return nil
+	})
+	defer sub.Unsubscribe()
+
+	// The deleted context is skipped rather than ending the subscription
+	select {
+	case cx := <-received:
+		if cx.ID != "ctx-1" || cx.Revision != 2 || string(cx.Payload) != "hi" {
+			t.Fatalf("unexpected context %+v", cx)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for context")
+	}
+}
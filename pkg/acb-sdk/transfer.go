@@ -0,0 +1,56 @@
+package acb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Archive formats accepted by ExportTenant
+const (
+	ArchiveNDJSON = "ndjson"
+	ArchiveTar    = "tar"
+)
+
+// ExportTenant streams an archive of every agent and context of the caller's
+// tenant, with revision history, to w. Only tenant admins may export. An
+// empty format selects ArchiveNDJSON.
+func (c *Client) ExportTenant(ctx context.Context, w io.Writer, format string) error {
+	query := url.Values{}
+	if format != "" {
+		query.Set("format", format)
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/tenant/export", query, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return NewSDKError("CONNECTION_FAILED", "export interrupted").WithError(err)
+	}
+	return nil
+}
+
+// ImportTenant uploads an archive written by ExportTenant into the caller's
+// tenant. Records whose ID exists are handled according to onConflict,
+// ConflictFail when empty; nothing is written if the import fails.
+func (c *Client) ImportTenant(ctx context.Context, archive io.Reader, onConflict ConflictPolicy) (*ImportSummary, error) {
+	query := url.Values{}
+	if onConflict != "" {
+		query.Set("on_conflict", string(onConflict))
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/tenant/import", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(archive)
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	var summary ImportSummary
+	resp, err := send(c.httpClient, req)
+	if err := decodeResponse(resp, err, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
@@ -0,0 +1,66 @@
+package acb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExportAndImportTenant(t *testing.T) {
+	var imported []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/tenant/export":
+			if r.URL.Query().Get("format") != "tar" {
+				t.Fatalf("unexpected query %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte("archive"))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/tenant/import":
+			if r.URL.Query().Get("on_conflict") != "skip" {
+				t.Fatalf("unexpected query %s", r.URL.RawQuery)
+			}
+			imported, _ = io.ReadAll(r.Body)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"source_tenant_id": "acme", "tenant_id": "acme-staging",
+				"contexts": map[string]int{"created": 2, "skipped": 1},
+			})
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	var buf bytes.Buffer
+	if err := c.ExportTenant(context.Background(), &buf, ArchiveTar); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	summary, err := c.ImportTenant(context.Background(), &buf, ConflictSkip)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if string(imported) != "archive" {
+		t.Fatalf("unexpected archive %q", imported)
+	}
+	if summary.TenantID != "acme-staging" || summary.Contexts.Created != 2 || summary.Contexts.Skipped != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+}
+
+func TestImportTenantConflict(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "context with ID ctx-1 already exists: conflict"})
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	if _, err := c.ImportTenant(context.Background(), bytes.NewReader(nil), ""); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+}
@@ -4,13 +4,30 @@ import "github.com/acb/internal/models"
 
 // Types exports SDK types
 type (
-	Agent         = models.Agent
-	Context       = models.Context
-	Message       = models.Message
-	AgentStatus   = models.AgentStatus
-	MessageType   = models.MessageType
-	ContextScope  = models.ContextScope
-	AccessControl = models.AccessControl
+	Agent            = models.Agent
+	Context          = models.Context
+	ContextRevision  = models.ContextRevision
+	ContextEvent     = models.ContextEvent
+	SearchResult     = models.ContextSearchResult
+	ContextMatch     = models.ContextMatch
+	EmbeddingConfig  = models.EmbeddingConfig
+	SimilarityMetric = models.SimilarityMetric
+	ContextLink      = models.ContextLink
+	Lineage          = models.Lineage
+	LineageDirection = models.LineageDirection
+	BatchMode        = models.BatchMode
+	ConflictPolicy   = models.ConflictPolicy
+	ImportSummary    = models.ImportSummary
+	ExpirationPolicy = models.ExpirationPolicy
+	ExpirationConfig = models.ExpirationConfig
+	LifecyclePolicy  = models.LifecyclePolicy
+	Lease            = models.ContextLease
+	Message          = models.Message
+	AgentStatus      = models.AgentStatus
+	MessageType      = models.MessageType
+	ContextScope     = models.ContextScope
+	AccessControl    = models.AccessControl
+	ContextEventType = models.ContextEventType
 )
 
 // Constants exports SDK constants
@@ -28,4 +45,28 @@ const (
 	MessageTypeCommand  = models.MessageTypeCommand
 	MessageTypeQuery    = models.MessageTypeQuery
 	MessageTypeResponse = models.MessageTypeResponse
+
+	ContextEventCreated  = models.ContextEventCreated
+	ContextEventUpdated  = models.ContextEventUpdated
+	ContextEventDeleted  = models.ContextEventDeleted
+	ContextEventRestored = models.ContextEventRestored
+	ContextEventExpired  = models.ContextEventExpired
+	ContextEventStale    = models.ContextEventStale
+
+	MetricCosine = models.MetricCosine
+	MetricDot    = models.MetricDot
+
+	LineageAncestors   = models.LineageAncestors
+	LineageDescendants = models.LineageDescendants
+
+	BatchAtomic  = models.BatchAtomic
+	BatchPartial = models.BatchPartial
+
+	ConflictFail      = models.ConflictFail
+	ConflictSkip      = models.ConflictSkip
+	ConflictOverwrite = models.ConflictOverwrite
+
+	ExpirationFixed          = models.ExpirationFixed
+	ExpirationSlidingOnRead  = models.ExpirationSlidingOnRead
+	ExpirationSlidingOnWrite = models.ExpirationSlidingOnWrite
 )
@@ -0,0 +1,96 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/acb/internal/models"
+)
+
+// watchWaitTimeout is how long the server holds each read of WatchContext
+// open before answering that nothing changed
+const watchWaitTimeout = 30 * time.Second
+
+// WatchContext calls handler with each revision of a context written after
+// revision after (0 delivers the current revision first), using reads the
+// server holds open until the context changes. Revisions written in quick
+// succession may be coalesced into the latest one. WatchContext blocks until
+// ctx is cancelled (returning nil), handler returns an error (returned as is)
+// or the context is deleted or expires (ErrNotFound); connection failures are
+// retried with backoff.
+func (c *Client) WatchContext(ctx context.Context, contextID string, after int, handler func(context.Context, *models.Context) error) error {
+	backoff := subscribeMinBackoff
+	for {
+		cx, err := c.waitForContext(ctx, contextID, after)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, ErrConnectionFailed) {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > subscribeMaxBackoff {
+				backoff = subscribeMaxBackoff
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		backoff = subscribeMinBackoff
+		if cx == nil {
+			continue // Nothing changed before the server's timeout
+		}
+
+		after = cx.Revision
+		if err := handler(ctx, cx); err != nil {
+			return err
+		}
+	}
+}
+
+// waitForContext makes one read waiting for a revision after after. It
+// returns nil when the server answers that nothing changed in time.
+func (c *Client) waitForContext(ctx context.Context, contextID string, after int) (*models.Context, error) {
+	q := url.Values{
+		"wait_for_version": {strconv.Itoa(after)},
+		"timeout":          {watchWaitTimeout.String()},
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, "/contexts/"+url.PathEscape(contextID), q, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The read is held open on top of any per-request timeout configured on
+	// the client
+	waitClient := *c.httpClient
+	if waitClient.Timeout > 0 {
+		waitClient.Timeout += watchWaitTimeout
+	}
+	resp, err := waitClient.Do(req)
+	if err != nil {
+		return nil, NewSDKError("CONNECTION_FAILED", "request failed").WithError(fmt.Errorf("%w: %v", ErrConnectionFailed, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, errorFromResponse(resp)
+	}
+	var body struct {
+		Context *models.Context `json:"context"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, NewSDKError("DECODE_ERROR", "failed to decode response").WithError(err)
+	}
+	return body.Context, nil
+}
@@ -0,0 +1,59 @@
+package acb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acb/internal/models"
+)
+
+func TestWatchContext(t *testing.T) {
+	var waits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/contexts/task-1" || r.URL.Query().Get("timeout") != "30s" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		after := r.URL.Query().Get("wait_for_version")
+		waits = append(waits, after)
+		switch {
+		case after == "3" && len(waits) == 1:
+			w.WriteHeader(http.StatusNotModified)
+		case after == "3":
+			_ = json.NewEncoder(w).Encode(map[string]any{"context": map[string]any{"id": "task-1", "revision": 5}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "context not found"})
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithEndpoint(srv.URL))
+	var seen []int
+	err := c.WatchContext(context.Background(), "task-1", 3, func(ctx context.Context, cx *models.Context) error {
+		seen = append(seen, cx.Revision)
+		return nil
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the watch to end with ErrNotFound, got %v", err)
+	}
+	if len(seen) != 1 || seen[0] != 5 {
+		t.Fatalf("unexpected revisions %v", seen)
+	}
+	if len(waits) != 3 || waits[2] != "5" {
+		t.Fatalf("unexpected waits %v", waits)
+	}
+
+	// Handler errors end the watch
+	stop := errors.New("stop")
+	waits = nil
+	err = c.WatchContext(context.Background(), "task-1", 3, func(ctx context.Context, cx *models.Context) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected the handler error, got %v", err)
+	}
+}